@@ -3,14 +3,30 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spacemeshos/address"
 	"github.com/spacemeshos/explorer-backend/internal/api"
+	"github.com/spacemeshos/explorer-backend/internal/config"
+	debugServer "github.com/spacemeshos/explorer-backend/internal/debug"
+	"github.com/spacemeshos/explorer-backend/internal/httpserver"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	appService "github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/internal/storage/changestream"
+	"github.com/spacemeshos/explorer-backend/internal/storage/clickhouse"
 	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
-	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/postgres"
 	"github.com/urfave/cli/v2"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 var (
@@ -20,12 +36,43 @@ var (
 )
 
 var (
-	listenStringFlag      string
-	mongoDbURLStringFlag  string
-	mongoDbNameStringFlag string
-	testnetBoolFlag       bool
-	allowedOrigins        = cli.NewStringSlice("*")
-	debug                 bool
+	listenStringFlag             string
+	mongoDbURLStringFlag         string
+	mongoDbNameStringFlag        string
+	collectionPrefixStringFlag   string
+	statsMongoDbURLStringFlag    string
+	statsMongoDbNameStringFlag   string
+	readPreferenceFlag           string
+	maxStalenessFlag             time.Duration
+	readConcernFlag              string
+	dbMaxPoolSizeFlag            uint64
+	dbMinPoolSizeFlag            uint64
+	dbConnectTimeoutFlag         time.Duration
+	dbServerSelectionTimeoutFlag time.Duration
+	dbCompressorsFlag            = cli.NewStringSlice()
+	dbTLSFlag                    bool
+	dbTLSCAFileFlag              string
+	dbTLSCertificateKeyFileFlag  string
+	dbTLSInsecureFlag            bool
+	dbAuthMechanismFlag          string
+	dbAuthSourceFlag             string
+	dbUsernameFlag               string
+	dbPasswordFlag               string
+	dbDriverStringFlag           string
+	postgresURLStringFlag        string
+	clickhouseURLFlag            string
+	redisURLFlag                 string
+	redisTTLFlag                 time.Duration
+	testnetBoolFlag              bool
+	eventsBoolFlag               bool
+	tierAfterEpochsFlag          uint
+	metricsListenFlag            string
+	debugListenFlag              string
+	shutdownTimeoutFlag          time.Duration
+	allowedOrigins               = cli.NewStringSlice("*")
+	debug                        bool
+	logLevelFlag                 string
+	logFormatFlag                string
 )
 
 var flags = []cli.Flag{
@@ -53,6 +100,213 @@ var flags = []cli.Flag{
 		Value:       "explorer",
 		EnvVars:     []string{"SPACEMESH_MONGO_DB"},
 	},
+	&cli.StringFlag{
+		Name:        "collection-prefix",
+		Usage:       "Prefix of the MongoDB collection names, e.g. `testnet.`, so that the explorers of several networks share --db",
+		Required:    false,
+		Destination: &collectionPrefixStringFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_COLLECTION_PREFIX"},
+	},
+	&cli.StringFlag{
+		Name:        "stats-mongodb",
+		Usage:       "MongoDB Uri string of the deployment holding the stats collections, defaults to --mongodb",
+		Required:    false,
+		Destination: &statsMongoDbURLStringFlag,
+		EnvVars:     []string{"SPACEMESH_STATS_MONGO_URI"},
+	},
+	&cli.StringFlag{
+		Name:        "stats-db",
+		Usage:       "MongoDB database name of the stats collections, defaults to --db",
+		Required:    false,
+		Destination: &statsMongoDbNameStringFlag,
+		EnvVars:     []string{"SPACEMESH_STATS_MONGO_DB"},
+	},
+	&cli.StringFlag{
+		Name:        "read-preference",
+		Usage:       "MongoDB read preference of the API queries: primary, primaryPreferred, secondary, secondaryPreferred or nearest. Reading from secondaries keeps the API traffic off the primary the collector writes to",
+		Required:    false,
+		Destination: &readPreferenceFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_READ_PREFERENCE"},
+	},
+	&cli.DurationFlag{
+		Name:        "max-staleness",
+		Usage:       "Maximum replication lag of the secondaries serving the API queries, at least 90s, used with --read-preference",
+		Required:    false,
+		Destination: &maxStalenessFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_MAX_STALENESS"},
+	},
+	&cli.StringFlag{
+		Name:        "read-concern",
+		Usage:       "MongoDB read concern of the API queries: local, available, majority, linearizable or snapshot. Defaults to the read concern of the url",
+		Required:    false,
+		Destination: &readConcernFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_READ_CONCERN"},
+	},
+	&cli.Uint64Flag{
+		Name:        "db-max-pool-size",
+		Usage:       "Maximum number of connections to each MongoDB server, defaults to the url or 100",
+		Required:    false,
+		Destination: &dbMaxPoolSizeFlag,
+		EnvVars:     []string{"SPACEMESH_DB_MAX_POOL_SIZE"},
+	},
+	&cli.Uint64Flag{
+		Name:        "db-min-pool-size",
+		Usage:       "Number of connections to each MongoDB server kept open when idle, defaults to the url or 0",
+		Required:    false,
+		Destination: &dbMinPoolSizeFlag,
+		EnvVars:     []string{"SPACEMESH_DB_MIN_POOL_SIZE"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-connect-timeout",
+		Usage:       "Timeout of the establishment of a MongoDB connection, defaults to the url or 30s",
+		Required:    false,
+		Destination: &dbConnectTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_CONNECT_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-server-selection-timeout",
+		Usage:       "Timeout of the selection of a MongoDB server available for an operation, defaults to the url or 30s",
+		Required:    false,
+		Destination: &dbServerSelectionTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_SERVER_SELECTION_TIMEOUT"},
+	},
+	&cli.StringSliceFlag{
+		Name:        "db-compressors",
+		Usage:       "Compressors of the MongoDB messages in order of preference: snappy, zlib or zstd, defaults to the url or none",
+		Required:    false,
+		Destination: dbCompressorsFlag,
+		EnvVars:     []string{"SPACEMESH_DB_COMPRESSORS"},
+	},
+	&cli.BoolFlag{
+		Name:        "db-tls",
+		Usage:       "Connect to MongoDB with TLS, implied by the other --db-tls flags. The TLS flags replace the TLS settings of the url",
+		Required:    false,
+		Destination: &dbTLSFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS"},
+	},
+	&cli.StringFlag{
+		Name:        "db-tls-ca-file",
+		Usage:       "PEM bundle of the authorities verifying the MongoDB server certificate, defaults to the system ones",
+		Required:    false,
+		Destination: &dbTLSCAFileFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_CA_FILE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-tls-certificate-key-file",
+		Usage:       "PEM file holding the client certificate and its private key, for the MONGODB-X509 authentication",
+		Required:    false,
+		Destination: &dbTLSCertificateKeyFileFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_CERTIFICATE_KEY_FILE"},
+	},
+	&cli.BoolFlag{
+		Name:        "db-tls-insecure",
+		Usage:       "Skip the verification of the MongoDB server certificate, do not use in production",
+		Required:    false,
+		Destination: &dbTLSInsecureFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_INSECURE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-auth-mechanism",
+		Usage:       "MongoDB authentication mechanism: SCRAM-SHA-1, SCRAM-SHA-256, MONGODB-X509 or MONGODB-AWS, negotiated if empty. The auth flags replace the credentials of the url",
+		Required:    false,
+		Destination: &dbAuthMechanismFlag,
+		EnvVars:     []string{"SPACEMESH_DB_AUTH_MECHANISM"},
+	},
+	&cli.StringFlag{
+		Name:        "db-auth-source",
+		Usage:       "Database of the MongoDB user, defaults to admin or $external",
+		Required:    false,
+		Destination: &dbAuthSourceFlag,
+		EnvVars:     []string{"SPACEMESH_DB_AUTH_SOURCE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-username",
+		Usage:       "MongoDB username, defaults to the subject of the client certificate with MONGODB-X509",
+		Required:    false,
+		Destination: &dbUsernameFlag,
+		EnvVars:     []string{"SPACEMESH_DB_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:        "db-password",
+		Usage:       "MongoDB password, prefer the environment variable to the flag",
+		Required:    false,
+		Destination: &dbPasswordFlag,
+		EnvVars:     []string{"SPACEMESH_DB_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:        "db-driver",
+		Usage:       "Explorer storage backend: mongo or postgres",
+		Required:    false,
+		Destination: &dbDriverStringFlag,
+		Value:       "mongo",
+		EnvVars:     []string{"SPACEMESH_DB_DRIVER"},
+	},
+	&cli.StringFlag{
+		Name:        "postgres",
+		Usage:       "Explorer PostgreSQL connection string in format postgres://<user>:<password>@<host>:<port>/<db>, used with --db-driver=postgres",
+		Required:    false,
+		Destination: &postgresURLStringFlag,
+		Value:       "postgres://localhost:5432/explorer",
+		EnvVars:     []string{"SPACEMESH_POSTGRES_URL"},
+	},
+	&cli.StringFlag{
+		Name:        "clickhouse",
+		Usage:       "Serve rewards aggregations from ClickHouse tables written by the collector clickhouse sink, in format clickhouse://<host>:<port>/<db>",
+		Required:    false,
+		Destination: &clickhouseURLFlag,
+		EnvVars:     []string{"SPACEMESH_CLICKHOUSE_URL"},
+	},
+	&cli.StringFlag{
+		Name:        "redis",
+		Usage:       "Cache network info, current layer and epoch and the first accounts pages in Redis, in format redis://<host>:<port>/<db>. The collector should run with the same --redis to invalidate them",
+		Required:    false,
+		Destination: &redisURLFlag,
+		EnvVars:     []string{"SPACEMESH_REDIS_URL"},
+	},
+	&cli.DurationFlag{
+		Name:        "redis-ttl",
+		Usage:       "Expiration of the Redis cache entries not invalidated by the collector",
+		Required:    false,
+		Destination: &redisTTLFlag,
+		Value:       5 * time.Minute,
+		EnvVars:     []string{"SPACEMESH_REDIS_TTL"},
+	},
+	&cli.StringFlag{
+		Name:        "metrics-listen",
+		Usage:       "Expose the Prometheus metrics, including the storage operations durations, on /metrics and the storage size report on /admin/storage in format <host>:<port>. Disabled if empty",
+		Required:    false,
+		Destination: &metricsListenFlag,
+		EnvVars:     []string{"SPACEMESH_METRICS_LISTEN"},
+	},
+	&cli.StringFlag{
+		Name:        "debug-listen",
+		Usage:       "Expose the pprof profiles on /debug/pprof/ and the expvar variables on /debug/vars in format <host>:<port>. Disabled if empty",
+		Required:    false,
+		Destination: &debugListenFlag,
+		EnvVars:     []string{"SPACEMESH_DEBUG_LISTEN"},
+	},
+	&cli.DurationFlag{
+		Name:        "shutdown-timeout",
+		Usage:       "Time given to the requests in flight on SIGINT or SIGTERM",
+		Required:    false,
+		Value:       httpserver.DefaultShutdownTimeout,
+		Destination: &shutdownTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_SHUTDOWN_TIMEOUT"},
+	},
+	&cli.BoolFlag{
+		Name:        "events",
+		Usage:       "Serve the inserted and updated layers, epochs, transactions, rewards and activations on /events and /ws/events, read from the MongoDB change streams. Requires a replica set",
+		Required:    false,
+		Destination: &eventsBoolFlag,
+		EnvVars:     []string{"SPACEMESH_EVENTS"},
+	},
+	&cli.UintFlag{
+		Name:        "tier-after-epochs",
+		Usage:       "The --tier-after-epochs of the collector, the deep pages of the layers, transactions and blocks lists are then continued in their archive collections. 0 if the tiering is disabled",
+		Required:    false,
+		Destination: &tierAfterEpochsFlag,
+		EnvVars:     []string{"SPACEMESH_TIER_AFTER_EPOCHS"},
+	},
 	&cli.BoolFlag{
 		Name:        "testnet",
 		Usage:       `Use this flag to enable testnet preset ("stest" instead of "sm" for wallet addresses)`,
@@ -73,38 +327,163 @@ var flags = []cli.Flag{
 		Destination: &debug,
 		EnvVars:     []string{"DEBUG"},
 	},
+
+	&cli.StringFlag{
+		Name:        "log-level",
+		Usage:       "Level of the logs: debug, info, warn or error",
+		Value:       "info",
+		Destination: &logLevelFlag,
+		EnvVars:     []string{"SPACEMESH_LOG_LEVEL"},
+	},
+	&cli.StringFlag{
+		Name:        "log-format",
+		Usage:       "Format of the logs: console or json",
+		Value:       logging.FormatConsole,
+		Destination: &logFormatFlag,
+		EnvVars:     []string{"SPACEMESH_LOG_FORMAT"},
+	},
 }
 
 func main() {
 	app := cli.NewApp()
 	app.Name = "Spacemesh Explorer REST API Server"
 	app.Version = fmt.Sprintf("%s, commit '%s', branch '%s'", version, commit, branch)
-	app.Flags = flags
+	app.Flags = config.WithFile(flags)
+	app.Before = func(ctx *cli.Context) error {
+		if err := config.Load(app.Flags)(ctx); err != nil {
+			return err
+		}
+		return logging.Setup(logLevelFlag, logFormatFlag)
+	}
 	app.Writer = os.Stderr
 
-	app.Action = func(ctx *cli.Context) error {
+	app.Action = func(cliCtx *cli.Context) error {
+		ctx, stop := signal.NotifyContext(cliCtx.Context, syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		g, gctx := errgroup.WithContext(ctx)
+
 		if testnetBoolFlag {
 			address.SetAddressConfig("stest")
-			log.Info(`network HRP set to "stest"`)
+			logging.Info("network HRP set", zap.String("hrp", "stest"))
 		}
 
-		dbReader, err := storagereader.NewStorageReader(context.Background(), mongoDbURLStringFlag, mongoDbNameStringFlag)
+		var dbReader storagereader.StorageReader
+		// conn is the connection settings of the mongo clients, shared by the change streams watcher
+		var conn *options.ClientOptions
+		var err error
+		switch dbDriverStringFlag {
+		case "mongo":
+			var opts []*options.ClientOptions
+			if readPreferenceFlag != "" {
+				opt, err := storagereader.ReadPreference(readPreferenceFlag, maxStalenessFlag)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, opt)
+			}
+			var rc *options.ClientOptions
+			if rc, err = storage.ReadConcern(readConcernFlag); err != nil {
+				return err
+			}
+			opts = append(opts, rc)
+			if conn, err = (storage.Connection{
+				MaxPoolSize:            dbMaxPoolSizeFlag,
+				MinPoolSize:            dbMinPoolSizeFlag,
+				ConnectTimeout:         dbConnectTimeoutFlag,
+				ServerSelectionTimeout: dbServerSelectionTimeoutFlag,
+				Compressors:            dbCompressorsFlag.Value(),
+				TLS:                    dbTLSFlag,
+				TLSCAFile:              dbTLSCAFileFlag,
+				TLSCertificateKeyFile:  dbTLSCertificateKeyFileFlag,
+				TLSInsecure:            dbTLSInsecureFlag,
+				AuthMechanism:          dbAuthMechanismFlag,
+				AuthSource:             dbAuthSourceFlag,
+				Username:               dbUsernameFlag,
+				Password:               dbPasswordFlag,
+			}).Options(); err != nil {
+				return err
+			}
+			opts = append(opts, conn)
+			var mongoReader *storagereader.Reader
+			if mongoReader, err = storagereader.NewStorageReader(context.Background(), mongoDbURLStringFlag, mongoDbNameStringFlag, collectionPrefixStringFlag, opts...); err != nil {
+				break
+			}
+			mongoReader.SetTiering(tierAfterEpochsFlag > 0)
+			err = mongoReader.OpenStatsDatabase(context.Background(), statsMongoDbURLStringFlag, statsMongoDbNameStringFlag, opts...)
+			dbReader = mongoReader
+		case "postgres":
+			dbReader, err = postgres.NewReader(context.Background(), postgresURLStringFlag)
+		default:
+			err = fmt.Errorf("unknown db driver `%s`", dbDriverStringFlag)
+		}
 		if err != nil {
 			return fmt.Errorf("error init storage reader: %w", err)
 		}
 
 		service := appService.NewService(dbReader, time.Minute)
+		if clickhouseURLFlag != "" {
+			analytics, err := clickhouse.NewReader(clickhouseURLFlag)
+			if err != nil {
+				return fmt.Errorf("error init analytics reader: %w", err)
+			}
+			defer analytics.Close()
+			service.SetAnalytics(analytics)
+		}
+		if redisURLFlag != "" {
+			redisCache, err := cache.New(redisURLFlag, redisTTLFlag)
+			if err != nil {
+				return fmt.Errorf("error init redis cache: %w", err)
+			}
+			defer redisCache.Close()
+			service.SetCache(redisCache)
+		}
+		if eventsBoolFlag {
+			if dbDriverStringFlag != "mongo" {
+				return fmt.Errorf("real-time events require the mongo db driver")
+			}
+			bus := changestream.NewBus()
+			watcher, err := changestream.New(context.Background(), mongoDbURLStringFlag, mongoDbNameStringFlag, collectionPrefixStringFlag, bus, conn)
+			if err != nil {
+				return fmt.Errorf("error init change streams watcher: %w", err)
+			}
+			defer watcher.Close()
+			g.Go(func() error {
+				watcher.Run(gctx)
+				return nil
+			})
+			service.SetEvents(bus)
+		}
+		if metricsListenFlag != "" {
+			g.Go(func() error {
+				api.WatchStorageStats(gctx, dbReader, storage.StorageStatsInterval)
+				return nil
+			})
+			g.Go(func() error {
+				api.WatchConcentration(gctx, service, storage.StorageStatsInterval)
+				return nil
+			})
+			g.Go(func() error {
+				mux := http.NewServeMux()
+				mux.Handle("/metrics", promhttp.Handler())
+				mux.Handle("/admin/storage", api.StorageStatsHandler(dbReader))
+				return httpserver.Serve(gctx, "metrics", metricsListenFlag, mux, shutdownTimeoutFlag)
+			})
+		}
+		g.Go(func() error {
+			return debugServer.Serve(gctx, debugListenFlag, nil, shutdownTimeoutFlag)
+		})
 		server := api.Init(service, allowedOrigins.Value(), debug)
+		g.Go(func() error {
+			return server.Run(gctx, listenStringFlag, shutdownTimeoutFlag)
+		})
 
-		log.Info(fmt.Sprintf("starting server on %s", listenStringFlag))
-		server.Run(listenStringFlag)
-
-		log.Info("server is shutdown")
-		return nil
+		err = g.Wait()
+		logging.Info("server is shutdown")
+		return err
 	}
 
 	if err := app.Run(os.Args); err != nil {
-		log.Info("%v", err)
+		logging.Error("api server failed", err)
 		os.Exit(1)
 	}
 
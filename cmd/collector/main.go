@@ -2,20 +2,36 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spacemeshos/address"
 	"github.com/spacemeshos/explorer-backend/collector"
 	"github.com/spacemeshos/explorer-backend/collector/sql"
+	"github.com/spacemeshos/explorer-backend/internal/config"
+	"github.com/spacemeshos/explorer-backend/internal/debug"
+	"github.com/spacemeshos/explorer-backend/internal/httpserver"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/pricefeed"
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/internal/systemd"
+	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/bench"
+	"github.com/spacemeshos/explorer-backend/storage/postgres"
 	"github.com/spacemeshos/go-spacemesh/common/types"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/urfave/cli/v2"
+	"golang.org/x/sync/errgroup"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 var (
@@ -29,15 +45,66 @@ var (
 	nodePrivateAddressStringFlag  string
 	mongoDbUrlStringFlag          string
 	mongoDbNameStringFlag         string
+	collectionPrefixStringFlag    string
+	statsMongoDbUrlStringFlag     string
+	statsMongoDbNameStringFlag    string
+	dbDriverStringFlag            string
+	postgresUrlStringFlag         string
+	migrateBoolFlag               bool
+	createIndexesBoolFlag         bool
 	testnetBoolFlag               bool
 	syncFromLayerFlag             int
 	syncMissingLayersBoolFlag     bool
 	sqlitePathStringFlag          string
 	metricsPortFlag               int
+	debugListenFlag               string
+	pidFileFlag                   string
+	watchdogStallFlag             time.Duration
+	shutdownTimeoutFlag           time.Duration
 	apiHostFlag                   string
 	apiPortFlag                   int
 	recalculateEpochStatsBoolFlag bool
 	atxSyncFlag                   bool
+	watchAccountsFlag             = cli.NewStringSlice()
+	sqliteSourcesFlag             = cli.NewStringSlice()
+	sinksFlag                     = cli.NewStringSlice()
+	redisURLFlag                  string
+	retentionFlag                 = cli.NewStringSlice()
+	archiveBoolFlag               bool
+	tierAfterEpochsFlag           uint
+	accountSnapshotLayersFlag     uint
+	rollupIntervalFlag            time.Duration
+	geoHeatmapIntervalFlag        time.Duration
+	priceFeedFlag                 string
+	priceIntervalFlag             time.Duration
+	writeConcernFlag              string
+	journalBoolFlag               bool
+	readConcernFlag               string
+	dbTimeoutFlag                 time.Duration
+	dbQueryTimeoutFlag            time.Duration
+	dbBulkTimeoutFlag             time.Duration
+	dbAggregateTimeoutFlag        time.Duration
+	dbRetryAttemptsFlag           int
+	dbRetryMaxBackoffFlag         time.Duration
+	dbMaxPoolSizeFlag             uint64
+	dbMinPoolSizeFlag             uint64
+	dbConnectTimeoutFlag          time.Duration
+	dbServerSelectionTimeoutFlag  time.Duration
+	dbCompressorsFlag             = cli.NewStringSlice()
+	dbTLSFlag                     bool
+	dbTLSCAFileFlag               string
+	dbTLSCertificateKeyFileFlag   string
+	dbTLSInsecureFlag             bool
+	dbAuthMechanismFlag           string
+	dbAuthSourceFlag              string
+	dbUsernameFlag                string
+	dbPasswordFlag                string
+	grpcCallTimeoutFlag           time.Duration
+	grpcKeepaliveTimeFlag         time.Duration
+	grpcKeepaliveTimeoutFlag      time.Duration
+	grpcMaxRecvMsgSizeFlag        int
+	logLevelFlag                  string
+	logFormatFlag                 string
 )
 
 var flags = []cli.Flag{
@@ -73,6 +140,80 @@ var flags = []cli.Flag{
 		Value:       "explorer",
 		EnvVars:     []string{"SPACEMESH_MONGO_DB"},
 	},
+	&cli.StringFlag{
+		Name:        "collection-prefix",
+		Usage:       "Prefix of the MongoDB collection names, e.g. `testnet.`, so that the explorers of several networks share --db",
+		Required:    false,
+		Destination: &collectionPrefixStringFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_COLLECTION_PREFIX"},
+	},
+	&cli.StringFlag{
+		Name:        "stats-mongodb",
+		Usage:       "MongoDB Uri string of the deployment holding the stats collections, defaults to --mongodb",
+		Required:    false,
+		Destination: &statsMongoDbUrlStringFlag,
+		EnvVars:     []string{"SPACEMESH_STATS_MONGO_URI"},
+	},
+	&cli.StringFlag{
+		Name:        "stats-db",
+		Usage:       "MongoDB database name of the stats collections, defaults to --db",
+		Required:    false,
+		Destination: &statsMongoDbNameStringFlag,
+		EnvVars:     []string{"SPACEMESH_STATS_MONGO_DB"},
+	},
+	&cli.StringFlag{
+		Name:        "write-concern",
+		Usage:       "MongoDB write concern: the number of members acknowledging the writes or majority. Lower it during the initial sync to speed up the ingestion and raise it afterwards. Defaults to the write concern of the url",
+		Required:    false,
+		Destination: &writeConcernFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_WRITE_CONCERN"},
+	},
+	&cli.BoolFlag{
+		Name:        "journal",
+		Usage:       "Acknowledge the MongoDB writes once written to the journal",
+		Required:    false,
+		Destination: &journalBoolFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_JOURNAL"},
+	},
+	&cli.StringFlag{
+		Name:        "read-concern",
+		Usage:       "MongoDB read concern: local, available, majority, linearizable or snapshot. Defaults to the read concern of the url",
+		Required:    false,
+		Destination: &readConcernFlag,
+		EnvVars:     []string{"SPACEMESH_MONGO_READ_CONCERN"},
+	},
+	&cli.StringFlag{
+		Name:        "db-driver",
+		Usage:       "Explorer storage backend: mongo or postgres",
+		Required:    false,
+		Destination: &dbDriverStringFlag,
+		Value:       "mongo",
+		EnvVars:     []string{"SPACEMESH_DB_DRIVER"},
+	},
+	&cli.StringFlag{
+		Name:        "postgres",
+		Usage:       "Explorer PostgreSQL connection string in format postgres://<user>:<password>@<host>:<port>/<db>, used with --db-driver=postgres",
+		Required:    false,
+		Destination: &postgresUrlStringFlag,
+		Value:       "postgres://localhost:5432/explorer",
+		EnvVars:     []string{"SPACEMESH_POSTGRES_URL"},
+	},
+	&cli.BoolFlag{
+		Name:        "migrate",
+		Usage:       "Apply pending MongoDB migrations on start, see the migrate command",
+		Required:    false,
+		Destination: &migrateBoolFlag,
+		Value:       true,
+		EnvVars:     []string{"SPACEMESH_MIGRATE"},
+	},
+	&cli.BoolFlag{
+		Name:        "create-indexes",
+		Usage:       "Create and drop the MongoDB indexes in the migrations and on start. Disable it if the indexes are managed and built out of the explorer, the differences with the expected indexes are logged",
+		Required:    false,
+		Destination: &createIndexesBoolFlag,
+		Value:       true,
+		EnvVars:     []string{"SPACEMESH_CREATE_INDEXES"},
+	},
 	&cli.BoolFlag{
 		Name:        "testnet",
 		Usage:       `Use this flag to enable testnet preset ("stest" instead of "sm" for wallet addresses)`,
@@ -82,7 +223,7 @@ var flags = []cli.Flag{
 	},
 	&cli.IntFlag{
 		Name:        "syncFromLayer",
-		Usage:       ``,
+		Usage:       `Layer to start the sync from when the database is empty`,
 		Required:    false,
 		Value:       0,
 		Destination: &syncFromLayerFlag,
@@ -104,6 +245,45 @@ var flags = []cli.Flag{
 		Value:       "explorer.sql",
 		EnvVars:     []string{"SPACEMESH_SQLITE"},
 	},
+	&cli.StringSliceFlag{
+		Name:        "sqlite-source",
+		Usage:       "Read a table from a separate sqlite file, in format <table>=<path> (e.g. atxs=/data/atx.sql). Can be repeated",
+		Required:    false,
+		Destination: sqliteSourcesFlag,
+		EnvVars:     []string{"SPACEMESH_SQLITE_SOURCES"},
+	},
+	&cli.DurationFlag{
+		Name:        "grpc-call-timeout",
+		Usage:       "Deadline of unary gRPC calls to the node",
+		Required:    false,
+		Value:       collector.DefaultGrpcConfig().CallTimeout,
+		Destination: &grpcCallTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_GRPC_CALL_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "grpc-keepalive-time",
+		Usage:       "Interval of keepalive pings on idle gRPC connections to the node",
+		Required:    false,
+		Value:       collector.DefaultGrpcConfig().KeepaliveTime,
+		Destination: &grpcKeepaliveTimeFlag,
+		EnvVars:     []string{"SPACEMESH_GRPC_KEEPALIVE_TIME"},
+	},
+	&cli.DurationFlag{
+		Name:        "grpc-keepalive-timeout",
+		Usage:       "Time to wait for a keepalive ack before closing the gRPC connection",
+		Required:    false,
+		Value:       collector.DefaultGrpcConfig().KeepaliveTimeout,
+		Destination: &grpcKeepaliveTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_GRPC_KEEPALIVE_TIMEOUT"},
+	},
+	&cli.IntFlag{
+		Name:        "grpc-max-recv-msg-size",
+		Usage:       "Maximum size in bytes of a gRPC message received from the node",
+		Required:    false,
+		Value:       collector.DefaultGrpcConfig().MaxRecvMsgSize,
+		Destination: &grpcMaxRecvMsgSizeFlag,
+		EnvVars:     []string{"SPACEMESH_GRPC_MAX_RECV_MSG_SIZE"},
+	},
 	&cli.IntFlag{
 		Name:        "metricsPort",
 		Usage:       ``,
@@ -112,9 +292,40 @@ var flags = []cli.Flag{
 		Destination: &metricsPortFlag,
 		EnvVars:     []string{"SPACEMESH_METRICS_PORT"},
 	},
+	&cli.StringFlag{
+		Name:        "debug-listen",
+		Usage:       "Expose the pprof profiles on /debug/pprof/ and the expvar variables on /debug/vars in format <host>:<port>. Disabled if empty",
+		Required:    false,
+		Destination: &debugListenFlag,
+		EnvVars:     []string{"SPACEMESH_DEBUG_LISTEN"},
+	},
+	&cli.StringFlag{
+		Name:        "pid-file",
+		Usage:       "Path of the file written with the pid of the collector and removed on exit. Disabled if empty",
+		Required:    false,
+		Value:       "/var/run/explorer-collector",
+		Destination: &pidFileFlag,
+		EnvVars:     []string{"SPACEMESH_PID_FILE"},
+	},
+	&cli.DurationFlag{
+		Name:        "watchdog-stall-timeout",
+		Usage:       "Stop pinging the systemd watchdog, enabled by WatchdogSec= of the unit, when the collector is behind the node and has not synced a layer for this duration",
+		Required:    false,
+		Value:       15 * time.Minute,
+		Destination: &watchdogStallFlag,
+		EnvVars:     []string{"SPACEMESH_WATCHDOG_STALL_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "shutdown-timeout",
+		Usage:       "Time given to the requests in flight and to the layers received from the node to be stored on SIGINT or SIGTERM",
+		Required:    false,
+		Value:       httpserver.DefaultShutdownTimeout,
+		Destination: &shutdownTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_SHUTDOWN_TIMEOUT"},
+	},
 	&cli.BoolFlag{
 		Name:        "recalculateEpochStats",
-		Usage:       `Use this flag to recalculate epoch stats`,
+		Usage:       `Recalculate the epoch stats on start, see the backfill command`,
 		Required:    false,
 		Destination: &recalculateEpochStatsBoolFlag,
 		Value:       false,
@@ -144,86 +355,1090 @@ var flags = []cli.Flag{
 		Destination: &atxSyncFlag,
 		EnvVars:     []string{"SPACEMESH_ATX_SYNC"},
 	},
+	&cli.StringSliceFlag{
+		Name:        "watch-accounts",
+		Usage:       `Persist only data touching the given addresses (watch mode). Layers and epochs are always stored, their stats count the whole chain`,
+		Required:    false,
+		Destination: watchAccountsFlag,
+		EnvVars:     []string{"SPACEMESH_WATCH_ACCOUNTS"},
+	},
+	&cli.StringSliceFlag{
+		Name:        "sink",
+		Usage:       `Publish collected entities to a message bus in addition to MongoDB, e.g. nats://localhost:4222/explorer, kafka://localhost:9092/explorer or clickhouse://localhost:9000/explorer`,
+		Required:    false,
+		Destination: sinksFlag,
+		EnvVars:     []string{"SPACEMESH_SINKS"},
+	},
+	&cli.StringFlag{
+		Name:        "redis",
+		Usage:       "Invalidate the API documents cache in Redis on writes, in format redis://<host>:<port>/<db>",
+		Required:    false,
+		Destination: &redisURLFlag,
+		EnvVars:     []string{"SPACEMESH_REDIS_URL"},
+	},
+	&cli.StringSliceFlag{
+		Name:        "retention",
+		Usage:       `Prune documents older than the given age, in format <collection>[.<field>]=<age>, e.g. txs.raw=180d to drop the raw payload of transactions older than 6 months or rewards=365d. Layers, epochs, accounts and smeshers are kept forever`,
+		Required:    false,
+		Destination: retentionFlag,
+		EnvVars:     []string{"SPACEMESH_RETENTION"},
+	},
+	&cli.BoolFlag{
+		Name:        "archive",
+		Usage:       "Keep the raw node responses of layers, rewards, transaction results and malfeasance proofs, to reprocess them without the node",
+		Required:    false,
+		Value:       false,
+		Destination: &archiveBoolFlag,
+		EnvVars:     []string{"SPACEMESH_ARCHIVE"},
+	},
+	&cli.UintFlag{
+		Name:        "tier-after-epochs",
+		Usage:       "Move the layers, transactions and blocks older than the given number of epochs to compressed archive collections, read by the API for the deep history. Requires the mongo db driver, 0 disables the tiering",
+		Required:    false,
+		Destination: &tierAfterEpochsFlag,
+		EnvVars:     []string{"SPACEMESH_TIER_AFTER_EPOCHS"},
+	},
+	&cli.UintFlag{
+		Name:        "account-snapshot-layers",
+		Usage:       "Snapshot the balances of all the accounts every given number of layers, so that the balance of an account at a layer is read from the last snapshot and the changes since. Requires the mongo db driver, 0 disables the snapshots",
+		Required:    false,
+		Destination: &accountSnapshotLayersFlag,
+		EnvVars:     []string{"SPACEMESH_ACCOUNT_SNAPSHOT_LAYERS"},
+	},
+	&cli.DurationFlag{
+		Name:        "rollup-interval",
+		Usage:       "Update the daily and the weekly rollups of the activity of the network at the given interval, 0 disables the updates. Only used by the mongo db driver, the others compute the rollups on read",
+		Required:    false,
+		Value:       10 * time.Minute,
+		Destination: &rollupIntervalFlag,
+		EnvVars:     []string{"SPACEMESH_ROLLUP_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:        "geo-heatmap-interval",
+		Usage:       "Aggregate the committed space of the located smeshers by country and by region at the given interval, 0 disables the aggregation. Only used by the mongo db driver, the others compute the heat-map on read",
+		Required:    false,
+		Value:       10 * time.Minute,
+		Destination: &geoHeatmapIntervalFlag,
+		EnvVars:     []string{"SPACEMESH_GEO_HEATMAP_INTERVAL"},
+	},
+	&cli.StringFlag{
+		Name:        "price-feed",
+		Usage:       "Record the SMH price in USD from a price API to serve fiat values of the volumes and the fees, e.g. coingecko://spacemesh or coinmarketcap://<api key>@SMH. Disabled by default, the collector then makes no external call",
+		Required:    false,
+		Destination: &priceFeedFlag,
+		EnvVars:     []string{"SPACEMESH_PRICE_FEED"},
+	},
+	&cli.DurationFlag{
+		Name:        "price-interval",
+		Usage:       "Record the price of the price feed at the given interval",
+		Required:    false,
+		Value:       10 * time.Minute,
+		Destination: &priceIntervalFlag,
+		EnvVars:     []string{"SPACEMESH_PRICE_INTERVAL"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-timeout",
+		Usage:       "Timeout of every database operation, unless overridden by the timeout of its class",
+		Required:    false,
+		Destination: &dbTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-query-timeout",
+		Usage:       fmt.Sprintf("Timeout of point reads, counts and single document writes (default %s)", storage.DefaultTimeouts.Query),
+		Required:    false,
+		Destination: &dbQueryTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_QUERY_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-bulk-timeout",
+		Usage:       fmt.Sprintf("Timeout of bulk writes (default %s)", storage.DefaultTimeouts.Bulk),
+		Required:    false,
+		Destination: &dbBulkTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_BULK_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-aggregate-timeout",
+		Usage:       fmt.Sprintf("Timeout of aggregation queries (default %s)", storage.DefaultTimeouts.Aggregate),
+		Required:    false,
+		Destination: &dbAggregateTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_AGGREGATE_TIMEOUT"},
+	},
+	&cli.IntFlag{
+		Name:        "db-retry-attempts",
+		Usage:       "Maximum number of attempts of the reads failing with a transient database error, 1 disables the retries",
+		Required:    false,
+		Value:       storage.DefaultRetryPolicy.Attempts,
+		Destination: &dbRetryAttemptsFlag,
+		EnvVars:     []string{"SPACEMESH_DB_RETRY_ATTEMPTS"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-retry-max-backoff",
+		Usage:       "Maximum pause between two attempts of a read failing with a transient database error",
+		Required:    false,
+		Value:       storage.DefaultRetryPolicy.MaxBackoff,
+		Destination: &dbRetryMaxBackoffFlag,
+		EnvVars:     []string{"SPACEMESH_DB_RETRY_MAX_BACKOFF"},
+	},
+	&cli.Uint64Flag{
+		Name:        "db-max-pool-size",
+		Usage:       "Maximum number of connections to each MongoDB server, defaults to the url or 100",
+		Required:    false,
+		Destination: &dbMaxPoolSizeFlag,
+		EnvVars:     []string{"SPACEMESH_DB_MAX_POOL_SIZE"},
+	},
+	&cli.Uint64Flag{
+		Name:        "db-min-pool-size",
+		Usage:       "Number of connections to each MongoDB server kept open when idle, defaults to the url or 0",
+		Required:    false,
+		Destination: &dbMinPoolSizeFlag,
+		EnvVars:     []string{"SPACEMESH_DB_MIN_POOL_SIZE"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-connect-timeout",
+		Usage:       "Timeout of the establishment of a MongoDB connection, defaults to the url or 30s",
+		Required:    false,
+		Destination: &dbConnectTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_CONNECT_TIMEOUT"},
+	},
+	&cli.DurationFlag{
+		Name:        "db-server-selection-timeout",
+		Usage:       "Timeout of the selection of a MongoDB server available for an operation, defaults to the url or 30s",
+		Required:    false,
+		Destination: &dbServerSelectionTimeoutFlag,
+		EnvVars:     []string{"SPACEMESH_DB_SERVER_SELECTION_TIMEOUT"},
+	},
+	&cli.StringSliceFlag{
+		Name:        "db-compressors",
+		Usage:       "Compressors of the MongoDB messages in order of preference: snappy, zlib or zstd, defaults to the url or none",
+		Required:    false,
+		Destination: dbCompressorsFlag,
+		EnvVars:     []string{"SPACEMESH_DB_COMPRESSORS"},
+	},
+	&cli.BoolFlag{
+		Name:        "db-tls",
+		Usage:       "Connect to MongoDB with TLS, implied by the other --db-tls flags. The TLS flags replace the TLS settings of the url",
+		Required:    false,
+		Destination: &dbTLSFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS"},
+	},
+	&cli.StringFlag{
+		Name:        "db-tls-ca-file",
+		Usage:       "PEM bundle of the authorities verifying the MongoDB server certificate, defaults to the system ones",
+		Required:    false,
+		Destination: &dbTLSCAFileFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_CA_FILE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-tls-certificate-key-file",
+		Usage:       "PEM file holding the client certificate and its private key, for the MONGODB-X509 authentication",
+		Required:    false,
+		Destination: &dbTLSCertificateKeyFileFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_CERTIFICATE_KEY_FILE"},
+	},
+	&cli.BoolFlag{
+		Name:        "db-tls-insecure",
+		Usage:       "Skip the verification of the MongoDB server certificate, do not use in production",
+		Required:    false,
+		Destination: &dbTLSInsecureFlag,
+		EnvVars:     []string{"SPACEMESH_DB_TLS_INSECURE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-auth-mechanism",
+		Usage:       "MongoDB authentication mechanism: SCRAM-SHA-1, SCRAM-SHA-256, MONGODB-X509 or MONGODB-AWS, negotiated if empty. The auth flags replace the credentials of the url",
+		Required:    false,
+		Destination: &dbAuthMechanismFlag,
+		EnvVars:     []string{"SPACEMESH_DB_AUTH_MECHANISM"},
+	},
+	&cli.StringFlag{
+		Name:        "db-auth-source",
+		Usage:       "Database of the MongoDB user, defaults to admin or $external",
+		Required:    false,
+		Destination: &dbAuthSourceFlag,
+		EnvVars:     []string{"SPACEMESH_DB_AUTH_SOURCE"},
+	},
+	&cli.StringFlag{
+		Name:        "db-username",
+		Usage:       "MongoDB username, defaults to the subject of the client certificate with MONGODB-X509",
+		Required:    false,
+		Destination: &dbUsernameFlag,
+		EnvVars:     []string{"SPACEMESH_DB_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:        "db-password",
+		Usage:       "MongoDB password, prefer the environment variable to the flag",
+		Required:    false,
+		Destination: &dbPasswordFlag,
+		EnvVars:     []string{"SPACEMESH_DB_PASSWORD"},
+	},
+
+	&cli.StringFlag{
+		Name:        "log-level",
+		Usage:       "Level of the logs: debug, info, warn or error",
+		Value:       "info",
+		Destination: &logLevelFlag,
+		EnvVars:     []string{"SPACEMESH_LOG_LEVEL"},
+	},
+	&cli.StringFlag{
+		Name:        "log-format",
+		Usage:       "Format of the logs: console or json",
+		Value:       logging.FormatConsole,
+		Destination: &logFormatFlag,
+		EnvVars:     []string{"SPACEMESH_LOG_FORMAT"},
+	},
 }
 
-func main() {
+// newApp returns the collector command line, serve is the default command.
+func newApp() *cli.App {
 	app := cli.NewApp()
 	app.Name = "Spacemesh Explorer Collector"
 	app.Version = fmt.Sprintf("%s, commit '%s', branch '%s'", version, commit, branch)
-	app.Flags = flags
+	app.Flags = config.WithFile(flags)
+	app.Before = func(ctx *cli.Context) error {
+		if err := config.Load(app.Flags)(ctx); err != nil {
+			return err
+		}
+		return logging.Setup(logLevelFlag, logFormatFlag)
+	}
 	app.Writer = os.Stderr
+	app.Commands = []*cli.Command{
+		{
+			Name:   "serve",
+			Usage:  "Collect the data of the node into the database and keep it in sync, the default command",
+			Action: serve,
+		},
+		{
+			Name:  "backfill",
+			Usage: "Sync the missing layers up to the verified layer of the node, then exit",
+			Flags: []cli.Flag{
+				&cli.UintFlag{
+					Name:  "from",
+					Usage: "First layer synced, the layer following the last stored one by default",
+				},
+				&cli.BoolFlag{
+					Name:  "epoch-stats",
+					Usage: "Recalculate the stats of all the epochs once the layers are synced",
+				},
+			},
+			Action: backfill,
+		},
+		{
+			Name:   "prune",
+			Usage:  "Apply the --retention policies once and exit, the collector applies them every hour",
+			Action: prune,
+		},
+		{
+			Name:  "export",
+			Usage: "Export the explorer database",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "checkpoint",
+					Usage:     "Write accounts, smeshers, epoch stats and the sync position to a checkpoint archive",
+					ArgsUsage: "<file>",
+					Action:    exportCheckpoint,
+				},
+				{
+					Name:   "snapshot",
+					Usage:  "Write the collections to a snapshot directory, an interrupted export resumes on the next run",
+					Flags:  snapshotExportFlags(),
+					Action: exportSnapshot,
+				},
+			},
+		},
+		{
+			Name:      "export-checkpoint",
+			Usage:     "Write accounts, smeshers, epoch stats and the sync position to a checkpoint archive, see export checkpoint",
+			ArgsUsage: "<file>",
+			Hidden:    true,
+			Action:    exportCheckpoint,
+		},
+		{
+			Name:      "import-checkpoint",
+			Usage:     "Load a checkpoint archive into an empty database",
+			ArgsUsage: "<file>",
+			Action:    importCheckpoint,
+		},
+		{
+			Name:      "import-labels",
+			Usage:     `Store the names of accounts and smeshers searched by the API, from a JSON array of {"kind": "account" or "smesher", "id", "name"}`,
+			ArgsUsage: "<file>",
+			Action:    importLabels,
+		},
+		{
+			Name:      "import-locations",
+			Usage:     `Set the locations of smeshers shown by the map view, from a JSON array of {"smesher", "name", "coordinates": [longitude, latitude]}`,
+			ArgsUsage: "<file>",
+			Action:    importLocations,
+		},
+		{
+			Name:  "migrate",
+			Usage: "Apply pending MongoDB migrations",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "status",
+					Usage: "List applied and pending migrations and the index drift without applying them",
+				},
+			},
+			Action: migrate,
+		},
+		{
+			Name:    "verify",
+			Aliases: []string{"check"},
+			Usage:   "Validate the invariants between collections and print the repair plan as JSON",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "apply",
+					Usage: "Apply the repair plan",
+				},
+			},
+			Action: verify,
+		},
+		{
+			Name:  "epoch-stats",
+			Usage: "Compare and restore the versions of the epoch stats",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "diff",
+					Usage: "Print the changes of the epoch stats between two versions as JSON",
+					Flags: []cli.Flag{
+						&cli.UintFlag{
+							Name:     "from",
+							Usage:    "Version compared",
+							Required: true,
+						},
+						&cli.UintFlag{
+							Name:  "to",
+							Usage: "Version compared to",
+							Value: uint(model.EpochStatsVersion),
+						},
+					},
+					Action: diffEpochStats,
+				},
+				{
+					Name:  "rollback",
+					Usage: "Serve the epoch stats of a previous version, the collector must run this version",
+					Flags: []cli.Flag{
+						&cli.UintFlag{
+							Name:     "version",
+							Usage:    "Version restored",
+							Required: true,
+						},
+					},
+					Action: rollbackEpochStats,
+				},
+			},
+		},
+		{
+			Name:  "storage",
+			Usage: "Dump and restore the explorer database",
+			Subcommands: []*cli.Command{
+				{
+					Name:   "export",
+					Usage:  "Write the collections to a snapshot directory, an interrupted export resumes on the next run",
+					Flags:  snapshotExportFlags(),
+					Action: exportSnapshot,
+				},
+				{
+					Name:  "import",
+					Usage: "Load a snapshot directory, an interrupted import resumes on the next run",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "in",
+							Usage:    "Snapshot directory",
+							Required: true,
+						},
+					},
+					Action: importSnapshot,
+				},
+			},
+		},
+		{
+			Name:  "bench",
+			Usage: "Write a synthetic chain and print the ingest throughput and the query latencies as JSON, use a dedicated database",
+			Flags: []cli.Flag{
+				&cli.UintFlag{
+					Name:  "layers",
+					Usage: "Number of layers generated",
+					Value: uint(bench.DefaultConfig.Layers),
+				},
+				&cli.UintFlag{
+					Name:  "epoch-layers",
+					Usage: "Number of layers per epoch",
+					Value: uint(bench.DefaultConfig.EpochNumLayers),
+				},
+				&cli.IntFlag{
+					Name:  "blocks",
+					Usage: "Blocks per layer",
+					Value: bench.DefaultConfig.BlocksPerLayer,
+				},
+				&cli.IntFlag{
+					Name:  "txs",
+					Usage: "Transactions per block",
+					Value: bench.DefaultConfig.TxsPerBlock,
+				},
+				&cli.IntFlag{
+					Name:  "accounts",
+					Usage: "Number of accounts sending and receiving the transactions",
+					Value: bench.DefaultConfig.Accounts,
+				},
+				&cli.IntFlag{
+					Name:  "smeshers",
+					Usage: "Number of smeshers activated every epoch",
+					Value: bench.DefaultConfig.Smeshers,
+				},
+				&cli.IntFlag{
+					Name:  "runs",
+					Usage: "Runs of every measured query",
+					Value: bench.DefaultConfig.Runs,
+				},
+			},
+			Action: benchmark,
+		},
+	}
 
-	app.Action = func(ctx *cli.Context) error {
-		var pidFile *os.File
+	app.Action = serve
+	return app
+}
+
+func main() {
+	if err := newApp().Run(os.Args); err != nil {
+		logging.Error("collector failed", err)
+		os.Exit(1)
+	}
 
-		if testnetBoolFlag {
-			address.SetAddressConfig("stest")
-			types.SetNetworkHRP("stest")
-			log.Info(`Network HRP set to "stest"`)
+	os.Exit(0)
+}
+
+// openStorage opens the storage backend selected with --db-driver.
+func openStorage() (storage.StorageWriter, error) {
+	switch dbDriverStringFlag {
+	case "mongo":
+		mongoStorage, err := openMongoStorage()
+		if err != nil {
+			return nil, fmt.Errorf("open mongodb storage: %w", err)
 		}
+		if migrateBoolFlag {
+			if err := mongoStorage.Migrate(context.Background()); err != nil {
+				return nil, fmt.Errorf("migrate mongodb: %w", err)
+			}
+		} else {
+			mongoStorage.LogIndexDrift(context.Background())
+		}
+		return mongoStorage, nil
+	case "postgres":
+		pgStorage, err := postgres.New(context.Background(), postgresUrlStringFlag)
+		if err != nil {
+			return nil, fmt.Errorf("open postgresql storage: %w", err)
+		}
+		return pgStorage, nil
+	}
+	return nil, fmt.Errorf("unknown db driver `%s`", dbDriverStringFlag)
+}
+
+// openMongoStorage opens the MongoDB storage with the write and read concerns and the connection
+// settings of the flags.
+func openMongoStorage() (*storage.Storage, error) {
+	wc, err := storage.WriteConcern(writeConcernFlag, journalBoolFlag)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := storage.ReadConcern(readConcernFlag)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := mongoConnection().Options()
+	if err != nil {
+		return nil, err
+	}
+	mongoStorage, err := storage.New(context.Background(), mongoDbUrlStringFlag, mongoDbNameStringFlag, collectionPrefixStringFlag, wc, rc, conn)
+	if err != nil {
+		return nil, err
+	}
+	mongoStorage.SetCreateIndexes(createIndexesBoolFlag)
+	if err := mongoStorage.OpenStatsDatabase(context.Background(), statsMongoDbUrlStringFlag, statsMongoDbNameStringFlag, wc, rc, conn); err != nil {
+		mongoStorage.Close()
+		return nil, err
+	}
+	return mongoStorage, nil
+}
+
+// mongoConnection returns the MongoDB client settings of the flags.
+func mongoConnection() storage.Connection {
+	return storage.Connection{
+		MaxPoolSize:            dbMaxPoolSizeFlag,
+		MinPoolSize:            dbMinPoolSizeFlag,
+		ConnectTimeout:         dbConnectTimeoutFlag,
+		ServerSelectionTimeout: dbServerSelectionTimeoutFlag,
+		Compressors:            dbCompressorsFlag.Value(),
+		TLS:                    dbTLSFlag,
+		TLSCAFile:              dbTLSCAFileFlag,
+		TLSCertificateKeyFile:  dbTLSCertificateKeyFileFlag,
+		TLSInsecure:            dbTLSInsecureFlag,
+		AuthMechanism:          dbAuthMechanismFlag,
+		AuthSource:             dbAuthSourceFlag,
+		Username:               dbUsernameFlag,
+		Password:               dbPasswordFlag,
+	}
+}
 
-		mongoStorage, err := storage.New(context.Background(), mongoDbUrlStringFlag, mongoDbNameStringFlag)
+// openReader opens the reader of the API on the storage of the flags.
+func openReader() (storagereader.StorageReader, error) {
+	switch dbDriverStringFlag {
+	case "mongo":
+		rc, err := storage.ReadConcern(readConcernFlag)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := mongoConnection().Options()
+		if err != nil {
+			return nil, err
+		}
+		reader, err := storagereader.NewStorageReader(context.Background(), mongoDbUrlStringFlag, mongoDbNameStringFlag, collectionPrefixStringFlag, rc, conn)
 		if err != nil {
-			log.Info("MongoDB storage open error %v", err)
+			return nil, err
+		}
+		if err := reader.OpenStatsDatabase(context.Background(), statsMongoDbUrlStringFlag, statsMongoDbNameStringFlag, rc, conn); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case "postgres":
+		return postgres.NewReader(context.Background(), postgresUrlStringFlag)
+	}
+	return nil, fmt.Errorf("unknown db driver `%s`", dbDriverStringFlag)
+}
+
+func exportCheckpoint(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("checkpoint file is required")
+	}
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	file, err := os.Create(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifest, err := mongoStorage.ExportCheckpoint(ctx.Context, file)
+	if err != nil {
+		return err
+	}
+	logging.Info("checkpoint written", logging.Layer(manifest.LastLayer), zap.String("file", file.Name()))
+	return file.Sync()
+}
+
+func importCheckpoint(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("checkpoint file is required")
+	}
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+	if err := mongoStorage.Migrate(ctx.Context); err != nil {
+		return err
+	}
+
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifest, err := mongoStorage.ImportCheckpoint(ctx.Context, file)
+	if err != nil {
+		return err
+	}
+	logging.Info("checkpoint imported, the sync resumes from the next layer", logging.Layer(manifest.LastLayer))
+	return nil
+}
+
+func importLabels(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("labels file is required")
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var labels []*model.Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("error decode labels: %w", err)
+	}
+
+	dbStorage, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer dbStorage.Close()
+	if err := dbStorage.UpsertLabels(ctx.Context, labels); err != nil {
+		return err
+	}
+	logging.Info("labels imported", zap.Int("labels", len(labels)))
+	return nil
+}
+
+func importLocations(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("locations file is required")
+	}
+	data, err := os.ReadFile(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	var locations []*model.SmesherLocation
+	if err := json.Unmarshal(data, &locations); err != nil {
+		return fmt.Errorf("error decode locations: %w", err)
+	}
+
+	dbStorage, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer dbStorage.Close()
+	if err := dbStorage.UpsertSmesherLocations(ctx.Context, locations); err != nil {
+		return err
+	}
+	logging.Info("smesher locations imported", zap.Int("locations", len(locations)))
+	return nil
+}
+
+// snapshotExportFlags are the flags of export snapshot and of storage export.
+func snapshotExportFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "out",
+			Usage:    "Snapshot directory",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name:  "collections",
+			Usage: "Collections to export, all by default",
+		},
+	}
+}
+
+func exportSnapshot(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	manifest, err := mongoStorage.ExportSnapshot(ctx.Context, ctx.String("out"), ctx.StringSlice("collections"))
+	if err != nil {
+		return err
+	}
+	logging.Info("snapshot written", zap.Int("collections", len(manifest.Collections)), zap.String("dir", ctx.String("out")))
+	return nil
+}
+
+func importSnapshot(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+	if err := mongoStorage.Migrate(ctx.Context); err != nil {
+		return err
+	}
+
+	manifest, err := mongoStorage.ImportSnapshot(ctx.Context, ctx.String("in"))
+	if err != nil {
+		return err
+	}
+	logging.Info("snapshot imported", zap.Int("collections", len(manifest.Collections)), zap.String("dir", ctx.String("in")))
+	return nil
+}
+
+func verify(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	inconsistencies, err := mongoStorage.CheckConsistency(ctx.Context)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, i := range inconsistencies {
+		if err := enc.Encode(i); err != nil {
 			return err
 		}
+	}
+	logging.Info("inconsistencies found", zap.Int("inconsistencies", len(inconsistencies)))
+	if !ctx.Bool("apply") {
+		return nil
+	}
+	applied, err := mongoStorage.RepairConsistency(ctx.Context, inconsistencies)
+	if err != nil {
+		return err
+	}
+	logging.Info("repairs applied", zap.Int("applied", applied), zap.Int("left", len(inconsistencies)-applied))
+	return nil
+}
+
+func diffEpochStats(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	diffs, err := mongoStorage.DiffEpochStats(ctx.Context, uint32(ctx.Uint("from")), uint32(ctx.Uint("to")))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, diff := range diffs {
+		if err := enc.Encode(diff); err != nil {
+			return err
+		}
+	}
+	logging.Info("epochs differ", zap.Int("epochs", len(diffs)))
+	return nil
+}
+
+func rollbackEpochStats(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
 
-		db, err := sql.Setup(sqlitePathStringFlag)
+	epochs, err := mongoStorage.RollbackEpochStats(ctx.Context, uint32(ctx.Uint("version")))
+	if err != nil {
+		return err
+	}
+	logging.Info("epoch stats rolled back", zap.Int("epochs", epochs), zap.Uint("version", ctx.Uint("version")))
+	return nil
+}
+
+func migrate(ctx *cli.Context) error {
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	if ctx.Bool("status") {
+		applied, err := mongoStorage.AppliedMigrations(ctx.Context)
+		if err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Ints(versions)
+		for _, version := range versions {
+			fmt.Printf("%4d  applied %s  %s\n", version, time.Unix(applied[version].Applied, 0).UTC().Format(time.RFC3339), applied[version].Description)
+		}
+		pending, err := mongoStorage.PendingMigrations(ctx.Context)
 		if err != nil {
-			log.Info("SQLite storage open error %v", err)
 			return err
 		}
-		dbClient := &sql.Client{}
+		for _, migration := range pending {
+			fmt.Printf("%4d  pending %20s  %s\n", migration.Version, "", migration.Description)
+		}
+		drift, err := mongoStorage.IndexDrift(ctx.Context)
+		if err != nil {
+			return err
+		}
+		for _, d := range drift {
+			fmt.Printf("index drift: %s\n", d)
+		}
+		return nil
+	}
 
-		c := collector.NewCollector(nodePublicAddressStringFlag, nodePrivateAddressStringFlag,
-			syncMissingLayersBoolFlag, syncFromLayerFlag, recalculateEpochStatsBoolFlag, mongoStorage, db, dbClient, atxSyncFlag)
-		mongoStorage.AccountUpdater = c
+	if err := mongoStorage.Migrate(ctx.Context); err != nil {
+		return err
+	}
+	logging.Info("database is up to date")
+	return nil
+}
 
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+func benchmark(ctx *cli.Context) error {
+	dbStorage, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer dbStorage.Close()
+	dbReader, err := openReader()
+	if err != nil {
+		return fmt.Errorf("open storage reader: %w", err)
+	}
 
-		pidFile, err = os.OpenFile("/var/run/explorer-collector", os.O_RDWR|os.O_CREATE, 0644)
-		if err == nil {
-			_, err := pidFile.Write([]byte("started"))
-			if err != nil {
-				return err
-			}
-			err = pidFile.Close()
-			if err != nil {
-				return err
-			}
+	report, err := bench.Run(ctx.Context, dbStorage, dbReader, bench.Config{
+		Layers:         uint32(ctx.Uint("layers")),
+		EpochNumLayers: uint32(ctx.Uint("epoch-layers")),
+		BlocksPerLayer: ctx.Int("blocks"),
+		TxsPerBlock:    ctx.Int("txs"),
+		Accounts:       ctx.Int("accounts"),
+		Smeshers:       ctx.Int("smeshers"),
+		Runs:           ctx.Int("runs"),
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	for _, t := range report.Ingest {
+		logging.Info("bench", zap.String("entity", t.Entity), zap.Int("count", t.Count), zap.Duration("duration", t.Duration), zap.Float64("per_second", t.PerSecond))
+	}
+	return nil
+}
+
+// openCollectorStorage opens the storage written by the collector with the settings of the flags.
+// The returned function closes the cache.
+func openCollectorStorage() (storage.StorageWriter, func(), error) {
+	if testnetBoolFlag {
+		address.SetAddressConfig("stest")
+		types.SetNetworkHRP("stest")
+		logging.Info("network HRP set", zap.String("hrp", "stest"))
+	}
+
+	dbStorage, err := openStorage()
+	if err != nil {
+		return nil, nil, err
+	}
+	dbStorage.SetTimeouts(storage.Timeouts{
+		Query:     dbQueryTimeoutFlag,
+		Bulk:      dbBulkTimeoutFlag,
+		Aggregate: dbAggregateTimeoutFlag,
+	}.WithDefault(dbTimeoutFlag))
+	dbStorage.SetRetryPolicy(storage.RetryPolicy{
+		Attempts:   dbRetryAttemptsFlag,
+		MinBackoff: storage.DefaultRetryPolicy.MinBackoff,
+		MaxBackoff: dbRetryMaxBackoffFlag,
+	})
+	dbStorage.SetWatchedAccounts(watchAccountsFlag.Value())
+	dbStorage.SetArchive(archiveBoolFlag)
+	if tierAfterEpochsFlag > 0 {
+		mongoStorage, ok := dbStorage.(*storage.Storage)
+		if !ok {
+			return nil, nil, fmt.Errorf("tiering requires the mongo db driver")
+		}
+		mongoStorage.SetTiering(uint32(tierAfterEpochsFlag))
+	}
+	if accountSnapshotLayersFlag > 0 {
+		mongoStorage, ok := dbStorage.(*storage.Storage)
+		if !ok {
+			return nil, nil, fmt.Errorf("account snapshots require the mongo db driver")
+		}
+		mongoStorage.SetAccountSnapshots(uint32(accountSnapshotLayersFlag))
+	}
+	for _, sinkURL := range sinksFlag.Value() {
+		snk, err := sink.New(sinkURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open sink: %w", err)
+		}
+		dbStorage.AddSink(snk)
+	}
+	closeCache := func() {}
+	if redisURLFlag != "" {
+		redisCache, err := cache.New(redisURLFlag, 0)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open redis cache: %w", err)
+		}
+		closeCache = func() { redisCache.Close() }
+		dbStorage.SetCache(redisCache)
+	}
+	return dbStorage, closeCache, nil
+}
+
+// newCollector returns the collector reading the node and its sqlite database into dbStorage.
+func newCollector(dbStorage storage.StorageWriter) (*collector.Collector, error) {
+	db, err := sql.Setup(sqlitePathStringFlag)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite storage: %w", err)
+	}
+	sources, err := sql.SetupSources(sqliteSourcesFlag.Value())
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite sources: %w", err)
+	}
+	dbClient := &sql.Client{Sources: sources}
+
+	c := collector.NewCollector(nodePublicAddressStringFlag, nodePrivateAddressStringFlag,
+		syncMissingLayersBoolFlag, syncFromLayerFlag, recalculateEpochStatsBoolFlag, dbStorage, db, dbClient, atxSyncFlag)
+	c.SetGrpcConfig(collector.GrpcConfig{
+		CallTimeout:      grpcCallTimeoutFlag,
+		KeepaliveTime:    grpcKeepaliveTimeFlag,
+		KeepaliveTimeout: grpcKeepaliveTimeoutFlag,
+		MaxRecvMsgSize:   grpcMaxRecvMsgSizeFlag,
+	})
+	dbStorage.SetAccountUpdater(c)
+	return c, nil
+}
+
+// retentionPolicies parses the policies of --retention.
+func retentionPolicies() ([]storage.RetentionPolicy, error) {
+	var policies []storage.RetentionPolicy
+	for _, in := range retentionFlag.Value() {
+		policy, err := storage.ParseRetentionPolicy(in)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// serve runs the collector until it is stopped by SIGINT or SIGTERM, it is the default command.
+// The servers and the collector stop together, and the failure of one of them stops the process.
+func serve(cliCtx *cli.Context) error {
+	ctx, stop := signal.NotifyContext(cliCtx.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbStorage, closeCache, err := openCollectorStorage()
+	if err != nil {
+		return err
+	}
+	defer closeCache()
+	defer dbStorage.Close()
+	policies, err := retentionPolicies()
+	if err != nil {
+		return err
+	}
+	dbStorage.SetRetention(policies)
+	if mongoStorage, ok := dbStorage.(*storage.Storage); ok {
+		mongoStorage.SetRollups(rollupIntervalFlag)
+		mongoStorage.SetGeoHeatmap(geoHeatmapIntervalFlag)
+	}
+	var feed pricefeed.Feed
+	if priceFeedFlag != "" {
+		if priceIntervalFlag <= 0 {
+			return fmt.Errorf("invalid price interval %s", priceIntervalFlag)
+		}
+		if feed, err = pricefeed.New(priceFeedFlag); err != nil {
+			return fmt.Errorf("open price feed: %w", err)
 		}
+	}
 
-		go func() {
-			<-sigs
-			os.Remove("/var/run/explorer-collector")
-			os.Exit(0)
-		}()
+	c, err := newCollector(dbStorage)
+	if err != nil {
+		return err
+	}
 
-		go func() {
-			for {
-				if err := c.Run(); err != nil {
-					fmt.Println(err)
-					time.Sleep(5 * time.Second)
-				}
+	removePidFile, err := systemd.WritePidFile(pidFileFlag)
+	if err != nil {
+		// the default path is not writable by non-root deployments, only a set path is required
+		if cliCtx.IsSet("pid-file") {
+			return err
+		}
+		logging.Error("pid file disabled, set --pid-file to a writable path or to empty", err)
+		removePidFile = func() {}
+	}
+	defer removePidFile()
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		for {
+			err := c.Run(gctx)
+			if gctx.Err() != nil {
+				return nil
+			}
+			logging.Error("collector stopped, restarting", err)
+			select {
+			case <-gctx.Done():
+				return nil
+			case <-time.After(5 * time.Second):
 			}
-		}()
+		}
+	})
+	g.Go(func() error {
+		// expose metrics endpoint, on its own mux to keep the debug handlers off the port
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		return httpserver.Serve(gctx, "metrics", fmt.Sprintf(":%d", metricsPortFlag), mux, shutdownTimeoutFlag)
+	})
+	g.Go(func() error {
+		return debug.Serve(gctx, debugListenFlag, c.AdminHandlers(), shutdownTimeoutFlag)
+	})
+	g.Go(func() error {
+		return c.StartHttpServer(gctx, apiHostFlag, apiPortFlag, shutdownTimeoutFlag)
+	})
+	if feed != nil {
+		g.Go(func() error {
+			pricefeed.Record(gctx, feed, priceIntervalFlag, dbStorage.UpsertPrice)
+			return nil
+		})
+	}
+	g.Go(func() error {
+		systemd.Watchdog(gctx, func() bool {
+			return !c.Stalled(watchdogStallFlag)
+		})
+		return nil
+	})
+	systemd.Ready()
 
-		go func() {
-			// expose metrics endpoint
-			http.Handle("/metrics", promhttp.Handler())
-			http.ListenAndServe(fmt.Sprintf(":%d", metricsPortFlag), nil)
-		}()
+	err = g.Wait()
+	logging.Info("stopping the collector")
+	systemd.Stopping()
+	flushCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeoutFlag)
+	defer cancel()
+	if err := c.Flush(flushCtx); err != nil {
+		logging.Error("layers left in queue on shutdown", err, logging.Duration(shutdownTimeoutFlag))
+	}
+	return err
+}
 
-		go c.StartHttpServer(apiHostFlag, apiPortFlag)
+func backfill(ctx *cli.Context) error {
+	signalCtx, stop := signal.NotifyContext(ctx.Context, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx.Context = signalCtx
 
-		select {}
+	dbStorage, closeCache, err := openCollectorStorage()
+	if err != nil {
+		return err
 	}
+	defer closeCache()
+	defer dbStorage.Close()
 
-	if err := app.Run(os.Args); err != nil {
-		log.Info("%+v", err)
-		os.Exit(1)
+	c, err := newCollector(dbStorage)
+	if err != nil {
+		return err
 	}
+	var from *uint32
+	if ctx.IsSet("from") {
+		layer := uint32(ctx.Uint("from"))
+		from = &layer
+	}
+	if err := c.Backfill(ctx.Context, from, ctx.Bool("epoch-stats")); err != nil {
+		return err
+	}
+	logging.Info("backfill done")
+	return nil
+}
 
-	os.Exit(0)
+func prune(ctx *cli.Context) error {
+	policies, err := retentionPolicies()
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return fmt.Errorf("no retention policy, set them with --retention")
+	}
+	mongoStorage, err := openMongoStorage()
+	if err != nil {
+		return fmt.Errorf("open mongodb storage: %w", err)
+	}
+	defer mongoStorage.Close()
+
+	n, err := mongoStorage.Prune(ctx.Context, policies)
+	if err != nil {
+		return err
+	}
+	logging.Info("documents pruned", zap.Int64("documents", n))
+	return nil
 }
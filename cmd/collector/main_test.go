@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommands(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{"serve by default", []string{"--db-driver", "unknown"}, "unknown db driver `unknown`"},
+		{"serve", []string{"--db-driver", "unknown", "serve"}, "unknown db driver `unknown`"},
+		{"backfill", []string{"--db-driver", "unknown", "backfill", "--from", "10", "--epoch-stats"}, "unknown db driver `unknown`"},
+		{"prune without policies", []string{"prune"}, "no retention policy, set them with --retention"},
+		{"verify", []string{"--write-concern", "invalid", "verify"}, "open mongodb storage: invalid write concern `invalid`"},
+		{"check is verify", []string{"--write-concern", "invalid", "check", "--apply"}, "open mongodb storage: invalid write concern `invalid`"},
+		{"export checkpoint", []string{"export", "checkpoint"}, "checkpoint file is required"},
+		{"export-checkpoint is export checkpoint", []string{"export-checkpoint"}, "checkpoint file is required"},
+		{"export snapshot", []string{"--write-concern", "invalid", "export", "snapshot", "--out", t.TempDir()}, "open mongodb storage: invalid write concern `invalid`"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newApp().Run(append([]string{"collector"}, tc.args...))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestHiddenCommands(t *testing.T) {
+	app := newApp()
+	var visible []string
+	for _, cmd := range app.VisibleCommands() {
+		visible = append(visible, cmd.Name)
+	}
+	require.Subset(t, visible, []string{"serve", "backfill", "prune", "export", "verify"})
+	require.NotContains(t, visible, "export-checkpoint")
+}
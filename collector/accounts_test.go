@@ -0,0 +1,58 @@
+package collector_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+func TestUpdateAccount(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	s := openStorage(t, testAPIServiceDB+"_update_account")
+
+	// an unknown account is inserted at version 0 only
+	require.NoError(t, s.UpdateAccount(ctx, "sm1", 0, 10, 1))
+	require.ErrorIs(t, s.UpdateAccount(ctx, "sm1", 0, 20, 2), storage.ErrStaleAccount)
+	require.ErrorIs(t, s.UpdateAccount(ctx, "sm2", 3, 20, 2), storage.ErrStaleAccount)
+
+	require.NoError(t, s.UpdateAccount(ctx, "sm1", 1, 30, 3))
+	require.ErrorIs(t, s.UpdateAccount(ctx, "sm1", 1, 40, 4), storage.ErrStaleAccount)
+
+	account, err := s.GetAccount(ctx, &bson.D{{Key: "address", Value: "sm1"}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), account.Balance)
+	require.Equal(t, uint64(3), account.Counter)
+	require.Equal(t, uint64(2), account.Version)
+	require.Equal(t, int64(1), s.GetAccountsCount(ctx, &bson.D{}))
+}
+
+func TestGenesisAccountCreated(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	name := testAPIServiceDB + "_genesis_account"
+	s := openStorage(t, name)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(ctx) })
+	address := types.GenerateAddress([]byte{1})
+
+	// the genesis account keeps the genesis layer once updated by a later snapshot or transaction
+	s.OnAccounts([]*types.Account{{Address: address, Balance: 1000}})
+	s.OnAccounts([]*types.Account{{Layer: 12, Address: address, Balance: 700, NextNonce: 1}})
+	_, err = client.Database(name).Collection("accounts").BulkWrite(ctx, []mongo.WriteModel{s.UpsertAccountQuery(15, address.String(), 700)})
+	require.NoError(t, err)
+
+	account, err := s.GetAccount(ctx, &bson.D{{Key: "address", Value: address.String()}})
+	require.NoError(t, err)
+	require.Equal(t, uint64(700), account.Balance)
+	require.Equal(t, uint64(0), account.Created)
+	require.Equal(t, int64(1), s.GetAccountsCount(ctx, &bson.D{{Key: "created", Value: 0}}))
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	sql2 "github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/collector/sql"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+)
+
+// activeSetClient returns the active sets and the activations of the node, the other methods of
+// DatabaseClient are not implemented.
+type activeSetClient struct {
+	sql.DatabaseClient
+	activeSets map[types.EpochID]int
+	atxs       map[int64]int
+}
+
+func (c *activeSetClient) GetEpochActiveSetSize(_ *sql2.Database, epoch types.EpochID) (int, error) {
+	return c.activeSets[epoch], nil
+}
+
+func (c *activeSetClient) CountAtxsByEpoch(_ *sql2.Database, epoch int64) (int, error) {
+	return c.atxs[epoch], nil
+}
+
+func TestSyncActiveSet(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	client := &activeSetClient{
+		activeSets: map[types.EpochID]int{2: 5},
+		atxs:       map[int64]int{1: 9, 2: 4},
+	}
+	c := NewCollector("", "", false, 0, false, s, nil, client, false)
+
+	for _, epoch := range []types.EpochID{2, 3} {
+		// without active set, the activations targeting the epoch are counted
+		size, ok := c.fetchActiveSet(epoch)
+		require.True(t, ok)
+		s.OnActiveSet(epoch.Uint32(), size)
+	}
+
+	reader := memory.NewReader(s)
+	epoch, err := reader.GetEpoch(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, uint32(5), epoch.ActiveSetSize)
+	epoch, err = reader.GetEpoch(context.Background(), 3)
+	require.NoError(t, err)
+	require.Equal(t, uint32(4), epoch.ActiveSetSize)
+}
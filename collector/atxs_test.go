@@ -2,7 +2,7 @@ package collector_test
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -14,19 +14,42 @@ import (
 
 func TestAtxs(t *testing.T) {
 	t.Parallel()
-	atxs, err := storageDB.GetActivations(context.TODO(), &bson.D{})
+	atxs, err := storageReader.GetActivations(context.TODO(), &bson.D{})
 	require.NoError(t, err)
 	require.Equal(t, len(generator.Activations), len(atxs))
 	for _, atx := range atxs {
-		// temporary hack until storage return data as slice of bson.B, not an struct.
-		atxEncoded, err := json.Marshal(atx.Map())
-		require.NoError(t, err)
-		var tmpAtx model.Activation
-		require.NoError(t, json.Unmarshal(atxEncoded, &tmpAtx))
-		atxGen, ok := generator.Activations[tmpAtx.Id]
+		atxGen, ok := generator.Activations[atx.Id]
 		require.True(t, ok)
-		tmpAtx.Coinbase = strings.ToLower(tmpAtx.Coinbase)
+		atx.Coinbase = strings.ToLower(atx.Coinbase)
 		atxGen.Coinbase = strings.ToLower(atxGen.Coinbase)
-		require.Equal(t, *atxGen, tmpAtx)
+		require.Equal(t, *atxGen, *atx)
 	}
 }
+
+func TestOnActivationsBatches(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	s := openStorage(t, testAPIServiceDB+"_activations")
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+
+	// more activations than a single batch, each with its own smesher and coinbase
+	atxs := make([]*model.Activation, 0, 2500)
+	for i := 0; i < cap(atxs); i++ {
+		atxs = append(atxs, &model.Activation{
+			Id:           fmt.Sprintf("0xa%d", i),
+			SmesherId:    fmt.Sprintf("0x5%d", i),
+			Coinbase:     fmt.Sprintf("sm%d", i),
+			NumUnits:     1,
+			PublishEpoch: 1,
+			TargetEpoch:  2,
+			Received:     int64(i),
+		})
+	}
+	s.OnActivations(atxs)
+
+	require.Equal(t, int64(len(atxs)), s.GetActivationsCount(ctx, &bson.D{}))
+	smeshers, err := s.GetSmeshersCount(ctx, &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(atxs)), smeshers)
+	require.Equal(t, int64(len(atxs)), s.GetAccountsCount(ctx, &bson.D{}))
+}
@@ -0,0 +1,51 @@
+package collector_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/collector"
+	"github.com/spacemeshos/explorer-backend/test/testseed"
+)
+
+func TestBackfill(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	s := openStorage(t, testAPIServiceDB+"_backfill")
+	c := collector.NewCollector(fmt.Sprintf("localhost:%d", node.NodePort),
+		fmt.Sprintf("localhost:%d", privateNode.NodePort), false,
+		0, false, s, sql.InMemory(), &testseed.Client{SeedGen: generator}, false)
+	s.AccountUpdater = c
+
+	from := uint32(10)
+	require.NoError(t, c.Backfill(ctx, &from, false))
+	last, err := s.GetLastLayer(ctx)
+	require.NoError(t, err)
+	require.Greater(t, last, from)
+	expected := 0
+	for number := range generator.Layers {
+		if number >= from && number <= last {
+			expected++
+		}
+	}
+	layers, err := s.GetLayersCount(ctx, &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(expected), layers)
+	layers, err = s.GetLayersCount(ctx, &bson.D{{Key: "number", Value: bson.D{{Key: "$lt", Value: from}}}})
+	require.NoError(t, err)
+	require.Zero(t, layers)
+
+	// without a first layer the backfill resumes after the last stored layer
+	require.NoError(t, c.Backfill(ctx, nil, false))
+	resumed, err := s.GetLastLayer(ctx)
+	require.NoError(t, err)
+	require.Equal(t, last, resumed)
+	layers, err = s.GetLayersCount(ctx, &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(expected), layers)
+}
@@ -2,27 +2,29 @@ package collector_test
 
 import (
 	"context"
-	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
-
-	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestBlocks(t *testing.T) {
 	t.Parallel()
-	blocks, err := storageDB.GetBlocks(context.TODO(), &bson.D{})
+	blocks, err := storageReader.GetBlocks(context.TODO(), &bson.D{})
 	require.NoError(t, err)
 	for _, block := range blocks {
-		// temporary hack, until storage return data as slice of bson.B not an struct.
-		blockEncoded, err := json.Marshal(block.Map())
-		require.NoError(t, err)
-		var tmpBlock model.Block
-		require.NoError(t, json.Unmarshal(blockEncoded, &tmpBlock))
-		generated := generator.Blocks[tmpBlock.Id]
+		generated := generator.Blocks[block.Id]
 		require.NotNil(t, generated)
-		require.Equal(t, *generated, tmpBlock)
+		require.Equal(t, *generated, *block)
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	t.Parallel()
+	require.NotEmpty(t, generator.Certificates)
+	for blockID, generated := range generator.Certificates {
+		cert, err := storageReader.GetBlockCertificate(context.TODO(), blockID)
+		require.NoError(t, err)
+		require.Equal(t, generated, cert)
 	}
 }
@@ -0,0 +1,42 @@
+package collector_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	imported := openStorage(t, testAPIServiceDB+"_checkpoint")
+	var archive bytes.Buffer
+	manifest, err := storageDB.(*storage.Storage).ExportCheckpoint(ctx, &archive)
+	require.NoError(t, err)
+	last, err := storageDB.GetLastLayer(ctx)
+	require.NoError(t, err)
+	require.Equal(t, last, manifest.LastLayer)
+	require.Equal(t, int64(1), manifest.Collections["layers"])
+
+	read, err := imported.ImportCheckpoint(ctx, bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, manifest.LastLayer, read.LastLayer)
+
+	// the sync resumes from the last layer of the checkpoint
+	last, err = imported.GetLastLayer(ctx)
+	require.NoError(t, err)
+	require.Equal(t, manifest.LastLayer, last)
+	require.Equal(t, manifest.Collections["accounts"], imported.GetAccountsCount(ctx, &bson.D{}))
+	smeshers, err := imported.GetSmeshersCount(ctx, &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, manifest.Collections["smeshers"], smeshers)
+
+	// a checkpoint is never mixed with existing state
+	_, err = imported.ImportCheckpoint(ctx, bytes.NewReader(archive.Bytes()))
+	require.ErrorContains(t, err, "database is not empty")
+}
@@ -10,15 +10,13 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/sync/errgroup"
-	"google.golang.org/grpc/keepalive"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/grpc/credentials/insecure"
-
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"google.golang.org/grpc"
+	"go.uber.org/zap"
 
-	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
 const (
@@ -34,11 +32,19 @@ type Listener interface {
 	OnNetworkInfo(genesisId string, genesisTime uint64, epochNumLayers uint32, maxTransactionsPerSecond uint64, layerDuration uint64, postUnitSize uint64)
 	OnNodeStatus(connectedPeers uint64, isSynced bool, syncedLayer uint32, topLayer uint32, verifiedLayer uint32)
 	OnLayer(layer *pb.Layer)
+	OnTransactionsReceived(received map[string]uint32)
 	OnAccounts(accounts []*types.Account)
 	OnReward(reward *pb.Reward)
+	OnRewards(rewards []*pb.Reward)
+	OnCertificates(certs []*model.BlockCertificate)
+	OnBallots(ballots []*model.Ballot)
+	OnActiveSet(epoch uint32, size uint32)
+	OnBeacon(epoch uint32, beacon string)
 	OnMalfeasanceProof(proof *pb.MalfeasanceProof)
 	OnTransactionResult(res *pb.TransactionResult, state *pb.TransactionState)
-	GetLastLayer(parent context.Context) uint32
+	GetLastLayer(parent context.Context) (uint32, error)
+	GetLayersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
+	GetLayerByNumber(parent context.Context, layerNumber uint32) (*model.Layer, error)
 	LayersInQueue() int
 	IsLayerInQueue(layer *pb.Layer) bool
 	GetEpochNumLayers() uint32
@@ -47,8 +53,11 @@ type Listener interface {
 	UpdateEpochStats(layer uint32)
 	OnActivation(atx *types.VerifiedActivationTx)
 	GetLastActivationReceived() int64
+	GetLayerHashCheckpoint(parent context.Context) (uint32, error)
+	SetLayerHashCheckpoint(parent context.Context, layer uint32) error
 	RecalculateEpochStats()
 	OnActivations(atxs []*model.Activation)
+	RedecodeTransactions(parent context.Context) error
 }
 
 type Collector struct {
@@ -69,6 +78,7 @@ type Collector struct {
 	transactionsClient pb.TransactionServiceClient
 	debugClient        pb.DebugServiceClient
 	smesherClient      pb.SmesherServiceClient
+	grpcConfig         GrpcConfig
 
 	streams       [streamType_count]bool
 	activeStreams int
@@ -78,12 +88,37 @@ type Collector struct {
 
 	// Stream status changed.
 	notify chan int
+
+	progress *syncProgress
+
+	// Layer and activation sync run as independent workers, each with its own checkpoint.
+	layerWorker *worker
+	layerTarget atomic.Uint32
+	nextLayer   uint32
+	// layerCheckpointSet is false until nextLayer is loaded from storage, as 0 is a valid layer.
+	layerCheckpointSet bool
+	layerAttempts      int
+	// skippedLayers are the layers skipped after layerSyncMaxAttempts attempts, retried by the
+	// next passes of the layer worker.
+	skippedLayers []uint32
+	atxWorker     *worker
+	atxCheckpoint int64
+
+	// stages are the decode and store stages of the layers, see ingestLayer.
+	stages *layerStages
+
+	verifyWorker  *worker
+	verifiedLayer uint32
+	// verifyCheckpointSet is false until verifiedLayer is loaded from storage.
+	verifyCheckpointSet bool
+	reingestedLayer     uint32
+	reingestCount       int
 }
 
 func NewCollector(nodePublicAddress string, nodePrivateAddress string, syncMissingLayersFlag bool,
 	syncFromLayerFlag int, recalculateEpochStatsFlag bool,
 	listener Listener, db *sql2.Database, dbClient sql.DatabaseClient, atxSyncFlag bool) *Collector {
-	return &Collector{
+	c := &Collector{
 		apiPublicUrl:              nodePublicAddress,
 		apiPrivateUrl:             nodePrivateAddress,
 		syncMissingLayersFlag:     syncMissingLayersFlag,
@@ -94,35 +129,33 @@ func NewCollector(nodePublicAddress string, nodePrivateAddress string, syncMissi
 		db:                        db,
 		dbClient:                  dbClient,
 		atxSyncFlag:               atxSyncFlag,
+		progress:                  &syncProgress{started: time.Now()},
+		grpcConfig:                DefaultGrpcConfig(),
+		stages:                    newLayerStages(),
 	}
+	c.layerWorker = newWorker("layers", layerSyncInterval, c.syncLayersToTarget)
+	c.atxWorker = newWorker("activations", atxSyncInterval, c.syncActivations)
+	c.verifyWorker = newWorker("layers verification", layerVerifyInterval, c.verifyLayers)
+	return c
 }
 
-func (c *Collector) Run() error {
-	log.Info("dial node %v and %v", c.apiPublicUrl, c.apiPrivateUrl)
-	c.connecting = true
-
-	//TODO: move to env
-	keepaliveOpts := keepalive.ClientParameters{
-		Time:                4 * time.Minute,
-		Timeout:             2 * time.Minute,
-		PermitWithoutStream: true,
-	}
-
-	publicConn, err := grpc.Dial(c.apiPublicUrl, grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithKeepaliveParams(keepaliveOpts),
-		grpc.WithBlock(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(50*1024*1024)))
+// connect dials the node and reads the network info, the returned function closes the connections.
+func (c *Collector) connect() (func(), error) {
+	logging.Info("dial node", zap.String("public", c.apiPublicUrl), zap.String("private", c.apiPrivateUrl))
+	publicConn, err := c.dial(c.apiPublicUrl)
 	if err != nil {
-		return errors.Join(errors.New("cannot dial node"), err)
+		return nil, errors.Join(errors.New("cannot dial node"), err)
 	}
-	defer publicConn.Close()
 
-	privateConn, err := grpc.Dial(c.apiPrivateUrl, grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithKeepaliveParams(keepaliveOpts),
-		grpc.WithBlock(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(50*1024*1024)))
+	privateConn, err := c.dial(c.apiPrivateUrl)
 	if err != nil {
-		return errors.Join(errors.New("cannot dial node"), err)
+		publicConn.Close()
+		return nil, errors.Join(errors.New("cannot dial node"), err)
+	}
+	closeConns := func() {
+		publicConn.Close()
+		privateConn.Close()
 	}
-	defer privateConn.Close()
 
 	c.nodeClient = pb.NewNodeServiceClient(publicConn)
 	c.meshClient = pb.NewMeshServiceClient(publicConn)
@@ -133,18 +166,38 @@ func (c *Collector) Run() error {
 
 	err = c.getNetworkInfo()
 	if err != nil {
-		return errors.Join(errors.New("cannot get network info"), err)
+		closeConns()
+		return nil, errors.Join(errors.New("cannot get network info"), err)
 	}
+	return closeConns, nil
+}
 
-	if c.atxSyncFlag {
-		err = c.syncActivations()
-		if err != nil {
-			return errors.Join(errors.New("cannot sync activations"), err)
-		}
+// Run syncs the node into the storage until ctx is done or a stream of the node fails.
+func (c *Collector) Run(ctx context.Context) error {
+	c.connecting = true
+	closeConns, err := c.connect()
+	if err != nil {
+		return err
 	}
+	defer closeConns()
+
+	// workers stop together with the node status stream which feeds them
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	// the streams stop together, so that Run returns and is restarted when one of them fails
+	streamsCtx, stopStreams := context.WithCancel(ctx)
+	defer stopStreams()
+	// the stages stop once Run returns, after the workers feeding them, and drain their queues
+	stagesCtx, stopStages := context.WithCancel(context.WithoutCancel(ctx))
+	defer c.startStages(stagesCtx)()
+	defer stopStages()
 
 	if c.syncMissingLayersFlag {
-		err = c.syncMissingLayers()
+		next, err := c.nextLayerToSync()
+		if err != nil {
+			return errors.Join(errors.New("cannot get the next layer to sync"), err)
+		}
+		err = c.syncMissingLayers(ctx, next)
 		if err != nil {
 			return errors.Join(errors.New("cannot sync missing layers"), err)
 		}
@@ -154,9 +207,26 @@ func (c *Collector) Run() error {
 		c.listener.RecalculateEpochStats()
 	}
 
+	// the workers start once nothing returns before g.Wait, so that a restarted Run does not leak them
 	g := new(errgroup.Group)
+	if c.atxSyncFlag {
+		g.Go(func() error {
+			return c.atxWorker.start(workersCtx)
+		})
+	}
+
+	g.Go(func() error {
+		return c.layerWorker.start(workersCtx)
+	})
+
 	g.Go(func() error {
-		err := c.syncStatusPump()
+		return c.verifyWorker.start(workersCtx)
+	})
+
+	g.Go(func() error {
+		defer stopWorkers()
+		defer stopStreams()
+		err := c.syncStatusPump(streamsCtx)
 		if err != nil {
 			return errors.Join(errors.New("cannot start sync status pump"), err)
 		}
@@ -164,7 +234,8 @@ func (c *Collector) Run() error {
 	})
 
 	g.Go(func() error {
-		err := c.transactionsPump()
+		defer stopStreams()
+		err := c.transactionsPump(streamsCtx)
 		if err != nil {
 			return errors.Join(errors.New("cannot start transactions pump"), err)
 		}
@@ -172,7 +243,8 @@ func (c *Collector) Run() error {
 	})
 
 	g.Go(func() error {
-		err := c.malfeasancePump()
+		defer stopStreams()
+		err := c.malfeasancePump(streamsCtx)
 		if err != nil {
 			return errors.Join(errors.New("cannot start sync malfeasance pump"), err)
 		}
@@ -180,29 +252,31 @@ func (c *Collector) Run() error {
 	})
 
 	g.Go(func() error {
-		for c.connecting || c.closing || c.online {
+		// every pump notifies its start and its stop
+		for stopped := 0; stopped < streamType_count; {
 			state := <-c.notify
-			log.Info("stream notify %v", state)
+			logging.Info("stream notify", zap.Int("state", state))
 			switch {
 			case state > 0:
 				c.streams[state-1] = true
 				c.activeStreams++
-				log.Info("stream connected %v", state)
+				logging.Info("stream connected", zap.Int("stream", state))
 			case state < 0:
 				c.streams[(-state)-1] = false
 				c.activeStreams--
+				stopped++
 				if c.activeStreams == 0 {
 					c.closing = false
 				}
-				log.Info("stream disconnected %v", state)
+				logging.Info("stream disconnected", zap.Int("stream", -state))
 			}
 			if c.activeStreams == streamType_count {
 				c.connecting = false
 				c.online = true
-				log.Info("all streams synchronized!")
+				logging.Info("all streams synchronized")
 			}
 			if c.online && c.activeStreams < streamType_count {
-				log.Info("streams desynchronized!!!")
+				logging.Warn("streams desynchronized")
 				c.online = false
 				c.closing = true
 			}
@@ -214,5 +288,39 @@ func (c *Collector) Run() error {
 		return err
 	}
 
+	return ctx.Err()
+}
+
+// Flush waits for the layers received from the node to be stored, until ctx is done.
+func (c *Collector) Flush(ctx context.Context) error {
+	return c.waitLayersQueue(ctx, 100*time.Millisecond)
+}
+
+// Backfill syncs the layers from the given one, or from the layer following the last stored one if
+// from is nil, up to the verified layer of the node and waits for them to be stored. It then
+// recalculates the epoch stats if asked and returns, the streams and the workers of Run are not
+// started.
+func (c *Collector) Backfill(ctx context.Context, from *uint32, recalculateEpochStats bool) error {
+	closeConns, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer closeConns()
+	stagesCtx, stopStages := context.WithCancel(context.WithoutCancel(ctx))
+	defer c.startStages(stagesCtx)()
+	defer stopStages()
+
+	var next uint32
+	if from != nil {
+		next = *from
+	} else if next, err = c.nextLayerToSync(); err != nil {
+		return errors.Join(errors.New("cannot get the next layer to sync"), err)
+	}
+	if err := c.syncMissingLayers(ctx, next); err != nil {
+		return errors.Join(errors.New("cannot sync missing layers"), err)
+	}
+	if recalculateEpochStats {
+		c.listener.RecalculateEpochStats()
+	}
 	return nil
 }
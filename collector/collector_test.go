@@ -3,17 +3,21 @@ package collector_test
 import (
 	"context"
 	"fmt"
-	"github.com/spacemeshos/explorer-backend/collector"
-	"github.com/spacemeshos/go-spacemesh/sql"
 	"os"
 	"testing"
 	"time"
 
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/collector"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
 	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
 	"github.com/spacemeshos/explorer-backend/test/testseed"
 	"github.com/spacemeshos/explorer-backend/test/testserver"
 )
@@ -21,33 +25,28 @@ import (
 const testAPIServiceDB = "explorer_test"
 
 var (
-	dbPort       = 27017
-	generator    *testseed.SeedGenerator
-	node         *testserver.FakeNode
-	collectorApp *collector.Collector
-	storageDB    *storage.Storage
+	// mongoURL is EXPLORER_TEST_MONGO_URL, the suite runs on the memory storage without it and the
+	// tests of the mongo features are skipped.
+	mongoURL      = os.Getenv("EXPLORER_TEST_MONGO_URL")
+	generator     *testseed.SeedGenerator
+	node          *testserver.FakeNode
+	privateNode   *testserver.FakePrivateNode
+	collectorApp  *collector.Collector
+	storageDB     storage.StorageWriter
+	storageReader storagereader.StorageReader
 )
 
 func TestMain(m *testing.M) {
-	mongoURL := fmt.Sprintf("mongodb://localhost:%d", dbPort)
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(mongoURL))
-	if err != nil {
-		fmt.Println("failed to connect to mongo", err)
-		os.Exit(1)
-	}
-
-	database := client.Database(testAPIServiceDB)
-	if database != nil {
-		if err = database.Drop(context.TODO()); err != nil {
-			fmt.Println("failed to drop db", err)
+	if mongoURL == "" {
+		s := memory.New()
+		storageDB, storageReader = s, memory.NewReader(s)
+	} else {
+		s, reader, err := openMongo(context.TODO(), testAPIServiceDB)
+		if err != nil {
+			fmt.Println("failed to init storage to mongo", err)
 			os.Exit(1)
 		}
-	}
-
-	storageDB, err = storage.New(context.TODO(), mongoURL, testAPIServiceDB)
-	if err != nil {
-		fmt.Println("failed to init storage to mongo", err)
-		os.Exit(1)
+		storageDB, storageReader = s, reader
 	}
 
 	sqlDb, err := sql.Open("file:test.db?cache=shared&mode=memory", sql.WithConnections(16), sql.WithMigrations(nil))
@@ -73,7 +72,7 @@ func TestMain(m *testing.M) {
 		}
 	}()
 
-	privateNode, err := testserver.CreateFakeSMPrivateNode(generator.FirstLayerTime, generator, seed)
+	privateNode, err = testserver.CreateFakeSMPrivateNode(generator.FirstLayerTime, generator, seed)
 	if err != nil {
 		fmt.Println("failed to generate fake private node", err)
 		os.Exit(1)
@@ -88,16 +87,28 @@ func TestMain(m *testing.M) {
 
 	collectorApp = collector.NewCollector(fmt.Sprintf("localhost:%d", node.NodePort),
 		fmt.Sprintf("localhost:%d", privateNode.NodePort), false,
-		0, false, storageDB, sqlDb, dbClient, true)
-	storageDB.AccountUpdater = collectorApp
+		int(seed.EpochNumLayers), false, storageDB, sqlDb, dbClient, true)
+	storageDB.SetAccountUpdater(collectorApp)
 	defer storageDB.Close()
-	go collectorApp.Run()
+	go collectorApp.Run(context.Background())
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 	for range ticker.C {
-		num := storageDB.GetRewardsCount(context.TODO(), &bson.D{})
-		if int(num) == len(generator.Rewards) {
+		num, err := storageReader.CountRewards(context.TODO(), &bson.D{})
+		if err != nil || int(num) != len(generator.Rewards) {
+			continue
+		}
+		// the results of the transactions are streamed apart from the layers
+		processed, err := storageReader.CountTransactions(context.TODO(), &bson.D{
+			{Key: "state", Value: int(pb.TransactionState_TRANSACTION_STATE_PROCESSED)},
+		})
+		if err != nil || int(processed) != len(generator.Transactions) {
+			continue
+		}
+		// the last layers may still be in the ingestion stages
+		layers, err := storageReader.CountLayers(context.TODO(), &bson.D{})
+		if err == nil && int(layers) == len(generator.Layers) && collectorApp.LayersInQueue() == 0 {
 			break
 		}
 	}
@@ -105,3 +116,41 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 	os.Exit(code)
 }
+
+// openMongo returns a migrated storage on the empty mongo database name and its reader.
+func openMongo(ctx context.Context, name string) (*storage.Storage, *storagereader.Reader, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Disconnect(ctx)
+	if err = client.Database(name).Drop(ctx); err != nil {
+		return nil, nil, err
+	}
+	s, err := storage.New(ctx, mongoURL, name, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = s.Migrate(ctx); err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+	reader, err := storagereader.NewStorageReader(ctx, mongoURL, name, "")
+	if err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+	return s, reader, nil
+}
+
+// openStorage returns a storage on the empty mongo database name, closed when the test ends. The
+// test is skipped without mongo.
+func openStorage(t *testing.T, name string) *storage.Storage {
+	if mongoURL == "" {
+		t.Skip("EXPLORER_TEST_MONGO_URL is not set")
+	}
+	s, _, err := openMongo(context.TODO(), name)
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	return s
+}
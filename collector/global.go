@@ -1,25 +1,22 @@
 package collector
 
 import (
-	"context"
 	"errors"
-	"fmt"
-	"time"
 
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"github.com/spacemeshos/go-spacemesh/log"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
 func (c *Collector) GetAccountState(address string) (uint64, uint64, error) {
 	req := &pb.AccountRequest{AccountId: &pb.AccountId{Address: address}}
 
-	// set timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := c.callContext()
 	defer cancel()
 
 	res, err := c.globalClient.Account(ctx, req)
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get account info: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get account info", err)
 		return 0, 0, err
 	}
 
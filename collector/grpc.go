@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcConfig configures the node gRPC clients.
+type GrpcConfig struct {
+	// CallTimeout is the deadline of unary calls. Streams have no deadline.
+	CallTimeout time.Duration
+	// KeepaliveTime is the period of keepalive pings on idle connections.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is the time to wait for a keepalive ack before closing the connection.
+	KeepaliveTimeout time.Duration
+	// MaxRecvMsgSize is the maximum size in bytes of a message received from the node.
+	MaxRecvMsgSize int
+}
+
+func DefaultGrpcConfig() GrpcConfig {
+	return GrpcConfig{
+		CallTimeout:      5 * time.Second,
+		KeepaliveTime:    4 * time.Minute,
+		KeepaliveTimeout: 2 * time.Minute,
+		MaxRecvMsgSize:   50 * 1024 * 1024,
+	}
+}
+
+// SetGrpcConfig replaces the gRPC configuration. It takes effect on the next connection.
+func (c *Collector) SetGrpcConfig(cfg GrpcConfig) {
+	c.grpcConfig = cfg
+}
+
+func (c *Collector) dial(address string) (*grpc.ClientConn, error) {
+	return grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                c.grpcConfig.KeepaliveTime,
+			Timeout:             c.grpcConfig.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithBlock(), grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(c.grpcConfig.MaxRecvMsgSize)))
+}
+
+// callContext returns a context bounded by the configured call deadline.
+func (c *Collector) callContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.grpcConfig.CallTimeout)
+}
+
+// grpcError adds a hint to errors caused by the gRPC configuration, which are otherwise hard to diagnose.
+func (c *Collector) grpcError(err error) error {
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return errors.Join(fmt.Errorf("message larger than the %d bytes limit, increase --grpc-max-recv-msg-size", c.grpcConfig.MaxRecvMsgSize), err)
+	case codes.DeadlineExceeded:
+		return errors.Join(fmt.Errorf("call exceeded the %v deadline, increase --grpc-call-timeout", c.grpcConfig.CallTimeout), err)
+	}
+	return err
+}
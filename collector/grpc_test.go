@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// globalState serves the accounts, the address `slow` answers after a second and the address
+// `large` answers with a 1MB address.
+type globalState struct {
+	pb.UnimplementedGlobalStateServiceServer
+}
+
+func (globalState) Account(_ context.Context, req *pb.AccountRequest) (*pb.AccountResponse, error) {
+	address := req.GetAccountId().GetAddress()
+	switch address {
+	case "slow":
+		time.Sleep(time.Second)
+	case "large":
+		address = strings.Repeat("a", 1024*1024)
+	}
+	return &pb.AccountResponse{AccountWrapper: &pb.Account{
+		AccountId:    &pb.AccountId{Address: address},
+		StateCurrent: &pb.AccountState{Counter: 1, Balance: &pb.Amount{Value: 100}},
+	}}, nil
+}
+
+func TestGrpcConfig(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	pb.RegisterGlobalStateServiceServer(server, globalState{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	c := &Collector{}
+	cfg := DefaultGrpcConfig()
+	cfg.CallTimeout = 100 * time.Millisecond
+	cfg.MaxRecvMsgSize = 64 * 1024
+	c.SetGrpcConfig(cfg)
+	conn, err := c.dial(lis.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	c.globalClient = pb.NewGlobalStateServiceClient(conn)
+
+	balance, counter, err := c.GetAccountState("sm1")
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), balance)
+	require.Equal(t, uint64(1), counter)
+
+	// the errors caused by the configuration tell which flag to change
+	_, _, err = c.GetAccountState("large")
+	require.ErrorContains(t, err, "--grpc-max-recv-msg-size")
+	_, _, err = c.GetAccountState("slow")
+	require.ErrorContains(t, err, "--grpc-call-timeout")
+}
@@ -1,27 +1,38 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/explorer-backend/internal/httpserver"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/go-spacemesh/common/types"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"net/http"
 	"strconv"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
+// StartHttpServer serves the sync endpoints on apiHost:apiPort until ctx is done, see
+// httpserver.Serve.
+func (c *Collector) StartHttpServer(ctx context.Context, apiHost string, apiPort int, timeout time.Duration) error {
 	e := echo.New()
 
+	e.GET("/sync", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, c.SyncStatus())
+	})
+
 	e.GET("/sync/atx/:id", func(ctx echo.Context) error {
 		id := ctx.Param("id")
 
-		log.Info("http syncing atx %s", id)
+		logging.Info("http syncing atx", zap.String("atx", id))
 		go func() {
 			atx, err := c.dbClient.GetAtxById(c.db, id)
 			if err != nil {
-				log.Warning("syncing atx %s failed with error %d", id, err)
+				logging.Error("http syncing atx", err, zap.String("atx", id))
 				return
 			}
 			if atx != nil {
@@ -40,14 +51,14 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 			return ctx.String(http.StatusBadRequest, "Invalid parameter")
 		}
 
-		log.Info("http syncing atxs from %d", timestamp)
+		logging.Info("http syncing atxs", zap.Int64("from", timestamp))
 		go func() {
-			err = c.dbClient.GetAtxsReceivedAfter(c.db, timestamp, func(atx *types.VerifiedActivationTx) bool {
+			err := c.dbClient.GetAtxsReceivedAfter(c.db, timestamp, func(atx *types.VerifiedActivationTx) bool {
 				c.listener.OnActivation(atx)
 				return true
 			})
 			if err != nil {
-				log.Warning("syncing atxs from %s failed with error %d", ts, err)
+				logging.Error("http syncing atxs", err, zap.Int64("from", timestamp))
 				return
 			}
 			c.listener.RecalculateEpochStats()
@@ -63,15 +74,15 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 			return ctx.String(http.StatusBadRequest, "Invalid parameter")
 		}
 
-		log.Info("http syncing atxs from %d", timestamp)
+		logging.Info("http syncing atxs", zap.Int64("from", timestamp))
 		go func() {
 			var atxs []*model.Activation
-			err = c.dbClient.GetAtxsReceivedAfter(c.db, timestamp, func(atx *types.VerifiedActivationTx) bool {
+			err := c.dbClient.GetAtxsReceivedAfter(c.db, timestamp, func(atx *types.VerifiedActivationTx) bool {
 				atxs = append(atxs, model.NewActivation(atx))
 				return true
 			})
 			if err != nil {
-				log.Warning("syncing atxs from %s failed with error %d", ts, err)
+				logging.Error("http syncing atxs", err, zap.Int64("from", timestamp))
 				return
 			}
 			c.listener.OnActivations(atxs)
@@ -88,14 +99,14 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 			return ctx.String(http.StatusBadRequest, "Invalid parameter")
 		}
 
-		log.Info("http syncing atxs for epoch %s", epoch)
+		logging.Info("http syncing atxs", logging.Epoch(uint32(epochId)))
 		go func() {
-			err = c.dbClient.GetAtxsByEpoch(c.db, epochId, func(atx *types.VerifiedActivationTx) bool {
+			err := c.dbClient.GetAtxsByEpoch(c.db, epochId, func(atx *types.VerifiedActivationTx) bool {
 				c.listener.OnActivation(atx)
 				return true
 			})
 			if err != nil {
-				log.Warning("syncing atxs for %s failed with error %d", epoch, err)
+				logging.Error("http syncing atxs", err, logging.Epoch(uint32(epochId)))
 				return
 			}
 			c.listener.RecalculateEpochStats()
@@ -111,11 +122,11 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 			return ctx.String(http.StatusBadRequest, "Invalid parameter")
 		}
 
-		log.Info("http syncing atxs for epoch %s", epoch)
+		logging.Info("http syncing atxs", logging.Epoch(uint32(epochId)))
 		go func() {
 			count, err := c.dbClient.CountAtxsByEpoch(c.db, epochId)
 			if err != nil {
-				log.Warning("syncing atxs for %s failed with error %d", epoch, err)
+				logging.Error("http syncing atxs", err, logging.Epoch(uint32(epochId)))
 				return
 			}
 			batchSize := 100000
@@ -128,7 +139,7 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 					return true
 				})
 				if err != nil {
-					log.Warning("syncing atxs for %s failed with error %d", epoch, err)
+					logging.Error("http syncing atxs", err, logging.Epoch(uint32(epochId)))
 					return
 				}
 				c.listener.OnActivations(atxs)
@@ -151,11 +162,11 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 		go func() {
 			l, err := c.dbClient.GetLayer(c.db, lid, c.listener.GetEpochNumLayers())
 			if err != nil {
-				log.Warning("%v", err)
+				logging.Error("http syncing layer", err, logging.Layer(lid.Uint32()))
 				return
 			}
 
-			log.Info("http syncing layer: %d", l.Number.Number)
+			logging.Info("http syncing layer", logging.Layer(l.Number.Number))
 			c.listener.OnLayer(l)
 		}()
 
@@ -171,23 +182,24 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 		lid := types.LayerID(layerId)
 
 		go func() {
-			log.Info("http syncing rewards for layer: %d", lid.Uint32())
+			logging.Info("http syncing rewards", logging.Layer(lid.Uint32()))
 			rewards, err := c.dbClient.GetLayerRewards(c.db, lid)
 			if err != nil {
-				log.Warning("%v", err)
+				logging.Error("http syncing rewards", err, logging.Layer(lid.Uint32()))
 				return
 			}
 
+			layerRewards := make([]*pb.Reward, 0, len(rewards))
 			for _, reward := range rewards {
-				r := &pb.Reward{
+				layerRewards = append(layerRewards, &pb.Reward{
 					Layer:       &pb.LayerNumber{Number: reward.Layer.Uint32()},
 					Total:       &pb.Amount{Value: reward.TotalReward},
 					LayerReward: &pb.Amount{Value: reward.LayerReward},
 					Coinbase:    &pb.AccountId{Address: reward.Coinbase.String()},
 					Smesher:     &pb.SmesherId{Id: reward.SmesherID.Bytes()},
-				}
-				c.listener.OnReward(r)
+				})
 			}
+			c.listener.OnRewards(layerRewards)
 
 			c.listener.UpdateEpochStats(lid.Uint32())
 		}()
@@ -195,5 +207,26 @@ func (c *Collector) StartHttpServer(apiHost string, apiPort int) {
 		return ctx.NoContent(http.StatusOK)
 	})
 
-	e.Logger.Fatal(e.Start(fmt.Sprintf("%s:%d", apiHost, apiPort)))
+	return httpserver.Serve(ctx, "collector api", fmt.Sprintf("%s:%d", apiHost, apiPort), e, timeout)
+}
+
+// AdminHandlers returns the handlers of the collector served on the admin address only, see
+// debug.Serve. POST /admin/redecode/txs re-decodes the stored transactions in the background.
+func (c *Collector) AdminHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"/admin/redecode/txs": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			go func() {
+				logging.Info("re-decoding transactions")
+				if err := c.listener.RedecodeTransactions(context.Background()); err != nil {
+					logging.Error("re-decode transactions", err)
+				}
+			}()
+			w.WriteHeader(http.StatusAccepted)
+		}),
+	}
 }
@@ -2,30 +2,49 @@ package collector_test
 
 import (
 	"context"
-	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
-
-	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestLayers(t *testing.T) {
 	t.Parallel()
-	layers, err := storageDB.GetLayers(context.TODO(), &bson.D{})
+	// the fees are paid by the processed transactions of the layer, part of them to its rewards
+	type fees struct{ collected, distributed, rewards uint64 }
+	expected := make(map[uint32]*fees, len(generator.Layers))
+	for number := range generator.Layers {
+		expected[number] = &fees{}
+	}
+	for _, tx := range generator.Transactions {
+		expected[tx.Layer].collected += tx.Fee
+	}
+	for _, reward := range generator.Rewards {
+		expected[reward.Layer].distributed += reward.Total - reward.LayerReward
+		expected[reward.Layer].rewards += reward.Total
+	}
+
+	layers, err := storageReader.GetLayers(context.TODO(), &bson.D{})
 	require.NoError(t, err)
 	require.Equal(t, len(generator.Layers), len(layers))
+	var collected uint64
 	for _, layer := range layers {
-		// temporary hack, until storage return data as slice of bson.B not an struct.
-		layerEncoded, err := json.Marshal(layer.Map())
-		require.NoError(t, err)
-		var tmpLayer model.Layer
-		require.NoError(t, json.Unmarshal(layerEncoded, &tmpLayer))
+		tmpLayer := *layer
 		generatedLayer, ok := generator.Layers[tmpLayer.Number]
 		require.True(t, ok)
-		tmpLayer.Rewards = generatedLayer.Rewards // todo should fill data from proto api
-		tmpLayer.Hash = tmpLayer.Hash[2:]         // contain string like `0x...`, cut 0x
+		tmpLayer.Hash = tmpLayer.Hash[2:] // contain string like `0x...`, cut 0x
+
+		fees := expected[tmpLayer.Number]
+		require.Equal(t, fees.rewards, tmpLayer.Rewards, "layer %d", tmpLayer.Number)
+		require.Equal(t, fees.collected, tmpLayer.FeesCollected, "layer %d", tmpLayer.Number)
+		require.Equal(t, fees.distributed, tmpLayer.FeesDistributed, "layer %d", tmpLayer.Number)
+		require.Equal(t, fees.collected-min(fees.collected, fees.distributed), tmpLayer.FeesBurned, "layer %d", tmpLayer.Number)
+		collected += tmpLayer.FeesCollected
+		// the generated layer rewards are random, the sum of the generated rewards is checked above
+		tmpLayer.Rewards = generatedLayer.Rewards
+		tmpLayer.FeesCollected, tmpLayer.FeesDistributed, tmpLayer.FeesBurned = 0, 0, 0
+		tmpLayer.FeeMin, tmpLayer.FeeMedian, tmpLayer.FeeMax = 0, 0, 0
 		require.Equal(t, *generatedLayer, tmpLayer)
 	}
+	require.NotZero(t, collected)
 }
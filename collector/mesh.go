@@ -2,8 +2,10 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/pipeline"
 	"github.com/spacemeshos/explorer-backend/utils"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,46 +14,55 @@ import (
 
 	empty "github.com/golang/protobuf/ptypes/empty"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"github.com/spacemeshos/go-spacemesh/log"
+	"go.uber.org/zap"
 )
 
+// errLastLayer is returned by syncLayer when the last stored layer cannot be read, the layer is then
+// retried rather than compared to a wrong position of the sync.
+var errLastLayer = errors.New("cannot get the last stored layer")
+
 func (c *Collector) getNetworkInfo() error {
-	// set timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := c.callContext()
 	defer cancel()
 
 	genesisTime, err := c.meshClient.GenesisTime(ctx, &pb.GenesisTimeRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get GenesisTime: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get GenesisTime", err)
 		return err
 	}
 
 	genesisId, err := c.meshClient.GenesisID(ctx, &pb.GenesisIDRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get NetId: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get NetId", err)
 	}
 
 	epochNumLayers, err := c.meshClient.EpochNumLayers(ctx, &pb.EpochNumLayersRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get EpochNumLayers: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get EpochNumLayers", err)
 		return err
 	}
 
 	maxTransactionsPerSecond, err := c.meshClient.MaxTransactionsPerSecond(ctx, &pb.MaxTransactionsPerSecondRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get MaxTransactionsPerSecond: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get MaxTransactionsPerSecond", err)
 		return err
 	}
 
 	layerDuration, err := c.meshClient.LayerDuration(ctx, &pb.LayerDurationRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get LayerDuration: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get LayerDuration", err)
 		return err
 	}
 
 	res, err := c.smesherClient.PostConfig(ctx, &empty.Empty{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get POST config: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot get POST config", err)
 		return err
 	}
 
@@ -67,56 +78,101 @@ func (c *Collector) getNetworkInfo() error {
 	return nil
 }
 
-func (c *Collector) syncMissingLayers() error {
-	status, err := c.nodeClient.Status(context.Background(), &pb.StatusRequest{})
+// nextLayerToSync returns the layer following the last stored one. The sync starts from the
+// --syncFromLayer layer, the genesis layer 0 by default, when no layer is stored yet. A failing
+// read is returned, so that the sync is retried instead of restarted from --syncFromLayer.
+func (c *Collector) nextLayerToSync() (uint32, error) {
+	lastLayer, err := c.listener.GetLastLayer(context.TODO())
+	if err != nil {
+		return 0, err
+	}
+	if lastLayer > 0 {
+		return lastLayer + 1, nil
+	}
+	layers, err := c.listener.GetLayersCount(context.TODO(), &bson.D{})
+	if err != nil {
+		return 0, err
+	}
+	if layers == 0 {
+		return c.syncFromLayerFlag, nil
+	}
+	return 1, nil
+}
+
+// syncMissingLayers syncs the layers from nextLayer up to the verified layer of the node.
+func (c *Collector) syncMissingLayers(parent context.Context, nextLayer uint32) error {
+	ctx, cancel := c.callContext()
+	defer cancel()
+	status, err := c.nodeClient.Status(ctx, &pb.StatusRequest{})
 	if err != nil {
-		log.Err(fmt.Errorf("cannot receive node status: %v", err))
+		err = c.grpcError(err)
+		logging.Error("cannot receive node status", err)
 		return err
 	}
 	syncedLayerNum := status.Status.VerifiedLayer.Number
-	lastLayer := c.listener.GetLastLayer(context.TODO())
+	c.progress.setTarget(syncedLayerNum)
 
-	if syncedLayerNum == lastLayer {
+	if nextLayer > syncedLayerNum {
 		return nil
 	}
 
-	log.Info("Syncing missing layers %d...%d", lastLayer+1, syncedLayerNum)
+	logging.Info("syncing missing layers", zap.Uint32("from", nextLayer), zap.Uint32("to", syncedLayerNum))
 
-	for i := lastLayer + 1; i <= syncedLayerNum; i++ {
+	for i := nextLayer; i <= syncedLayerNum; i++ {
+		if err := parent.Err(); err != nil {
+			return err
+		}
 		err := c.syncLayer(types.LayerID(i))
+		if errors.Is(err, errLastLayer) {
+			// the following layers would be synced past the missing one
+			return err
+		}
 		if err != nil {
-			log.Warning("syncMissingLayers error: %v", err)
+			logging.Error("cannot sync missing layer", err, logging.Layer(i))
 		}
 	}
 
-	log.Info("Waiting for layers queue to be empty")
+	logging.Info("waiting for the layers queue to be empty")
+	return c.waitLayersQueue(parent, 15*time.Second)
+}
+
+// LayersInQueue returns the number of layers being ingested, in the stages of the collector or in
+// the queue of the listener.
+func (c *Collector) LayersInQueue() int {
+	return c.stages.len() + c.listener.LayersInQueue()
+}
+
+// waitLayersQueue polls the stages and the storage every interval until no layer is queued or ctx
+// is done.
+func (c *Collector) waitLayersQueue(ctx context.Context, interval time.Duration) error {
 	for {
-		layersInQueue := c.listener.LayersInQueue()
-		if layersInQueue > 0 {
-			log.Info("%d layers in queue. Waiting", layersInQueue)
-			time.Sleep(15 * time.Second)
-		} else {
-			break
+		layersInQueue := c.LayersInQueue()
+		if layersInQueue == 0 {
+			return nil
+		}
+		logging.Info("waiting for the layers in queue", zap.Int("layers", layersInQueue))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
 		}
 	}
-
-	return nil
 }
 
-func (c *Collector) malfeasancePump() error {
+func (c *Collector) malfeasancePump(ctx context.Context) error {
 	var req = pb.MalfeasanceStreamRequest{}
 
-	log.Info("Start mesh malfeasance pump")
+	logging.Info("start mesh malfeasance pump")
 	defer func() {
 		c.notify <- -streamType_mesh_Malfeasance
-		log.Info("Stop mesh malfeasance pump")
+		logging.Info("stop mesh malfeasance pump")
 	}()
 
 	c.notify <- +streamType_mesh_Malfeasance
 
-	stream, err := c.meshClient.MalfeasanceStream(context.Background(), &req)
+	stream, err := c.meshClient.MalfeasanceStream(ctx, &req)
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get malfeasance stream: %v", err))
+		logging.Error("cannot get malfeasance stream", err)
 		return err
 	}
 
@@ -125,8 +181,12 @@ func (c *Collector) malfeasancePump() error {
 		if err == io.EOF {
 			return err
 		}
+		if ctx.Err() != nil {
+			// stopped by Run
+			return ctx.Err()
+		}
 		if err != nil {
-			log.Err(fmt.Errorf("cannot receive malfeasance proof: %v", err))
+			logging.Error("cannot receive malfeasance proof", err)
 			return err
 		}
 		proof := response.GetProof()
@@ -135,51 +195,63 @@ func (c *Collector) malfeasancePump() error {
 }
 
 func (c *Collector) syncLayer(lid types.LayerID) error {
+	start := time.Now()
 	layer, err := c.dbClient.GetLayer(c.db, lid, c.listener.GetEpochNumLayers())
 	if err != nil {
 		return err
 	}
+	pipeline.Observe(pipeline.StageFetchLayer, start)
 
-	if c.listener.IsLayerInQueue(layer) {
-		log.Info("layer %d is already in queue", layer.Number.Number)
+	if c.isLayerPending(layer) {
+		logging.Info("layer is already in queue", logging.Layer(layer.Number.Number))
 		return nil
 	}
 
-	if lastLayer := c.listener.GetLastLayer(context.TODO()); lastLayer >= layer.Number.Number {
-		log.Info("layer %d is already in database", layer.Number.Number)
+	next, err := c.nextLayerToSync()
+	if err != nil {
+		return fmt.Errorf("%w: %w", errLastLayer, err)
+	}
+	if next > layer.Number.Number {
+		logging.Info("layer is already in database", logging.Layer(layer.Number.Number))
 		return nil
 	}
 
-	log.Info("syncing layer: %d", layer.Number.Number)
-	c.listener.OnLayer(layer)
+	c.ingestLayer(layer)
+	c.reportSyncProgress(layer.Number.Number)
 
-	log.Info("syncing accounts for layer: %d", layer.Number.Number)
-	accounts, err := c.dbClient.AccountsSnapshot(c.db, lid)
-	if err != nil {
-		log.Warning("%v\n", err)
-	}
-	c.listener.OnAccounts(accounts)
+	return nil
+}
 
-	log.Info("syncing rewards for layer: %d", layer.Number.Number)
-	rewards, err := c.dbClient.GetLayerRewards(c.db, lid)
+// fetchActiveSet returns the active set size of the epoch. If the node has no active set for the
+// epoch, the number of activations targeting it is used instead.
+func (c *Collector) fetchActiveSet(epoch types.EpochID) (uint32, bool) {
+	size, err := c.dbClient.GetEpochActiveSetSize(c.db, epoch)
 	if err != nil {
-		log.Warning("%v\n", err)
+		logging.Error("cannot get the active set", err, logging.Epoch(epoch.Uint32()))
 	}
-
-	for _, reward := range rewards {
-		r := &pb.Reward{
-			Layer:       &pb.LayerNumber{Number: reward.Layer.Uint32()},
-			Total:       &pb.Amount{Value: reward.TotalReward},
-			LayerReward: &pb.Amount{Value: reward.LayerReward},
-			Coinbase:    &pb.AccountId{Address: reward.Coinbase.String()},
-			Smesher:     &pb.SmesherId{Id: reward.SmesherID.Bytes()},
+	if size == 0 && epoch > 0 {
+		size, err = c.dbClient.CountAtxsByEpoch(c.db, int64(epoch-1))
+		if err != nil {
+			logging.Error("cannot count the activations", err, logging.Epoch(epoch.Uint32()-1))
+			return 0, false
 		}
-		c.listener.OnReward(r)
 	}
+	return uint32(size), true
+}
 
-	c.listener.UpdateEpochStats(layer.Number.Number)
-
-	return nil
+// fetchBeacon returns the beacon of the epoch. An epoch without a beacon in the node is not
+// stored, so that its beacon is reported missing.
+func (c *Collector) fetchBeacon(epoch types.EpochID) (types.Beacon, bool) {
+	beacon, err := c.dbClient.GetEpochBeacon(c.db, epoch)
+	if err != nil {
+		logging.Error("cannot get the beacon", err, logging.Epoch(epoch.Uint32()))
+		return types.EmptyBeacon, false
+	}
+	if beacon == types.EmptyBeacon {
+		logging.Warn("no beacon", logging.Epoch(epoch.Uint32()))
+		return types.EmptyBeacon, false
+	}
+	return beacon, true
 }
 
 func (c *Collector) syncNotProcessedTxs() error {
@@ -194,12 +266,14 @@ func (c *Collector) syncNotProcessedTxs() error {
 			return err
 		}
 
-		state, err := c.transactionsClient.TransactionsState(context.TODO(), &pb.TransactionsStateRequest{
+		ctx, cancel := c.callContext()
+		state, err := c.transactionsClient.TransactionsState(ctx, &pb.TransactionsStateRequest{
 			TransactionId:       []*pb.TransactionId{{Id: txId}},
 			IncludeTransactions: false,
 		})
+		cancel()
 		if err != nil {
-			return err
+			return c.grpcError(err)
 		}
 
 		txState := state.TransactionsState[0]
@@ -221,40 +295,26 @@ func (c *Collector) syncAllRewards() error {
 		return fmt.Errorf("%v\n", err)
 	}
 
+	layerRewards := make([]*pb.Reward, 0, len(rewards))
 	for _, reward := range rewards {
-		r := &pb.Reward{
+		layerRewards = append(layerRewards, &pb.Reward{
 			Layer:       &pb.LayerNumber{Number: reward.Layer.Uint32()},
 			Total:       &pb.Amount{Value: reward.TotalReward},
 			LayerReward: &pb.Amount{Value: reward.LayerReward},
 			Coinbase:    &pb.AccountId{Address: reward.Coinbase.String()},
 			Smesher:     &pb.SmesherId{Id: reward.SmesherID.Bytes()},
-		}
-		c.listener.OnReward(r)
+		})
 	}
+	c.listener.OnRewards(layerRewards)
 
 	return nil
 }
 
-func (c *Collector) syncActivations() error {
-	received := c.listener.GetLastActivationReceived()
-	log.Info("Syncing activations from %d", received)
-
-	var atxs []*model.Activation
-	err := c.dbClient.GetAtxsReceivedAfter(c.db, received, func(atx *types.VerifiedActivationTx) bool {
-		atxs = append(atxs, model.NewActivation(atx))
-		return true
-	})
+func (c *Collector) createFutureEpoch() error {
+	lastLayer, err := c.listener.GetLastLayer(context.Background())
 	if err != nil {
 		return err
 	}
-
-	c.listener.OnActivations(atxs)
-
-	return nil
-}
-
-func (c *Collector) createFutureEpoch() error {
-	lastLayer := c.listener.GetLastLayer(context.Background())
 	epochNumLayers := c.listener.GetEpochNumLayers()
 
 	if epochNumLayers > 0 {
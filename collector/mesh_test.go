@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	sql2 "github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/accounts"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/collector/sql"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+)
+
+func TestNextLayerToSync(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	c := NewCollector("", "", false, 0, false, s, nil, nil, false)
+
+	next := func() uint32 {
+		next, err := c.nextLayerToSync()
+		require.NoError(t, err)
+		return next
+	}
+
+	// the genesis layer is synced first
+	require.Equal(t, uint32(0), next())
+
+	s.OnLayer(&pb.Layer{Number: &pb.LayerNumber{Number: 0}, Status: pb.Layer_LAYER_STATUS_CONFIRMED})
+	require.Equal(t, uint32(1), next())
+
+	s.OnLayer(&pb.Layer{Number: &pb.LayerNumber{Number: 1}, Status: pb.Layer_LAYER_STATUS_CONFIRMED})
+	require.Equal(t, uint32(2), next())
+}
+
+func TestIngestGenesisLayer(t *testing.T) {
+	db := sql2.InMemory()
+	defer db.Close()
+	first, second := types.GenerateAddress([]byte{1}), types.GenerateAddress([]byte{2})
+	// the genesis accounts are written at layer 0
+	require.NoError(t, accounts.Update(db, &types.Account{Address: first, Balance: 1000}))
+	require.NoError(t, accounts.Update(db, &types.Account{Address: second, Balance: 500}))
+
+	s := memory.New()
+	defer s.Close()
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	client := &sql.Client{}
+	c := NewCollector("", "", false, 0, false, s, db, client, false)
+	reader := memory.NewReader(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer c.startStages(ctx)()
+	defer cancel()
+
+	ingest := func(number uint32) {
+		layer, err := client.GetLayer(db, types.LayerID(number), 10)
+		require.NoError(t, err)
+		c.ingestLayer(layer)
+		require.NoError(t, c.waitLayersQueue(ctx, time.Millisecond))
+	}
+	ingest(0)
+
+	layer, err := reader.GetLayer(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), layer.Epoch)
+	balances := map[string]uint64{}
+	stored, err := reader.GetAccounts(ctx, &bson.D{})
+	require.NoError(t, err)
+	for _, account := range stored {
+		require.Equal(t, uint64(0), account.Created)
+		balances[account.Address] = account.Balance
+	}
+	require.Equal(t, map[string]uint64{first.String(): 1000, second.String(): 500}, balances)
+
+	epoch, err := reader.GetEpoch(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), epoch.LayerStart)
+	require.Equal(t, uint32(9), epoch.LayerEnd)
+	require.Equal(t, int64(2), epoch.Stats.Current.Accounts)
+	require.Equal(t, int64(2), epoch.Stats.Current.NewAccounts)
+
+	// a genesis account updated later is still counted in the genesis epoch
+	require.NoError(t, accounts.Update(db, &types.Account{Layer: 12, Address: first, Balance: 700, NextNonce: 1}))
+	ingest(12)
+
+	stored, err = reader.GetAccounts(ctx, &bson.D{{Key: "address", Value: first.String()}})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	require.Equal(t, uint64(700), stored[0].Balance)
+	require.Equal(t, uint64(0), stored[0].Created)
+
+	epoch, err = reader.GetEpoch(ctx, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), epoch.Stats.Current.Accounts)
+	epoch, err = reader.GetEpoch(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), epoch.Stats.Current.Accounts)
+	require.Equal(t, int64(0), epoch.Stats.Current.NewAccounts)
+}
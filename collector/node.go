@@ -2,71 +2,56 @@ package collector
 
 import (
 	"context"
-	"fmt"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"github.com/spacemeshos/go-spacemesh/common/types"
 	"io"
 
-	"github.com/spacemeshos/go-spacemesh/log"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
-func (c *Collector) syncStatusPump() error {
+func (c *Collector) syncStatusPump(ctx context.Context) error {
 	req := pb.StatusStreamRequest{}
 
-	log.Info("Start node sync status pump")
+	logging.Info("start node sync status pump")
 	defer func() {
 		c.notify <- -streamType_node_SyncStatus
-		log.Info("Stop node sync status pump")
+		logging.Info("stop node sync status pump")
 	}()
 
 	c.notify <- +streamType_node_SyncStatus
 
-	stream, err := c.nodeClient.StatusStream(context.Background(), &req)
+	stream, err := c.nodeClient.StatusStream(ctx, &req)
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get sync status stream: %v", err))
+		logging.Error("cannot get sync status stream", err)
 		return err
 	}
 
 	for {
 		res, err := stream.Recv()
 		if err == io.EOF {
-			log.Info("syncStatusPump: EOF")
+			logging.Info("node sync status stream closed")
 			return err
 		}
+		if ctx.Err() != nil {
+			// stopped by Run
+			return ctx.Err()
+		}
 		if err != nil {
-			log.Err(fmt.Errorf("cannot receive sync status: %v", err))
+			logging.Error("cannot receive sync status", err)
 			return err
 		}
 
 		status := res.GetStatus()
-		log.Info("Node sync status: %v", status)
-
-		lastLayer := c.listener.GetLastLayer(context.TODO())
-		if lastLayer != status.GetVerifiedLayer().GetNumber() {
-			for i := lastLayer + 1; i <= status.GetVerifiedLayer().GetNumber(); i++ {
-				err := c.syncLayer(types.LayerID(i))
-				if err != nil {
-					log.Warning("syncLayer error: %v", err)
-				}
-
-				err = c.syncNotProcessedTxs()
-				if err != nil {
-					log.Warning("syncNotProcessedTxs error: %v", err)
-				}
-
-				if c.atxSyncFlag {
-					err = c.syncActivations()
-					if err != nil {
-						log.Warning("syncActivations error: %v", err)
-					}
-				}
+		logging.Info("node sync status",
+			zap.Uint64("connected_peers", status.GetConnectedPeers()),
+			zap.Bool("synced", status.GetIsSynced()),
+			zap.Uint32("synced_layer", status.GetSyncedLayer().GetNumber()),
+			zap.Uint32("top_layer", status.GetTopLayer().GetNumber()),
+			zap.Uint32("verified_layer", status.GetVerifiedLayer().GetNumber()),
+		)
 
-				err = c.createFutureEpoch()
-				if err != nil {
-					log.Warning("createFutureEpoch error: %v", err)
-				}
-			}
-		}
+		c.setLayerTarget(status.GetVerifiedLayer().GetNumber())
 
 		c.listener.OnNodeStatus(
 			status.GetConnectedPeers(),
@@ -0,0 +1,46 @@
+package collector_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	s := openStorage(t, testAPIServiceDB+"_prune")
+
+	// without network info there is no last layer to prune from
+	_, err := s.Prune(ctx, []storage.RetentionPolicy{{Collection: "rewards", MaxAge: time.Hour}})
+	require.Error(t, err)
+
+	require.NoError(t, s.UpsertNetworkInfo(ctx, &model.NetworkInfo{EpochNumLayers: 10, LayerDuration: 60, LastLayer: 100}))
+	for layer := uint32(1); layer <= 100; layer++ {
+		require.NoError(t, s.UpsertReward(ctx, &model.Reward{
+			Layer:    layer,
+			Smesher:  fmt.Sprintf("0x%02x", layer),
+			Coinbase: "sm1",
+			Total:    100,
+		}))
+	}
+
+	// an hour is 60 layers of a minute, the rewards before layer 40 are pruned
+	n, err := s.Prune(ctx, []storage.RetentionPolicy{{Collection: "rewards", MaxAge: time.Hour}})
+	require.NoError(t, err)
+	require.Equal(t, int64(39), n)
+	require.Equal(t, int64(61), s.GetRewardsCount(ctx, &bson.D{}))
+	require.Zero(t, s.GetRewardsCount(ctx, &bson.D{{Key: "layer", Value: bson.D{{Key: "$lt", Value: 40}}}}))
+
+	// the pruned rewards are not pruned again
+	n, err = s.Prune(ctx, []storage.RetentionPolicy{{Collection: "rewards", MaxAge: time.Hour}})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
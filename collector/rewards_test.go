@@ -2,27 +2,26 @@ package collector_test
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/spacemeshos/explorer-backend/model"
 	"strings"
 	"testing"
 
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestRewards(t *testing.T) {
 	t.Parallel()
-	rewards, err := storageDB.GetRewards(context.TODO(), &bson.D{})
+	rewards, err := storageReader.GetRewards(context.TODO(), &bson.D{})
 	require.NoError(t, err)
 	require.Equal(t, len(generator.Rewards), len(rewards))
 
 	for _, reward := range rewards {
-		// temporary hack, until storage return data as slice of bson.B not an struct.
-		rewardEncoded, err := json.Marshal(reward.Map())
-		require.NoError(t, err)
-		var tmpReward model.Reward
-		require.NoError(t, json.Unmarshal(rewardEncoded, &tmpReward))
+		tmpReward := *reward
 		generatedReward, ok := generator.Rewards[strings.ToLower(tmpReward.Smesher)]
 		require.True(t, ok, "reward not found")
 		generatedReward.Smesher = strings.ToLower(generatedReward.Smesher)
@@ -30,6 +29,49 @@ func TestRewards(t *testing.T) {
 		tmpReward.Coinbase = strings.ToLower(tmpReward.Coinbase)
 		generatedReward.Coinbase = strings.ToLower(generatedReward.Coinbase)
 		tmpReward.ID = "" // id is internal mongo id. before insert to db we do not know it.
+		// the reward is linked to the activation of its smesher
+		atx, ok := generator.Activations[tmpReward.Atx]
+		require.True(t, ok, "activation not found")
+		require.Equal(t, tmpReward.Smesher, strings.ToLower(atx.SmesherId))
+		tmpReward.Atx = ""
 		require.Equal(t, *generatedReward, tmpReward)
 	}
 }
+
+func TestRewardCountersWithStatsDatabase(t *testing.T) {
+	t.Parallel()
+	ctx := context.TODO()
+	name := testAPIServiceDB + "_reward_counters"
+	s := openStorage(t, name)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(ctx) })
+	statsDB := client.Database(name + "_stats")
+	require.NoError(t, statsDB.Drop(ctx))
+	t.Cleanup(func() { statsDB.Drop(ctx) })
+	require.NoError(t, s.OpenStatsDatabase(ctx, "", statsDB.Name()))
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1, Received: 10},
+	})
+
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 90},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(11), reward(12)})
+
+	// the counters of the raw smeshers collection are not written to the stats database
+	smesher, err := s.GetSmesher(ctx, &bson.D{{Key: "id", Value: "0x51"}})
+	require.NoError(t, err)
+	require.Equal(t, int64(200), smesher.TotalRewards)
+	require.Equal(t, int64(2), smesher.RewardsCount)
+	n, err := statsDB.Collection("smeshers").CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -7,5 +7,5 @@ import (
 )
 
 func (c *Client) AccountsSnapshot(db *sql.Database, lid types.LayerID) (rst []*types.Account, err error) {
-	return accounts.Snapshot(db, lid)
+	return accounts.Snapshot(c.source(db, TableAccounts), lid)
 }
@@ -0,0 +1,33 @@
+package sql
+
+import (
+	"github.com/spacemeshos/go-spacemesh/codec"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// GetEpochActiveSetSize returns the number of identities in the largest active set known for the epoch,
+// or 0 if the node did not store any active set for it.
+func (c *Client) GetEpochActiveSetSize(db *sql.Database, epoch types.EpochID) (int, error) {
+	var (
+		size int
+		derr error
+	)
+	_, err := c.source(db, TableActiveSets).Exec("select active_set from activesets where epoch = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(epoch))
+		}, func(stmt *sql.Statement) bool {
+			var set types.EpochActiveSet
+			if _, derr = codec.DecodeFrom(stmt.ColumnReader(0), &set); derr != nil {
+				return false
+			}
+			if len(set.Set) > size {
+				size = len(set.Set)
+			}
+			return true
+		})
+	if err != nil {
+		return 0, err
+	}
+	return size, derr
+}
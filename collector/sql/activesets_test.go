@@ -0,0 +1,37 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/activesets"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEpochActiveSetSize(t *testing.T) {
+	db := sql.InMemory()
+	defer db.Close()
+	c := &Client{}
+
+	size, err := c.GetEpochActiveSetSize(db, 2)
+	require.NoError(t, err)
+	require.Zero(t, size)
+
+	// the largest of the active sets of the epoch
+	require.NoError(t, activesets.Add(db, types.Hash32{1}, &types.EpochActiveSet{
+		Epoch: 2,
+		Set:   []types.ATXID{{1}, {2}},
+	}))
+	require.NoError(t, activesets.Add(db, types.Hash32{2}, &types.EpochActiveSet{
+		Epoch: 2,
+		Set:   []types.ATXID{{1}, {2}, {3}},
+	}))
+	require.NoError(t, activesets.Add(db, types.Hash32{3}, &types.EpochActiveSet{
+		Epoch: 3,
+		Set:   []types.ATXID{{1}, {2}, {3}, {4}},
+	}))
+	size, err = c.GetEpochActiveSetSize(db, 2)
+	require.NoError(t, err)
+	require.Equal(t, 3, size)
+}
@@ -60,6 +60,7 @@ func decoder(fn decoderCallback) sql.Decoder {
 }
 
 func (c *Client) GetAtxsReceivedAfter(db *sql.Database, ts int64, fn func(tx *types.VerifiedActivationTx) bool) error {
+	db = c.source(db, TableAtxs)
 	var derr error
 	_, err := db.Exec(
 		fullQuery+` WHERE received > ?1`,
@@ -81,6 +82,7 @@ func (c *Client) GetAtxsReceivedAfter(db *sql.Database, ts int64, fn func(tx *ty
 }
 
 func (c *Client) GetAtxsByEpoch(db *sql.Database, epoch int64, fn func(tx *types.VerifiedActivationTx) bool) error {
+	db = c.source(db, TableAtxs)
 	var derr error
 	_, err := db.Exec(
 		fullQuery+` WHERE epoch = ?1 ORDER BY epoch asc, id asc`,
@@ -102,6 +104,7 @@ func (c *Client) GetAtxsByEpoch(db *sql.Database, epoch int64, fn func(tx *types
 }
 
 func (c *Client) CountAtxsByEpoch(db *sql.Database, epoch int64) (int, error) {
+	db = c.source(db, TableAtxs)
 	var totalCount int
 	_, err := db.Exec(
 		`SELECT COUNT(*) FROM atxs WHERE epoch = ?1`,
@@ -118,6 +121,7 @@ func (c *Client) CountAtxsByEpoch(db *sql.Database, epoch int64) (int, error) {
 }
 
 func (c *Client) GetAtxsByEpochPaginated(db *sql.Database, epoch, limit, offset int64, fn func(tx *types.VerifiedActivationTx) bool) error {
+	db = c.source(db, TableAtxs)
 	var derr error
 	_, err := db.Exec(
 		fullQuery+` WHERE epoch = ?1 ORDER BY epoch asc, id asc LIMIT ?2 OFFSET ?3`,
@@ -149,7 +153,7 @@ func (c *Client) GetAtxById(db *sql.Database, id string) (*types.VerifiedActivat
 	var atxId types.ATXID
 	copy(atxId[:], idBytes)
 
-	atx, err := atxs.Get(db, atxId)
+	atx, err := atxs.Get(c.source(db, TableAtxs), atxId)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,11 @@
+package sql
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/ballots"
+)
+
+func (c *Client) GetLayerBallots(db *sql.Database, lid types.LayerID) ([]*types.Ballot, error) {
+	return ballots.Layer(c.source(db, TableBallots), lid)
+}
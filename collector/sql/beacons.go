@@ -0,0 +1,19 @@
+package sql
+
+import (
+	"errors"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/beacons"
+)
+
+// GetEpochBeacon returns the beacon the node uses for the epoch, or the empty beacon if the node has
+// none for it.
+func (c *Client) GetEpochBeacon(db *sql.Database, epoch types.EpochID) (types.Beacon, error) {
+	beacon, err := beacons.Get(c.source(db, TableBeacons), epoch)
+	if errors.Is(err, sql.ErrNotFound) {
+		return types.EmptyBeacon, nil
+	}
+	return beacon, err
+}
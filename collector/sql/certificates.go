@@ -0,0 +1,19 @@
+package sql
+
+import (
+	"errors"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+)
+
+// GetLayerCertificates returns the certificates of the blocks of the layer, none if the layer has no
+// hare output yet.
+func (c *Client) GetLayerCertificates(db *sql.Database, lid types.LayerID) ([]certificates.CertValidity, error) {
+	certs, err := certificates.Get(c.source(db, TableCertificates), lid)
+	if errors.Is(err, sql.ErrNotFound) {
+		return nil, nil
+	}
+	return certs, err
+}
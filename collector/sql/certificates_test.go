@@ -0,0 +1,60 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+func TestGetLayerCertificates(t *testing.T) {
+	db := sql.InMemory()
+	defer db.Close()
+	c := &Client{}
+	lid := types.LayerID(12)
+
+	certs, err := c.GetLayerCertificates(db, lid)
+	require.NoError(t, err)
+	require.Empty(t, certs)
+
+	block := types.BlockID{1}
+	smeshers := []types.NodeID{{0x51}, {0x52}}
+	require.NoError(t, certificates.Add(db, lid, &types.Certificate{
+		BlockID: block,
+		Signatures: []types.CertifyMessage{
+			{CertifyContent: types.CertifyContent{LayerID: lid, BlockID: block, EligibilityCnt: 2}, SmesherID: smeshers[0]},
+			{CertifyContent: types.CertifyContent{LayerID: lid, BlockID: block, EligibilityCnt: 3}, SmesherID: smeshers[1]},
+		},
+	}))
+	// the hare output of a layer without a certificate yet
+	require.NoError(t, certificates.SetHareOutputInvalid(db, lid, types.BlockID{2}))
+
+	certs, err = c.GetLayerCertificates(db, lid)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	converted := make(map[string]*model.BlockCertificate, len(certs))
+	for _, cert := range certs {
+		mc := model.NewBlockCertificate(lid, cert)
+		converted[mc.BlockId] = mc
+	}
+	require.Equal(t, &model.BlockCertificate{
+		Layer:         12,
+		BlockId:       utils.NBytesToHex(block.Bytes(), 20),
+		Valid:         true,
+		Eligibilities: 5,
+		Signers: []model.CertificateSigner{
+			{Smesher: utils.BytesToHex(smeshers[0].Bytes()), Eligibilities: 2},
+			{Smesher: utils.BytesToHex(smeshers[1].Bytes()), Eligibilities: 3},
+		},
+	}, converted[utils.NBytesToHex(block.Bytes(), 20)])
+	invalid := converted[utils.NBytesToHex(types.BlockID{2}.Bytes(), 20)]
+	require.NotNil(t, invalid)
+	require.False(t, invalid.Valid)
+	require.Empty(t, invalid.Signers)
+	require.Zero(t, invalid.Eligibilities)
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/go-spacemesh/common/types"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/spacemeshos/go-spacemesh/sql/atxs"
 	"github.com/spacemeshos/go-spacemesh/sql/ballots"
@@ -15,18 +14,22 @@ import (
 	"github.com/spacemeshos/go-spacemesh/sql/transactions"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
 func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32) (*pb.Layer, error) {
 	var bs []*pb.Block
 	var activations []types.ATXID
 
-	blts, err := ballots.Layer(db, lid)
+	blts, err := ballots.Layer(c.source(db, TableBallots), lid)
 	if err != nil {
 		return nil, err
 	}
 
-	blks, err := blocks.Layer(db, lid)
+	blks, err := blocks.Layer(c.source(db, TableBlocks), lid)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +40,7 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 		if b == nil {
 			continue
 		}
-		mtxs, missing := getMeshTransactions(db, b.TxIDs)
+		mtxs, missing := getMeshTransactions(c.source(db, TableTransactions), b.TxIDs)
 		if len(missing) != 0 {
 			return nil, status.Errorf(codes.Internal, "error retrieving tx data")
 		}
@@ -54,8 +57,9 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 	}
 
 	epoch := lid.Uint32() / numLayers
-	if lid.Uint32()%numLayers == 0 {
-		atxsId, err := atxs.GetIDsByEpoch(context.Background(), db, types.EpochID(epoch-1))
+	// the genesis epoch has no previous epoch to take activations from
+	if lid.Uint32()%numLayers == 0 && epoch > 0 {
+		atxsId, err := atxs.GetIDsByEpoch(context.Background(), c.source(db, TableAtxs), types.EpochID(epoch-1))
 		if err != nil {
 			return nil, err
 		}
@@ -67,7 +71,7 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 	var pbActivations []*pb.Activation
 
 	// Add unique ATXIDs
-	atxids, matxs := GetATXs(db, activations)
+	atxids, matxs := GetATXs(c.source(db, TableAtxs), activations)
 	if len(matxs) != 0 {
 		return nil, status.Errorf(codes.Internal, "error retrieving activations data")
 	}
@@ -75,18 +79,18 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 		pbActivations = append(pbActivations, convertActivation(atx))
 	}
 
-	stateRoot, err := layers.GetStateHash(db, layer.Index())
+	stateRoot, err := layers.GetStateHash(c.source(db, TableLayers), layer.Index())
 	if err != nil {
 		// This is expected. We can only retrieve state root for a layer that was applied to state,
 		// which only happens after it's approved/confirmed.
-		log.Debug("no state root for layer", err)
+		logging.Debug("no state root for layer", logging.Layer(lid.Uint32()), zap.Error(err))
 	}
 
-	hash, err := layers.GetAggregatedHash(db, lid)
+	hash, err := layers.GetAggregatedHash(c.source(db, TableLayers), lid)
 	if err != nil {
 		// This is expected. We can only retrieve state root for a layer that was applied to state,
 		// which only happens after it's approved/confirmed.
-		log.Debug("no mesh hash at layer", err)
+		logging.Debug("no mesh hash at layer", logging.Layer(lid.Uint32()), zap.Error(err))
 	}
 	return &pb.Layer{
 		Number:        &pb.LayerNumber{Number: layer.Index().Uint32()},
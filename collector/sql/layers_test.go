@@ -0,0 +1,22 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetGenesisLayer(t *testing.T) {
+	db := sql.InMemory()
+	defer db.Close()
+	c := &Client{}
+
+	// the genesis epoch has no previous epoch to take activations from
+	layer, err := c.GetLayer(db, types.LayerID(0), 10)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0), layer.GetNumber().GetNumber())
+	require.Empty(t, layer.GetBlocks())
+	require.Empty(t, layer.GetActivations())
+}
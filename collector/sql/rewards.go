@@ -6,7 +6,7 @@ import (
 )
 
 func (c *Client) GetLayerRewards(db *sql.Database, lid types.LayerID) (rst []*types.Reward, err error) {
-	_, err = db.Exec("select coinbase, layer, total_reward, layer_reward, pubkey from rewards where layer = ?1;",
+	_, err = c.source(db, TableRewards).Exec("select coinbase, layer, total_reward, layer_reward, pubkey from rewards where layer = ?1;",
 		func(stmt *sql.Statement) {
 			stmt.BindInt64(1, int64(lid))
 		}, func(stmt *sql.Statement) bool {
@@ -29,7 +29,7 @@ func (c *Client) GetLayerRewards(db *sql.Database, lid types.LayerID) (rst []*ty
 }
 
 func (c *Client) GetAllRewards(db *sql.Database) (rst []*types.Reward, err error) {
-	_, err = db.Exec("select coinbase, layer, total_reward, layer_reward, pubkey from rewards;",
+	_, err = c.source(db, TableRewards).Exec("select coinbase, layer, total_reward, layer_reward, pubkey from rewards;",
 		nil, func(stmt *sql.Statement) bool {
 			addrBytes := stmt.ColumnViewBytes(0)
 
@@ -0,0 +1,80 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// Tables which can be read from a separate sqlite source.
+const (
+	TableAccounts     = "accounts"
+	TableActiveSets   = "activesets"
+	TableAtxs         = "atxs"
+	TableBallots      = "ballots"
+	TableBeacons      = "beacons"
+	TableBlocks       = "blocks"
+	TableCertificates = "certificates"
+	TableLayers       = "layers"
+	TableRewards      = "rewards"
+	TableTransactions = "transactions"
+)
+
+var tables = map[string]struct{}{
+	TableAccounts:     {},
+	TableActiveSets:   {},
+	TableAtxs:         {},
+	TableBallots:      {},
+	TableBeacons:      {},
+	TableBlocks:       {},
+	TableCertificates: {},
+	TableLayers:       {},
+	TableRewards:      {},
+	TableTransactions: {},
+}
+
+// SetupSources opens additional sqlite sources given as `<table>=<path>`, e.g. `atxs=/data/atx.sql`.
+// A path may be shared by several tables, it is opened once. A table has at most one source, the
+// opened sources are closed if a spec is invalid.
+func SetupSources(specs []string) (map[string]*sql.Database, error) {
+	sources := make(map[string]*sql.Database, len(specs))
+	opened := make(map[string]*sql.Database)
+	fail := func(err error) (map[string]*sql.Database, error) {
+		for _, db := range opened {
+			db.Close()
+		}
+		return nil, err
+	}
+	for _, spec := range specs {
+		table, path, ok := strings.Cut(spec, "=")
+		if !ok || table == "" || path == "" {
+			return fail(fmt.Errorf("invalid sqlite source `%s`, expected <table>=<path>", spec))
+		}
+		if _, ok := tables[table]; !ok {
+			return fail(fmt.Errorf("invalid sqlite source `%s`: unknown table `%s`", spec, table))
+		}
+		if _, ok := sources[table]; ok {
+			return fail(fmt.Errorf("invalid sqlite source `%s`: duplicate table `%s`", spec, table))
+		}
+		db, ok := opened[path]
+		if !ok {
+			var err error
+			db, err = Setup(path)
+			if err != nil {
+				return fail(fmt.Errorf("open sqlite source `%s`: %w", path, err))
+			}
+			opened[path] = db
+		}
+		sources[table] = db
+	}
+	return sources, nil
+}
+
+// source returns the database holding the table, or db if the table has no separate source.
+func (c *Client) source(db *sql.Database, table string) *sql.Database {
+	if src, ok := c.Sources[table]; ok {
+		return src
+	}
+	return db
+}
@@ -0,0 +1,72 @@
+package sql
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupSources(t *testing.T) {
+	dir := t.TempDir()
+	atxs, state := filepath.Join(dir, "atxs.sql"), filepath.Join(dir, "state.sql")
+	for _, path := range []string{atxs, state} {
+		db, err := sql.Open("file:" + path)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+	}
+
+	for _, tc := range []struct {
+		name  string
+		specs []string
+		// paths are the paths of the tables, the tables of a path share one database
+		paths map[string]string
+		err   string
+	}{
+		{name: "none", paths: map[string]string{}},
+		{
+			name:  "multiple sources",
+			specs: []string{"atxs=" + atxs, "ballots=" + atxs, "rewards=" + state},
+			paths: map[string]string{TableAtxs: atxs, TableBallots: atxs, TableRewards: state},
+		},
+		{name: "duplicate table", specs: []string{"atxs=" + atxs, "atxs=" + state}, err: "duplicate table"},
+		{name: "missing path", specs: []string{"atxs="}, err: "expected <table>=<path>"},
+		{name: "missing table", specs: []string{atxs}, err: "expected <table>=<path>"},
+		{name: "unknown table", specs: []string{"proposals=" + atxs}, err: "unknown table"},
+		{name: "missing file", specs: []string{"atxs=" + filepath.Join(dir, "missing.sql")}, err: "open sqlite source"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sources, err := SetupSources(tc.specs)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, sources, len(tc.paths))
+			byPath := make(map[string]*sql.Database)
+			for table, path := range tc.paths {
+				require.Contains(t, sources, table)
+				if db, ok := byPath[path]; ok {
+					require.Same(t, db, sources[table])
+				}
+				byPath[path] = sources[table]
+			}
+			for _, db := range byPath {
+				require.NoError(t, db.Close())
+			}
+		})
+	}
+}
+
+func TestClientSource(t *testing.T) {
+	db, atxs := sql.InMemory(), sql.InMemory()
+	defer db.Close()
+	defer atxs.Close()
+
+	c := &Client{Sources: map[string]*sql.Database{TableAtxs: atxs}}
+	require.Same(t, atxs, c.source(db, TableAtxs))
+	// the tables without a source are read from the node database
+	require.Same(t, db, c.source(db, TableBallots))
+	require.Same(t, db, (&Client{}).source(db, TableAtxs))
+}
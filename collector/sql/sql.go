@@ -5,6 +5,7 @@ import (
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
 )
 
 type DatabaseClient interface {
@@ -17,9 +18,18 @@ type DatabaseClient interface {
 	CountAtxsByEpoch(db *sql.Database, epoch int64) (int, error)
 	GetAtxsByEpochPaginated(db *sql.Database, epoch, limit, offset int64, fn func(tx *types.VerifiedActivationTx) bool) error
 	GetAtxById(db *sql.Database, id string) (*types.VerifiedActivationTx, error)
+	GetLayerCertificates(db *sql.Database, lid types.LayerID) ([]certificates.CertValidity, error)
+	GetLayerBallots(db *sql.Database, lid types.LayerID) ([]*types.Ballot, error)
+	GetEpochActiveSetSize(db *sql.Database, epoch types.EpochID) (int, error)
+	GetEpochBeacon(db *sql.Database, epoch types.EpochID) (types.Beacon, error)
+	GetLayerTransactionsReceived(db *sql.Database, lid types.LayerID) (map[types.TransactionID]int64, error)
 }
 
-type Client struct{}
+// Client reads node data from sqlite. Queries go to the database passed to each method unless
+// the table they read has its own source in Sources, as newer nodes keep some tables in separate files.
+type Client struct {
+	Sources map[string]*sql.Database
+}
 
 func Setup(path string) (db *sql.Database, err error) {
 	db, err = sql.Open(fmt.Sprintf("file:%s?mode=ro", path),
@@ -0,0 +1,25 @@
+package sql
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql"
+)
+
+// GetLayerTransactionsReceived returns the time the node first received each transaction applied in
+// the layer. The node keeps the time of the first sighting when the transaction is received again.
+func (c *Client) GetLayerTransactionsReceived(db *sql.Database, lid types.LayerID) (map[types.TransactionID]int64, error) {
+	received := make(map[types.TransactionID]int64)
+	_, err := c.source(db, TableTransactions).Exec("select id, timestamp from transactions where layer = ?1;",
+		func(stmt *sql.Statement) {
+			stmt.BindInt64(1, int64(lid))
+		}, func(stmt *sql.Statement) bool {
+			var id types.TransactionID
+			stmt.ColumnBytes(0, id[:])
+			received[id] = stmt.ColumnInt64(1)
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+	return received, nil
+}
@@ -0,0 +1,296 @@
+package collector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/pipeline"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// The layers are ingested in three stages connected by bounded queues: the fetch stage reads the
+// layer data from the node database in the caller, the decode stage converts it to the storage
+// models and the store stage hands it to the listener. A slow stage fills the queue feeding it,
+// which blocks the stage before it up to the layer worker, so that the layers waiting in memory
+// are bounded by the sizes of the queues.
+const (
+	decodeQueueSize = 8
+	storeQueueSize  = 8
+)
+
+// fetchedLayer is the data of a layer read from the node database.
+type fetchedLayer struct {
+	layer    *pb.Layer
+	fetched  time.Time
+	received map[types.TransactionID]int64
+	accounts []*types.Account
+	rewards  []*types.Reward
+	certs    []certificates.CertValidity
+	ballots  []*types.Ballot
+	// activeSet and beacon are set for the first layer of an epoch only.
+	activeSet *uint32
+	beacon    *types.Beacon
+}
+
+// decodedLayer is the data of a layer converted to the storage models.
+type decodedLayer struct {
+	layer     *pb.Layer
+	fetched   time.Time
+	received  map[string]uint32
+	accounts  []*types.Account
+	rewards   []*pb.Reward
+	certs     []*model.BlockCertificate
+	ballots   []*model.Ballot
+	activeSet *uint32
+	beacon    string
+}
+
+// layerStages are the queues of the decode and store stages, and the layers in any of them.
+type layerStages struct {
+	decode *pipeline.Queue[*fetchedLayer]
+	store  *pipeline.Queue[*decodedLayer]
+
+	lock    sync.Mutex
+	pending map[uint32]int
+	count   int
+}
+
+func newLayerStages() *layerStages {
+	return &layerStages{
+		decode:  pipeline.NewQueue[*fetchedLayer](pipeline.StageDecodeLayer, decodeQueueSize),
+		store:   pipeline.NewQueue[*decodedLayer](pipeline.StageStoreLayer, storeQueueSize),
+		pending: make(map[uint32]int),
+	}
+}
+
+// add records the layer entering the stages.
+func (s *layerStages) add(number uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending[number]++
+	s.count++
+}
+
+// done removes a copy of the layer from the stages.
+func (s *layerStages) done(number uint32) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pending[number] <= 1 {
+		delete(s.pending, number)
+	} else {
+		s.pending[number]--
+	}
+	s.count--
+}
+
+// has reports whether the layer is in the stages.
+func (s *layerStages) has(number uint32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pending[number] > 0
+}
+
+// len returns the number of layers in the stages.
+func (s *layerStages) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.count
+}
+
+// isLayerPending reports whether the layer is in the stages or in the queue of the listener.
+func (c *Collector) isLayerPending(layer *pb.Layer) bool {
+	return c.stages.has(layer.Number.Number) || c.listener.IsLayerInQueue(layer)
+}
+
+// ingestLayer fetches the layer with its accounts, rewards, certificates and ballots, and queues it
+// for decoding. It blocks while the decode queue is full, the stages must be started, see
+// startStages.
+func (c *Collector) ingestLayer(layer *pb.Layer) {
+	c.stages.add(layer.Number.Number)
+	c.stages.decode.Push(c.fetchLayer(layer))
+}
+
+// fetchLayer reads the data of the layer from the node database, the data failing to be read is
+// logged and left empty.
+func (c *Collector) fetchLayer(layer *pb.Layer) *fetchedLayer {
+	lid := types.LayerID(layer.Number.Number)
+	fields := c.layerFields(layer)
+	fetched := &fetchedLayer{layer: layer, fetched: time.Now()}
+	var err error
+
+	fetched.received, err = c.dbClient.GetLayerTransactionsReceived(c.db, lid)
+	if err != nil {
+		logging.Error("cannot get the received times of the transactions", err, fields...)
+	}
+
+	start := time.Now()
+	fetched.accounts, err = c.dbClient.AccountsSnapshot(c.db, lid)
+	if err != nil {
+		logging.Error("cannot get the accounts", err, fields...)
+	}
+	pipeline.Observe(pipeline.StageFetchAccounts, start)
+
+	start = time.Now()
+	fetched.rewards, err = c.dbClient.GetLayerRewards(c.db, lid)
+	if err != nil {
+		logging.Error("cannot get the rewards", err, fields...)
+	}
+	pipeline.Observe(pipeline.StageFetchRewards, start)
+
+	fetched.certs, err = c.dbClient.GetLayerCertificates(c.db, lid)
+	if err != nil {
+		logging.Error("cannot get the certificates", err, fields...)
+	}
+
+	fetched.ballots, err = c.dbClient.GetLayerBallots(c.db, lid)
+	if err != nil {
+		logging.Error("cannot get the ballots", err, fields...)
+	}
+
+	if epochNumLayers := c.listener.GetEpochNumLayers(); epochNumLayers > 0 && layer.Number.Number%epochNumLayers == 0 {
+		epoch := types.EpochID(layer.Number.Number / epochNumLayers)
+		if size, ok := c.fetchActiveSet(epoch); ok {
+			fetched.activeSet = &size
+		}
+		if beacon, ok := c.fetchBeacon(epoch); ok {
+			fetched.beacon = &beacon
+		}
+	}
+	return fetched
+}
+
+// startStages runs the decode and store stages until ctx is done. They then drain their queues, the
+// store stage once the decode stage has returned, and the returned function waits for them. The
+// layers must not be ingested anymore once ctx is done.
+func (c *Collector) startStages(ctx context.Context) (wait func()) {
+	storeCtx, stopStore := context.WithCancel(context.WithoutCancel(ctx))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer stopStore()
+		c.decodeLayers(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		c.storeLayers(storeCtx)
+	}()
+	return wg.Wait
+}
+
+// decodeLayers is the decode stage, it converts the fetched layers and queues them for storing.
+func (c *Collector) decodeLayers(ctx context.Context) {
+	for {
+		fetched, ok := c.stages.decode.Pop(ctx)
+		if !ok {
+			return
+		}
+		start := time.Now()
+		decoded := c.decodeLayer(fetched)
+		pipeline.Observe(pipeline.StageDecodeLayer, start)
+		c.stages.store.Push(decoded)
+	}
+}
+
+func (c *Collector) decodeLayer(in *fetchedLayer) *decodedLayer {
+	lid := types.LayerID(in.layer.Number.Number)
+	out := &decodedLayer{
+		layer:     in.layer,
+		fetched:   in.fetched,
+		received:  make(map[string]uint32, len(in.received)),
+		accounts:  in.accounts,
+		rewards:   make([]*pb.Reward, 0, len(in.rewards)),
+		certs:     make([]*model.BlockCertificate, 0, len(in.certs)),
+		ballots:   make([]*model.Ballot, 0, len(in.ballots)),
+		activeSet: in.activeSet,
+	}
+	for id, timestamp := range in.received {
+		out.received[utils.BytesToHex(id.Bytes())] = uint32(time.Unix(0, timestamp).Unix())
+	}
+	for _, reward := range in.rewards {
+		out.rewards = append(out.rewards, &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: reward.Layer.Uint32()},
+			Total:       &pb.Amount{Value: reward.TotalReward},
+			LayerReward: &pb.Amount{Value: reward.LayerReward},
+			Coinbase:    &pb.AccountId{Address: reward.Coinbase.String()},
+			Smesher:     &pb.SmesherId{Id: reward.SmesherID.Bytes()},
+		})
+	}
+	for _, cert := range in.certs {
+		out.certs = append(out.certs, model.NewBlockCertificate(lid, cert))
+	}
+	for _, ballot := range in.ballots {
+		out.ballots = append(out.ballots, model.NewBallot(ballot, c.listener.GetEpochNumLayers()))
+	}
+	if in.beacon != nil {
+		out.beacon = in.beacon.String()
+	}
+	return out
+}
+
+// storeLayers is the store stage, it hands the decoded layers to the listener.
+func (c *Collector) storeLayers(ctx context.Context) {
+	for {
+		layer, ok := c.stages.store.Pop(ctx)
+		if !ok {
+			return
+		}
+		start := time.Now()
+		c.storeLayer(layer)
+		pipeline.Observe(pipeline.StageStoreLayer, start)
+		c.stages.done(layer.layer.Number.Number)
+	}
+}
+
+func (c *Collector) storeLayer(in *decodedLayer) {
+	number := in.layer.Number.Number
+	// the received times are passed first, to be stored with the transactions of the layer
+	c.listener.OnTransactionsReceived(in.received)
+	c.listener.OnLayer(in.layer)
+	c.listener.OnAccounts(in.accounts)
+	c.listener.OnRewards(in.rewards)
+	c.listener.OnCertificates(in.certs)
+	c.listener.OnBallots(in.ballots)
+
+	if epochNumLayers := c.listener.GetEpochNumLayers(); epochNumLayers > 0 {
+		epoch := number / epochNumLayers
+		if in.activeSet != nil {
+			logging.Info("active set", logging.Epoch(epoch), zap.Uint32("identities", *in.activeSet))
+			c.listener.OnActiveSet(epoch, *in.activeSet)
+		}
+		if in.beacon != "" {
+			c.listener.OnBeacon(epoch, in.beacon)
+		}
+	}
+
+	c.listener.UpdateEpochStats(number)
+	// the future epoch follows the last stored layer. OnLayer may only queue the layer, so it can be
+	// the previous one, and the future epoch is then created with the next layer
+	if err := c.createFutureEpoch(); err != nil {
+		logging.Error("cannot create the future epoch", err, c.layerFields(in.layer)...)
+	}
+	logging.Info("layer ingested", append(c.layerFields(in.layer),
+		zap.Int("accounts", len(in.accounts)),
+		zap.Int("rewards", len(in.rewards)),
+		zap.Int("certificates", len(in.certs)),
+		zap.Int("ballots", len(in.ballots)),
+		logging.Duration(time.Since(in.fetched)),
+	)...)
+}
+
+// layerFields are the logging fields of the layer.
+func (c *Collector) layerFields(layer *pb.Layer) []zap.Field {
+	fields := []zap.Field{logging.Layer(layer.Number.Number)}
+	if epochNumLayers := c.listener.GetEpochNumLayers(); epochNumLayers > 0 {
+		fields = append(fields, logging.Epoch(layer.Number.Number/epochNumLayers))
+	}
+	return fields
+}
@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	sql2 "github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/collector/sql"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// emptyLayersClient returns layers without data, the other methods of DatabaseClient are not
+// implemented.
+type emptyLayersClient struct {
+	sql.DatabaseClient
+}
+
+func (emptyLayersClient) GetLayerTransactionsReceived(*sql2.Database, types.LayerID) (map[types.TransactionID]int64, error) {
+	return nil, nil
+}
+
+func (emptyLayersClient) AccountsSnapshot(*sql2.Database, types.LayerID) ([]*types.Account, error) {
+	return nil, nil
+}
+
+func (emptyLayersClient) GetLayerRewards(*sql2.Database, types.LayerID) ([]*types.Reward, error) {
+	return nil, nil
+}
+
+func (emptyLayersClient) GetLayerCertificates(*sql2.Database, types.LayerID) ([]certificates.CertValidity, error) {
+	return nil, nil
+}
+
+func (emptyLayersClient) GetLayerBallots(*sql2.Database, types.LayerID) ([]*types.Ballot, error) {
+	return nil, nil
+}
+
+// slowListener stores the layers once they are released, the other methods of Listener are not
+// implemented.
+type slowListener struct {
+	Listener
+	release chan struct{}
+	stored  []uint32
+}
+
+func (l *slowListener) OnTransactionsReceived(map[string]uint32) {}
+
+func (l *slowListener) OnLayer(layer *pb.Layer) {
+	<-l.release
+	l.stored = append(l.stored, layer.Number.Number)
+}
+
+func (l *slowListener) OnAccounts([]*types.Account) {}
+
+func (l *slowListener) OnRewards([]*pb.Reward) {}
+
+func (l *slowListener) OnCertificates([]*model.BlockCertificate) {}
+
+func (l *slowListener) OnBallots([]*model.Ballot) {}
+
+func (l *slowListener) GetEpochNumLayers() uint32 { return 0 }
+
+func (l *slowListener) UpdateEpochStats(uint32) {}
+
+func (l *slowListener) GetLastLayer(context.Context) (uint32, error) { return 0, nil }
+
+func (l *slowListener) LayersInQueue() int { return 0 }
+
+func TestLayerStagesBackpressure(t *testing.T) {
+	listener := &slowListener{release: make(chan struct{})}
+	c := NewCollector("", "", false, 0, false, listener, nil, emptyLayersClient{}, false)
+	stagesCtx, stopStages := context.WithCancel(context.Background())
+	wait := c.startStages(stagesCtx)
+
+	const layers = 30
+	var ingested atomic.Int32
+	go func() {
+		for i := uint32(0); i < layers; i++ {
+			c.ingestLayer(&pb.Layer{Number: &pb.LayerNumber{Number: i}})
+			ingested.Add(1)
+		}
+	}()
+
+	// the store stage holds a layer, the decode stage another one waiting for room in the store
+	// queue, and the fetch stage blocks once both queues are full
+	held := int32(decodeQueueSize + storeQueueSize + 2)
+	require.Eventually(t, func() bool { return ingested.Load() == held }, time.Second, time.Millisecond)
+	require.Never(t, func() bool { return ingested.Load() > held }, 100*time.Millisecond, time.Millisecond)
+	require.True(t, c.isLayerPending(&pb.Layer{Number: &pb.LayerNumber{Number: 0}}))
+
+	close(listener.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Eventually(t, func() bool { return ingested.Load() == layers }, 5*time.Second, time.Millisecond)
+	require.NoError(t, c.waitLayersQueue(ctx, time.Millisecond))
+	require.Len(t, listener.stored, layers)
+	for i, number := range listener.stored {
+		require.Equal(t, uint32(i), number)
+	}
+
+	// the stages store the queued layers before they return
+	for i := uint32(layers); i < layers+decodeQueueSize; i++ {
+		c.ingestLayer(&pb.Layer{Number: &pb.LayerNumber{Number: i}})
+	}
+	stopStages()
+	wait()
+	require.Len(t, listener.stored, layers+decodeQueueSize)
+	require.Zero(t, c.stages.len())
+}
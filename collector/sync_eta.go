@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+const (
+	// syncRateWindow is the period used to compute the recent sync rate.
+	syncRateWindow = 5 * time.Minute
+	// syncProgressLogInterval is the number of layers between two progress log lines.
+	syncProgressLogInterval = 100
+)
+
+var (
+	metricSyncEta = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "explorer_sync_eta_seconds",
+		Help: "Estimated time remaining until the collector catches up with the node",
+	})
+	metricSyncRate = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "explorer_sync_layers_per_second",
+		Help: "Number of layers synced per second over the recent window",
+	})
+)
+
+// SyncStatus describes the collector sync progress.
+type SyncStatus struct {
+	LastLayer       uint32  `json:"lastLayer"`
+	NodeLayer       uint32  `json:"nodeLayer"`
+	LayersPerSecond float64 `json:"layersPerSecond"`
+	Eta             int64   `json:"eta"` // estimated seconds left, -1 if unknown
+	Synced          bool    `json:"synced"`
+}
+
+type syncSample struct {
+	at    time.Time
+	layer uint32
+}
+
+// syncProgress keeps recently synced layers to estimate the time left for sync.
+type syncProgress struct {
+	mu      sync.Mutex
+	samples []syncSample
+	target  uint32
+	// started is when the collector started, the progress of a collector which has not synced a
+	// layer yet is measured from it.
+	started time.Time
+}
+
+func (p *syncProgress) setTarget(layer uint32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.target = layer
+}
+
+func (p *syncProgress) record(layer uint32, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.samples = append(p.samples, syncSample{at: at, layer: layer})
+	i := 0
+	for i < len(p.samples)-1 && at.Sub(p.samples[i].at) > syncRateWindow {
+		i++
+	}
+	p.samples = p.samples[i:]
+}
+
+func (p *syncProgress) status() SyncStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := SyncStatus{NodeLayer: p.target, Eta: -1}
+	if len(p.samples) == 0 {
+		return st
+	}
+	first, last := p.samples[0], p.samples[len(p.samples)-1]
+	st.LastLayer = last.layer
+	if st.LastLayer >= st.NodeLayer {
+		st.Synced = true
+		st.Eta = 0
+		return st
+	}
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 || last.layer <= first.layer {
+		return st
+	}
+	st.LayersPerSecond = float64(last.layer-first.layer) / elapsed
+	st.Eta = int64(float64(st.NodeLayer-st.LastLayer) / st.LayersPerSecond)
+	return st
+}
+
+// stalled tells if the node is ahead and no layer was synced during the timeout before now.
+func (p *syncProgress) stalled(timeout time.Duration, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last := syncSample{at: p.started}
+	if len(p.samples) > 0 {
+		last = p.samples[len(p.samples)-1]
+	}
+	return last.layer < p.target && now.Sub(last.at) > timeout
+}
+
+// SyncStatus returns the current sync progress of the collector.
+func (c *Collector) SyncStatus() SyncStatus {
+	return c.progress.status()
+}
+
+func (c *Collector) reportSyncProgress(layer uint32) {
+	c.progress.record(layer, time.Now())
+	st := c.progress.status()
+	metricSyncRate.Set(st.LayersPerSecond)
+	if st.Eta >= 0 {
+		metricSyncEta.Set(float64(st.Eta))
+	}
+	if st.Synced || layer%syncProgressLogInterval != 0 {
+		return
+	}
+	if st.Eta < 0 {
+		logging.Info("sync progress", logging.Layer(st.LastLayer), zap.Uint32("node_layer", st.NodeLayer))
+		return
+	}
+	logging.Info("sync progress", logging.Layer(st.LastLayer), zap.Uint32("node_layer", st.NodeLayer),
+		zap.Float64("layers_per_second", st.LayersPerSecond), zap.Duration("eta", time.Duration(st.Eta)*time.Second))
+}
+
+// Stalled tells if the collector is behind the node and has not synced a layer during the timeout,
+// a collector waiting for the next layer of a synced node is not stalled.
+func (c *Collector) Stalled(timeout time.Duration) bool {
+	return c.progress.stalled(timeout, time.Now())
+}
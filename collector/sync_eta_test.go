@@ -0,0 +1,48 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncProgress(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	p := &syncProgress{started: start}
+	require.Equal(t, SyncStatus{Eta: -1}, p.status())
+
+	p.setTarget(1000)
+	p.record(100, start)
+	p.record(110, start.Add(10*time.Second))
+	p.record(120, start.Add(20*time.Second))
+	require.Equal(t, SyncStatus{LastLayer: 120, NodeLayer: 1000, LayersPerSecond: 1, Eta: 880}, p.status())
+
+	// the samples older than the window are dropped from the rate
+	p.record(130, start.Add(syncRateWindow+20*time.Second))
+	st := p.status()
+	require.Equal(t, uint32(130), st.LastLayer)
+	require.InDelta(t, 10.0/float64(syncRateWindow/time.Second), st.LayersPerSecond, 1e-9)
+
+	p.record(1000, start.Add(syncRateWindow+30*time.Second))
+	require.Equal(t, SyncStatus{LastLayer: 1000, NodeLayer: 1000, Eta: 0, Synced: true}, p.status())
+}
+
+func TestSyncProgressStalled(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	p := &syncProgress{started: start}
+	// a collector which has not heard of the node is not stalled
+	require.False(t, p.stalled(time.Minute, start.Add(time.Hour)))
+
+	p.setTarget(100)
+	require.False(t, p.stalled(time.Minute, start.Add(30*time.Second)))
+	require.True(t, p.stalled(time.Minute, start.Add(2*time.Minute)))
+
+	p.record(50, start.Add(2*time.Minute))
+	require.False(t, p.stalled(time.Minute, start.Add(150*time.Second)))
+	require.True(t, p.stalled(time.Minute, start.Add(4*time.Minute)))
+
+	// a synced collector waits for the next layer of the node
+	p.record(100, start.Add(4*time.Minute))
+	require.False(t, p.stalled(time.Minute, start.Add(time.Hour)))
+}
@@ -2,31 +2,36 @@ package collector
 
 import (
 	"context"
-	"fmt"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"io"
-)
-
-func (c *Collector) transactionsPump() error {
-	lastLayer := c.listener.GetLastLayer(context.Background())
 
-	req := pb.TransactionResultsRequest{
-		Start: lastLayer - 500,
-		Watch: true,
-	}
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
 
-	log.Info("Start transactions pump")
+func (c *Collector) transactionsPump(ctx context.Context) error {
+	logging.Info("start transactions pump")
 	defer func() {
 		c.notify <- -streamType_transactions
-		log.Info("Stop transactions pump")
+		logging.Info("stop transactions pump")
 	}()
 
 	c.notify <- +streamType_transactions
 
-	stream, err := c.transactionsClient.StreamResults(context.Background(), &req)
+	// read once the pump is notified, so that Run sees it stop when the read fails
+	lastLayer, err := c.listener.GetLastLayer(ctx)
 	if err != nil {
-		log.Err(fmt.Errorf("cannot get transactions stream results: %v", err))
+		logging.Error("cannot get the last stored layer", err)
+		return err
+	}
+
+	req := pb.TransactionResultsRequest{
+		Start: lastLayer - 500,
+		Watch: true,
+	}
+
+	stream, err := c.transactionsClient.StreamResults(ctx, &req)
+	if err != nil {
+		logging.Error("cannot get transactions stream results", err)
 		return err
 	}
 
@@ -35,20 +40,27 @@ func (c *Collector) transactionsPump() error {
 		if err == io.EOF {
 			return err
 		}
+		if ctx.Err() != nil {
+			// stopped by Run
+			return ctx.Err()
+		}
 		if err != nil {
-			log.Err(fmt.Errorf("cannot receive transaction result: %v", err))
+			logging.Error("cannot receive transaction result", err)
 			return err
 		}
 		if response == nil {
 			continue
 		}
 
-		state, err := c.transactionsClient.TransactionsState(context.TODO(), &pb.TransactionsStateRequest{
+		ctx, cancel := c.callContext()
+		state, err := c.transactionsClient.TransactionsState(ctx, &pb.TransactionsStateRequest{
 			TransactionId:       []*pb.TransactionId{{Id: response.Tx.Id}},
 			IncludeTransactions: false,
 		})
+		cancel()
 		if err != nil {
-			log.Err(fmt.Errorf("cannot receive transaction state: %v", err))
+			err = c.grpcError(err)
+			logging.Error("cannot receive transaction state", err)
 			return err
 		}
 
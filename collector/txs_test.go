@@ -2,16 +2,25 @@ package collector_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/spacemeshos/go-spacemesh/sql"
 	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/collector"
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+	"github.com/spacemeshos/explorer-backend/test/testseed"
 )
 
 func TestTransactions(t *testing.T) {
 	t.Parallel()
-	txs, err := storageDB.GetTransactions(context.TODO(), &bson.D{})
+	txs, err := storageReader.GetTransactions(context.TODO(), &bson.D{})
 	require.NoError(t, err)
 	require.Equal(t, len(generator.Transactions), len(txs))
 	for _, tx := range txs {
@@ -25,6 +34,35 @@ func TestTransactions(t *testing.T) {
 		generatedTx.PublicKey = "" // we do not encode it to send tx, omit this.
 		generatedTx.Signature = "" // we generate sign on emulation of pb stream.
 		tx.Signature = ""          // we generate sign on emulation of pb stream.
-		require.Equal(t, *generatedTx, tx)
+		tx.Raw = nil               // the payload is checked through the decoded fields.
+		require.Equal(t, *generatedTx, *tx)
+	}
+}
+
+// lastLayerErrStorage fails the reads of the last stored layer.
+type lastLayerErrStorage struct {
+	*docstore.Storage
+}
+
+func (s lastLayerErrStorage) GetLastLayer(context.Context) (uint32, error) {
+	return 0, errors.New("primary stepped down")
+}
+
+func TestTransactionsPumpLastLayerError(t *testing.T) {
+	t.Parallel()
+	s := memory.New()
+	defer s.Close()
+	c := collector.NewCollector(fmt.Sprintf("localhost:%d", node.NodePort),
+		fmt.Sprintf("localhost:%d", privateNode.NodePort), false,
+		0, false, lastLayerErrStorage{s}, sql.InMemory(), &testseed.Client{SeedGen: generator}, false)
+
+	// the failing pump stops the other streams, so Run returns and is restarted
+	done := make(chan error, 1)
+	go func() { done <- c.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "primary stepped down")
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "Run did not return")
 	}
 }
@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+const (
+	// layerVerifyInterval is the period between two verification passes.
+	layerVerifyInterval = time.Minute
+	// layerVerifyBatch is the maximum number of layers requested from the node at once.
+	layerVerifyBatch = 100
+)
+
+var (
+	metricLayerHashMismatch = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "explorer_layer_hash_mismatch_total",
+		Help: "Number of stored layers whose hash differs from the hash reported by the node",
+	})
+	metricLayerVerifyGaps = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "explorer_layer_verify_gaps_total",
+		Help: "Number of layers skipped by the verification because the node did not return them",
+	})
+	metricLastVerifiedLayer = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "explorer_last_verified_layer",
+		Help: "Last layer whose stored hash matched the hash reported by the node",
+	})
+)
+
+// verifyLayers compares the hash of committed layers with the mesh hash reported by the node.
+// A diverging layer is re-ingested and verified again on the next pass; it is skipped after
+// layerSyncMaxAttempts re-ingests. The last verified layer is stored, so a restart resumes from it,
+// and the layers missing from the answer of the node are logged and skipped.
+func (c *Collector) verifyLayers() error {
+	if !c.verifyCheckpointSet {
+		layer, err := c.listener.GetLayerHashCheckpoint(context.TODO())
+		if err != nil {
+			return err
+		}
+		c.verifiedLayer = layer
+		c.verifyCheckpointSet = true
+		metricLastVerifiedLayer.Set(float64(layer))
+	}
+	defer c.saveVerifiedLayer(c.verifiedLayer)
+
+	lastLayer, err := c.listener.GetLastLayer(context.TODO())
+	if err != nil {
+		return fmt.Errorf("cannot get the last stored layer: %w", err)
+	}
+	for c.verifiedLayer < lastLayer {
+		end := c.verifiedLayer + layerVerifyBatch
+		if end > lastLayer {
+			end = lastLayer
+		}
+
+		ctx, cancel := c.callContext()
+		res, err := c.meshClient.LayersQuery(ctx, &pb.LayersQueryRequest{
+			StartLayer: &pb.LayerNumber{Number: c.verifiedLayer + 1},
+			EndLayer:   &pb.LayerNumber{Number: end},
+		})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("cannot query layers %d...%d: %w", c.verifiedLayer+1, end, c.grpcError(err))
+		}
+
+		for _, nodeLayer := range res.GetLayer() {
+			number := nodeLayer.GetNumber().GetNumber()
+			if number <= c.verifiedLayer || number > end {
+				continue
+			}
+			if len(nodeLayer.GetHash()) == 0 || types.BytesToHash(nodeLayer.GetHash()) == types.EmptyLayerHash {
+				// the node has not computed the mesh hash yet
+				return nil
+			}
+			if number > c.verifiedLayer+1 {
+				c.skipLayers(c.verifiedLayer+1, number-1)
+			}
+
+			stored, err := c.listener.GetLayerByNumber(context.TODO(), number)
+			if err != nil {
+				return fmt.Errorf("cannot get stored layer %d: %w", number, err)
+			}
+			nodeHash := utils.BytesToHex(nodeLayer.GetHash())
+			if stored.Hash != nodeHash {
+				metricLayerHashMismatch.Inc()
+				logging.Warn("layer hash mismatch", logging.Layer(number), zap.String("stored", stored.Hash), zap.String("node", nodeHash))
+				if c.reingest(number) {
+					return nil
+				}
+			}
+
+			c.verifiedLayer = number
+			metricLastVerifiedLayer.Set(float64(number))
+		}
+
+		if c.verifiedLayer < end {
+			// the node did not return all requested layers
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// skipLayers advances the verification past the layers the node did not return while it returned
+// a later one.
+func (c *Collector) skipLayers(from, to uint32) {
+	metricLayerVerifyGaps.Add(float64(to - from + 1))
+	logging.Warn("layers missing from the node, skipping their verification", logging.Layer(from), zap.Uint32("to", to))
+	c.verifiedLayer = to
+}
+
+// saveVerifiedLayer stores the last verified layer if it moved past the given one.
+func (c *Collector) saveVerifiedLayer(previous uint32) {
+	if c.verifiedLayer == previous {
+		return
+	}
+	if err := c.listener.SetLayerHashCheckpoint(context.TODO(), c.verifiedLayer); err != nil {
+		logging.Error("save verified layer", err, logging.Layer(c.verifiedLayer))
+	}
+}
+
+// reingest queues the layer again and reports whether it should be verified once more. Only the
+// re-ingests which ran count as attempts, not the checks while the layer is still pending.
+func (c *Collector) reingest(number uint32) bool {
+	if c.reingestedLayer != number {
+		c.reingestedLayer = number
+		c.reingestCount = 0
+	}
+	if c.reingestCount >= layerSyncMaxAttempts {
+		logging.Warn("layer still diverges after the re-ingests, skipping", logging.Layer(number), zap.Int("reingests", c.reingestCount))
+		return false
+	}
+
+	layer, err := c.dbClient.GetLayer(c.db, types.LayerID(number), c.listener.GetEpochNumLayers())
+	if err != nil {
+		logging.Error("cannot re-ingest layer", err, logging.Layer(number))
+		return true
+	}
+	if c.isLayerPending(layer) {
+		return true
+	}
+	c.reingestCount++
+	logging.Info("re-ingesting layer", logging.Layer(number), zap.Int("attempt", c.reingestCount))
+	c.ingestLayer(layer)
+	return true
+}
@@ -0,0 +1,128 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	sql2 "github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// verifyListener is the storage of the layer verification, the other methods of Listener are not
+// implemented.
+type verifyListener struct {
+	Listener
+	lastLayer  uint32
+	checkpoint uint32
+}
+
+func (l *verifyListener) GetLastLayer(context.Context) (uint32, error) {
+	return l.lastLayer, nil
+}
+
+func (l *verifyListener) GetLayerByNumber(_ context.Context, number uint32) (*model.Layer, error) {
+	return &model.Layer{Number: number, Hash: utils.BytesToHex(layerHash(number))}, nil
+}
+
+func (l *verifyListener) GetLayerHashCheckpoint(context.Context) (uint32, error) {
+	return l.checkpoint, nil
+}
+
+func (l *verifyListener) SetLayerHashCheckpoint(_ context.Context, layer uint32) error {
+	l.checkpoint = layer
+	return nil
+}
+
+func (l *verifyListener) GetEpochNumLayers() uint32 { return 0 }
+
+func (l *verifyListener) IsLayerInQueue(*pb.Layer) bool { return false }
+
+// reingestClient returns the layers to re-ingest without data.
+type reingestClient struct {
+	emptyLayersClient
+}
+
+func (reingestClient) GetLayer(_ *sql2.Database, lid types.LayerID, _ uint32) (*pb.Layer, error) {
+	return &pb.Layer{Number: &pb.LayerNumber{Number: lid.Uint32()}}, nil
+}
+
+// verifyMesh returns the layers of the node, except the missing ones.
+type verifyMesh struct {
+	pb.MeshServiceClient
+	missing  map[uint32]bool
+	requests []*pb.LayersQueryRequest
+}
+
+func (m *verifyMesh) LayersQuery(_ context.Context, in *pb.LayersQueryRequest, _ ...grpc.CallOption) (*pb.LayersQueryResponse, error) {
+	m.requests = append(m.requests, in)
+	res := &pb.LayersQueryResponse{}
+	for n := in.GetStartLayer().GetNumber(); n <= in.GetEndLayer().GetNumber(); n++ {
+		if !m.missing[n] {
+			res.Layer = append(res.Layer, &pb.Layer{Number: &pb.LayerNumber{Number: n}, Hash: layerHash(n)})
+		}
+	}
+	return res, nil
+}
+
+func layerHash(number uint32) []byte {
+	hash := make([]byte, 32)
+	hash[0] = byte(number)
+	return hash
+}
+
+func TestVerifyLayers(t *testing.T) {
+	listener := &verifyListener{lastLayer: 6, checkpoint: 2}
+	mesh := &verifyMesh{missing: map[uint32]bool{4: true}}
+	c := NewCollector("", "", false, 0, false, listener, nil, nil, false)
+	c.meshClient = mesh
+
+	gaps := testutil.ToFloat64(metricLayerVerifyGaps)
+	require.NoError(t, c.verifyLayers())
+	// resumed from the stored checkpoint and moved past the missing layer
+	require.Len(t, mesh.requests, 1)
+	require.Equal(t, uint32(3), mesh.requests[0].GetStartLayer().GetNumber())
+	require.Equal(t, uint32(6), mesh.requests[0].GetEndLayer().GetNumber())
+	require.Equal(t, uint32(6), listener.checkpoint)
+	require.Equal(t, gaps+1, testutil.ToFloat64(metricLayerVerifyGaps))
+	require.Equal(t, float64(6), testutil.ToFloat64(metricLastVerifiedLayer))
+
+	// a restart resumes from the stored checkpoint
+	mesh.requests = nil
+	c = NewCollector("", "", false, 0, false, listener, nil, nil, false)
+	c.meshClient = mesh
+	require.NoError(t, c.verifyLayers())
+	require.Empty(t, mesh.requests)
+
+	listener.lastLayer = 8
+	require.NoError(t, c.verifyLayers())
+	require.Len(t, mesh.requests, 1)
+	require.Equal(t, uint32(7), mesh.requests[0].GetStartLayer().GetNumber())
+	require.Equal(t, uint32(8), listener.checkpoint)
+}
+
+func TestReingestPendingLayer(t *testing.T) {
+	c := NewCollector("", "", false, 0, false, &verifyListener{}, nil, reingestClient{}, false)
+
+	// the checks while the layer is still in the stages are not attempts
+	c.stages.add(3)
+	for i := 0; i <= layerSyncMaxAttempts; i++ {
+		require.True(t, c.reingest(3))
+	}
+	require.Zero(t, c.reingestCount)
+
+	c.stages.done(3)
+	require.True(t, c.reingest(3))
+	require.Equal(t, 1, c.reingestCount)
+	require.True(t, c.stages.has(3))
+
+	// the re-ingested layer is pending in turn
+	require.True(t, c.reingest(3))
+	require.Equal(t, 1, c.reingestCount)
+}
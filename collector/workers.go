@@ -0,0 +1,175 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+const (
+	// atxSyncInterval is the period between two activation syncs when the worker is not woken up earlier.
+	atxSyncInterval = 30 * time.Second
+	// layerSyncInterval is the period between two layer syncs when the worker is not woken up earlier.
+	layerSyncInterval = time.Minute
+
+	workerMinBackoff = time.Second
+	workerMaxBackoff = 2 * time.Minute
+	// layerSyncMaxAttempts is the number of failed attempts after which a layer is skipped.
+	layerSyncMaxAttempts = 5
+)
+
+// worker runs a sync task on its own schedule, backing off exponentially while the task fails.
+type worker struct {
+	name     string
+	interval time.Duration
+	wake     chan struct{}
+	run      func() error
+}
+
+func newWorker(name string, interval time.Duration, run func() error) *worker {
+	return &worker{
+		name:     name,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+		run:      run,
+	}
+}
+
+// trigger asks the worker to run as soon as possible. It never blocks.
+func (w *worker) trigger() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// start runs the worker until ctx is cancelled.
+func (w *worker) start(ctx context.Context) error {
+	logging.Info("start worker", zap.String("worker", w.name))
+	defer logging.Info("stop worker", zap.String("worker", w.name))
+	backoff := workerMinBackoff
+	for {
+		err := w.run()
+		if err == nil {
+			backoff = workerMinBackoff
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-w.wake:
+			case <-time.After(w.interval):
+			}
+			continue
+		}
+
+		// wake-ups do not cut the backoff short, so a failing task is not retried on every node update
+		logging.Error("worker error, retrying", err, zap.String("worker", w.name), zap.Duration("backoff", backoff))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > workerMaxBackoff {
+			backoff = workerMaxBackoff
+		}
+	}
+}
+
+// setLayerTarget records the last layer verified by the node and wakes the workers up.
+func (c *Collector) setLayerTarget(layer uint32) {
+	c.layerTarget.Store(layer)
+	c.progress.setTarget(layer)
+	c.layerWorker.trigger()
+	if c.atxSyncFlag {
+		c.atxWorker.trigger()
+	}
+}
+
+// syncLayersToTarget syncs layers from the worker checkpoint up to the last layer verified by the node.
+// A failing layer is retried with backoff and skipped after layerSyncMaxAttempts attempts. The
+// skipped layers are recorded and retried on the next passes, see retrySkippedLayers.
+func (c *Collector) syncLayersToTarget() error {
+	if !c.layerCheckpointSet {
+		next, err := c.nextLayerToSync()
+		if err != nil {
+			return fmt.Errorf("cannot get the next layer to sync: %w", err)
+		}
+		c.nextLayer = next
+		c.layerCheckpointSet = true
+	}
+	c.retrySkippedLayers()
+
+	target := c.layerTarget.Load()
+	for ; c.nextLayer <= target; c.nextLayer++ {
+		if err := c.syncLayer(types.LayerID(c.nextLayer)); err != nil {
+			c.layerAttempts++
+			if c.layerAttempts < layerSyncMaxAttempts {
+				return fmt.Errorf("sync layer %d: %w", c.nextLayer, err)
+			}
+			logging.Error("cannot sync layer, skipping it", err, logging.Layer(c.nextLayer), zap.Int("attempts", c.layerAttempts))
+			c.skippedLayers = append(c.skippedLayers, c.nextLayer)
+		}
+		c.layerAttempts = 0
+
+		if err := c.syncNotProcessedTxs(); err != nil {
+			logging.Error("cannot sync the not processed transactions", err)
+		}
+	}
+
+	return nil
+}
+
+// retrySkippedLayers ingests the layers skipped by the previous passes, once per pass. The later
+// layers are stored already, so the skipped ones are ingested without checking the last stored
+// layer, and kept for the next pass if they still fail.
+func (c *Collector) retrySkippedLayers() {
+	skipped := c.skippedLayers[:0]
+	for _, number := range c.skippedLayers {
+		layer, err := c.dbClient.GetLayer(c.db, types.LayerID(number), c.listener.GetEpochNumLayers())
+		if err != nil {
+			logging.Error("cannot sync skipped layer", err, logging.Layer(number))
+			skipped = append(skipped, number)
+			continue
+		}
+		if !c.isLayerPending(layer) {
+			logging.Info("syncing skipped layer", logging.Layer(number))
+			c.ingestLayer(layer)
+		}
+	}
+	c.skippedLayers = skipped
+}
+
+// syncActivations stores activations received by the node after the worker checkpoint.
+func (c *Collector) syncActivations() error {
+	if c.atxCheckpoint == 0 {
+		c.atxCheckpoint = c.listener.GetLastActivationReceived()
+	}
+	logging.Info("syncing activations", zap.Int64("from", c.atxCheckpoint))
+
+	received := c.atxCheckpoint
+	var atxs []*model.Activation
+	err := c.dbClient.GetAtxsReceivedAfter(c.db, c.atxCheckpoint, func(atx *types.VerifiedActivationTx) bool {
+		activation := model.NewActivation(atx)
+		if activation.Received > received {
+			received = activation.Received
+		}
+		atxs = append(atxs, activation)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(atxs) > 0 {
+		c.listener.OnActivations(atxs)
+	}
+	c.atxCheckpoint = received
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	sql2 "github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/collector/sql"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestWorker(t *testing.T) {
+	runs := make(chan int, 10)
+	count := 0
+	w := newWorker("test", time.Hour, func() error {
+		count++
+		runs <- count
+		if count == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.start(ctx)
+	}()
+	require.Equal(t, 1, <-runs)
+
+	// the failed run is retried after the backoff, the wake-ups in between are coalesced into one run
+	start := time.Now()
+	w.trigger()
+	w.trigger()
+	require.Equal(t, 2, <-runs)
+	require.GreaterOrEqual(t, time.Since(start), workerMinBackoff/2)
+	require.Equal(t, 3, <-runs)
+
+	// a wake-up runs the worker before its interval
+	w.trigger()
+	require.Equal(t, 4, <-runs)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Empty(t, runs)
+}
+
+func TestWorkersIndependent(t *testing.T) {
+	release := make(chan struct{})
+	slow := newWorker("slow", time.Hour, func() error {
+		<-release
+		return nil
+	})
+	runs := make(chan struct{}, 10)
+	fast := newWorker("fast", time.Hour, func() error {
+		runs <- struct{}{}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go slow.start(ctx)
+	go fast.start(ctx)
+
+	// the fast worker keeps running while the slow one is stuck
+	<-runs
+	fast.trigger()
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("fast worker stalled by the slow one")
+	}
+	close(release)
+}
+
+// layersClient returns the layers of the node, except the failing ones. The other methods of
+// DatabaseClient are not implemented.
+type layersClient struct {
+	sql.DatabaseClient
+	failing map[uint32]bool
+}
+
+func (c *layersClient) GetLayer(_ *sql2.Database, lid types.LayerID, _ uint32) (*pb.Layer, error) {
+	if c.failing[lid.Uint32()] {
+		return nil, errors.New("failed")
+	}
+	return &pb.Layer{Number: &pb.LayerNumber{Number: lid.Uint32()}}, nil
+}
+
+// queueListener records the synced layers as queued, the other methods of Listener are not
+// implemented.
+type queueListener struct {
+	Listener
+	queued []uint32
+	// err fails the reads of the last stored layer
+	err error
+}
+
+func (l *queueListener) IsLayerInQueue(layer *pb.Layer) bool {
+	l.queued = append(l.queued, layer.Number.Number)
+	return true
+}
+
+func (l *queueListener) GetLastLayer(context.Context) (uint32, error) { return 0, l.err }
+
+func (l *queueListener) GetLayersCount(context.Context, *bson.D, ...*options.CountOptions) (int64, error) {
+	return 0, l.err
+}
+
+func (l *queueListener) GetEpochNumLayers() uint32 { return 0 }
+
+func (l *queueListener) GetTransactions(context.Context, *bson.D, ...*options.FindOptions) ([]model.Transaction, error) {
+	return nil, nil
+}
+
+func TestSyncLayersSkipped(t *testing.T) {
+	listener := &queueListener{}
+	client := &layersClient{failing: map[uint32]bool{2: true}}
+	c := NewCollector("", "", false, 1, false, listener, nil, client, false)
+	c.layerTarget.Store(3)
+
+	for i := 1; i < layerSyncMaxAttempts; i++ {
+		require.Error(t, c.syncLayersToTarget())
+	}
+	// the failing layer is skipped and recorded
+	require.NoError(t, c.syncLayersToTarget())
+	require.Equal(t, []uint32{1, 3}, listener.queued)
+	require.Equal(t, []uint32{2}, c.skippedLayers)
+	require.Equal(t, uint32(4), c.nextLayer)
+
+	// still failing, it is kept for the next pass
+	require.NoError(t, c.syncLayersToTarget())
+	require.Equal(t, []uint32{2}, c.skippedLayers)
+
+	// the next pass backfills it
+	delete(client.failing, 2)
+	require.NoError(t, c.syncLayersToTarget())
+	require.Equal(t, []uint32{1, 3, 2}, listener.queued)
+	require.Empty(t, c.skippedLayers)
+}
+
+func TestSyncLayersStorageError(t *testing.T) {
+	listener := &queueListener{err: errors.New("primary stepped down")}
+	c := NewCollector("", "", false, 5, false, listener, nil, &layersClient{}, false)
+	c.layerTarget.Store(6)
+
+	// the sync is not started from --syncFromLayer when the last stored layer cannot be read
+	require.ErrorIs(t, c.syncLayersToTarget(), listener.err)
+	require.False(t, c.layerCheckpointSet)
+	require.Empty(t, listener.queued)
+
+	listener.err = nil
+	require.NoError(t, c.syncLayersToTarget())
+	require.Equal(t, []uint32{5, 6}, listener.queued)
+}
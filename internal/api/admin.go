@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// StorageStatsHandler serves the size of every collection, see storagereader.Reader.GetStorageStats.
+// It is an admin endpoint, to be served with the metrics and not by the public API.
+func StorageStatsHandler(reader storagereader.StorageReader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats, err := reader.GetStorageStats(r.Context())
+		if err != nil {
+			logging.Error("storage stats", err)
+			http.Error(w, "error get storage stats", http.StatusInternalServerError)
+			return
+		}
+		storage.RecordStorageStats(stats)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			logging.Error("storage stats", err)
+		}
+	})
+}
+
+// WatchStorageStats refreshes the collection size metrics every interval until ctx is done.
+func WatchStorageStats(ctx context.Context, reader storagereader.StorageReader, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := reader.GetStorageStats(ctx)
+		if err != nil {
+			logging.Error("storage stats", err)
+		} else {
+			storage.RecordStorageStats(stats)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+)
+
+func TestStorageStatsHandler(t *testing.T) {
+	s := memory.New()
+	s.OnNetworkInfo("genesis", 1, 10, 10, 300, 1)
+	require.NoError(t, s.UpsertLabels(context.Background(), []*model.Label{
+		{Kind: model.LabelAccount, Id: "account", Name: "first"},
+		{Kind: model.LabelSmesher, Id: "smesher", Name: "second"},
+	}))
+
+	rec := httptest.NewRecorder()
+	StorageStatsHandler(memory.NewReader(s)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/storage", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats []*model.CollectionStats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+	require.Equal(t, []*model.CollectionStats{
+		{Collection: "labels", Documents: 2},
+		{Collection: "networkinfo", Documents: 1},
+		{Collection: "smesher_history", Documents: 1},
+	}, stats)
+}
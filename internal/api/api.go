@@ -2,18 +2,17 @@ package api
 
 import (
 	"context"
-	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/spacemeshos/explorer-backend/internal/api/handler"
 	"github.com/spacemeshos/explorer-backend/internal/api/router"
+	"github.com/spacemeshos/explorer-backend/internal/httpserver"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/internal/service"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 type Api struct {
@@ -48,10 +47,23 @@ func Init(appService service.AppService, allowedOrigins []string, debug bool) *A
 	}
 
 	e.Use(middleware.RequestLoggerWithConfig(middleware.RequestLoggerConfig{
-		LogStatus: true,
-		LogURI:    true,
+		LogStatus:  true,
+		LogURI:     true,
+		LogMethod:  true,
+		LogLatency: true,
+		LogError:   true,
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
-			log.Info("%s [%d] - %s", time.Now().Format(time.RFC3339), c.Response().Status, c.Request().URL.Path)
+			fields := []zap.Field{
+				zap.String("method", v.Method),
+				zap.String("path", c.Request().URL.Path),
+				zap.Int("status", v.Status),
+				logging.Duration(v.Latency),
+			}
+			if v.Error != nil && v.Status >= http.StatusInternalServerError {
+				logging.Error("request", v.Error, fields...)
+				return nil
+			}
+			logging.Info("request", fields...)
 			return nil
 		},
 	}))
@@ -68,22 +80,8 @@ func Init(appService service.AppService, allowedOrigins []string, debug bool) *A
 	}
 }
 
-func (a *Api) Run(address string) {
-	log.Info("server is running. For exit <CTRL-c>")
-	if err := a.Echo.Start(address); err != nil {
-		log.Err(fmt.Errorf("server stopped: %s", err))
-	}
-
-	sysSignal := make(chan os.Signal, 1)
-	signal.Notify(sysSignal, syscall.SIGINT, syscall.SIGTERM)
-
-	s := <-sysSignal
-	log.Info("exiting, got signal %v", s)
-	if err := a.Shutdown(); err != nil {
-		log.Err(fmt.Errorf("error on shutdown: %v", err))
-	}
-}
-
-func (a *Api) Shutdown() error {
-	return a.Echo.Shutdown(context.TODO())
+// Run serves the API on the address until ctx is done, then waits up to timeout for the requests
+// in flight, see httpserver.Serve.
+func (a *Api) Run(ctx context.Context, address string, timeout time.Duration) error {
+	return httpserver.Serve(ctx, "api", address, a.Echo, timeout)
 }
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+var (
+	metricTopCoinbasesSpaceShare = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_epoch_top_coinbases_space_share",
+		Help: "Part of the storage committed to the current epoch held by its top coinbases, from 0 to 1",
+	}, []string{"top"})
+	metricConcentrationEpoch = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "explorer_epoch_top_coinbases_epoch",
+		Help: "Epoch of the top coinbases space share metrics",
+	})
+)
+
+// ConcentrationTops are the numbers of top coinbases of the space share metrics.
+var ConcentrationTops = []int{1, 5, 10}
+
+// RecordConcentration sets the space share metrics of the top coinbases from the concentration of
+// an epoch, its top coinbases are sorted by space.
+func RecordConcentration(concentration *model.CoinbaseConcentration) {
+	metricConcentrationEpoch.Set(float64(concentration.Epoch))
+	for _, top := range ConcentrationTops {
+		var space int64
+		for _, coinbase := range concentration.Top[:min(top, len(concentration.Top))] {
+			space += coinbase.Space
+		}
+		share := 0.0
+		if concentration.Space > 0 {
+			share = float64(space) / float64(concentration.Space)
+		}
+		metricTopCoinbasesSpaceShare.WithLabelValues(strconv.Itoa(top)).Set(share)
+	}
+}
+
+// WatchConcentration refreshes the space share metrics of the top coinbases of the current epoch
+// every interval until ctx is done.
+func WatchConcentration(ctx context.Context, svc service.AppService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := refreshConcentration(ctx, svc); err != nil {
+			logging.Error("coinbase concentration", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func refreshConcentration(ctx context.Context, svc service.AppService) error {
+	_, epoch, _, err := svc.GetState(ctx)
+	if err != nil {
+		return err
+	}
+	if epoch == nil {
+		return nil
+	}
+	concentration, err := svc.GetCoinbaseConcentration(ctx, epoch.Number, slices.Max(ConcentrationTops))
+	if err != nil {
+		return err
+	}
+	RecordConcentration(concentration)
+	return nil
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+)
+
+func TestRefreshConcentration(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	s.OnNetworkInfo("genesis", 1, 10, 10, 300, 1)
+	for i := uint32(20); i <= 25; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	var atxs []*model.Activation
+	for i, units := range []uint32{40, 20, 10, 10, 5, 5, 4, 3, 2, 1, 0, 0} {
+		atxs = append(atxs, &model.Activation{
+			Id:                fmt.Sprintf("0x%02x", i),
+			SmesherId:         fmt.Sprintf("0x5%x", i),
+			Coinbase:          fmt.Sprintf("sm%d", i),
+			NumUnits:          units,
+			EffectiveNumUnits: units,
+			TargetEpoch:       2,
+		})
+	}
+	s.OnActivations(atxs)
+	s.UpdateEpochStats(25)
+
+	require.NoError(t, refreshConcentration(context.Background(), service.NewService(memory.NewReader(s), time.Second)))
+	require.EqualValues(t, 2, testutil.ToFloat64(metricConcentrationEpoch))
+	require.EqualValues(t, 0.4, testutil.ToFloat64(metricTopCoinbasesSpaceShare.WithLabelValues("1")))
+	require.EqualValues(t, 0.85, testutil.ToFloat64(metricTopCoinbasesSpaceShare.WithLabelValues("5")))
+	require.EqualValues(t, 1, testutil.ToFloat64(metricTopCoinbasesSpaceShare.WithLabelValues("10")))
+}
@@ -7,13 +7,26 @@ import (
 	"github.com/spacemeshos/explorer-backend/internal/service"
 	"github.com/spacemeshos/explorer-backend/model"
 	"net/http"
+	"strconv"
 )
 
 func Accounts(c echo.Context) error {
 	cc := c.(*ApiContext)
 
 	pageNum, pageSize := GetPagination(c)
-	accounts, total, err := cc.Service.GetAccounts(context.TODO(), pageNum, pageSize)
+	var (
+		accounts []*model.Account
+		total    int64
+	)
+	template, err := templateParam(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if template != "" {
+		accounts, total, err = cc.Service.GetTemplateAccounts(context.TODO(), template, pageNum, pageSize)
+	} else {
+		accounts, total, err = cc.Service.GetAccounts(context.TODO(), pageNum, pageSize)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get accounts list: %w", err)
 	}
@@ -38,6 +51,25 @@ func Account(c echo.Context) error {
 	return c.JSON(http.StatusOK, DataResponse{Data: []*model.Account{account}})
 }
 
+// AccountBalance serves the balance of the account after the layer of the `layer` query parameter.
+func AccountBalance(c echo.Context) error {
+	cc := c.(*ApiContext)
+
+	layer, err := strconv.ParseUint(c.QueryParam("layer"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid layer")
+	}
+	balance, err := cc.Service.GetAccountBalanceAt(context.TODO(), c.Param("id"), uint32(layer))
+	if err != nil {
+		if err == service.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		return fmt.Errorf("failed to get account `%s` balance at layer %d: %w", c.Param("id"), layer, err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: []*model.AccountSnapshot{balance}})
+}
+
 func AccountDetails(c echo.Context) error {
 	cc := c.(*ApiContext)
 	pageNum, pageSize := GetPagination(cc)
@@ -50,9 +82,11 @@ func AccountDetails(c echo.Context) error {
 
 	switch c.Param("entity") {
 	case txs:
-		response, total, err = cc.Service.GetAccountTransactions(context.TODO(), accountID, pageNum, pageSize)
+		response, total, err = cc.Service.GetAccountTransactions(queryContext(c), accountID, pageNum, pageSize)
 	case rewards:
 		response, total, err = cc.Service.GetAccountRewards(context.TODO(), accountID, pageNum, pageSize)
+	case smeshers:
+		response, total, err = cc.Service.GetAccountSmeshers(context.TODO(), accountID, pageNum, pageSize)
 	default:
 		return echo.NewHTTPError(http.StatusNotFound, "entity not found")
 	}
@@ -1,10 +1,13 @@
 package handler_test
 
 import (
+	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestAccounts(t *testing.T) { // accounts
@@ -85,3 +88,42 @@ func TestAccountRewards(t *testing.T) { // /accounts/{id}/rewards
 		}
 	}
 }
+
+func TestTemplateAccounts(t *testing.T) { // /accounts?template={template}
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/accounts?template=wallet&pagesize=1000")
+	res.RequireOK(t)
+	var resp accountResp
+	res.RequireUnmarshal(t, &resp)
+	// the seeded accounts are spawned wallets
+	require.NotEmpty(t, resp.Data)
+	require.Len(t, resp.Data, len(generator.Accounts))
+	for _, acc := range resp.Data {
+		require.Equal(t, model.TemplateWallet, acc.Template)
+		require.Contains(t, generator.Accounts, strings.ToLower(acc.Address))
+	}
+	res = apiServer.Get(t, apiPrefix+"/accounts?template=multisig")
+	res.RequireOK(t)
+	var multisigResp accountResp
+	res.RequireUnmarshal(t, &multisigResp)
+	require.Empty(t, multisigResp.Data)
+
+	res = apiServer.Get(t, apiPrefix+"/accounts?template=unknown")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+func TestAccountBalance(t *testing.T) { // /accounts/{id}/balance?layer={layer}
+	t.Parallel()
+	for _, acc := range generator.Accounts {
+		res := apiServer.Get(t, apiPrefix+"/accounts/"+acc.Account.Address+"/balance?layer=1")
+		res.RequireOK(t)
+		var resp accountBalanceResp
+		res.RequireUnmarshal(t, &resp)
+		require.Equal(t, 1, len(resp.Data))
+		require.Equal(t, acc.Account.Address, resp.Data[0].Address)
+		require.EqualValues(t, 1, resp.Data[0].Layer)
+
+		res = apiServer.Get(t, apiPrefix+"/accounts/"+acc.Account.Address+"/balance?layer=latest")
+		require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	}
+}
@@ -36,3 +36,28 @@ func Activation(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, DataResponse{Data: []*model.Activation{atx}})
 }
+
+func ActivationDetails(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(cc)
+	var (
+		response interface{}
+		err      error
+		total    int64
+	)
+
+	switch c.Param("entity") {
+	case rewards:
+		response, total, err = cc.Service.GetActivationRewards(context.TODO(), c.Param("id"), pageNum, pageSize)
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, "entity not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get activation entity `%s` list: %w", c.Param("entity"), err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       response,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
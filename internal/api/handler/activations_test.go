@@ -40,3 +40,16 @@ func TestActivation(t *testing.T) { // /atxs/{id}
 		require.Equal(t, *generatedAtx, respLoop.Data[0])
 	}
 }
+
+func TestActivationRewards(t *testing.T) { // /atxs/{id}/rewards
+	t.Parallel()
+	for id := range generator.Epochs.GetActivations() {
+		res := apiServer.Get(t, apiPrefix+"/atxs/"+id+"/rewards?pagesize=1000")
+		res.RequireOK(t)
+		var resp rewardResp
+		res.RequireUnmarshal(t, &resp)
+		for _, reward := range resp.Data {
+			require.Equal(t, id, reward.Atx)
+		}
+	}
+}
@@ -15,6 +15,9 @@ func TestBlocks(t *testing.T) { // /blocks/{id}
 			var resp blockResp
 			res.RequireUnmarshal(t, &resp)
 			require.Equal(t, 1, len(resp.Data))
+			// the seeded blocks are all certified
+			require.Equal(t, generator.Certificates[block.Id], resp.Data[0].Certificate)
+			resp.Data[0].Certificate = nil
 			require.Equal(t, block, &resp.Data[0])
 		}
 	}
@@ -40,10 +40,37 @@ func Epoch(c echo.Context) error {
 		}
 		return fmt.Errorf("failed to get epoch info: %w", err)
 	}
+	if version := c.QueryParam("statsVersion"); version != "" {
+		if err := setStatsVersion(cc, epochs, version); err != nil {
+			return err
+		}
+	}
 
 	return c.JSON(http.StatusOK, DataResponse{Data: []*model.Epoch{epochs}})
 }
 
+// EpochComparison serves the stats of the epochs `a` and `b` side by side, with their changes.
+func EpochComparison(c echo.Context) error {
+	cc := c.(*ApiContext)
+	var epochs [2]int
+	for i, param := range []string{"a", "b"} {
+		epoch, err := strconv.Atoi(c.QueryParam(param))
+		if err != nil || epoch < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s", param))
+		}
+		epochs[i] = epoch
+	}
+	comparison, err := cc.Service.CompareEpochs(context.TODO(), epochs[0], epochs[1])
+	if err != nil {
+		if err == service.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		return fmt.Errorf("failed to compare epochs: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: comparison})
+}
+
 func EpochDetails(c echo.Context) error {
 	cc := c.(*ApiContext)
 	pageNum, pageSize := GetPagination(c)
@@ -60,13 +87,15 @@ func EpochDetails(c echo.Context) error {
 	case layers:
 		response, total, err = cc.Service.GetEpochLayers(context.TODO(), epochID, pageNum, pageSize)
 	case txs:
-		response, total, err = cc.Service.GetEpochTransactions(context.TODO(), epochID, pageNum, pageSize)
+		response, total, err = cc.Service.GetEpochTransactions(queryContext(c), epochID, pageNum, pageSize)
 	case smeshers:
 		response, total, err = cc.Service.GetEpochSmeshers(context.TODO(), epochID, pageNum, pageSize)
 	case rewards:
 		response, total, err = cc.Service.GetEpochRewards(context.TODO(), epochID, pageNum, pageSize)
 	case atxs:
 		response, total, err = cc.Service.GetEpochActivations(context.TODO(), epochID, pageNum, pageSize)
+	case history:
+		response, total, err = cc.Service.GetEpochStatsHistory(context.TODO(), epochID, pageNum, pageSize)
 	default:
 		return fiber.NewError(fiber.StatusNotFound, "entity not found")
 	}
@@ -79,3 +108,28 @@ func EpochDetails(c echo.Context) error {
 		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
 	})
 }
+
+// setStatsVersion replaces the stats of the epoch with the ones of the given version of the
+// formulas. The rewards are not versioned, they are summed by the collector.
+func setStatsVersion(cc *ApiContext, epoch *model.Epoch, version string) error {
+	v, err := strconv.ParseUint(version, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid statsVersion")
+	}
+	stats, err := cc.Service.GetEpochStats(context.TODO(), int(epoch.Number), uint32(v))
+	if err != nil {
+		if err == service.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		return fmt.Errorf("failed to get epoch stats: %w", err)
+	}
+	for _, st := range []struct{ from, to *model.Statistics }{
+		{&epoch.Stats.Current, &stats.Stats.Current},
+		{&epoch.Stats.Cumulative, &stats.Stats.Cumulative},
+	} {
+		st.to.Rewards, st.to.RewardsNumber = st.from.Rewards, st.from.RewardsNumber
+	}
+	epoch.Stats = stats.Stats
+	epoch.StatsVersion = stats.Version
+	return nil
+}
@@ -2,6 +2,7 @@ package handler_test
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"testing"
@@ -59,6 +60,33 @@ func TestEpochHandler(t *testing.T) {
 	}
 }
 
+type epochComparisonResp struct {
+	Data model.EpochComparison `json:"data"`
+}
+
+func TestEpochComparisonHandler(t *testing.T) { // /epochs/compare
+	t.Parallel()
+	var epochs [2]model.Epoch
+	for i, ep := range generator.Epochs[:2] {
+		res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/epochs/%d", ep.Epoch.Number))
+		res.RequireOK(t)
+		var resp epochResp
+		res.RequireUnmarshal(t, &resp)
+		epochs[i] = resp.Data[0]
+	}
+
+	res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/epochs/compare?a=%d&b=%d", epochs[0].Number, epochs[1].Number))
+	res.RequireOK(t)
+	var resp epochComparisonResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, *model.NewEpochComparison(&epochs[0], &epochs[1]), resp.Data)
+
+	res = apiServer.Get(t, apiPrefix+"/epochs/compare?a=1")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/epochs/compare?a=%d&b=100000", epochs[0].Number))
+	require.Equal(t, http.StatusNotFound, res.Res.StatusCode)
+}
+
 func TestEpochLayersHandler(t *testing.T) {
 	t.Parallel()
 	for _, ep := range generator.Epochs {
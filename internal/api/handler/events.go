@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/internal/storage/changestream"
+)
+
+// eventsKeepalive is the interval of the keepalive comments of the events stream, so that proxies
+// don't close idle streams.
+const eventsKeepalive = 30 * time.Second
+
+// subscribe subscribes to the kinds of the comma separated `kinds` query parameter, all if not set.
+func subscribe(c echo.Context) (*changestream.Subscription, error) {
+	cc := c.(*ApiContext)
+	var kinds []changestream.Kind
+	if param := c.QueryParam("kinds"); param != "" {
+		for _, kind := range strings.Split(param, ",") {
+			kinds = append(kinds, changestream.Kind(kind))
+		}
+	}
+	sub, err := cc.Service.Subscribe(kinds...)
+	if errors.Is(err, service.ErrEventsDisabled) {
+		return nil, echo.NewHTTPError(http.StatusNotImplemented, err.Error())
+	}
+	return sub, err
+}
+
+// Events streams the real-time events as server-sent events.
+func Events(c echo.Context) error {
+	sub, err := subscribe(c)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	ticker := time.NewTicker(eventsKeepalive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-ticker.C:
+			if _, err := fmt.Fprint(res, ": keepalive\n\n"); err != nil {
+				return nil
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				logging.Error("Events: encode event", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Kind, data); err != nil {
+				return nil
+			}
+		}
+		res.Flush()
+	}
+}
+
+// EventsWS streams the real-time events over a websocket.
+func EventsWS(c echo.Context) error {
+	sub, err := subscribe(c)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	ws, err := Upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		logging.Error("EventsWS: upgrade", err)
+		return nil
+	}
+	defer ws.Close()
+
+	// the client never sends messages, reading detects when it goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := ws.WriteJSON(event); err != nil {
+				return nil
+			}
+		}
+	}
+}
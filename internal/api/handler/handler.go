@@ -1,19 +1,25 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
 	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 const (
 	// list of items to search from GET request.
-	txs      = "txs"
-	atxs     = "atxs"
-	blocks   = "blocks"
-	layers   = "layers"
-	rewards  = "rewards"
-	smeshers = "smeshers"
+	txs           = "txs"
+	atxs          = "atxs"
+	blocks        = "blocks"
+	layers        = "layers"
+	rewards       = "rewards"
+	smeshers      = "smeshers"
+	history       = "history"
+	participation = "participation"
 )
 
 var Upgrader = websocket.Upgrader{}
@@ -34,4 +40,25 @@ type PaginatedDataResponse struct {
 
 type RedirectResponse struct {
 	Redirect string `json:"redirect"`
+	// Results are the entities found by name, the redirect is the best match.
+	Results []SearchResult `json:"results,omitempty"`
+}
+
+// queryContext returns the context of the service queries of the request, `?includeOrphaned=true`
+// includes the blocks and transactions orphaned by reorgs.
+func queryContext(c echo.Context) context.Context {
+	if c.QueryParam("includeOrphaned") == "true" {
+		return service.WithOrphaned(context.TODO())
+	}
+	return context.TODO()
+}
+
+// templateParam returns the template name of the `?template=` filter of accounts and transactions,
+// empty if it is not set.
+func templateParam(c echo.Context) (string, error) {
+	template := c.QueryParam("template")
+	if template != "" && !model.IsTemplate(template) {
+		return "", fmt.Errorf("unknown template `%s`", template)
+	}
+	return template, nil
 }
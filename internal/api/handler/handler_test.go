@@ -3,7 +3,6 @@ package handler_test
 import (
 	"context"
 	"fmt"
-	"github.com/spacemeshos/explorer-backend/model"
 	"os"
 	"testing"
 	"time"
@@ -12,7 +11,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+	"github.com/spacemeshos/explorer-backend/storage/memory"
 	"github.com/spacemeshos/explorer-backend/test/testseed"
 	"github.com/spacemeshos/explorer-backend/test/testserver"
 )
@@ -23,40 +25,36 @@ var (
 	apiServer *testserver.TestAPIService
 	generator *testseed.SeedGenerator
 	seed      *testseed.TestServerSeed
-	dbPort    = 27017
+	db        store
 )
 
+// store is a storage the handlers are tested against.
+type store interface {
+	storage.StorageWriter
+	testseed.Store
+}
+
 func TestMain(m *testing.M) {
-	mongoURL := fmt.Sprintf("mongodb://localhost:%d", dbPort)
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURL))
-	if err != nil {
-		fmt.Println("failed to connect to mongo", err)
-		os.Exit(1)
-	}
-	database := client.Database(testAPIServiceDB)
-	if database != nil {
-		if err = database.Drop(context.Background()); err != nil {
-			fmt.Println("failed to drop db", err)
+	seed = testseed.GetServerSeed()
+	// the suite runs on the memory storage, or on mongo at EXPLORER_TEST_MONGO_URL
+	var (
+		dbReader storagereader.StorageReader
+		err      error
+	)
+	mongoURL := os.Getenv("EXPLORER_TEST_MONGO_URL")
+	if mongoURL == "" {
+		s := memory.New()
+		db, dbReader = s, memory.NewReader(s)
+	} else {
+		db, dbReader, err = openMongo(context.Background(), mongoURL)
+		if err != nil {
+			fmt.Println("failed to init storage to mongo", err)
 			os.Exit(1)
 		}
 	}
-
-	db, err := storage.New(context.Background(), mongoURL, testAPIServiceDB)
-	if err != nil {
-		fmt.Println("failed to init storage to mongo", err)
-		os.Exit(1)
-	}
-	seed = testseed.GetServerSeed()
 	db.OnNetworkInfo(string(seed.GenesisID), seed.GenesisTime, seed.EpochNumLayers, seed.MaxTransactionPerSecond, seed.LayersDuration, seed.GetPostUnitsSize())
 
-	dbReader, err := storagereader.NewStorageReader(context.Background(), mongoURL, testAPIServiceDB)
-	if err != nil {
-		fmt.Println("failed to init storage to mongo", err)
-		os.Exit(1)
-	}
-
 	apiServer, err = testserver.StartTestAPIServiceV2(db, dbReader)
-	// old version of app here apiServer, err = testserver.StartTestAPIService(dbPort, db)
 	if err != nil {
 		fmt.Println("failed to start test api service", err)
 		os.Exit(1)
@@ -66,18 +64,58 @@ func TestMain(m *testing.M) {
 		fmt.Println("failed to generate epochs", err)
 		os.Exit(1)
 	}
+	if err = generator.GenerateVault(); err != nil {
+		fmt.Println("failed to generate vault", err)
+		os.Exit(1)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 	if err = generator.SaveEpoches(ctx, db); err != nil {
 		fmt.Println("failed to save generated epochs", err)
 		os.Exit(1)
 	}
+	// the seeded layers are not ingested, the last one is recorded as the collector does on a layer
+	lastLayer, _, _ := generator.GetLastLayer()
+	switch s := db.(type) {
+	case *storage.Storage:
+		s.NetworkInfo.LastLayer = lastLayer
+		s.SetRollups(time.Hour)
+	case *docstore.Storage:
+		s.NetworkInfo.LastLayer = lastLayer
+	}
+	db.OnNodeStatus(0, false, 0, 0, 0)
 
 	code := m.Run()
 	db.Close()
 	os.Exit(code)
 }
 
+// openMongo returns a migrated storage on the empty test database and its reader.
+func openMongo(ctx context.Context, mongoURL string) (*storage.Storage, *storagereader.Reader, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.Disconnect(ctx)
+	if err = client.Database(testAPIServiceDB).Drop(ctx); err != nil {
+		return nil, nil, err
+	}
+	db, err := storage.New(ctx, mongoURL, testAPIServiceDB, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = db.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	dbReader, err := storagereader.NewStorageReader(ctx, mongoURL, testAPIServiceDB, "")
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return db, dbReader, nil
+}
+
 type layerResp struct {
 	Data       []model.Layer `json:"data"`
 	Pagination pagination    `json:"pagination"`
@@ -108,10 +146,24 @@ type accountResp struct {
 	Pagination pagination      `json:"pagination"`
 }
 
+type accountBalanceResp struct {
+	Data []model.AccountSnapshot `json:"data"`
+}
+
 type atxResp struct {
 	Data       []model.Activation `json:"data"`
 	Pagination pagination         `json:"pagination"`
 }
+
+type participationResp struct {
+	Data       []model.SmesherParticipation `json:"data"`
+	Pagination pagination                   `json:"pagination"`
+}
+type smesherHistoryResp struct {
+	Data       []model.SmesherChange `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
 type blockResp struct {
 	Data       []model.Block `json:"data"`
 	Pagination pagination    `json:"pagination"`
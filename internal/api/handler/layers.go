@@ -59,9 +59,9 @@ func LayerDetails(c echo.Context) error {
 
 	switch c.Param("entity") {
 	case blocks:
-		response, total, err = cc.Service.GetLayerBlocks(context.TODO(), layerID, pageNum, pageSize)
+		response, total, err = cc.Service.GetLayerBlocks(queryContext(c), layerID, pageNum, pageSize)
 	case txs:
-		response, total, err = cc.Service.GetLayerTransactions(context.TODO(), layerID, pageNum, pageSize)
+		response, total, err = cc.Service.GetLayerTransactions(queryContext(c), layerID, pageNum, pageSize)
 	case smeshers:
 		response, total, err = cc.Service.GetLayerSmeshers(context.TODO(), layerID, pageNum, pageSize)
 	case rewards:
@@ -6,12 +6,13 @@ import (
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"net/http"
 	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
 func HealthzHandler(c echo.Context) error {
@@ -53,7 +54,7 @@ func NetworkInfo(c echo.Context) error {
 func NetworkInfoWS(c echo.Context) error {
 	ws, err := Upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
-		log.Err(fmt.Errorf("NetworkInfoWS: upgrade error: %w\n", err))
+		logging.Error("NetworkInfoWS: upgrade", err)
 		return nil
 	}
 	defer ws.Close()
@@ -64,7 +65,7 @@ func NetworkInfoWS(c echo.Context) error {
 	for ; true; <-ticker.C {
 		if err := serveNetworkInfo(c, ws); err != nil {
 			if !errors.Is(err, syscall.EPIPE) {
-				log.Err(fmt.Errorf("NetworkInfoWS: serve network info: %s", err))
+				logging.Error("NetworkInfoWS: serve network info", err)
 				return nil
 			}
 		}
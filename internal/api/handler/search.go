@@ -5,14 +5,46 @@ import (
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"net/url"
 	"strings"
+
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
+// SearchResult is an account or a smesher found by name.
+type SearchResult struct {
+	*model.Label
+	Redirect string `json:"redirect"`
+}
+
 func Search(c echo.Context) error {
 	cc := c.(*ApiContext)
 
 	search := strings.ToLower(c.Param("id"))
 	redirectURL, err := cc.Service.Search(context.TODO(), search)
+	if err == service.ErrNotFound {
+		// not an id, look up the names of the accounts and smeshers
+		text, unescapeErr := url.PathUnescape(c.Param("id"))
+		if unescapeErr != nil {
+			return echo.ErrNotFound
+		}
+		labels, err := cc.Service.SearchLabels(context.TODO(), text)
+		if err != nil {
+			if err == service.ErrNotFound {
+				return echo.ErrNotFound
+			}
+			return fmt.Errorf("error search labels `%s`: %w", text, err)
+		}
+		results := make([]SearchResult, 0, len(labels))
+		for _, label := range labels {
+			results = append(results, SearchResult{Label: label, Redirect: label.URL()})
+		}
+		return c.JSON(http.StatusOK, RedirectResponse{
+			Redirect: results[0].Redirect,
+			Results:  results,
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("error search `%s`: %w", search, err)
 	}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+const (
+	// mapSmeshersLimit is the default and maximum number of smeshers returned to the map view.
+	mapSmeshersLimit = 1000
+	// maxNearRadius is the maximum radius of the searches by distance, in meters.
+	maxNearRadius = 20_000_000
+)
+
+// SmeshersNear serves the located smeshers within `radius` meters of `lon` and `lat`, nearest
+// first.
+func SmeshersNear(c echo.Context) error {
+	cc := c.(*ApiContext)
+	var params [3]float64
+	for i, name := range []string{"lon", "lat", "radius"} {
+		v, err := strconv.ParseFloat(c.QueryParam(name), 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid %s", name))
+		}
+		params[i] = v
+	}
+	geo := model.Geo{Coordinates: [2]float64{params[0], params[1]}}
+	if err := geo.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if params[2] <= 0 || params[2] > maxNearRadius {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("radius must be in (0, %d] meters", maxNearRadius))
+	}
+	smeshers, err := cc.Service.GetSmeshersNear(context.TODO(), params[0], params[1], params[2], mapLimit(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, DataResponse{Data: smeshers})
+}
+
+// SmeshersWithin serves the located smeshers in the bounding box `bbox`, in format
+// minLon,minLat,maxLon,maxLat.
+func SmeshersWithin(c echo.Context) error {
+	cc := c.(*ApiContext)
+	parts := strings.Split(c.QueryParam("bbox"), ",")
+	if len(parts) != 4 {
+		return echo.NewHTTPError(http.StatusBadRequest, "bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid bbox coordinate `%s`", part))
+		}
+		values[i] = v
+	}
+	box := model.GeoBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	if err := box.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	smeshers, err := cc.Service.GetSmeshersWithin(context.TODO(), box, mapLimit(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, DataResponse{Data: smeshers})
+}
+
+// GeoHeatmap serves the committed space of the located smeshers by country and by region, as last
+// aggregated.
+func GeoHeatmap(c echo.Context) error {
+	cc := c.(*ApiContext)
+	heatmap, err := cc.Service.GetGeoHeatmap(context.TODO())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, DataResponse{Data: heatmap})
+}
+
+// mapLimit returns the `limit` of smeshers of the request, at most mapSmeshersLimit.
+func mapLimit(c echo.Context) int64 {
+	limit, err := strconv.ParseInt(c.QueryParam("limit"), 10, 64)
+	if err != nil || limit <= 0 || limit > mapSmeshersLimit {
+		return mapSmeshersLimit
+	}
+	return limit
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/internal/service"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/spacemeshos/go-spacemesh/log"
 
 	"github.com/spacemeshos/explorer-backend/model"
 )
@@ -16,9 +16,21 @@ import (
 func Smeshers(c echo.Context) error {
 	cc := c.(*ApiContext)
 	pageNum, pageSize := GetPagination(c)
-	smeshersList, total, err := cc.Service.GetSmeshers(context.TODO(), pageNum, pageSize)
+	var (
+		smeshersList []*model.Smesher
+		total        int64
+		err          error
+	)
+	switch c.QueryParam("sort") {
+	case "":
+		smeshersList, total, err = cc.Service.GetSmeshers(context.TODO(), pageNum, pageSize)
+	case rewards:
+		smeshersList, total, err = cc.Service.GetTopSmeshers(context.TODO(), pageNum, pageSize)
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown sort `%s`", c.QueryParam("sort")))
+	}
 	if err != nil {
-		log.Err(fmt.Errorf("failed to get smeshers list: %s", err))
+		logging.Error("failed to get smeshers list", err)
 		return err
 	}
 
@@ -54,11 +66,15 @@ func SmesherDetails(c echo.Context) error {
 		response, total, err = cc.Service.GetSmesherActivations(context.TODO(), c.Param("id"), pageNum, pageSize)
 	case rewards:
 		response, total, err = cc.Service.GetSmesherRewards(context.TODO(), c.Param("id"), pageNum, pageSize)
+	case history:
+		response, total, err = cc.Service.GetSmesherHistory(context.TODO(), c.Param("id"), pageNum, pageSize)
+	case participation:
+		response, total, err = cc.Service.GetSmesherParticipation(context.TODO(), c.Param("id"), pageNum, pageSize)
 	default:
 		return fiber.NewError(fiber.StatusNotFound, "entity not found")
 	}
 	if err != nil {
-		log.Err(fmt.Errorf("failed to get smesher entity `%s` details: %s", c.Param("entity"), err))
+		logging.Error(fmt.Sprintf("failed to get smesher entity `%s` details", c.Param("entity")), err)
 		return err
 	}
 
@@ -1,10 +1,14 @@
 package handler_test
 
 import (
+	"context"
+	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestSmeshersHandler(t *testing.T) { // /smeshers
@@ -70,3 +74,66 @@ func TestSmesherRewardsHandler(t *testing.T) { // /smeshers/{id}/rewards
 		}
 	}
 }
+
+func TestSmesherHistoryHandler(t *testing.T) { // /smeshers/{id}/history
+	t.Parallel()
+	for _, epoch := range generator.Epochs {
+		for _, smesher := range epoch.Smeshers {
+			res := apiServer.Get(t, apiPrefix+"/smeshers/"+smesher.Id+"/history")
+			res.RequireOK(t)
+			var resp smesherHistoryResp
+			res.RequireUnmarshal(t, &resp)
+			require.Equal(t, 1, len(resp.Data))
+			require.Equal(t, uint32(epoch.Epoch.Number), resp.Data[0].Epoch)
+			require.Equal(t, smesher.Coinbase, resp.Data[0].Coinbase)
+			require.Equal(t, smesher.CommitmentSize, resp.Data[0].CommitmentSize)
+		}
+	}
+
+	// the smesher is not seeded so its labels do not rename the seeded activations
+	smesherID := "0x" + strings.Repeat("5e", 32)
+	for _, name := range []string{"pool", "pool", "new pool"} {
+		require.NoError(t, db.UpsertLabels(context.Background(), []*model.Label{{Kind: model.LabelSmesher, Id: smesherID, Name: name}}))
+	}
+	res := apiServer.Get(t, apiPrefix+"/smeshers/"+smesherID+"/history")
+	res.RequireOK(t)
+	var resp smesherHistoryResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, 1, len(resp.Data))
+	require.Equal(t, "new pool", resp.Data[0].Name)
+	require.Equal(t, []string{"name"}, resp.Data[0].Changed)
+}
+
+func TestSmesherParticipationHandler(t *testing.T) { // /smeshers/{id}/participation
+	t.Parallel()
+	for _, epoch := range generator.Epochs {
+		for _, smesher := range epoch.Smeshers {
+			res := apiServer.Get(t, apiPrefix+"/smeshers/"+smesher.Id+"/participation")
+			res.RequireOK(t)
+			var resp participationResp
+			res.RequireUnmarshal(t, &resp)
+			require.Equal(t, 1, len(resp.Data))
+			for _, atx := range epoch.Activations {
+				if atx.SmesherId == smesher.Id {
+					require.Equal(t, atx.TargetEpoch, resp.Data[0].Epoch)
+					require.Equal(t, int64(atx.Weight), resp.Data[0].Weight)
+				}
+			}
+		}
+	}
+}
+
+func TestTopSmeshersHandler(t *testing.T) { // /smeshers?sort=rewards
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/smeshers?sort=rewards&pagesize=1000")
+	res.RequireOK(t)
+	var resp smesherResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(generator.Smeshers), len(resp.Data))
+	for i := 1; i < len(resp.Data); i++ {
+		require.GreaterOrEqual(t, resp.Data[i-1].TotalRewards, resp.Data[i].TotalRewards)
+	}
+
+	res = apiServer.Get(t, apiPrefix+"/smeshers?sort=unknown")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+const (
+	// coinbasesTop is the default number of top coinbases of the concentration stats, and
+	// maxCoinbasesTop their maximum.
+	coinbasesTop    = 10
+	maxCoinbasesTop = 1000
+	// rollupPeriods is the default number of periods of the rollups, and maxRollupPeriods their
+	// maximum.
+	rollupPeriods    = 30
+	maxRollupPeriods = 1000
+	// pricesRange is the default range of the prices, and maxPricesRange its maximum, in seconds.
+	pricesRange    = 24 * 60 * 60
+	maxPricesRange = 31 * 24 * 60 * 60
+)
+
+func DailyTransactions(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	days, total, err := cc.Service.GetDailyTransactions(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get daily transactions: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       days,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func Decentralization(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetDecentralization(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get decentralization: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func EmptyLayers(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetEmptyLayers(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get empty layers: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func SmeshersChurn(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetSmeshersChurn(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get smeshers churn: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func VestingUnlocks(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	unlocks, total, err := cc.Service.GetVestingUnlocks(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get vesting unlocks: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       unlocks,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func VaultDrawdown(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetVaultDrawdown(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get vault drawdown: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func SpaceChart(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetSpaceGrowth(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get space growth: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func ActiveSmeshersChart(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetActiveSmeshers(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get active smeshers: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func DailyAccountsChart(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetDailyAccounts(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get daily accounts: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func EpochAccountsChart(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetEpochAccounts(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get epoch accounts: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func TransactionTypes(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	period := c.QueryParam("period")
+	switch period {
+	case "":
+		period = model.PeriodDay
+	case model.PeriodDay, model.PeriodEpoch:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown period `%s`", period))
+	}
+	series, total, err := cc.Service.GetTransactionTypes(context.TODO(), period, pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get transaction types: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func BlockFill(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetBlockFill(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get block fill: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func Issuance(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetIssuance(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get issuance: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func SupplyBreakdown(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetSupplyBreakdown(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get supply breakdown: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func FeeSeries(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	period := c.QueryParam("period")
+	switch period {
+	case "":
+		period = model.PeriodLayer
+	case model.PeriodLayer, model.PeriodEpoch:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown period `%s`", period))
+	}
+	series, total, err := cc.Service.GetFeeSeries(context.TODO(), period, pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get fee series: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+// CoinbaseConcentration serves the storage and the rewards of the epoch held by the `top`
+// coinbases.
+func CoinbaseConcentration(c echo.Context) error {
+	cc := c.(*ApiContext)
+	epoch, err := strconv.ParseInt(c.Param("epoch"), 10, 32)
+	if err != nil || epoch < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid epoch")
+	}
+	top := coinbasesTop
+	if param := c.QueryParam("top"); param != "" {
+		top, err = strconv.Atoi(param)
+		if err != nil || top <= 0 || top > maxCoinbasesTop {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("top must be in [1, %d]", maxCoinbasesTop))
+		}
+	}
+	concentration, err := cc.Service.GetCoinbaseConcentration(context.TODO(), int32(epoch), top)
+	if err != nil {
+		return fmt.Errorf("failed to get coinbase concentration: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: concentration})
+}
+
+func CommitmentHistogram(c echo.Context) error {
+	cc := c.(*ApiContext)
+	epoch, err := strconv.ParseInt(c.Param("epoch"), 10, 32)
+	if err != nil || epoch < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid epoch")
+	}
+	histogram, err := cc.Service.GetCommitmentHistogram(context.TODO(), int32(epoch))
+	if err != nil {
+		return fmt.Errorf("failed to get commitment histogram: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: histogram})
+}
+
+func RewardLuck(c echo.Context) error {
+	cc := c.(*ApiContext)
+	epoch, err := strconv.ParseInt(c.Param("epoch"), 10, 32)
+	if err != nil || epoch < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid epoch")
+	}
+	luck, err := cc.Service.GetRewardLuck(context.TODO(), int32(epoch))
+	if err != nil {
+		return fmt.Errorf("failed to get reward luck: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: luck})
+}
+
+func BalanceCohorts(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetBalanceCohorts(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get balance cohorts: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func InclusionTime(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetInclusionTime(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get inclusion time: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func BallotParticipation(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetBallotParticipation(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get ballot participation: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+func MalfeasanceStats(c echo.Context) error {
+	cc := c.(*ApiContext)
+	pageNum, pageSize := GetPagination(c)
+	series, total, err := cc.Service.GetMalfeasanceStats(context.TODO(), pageNum, pageSize)
+	if err != nil {
+		return fmt.Errorf("failed to get malfeasance stats: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, PaginatedDataResponse{
+		Data:       series,
+		Pagination: GetPaginationMetadata(total, pageNum, pageSize),
+	})
+}
+
+// Rollups serves the rollups of the days or the weeks overlapping [from, to], unix timestamps
+// defaulting to the last rollupPeriods periods.
+func Rollups(c echo.Context) error {
+	cc := c.(*ApiContext)
+	period := c.QueryParam("period")
+	switch period {
+	case "":
+		period = model.PeriodDay
+	case model.PeriodDay, model.PeriodWeek:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("unknown period `%s`", period))
+	}
+	length := uint64(model.PeriodSeconds(period))
+	to := uint64(time.Now().Unix())
+	if param := c.QueryParam("to"); param != "" {
+		var err error
+		to, err = strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+	}
+	from := to - min(to, (rollupPeriods-1)*length)
+	if param := c.QueryParam("from"); param != "" {
+		var err error
+		from, err = strconv.ParseUint(param, 10, 32)
+		if err != nil || from > to {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+	}
+	from = uint64(model.PeriodStart(period, uint32(from)))
+	if (to-from)/length >= maxRollupPeriods {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("the range must not exceed %d periods", maxRollupPeriods))
+	}
+	rollups, err := cc.Service.GetRollups(context.TODO(), period, uint32(from), uint32(to))
+	if err != nil {
+		return fmt.Errorf("failed to get rollups: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: rollups})
+}
+
+// Prices serves the SMH prices in USD recorded from the price feed in [from, to], by default the
+// ones of the last day. The list is empty without a price feed.
+func Prices(c echo.Context) error {
+	cc := c.(*ApiContext)
+	to := uint64(time.Now().Unix())
+	if param := c.QueryParam("to"); param != "" {
+		var err error
+		to, err = strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+		}
+	}
+	from := to - min(to, pricesRange)
+	if param := c.QueryParam("from"); param != "" {
+		var err error
+		from, err = strconv.ParseUint(param, 10, 32)
+		if err != nil || from > to {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+	}
+	if to-from > maxPricesRange {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("the range must not exceed %d seconds", maxPricesRange))
+	}
+	prices, err := cc.Service.GetPrices(context.TODO(), uint32(from), uint32(to))
+	if err != nil {
+		return fmt.Errorf("failed to get prices: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: prices})
+}
+
+// NetworkHealth serves the composite health score of the network with its components.
+func NetworkHealth(c echo.Context) error {
+	cc := c.(*ApiContext)
+	health, err := cc.Service.GetNetworkHealth(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to get network health: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: health})
+}
+
+func Totals(c echo.Context) error {
+	cc := c.(*ApiContext)
+	totals, err := cc.Service.GetTotals(context.TODO())
+	if err != nil {
+		return fmt.Errorf("failed to get totals: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: totals})
+}
+
+func RewardsProjection(c echo.Context) error {
+	cc := c.(*ApiContext)
+	numUnits, err := strconv.ParseUint(c.QueryParam("numUnits"), 10, 32)
+	if err != nil || numUnits == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid numUnits")
+	}
+	epoch, err := strconv.ParseInt(c.QueryParam("epoch"), 10, 32)
+	if err != nil || epoch < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid epoch")
+	}
+	projection, err := cc.Service.GetRewardsProjection(context.TODO(), uint32(numUnits), int32(epoch))
+	if err != nil {
+		if err == service.ErrNotFound {
+			return echo.ErrNotFound
+		}
+		return fmt.Errorf("failed to get rewards projection: %w", err)
+	}
+
+	return c.JSON(http.StatusOK, DataResponse{Data: projection})
+}
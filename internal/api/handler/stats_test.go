@@ -0,0 +1,823 @@
+package handler_test
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/pkg/economics"
+	"github.com/spacemeshos/explorer-backend/test/testseed"
+)
+
+type dailyTransactionsResp struct {
+	Data       []model.DailyTransactions `json:"data"`
+	Pagination pagination                `json:"pagination"`
+}
+
+func TestDailyTransactions(t *testing.T) { // /stats/txs/daily
+	t.Parallel()
+	expected := make(map[uint32]model.DailyTransactions)
+	for _, tx := range generator.Epochs.GetTransactions() {
+		day := tx.Timestamp - tx.Timestamp%(24*60*60)
+		stats := expected[day]
+		stats.Day = day
+		stats.Count++
+		stats.Amount += int64(tx.Amount)
+		expected[day] = stats
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/txs/daily?pagesize=1000")
+	res.RequireOK(t)
+	var resp dailyTransactionsResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, stats := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Day, stats.Day)
+		}
+		require.Equal(t, expected[stats.Day], stats)
+	}
+}
+
+type decentralizationResp struct {
+	Data       []model.Decentralization `json:"data"`
+	Pagination pagination               `json:"pagination"`
+}
+
+func TestDecentralization(t *testing.T) { // /stats/decentralization
+	t.Parallel()
+	expected := make(map[int32]model.Decentralization, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.Decentralization{
+			Epoch:           epoch.Epoch.Number,
+			SpaceGini:       stats.SpaceGini,
+			SpaceNakamoto:   stats.SpaceNakamoto,
+			RewardsGini:     stats.RewardsGini,
+			RewardsNakamoto: stats.RewardsNakamoto,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/decentralization?pagesize=1000")
+	res.RequireOK(t)
+	var resp decentralizationResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, stats := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Epoch, stats.Epoch)
+		}
+		require.Equal(t, expected[stats.Epoch], stats)
+	}
+}
+
+type emptyLayersResp struct {
+	Data       []model.EmptyLayers `json:"data"`
+	Pagination pagination          `json:"pagination"`
+}
+
+func TestEmptyLayers(t *testing.T) { // /stats/layers/empty
+	t.Parallel()
+	expected := make(map[int32]model.EmptyLayers, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		series := model.EmptyLayers{Epoch: epoch.Epoch.Number, Layers: epoch.Epoch.Layers}
+		for _, layer := range epoch.Layers {
+			if layer.Layer.BlocksNumber == 0 {
+				series.Empty++
+			}
+			if layer.Layer.Txs == 0 {
+				series.WithoutTxs++
+			}
+		}
+		expected[epoch.Epoch.Number] = series
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/layers/empty?pagesize=1000")
+	res.RequireOK(t)
+	var resp emptyLayersResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for _, series := range resp.Data {
+		require.Equal(t, expected[series.Epoch], series)
+	}
+}
+
+type blockFillResp struct {
+	Data       []model.BlockFill `json:"data"`
+	Pagination pagination        `json:"pagination"`
+}
+
+func TestBlockFill(t *testing.T) { // /stats/blocks
+	t.Parallel()
+	expected := make(map[uint32][]*model.Block, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		for _, block := range epoch.Blocks {
+			expected[block.Epoch] = append(expected[block.Epoch], block)
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/blocks?pagesize=1000")
+	res.RequireOK(t)
+	var resp blockFillResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for _, fill := range resp.Data {
+		require.Equal(t, *model.NewBlockFill(fill.Epoch, model.NewBlockStats(expected[fill.Epoch])), fill)
+	}
+}
+
+type smeshersChurnResp struct {
+	Data       []model.SmeshersChurn `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestSmeshersChurn(t *testing.T) { // /stats/smeshers/churn
+	t.Parallel()
+	expected := make(map[int32]model.SmeshersChurn, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.SmeshersChurn{
+			Epoch:     epoch.Epoch.Number,
+			New:       stats.NewSmeshers,
+			Returning: stats.ReturningSmeshers,
+			Stopped:   stats.StoppedSmeshers,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/smeshers/churn?pagesize=1000")
+	res.RequireOK(t)
+	var resp smeshersChurnResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, churn := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Epoch, churn.Epoch)
+		}
+		require.Equal(t, expected[churn.Epoch], churn)
+	}
+}
+
+type vestingUnlocksResp struct {
+	Data       []model.VestingUnlock `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestVestingUnlocks(t *testing.T) { // /stats/vesting
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/stats/vesting?pagesize=1000")
+	res.RequireOK(t)
+	var resp vestingUnlocksResp
+	res.RequireUnmarshal(t, &resp)
+	// the unlocks run from the current epoch until the end of the vesting of the generated vault
+	current := uint32(generator.Epochs[len(generator.Epochs)-1].Epoch.Number)
+	require.Len(t, resp.Data, testseed.VaultVestingEnd-int(current))
+	for i, unlock := range resp.Data {
+		epoch := current + uint32(i)
+		require.Equal(t, model.VestingUnlock{
+			Epoch:    epoch,
+			Unlocked: vaultVested(epoch) - vaultVested(epoch-1),
+			Vested:   vaultVested(epoch),
+			Locked:   generator.Vault.TotalAmount - vaultVested(epoch),
+		}, unlock)
+	}
+	require.Equal(t, uint64(seed.EpochNumLayers)*testseed.VaultLayerVesting, resp.Data[0].Unlocked)
+	require.Equal(t, generator.Vault.TotalAmount, resp.Data[len(resp.Data)-1].Vested)
+}
+
+// vaultVested returns the amount of the generated vault vested by the end of the epoch.
+func vaultVested(epoch uint32) uint64 {
+	layers := min(max(int64(epoch)+1-testseed.VaultVestingStart, 0), testseed.VaultVestingEnd-testseed.VaultVestingStart) * int64(seed.EpochNumLayers)
+	return uint64(layers) * testseed.VaultLayerVesting
+}
+
+type vaultDrawdownResp struct {
+	Data       []model.VaultDrawdown `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestVaultDrawdown(t *testing.T) { // /stats/vesting/drawdown
+	t.Parallel()
+	current := uint32(generator.Epochs[len(generator.Epochs)-1].Epoch.Number)
+	expected := make(map[uint32]model.VaultDrawdown, current+1)
+	var cumulative uint64
+	for epoch := uint32(0); epoch <= current; epoch++ {
+		drawdown := model.VaultDrawdown{Epoch: epoch, Vested: vaultVested(epoch)}
+		for _, drain := range testseed.VaultDrains {
+			if drain.Epoch == epoch {
+				drawdown.Drains++
+				drawdown.Drained += drain.Amount
+			}
+		}
+		cumulative += drawdown.Drained
+		drawdown.Cumulative = cumulative
+		drawdown.Undrained = drawdown.Vested - cumulative
+		drawdown.Remaining = generator.Vault.TotalAmount - cumulative
+		expected[epoch] = drawdown
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/vesting/drawdown?pagesize=1000")
+	res.RequireOK(t)
+	var resp vaultDrawdownResp
+	res.RequireUnmarshal(t, &resp)
+	require.Len(t, resp.Data, len(expected))
+	require.Equal(t, len(resp.Data), resp.Pagination.TotalCount)
+	for i, drawdown := range resp.Data {
+		require.Equal(t, current-uint32(i), drawdown.Epoch)
+		require.Equal(t, expected[drawdown.Epoch], drawdown)
+	}
+	require.Equal(t, uint64(50_000), resp.Data[0].Cumulative)
+	require.NotZero(t, resp.Data[0].Undrained)
+}
+
+type spaceGrowthResp struct {
+	Data       []model.SpaceGrowth `json:"data"`
+	Pagination pagination          `json:"pagination"`
+}
+
+func TestSpaceChart(t *testing.T) { // /charts/space
+	t.Parallel()
+	expected := make(map[int32]model.SpaceGrowth, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.SpaceGrowth{
+			Epoch: epoch.Epoch.Number,
+			Space: stats.Space,
+			Delta: stats.SpaceDelta,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/charts/space?pagesize=1000")
+	res.RequireOK(t)
+	var resp spaceGrowthResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, growth := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Epoch, growth.Epoch)
+		}
+		require.Equal(t, expected[growth.Epoch], growth)
+	}
+}
+
+type activeSmeshersResp struct {
+	Data       []model.ActiveSmeshers `json:"data"`
+	Pagination pagination             `json:"pagination"`
+}
+
+func TestActiveSmeshersChart(t *testing.T) { // /charts/smeshers
+	t.Parallel()
+	expected := make(map[int32]model.ActiveSmeshers, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.ActiveSmeshers{
+			Epoch:     epoch.Epoch.Number,
+			Activated: stats.Smeshers,
+			Rewarded:  stats.RewardedSmeshers,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/charts/smeshers?pagesize=1000")
+	res.RequireOK(t)
+	var resp activeSmeshersResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for _, series := range resp.Data {
+		require.Equal(t, expected[series.Epoch], series)
+	}
+}
+
+type dailyAccountsResp struct {
+	Data       []model.DailyAccounts `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestDailyAccountsChart(t *testing.T) { // /charts/accounts/daily
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/charts/accounts/daily?pagesize=1000")
+	res.RequireOK(t)
+	var resp dailyAccountsResp
+	res.RequireUnmarshal(t, &resp)
+	require.NotEmpty(t, resp.Data)
+	require.Equal(t, int64(len(generator.Accounts)), resp.Data[0].Total)
+	for i := 1; i < len(resp.Data); i++ {
+		require.Greater(t, resp.Data[i-1].Day, resp.Data[i].Day)
+		require.Equal(t, resp.Data[i-1].Total-resp.Data[i-1].New, resp.Data[i].Total)
+	}
+	last := resp.Data[len(resp.Data)-1]
+	require.Equal(t, last.New, last.Total)
+}
+
+type epochAccountsResp struct {
+	Data       []model.EpochAccounts `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestEpochAccountsChart(t *testing.T) { // /charts/accounts/epochs
+	t.Parallel()
+	expected := make(map[int32]model.EpochAccounts, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.EpochAccounts{
+			Epoch: epoch.Epoch.Number,
+			New:   stats.NewAccounts,
+			Total: stats.Accounts,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/charts/accounts/epochs?pagesize=1000")
+	res.RequireOK(t)
+	var resp epochAccountsResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for _, accounts := range resp.Data {
+		require.Equal(t, expected[accounts.Epoch], accounts)
+	}
+}
+
+type transactionTypesResp struct {
+	Data       []model.TransactionTypes `json:"data"`
+	Pagination pagination               `json:"pagination"`
+}
+
+func TestTransactionTypes(t *testing.T) { // /stats/tx-types
+	t.Parallel()
+	expected := make(map[uint32]map[string]*model.TransactionTypeCount)
+	for _, tx := range generator.Epochs.GetTransactions() {
+		day := tx.Timestamp - tx.Timestamp%(24*60*60)
+		if expected[day] == nil {
+			expected[day] = make(map[string]*model.TransactionTypeCount)
+		}
+		name := model.TransactionTypeName(tx.Type)
+		if expected[day][name] == nil {
+			expected[day][name] = &model.TransactionTypeCount{}
+		}
+		expected[day][name].Count++
+		expected[day][name].Amount += int64(tx.Amount)
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/tx-types?pagesize=1000")
+	res.RequireOK(t)
+	var resp transactionTypesResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, stats := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Period, stats.Period)
+		}
+		require.Equal(t, expected[stats.Period], stats.Types)
+	}
+
+	res = apiServer.Get(t, apiPrefix+"/stats/tx-types?period=epoch&pagesize=1000")
+	res.RequireOK(t)
+	var epochResp transactionTypesResp
+	res.RequireUnmarshal(t, &epochResp)
+	var count int64
+	for _, stats := range epochResp.Data {
+		for _, types := range stats.Types {
+			count += types.Count
+		}
+	}
+	require.Equal(t, int64(len(generator.Epochs.GetTransactions())), count)
+
+	res = apiServer.Get(t, apiPrefix+"/stats/tx-types?period=week")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type feeSeriesResp struct {
+	Data       []model.FeeSeries `json:"data"`
+	Pagination pagination        `json:"pagination"`
+}
+
+func TestFeeSeries(t *testing.T) { // /stats/fees/series
+	t.Parallel()
+	expected := make(map[uint32]model.FeeSeries)
+	for _, layer := range generator.Epochs.GetLayers() {
+		expected[layer.Number] = model.FeeSeries{
+			Period: layer.Number,
+			Total:  layer.FeesCollected,
+			Min:    layer.FeeMin,
+			Median: layer.FeeMedian,
+			Max:    layer.FeeMax,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/fees/series?pagesize=1000")
+	res.RequireOK(t)
+	var resp feeSeriesResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	for i, fees := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Period, fees.Period)
+		}
+		require.Equal(t, expected[fees.Period], fees)
+	}
+
+	epochs := make(map[uint32]model.FeeSeries, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		epochs[uint32(epoch.Epoch.Number)] = model.FeeSeries{
+			Period: uint32(epoch.Epoch.Number),
+			Total:  uint64(stats.FeesCollected),
+			Min:    uint64(stats.FeeMin),
+			Median: uint64(stats.FeeMedian),
+			Max:    uint64(stats.FeeMax),
+		}
+	}
+	res = apiServer.Get(t, apiPrefix+"/stats/fees/series?period=epoch&pagesize=1000")
+	res.RequireOK(t)
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(epochs), len(resp.Data))
+	for _, fees := range resp.Data {
+		require.Equal(t, epochs[fees.Period], fees)
+	}
+
+	res = apiServer.Get(t, apiPrefix+"/stats/fees/series?period=day")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type issuanceResp struct {
+	Data       []model.Issuance `json:"data"`
+	Pagination pagination       `json:"pagination"`
+}
+
+func TestIssuance(t *testing.T) { // /stats/issuance
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/stats/issuance?pagesize=1000")
+	res.RequireOK(t)
+	var resp issuanceResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(generator.Epochs), len(resp.Data))
+	for _, issuance := range resp.Data {
+		first := uint32(issuance.Epoch) * seed.EpochNumLayers
+		require.Equal(t, economics.IssuedBetween(first, first+seed.EpochNumLayers-1, seed.EpochNumLayers), issuance.Expected)
+		require.Equal(t, int64(issuance.Observed)-int64(issuance.Expected), issuance.Divergence)
+		require.Equal(t, issuance.Complete && issuance.Divergence != 0, issuance.Diverged)
+	}
+}
+
+type supplyBreakdownResp struct {
+	Data       []model.SupplyBreakdown `json:"data"`
+	Pagination pagination              `json:"pagination"`
+}
+
+func TestSupplyBreakdown(t *testing.T) { // /stats/supply-breakdown
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/stats/supply-breakdown?pagesize=1000")
+	res.RequireOK(t)
+	var resp supplyBreakdownResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(generator.Epochs), len(resp.Data))
+	for i, supply := range resp.Data {
+		if i > 0 {
+			require.Greater(t, resp.Data[i-1].Epoch, supply.Epoch)
+			require.GreaterOrEqual(t, resp.Data[i-1].Total+resp.Data[i-1].FeesBurned, supply.Total+supply.FeesBurned)
+		}
+		require.Equal(t, uint64(economics.TotalVaulted), supply.Genesis)
+		require.Equal(t, supply.Genesis, supply.Vested+supply.Locked)
+		require.Equal(t, supply.Genesis+supply.Rewards-supply.FeesBurned, supply.Total)
+		require.Equal(t, supply.Total-supply.Locked, supply.Circulating)
+	}
+}
+
+type coinbaseConcentrationResp struct {
+	Data model.CoinbaseConcentration `json:"data"`
+}
+
+func TestCoinbaseConcentration(t *testing.T) { // /stats/coinbases/:epoch
+	t.Parallel()
+	epoch := generator.Epochs[0].Epoch.Number
+	coinbases := make(map[string]int64)
+	var units int64
+	for _, atx := range generator.Epochs.GetActivations() {
+		if int32(atx.TargetEpoch) == epoch {
+			coinbases[atx.Coinbase] += int64(atx.EffectiveNumUnits)
+			units += int64(atx.EffectiveNumUnits)
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/coinbases/%d?top=1000", epoch))
+	res.RequireOK(t)
+	var resp coinbaseConcentrationResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, epoch, resp.Data.Epoch)
+	require.Equal(t, units*int64(seed.GetPostUnitsSize()), resp.Data.Space)
+	for i, share := range resp.Data.Top {
+		if i > 0 {
+			require.GreaterOrEqual(t, resp.Data.Top[i-1].Space, share.Space)
+		}
+		require.Equal(t, coinbases[share.Coinbase], share.Units)
+	}
+
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/coinbases/%d?top=0", epoch))
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type commitmentHistogramResp struct {
+	Data model.CommitmentHistogram `json:"data"`
+}
+
+func TestCommitmentHistogram(t *testing.T) { // /stats/commitments/:epoch
+	t.Parallel()
+	epoch := generator.Epochs[0].Epoch.Number
+	var activations, units int64
+	for _, atx := range generator.Epochs.GetActivations() {
+		if int32(atx.TargetEpoch) == epoch {
+			activations++
+			units += int64(atx.NumUnits)
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/commitments/%d", epoch))
+	res.RequireOK(t)
+	var resp commitmentHistogramResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, epoch, resp.Data.Epoch)
+	require.Equal(t, activations, resp.Data.Activations)
+	require.Equal(t, units, resp.Data.Units)
+	require.Equal(t, units*int64(seed.GetPostUnitsSize()), resp.Data.Space)
+	var bucketed int64
+	for _, bucket := range resp.Data.Buckets {
+		require.Less(t, bucket.From, bucket.To)
+		bucketed += bucket.Activations
+	}
+	require.Equal(t, activations, bucketed)
+
+	res = apiServer.Get(t, apiPrefix+"/stats/commitments/-1")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type rewardLuckResp struct {
+	Data model.RewardLuck `json:"data"`
+}
+
+func TestRewardLuck(t *testing.T) { // /stats/luck/:epoch
+	t.Parallel()
+	epoch := generator.Epochs[0].Epoch.Number
+	smeshers := make(map[string]bool)
+	for _, atx := range generator.Epochs.GetActivations() {
+		if int32(atx.TargetEpoch) == epoch && atx.Weight > 0 {
+			smeshers[atx.SmesherId] = true
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/luck/%d", epoch))
+	res.RequireOK(t)
+	var resp rewardLuckResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, epoch, resp.Data.Epoch)
+	require.Equal(t, int64(len(smeshers)), resp.Data.Smeshers)
+	var bucketed int64
+	for _, bucket := range resp.Data.Buckets {
+		require.Less(t, bucket.From, bucket.To)
+		require.LessOrEqual(t, bucket.Dry, bucket.Smeshers)
+		bucketed += bucket.Smeshers
+	}
+	require.Equal(t, resp.Data.Smeshers, bucketed)
+
+	res = apiServer.Get(t, apiPrefix+"/stats/luck/-1")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type rewardsProjectionResp struct {
+	Data model.RewardsProjection `json:"data"`
+}
+
+func TestRewardsProjection(t *testing.T) { // /calc/rewards
+	t.Parallel()
+	epoch := generator.Epochs[len(generator.Epochs)-1].Epoch
+	res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/calc/rewards?numUnits=4&epoch=%d", epoch.Number))
+	res.RequireOK(t)
+	var resp rewardsProjectionResp
+	res.RequireUnmarshal(t, &resp)
+	first := uint32(epoch.Number) * seed.EpochNumLayers
+	require.Equal(t, epoch.Number, resp.Data.SpaceEpoch)
+	require.Equal(t, 4*int64(seed.GetPostUnitsSize()), resp.Data.Space)
+	require.Equal(t, resp.Data.Space+epoch.Stats.Current.Space, resp.Data.NetworkSpace)
+	require.Equal(t, economics.IssuedBetween(first, first+seed.EpochNumLayers-1, seed.EpochNumLayers), resp.Data.Subsidy)
+	require.LessOrEqual(t, resp.Data.Rewards, resp.Data.Subsidy)
+
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/calc/rewards?numUnits=0&epoch=%d", epoch.Number))
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+"/calc/rewards?numUnits=4")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type rollupsResp struct {
+	Data []model.Rollup `json:"data"`
+}
+
+func TestRollups(t *testing.T) { // /stats/rollups
+	t.Parallel()
+	var expected model.Rollup
+	from, to := uint32(math.MaxUint32), uint32(0)
+	for _, tx := range generator.Epochs.GetTransactions() {
+		expected.Txs++
+		expected.Volume += int64(tx.Amount)
+		expected.Fees += int64(tx.Fee)
+		from, to = min(from, tx.Timestamp), max(to, tx.Timestamp)
+	}
+	for _, reward := range generator.Epochs.GetRewards() {
+		expected.Rewards += int64(reward.Total)
+		from, to = min(from, reward.Timestamp), max(to, reward.Timestamp)
+	}
+
+	// the rollups are computed by the first run of the job started after the seed
+	var resp rollupsResp
+	require.Eventually(t, func() bool {
+		res := apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/rollups?period=week&from=%d&to=%d", from, to))
+		res.RequireOK(t)
+		res.RequireUnmarshal(t, &resp)
+		var txs int64
+		for _, rollup := range resp.Data {
+			txs += rollup.Txs
+		}
+		return txs == expected.Txs
+	}, 10*time.Second, 100*time.Millisecond)
+	var total model.Rollup
+	for i, rollup := range resp.Data {
+		if i > 0 {
+			require.Less(t, resp.Data[i-1].Start, rollup.Start)
+		}
+		total.Volume += rollup.Volume
+		total.Fees += rollup.Fees
+		total.Rewards += rollup.Rewards
+	}
+	require.Equal(t, expected.Volume, total.Volume)
+	require.Equal(t, expected.Fees, total.Fees)
+	require.Equal(t, expected.Rewards, total.Rewards)
+
+	res := apiServer.Get(t, apiPrefix+"/stats/rollups?period=month")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/rollups?from=%d&to=%d", to, from))
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/rollups?from=0&to=%d", to))
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type pricesResp struct {
+	Data []model.Price `json:"data"`
+}
+
+func TestPrices(t *testing.T) { // /stats/prices
+	t.Parallel()
+	// the test collector runs without a price feed
+	res := apiServer.Get(t, apiPrefix+"/stats/prices")
+	res.RequireOK(t)
+	var resp pricesResp
+	res.RequireUnmarshal(t, &resp)
+	require.Empty(t, resp.Data)
+
+	res = apiServer.Get(t, apiPrefix+"/stats/prices?from=2&to=1")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+fmt.Sprintf("/stats/prices?from=0&to=%d", time.Now().Unix()))
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
+
+type networkHealthResp struct {
+	Data model.NetworkHealth `json:"data"`
+}
+
+func TestNetworkHealth(t *testing.T) { // /stats/health
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/stats/health")
+	res.RequireOK(t)
+	var resp networkHealthResp
+	res.RequireUnmarshal(t, &resp)
+	require.Len(t, resp.Data.Components, 4)
+	var score, weight int64
+	for _, component := range resp.Data.Components {
+		require.GreaterOrEqual(t, component.Score, int64(0))
+		require.LessOrEqual(t, component.Score, int64(10000))
+		score += component.Score * component.Weight
+		weight += component.Weight
+	}
+	require.Equal(t, int64(100), weight)
+	require.InDelta(t, score/100, resp.Data.Score, 1)
+	require.NotEmpty(t, resp.Data.Status)
+}
+
+type balanceCohortsResp struct {
+	Data       []model.BalanceCohorts `json:"data"`
+	Pagination pagination             `json:"pagination"`
+}
+
+func TestBalanceCohorts(t *testing.T) { // /stats/cohorts
+	t.Parallel()
+	res := apiServer.Get(t, apiPrefix+"/stats/cohorts?pagesize=1000")
+	res.RequireOK(t)
+	var resp balanceCohortsResp
+	res.RequireUnmarshal(t, &resp)
+	require.NotEmpty(t, resp.Data)
+	require.Equal(t, len(resp.Data), resp.Pagination.TotalCount)
+	for i, cohorts := range resp.Data {
+		if i > 0 {
+			require.Equal(t, resp.Data[i-1].Epoch-1, cohorts.Epoch)
+		}
+		require.Len(t, cohorts.Cohorts, len(model.CohortBounds))
+		var balance uint64
+		for _, cohort := range cohorts.Cohorts {
+			balance += cohort.Balance
+		}
+		require.Equal(t, cohorts.Balance, balance)
+	}
+	require.Equal(t, uint32(0), resp.Data[len(resp.Data)-1].Epoch)
+}
+
+type inclusionTimeResp struct {
+	Data       []model.InclusionTime `json:"data"`
+	Pagination pagination            `json:"pagination"`
+}
+
+func TestInclusionTime(t *testing.T) { // /stats/inclusion-time
+	t.Parallel()
+	expected := make(map[int32]model.InclusionTime, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		stats := epoch.Epoch.Stats.Current
+		expected[epoch.Epoch.Number] = model.InclusionTime{
+			Epoch:  epoch.Epoch.Number,
+			Txs:    stats.InclusionTxs,
+			Mean:   stats.InclusionMean,
+			Median: stats.InclusionMedian,
+			P90:    stats.InclusionP90,
+			P99:    stats.InclusionP99,
+		}
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/inclusion-time?pagesize=1000")
+	res.RequireOK(t)
+	var resp inclusionTimeResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	require.Equal(t, len(expected), resp.Pagination.TotalCount)
+	for _, series := range resp.Data {
+		require.Equal(t, expected[series.Epoch], series)
+	}
+}
+
+type ballotParticipationResp struct {
+	Data       []*model.BallotParticipation `json:"data"`
+	Pagination pagination                   `json:"pagination"`
+}
+
+func TestBallotParticipation(t *testing.T) { // /stats/ballots
+	t.Parallel()
+	expected := make(map[int32]*model.BallotParticipation, len(generator.Epochs))
+	for _, epoch := range generator.Epochs {
+		expected[epoch.Epoch.Number] = model.NewBallotParticipation(&epoch.Epoch)
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/ballots?pagesize=1000")
+	res.RequireOK(t)
+	var resp ballotParticipationResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, len(expected), len(resp.Data))
+	require.Equal(t, len(expected), resp.Pagination.TotalCount)
+	for _, series := range resp.Data {
+		require.Equal(t, expected[series.Epoch], series)
+	}
+}
+
+type malfeasanceStatsResp struct {
+	Data       []*model.MalfeasanceStats `json:"data"`
+	Pagination pagination                `json:"pagination"`
+}
+
+func TestMalfeasanceStats(t *testing.T) { // /stats/malfeasance
+	t.Parallel()
+	// the seeded network has no malicious smeshers
+	res := apiServer.Get(t, apiPrefix+"/stats/malfeasance")
+	res.RequireOK(t)
+	var resp malfeasanceStatsResp
+	res.RequireUnmarshal(t, &resp)
+	require.Empty(t, resp.Data)
+	require.Equal(t, 0, resp.Pagination.TotalCount)
+}
+
+type totalsResp struct {
+	Data model.Totals `json:"data"`
+}
+
+func TestTotals(t *testing.T) { // /stats/totals
+	t.Parallel()
+	var rewards int64
+	for _, reward := range generator.Rewards {
+		rewards += int64(reward.Total)
+	}
+
+	res := apiServer.Get(t, apiPrefix+"/stats/totals")
+	res.RequireOK(t)
+	var resp totalsResp
+	res.RequireUnmarshal(t, &resp)
+	require.Equal(t, int64(len(generator.Transactions)), resp.Data.Txs)
+	require.Equal(t, int64(len(generator.Smeshers)), resp.Data.Smeshers)
+	require.Equal(t, int64(len(generator.Rewards)), resp.Data.RewardsCount)
+	require.Equal(t, rewards, resp.Data.Rewards)
+	require.NotZero(t, resp.Data.Updated)
+	require.LessOrEqual(t, resp.Data.Updated, uint32(time.Now().Unix()))
+}
@@ -1,11 +1,11 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"github.com/labstack/echo/v4"
 	"github.com/spacemeshos/explorer-backend/internal/service"
 	"net/http"
+	"strconv"
 
 	"github.com/spacemeshos/explorer-backend/model"
 )
@@ -13,7 +13,19 @@ import (
 func Transactions(c echo.Context) error {
 	cc := c.(*ApiContext)
 	pageNum, pageSize := GetPagination(c)
-	txs, total, err := cc.Service.GetTransactions(context.TODO(), pageNum, pageSize)
+	filter, err := transactionFilter(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	var (
+		txs   []*model.Transaction
+		total int64
+	)
+	if filter != nil {
+		txs, total, err = cc.Service.GetFilteredTransactions(queryContext(c), filter, pageNum, pageSize)
+	} else {
+		txs, total, err = cc.Service.GetTransactions(queryContext(c), pageNum, pageSize)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get transactions list: %w", err)
 	}
@@ -24,9 +36,49 @@ func Transactions(c echo.Context) error {
 	})
 }
 
+// transactionFilter parses the filter query parameters, it returns nil if none is set.
+func transactionFilter(c echo.Context) (*model.TransactionFilter, error) {
+	filter := &model.TransactionFilter{
+		Principal:   c.QueryParam("principal"),
+		Destination: c.QueryParam("destination"),
+	}
+	set := filter.Principal != "" || filter.Destination != ""
+	if method := c.QueryParam("method"); method != "" {
+		m, err := strconv.ParseUint(method, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid method `%s`", method)
+		}
+		filter.Method = new(uint32)
+		*filter.Method = uint32(m)
+		set = true
+	}
+	template, err := templateParam(c)
+	if err != nil {
+		return nil, err
+	}
+	if template != "" {
+		filter.Template = template
+		set = true
+	}
+	for param, amount := range map[string]*uint64{"minAmount": &filter.MinAmount, "maxAmount": &filter.MaxAmount} {
+		if value := c.QueryParam(param); value != "" {
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s `%s`", param, value)
+			}
+			*amount = v
+			set = true
+		}
+	}
+	if !set {
+		return nil, nil
+	}
+	return filter, nil
+}
+
 func Transaction(c echo.Context) error {
 	cc := c.(*ApiContext)
-	tx, err := cc.Service.GetTransaction(context.TODO(), c.Param("id"))
+	tx, err := cc.Service.GetTransaction(queryContext(c), c.Param("id"))
 	if err != nil {
 		if err == service.ErrNotFound {
 			return echo.ErrNotFound
@@ -1,9 +1,13 @@
 package handler_test
 
 import (
+	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 func TestTransactions(t *testing.T) { // /txs
@@ -33,3 +37,39 @@ func TestTransaction(t *testing.T) { // /txs/{id}
 		require.Equal(t, *tx, resp.Data[0])
 	}
 }
+
+func TestFilteredTransactions(t *testing.T) { // /txs?principal={address}&minAmount={amount}
+	t.Parallel()
+	insertedTxs := generator.Epochs.GetTransactions()
+	for _, tx := range insertedTxs {
+		res := apiServer.Get(t, fmt.Sprintf("%s/txs?principal=%s&minAmount=%d&pagesize=1000", apiPrefix, tx.Sender, tx.Amount))
+		res.RequireOK(t)
+		var resp transactionResp
+		res.RequireUnmarshal(t, &resp)
+		require.NotEmpty(t, resp.Data)
+		for _, filtered := range resp.Data {
+			require.Equal(t, tx.Sender, filtered.Sender)
+			require.GreaterOrEqual(t, filtered.Amount, tx.Amount)
+		}
+	}
+
+	// the seeded transactions are sent by wallets, the vault drains have no template
+	res := apiServer.Get(t, apiPrefix+"/txs?template=wallet&pagesize=1000")
+	res.RequireOK(t)
+	var resp transactionResp
+	res.RequireUnmarshal(t, &resp)
+	require.NotEmpty(t, resp.Data)
+	for _, tx := range resp.Data {
+		require.Equal(t, model.TemplateWallet, tx.Template)
+	}
+	res = apiServer.Get(t, apiPrefix+"/txs?template=vault")
+	res.RequireOK(t)
+	var vaultResp transactionResp
+	res.RequireUnmarshal(t, &vaultResp)
+	require.Empty(t, vaultResp.Data)
+
+	res = apiServer.Get(t, apiPrefix+"/txs?method=spawn")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+	res = apiServer.Get(t, apiPrefix+"/txs?template=unknown")
+	require.Equal(t, http.StatusBadRequest, res.Res.StatusCode)
+}
@@ -12,7 +12,11 @@ func Init(e *echo.Echo) {
 	e.GET("/network-info", handler.NetworkInfo)
 	e.GET("/ws/network-info", handler.NetworkInfoWS)
 
+	e.GET("/events", handler.Events)
+	e.GET("/ws/events", handler.EventsWS)
+
 	e.GET("/epochs", handler.Epochs)
+	e.GET("/epochs/compare", handler.EpochComparison)
 	e.GET("/epochs/:id", handler.Epoch)
 	e.GET("/epochs/:id/:entity", handler.EpochDetails)
 
@@ -21,11 +25,15 @@ func Init(e *echo.Echo) {
 	e.GET("/layers/:id/:entity", handler.LayerDetails)
 
 	e.GET("/smeshers", handler.Smeshers)
+	e.GET("/smeshers/near", handler.SmeshersNear)
+	e.GET("/smeshers/within", handler.SmeshersWithin)
+	e.GET("/smeshers/heatmap", handler.GeoHeatmap)
 	e.GET("/smeshers/:id", handler.Smesher)
 	e.GET("/smeshers/:id/:entity", handler.SmesherDetails)
 
 	e.GET("/atxs", handler.Activations)
 	e.GET("/atxs/:id", handler.Activation)
+	e.GET("/atxs/:id/:entity", handler.ActivationDetails)
 
 	e.GET("/txs", handler.Transactions)
 	e.GET("/txs/:id", handler.Transaction)
@@ -37,9 +45,40 @@ func Init(e *echo.Echo) {
 
 	e.GET("/accounts", handler.Accounts)
 	e.GET("/accounts/:id", handler.Account)
+	e.GET("/accounts/:id/balance", handler.AccountBalance)
 	e.GET("/accounts/:id/:entity", handler.AccountDetails)
 
 	e.GET("/blocks/:id", handler.Block)
 
 	e.GET("/search/:id", handler.Search)
+
+	e.GET("/stats/txs/daily", handler.DailyTransactions)
+	e.GET("/stats/decentralization", handler.Decentralization)
+	e.GET("/stats/smeshers/churn", handler.SmeshersChurn)
+	e.GET("/stats/layers/empty", handler.EmptyLayers)
+	e.GET("/stats/vesting", handler.VestingUnlocks)
+	e.GET("/stats/vesting/drawdown", handler.VaultDrawdown)
+	e.GET("/stats/tx-types", handler.TransactionTypes)
+	e.GET("/stats/blocks", handler.BlockFill)
+	e.GET("/stats/fees/series", handler.FeeSeries)
+	e.GET("/stats/issuance", handler.Issuance)
+	e.GET("/stats/supply-breakdown", handler.SupplyBreakdown)
+	e.GET("/stats/coinbases/:epoch", handler.CoinbaseConcentration)
+	e.GET("/stats/commitments/:epoch", handler.CommitmentHistogram)
+	e.GET("/stats/luck/:epoch", handler.RewardLuck)
+	e.GET("/stats/rollups", handler.Rollups)
+	e.GET("/stats/prices", handler.Prices)
+	e.GET("/stats/cohorts", handler.BalanceCohorts)
+	e.GET("/stats/inclusion-time", handler.InclusionTime)
+	e.GET("/stats/ballots", handler.BallotParticipation)
+	e.GET("/stats/malfeasance", handler.MalfeasanceStats)
+	e.GET("/stats/health", handler.NetworkHealth)
+	e.GET("/stats/totals", handler.Totals)
+
+	e.GET("/charts/space", handler.SpaceChart)
+	e.GET("/charts/smeshers", handler.ActiveSmeshersChart)
+	e.GET("/charts/accounts/daily", handler.DailyAccountsChart)
+	e.GET("/charts/accounts/epochs", handler.EpochAccountsChart)
+
+	e.GET("/calc/rewards", handler.RewardsProjection)
 }
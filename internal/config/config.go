@@ -0,0 +1,92 @@
+// Package config loads the settings of the collector and of the API server from a configuration
+// file, see WithFile.
+//
+// The keys of the file are the names of the flags, e.g.
+//
+//	mongodb: mongodb://localhost:27017
+//	db: explorer
+//	db-compressors: [zstd, snappy]
+//
+// A setting is taken from the first of the command line flags, the environment variables, the
+// configuration file and the default of the flag.
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+// FlagName is the name of the flag of the path of the configuration file.
+const FlagName = "config"
+
+// WithFile returns the flags, which can be set by a configuration file, and the flag of the path of
+// the file. The Before of the app must be set to Load with the returned flags.
+func WithFile(flags []cli.Flag) []cli.Flag {
+	result := make([]cli.Flag, 0, len(flags)+1)
+	result = append(result, &cli.StringFlag{
+		Name:    FlagName,
+		Usage:   "Path of a YAML, TOML or JSON file of settings, keyed by the names of the flags",
+		EnvVars: []string{"SPACEMESH_CONFIG"},
+	})
+	for _, flag := range flags {
+		result = append(result, wrap(flag))
+	}
+	return result
+}
+
+// wrap returns the flag which can be set by the configuration file, the flags of unsupported types
+// are only set by the command line and the environment.
+func wrap(flag cli.Flag) cli.Flag {
+	switch f := flag.(type) {
+	case *cli.BoolFlag:
+		return altsrc.NewBoolFlag(f)
+	case *cli.DurationFlag:
+		return altsrc.NewDurationFlag(f)
+	case *cli.IntFlag:
+		return altsrc.NewIntFlag(f)
+	case *cli.Int64Flag:
+		return altsrc.NewInt64Flag(f)
+	case *cli.UintFlag:
+		return altsrc.NewUintFlag(f)
+	case *cli.Uint64Flag:
+		return altsrc.NewUint64Flag(f)
+	case *cli.Float64Flag:
+		return altsrc.NewFloat64Flag(f)
+	case *cli.StringFlag:
+		return altsrc.NewStringFlag(f)
+	case *cli.StringSliceFlag:
+		return altsrc.NewStringSliceFlag(f)
+	default:
+		return flag
+	}
+}
+
+// Load returns the Before of the app which applies the configuration file to the flags not set by
+// the command line or the environment.
+func Load(flags []cli.Flag) cli.BeforeFunc {
+	return altsrc.InitInputSourceWithContext(flags, func(ctx *cli.Context) (altsrc.InputSourceContext, error) {
+		path := ctx.String(FlagName)
+		if path == "" {
+			return &altsrc.MapInputSource{}, nil
+		}
+		return source(path)
+	})
+}
+
+// source reads the configuration file in the format of its extension.
+func source(path string) (altsrc.InputSourceContext, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return altsrc.NewYamlSourceFromFile(path)
+	case ".toml":
+		return altsrc.NewTomlSourceFromFile(path)
+	case ".json":
+		return altsrc.NewJSONSourceFromFile(path)
+	default:
+		return nil, fmt.Errorf("unsupported config file `%s`, expected .yaml, .yml, .toml or .json", path)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+type settings struct {
+	db          string
+	url         string
+	timeout     time.Duration
+	compressors []string
+}
+
+func run(t *testing.T, args ...string) (*settings, error) {
+	s := &settings{}
+	compressors := cli.NewStringSlice()
+	flags := []cli.Flag{
+		&cli.StringFlag{Name: "db", Value: "explorer", Destination: &s.db},
+		&cli.StringFlag{Name: "mongodb", EnvVars: []string{"TEST_CONFIG_MONGODB"}, Destination: &s.url},
+		&cli.DurationFlag{Name: "db-timeout", Value: time.Second, Destination: &s.timeout},
+		&cli.StringSliceFlag{Name: "db-compressors", Destination: compressors},
+	}
+	app := cli.NewApp()
+	app.Flags = WithFile(flags)
+	app.Before = Load(app.Flags)
+	app.Action = func(*cli.Context) error {
+		s.compressors = compressors.Value()
+		return nil
+	}
+	err := app.Run(append([]string{"app"}, args...))
+	return s, err
+}
+
+func write(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yaml := write(t, "explorer.yaml", "mongodb: mongodb://file:27017\ndb-timeout: 5s\ndb-compressors: [zstd, snappy]\n")
+
+	s, err := run(t)
+	require.NoError(t, err)
+	require.Equal(t, &settings{db: "explorer", timeout: time.Second, compressors: []string{}}, s)
+
+	s, err = run(t, "--config", yaml)
+	require.NoError(t, err)
+	require.Equal(t, &settings{db: "explorer", url: "mongodb://file:27017", timeout: 5 * time.Second, compressors: []string{"zstd", "snappy"}}, s)
+
+	t.Setenv("TEST_CONFIG_MONGODB", "mongodb://env:27017")
+	s, err = run(t, "--config", yaml)
+	require.NoError(t, err)
+	require.Equal(t, "mongodb://env:27017", s.url)
+
+	s, err = run(t, "--config", yaml, "--mongodb", "mongodb://flag:27017", "--db-timeout", "2s")
+	require.NoError(t, err)
+	require.Equal(t, "mongodb://flag:27017", s.url)
+	require.Equal(t, 2*time.Second, s.timeout)
+}
+
+func TestLoadFormats(t *testing.T) {
+	for _, path := range []string{
+		write(t, "explorer.yml", "db: mainnet\n"),
+		write(t, "explorer.toml", "db = \"mainnet\"\n"),
+		write(t, "explorer.json", `{"db": "mainnet"}`),
+	} {
+		s, err := run(t, "--config", path)
+		require.NoError(t, err, path)
+		require.Equal(t, "mainnet", s.db, path)
+	}
+
+	_, err := run(t, "--config", write(t, "explorer.ini", "db=mainnet\n"))
+	require.ErrorContains(t, err, "unsupported config file")
+	_, err = run(t, "--config", filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
@@ -0,0 +1,43 @@
+// Package debug serves the runtime profiles of net/http/pprof and the variables of expvar, to
+// diagnose the memory and the goroutines of a running collector or API server.
+//
+// The admin handlers of the binaries, such as the re-decoding of the transactions by the collector,
+// are served next to them. The handlers are only served on the dedicated admin address of Serve, never by the public API.
+// Importing net/http/pprof and expvar registers them on http.DefaultServeMux too, so the other
+// servers of the binaries must not use it.
+package debug
+
+import (
+	"context"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/internal/httpserver"
+)
+
+// Handler returns the handler of the pprof profiles on /debug/pprof/, of the expvar variables on
+// /debug/vars and of the admin handlers on their pattern.
+func Handler(admin map[string]http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	for pattern, handler := range admin {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// Serve serves Handler on the address in format <host>:<port> until ctx is done, see
+// httpserver.Serve. It returns at once if the address is empty.
+func Serve(ctx context.Context, address string, admin map[string]http.Handler, timeout time.Duration) error {
+	if address == "" {
+		return nil
+	}
+	return httpserver.Serve(ctx, "debug", address, Handler(admin), timeout)
+}
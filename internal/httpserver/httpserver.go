@@ -0,0 +1,45 @@
+// Package httpserver runs the HTTP servers of the collector and of the API server until the root
+// context of the process is cancelled.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// DefaultShutdownTimeout is the time given to the requests in flight and to the collector to flush
+// its state once the process is asked to stop.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Serve serves the handler on the address in format <host>:<port> until ctx is done, then shuts
+// the server down, waiting up to timeout for the requests in flight. It returns nil once the server
+// is shut down and the error of the server if it stops by itself, e.g. when the address is in use.
+func Serve(ctx context.Context, name, address string, handler http.Handler, timeout time.Duration) error {
+	server := &http.Server{Addr: address, Handler: handler}
+	done := make(chan error, 1)
+	go func() {
+		logging.Info("starting "+name+" server", logging.Address(address))
+		done <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-done:
+		return fmt.Errorf("%s server: %w", name, err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutdown %s server: %w", name, err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s server: %w", name, err)
+	}
+	logging.Info(name+" server is shutdown", logging.Address(address))
+	return nil
+}
@@ -0,0 +1,100 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func freeAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestServe(t *testing.T) {
+	address := freeAddress(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		fmt.Fprint(w, "done")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, "test", address, handler, 5*time.Second)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		var res *http.Response
+		var err error
+		require.Eventually(t, func() bool {
+			res, err = http.Get("http://" + address)
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	// the request in flight is served before the server is shut down
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("server shut down before the request in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	require.Equal(t, "done", <-body)
+	require.NoError(t, <-served)
+}
+
+func TestServeError(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	err = Serve(context.Background(), "test", l.Addr().String(), http.NotFoundHandler(), time.Second)
+	require.ErrorContains(t, err, "test server")
+}
+
+func TestServeTimeout(t *testing.T) {
+	address := freeAddress(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, "test", address, handler, 50*time.Millisecond)
+	}()
+	go func() {
+		require.Eventually(t, func() bool {
+			res, err := http.Get("http://" + address)
+			if err == nil {
+				res.Body.Close()
+			}
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+	}()
+	<-started
+	cancel()
+	require.ErrorIs(t, <-served, context.DeadlineExceeded)
+}
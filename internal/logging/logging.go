@@ -0,0 +1,100 @@
+// Package logging configures the structured logger of the collector and of the API server, and
+// provides the fields shared by their logs.
+//
+// The logger backs both zap and the global logger of the go-spacemesh log package, so the printf
+// style logs of the go-spacemesh package follow the level and the format too.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/go-spacemesh/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The output formats of the logs.
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// New returns the logger of the level, one of debug, info, warn and error, writing to w in the
+// format.
+func New(level, format string, w zapcore.WriteSyncer) (*zap.Logger, error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(strings.ToLower(level))); err != nil {
+		return nil, fmt.Errorf("invalid log level `%s`", level)
+	}
+	var encoder zapcore.Encoder
+	switch format {
+	case FormatConsole, "":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	case FormatJSON:
+		config := zap.NewProductionEncoderConfig()
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoder = zapcore.NewJSONEncoder(config)
+	default:
+		return nil, fmt.Errorf("invalid log format `%s`, expected %s or %s", format, FormatConsole, FormatJSON)
+	}
+	return zap.New(zapcore.NewCore(encoder, w, lvl)), nil
+}
+
+// the logs are written at the info level to the console until Setup is called.
+func init() {
+	_ = Setup(zapcore.InfoLevel.String(), FormatConsole)
+}
+
+// Setup installs the logger of the level and the format writing to stdout as the global logger.
+func Setup(level, format string) error {
+	logger, err := New(level, format, zapcore.Lock(os.Stdout))
+	if err != nil {
+		return err
+	}
+	zap.ReplaceGlobals(logger)
+	log.SetLogger(log.NewFromLog(logger))
+	return nil
+}
+
+// Error logs the error at the error level, which the alerting watches.
+func Error(msg string, err error, fields ...zap.Field) {
+	zap.L().Error(msg, append(fields, zap.Error(err))...)
+}
+
+// Warn logs the message at the warn level.
+func Warn(msg string, fields ...zap.Field) {
+	zap.L().Warn(msg, fields...)
+}
+
+// Info logs the message at the info level.
+func Info(msg string, fields ...zap.Field) {
+	zap.L().Info(msg, fields...)
+}
+
+// Debug logs the message at the debug level.
+func Debug(msg string, fields ...zap.Field) {
+	zap.L().Debug(msg, fields...)
+}
+
+func Layer(layer uint32) zap.Field {
+	return zap.Uint32("layer", layer)
+}
+
+func Epoch(epoch uint32) zap.Field {
+	return zap.Uint32("epoch", epoch)
+}
+
+func Collection(name string) zap.Field {
+	return zap.String("collection", name)
+}
+
+func Duration(d time.Duration) zap.Field {
+	return zap.Duration("duration", d)
+}
+
+func Address(address string) zap.Field {
+	return zap.String("address", address)
+}
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New("warn", FormatJSON, zapcore.AddSync(&buf))
+	require.NoError(t, err)
+	logger.Info("skipped")
+	logger.Error("updateLayer", zap.Error(errors.New("boom")), Layer(12), Epoch(3), Collection("layers"), Duration(time.Second))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 1)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal(t, "error", entry["level"])
+	require.Equal(t, "updateLayer", entry["msg"])
+	require.Equal(t, "boom", entry["error"])
+	require.Equal(t, float64(12), entry["layer"])
+	require.Equal(t, float64(3), entry["epoch"])
+	require.Equal(t, "layers", entry["collection"])
+	require.Equal(t, float64(1), entry["duration"])
+
+	buf.Reset()
+	logger, err = New("DEBUG", FormatConsole, zapcore.AddSync(&buf))
+	require.NoError(t, err)
+	logger.Debug("layer ingested", Layer(12))
+	require.Contains(t, buf.String(), "layer ingested")
+	require.Contains(t, buf.String(), `"layer": 12`)
+
+	_, err = New("verbose", FormatJSON, zapcore.AddSync(&buf))
+	require.ErrorContains(t, err, "invalid log level")
+	_, err = New("info", "xml", zapcore.AddSync(&buf))
+	require.ErrorContains(t, err, "invalid log format")
+}
@@ -0,0 +1,60 @@
+// Package pipeline exposes metrics describing the collector ingestion stages.
+// Stages are identified by name and report queue depth, throughput and latency,
+// so back-pressure can be traced to the stage where it originates.
+package pipeline
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Stage names used as the `stage` label value.
+const (
+	StageFetchLayer      = "fetch_layer"
+	StageFetchAccounts   = "fetch_accounts"
+	StageFetchRewards    = "fetch_rewards"
+	StageDecodeLayer     = "decode_layer"
+	StageStoreLayer      = "store_layer"
+	StageEnqueueLayer    = "enqueue_layer"
+	StageWriteLayer      = "write_layer"
+	StageWriteAccounts   = "write_accounts"
+	StageWriteReward     = "write_reward"
+	StageEpochStats      = "epoch_stats"
+	StageAccountBalances = "account_balances"
+)
+
+var (
+	metricQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_pipeline_queue_depth",
+		Help: "Number of items waiting to be processed by a pipeline stage",
+	}, []string{"stage"})
+
+	metricProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_pipeline_processed_total",
+		Help: "Number of items processed by a pipeline stage",
+	}, []string{"stage"})
+
+	metricBlocked = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_pipeline_blocked_seconds_total",
+		Help: "Time spent waiting for room in the queue of a pipeline stage",
+	}, []string{"stage"})
+
+	metricDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "explorer_pipeline_stage_duration_seconds",
+		Help:    "Time spent by a pipeline stage processing a single item",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"stage"})
+)
+
+// Observe records one processed item for the stage which started at `start`.
+func Observe(stage string, start time.Time) {
+	metricDuration.WithLabelValues(stage).Observe(time.Since(start).Seconds())
+	metricProcessed.WithLabelValues(stage).Inc()
+}
+
+// SetQueueDepth reports the current number of items waiting for the stage.
+func SetQueueDepth(stage string, depth int) {
+	metricQueueDepth.WithLabelValues(stage).Set(float64(depth))
+}
@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// Queue is a bounded channel between two stages, its depth is reported as the queue depth of the
+// consuming stage. Push blocks while the queue is full, so a slow stage holds back the stages
+// feeding it instead of buffering without bound, and the time spent blocked is reported too.
+type Queue[T any] struct {
+	stage string
+	items chan T
+}
+
+// NewQueue returns a queue of at most size items waiting for the stage.
+func NewQueue[T any](stage string, size int) *Queue[T] {
+	return &Queue[T]{stage: stage, items: make(chan T, size)}
+}
+
+// Push queues the item, waiting for room if the queue is full.
+func (q *Queue[T]) Push(item T) {
+	start := time.Now()
+	q.items <- item
+	metricBlocked.WithLabelValues(q.stage).Add(time.Since(start).Seconds())
+	SetQueueDepth(q.stage, len(q.items))
+}
+
+// Pop waits for the next item. Once ctx is done, it returns the items left in the queue, so that
+// the stage drains it, and then false.
+func (q *Queue[T]) Pop(ctx context.Context) (T, bool) {
+	select {
+	case item := <-q.items:
+		SetQueueDepth(q.stage, len(q.items))
+		return item, true
+	case <-ctx.Done():
+	}
+	select {
+	case item := <-q.items:
+		SetQueueDepth(q.stage, len(q.items))
+		return item, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
+
+// Len returns the number of items waiting in the queue.
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
@@ -0,0 +1,73 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueBackpressure(t *testing.T) {
+	q := NewQueue[int]("test_queue", 2)
+	q.Push(1)
+	q.Push(2)
+	require.Equal(t, 2, q.Len())
+	require.Equal(t, float64(2), testutil.ToFloat64(metricQueueDepth.WithLabelValues("test_queue")))
+
+	// the queue is full, the producer waits for the consumer
+	pushed := make(chan struct{})
+	go func() {
+		q.Push(3)
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+		t.Fatal("pushed to a full queue")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Equal(t, 1, pop(t, q))
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("producer still blocked after a pop")
+	}
+	require.Equal(t, 2, pop(t, q))
+	require.Equal(t, 3, pop(t, q))
+	require.Zero(t, q.Len())
+	require.Zero(t, testutil.ToFloat64(metricQueueDepth.WithLabelValues("test_queue")))
+	require.Greater(t, testutil.ToFloat64(metricBlocked.WithLabelValues("test_queue")), 0.04)
+}
+
+func pop(t *testing.T, q *Queue[int]) int {
+	item, ok := q.Pop(context.Background())
+	require.True(t, ok)
+	return item
+}
+
+func TestQueuePopDone(t *testing.T) {
+	q := NewQueue[int]("test_queue_done", 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	popped := make(chan bool)
+	go func() {
+		_, ok := q.Pop(ctx)
+		popped <- ok
+	}()
+	cancel()
+	select {
+	case ok := <-popped:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("pop still blocked after ctx is done")
+	}
+
+	// the items left in the queue are returned first
+	q.Push(1)
+	item, ok := q.Pop(ctx)
+	require.True(t, ok)
+	require.Equal(t, 1, item)
+	_, ok = q.Pop(ctx)
+	require.False(t, ok)
+}
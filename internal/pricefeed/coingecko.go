@@ -0,0 +1,41 @@
+package pricefeed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CoinGecko reads the price from the simple price API of CoinGecko.
+type CoinGecko struct {
+	client  *http.Client
+	baseURL string
+	coin    string
+	apiKey  string
+}
+
+func NewCoinGecko(coin, apiKey string) *CoinGecko {
+	return &CoinGecko{client: http.DefaultClient, baseURL: "https://api.coingecko.com/api/v3", coin: coin, apiKey: apiKey}
+}
+
+func (g *CoinGecko) Name() string {
+	return "coingecko"
+}
+
+func (g *CoinGecko) Price(ctx context.Context) (float64, error) {
+	query := url.Values{"ids": {g.coin}, "vs_currencies": {"usd"}}
+	headers := map[string]string{}
+	if g.apiKey != "" {
+		headers["x-cg-demo-api-key"] = g.apiKey
+	}
+	var prices map[string]map[string]float64
+	if err := getJSON(ctx, g.client, g.baseURL+"/simple/price?"+query.Encode(), headers, &prices); err != nil {
+		return 0, err
+	}
+	usd, ok := prices[g.coin]["usd"]
+	if !ok {
+		return 0, fmt.Errorf("no price of `%s`", g.coin)
+	}
+	return usd, nil
+}
@@ -0,0 +1,50 @@
+package pricefeed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// CoinMarketCap reads the price from the latest quotes API of CoinMarketCap, which requires a key.
+type CoinMarketCap struct {
+	client  *http.Client
+	baseURL string
+	symbol  string
+	apiKey  string
+}
+
+func NewCoinMarketCap(symbol, apiKey string) *CoinMarketCap {
+	return &CoinMarketCap{client: http.DefaultClient, baseURL: "https://pro-api.coinmarketcap.com", symbol: symbol, apiKey: apiKey}
+}
+
+func (m *CoinMarketCap) Name() string {
+	return "coinmarketcap"
+}
+
+func (m *CoinMarketCap) Price(ctx context.Context) (float64, error) {
+	query := url.Values{"symbol": {m.symbol}, "convert": {"USD"}}
+	var quotes struct {
+		Data map[string][]struct {
+			Quote map[string]struct {
+				Price float64 `json:"price"`
+			} `json:"quote"`
+		} `json:"data"`
+	}
+	err := getJSON(ctx, m.client, m.baseURL+"/v2/cryptocurrency/quotes/latest?"+query.Encode(),
+		map[string]string{"X-CMC_PRO_API_KEY": m.apiKey}, &quotes)
+	if err != nil {
+		return 0, err
+	}
+	// the symbol may be shared by several coins, the first one is the most capitalized
+	coins := quotes.Data[m.symbol]
+	if len(coins) == 0 {
+		return 0, fmt.Errorf("no price of `%s`", m.symbol)
+	}
+	usd, ok := coins[0].Quote["USD"]
+	if !ok {
+		return 0, fmt.Errorf("no USD price of `%s`", m.symbol)
+	}
+	return usd.Price, nil
+}
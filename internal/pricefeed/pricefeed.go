@@ -0,0 +1,102 @@
+// Package pricefeed records the SMH price in USD from a public price API. The feed is optional:
+// without it the collector makes no external call and the API serves no fiat values.
+package pricefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// requestTimeout bounds a request to the price API.
+const requestTimeout = 10 * time.Second
+
+// Feed returns the current SMH price in USD.
+type Feed interface {
+	Name() string
+	Price(ctx context.Context) (float64, error)
+}
+
+// New creates a feed from its url:
+//
+//	coingecko://[<api key>@]<coin id>, e.g. coingecko://spacemesh
+//	coinmarketcap://<api key>@<symbol>, e.g. coinmarketcap://<api key>@SMH
+//
+// The coingecko key is a demo key, the public API is used without it.
+func New(rawURL string) (Feed, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid price feed url `%s`: %w", rawURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid price feed url `%s`: missing coin", rawURL)
+	}
+	switch u.Scheme {
+	case "coingecko":
+		return NewCoinGecko(u.Host, u.User.Username()), nil
+	case "coinmarketcap":
+		if u.User.Username() == "" {
+			return nil, fmt.Errorf("invalid price feed url `%s`: missing api key", rawURL)
+		}
+		return NewCoinMarketCap(strings.ToUpper(u.Host), u.User.Username()), nil
+	}
+	return nil, fmt.Errorf("unsupported price feed `%s`", u.Scheme)
+}
+
+// Record saves the price of the feed every interval until the context is done. The failures are
+// logged, a missed price only leaves a gap in the recorded prices.
+func Record(ctx context.Context, feed Feed, interval time.Duration, save func(context.Context, *model.Price) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := record(ctx, feed, save); err != nil {
+			logging.Error(fmt.Sprintf("price feed %s", feed.Name()), err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func record(ctx context.Context, feed Feed, save func(context.Context, *model.Price) error) error {
+	usd, err := feed.Price(ctx)
+	if err != nil {
+		return err
+	}
+	return save(ctx, &model.Price{Timestamp: uint32(time.Now().Unix()), USD: usd, Source: feed.Name()})
+}
+
+// getJSON decodes the response of a GET request with the headers.
+func getJSON(parent context.Context, client *http.Client, rawURL string, headers map[string]string, out any) error {
+	ctx, cancel := context.WithTimeout(parent, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decode price: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package pricefeed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestNew(t *testing.T) {
+	feed, err := New("coingecko://spacemesh")
+	require.NoError(t, err)
+	require.Equal(t, &CoinGecko{client: http.DefaultClient, baseURL: "https://api.coingecko.com/api/v3", coin: "spacemesh"}, feed)
+
+	feed, err = New("coinmarketcap://key@smh")
+	require.NoError(t, err)
+	require.Equal(t, "SMH", feed.(*CoinMarketCap).symbol)
+	require.Equal(t, "key", feed.(*CoinMarketCap).apiKey)
+
+	for _, rawURL := range []string{"coinmarketcap://SMH", "coingecko://", "binance://SMHUSDT"} {
+		_, err = New(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestCoinGecko(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/simple/price", r.URL.Path)
+		require.Equal(t, "key", r.Header.Get("x-cg-demo-api-key"))
+		if r.URL.Query().Get("ids") != "spacemesh" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"spacemesh":{"usd":0.4231}}`))
+	}))
+	defer server.Close()
+
+	feed := NewCoinGecko("spacemesh", "key")
+	feed.baseURL = server.URL
+	usd, err := feed.Price(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0.4231, usd)
+
+	feed.coin = "unknown"
+	_, err = feed.Price(context.Background())
+	require.Error(t, err)
+}
+
+func TestCoinMarketCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CMC_PRO_API_KEY") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "/v2/cryptocurrency/quotes/latest", r.URL.Path)
+		w.Write([]byte(`{"data":{"SMH":[{"quote":{"USD":{"price":0.5}}},{"quote":{"USD":{"price":7}}}]}}`))
+	}))
+	defer server.Close()
+
+	feed := NewCoinMarketCap("SMH", "key")
+	feed.baseURL = server.URL
+	usd, err := feed.Price(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0.5, usd)
+
+	feed.apiKey = "wrong"
+	_, err = feed.Price(context.Background())
+	require.ErrorContains(t, err, "401")
+}
+
+func TestRecord(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"spacemesh":{"usd":2}}`))
+	}))
+	defer server.Close()
+	feed := NewCoinGecko("spacemesh", "")
+	feed.baseURL = server.URL
+
+	var saved *model.Price
+	require.NoError(t, record(context.Background(), feed, func(_ context.Context, price *model.Price) error {
+		saved = price
+		return nil
+	}))
+	require.Equal(t, 2.0, saved.USD)
+	require.Equal(t, "coingecko", saved.Source)
+	require.NoError(t, saved.Validate())
+}
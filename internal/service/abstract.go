@@ -4,18 +4,23 @@ import (
 	"context"
 	"errors"
 
+	"github.com/spacemeshos/explorer-backend/internal/storage/changestream"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
 // ErrNotFound is returned when a resource is not found. Router will serve 404 error if this is returned.
 var ErrNotFound = errors.New("not found")
 
+// ErrEventsDisabled is returned by Subscribe if the real-time events are not enabled.
+var ErrEventsDisabled = errors.New("real-time events are disabled")
+
 // AppService is an interface for interacting with the app collection.
 type AppService interface {
 	GetState(ctx context.Context) (*model.NetworkInfo, *model.Epoch, *model.Layer, error)
 	GetNetworkInfo(ctx context.Context) (*model.NetworkInfo, error)
 	Search(ctx context.Context, search string) (string, error)
 	Ping(ctx context.Context) error
+	Subscribe(kinds ...changestream.Kind) (*changestream.Subscription, error)
 
 	model.EpochService
 	model.LayerService
@@ -26,4 +31,6 @@ type AppService interface {
 	model.ActivationService
 	model.AppService
 	model.BlockService
+	model.StatsService
+	model.LabelService
 }
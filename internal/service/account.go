@@ -4,12 +4,12 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
 	"github.com/spacemeshos/address"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -17,7 +17,7 @@ import (
 func (e *Service) GetAccount(ctx context.Context, accountID string) (*model.Account, error) {
 	addr, err := address.StringToAddress(accountID)
 	if err != nil {
-		log.Err(fmt.Errorf("GetAccount error: %v", err))
+		logging.Error("GetAccount error", err)
 		return nil, ErrNotFound
 	}
 
@@ -68,12 +68,44 @@ func (e *Service) GetAccount(ctx context.Context, accountID string) (*model.Acco
 
 // GetAccounts returns accounts by filter.
 func (e *Service) GetAccounts(ctx context.Context, page, perPage int64) ([]*model.Account, int64, error) {
-	return e.getAccounts(ctx, &bson.D{}, e.getFindOptions("layer", page, perPage).SetProjection(bson.D{
+	load := func() (*accountsPage, error) {
+		accs, total, err := e.getAccounts(ctx, &bson.D{}, e.getFindOptions("layer", page, perPage).SetProjection(bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "layer", Value: 0},
+		}))
+		if err != nil {
+			return nil, err
+		}
+		return &accountsPage{Accounts: accs, Total: total}, nil
+	}
+	if page != 1 {
+		res, err := load()
+		if err != nil {
+			return nil, 0, err
+		}
+		return res.Accounts, res.Total, nil
+	}
+	res, err := cached(ctx, e.cache, cache.KeyTopAccounts, fmt.Sprint(perPage), load)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Accounts, res.Total, nil
+}
+
+// GetTemplateAccounts returns the accounts spawned with the template.
+func (e *Service) GetTemplateAccounts(ctx context.Context, template string, page, perPage int64) ([]*model.Account, int64, error) {
+	return e.getAccounts(ctx, &bson.D{{Key: "template", Value: template}}, e.getFindOptions("layer", page, perPage).SetProjection(bson.D{
 		{Key: "_id", Value: 0},
 		{Key: "layer", Value: 0},
 	}))
 }
 
+// accountsPage is the cached first page of accounts.
+type accountsPage struct {
+	Accounts []*model.Account `json:"accounts"`
+	Total    int64            `json:"total"`
+}
+
 // GetAccountTransactions returns transactions by account id.
 func (e *Service) GetAccountTransactions(ctx context.Context, accountID string, page, perPage int64) ([]*model.Transaction, int64, error) {
 	addr, err := address.StringToAddress(accountID)
@@ -104,6 +136,40 @@ func (e *Service) GetAccountRewards(ctx context.Context, accountID string, page,
 	return e.getRewards(ctx, &bson.D{{Key: "coinbase", Value: addr.String()}}, opts)
 }
 
+// GetAccountBalanceAt returns the balance of the account after the layer.
+func (e *Service) GetAccountBalanceAt(ctx context.Context, accountID string, layer uint32) (*model.AccountSnapshot, error) {
+	addr, err := address.StringToAddress(accountID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	total, err := e.storage.CountAccounts(ctx, &bson.D{{Key: "address", Value: addr.String()}})
+	if err != nil {
+		return nil, fmt.Errorf("error count accounts: %w", err)
+	}
+	if total == 0 {
+		return nil, ErrNotFound
+	}
+	balance, err := e.storage.GetBalanceAt(ctx, addr.String(), layer)
+	if err != nil {
+		return nil, fmt.Errorf("error get balance at layer: %w", err)
+	}
+	return balance, nil
+}
+
+// GetAccountSmeshers returns the smeshers paying their rewards to the account, with the sum and the
+// number of the rewards paid, highest sum first.
+func (e *Service) GetAccountSmeshers(ctx context.Context, accountID string, page, perPage int64) (smeshers []*model.SmesherCoinbase, total int64, err error) {
+	addr, err := address.StringToAddress(accountID)
+	if err != nil {
+		return nil, 0, ErrNotFound
+	}
+	total, err = e.storage.FindPage(ctx, "coinbases", &bson.D{{Key: "coinbase", Value: addr.String()}}, e.getFindOptions("totalRewards", page, perPage), &smeshers)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get account smeshers: %w", err)
+	}
+	return smeshers, total, nil
+}
+
 func (e *Service) getAccounts(ctx context.Context, filter *bson.D, options *options.FindOptions) (accs []*model.Account, total int64, err error) {
 	total, err = e.storage.CountAccounts(ctx, filter)
 	if err != nil {
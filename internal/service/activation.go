@@ -26,19 +26,23 @@ func (e *Service) GetActivation(ctx context.Context, activationID string) (*mode
 	if total == 0 {
 		return nil, ErrNotFound
 	}
+	// the analytics store does not link the rewards to the activations
+	atx[0].Rewards, atx[0].RewardsCount, err = e.storage.GetTotalRewards(ctx, &bson.D{{Key: "atx", Value: atx[0].Id}})
+	if err != nil {
+		return nil, fmt.Errorf("error count atx rewards: %w", err)
+	}
 	return atx[0], nil
 }
 
-func (e *Service) getActivations(ctx context.Context, filter *bson.D, options *options.FindOptions) (atxs []*model.Activation, total int64, err error) {
-	total, err = e.storage.CountActivations(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error count atxs: %w", err)
-	}
-	if total == 0 {
-		return []*model.Activation{}, 0, nil
-	}
+// GetActivationRewards returns the rewards earned by the atx.
+func (e *Service) GetActivationRewards(ctx context.Context, activationID string, page, perPage int64) ([]*model.Reward, int64, error) {
+	opts := e.getFindOptions("layer", page, perPage)
+	opts.SetProjection(bson.D{})
+	return e.getRewards(ctx, &bson.D{{Key: "atx", Value: strings.ToLower(activationID)}}, opts)
+}
 
-	atxs, err = e.storage.GetActivations(ctx, filter, options)
+func (e *Service) getActivations(ctx context.Context, filter *bson.D, options *options.FindOptions) (atxs []*model.Activation, total int64, err error) {
+	total, err = e.storage.FindPage(ctx, "activations", filter, options, &atxs)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error get atxs: %w", err)
 	}
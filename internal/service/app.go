@@ -29,14 +29,7 @@ func (e *Service) GetApp(ctx context.Context, appID string) (*model.App, error)
 }
 
 func (e *Service) getApps(ctx context.Context, filter *bson.D, options *options.FindOptions) (apps []*model.App, total int64, err error) {
-	total, err = e.storage.CountApps(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error count apps: %w", err)
-	}
-	if total == 0 {
-		return []*model.App{}, 0, nil
-	}
-	apps, err = e.storage.GetApps(ctx, filter, options)
+	total, err = e.storage.FindPage(ctx, "apps", filter, options, &apps)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error get apps: %w", err)
 	}
@@ -7,7 +7,9 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -20,18 +22,18 @@ func (e *Service) GetBlock(ctx context.Context, blockID string) (*model.Block, e
 	if len(blocks) == 0 {
 		return nil, ErrNotFound
 	}
+	// the certificate is optional, the block is returned without it if it can't be read
+	cert, err := e.storage.GetBlockCertificate(ctx, blocks[0].Id)
+	if err != nil {
+		logging.Error("GetBlock: get block certificate", err, zap.String("block", blocks[0].Id))
+	}
+	blocks[0].Certificate = cert
 	return blocks[0], nil
 }
 
 func (e *Service) getBlocks(ctx context.Context, filter *bson.D, options *options.FindOptions) (blocks []*model.Block, total int64, err error) {
-	total, err = e.storage.CountBlocks(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error count blocks: %w", err)
-	}
-	if total == 0 {
-		return []*model.Block{}, 0, nil
-	}
-	blocks, err = e.storage.GetBlocks(ctx, filter, options)
+	filter = visible(ctx, filter)
+	total, err = e.storage.FindPage(ctx, "blocks", filter, options, &blocks)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error get blocks: %w", err)
 	}
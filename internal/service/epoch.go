@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -18,15 +19,21 @@ func (e *Service) GetCurrentEpoch(ctx context.Context) (*model.Epoch, error) {
 	loadTime := e.currentEpochLoaded
 	e.currentEpochMU.RUnlock()
 	if epoch == nil || loadTime.Add(e.cacheTTL).Unix() < time.Now().Unix() {
-		now := time.Now().Unix()
-		epochs, err := e.storage.GetEpochs(ctx, &bson.D{{Key: "start", Value: bson.D{{Key: "$lte", Value: now}}}}, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+		var err error
+		epoch, err = cached(ctx, e.cache, cache.KeyCurrentEpoch, "", func() (*model.Epoch, error) {
+			now := time.Now().Unix()
+			epochs, err := e.storage.GetEpochs(ctx, &bson.D{{Key: "start", Value: bson.D{{Key: "$lte", Value: now}}}}, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+			if err != nil || len(epochs) == 0 {
+				return nil, err
+			}
+			return epochs[0], nil
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to get epoch: %w", err)
 		}
-		if len(epochs) == 0 {
+		if epoch == nil {
 			return nil, nil
 		}
-		epoch = epochs[0]
 
 		e.currentEpochMU.Lock()
 		e.currentEpoch = epoch
@@ -48,6 +55,42 @@ func (e *Service) GetEpoch(ctx context.Context, epochNum int) (*model.Epoch, err
 	return epoch, nil
 }
 
+// CompareEpochs returns the stats of the epochs a and b side by side, with their changes from a to b.
+func (e *Service) CompareEpochs(ctx context.Context, a, b int) (*model.EpochComparison, error) {
+	epochA, err := e.GetEpoch(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	epochB, err := e.GetEpoch(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewEpochComparison(epochA, epochB), nil
+}
+
+// GetEpochStatsHistory returns the stats of the epoch computed by every version of the formulas,
+// latest version first.
+func (e *Service) GetEpochStatsHistory(ctx context.Context, epochNum int, page, perPage int64) (history []*model.EpochStats, total int64, err error) {
+	filter := &bson.D{{Key: "epoch", Value: epochNum}}
+	total, err = e.storage.FindPage(ctx, "epoch_stats", filter, e.getFindOptions("version", page, perPage), &history)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get epoch stats: %w", err)
+	}
+	return history, total, nil
+}
+
+// GetEpochStats returns the stats of the epoch computed by the given version of the formulas.
+func (e *Service) GetEpochStats(ctx context.Context, epochNum int, version uint32) (*model.EpochStats, error) {
+	stats, err := e.storage.GetEpochStats(ctx, &bson.D{{Key: "epoch", Value: epochNum}, {Key: "version", Value: version}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get epoch `%d` stats version %d: %w", epochNum, version, err)
+	}
+	if len(stats) == 0 {
+		return nil, ErrNotFound
+	}
+	return stats[0], nil
+}
+
 // GetEpochs returns list of epochs.
 func (e *Service) GetEpochs(ctx context.Context, page, perPage int64) ([]*model.Epoch, int64, error) {
 	total, err := e.storage.CountEpochs(ctx, &bson.D{})
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetNetworkHealth returns the composite health score of the network, see model.NewNetworkHealth.
+func (e *Service) GetNetworkHealth(ctx context.Context) (*model.NetworkHealth, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	current, err := e.GetCurrentEpoch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get current epoch: %w", err)
+	}
+	var previous *model.Epoch
+	if current != nil && current.Number > 0 {
+		previous, err = e.GetEpoch(ctx, int(current.Number-1))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+	return model.NewNetworkHealth(net, current, previous, uint32(time.Now().Unix())), nil
+}
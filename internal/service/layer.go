@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -18,14 +19,20 @@ func (e *Service) GetCurrentLayer(ctx context.Context) (*model.Layer, error) {
 	loadTime := e.currentLayerLoaded
 	e.currentLayerMU.RUnlock()
 	if layer == nil || loadTime.Add(e.cacheTTL).Unix() < time.Now().Unix() {
-		layers, err := e.storage.GetLayers(ctx, &bson.D{}, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+		var err error
+		layer, err = cached(ctx, e.cache, cache.KeyCurrentLayer, "", func() (*model.Layer, error) {
+			layers, err := e.storage.GetLayers(ctx, &bson.D{}, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+			if err != nil || len(layers) == 0 {
+				return nil, err
+			}
+			return layers[0], nil
+		})
 		if err != nil {
 			return nil, fmt.Errorf("error get layers: %s", err)
 		}
-		if len(layers) == 0 {
+		if layer == nil {
 			return nil, nil
 		}
-		layer = layers[0]
 
 		e.currentLayerMU.Lock()
 		e.currentLayer = layer
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetPrices returns the SMH prices in USD recorded in [from, to], by timestamp. There is none
+// without a price feed.
+func (e *Service) GetPrices(ctx context.Context, from, to uint32) ([]*model.Price, error) {
+	prices, err := e.storage.GetPrices(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error get prices: %w", err)
+	}
+	return prices, nil
+}
+
+// getPrices returns the prices of the periods starting in [from, to) and lasting length, including
+// the ones of the PriceLookback before, see model.Prices.Mean.
+func (e *Service) getPrices(ctx context.Context, from, to, length uint32) (model.Prices, error) {
+	return e.GetPrices(ctx, from-min(from, model.PriceLookback), to+length)
+}
+
+// setDailyTransactionsUSD sets the value in USD of the amounts of the days with recorded prices.
+func (e *Service) setDailyTransactionsUSD(ctx context.Context, days []*model.DailyTransactions) error {
+	if len(days) == 0 {
+		return nil
+	}
+	from, to := days[0].Day, days[0].Day
+	for _, day := range days {
+		from, to = min(from, day.Day), max(to, day.Day)
+	}
+	length := model.PeriodSeconds(model.PeriodDay)
+	prices, err := e.getPrices(ctx, from, to, length)
+	if err != nil || len(prices) == 0 {
+		return err
+	}
+	for _, day := range days {
+		day.AmountUSD = model.Fiat(day.Amount, prices.Mean(day.Day, day.Day+length))
+	}
+	return nil
+}
+
+// setFeeSeriesUSD sets the value in USD of the total fees of the layers or of the epochs with
+// recorded prices.
+func (e *Service) setFeeSeriesUSD(ctx context.Context, period string, series []*model.FeeSeries) error {
+	if len(series) == 0 {
+		return nil
+	}
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return fmt.Errorf("error get network info: %w", err)
+	}
+	length := net.LayerDuration
+	if period == model.PeriodEpoch {
+		length *= net.EpochNumLayers
+	}
+	if length == 0 {
+		return nil
+	}
+	start := func(s *model.FeeSeries) uint32 {
+		return net.GenesisTime + s.Period*length
+	}
+	from, to := start(series[0]), start(series[0])
+	for _, s := range series {
+		from, to = min(from, start(s)), max(to, start(s))
+	}
+	prices, err := e.getPrices(ctx, from, to, length)
+	if err != nil || len(prices) == 0 {
+		return err
+	}
+	for _, s := range series {
+		s.TotalUSD = model.Fiat(int64(s.Total), prices.Mean(start(s), start(s)+length))
+	}
+	return nil
+}
+
+// setRollupsUSD sets the value in USD of the volume, the fees and the rewards of the rollups with
+// recorded prices.
+func (e *Service) setRollupsUSD(ctx context.Context, period string, rollups []*model.Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	length := model.PeriodSeconds(period)
+	from, to := rollups[0].Start, rollups[0].Start
+	for _, rollup := range rollups {
+		from, to = min(from, rollup.Start), max(to, rollup.Start)
+	}
+	prices, err := e.getPrices(ctx, from, to, length)
+	if err != nil || len(prices) == 0 {
+		return err
+	}
+	for _, rollup := range rollups {
+		usd := prices.Mean(rollup.Start, rollup.Start+length)
+		rollup.VolumeUSD = model.Fiat(rollup.Volume, usd)
+		rollup.FeesUSD = model.Fiat(rollup.Fees, usd)
+		rollup.RewardsUSD = model.Fiat(rollup.Rewards, usd)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -39,14 +40,7 @@ func (e *Service) GetRewards(ctx context.Context, page, perPage int64) ([]*model
 }
 
 func (e *Service) getRewards(ctx context.Context, filter *bson.D, options *options.FindOptions) (rewards []*model.Reward, total int64, err error) {
-	total, err = e.storage.CountRewards(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error count rewards: %w", err)
-	}
-	if total == 0 {
-		return []*model.Reward{}, 0, nil
-	}
-	rewards, err = e.storage.GetRewards(ctx, filter, options)
+	total, err = e.storage.FindPage(ctx, "rewards", filter, options, &rewards)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error get rewards: %w", err)
 	}
@@ -54,5 +48,12 @@ func (e *Service) getRewards(ctx context.Context, filter *bson.D, options *optio
 }
 
 func (e *Service) GetTotalRewards(ctx context.Context, filter *bson.D) (int64, int64, error) {
+	if e.analytics != nil {
+		total, count, err := e.analytics.GetTotalRewards(ctx, filter)
+		if err == nil {
+			return total, count, nil
+		}
+		logging.Error("analytics: get total rewards", err)
+	}
 	return e.storage.GetTotalRewards(ctx, filter)
 }
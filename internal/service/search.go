@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+
+	"github.com/spacemeshos/explorer-backend/model"
 )
 
 const (
@@ -15,6 +17,8 @@ const (
 	blockIDLength = 42
 	// idLength is the expected length of a transactionID | activation | smesher.
 	idLength = 66
+	// labelsSearchLimit is the number of labels returned by a search by name.
+	labelsSearchLimit = 10
 )
 
 // Search try guess entity to search and find related one.
@@ -71,3 +75,16 @@ func (e *Service) Search(ctx context.Context, search string) (string, error) {
 	}
 	return "", ErrNotFound
 }
+
+// SearchLabels returns the accounts and smeshers whose name contains a word of the text, best
+// matches first.
+func (e *Service) SearchLabels(ctx context.Context, text string) ([]*model.Label, error) {
+	labels, err := e.storage.SearchLabels(ctx, text, labelsSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, ErrNotFound
+	}
+	return labels, nil
+}
@@ -6,12 +6,17 @@ import (
 	"sync"
 	"time"
 
-	"github.com/spacemeshos/go-spacemesh/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/internal/storage/changestream"
 	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
 	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 // Service main app service which working with database.
@@ -28,8 +33,11 @@ type Service struct {
 	currentLayerMU     *sync.RWMutex
 	currentLayerLoaded time.Time
 
-	cacheTTL time.Duration
-	storage  storagereader.StorageReader
+	cacheTTL  time.Duration
+	storage   storagereader.StorageReader
+	analytics storagereader.AnalyticsReader
+	cache     *cache.Cache
+	events    *changestream.Bus
 }
 
 // NewService creates new service instance.
@@ -43,11 +51,74 @@ func NewService(reader storagereader.StorageReader, cacheTTL time.Duration) *Ser
 	}
 
 	if _, err := service.GetNetworkInfo(context.Background()); err != nil {
-		log.Err(fmt.Errorf("error load network info: %w", err))
+		logging.Error("error load network info", err)
 	}
 	return service
 }
 
+// SetAnalytics routes the rewards aggregations to an analytics store. Mongo still serves them
+// if the analytics store fails.
+func (e *Service) SetAnalytics(reader storagereader.AnalyticsReader) {
+	e.analytics = reader
+}
+
+// SetCache shares the network info, current layer and epoch and the first accounts pages
+// between API servers through a Redis cache invalidated by the collector.
+func (e *Service) SetCache(c *cache.Cache) {
+	e.cache = c
+}
+
+// SetEvents enables the real-time events published by the change streams watcher.
+func (e *Service) SetEvents(bus *changestream.Bus) {
+	e.events = bus
+}
+
+// Subscribe returns a subscription to the real-time events of the given kinds, or to all events if
+// no kind is given.
+func (e *Service) Subscribe(kinds ...changestream.Kind) (*changestream.Subscription, error) {
+	if e.events == nil {
+		return nil, ErrEventsDisabled
+	}
+	return e.events.Subscribe(kinds...), nil
+}
+
+// cached returns the document stored under key in the Redis cache, or loads it and stores it.
+// Cache failures fall back to load. The field selects an entry of a hash key if not empty.
+func cached[T any](ctx context.Context, c *cache.Cache, key, field string, load func() (T, error)) (T, error) {
+	if c == nil {
+		return load()
+	}
+	var (
+		v   T
+		hit bool
+		err error
+	)
+	if field == "" {
+		hit, err = c.Get(ctx, key, &v)
+	} else {
+		hit, err = c.GetField(ctx, key, field, &v)
+	}
+	if err != nil {
+		logging.Error("get from cache", err, zap.String("key", key))
+	}
+	if hit {
+		return v, nil
+	}
+	v, err = load()
+	if err != nil {
+		return v, err
+	}
+	if field == "" {
+		err = c.Set(ctx, key, v)
+	} else {
+		err = c.SetField(ctx, key, field, v)
+	}
+	if err != nil {
+		logging.Error("set to cache", err, zap.String("key", key))
+	}
+	return v, nil
+}
+
 // GetState returns state of the network, current layer and epoch.
 func (e *Service) GetState(ctx context.Context) (*model.NetworkInfo, *model.Epoch, *model.Layer, error) {
 	net, err := e.GetNetworkInfo(ctx)
@@ -72,7 +143,9 @@ func (e *Service) GetNetworkInfo(ctx context.Context) (net *model.NetworkInfo, e
 	loadTime := e.networkInfoLoaded
 	e.networkInfoMU.RUnlock()
 	if net == nil || loadTime.Add(e.cacheTTL).Unix() < time.Now().Unix() {
-		net, err = e.storage.GetNetworkInfo(ctx)
+		net, err = cached(ctx, e.cache, cache.KeyNetworkInfo, "", func() (*model.NetworkInfo, error) {
+			return e.storage.GetNetworkInfo(ctx)
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed get networkInfo: %w", err)
 		}
@@ -104,12 +177,32 @@ func (e *Service) getEpochLayers(epoch int) (uint32, uint32) {
 	e.networkInfoMU.RLock()
 	net := e.networkInfo
 	e.networkInfoMU.RUnlock()
-	start := uint32(epoch) * net.EpochNumLayers
-	end := start + net.EpochNumLayers - 1
-	return start, end
+	return utils.EpochLayers(uint32(epoch), net.EpochNumLayers)
 }
 
 // Ping checks if the database is reachable.
 func (e *Service) Ping(ctx context.Context) error {
 	return e.storage.Ping(ctx)
 }
+
+type includeOrphanedKey struct{}
+
+// WithOrphaned returns a context whose queries also return the blocks and transactions orphaned by
+// reorgs, they are hidden by default.
+func WithOrphaned(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeOrphanedKey{}, true)
+}
+
+// visible returns the filter restricted to the documents which were not orphaned by a reorg,
+// unless the context includes them.
+func visible(ctx context.Context, filter *bson.D) *bson.D {
+	if include, _ := ctx.Value(includeOrphanedKey{}).(bool); include {
+		return filter
+	}
+	var query bson.D
+	if filter != nil {
+		query = append(query, *filter...)
+	}
+	query = append(query, storage.NotOrphaned)
+	return &query
+}
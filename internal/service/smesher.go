@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"fmt"
+	"math"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 )
 
@@ -24,14 +27,16 @@ func (e *Service) GetSmesher(ctx context.Context, smesherID string) (*model.Smes
 
 // GetSmeshers returns smeshers by filter.
 func (e *Service) GetSmeshers(ctx context.Context, page, perPage int64) (smeshers []*model.Smesher, total int64, err error) {
-	total, err = e.storage.CountSmeshers(ctx, &bson.D{})
+	total, err = e.storage.FindPage(ctx, "smeshers", &bson.D{}, e.getFindOptions("timestamp", page, perPage), &smeshers)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count total smeshers: %w", err)
-	}
-	if total == 0 {
-		return []*model.Smesher{}, 0, nil
+		return nil, 0, fmt.Errorf("failed to get smeshers: %w", err)
 	}
-	smeshers, err = e.storage.GetSmeshers(ctx, &bson.D{}, e.getFindOptions("timestamp", page, perPage))
+	return smeshers, total, nil
+}
+
+// GetTopSmeshers returns smeshers by descending total rewards.
+func (e *Service) GetTopSmeshers(ctx context.Context, page, perPage int64) (smeshers []*model.Smesher, total int64, err error) {
+	total, err = e.storage.FindPage(ctx, "smeshers", &bson.D{}, e.getFindOptions("totalRewards", page, perPage), &smeshers)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get smeshers: %w", err)
 	}
@@ -52,16 +57,96 @@ func (e *Service) GetSmesherRewards(ctx context.Context, smesherID string, page,
 
 // CountSmesherRewards returns smesher rewards count by filter.
 func (e *Service) CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error) {
+	if e.analytics != nil {
+		total, count, err = e.analytics.CountSmesherRewards(ctx, smesherID)
+		if err == nil {
+			return total, count, nil
+		}
+		logging.Error("analytics: count smesher rewards", err)
+	}
 	return e.storage.CountSmesherRewards(ctx, smesherID)
 }
 
-func (e *Service) getSmeshers(ctx context.Context, filter *bson.D, options *options.FindOptions) (smeshers []*model.Smesher, total int64, err error) {
-	total, err = e.storage.CountEpochSmeshers(ctx, filter)
+// GetSmesherHistory returns the changes of the smesher fields, latest first.
+func (e *Service) GetSmesherHistory(ctx context.Context, smesherID string, page, perPage int64) (changes []*model.SmesherChange, total int64, err error) {
+	filter := &bson.D{{Key: "smesher", Value: smesherID}}
+	total, err = e.storage.FindPage(ctx, "smesher_history", filter, e.getFindOptions("epoch", page, perPage), &changes)
 	if err != nil {
-		return []*model.Smesher{}, 0, err
+		return nil, 0, fmt.Errorf("failed to get smesher history: %w", err)
 	}
+	return changes, total, nil
+}
 
-	smeshers, err = e.storage.GetEpochSmeshers(ctx, filter, options)
+// GetSmesherParticipation returns the participation of the smesher in the epochs targeted by its
+// activations, latest first.
+func (e *Service) GetSmesherParticipation(ctx context.Context, smesherID string, page, perPage int64) (participation []*model.SmesherParticipation, total int64, err error) {
+	atxs, total, err := e.getActivations(ctx, &bson.D{{Key: "smesher", Value: smesherID}}, e.getFindOptions("targetEpoch", page, perPage))
+	if err != nil {
+		return nil, 0, err
+	}
+	participation = make([]*model.SmesherParticipation, 0, len(atxs))
+	for _, atx := range atxs {
+		layerStart, layerEnd := e.getEpochLayers(int(atx.TargetEpoch))
+		epoch, err := e.storage.GetSmesherParticipation(ctx, smesherID, atx.TargetEpoch, layerStart, layerEnd)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get smesher participation: %w", err)
+		}
+		expected := func(total int64) int64 {
+			if epoch.TotalWeight == 0 {
+				return 0
+			}
+			return int64(math.Round(float64(total) * float64(epoch.Weight) / float64(epoch.TotalWeight)))
+		}
+		epoch.WeightShare = expected(1e4)
+		epoch.ExpectedEligibilities = expected(epoch.TotalEligibilities)
+		epoch.ExpectedBallots = expected(epoch.TotalBallots)
+		epoch.ExpectedRewards = expected(epoch.TotalRewards)
+		if epoch.ExpectedRewards > 0 {
+			epoch.Score = int64(math.Round(1e4 * float64(epoch.Rewards) / float64(epoch.ExpectedRewards)))
+		}
+		if epoch.EpochEligibilities > 0 {
+			epoch.VoteRate = int64(math.Round(1e4 * float64(epoch.Votes) / float64(epoch.EpochEligibilities)))
+		}
+		participation = append(participation, epoch)
+	}
+	return participation, total, nil
+}
+
+// GetSmeshersNear returns the located smeshers within radius meters of the point, nearest first.
+func (e *Service) GetSmeshersNear(ctx context.Context, lon, lat, radius float64, limit int64) ([]*model.Smesher, error) {
+	smeshers, err := e.storage.GetSmeshersNear(ctx, lon, lat, radius, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get smeshers near: %w", err)
+	}
+	if smeshers == nil {
+		return []*model.Smesher{}, nil
+	}
+	return smeshers, nil
+}
+
+// GetSmeshersWithin returns the located smeshers in the bounding box.
+func (e *Service) GetSmeshersWithin(ctx context.Context, box model.GeoBox, limit int64) ([]*model.Smesher, error) {
+	smeshers, err := e.storage.GetSmeshersWithin(ctx, box, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get smeshers within: %w", err)
+	}
+	if smeshers == nil {
+		return []*model.Smesher{}, nil
+	}
+	return smeshers, nil
+}
+
+// GetGeoHeatmap returns the committed space of the located smeshers by country and by region.
+func (e *Service) GetGeoHeatmap(ctx context.Context) (*model.GeoHeatmap, error) {
+	heatmap, err := e.storage.GetGeoHeatmap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get geo heat-map: %w", err)
+	}
+	return heatmap, nil
+}
+
+func (e *Service) getSmeshers(ctx context.Context, filter *bson.D, options *options.FindOptions) (smeshers []*model.Smesher, total int64, err error) {
+	total, err = e.storage.FindPage(ctx, "smeshers", filter, options, &smeshers)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error load smeshers: %w", err)
 	}
@@ -0,0 +1,720 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/pkg/economics"
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// GetDailyTransactions returns the number and the amount of transactions by day, latest first.
+func (e *Service) GetDailyTransactions(ctx context.Context, page, perPage int64) ([]*model.DailyTransactions, int64, error) {
+	total, err := e.storage.CountDailyTransactions(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count daily transactions: %w", err)
+	}
+	if total == 0 {
+		return []*model.DailyTransactions{}, 0, nil
+	}
+	days, err := e.storage.GetDailyTransactions(ctx, e.getFindOptionsSort(bson.D{{Key: "day", Value: -1}}, page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get daily transactions: %w", err)
+	}
+	if err := e.setDailyTransactionsUSD(ctx, days); err != nil {
+		return nil, 0, err
+	}
+	return days, total, nil
+}
+
+// GetDecentralization returns the decentralization metrics by epoch, latest first.
+func (e *Service) GetDecentralization(ctx context.Context, page, perPage int64) ([]*model.Decentralization, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.Decentralization{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.Decentralization, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.Decentralization{
+			Epoch:           epoch.Number,
+			SpaceGini:       epoch.Stats.Current.SpaceGini,
+			SpaceNakamoto:   epoch.Stats.Current.SpaceNakamoto,
+			RewardsGini:     epoch.Stats.Current.RewardsGini,
+			RewardsNakamoto: epoch.Stats.Current.RewardsNakamoto,
+		})
+	}
+	return series, total, nil
+}
+
+// GetSmeshersChurn returns the smeshers which joined, came back to and left the network by epoch,
+// latest first.
+func (e *Service) GetSmeshersChurn(ctx context.Context, page, perPage int64) ([]*model.SmeshersChurn, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.SmeshersChurn{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.SmeshersChurn, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.SmeshersChurn{
+			Epoch:     epoch.Number,
+			New:       epoch.Stats.Current.NewSmeshers,
+			Returning: epoch.Stats.Current.ReturningSmeshers,
+			Stopped:   epoch.Stats.Current.StoppedSmeshers,
+		})
+	}
+	return series, total, nil
+}
+
+// GetVestingUnlocks returns the amounts of the vaults vested by the end of each epoch, from the
+// current epoch until the end of the last vesting.
+func (e *Service) GetVestingUnlocks(ctx context.Context, page, perPage int64) ([]*model.VestingUnlock, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	vaults, err := e.storage.GetVaults(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get vaults: %w", err)
+	}
+	unlocks := model.VestingUnlocks(vaults, utils.LayerEpoch(net.LastLayer, net.EpochNumLayers), net.EpochNumLayers)
+	total := int64(len(unlocks))
+	start := min((page-1)*perPage, total)
+	return unlocks[start:min(start+perPage, total)], total, nil
+}
+
+// GetVaultDrawdown returns the amounts drained from the vaults by epoch, latest first, from the
+// genesis until the current epoch.
+func (e *Service) GetVaultDrawdown(ctx context.Context, page, perPage int64) ([]*model.VaultDrawdown, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	vaults, err := e.storage.GetVaults(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get vaults: %w", err)
+	}
+	var drains []*model.Transaction
+	if len(vaults) > 0 {
+		addresses := make(bson.A, 0, len(vaults))
+		for _, v := range vaults {
+			addresses = append(addresses, v.Address)
+		}
+		drains, err = e.storage.GetTransactions(ctx, &bson.D{
+			{Key: "type", Value: transaction.TypeDrainVault},
+			{Key: "result", Value: int(pb.TransactionResult_SUCCESS)},
+			{Key: "touchedAddresses", Value: bson.D{{Key: "$in", Value: addresses}}},
+			{Key: "orphaned", Value: bson.D{{Key: "$ne", Value: true}}},
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("error get vault drains: %w", err)
+		}
+	}
+	series := model.VaultDrawdowns(vaults, drains, utils.LayerEpoch(net.LastLayer, net.EpochNumLayers), net.EpochNumLayers)
+	slices.Reverse(series)
+	total := int64(len(series))
+	start := min((page-1)*perPage, total)
+	return series[start:min(start+perPage, total)], total, nil
+}
+
+// GetSpaceGrowth returns the storage committed to the network and its change by epoch, latest first.
+func (e *Service) GetSpaceGrowth(ctx context.Context, page, perPage int64) ([]*model.SpaceGrowth, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.SpaceGrowth{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.SpaceGrowth, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.SpaceGrowth{
+			Epoch: epoch.Number,
+			Space: epoch.Stats.Current.Space,
+			Delta: epoch.Stats.Current.SpaceDelta,
+		})
+	}
+	return series, total, nil
+}
+
+// GetActiveSmeshers returns the number of smeshers with an activation and of smeshers rewarded by
+// epoch, latest first.
+func (e *Service) GetActiveSmeshers(ctx context.Context, page, perPage int64) ([]*model.ActiveSmeshers, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.ActiveSmeshers{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.ActiveSmeshers, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.ActiveSmeshers{
+			Epoch:     epoch.Number,
+			Activated: epoch.Stats.Current.Smeshers,
+			Rewarded:  epoch.Stats.Current.RewardedSmeshers,
+		})
+	}
+	return series, total, nil
+}
+
+// GetEmptyLayers returns the layers without a block and without a transaction by epoch, latest first.
+func (e *Service) GetEmptyLayers(ctx context.Context, page, perPage int64) ([]*model.EmptyLayers, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.EmptyLayers{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.EmptyLayers, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.EmptyLayers{
+			Epoch:      epoch.Number,
+			Layers:     epoch.Layers,
+			Empty:      epoch.Stats.Current.EmptyLayers,
+			WithoutTxs: epoch.Stats.Current.LayersWithoutTxs,
+		})
+	}
+	return series, total, nil
+}
+
+// GetDailyAccounts returns the number of new accounts and of all the accounts by day, latest first.
+func (e *Service) GetDailyAccounts(ctx context.Context, page, perPage int64) ([]*model.DailyAccounts, int64, error) {
+	total, err := e.storage.CountDailyAccounts(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count daily accounts: %w", err)
+	}
+	if total == 0 {
+		return []*model.DailyAccounts{}, 0, nil
+	}
+	days, err := e.storage.GetDailyAccounts(ctx, e.getFindOptionsSort(bson.D{{Key: "day", Value: -1}}, page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get daily accounts: %w", err)
+	}
+	if len(days) == 0 {
+		return days, total, nil
+	}
+	// the accounts seen by the end of a day are all the accounts but the ones of the later days
+	accounts, err := e.storage.SumDailyAccounts(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error sum daily accounts: %w", err)
+	}
+	later, err := e.storage.SumDailyAccounts(ctx, &bson.D{{Key: "day", Value: bson.D{{Key: "$gt", Value: days[0].Day}}}})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error sum daily accounts: %w", err)
+	}
+	for _, day := range days {
+		day.Total = accounts - later
+		later += day.New
+	}
+	return days, total, nil
+}
+
+// GetEpochAccounts returns the number of new accounts and of all the accounts by epoch, latest first.
+func (e *Service) GetEpochAccounts(ctx context.Context, page, perPage int64) ([]*model.EpochAccounts, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.EpochAccounts{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.EpochAccounts, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.EpochAccounts{
+			Epoch: epoch.Number,
+			New:   epoch.Stats.Current.NewAccounts,
+			Total: epoch.Stats.Current.Accounts,
+		})
+	}
+	return series, total, nil
+}
+
+// GetTransactionTypes returns the number and the amount of transactions by decoded type, by day or
+// by epoch depending on the period, latest first.
+func (e *Service) GetTransactionTypes(ctx context.Context, period string, page, perPage int64) ([]*model.TransactionTypes, int64, error) {
+	stats, err := e.storage.GetTransactionTypesStats(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get transaction types: %w", err)
+	}
+	periods := make(map[uint32]*model.TransactionTypes)
+	for _, s := range stats {
+		key := s.Day
+		if period == model.PeriodEpoch {
+			key = s.Epoch
+		}
+		types, ok := periods[key]
+		if !ok {
+			types = &model.TransactionTypes{Period: key, Types: make(map[string]*model.TransactionTypeCount)}
+			periods[key] = types
+		}
+		name := model.TransactionTypeName(s.Type)
+		count, ok := types.Types[name]
+		if !ok {
+			count = &model.TransactionTypeCount{}
+			types.Types[name] = count
+		}
+		count.Count += s.Count
+		count.Amount += s.Amount
+	}
+	series := make([]*model.TransactionTypes, 0, len(periods))
+	for _, types := range periods {
+		series = append(series, types)
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Period > series[j].Period })
+	total := int64(len(series))
+	start := min((page-1)*perPage, total)
+	return series[start:min(start+perPage, total)], total, nil
+}
+
+// GetBlockFill returns the number of transactions and the size of the blocks by epoch, on average
+// and by bucket, latest first.
+func (e *Service) GetBlockFill(ctx context.Context, page, perPage int64) ([]*model.BlockFill, int64, error) {
+	stats, err := e.storage.GetBlockStats(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get blocks stats: %w", err)
+	}
+	epochs := make(map[uint32][]*model.BlockStats)
+	for _, s := range stats {
+		epochs[s.Epoch] = append(epochs[s.Epoch], s)
+	}
+	series := make([]*model.BlockFill, 0, len(epochs))
+	for epoch, stats := range epochs {
+		series = append(series, model.NewBlockFill(epoch, stats))
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Epoch > series[j].Epoch })
+	total := int64(len(series))
+	start := min((page-1)*perPage, total)
+	return series[start:min(start+perPage, total)], total, nil
+}
+
+// GetFeeSeries returns the fees paid by the processed transactions by layer or by epoch depending
+// on the period, latest first.
+func (e *Service) GetFeeSeries(ctx context.Context, period string, page, perPage int64) ([]*model.FeeSeries, int64, error) {
+	if period == model.PeriodEpoch {
+		total, err := e.storage.CountEpochs(ctx, &bson.D{})
+		if err != nil {
+			return nil, 0, fmt.Errorf("error count epochs: %w", err)
+		}
+		if total == 0 {
+			return []*model.FeeSeries{}, 0, nil
+		}
+		epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error get epochs: %w", err)
+		}
+		series := make([]*model.FeeSeries, 0, len(epochs))
+		for _, epoch := range epochs {
+			stats := epoch.Stats.Current
+			series = append(series, &model.FeeSeries{
+				Period: uint32(epoch.Number),
+				Total:  uint64(stats.FeesCollected),
+				Min:    uint64(stats.FeeMin),
+				Median: uint64(stats.FeeMedian),
+				Max:    uint64(stats.FeeMax),
+			})
+		}
+		if err := e.setFeeSeriesUSD(ctx, period, series); err != nil {
+			return nil, 0, err
+		}
+		return series, total, nil
+	}
+
+	total, err := e.storage.CountLayers(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count layers: %w", err)
+	}
+	if total == 0 {
+		return []*model.FeeSeries{}, 0, nil
+	}
+	layers, err := e.storage.GetLayers(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get layers: %w", err)
+	}
+	series := make([]*model.FeeSeries, 0, len(layers))
+	for _, layer := range layers {
+		series = append(series, &model.FeeSeries{
+			Period: layer.Number,
+			Total:  layer.FeesCollected,
+			Min:    layer.FeeMin,
+			Median: layer.FeeMedian,
+			Max:    layer.FeeMax,
+		})
+	}
+	if err := e.setFeeSeriesUSD(ctx, period, series); err != nil {
+		return nil, 0, err
+	}
+	return series, total, nil
+}
+
+// GetIssuance compares the subsidy of the rewards collected by epoch to the issuance curve of the
+// protocol, latest first.
+func (e *Service) GetIssuance(ctx context.Context, page, perPage int64) ([]*model.Issuance, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 || net.EpochNumLayers == 0 {
+		return []*model.Issuance{}, total, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.Issuance, 0, len(epochs))
+	for _, epoch := range epochs {
+		first := uint32(epoch.Number) * net.EpochNumLayers
+		last := first + net.EpochNumLayers - 1
+		issuance := &model.Issuance{
+			Epoch:    epoch.Number,
+			Expected: economics.IssuedBetween(first, last, net.EpochNumLayers),
+			Observed: uint64(max(epoch.Stats.Current.Rewards-epoch.Stats.Current.FeesDistributed, 0)),
+			Complete: last <= net.LastLayer,
+		}
+		issuance.Divergence = int64(issuance.Observed) - int64(issuance.Expected)
+		issuance.Diverged = issuance.Complete && issuance.Divergence != 0
+		series = append(series, issuance)
+	}
+	return series, total, nil
+}
+
+// GetSupplyBreakdown returns the supply by the end of each epoch by origin, latest first. The vested
+// funds of the ongoing epoch are the ones vested by its last collected layer.
+func (e *Service) GetSupplyBreakdown(ctx context.Context, page, perPage int64) ([]*model.SupplyBreakdown, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 || net.EpochNumLayers == 0 {
+		return []*model.SupplyBreakdown{}, total, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	vaults, err := e.storage.GetVaults(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get vaults: %w", err)
+	}
+	series := make([]*model.SupplyBreakdown, 0, len(epochs))
+	for _, epoch := range epochs {
+		_, layerEnd := utils.EpochLayers(uint32(epoch.Number), net.EpochNumLayers)
+		// the rewards of the epochs are not cumulated by the collector
+		rewards, _, err := e.GetTotalRewards(ctx, &bson.D{{Key: "layer", Value: bson.D{{Key: "$lte", Value: layerEnd}}}})
+		if err != nil {
+			return nil, 0, fmt.Errorf("error get total rewards: %w", err)
+		}
+		var vested uint64
+		for _, v := range vaults {
+			vested += v.Vested(min(layerEnd+1, net.LastLayer))
+		}
+		stats := epoch.Stats.Cumulative
+		supply := &model.SupplyBreakdown{
+			Epoch:      epoch.Number,
+			Genesis:    economics.TotalVaulted,
+			Vested:     min(vested, economics.TotalVaulted),
+			Rewards:    uint64(max(rewards-stats.FeesDistributed, 0)),
+			FeesBurned: uint64(max(stats.FeesBurned, 0)),
+		}
+		supply.Locked = supply.Genesis - supply.Vested
+		supply.Total = supply.Genesis + supply.Rewards - min(supply.FeesBurned, supply.Genesis+supply.Rewards)
+		supply.Circulating = supply.Total - min(supply.Locked, supply.Total)
+		series = append(series, supply)
+	}
+	return series, total, nil
+}
+
+// GetCoinbaseConcentration returns the storage and the rewards of the epoch by coinbase, with the
+// part of them held by the top coinbases.
+func (e *Service) GetCoinbaseConcentration(ctx context.Context, epoch int32, top int) (*model.CoinbaseConcentration, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	layerStart, layerEnd := utils.EpochLayers(uint32(epoch), net.EpochNumLayers)
+	coinbases, err := e.storage.GetEpochCoinbases(ctx, uint32(epoch), layerStart, layerEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch coinbases: %w", err)
+	}
+	concentration := &model.CoinbaseConcentration{Epoch: epoch, Coinbases: int64(len(coinbases))}
+	for _, coinbase := range coinbases {
+		coinbase.Space = coinbase.Units * int64(net.PostUnitSize)
+		concentration.Space += coinbase.Space
+		concentration.Rewards += coinbase.Rewards
+	}
+	basisPoints := func(part, total int64) int64 {
+		if total == 0 {
+			return 0
+		}
+		return int64(math.Round(1e4 * float64(part) / float64(total)))
+	}
+	for _, coinbase := range coinbases {
+		coinbase.SpaceShare = basisPoints(coinbase.Space, concentration.Space)
+		coinbase.RewardsShare = basisPoints(coinbase.Rewards, concentration.Rewards)
+	}
+	top = min(top, len(coinbases))
+
+	sort.Slice(coinbases, func(i, j int) bool {
+		if coinbases[i].Rewards != coinbases[j].Rewards {
+			return coinbases[i].Rewards > coinbases[j].Rewards
+		}
+		return coinbases[i].Coinbase < coinbases[j].Coinbase
+	})
+	var rewards int64
+	for _, coinbase := range coinbases[:top] {
+		rewards += coinbase.Rewards
+	}
+	concentration.TopRewardsShare = basisPoints(rewards, concentration.Rewards)
+
+	sort.Slice(coinbases, func(i, j int) bool {
+		if coinbases[i].Space != coinbases[j].Space {
+			return coinbases[i].Space > coinbases[j].Space
+		}
+		return coinbases[i].Coinbase < coinbases[j].Coinbase
+	})
+	var space int64
+	for _, coinbase := range coinbases[:top] {
+		space += coinbase.Space
+	}
+	concentration.TopSpaceShare = basisPoints(space, concentration.Space)
+	concentration.Top = coinbases[:top]
+	return concentration, nil
+}
+
+// GetCommitmentHistogram returns the distribution of the activations targeting the epoch by their
+// number of units.
+func (e *Service) GetCommitmentHistogram(ctx context.Context, epoch int32) (*model.CommitmentHistogram, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	units, err := e.storage.GetEpochUnits(ctx, uint32(epoch))
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch units: %w", err)
+	}
+	return model.NewCommitmentHistogram(epoch, units, net.PostUnitSize), nil
+}
+
+// GetRewardLuck returns the luck of the smeshers of the epoch by the size of their commitment.
+func (e *Service) GetRewardLuck(ctx context.Context, epoch int32) (*model.RewardLuck, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	layerStart, layerEnd := utils.EpochLayers(uint32(epoch), net.EpochNumLayers)
+	smeshers, err := e.storage.GetEpochSmesherRewards(ctx, uint32(epoch), layerStart, layerEnd)
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch smesher rewards: %w", err)
+	}
+	return model.NewRewardLuck(epoch, smeshers), nil
+}
+
+// GetRollups returns the rollups of the period starting in [from, to].
+func (e *Service) GetRollups(ctx context.Context, period string, from, to uint32) ([]*model.Rollup, error) {
+	rollups, err := e.storage.GetRollups(ctx, period, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups: %w", err)
+	}
+	if err := e.setRollupsUSD(ctx, period, rollups); err != nil {
+		return nil, err
+	}
+	return rollups, nil
+}
+
+// GetBalanceCohorts returns the balances of the accounts by age of their last change at the end of
+// the epochs, latest first. The current epoch is not over, its cohorts are the ones at the last layer.
+func (e *Service) GetBalanceCohorts(ctx context.Context, page, perPage int64) ([]*model.BalanceCohorts, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	if net.EpochNumLayers == 0 {
+		return []*model.BalanceCohorts{}, 0, nil
+	}
+	series, err := e.storage.GetBalanceCohorts(ctx, net.EpochNumLayers, utils.LayerEpoch(net.LastLayer, net.EpochNumLayers))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get balance cohorts: %w", err)
+	}
+	slices.Reverse(series)
+	total := int64(len(series))
+	start := min((page-1)*perPage, total)
+	return series[start:min(start+perPage, total)], total, nil
+}
+
+// GetInclusionTime returns the delays between the first sighting of the transactions by the node and
+// their inclusion by epoch, latest first. Only the transactions with a known received time count.
+func (e *Service) GetInclusionTime(ctx context.Context, page, perPage int64) ([]*model.InclusionTime, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.InclusionTime{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.InclusionTime, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, &model.InclusionTime{
+			Epoch:  epoch.Number,
+			Txs:    epoch.Stats.Current.InclusionTxs,
+			Mean:   epoch.Stats.Current.InclusionMean,
+			Median: epoch.Stats.Current.InclusionMedian,
+			P90:    epoch.Stats.Current.InclusionP90,
+			P99:    epoch.Stats.Current.InclusionP99,
+		})
+	}
+	return series, total, nil
+}
+
+// GetMalfeasanceStats returns the malfeasance proofs by epoch, latest first, with the storage their
+// smeshers were removed from the active set with.
+func (e *Service) GetMalfeasanceStats(ctx context.Context, page, perPage int64) ([]*model.MalfeasanceStats, int64, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get network info: %w", err)
+	}
+	proofs, err := e.storage.GetMalfeasanceProofs(ctx, &bson.D{}, options.Find().SetSort(bson.D{{Key: "layer", Value: 1}}))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get malfeasance proofs: %w", err)
+	}
+	smeshers := make(bson.A, 0, len(proofs))
+	for _, proof := range proofs {
+		smeshers = append(smeshers, proof.Smesher)
+	}
+	var atxs []*model.Activation
+	if len(smeshers) > 0 {
+		atxs, err = e.storage.GetActivations(ctx, &bson.D{{Key: "smesher", Value: bson.D{{Key: "$in", Value: smeshers}}}})
+		if err != nil {
+			return nil, 0, fmt.Errorf("error get activations: %w", err)
+		}
+	}
+	series := model.NewMalfeasanceStats(proofs, atxs, net.EpochNumLayers, net.PostUnitSize)
+	slices.Reverse(series)
+	total := int64(len(series))
+	start := min((page-1)*perPage, total)
+	return series[start:min(start+perPage, total)], total, nil
+}
+
+// GetBallotParticipation returns the participation of the smeshers in the ballots by epoch, latest
+// first.
+func (e *Service) GetBallotParticipation(ctx context.Context, page, perPage int64) ([]*model.BallotParticipation, int64, error) {
+	total, err := e.storage.CountEpochs(ctx, &bson.D{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	if total == 0 {
+		return []*model.BallotParticipation{}, 0, nil
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{}, e.getFindOptions("number", page, perPage))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error get epochs: %w", err)
+	}
+	series := make([]*model.BallotParticipation, 0, len(epochs))
+	for _, epoch := range epochs {
+		series = append(series, model.NewBallotParticipation(epoch))
+	}
+	return series, total, nil
+}
+
+// GetRewardsProjection returns the subsidy expected for a commitment of num units in the epoch. The
+// epoch may be in the future, the storage of the network is then the last one stored.
+func (e *Service) GetRewardsProjection(ctx context.Context, numUnits uint32, epoch int32) (*model.RewardsProjection, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	epochs, err := e.storage.GetEpochs(ctx, &bson.D{{Key: "number", Value: bson.D{{Key: "$lte", Value: epoch}}}}, e.getFindOptions("number", 1, 1))
+	if err != nil {
+		return nil, fmt.Errorf("error get epochs: %w", err)
+	}
+	if len(epochs) == 0 || net.EpochNumLayers == 0 {
+		return nil, ErrNotFound
+	}
+	first := uint32(epoch) * net.EpochNumLayers
+	projection := &model.RewardsProjection{
+		Epoch:      epoch,
+		NumUnits:   numUnits,
+		Space:      int64(numUnits) * int64(net.PostUnitSize),
+		SpaceEpoch: epochs[0].Number,
+		Subsidy:    economics.IssuedBetween(first, first+net.EpochNumLayers-1, net.EpochNumLayers),
+	}
+	projection.NetworkSpace = epochs[0].Stats.Current.Space + projection.Space
+	if projection.NetworkSpace > 0 {
+		projection.Rewards = uint64(math.Round(float64(projection.Subsidy) * float64(projection.Space) / float64(projection.NetworkSpace)))
+	}
+	if duration := uint64(net.EpochNumLayers) * uint64(net.LayerDuration); duration > 0 {
+		projection.DailyRewards = projection.Rewards * 86400 / duration
+	}
+	return projection, nil
+}
+
+// GetTotals returns the global totals of the network from the counters maintained by the collector,
+// with the space of the epoch of the last collected layer and the staleness of the counters.
+func (e *Service) GetTotals(ctx context.Context) (*model.Totals, error) {
+	net, err := e.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	totals, err := e.storage.GetTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get totals: %w", err)
+	}
+	var epoch uint32
+	if net.EpochNumLayers > 0 {
+		epoch = net.LastLayer / net.EpochNumLayers
+	}
+	totals.Resolve(epoch, uint32(time.Now().Unix()))
+	totals.LastLayer = net.LastLayer
+	return totals, nil
+}
@@ -31,15 +31,43 @@ func (e *Service) GetTransactions(ctx context.Context, page, perPage int64) (txs
 	}, page, perPage))
 }
 
-func (e *Service) getTransactions(ctx context.Context, filter *bson.D, options *options.FindOptions) (txs []*model.Transaction, total int64, err error) {
-	total, err = e.storage.CountTransactions(ctx, filter)
-	if err != nil {
-		return nil, 0, fmt.Errorf("error count txs: %w", err)
+// GetFilteredTransactions returns the transactions matching the filter.
+func (e *Service) GetFilteredTransactions(ctx context.Context, filter *model.TransactionFilter, page, perPage int64) (txs []*model.Transaction, total int64, err error) {
+	return e.getTransactions(ctx, transactionsFilter(filter), e.getFindOptionsSort(bson.D{
+		{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1},
+	}, page, perPage))
+}
+
+func transactionsFilter(filter *model.TransactionFilter) *bson.D {
+	query := bson.D{}
+	if filter.Method != nil {
+		query = append(query, bson.E{Key: "method", Value: *filter.Method})
 	}
-	if total == 0 {
-		return []*model.Transaction{}, 0, nil
+	if filter.Template != "" {
+		query = append(query, bson.E{Key: "template", Value: filter.Template})
+	}
+	if filter.Principal != "" {
+		query = append(query, bson.E{Key: "sender", Value: filter.Principal})
 	}
-	txs, err = e.storage.GetTransactions(ctx, filter, options)
+	if filter.Destination != "" {
+		query = append(query, bson.E{Key: "receiver", Value: filter.Destination})
+	}
+	amount := bson.D{}
+	if filter.MinAmount > 0 {
+		amount = append(amount, bson.E{Key: "$gte", Value: filter.MinAmount})
+	}
+	if filter.MaxAmount > 0 {
+		amount = append(amount, bson.E{Key: "$lte", Value: filter.MaxAmount})
+	}
+	if len(amount) > 0 {
+		query = append(query, bson.E{Key: "amount", Value: amount})
+	}
+	return &query
+}
+
+func (e *Service) getTransactions(ctx context.Context, filter *bson.D, options *options.FindOptions) (txs []*model.Transaction, total int64, err error) {
+	filter = visible(ctx, filter)
+	total, err = e.storage.FindPage(ctx, "txs", filter, options, &txs)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error get txs: %w", err)
 	}
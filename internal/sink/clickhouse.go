@@ -0,0 +1,196 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+const (
+	clickhouseBatchSize     = 10000
+	clickhouseFlushInterval = 5 * time.Second
+)
+
+// clickhouseTables are the analytics tables. ReplacingMergeTree collapses the rows written again
+// when a layer is re-ingested, queries read them with FINAL.
+var clickhouseTables = []string{
+	`CREATE TABLE IF NOT EXISTS rewards (
+		smesher String,
+		coinbase String,
+		layer UInt32,
+		total UInt64,
+		layer_reward UInt64,
+		timestamp UInt32
+	) ENGINE = ReplacingMergeTree ORDER BY (smesher, layer)`,
+	`CREATE TABLE IF NOT EXISTS txs (
+		id String,
+		layer UInt32,
+		sender String,
+		receiver String,
+		amount UInt64,
+		fee UInt64,
+		state Int32,
+		timestamp UInt32
+	) ENGINE = ReplacingMergeTree ORDER BY (layer, id)`,
+}
+
+// ClickHouse writes rewards and transactions to ClickHouse for the analytics queries of the API.
+// Rows are buffered and inserted in batches; other entities are ignored.
+type ClickHouse struct {
+	conn driver.Conn
+
+	mu      sync.Mutex
+	rewards []*model.Reward
+	txs     []*model.Transaction
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClickHouse connects to clickhouse://<user>:<password>@<host>:9000/<db> and creates the tables.
+func NewClickHouse(rawURL string) (*ClickHouse, error) {
+	conn, err := OpenClickHouse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, stmt := range clickhouseTables {
+		if err := conn.Exec(ctx, stmt); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error init clickhouse tables: %w", err)
+		}
+	}
+
+	c := &ClickHouse{conn: conn, done: make(chan struct{})}
+	c.wg.Add(1)
+	go c.run()
+	return c, nil
+}
+
+// OpenClickHouse opens and pings a ClickHouse connection.
+func OpenClickHouse(rawURL string) (driver.Conn, error) {
+	opts, err := clickhouse.ParseDSN(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clickhouse url: %w", err)
+	}
+	conn, err := clickhouse.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error connect to clickhouse: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error ping to clickhouse: %w", err)
+	}
+	return conn, nil
+}
+
+func (c *ClickHouse) Write(ctx context.Context, entity, key string, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch entity {
+	case EntityReward:
+		var reward model.Reward
+		if err := json.Unmarshal(data, &reward); err != nil {
+			return err
+		}
+		c.rewards = append(c.rewards, &reward)
+	case EntityTransaction:
+		var tx model.Transaction
+		if err := json.Unmarshal(data, &tx); err != nil {
+			return err
+		}
+		c.txs = append(c.txs, &tx)
+	default:
+		return nil
+	}
+	if len(c.rewards)+len(c.txs) >= clickhouseBatchSize {
+		return c.flush(ctx)
+	}
+	return nil
+}
+
+func (c *ClickHouse) run() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(clickhouseFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			if err := c.flush(context.Background()); err != nil {
+				logging.Error("sink: clickhouse flush", err)
+			}
+			c.mu.Unlock()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// flush inserts the buffered rows, it must be called with mu held.
+func (c *ClickHouse) flush(ctx context.Context) error {
+	if len(c.rewards) > 0 {
+		batch, err := c.conn.PrepareBatch(ctx, "INSERT INTO rewards")
+		if err != nil {
+			return err
+		}
+		for _, r := range c.rewards {
+			if err := batch.Append(rewardRow(r)...); err != nil {
+				return err
+			}
+		}
+		if err := batch.Send(); err != nil {
+			return err
+		}
+		c.rewards = c.rewards[:0]
+	}
+	if len(c.txs) > 0 {
+		batch, err := c.conn.PrepareBatch(ctx, "INSERT INTO txs")
+		if err != nil {
+			return err
+		}
+		for _, tx := range c.txs {
+			if err := batch.Append(txRow(tx)...); err != nil {
+				return err
+			}
+		}
+		if err := batch.Send(); err != nil {
+			return err
+		}
+		c.txs = c.txs[:0]
+	}
+	return nil
+}
+
+// rewardRow returns the columns of the reward in the rewards table.
+func rewardRow(r *model.Reward) []any {
+	return []any{r.Smesher, r.Coinbase, r.Layer, r.Total, r.LayerReward, r.Timestamp}
+}
+
+// txRow returns the columns of the transaction in the txs table.
+func txRow(tx *model.Transaction) []any {
+	return []any{tx.Id, tx.Layer, tx.Sender, tx.Receiver, tx.Amount, tx.Fee, int32(tx.State), tx.Timestamp}
+}
+
+func (c *ClickHouse) Close() error {
+	close(c.done)
+	c.wg.Wait()
+	c.mu.Lock()
+	err := c.flush(context.Background())
+	c.mu.Unlock()
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
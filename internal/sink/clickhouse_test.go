@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestClickHouseRows(t *testing.T) {
+	reward := &model.Reward{Smesher: "0x51", Coinbase: "sm1", Layer: 12, Total: 100, LayerReward: 90, Timestamp: 1000}
+	require.Equal(t, []any{"0x51", "sm1", uint32(12), uint64(100), uint64(90), uint32(1000)}, rewardRow(reward))
+
+	tx := &model.Transaction{Id: "0x01", Layer: 12, Sender: "sm1", Receiver: "sm2", Amount: 50, Fee: 2, State: 3, Timestamp: 1000}
+	require.Equal(t, []any{"0x01", uint32(12), "sm1", "sm2", uint64(50), uint64(2), int32(3), uint32(1000)}, txRow(tx))
+}
+
+func TestClickHouseWrite(t *testing.T) {
+	ctx := context.Background()
+	c := &ClickHouse{}
+	encode := func(doc any) []byte {
+		data, err := json.Marshal(doc)
+		require.NoError(t, err)
+		return data
+	}
+
+	reward := &model.Reward{Smesher: "0x51", Coinbase: "sm1", Layer: 12, Total: 100}
+	tx := &model.Transaction{Id: "0x01", Layer: 12, Sender: "sm1", Amount: 50}
+	require.NoError(t, c.Write(ctx, EntityReward, "0x51", encode(reward)))
+	require.NoError(t, c.Write(ctx, EntityTransaction, tx.Id, encode(tx)))
+	// the other entities are not stored in clickhouse
+	require.NoError(t, c.Write(ctx, EntityLayer, "12", encode(&model.Layer{Number: 12})))
+	require.Error(t, c.Write(ctx, EntityReward, "0x52", []byte("{")))
+
+	require.Equal(t, []*model.Reward{reward}, c.rewards)
+	require.Len(t, c.txs, 1)
+	require.Equal(t, txRow(tx), txRow(c.txs[0]))
+}
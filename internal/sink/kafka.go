@@ -0,0 +1,65 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+var metricKafkaFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "explorer_sink_kafka_failed_messages_total",
+	Help: "Number of messages the Kafka sink failed to deliver",
+}, []string{"topic"})
+
+// Kafka publishes entities to Kafka topics. Messages are keyed by entity id,
+// so updates of the same entity stay ordered within a partition.
+type Kafka struct {
+	writer *kafka.Writer
+	prefix string
+}
+
+func NewKafka(brokers []string, prefix string) *Kafka {
+	return &Kafka{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			Async:                  true,
+			AllowAutoTopicCreation: true,
+			Completion:             kafkaCompletion,
+		},
+		prefix: prefix,
+	}
+}
+
+// kafkaCompletion logs and counts the messages the asynchronous writer failed to deliver, its
+// WriteMessages does not return them.
+func kafkaCompletion(messages []kafka.Message, err error) {
+	if err == nil {
+		return
+	}
+	topics := make(map[string]int)
+	for _, m := range messages {
+		topics[m.Topic]++
+	}
+	for topic, n := range topics {
+		metricKafkaFailed.WithLabelValues(topic).Add(float64(n))
+		logging.Error("sink: kafka deliver", err, zap.String("topic", topic), zap.Int("messages", n))
+	}
+}
+
+func (k *Kafka) Write(ctx context.Context, entity, key string, data []byte) error {
+	return k.writer.WriteMessages(ctx, kafka.Message{
+		Topic: subject(k.prefix, entity),
+		Key:   []byte(key),
+		Value: data,
+	})
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,39 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Nats publishes entities to NATS subjects.
+type Nats struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+func NewNats(rawURL, prefix string) (*Nats, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = ""
+	conn, err := nats.Connect(u.String(), nats.Name("explorer-collector"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connect to nats: %w", err)
+	}
+	return &Nats{conn: conn, prefix: prefix}, nil
+}
+
+func (n *Nats) Write(_ context.Context, entity, key string, data []byte) error {
+	msg := nats.NewMsg(subject(n.prefix, entity))
+	msg.Header.Set("key", key)
+	msg.Data = data
+	return n.conn.PublishMsg(msg)
+}
+
+func (n *Nats) Close() error {
+	return n.conn.Drain()
+}
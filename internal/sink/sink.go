@@ -0,0 +1,94 @@
+// Package sink fans out entities written by the collector to downstream consumers.
+// Mongo remains the primary store used by the API; sinks configured here receive
+// a JSON copy of every entity for analytics pipelines.
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// Entity names used as the message subject or topic suffix.
+const (
+	EntityLayer            = "layers"
+	EntityBlock            = "blocks"
+	EntityTransaction      = "txs"
+	EntityReward           = "rewards"
+	EntityActivation       = "atxs"
+	EntityAccount          = "accounts"
+	EntityMalfeasanceProof = "malfeasance_proofs"
+	EntityCertificate      = "certificates"
+)
+
+// Sink receives entities written by the collector.
+type Sink interface {
+	// Write publishes the JSON encoded entity. key identifies the entity, e.g. a layer number or tx id.
+	Write(ctx context.Context, entity, key string, data []byte) error
+	Close() error
+}
+
+// Multi writes each entity to all its sinks. Failures are logged and do not stop the fan-out.
+type Multi []Sink
+
+// Publish encodes the document and writes it to every sink.
+func (m Multi) Publish(ctx context.Context, entity, key string, doc any) {
+	if len(m) == 0 {
+		return
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		logging.Error(fmt.Sprintf("sink: encode %s %s", entity, key), err)
+		return
+	}
+	for _, s := range m {
+		if err := s.Write(ctx, entity, key, data); err != nil {
+			logging.Error(fmt.Sprintf("sink: write %s %s", entity, key), err)
+		}
+	}
+}
+
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// New creates a sink from its url:
+//
+//	nats://host:4222/<subject prefix>
+//	kafka://broker1:9092,broker2:9092/<topic prefix>
+//	clickhouse://<user>:<password>@host:9000/<database>
+//
+// Entities are published to `<prefix>.<entity>`, the prefix defaults to `explorer`.
+// The clickhouse sink stores rewards and transactions in tables used by the API analytics queries.
+func New(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink url `%s`: %w", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix == "" {
+		prefix = "explorer"
+	}
+	switch u.Scheme {
+	case "nats":
+		return NewNats(rawURL, prefix)
+	case "kafka":
+		return NewKafka(strings.Split(u.Host, ","), prefix), nil
+	case "clickhouse":
+		return NewClickHouse(rawURL)
+	}
+	return nil, fmt.Errorf("unsupported sink `%s`", u.Scheme)
+}
+
+func subject(prefix, entity string) string {
+	return prefix + "." + entity
+}
@@ -0,0 +1,108 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+)
+
+type message struct {
+	entity, key, data string
+}
+
+// fakeSink records the written entities, writes and close fail with err.
+type fakeSink struct {
+	err      error
+	messages []message
+	closed   bool
+}
+
+func (f *fakeSink) Write(_ context.Context, entity, key string, data []byte) error {
+	f.messages = append(f.messages, message{entity, key, string(data)})
+	return f.err
+}
+
+func (f *fakeSink) Close() error {
+	f.closed = true
+	return f.err
+}
+
+func TestMultiPublish(t *testing.T) {
+	ctx := context.Background()
+	failing, first, second := &fakeSink{err: errors.New("unavailable")}, &fakeSink{}, &fakeSink{}
+	sinks := Multi{first, failing, second}
+
+	// a failing sink does not stop the fan-out
+	sinks.Publish(ctx, EntityLayer, "12", map[string]int{"number": 12})
+	sinks.Publish(ctx, EntityTransaction, "0x01", map[string]string{"id": "0x01"})
+	expected := []message{
+		{EntityLayer, "12", `{"number":12}`},
+		{EntityTransaction, "0x01", `{"id":"0x01"}`},
+	}
+	for _, s := range []*fakeSink{first, failing, second} {
+		require.Equal(t, expected, s.messages)
+	}
+
+	// a document that can not be encoded is written to no sink
+	sinks.Publish(ctx, EntityReward, "0x02", make(chan int))
+	require.Len(t, first.messages, 2)
+
+	Multi(nil).Publish(ctx, EntityLayer, "13", map[string]int{"number": 13})
+}
+
+func TestMultiClose(t *testing.T) {
+	first, second := &fakeSink{err: errors.New("first")}, &fakeSink{err: errors.New("second")}
+	err := Multi{first, &fakeSink{}, second}.Close()
+	require.ErrorIs(t, err, first.err)
+	require.ErrorIs(t, err, second.err)
+	require.True(t, first.closed)
+	require.True(t, second.closed)
+
+	require.NoError(t, Multi{&fakeSink{}}.Close())
+}
+
+func TestNew(t *testing.T) {
+	s, err := New("kafka://broker1:9092,broker2:9092/mainnet")
+	require.NoError(t, err)
+	kafka := s.(*Kafka)
+	require.Equal(t, "mainnet", kafka.prefix)
+	require.Equal(t, "broker1:9092,broker2:9092", kafka.writer.Addr.String())
+
+	s, err = New("kafka://broker1:9092")
+	require.NoError(t, err)
+	require.Equal(t, "explorer", s.(*Kafka).prefix)
+
+	// the nats sink connects on creation
+	_, err = New("nats://127.0.0.1:1/mainnet")
+	require.ErrorContains(t, err, "connect to nats")
+
+	for _, rawURL := range []string{"amqp://host:5672/explorer", "broker1:9092", ":bad"} {
+		_, err = New(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestKafkaCompletion(t *testing.T) {
+	failed := func(topic string) float64 {
+		return testutil.ToFloat64(metricKafkaFailed.WithLabelValues(topic))
+	}
+	layers, rewards := failed("test.layer"), failed("test.reward")
+	messages := []kafka.Message{{Topic: "test.layer"}, {Topic: "test.layer"}, {Topic: "test.reward"}}
+
+	// the delivered messages are not counted
+	kafkaCompletion(messages, nil)
+	require.Equal(t, layers, failed("test.layer"))
+
+	kafkaCompletion(messages, errors.New("leader not available"))
+	require.Equal(t, layers+2, failed("test.layer"))
+	require.Equal(t, rewards+1, failed("test.reward"))
+
+	// the writer reports its deliveries to the completion
+	s, err := New("kafka://broker1:9092")
+	require.NoError(t, err)
+	require.NotNil(t, s.(*Kafka).writer.Completion)
+}
@@ -0,0 +1,101 @@
+// Package cache keeps the most read documents (network info, current layer and epoch, first pages
+// of accounts) in Redis, shared by the API servers. The collector invalidates the keys when it
+// writes the documents.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	KeyNetworkInfo  = "explorer:networkinfo"
+	KeyCurrentLayer = "explorer:layer:current"
+	KeyCurrentEpoch = "explorer:epoch:current"
+	// KeyTopAccounts is a hash of the first accounts pages by page size.
+	KeyTopAccounts = "explorer:accounts:top"
+)
+
+// Cache is a Redis document cache. Entries expire after ttl even if they are not invalidated.
+type Cache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// New connects to redis://<user>:<password>@<host>:<port>/<db>.
+func New(rawURL string, ttl time.Duration) (*Cache, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error ping to redis: %w", err)
+	}
+	return &Cache{client: client, ttl: ttl}, nil
+}
+
+func (c *Cache) Close() error {
+	return c.client.Close()
+}
+
+// Get decodes the document stored under key into out. It reports false on a miss.
+func (c *Cache) Get(ctx context.Context, key string, out any) (bool, error) {
+	data, err := c.client.Get(ctx, key).Bytes()
+	return decode(data, err, out)
+}
+
+// Set stores the document under key.
+func (c *Cache) Set(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.client.Set(ctx, key, data, c.ttl).Err()
+}
+
+// GetField decodes the document stored in the field of the hash key into out. It reports false on a miss.
+func (c *Cache) GetField(ctx context.Context, key, field string, out any) (bool, error) {
+	data, err := c.client.HGet(ctx, key, field).Bytes()
+	return decode(data, err, out)
+}
+
+// SetField stores the document in the field of the hash key. The ttl applies to the whole hash.
+func (c *Cache) SetField(ctx context.Context, key, field string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, field, data)
+		pipe.Expire(ctx, key, c.ttl)
+		return nil
+	})
+	return err
+}
+
+// Invalidate removes the keys, the next read loads them from the database.
+func (c *Cache) Invalidate(ctx context.Context, keys ...string) error {
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func decode(data []byte, err error, out any) (bool, error) {
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return false, err
+	}
+	return true, nil
+}
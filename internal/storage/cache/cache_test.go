@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache/cachetest"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	server := cachetest.NewServer(t)
+	c, err := cache.New(server.URL(), time.Minute)
+	require.NoError(t, err)
+	defer c.Close()
+
+	var info model.NetworkInfo
+	hit, err := c.Get(ctx, cache.KeyNetworkInfo, &info)
+	require.NoError(t, err)
+	require.False(t, hit)
+
+	require.NoError(t, c.Set(ctx, cache.KeyNetworkInfo, &model.NetworkInfo{GenesisId: "0x01", LastLayer: 12}))
+	hit, err = c.Get(ctx, cache.KeyNetworkInfo, &info)
+	require.NoError(t, err)
+	require.True(t, hit)
+	require.Equal(t, model.NetworkInfo{GenesisId: "0x01", LastLayer: 12}, info)
+
+	var accounts []string
+	hit, err = c.GetField(ctx, cache.KeyTopAccounts, "20", &accounts)
+	require.NoError(t, err)
+	require.False(t, hit)
+	require.NoError(t, c.SetField(ctx, cache.KeyTopAccounts, "20", []string{"a", "b"}))
+	require.NoError(t, c.SetField(ctx, cache.KeyTopAccounts, "50", []string{"a"}))
+	hit, err = c.GetField(ctx, cache.KeyTopAccounts, "20", &accounts)
+	require.NoError(t, err)
+	require.True(t, hit)
+	require.Equal(t, []string{"a", "b"}, accounts)
+
+	// an invalidated hash drops all its pages
+	require.NoError(t, c.Invalidate(ctx, cache.KeyNetworkInfo, cache.KeyTopAccounts))
+	require.Zero(t, server.Keys())
+	hit, err = c.GetField(ctx, cache.KeyTopAccounts, "50", &accounts)
+	require.NoError(t, err)
+	require.False(t, hit)
+}
+
+func TestCacheUnreachable(t *testing.T) {
+	_, err := cache.New("redis://127.0.0.1:1", time.Minute)
+	require.ErrorContains(t, err, "error ping to redis")
+
+	_, err = cache.New("http://localhost", time.Minute)
+	require.ErrorContains(t, err, "invalid redis url")
+}
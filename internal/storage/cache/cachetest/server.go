@@ -0,0 +1,201 @@
+// Package cachetest serves the subset of the Redis protocol used by the cache, to test it without
+// a Redis server.
+package cachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is an in-memory Redis server supporting the strings and hashes commands of the cache.
+// Expirations are accepted and ignored.
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+}
+
+// NewServer starts a server on a local port, stopped when the test ends.
+func NewServer(t testing.TB) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		listener: l,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.serve()
+	return s
+}
+
+// URL returns the url of the server for cache.New.
+func (s *Server) URL() string {
+	return "redis://" + s.listener.Addr().String()
+}
+
+// Keys returns the number of stored keys.
+func (s *Server) Keys() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.strings) + len(s.hashes)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var queued [][]string
+	inMulti := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inMulti = true
+			w.WriteString("+OK\r\n")
+		case name == "EXEC":
+			fmt.Fprintf(w, "*%d\r\n", len(queued))
+			for _, cmd := range queued {
+				w.WriteString(s.exec(cmd))
+			}
+			queued, inMulti = nil, false
+		case inMulti:
+			queued = append(queued, args)
+			w.WriteString("+QUEUED\r\n")
+		default:
+			w.WriteString(s.exec(args))
+		}
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// exec runs the command and returns its encoded reply.
+func (s *Server) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		v, ok := s.strings[args[1]]
+		return bulk(v, ok)
+	case "SET":
+		s.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "HGET":
+		v, ok := s.hashes[args[1]][args[2]]
+		return bulk(v, ok)
+	case "HSET":
+		hash, ok := s.hashes[args[1]]
+		if !ok {
+			hash = make(map[string]string)
+			s.hashes[args[1]] = hash
+		}
+		added := 0
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, ok := hash[args[i]]; !ok {
+				added++
+			}
+			hash[args[i]] = args[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", added)
+	case "EXPIRE":
+		_, isString := s.strings[args[1]]
+		_, isHash := s.hashes[args[1]]
+		if isString || isHash {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			_, isString := s.strings[key]
+			_, isHash := s.hashes[key]
+			if isString || isHash {
+				deleted++
+			}
+			delete(s.strings, key)
+			delete(s.hashes, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func bulk(v string, ok bool) string {
+	if !ok {
+		return "$-1\r\n"
+	}
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid command length %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("unexpected argument %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid argument length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
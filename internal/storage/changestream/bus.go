@@ -0,0 +1,93 @@
+// Package changestream tails the change streams of the explorer collections and publishes the
+// changed documents to an in-process bus, which feeds the real-time API endpoints independently
+// of the collector write path.
+package changestream
+
+import "sync"
+
+// Kind is the entity type of an event, named after the API resources.
+type Kind string
+
+const (
+	KindLayer       Kind = "layers"
+	KindEpoch       Kind = "epochs"
+	KindTransaction Kind = "txs"
+	KindReward      Kind = "rewards"
+	KindActivation  Kind = "atxs"
+)
+
+// subscriptionBuffer is the number of events queued for a subscriber. Events are dropped for
+// subscribers which don't keep up, so a slow client never delays the others.
+const subscriptionBuffer = 256
+
+// Event is an inserted or updated document.
+type Event struct {
+	Kind Kind `json:"kind"`
+	// Operation is insert, update or replace.
+	Operation string `json:"operation"`
+	// Data is the document decoded into its model, e.g. *model.Layer.
+	Data any `json:"data"`
+}
+
+// Bus fans out the events to the subscribers.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of the subscribed kinds until it is closed.
+type Subscription struct {
+	bus    *Bus
+	kinds  map[Kind]bool
+	events chan Event
+}
+
+// Subscribe returns a subscription to the events of the given kinds, or to all events if no kind is
+// given.
+func (b *Bus) Subscribe(kinds ...Kind) *Subscription {
+	sub := &Subscription{bus: b, events: make(chan Event, subscriptionBuffer)}
+	if len(kinds) > 0 {
+		sub.kinds = make(map[Kind]bool, len(kinds))
+		for _, kind := range kinds {
+			sub.kinds[kind] = true
+		}
+	}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish sends the event to the subscribers of its kind without blocking.
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.kinds != nil && !sub.kinds[e.Kind] {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			metricDroppedEvents.Inc()
+		}
+	}
+}
+
+// Events returns the channel of the events, it is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.events)
+	}
+}
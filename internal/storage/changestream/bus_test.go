@@ -0,0 +1,37 @@
+package changestream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe()
+	layers := bus.Subscribe(KindLayer)
+
+	bus.Publish(Event{Kind: KindTransaction, Operation: "insert"})
+	bus.Publish(Event{Kind: KindLayer, Operation: "update"})
+
+	require.Equal(t, KindTransaction, (<-all.Events()).Kind)
+	require.Equal(t, KindLayer, (<-all.Events()).Kind)
+	require.Equal(t, Event{Kind: KindLayer, Operation: "update"}, <-layers.Events())
+	require.Empty(t, layers.Events())
+
+	layers.Close()
+	layers.Close()
+	_, ok := <-layers.Events()
+	require.False(t, ok)
+	bus.Publish(Event{Kind: KindLayer})
+	require.Len(t, all.Events(), 1)
+}
+
+func TestBusDropsForSlowSubscribers(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe()
+	for i := 0; i < subscriptionBuffer+10; i++ {
+		bus.Publish(Event{Kind: KindReward})
+	}
+	require.Len(t, sub.Events(), subscriptionBuffer)
+}
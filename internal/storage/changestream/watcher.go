@@ -0,0 +1,149 @@
+package changestream
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// retryInterval is the pause before watching again after the change stream failed.
+const retryInterval = 5 * time.Second
+
+var (
+	metricEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_changestream_events",
+		Help: "Number of documents changes published to the real-time API",
+	}, []string{"kind"})
+	metricDroppedEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "explorer_changestream_dropped_events",
+		Help: "Number of events dropped for subscribers not keeping up",
+	})
+)
+
+type watched struct {
+	kind   Kind
+	decode func(bson.Raw) (any, error)
+}
+
+func decodeAs[T any](doc bson.Raw) (any, error) {
+	var v T
+	if err := bson.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// collections are the watched collections.
+var collections = map[string]watched{
+	"layers":      {KindLayer, decodeAs[model.Layer]},
+	"epochs":      {KindEpoch, decodeAs[model.Epoch]},
+	"txs":         {KindTransaction, decodeAs[model.Transaction]},
+	"rewards":     {KindReward, decodeAs[model.Reward]},
+	"activations": {KindActivation, decodeAs[model.Activation]},
+}
+
+// Watcher publishes the changes of the watched collections to a bus. Change streams are only
+// available on replica sets and sharded clusters.
+type Watcher struct {
+	client *mongo.Client
+	db     *storage.Database
+	bus    *Bus
+
+	// resumeToken is the position of the last published change, the stream resumes after it when
+	// it is opened again.
+	resumeToken bson.Raw
+}
+
+// New connects to the database with a dedicated client, so that the long lived change stream
+// cursor does not hold a connection of the API queries pool. The collection names start with the
+// prefix, see storage.Database. The options override the ones of the url, e.g. the TLS settings.
+func New(ctx context.Context, dbURL string, dbName string, prefix string, bus *Bus, opts ...*options.ClientOptions) (*Watcher, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{options.Client().ApplyURI(dbURL)}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("error connect to db: %w", err)
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		return nil, fmt.Errorf("error ping to db: %w", err)
+	}
+	return &Watcher{client: client, db: storage.NewDatabase(client, dbName, prefix), bus: bus}, nil
+}
+
+// Run publishes the changes until the context is canceled, opening the stream again if it fails.
+func (w *Watcher) Run(ctx context.Context) {
+	for {
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.Error("change stream", err)
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	names := make(bson.A, 0, len(collections))
+	for name := range collections {
+		names = append(names, w.db.CollectionName(name))
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{
+		{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}},
+		{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: names}}},
+	}}}}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if w.resumeToken != nil {
+		opts.SetResumeAfter(w.resumeToken)
+	}
+	stream, err := w.db.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change struct {
+			OperationType string `bson:"operationType"`
+			Ns            struct {
+				Coll string `bson:"coll"`
+			} `bson:"ns"`
+			FullDocument bson.Raw `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		w.resumeToken = stream.ResumeToken()
+		c, ok := collections[strings.TrimPrefix(change.Ns.Coll, w.db.Prefix())]
+		if !ok || change.FullDocument == nil {
+			// the document was removed before the update was looked up
+			continue
+		}
+		data, err := c.decode(change.FullDocument)
+		if err != nil {
+			logging.Error("change stream: decode", err, logging.Collection(change.Ns.Coll))
+			continue
+		}
+		metricEvents.WithLabelValues(string(c.kind)).Inc()
+		w.bus.Publish(Event{Kind: c.kind, Operation: change.OperationType, Data: data})
+	}
+	return stream.Err()
+}
+
+func (w *Watcher) Close() error {
+	return w.client.Disconnect(context.Background())
+}
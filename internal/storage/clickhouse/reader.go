@@ -0,0 +1,96 @@
+// Package clickhouse serves the API aggregations over rewards from the tables written by the
+// clickhouse sink, while Mongo keeps serving point lookups.
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+)
+
+var _ storagereader.AnalyticsReader = (*Reader)(nil)
+
+// rewardColumns maps the reward document fields accepted in filters to their columns.
+var rewardColumns = map[string]string{
+	"layer":    "layer",
+	"smesher":  "smesher",
+	"coinbase": "coinbase",
+}
+
+// Reader runs the analytics queries. This client is read-only.
+type Reader struct {
+	conn driver.Conn
+}
+
+// NewReader connects to clickhouse://<user>:<password>@<host>:9000/<db>.
+func NewReader(rawURL string) (*Reader, error) {
+	conn, err := sink.OpenClickHouse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{conn: conn}, nil
+}
+
+func (r *Reader) Close() error {
+	return r.conn.Close()
+}
+
+// GetTotalRewards returns the sum and the number of rewards matching the filter. Only equality
+// and range conditions on layer, smesher and coinbase are supported.
+func (r *Reader) GetTotalRewards(ctx context.Context, filter *bson.D) (total, count int64, err error) {
+	where, args, err := rewardsWhere(filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	var sum, n uint64
+	err = r.conn.QueryRow(ctx, "SELECT sum(total), count() FROM rewards FINAL WHERE "+where, args...).Scan(&sum, &n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get total rewards: %w", err)
+	}
+	return int64(sum), int64(n), nil
+}
+
+// CountCoinbaseRewards returns the sum and the number of rewards for given coinbase address.
+func (r *Reader) CountCoinbaseRewards(ctx context.Context, coinbase string) (total, count int64, err error) {
+	return r.GetTotalRewards(ctx, &bson.D{{Key: "coinbase", Value: coinbase}})
+}
+
+// CountSmesherRewards returns the sum and the number of rewards of the smesher.
+func (r *Reader) CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error) {
+	return r.GetTotalRewards(ctx, &bson.D{{Key: "smesher", Value: smesherID}})
+}
+
+func rewardsWhere(filter *bson.D) (string, []any, error) {
+	conds := []string{"1"}
+	var args []any
+	if filter == nil {
+		return conds[0], nil, nil
+	}
+	for _, e := range *filter {
+		column, ok := rewardColumns[e.Key]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported rewards filter `%s`", e.Key)
+		}
+		ops, ok := e.Value.(bson.D)
+		if !ok {
+			conds = append(conds, column+" = ?")
+			args = append(args, e.Value)
+			continue
+		}
+		for _, op := range ops {
+			cmp, ok := map[string]string{"$eq": "=", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op.Key]
+			if !ok {
+				return "", nil, fmt.Errorf("unsupported rewards filter operator `%s`", op.Key)
+			}
+			conds = append(conds, column+" "+cmp+" ?")
+			args = append(args, op.Value)
+		}
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
@@ -0,0 +1,43 @@
+package clickhouse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRewardsWhere(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		filter *bson.D
+		where  string
+		args   []any
+	}{
+		{name: "all", where: "1"},
+		{
+			name:   "equality",
+			filter: &bson.D{{Key: "smesher", Value: "0x51"}, {Key: "coinbase", Value: "sm1"}},
+			where:  "1 AND smesher = ? AND coinbase = ?",
+			args:   []any{"0x51", "sm1"},
+		},
+		{
+			name:   "range",
+			filter: &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: 10}, {Key: "$lt", Value: 20}}}},
+			where:  "1 AND layer >= ? AND layer < ?",
+			args:   []any{10, 20},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			where, args, err := rewardsWhere(tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.where, where)
+			require.Equal(t, tc.args, args)
+		})
+	}
+
+	_, _, err := rewardsWhere(&bson.D{{Key: "atx", Value: "0xa1"}})
+	require.Error(t, err)
+	_, _, err = rewardsWhere(&bson.D{{Key: "layer", Value: bson.D{{Key: "$in", Value: bson.A{1, 2}}}}})
+	require.Error(t, err)
+}
@@ -13,6 +13,7 @@ import (
 type StorageReader interface {
 	Ping(ctx context.Context) error
 	GetNetworkInfo(ctx context.Context) (*model.NetworkInfo, error)
+	FindPage(ctx context.Context, collection string, query *bson.D, opts *options.FindOptions, out any) (int64, error)
 
 	CountTransactions(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetTransactions(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Transaction, error)
@@ -27,16 +28,20 @@ type StorageReader interface {
 	CountAccounts(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetAccounts(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Account, error)
 	GetAccountSummary(ctx context.Context, address string) (*model.AccountSummary, error)
+	GetBalanceAt(ctx context.Context, address string, layer uint32) (*model.AccountSnapshot, error)
 
 	CountActivations(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetActivations(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Activation, error)
 
 	CountBlocks(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetBlocks(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Block, error)
+	GetBlockCertificate(ctx context.Context, blockID string) (*model.BlockCertificate, error)
 
 	CountEpochs(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetEpochs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Epoch, error)
 	GetEpoch(ctx context.Context, epochNumber int) (*model.Epoch, error)
+	CountEpochStats(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
+	GetEpochStats(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.EpochStats, error)
 
 	CountLayers(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
 	GetLayers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Layer, error)
@@ -56,4 +61,38 @@ type StorageReader interface {
 	CountEpochSmeshers(ctx context.Context, query *bson.D) (int64, error)
 	GetEpochSmeshers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Smesher, error)
 	CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error)
+	CountSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
+	GetSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.SmesherChange, error)
+	GetSmeshersNear(ctx context.Context, lon, lat, radius float64, limit int64) ([]*model.Smesher, error)
+	GetSmeshersWithin(ctx context.Context, box model.GeoBox, limit int64) ([]*model.Smesher, error)
+	GetGeoHeatmap(ctx context.Context) (*model.GeoHeatmap, error)
+	GetSmesherParticipation(ctx context.Context, smesherID string, epoch, layerStart, layerEnd uint32) (*model.SmesherParticipation, error)
+	GetMalfeasanceProofs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.MalfeasanceProof, error)
+
+	CountDailyTransactions(ctx context.Context) (int64, error)
+	GetDailyTransactions(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyTransactions, error)
+	CountDailyAccounts(ctx context.Context) (int64, error)
+	GetDailyAccounts(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyAccounts, error)
+	SumDailyAccounts(ctx context.Context, query *bson.D) (int64, error)
+	GetTransactionTypesStats(ctx context.Context, query *bson.D) ([]*model.TransactionTypeStats, error)
+	GetBlockStats(ctx context.Context, query *bson.D) ([]*model.BlockStats, error)
+	GetEpochCoinbases(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.CoinbaseShare, error)
+	GetEpochUnits(ctx context.Context, epoch uint32) ([]*model.UnitsCount, error)
+	GetEpochSmesherRewards(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.SmesherEpochRewards, error)
+	GetRollups(ctx context.Context, period string, from, to uint32) ([]*model.Rollup, error)
+	GetBalanceCohorts(ctx context.Context, epochNumLayers, lastEpoch uint32) ([]*model.BalanceCohorts, error)
+	GetVaults(ctx context.Context) ([]*model.Vault, error)
+	GetTotals(ctx context.Context) (*model.Totals, error)
+
+	SearchLabels(ctx context.Context, text string, limit int64) ([]*model.Label, error)
+	GetPrices(ctx context.Context, from, to uint32) ([]*model.Price, error)
+
+	GetStorageStats(ctx context.Context) ([]*model.CollectionStats, error)
+}
+
+// AnalyticsReader serves the aggregations over rewards from an analytics store, see the clickhouse sink.
+type AnalyticsReader interface {
+	GetTotalRewards(ctx context.Context, filter *bson.D) (total, count int64, err error)
+	CountCoinbaseRewards(ctx context.Context, coinbase string) (total, count int64, err error)
+	CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error)
 }
@@ -2,8 +2,10 @@ package storagereader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/spacemeshos/explorer-backend/model"
@@ -11,7 +13,7 @@ import (
 
 // CountAccounts returns the number of accounts matching the query.
 func (s *Reader) CountAccounts(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	return s.db.Collection("accounts").CountDocuments(ctx, query, opts...)
+	return s.countDocuments(ctx, "accounts", query, opts...)
 }
 
 // GetAccounts returns the accounts matching the query.
@@ -25,7 +27,7 @@ func (s *Reader) GetAccounts(ctx context.Context, query *bson.D, opts ...*option
 		bson.D{
 			{"$lookup",
 				bson.D{
-					{"from", "txs"},
+					{"from", s.db.CollectionName("txs")},
 					{"let", bson.D{{"addr", "$address"}}},
 					{"pipeline",
 						bson.A{
@@ -185,3 +187,27 @@ func (s *Reader) GetAccountSummary(ctx context.Context, address string) (*model.
 
 	return &accSummary, nil
 }
+
+// GetBalanceAt returns the balance of the account after the layer, from the last snapshot of the
+// account before the layer and the last balance change since.
+func (s *Reader) GetBalanceAt(ctx context.Context, address string, layer uint32) (*model.AccountSnapshot, error) {
+	upTo := bson.D{{Key: "address", Value: address}, {Key: "layer", Value: bson.D{{Key: "$lte", Value: layer}}}}
+	latest := options.FindOne().SetSort(bson.D{{Key: "layer", Value: -1}})
+
+	var snapshot *model.AccountSnapshot
+	err := s.findOne(ctx, "account_snapshots", upTo, &snapshot, latest)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("error get snapshot of `%s` at layer %d: %w", address, layer, err)
+	}
+	changed := bson.D{{Key: "$lte", Value: layer}}
+	if snapshot != nil {
+		changed = append(changed, bson.E{Key: "$gte", Value: snapshot.Layer})
+	}
+	var change *model.BalanceChange
+	err = s.findOne(ctx, "balance_changes",
+		bson.D{{Key: "address", Value: address}, {Key: "layer", Value: changed}}, &change, latest)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("error get balance of `%s` at layer %d: %w", address, layer, err)
+	}
+	return model.BalanceAt(address, layer, snapshot, change), nil
+}
@@ -12,7 +12,7 @@ import (
 
 // CountActivations returns the number of activations matching the query.
 func (s *Reader) CountActivations(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("activations").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "activations", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count activations: %w", err)
 	}
@@ -21,13 +21,9 @@ func (s *Reader) CountActivations(ctx context.Context, query *bson.D, opts ...*o
 
 // GetActivations returns the activations matching the query.
 func (s *Reader) GetActivations(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Activation, error) {
-	cursor, err := s.db.Collection("activations").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get activations: %w", err)
-	}
 	var docs []*model.Activation
-	if err = cursor.All(ctx, &docs); err != nil {
-		return nil, fmt.Errorf("error decode activations: %w", err)
+	if err := s.findAll(ctx, "activations", query, &docs, opts...); err != nil {
+		return nil, fmt.Errorf("failed to get activations: %w", err)
 	}
 	return docs, nil
 }
@@ -12,7 +12,7 @@ import (
 
 // CountApps returns the number of apps matching the query.
 func (s *Reader) CountApps(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("apps").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "apps", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count apps: %w", err)
 	}
@@ -21,13 +21,9 @@ func (s *Reader) CountApps(ctx context.Context, query *bson.D, opts ...*options.
 
 // GetApps returns the apps matching the query.
 func (s *Reader) GetApps(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.App, error) {
-	cursor, err := s.db.Collection("apps").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get apps: %w", err)
-	}
 	var docs []*model.App
-	if err = cursor.All(ctx, &docs); err != nil {
-		return nil, fmt.Errorf("error decode apps: %w", err)
+	if err := s.findAll(ctx, "apps", query, &docs, opts...); err != nil {
+		return nil, fmt.Errorf("failed to get apps: %w", err)
 	}
 	return docs, nil
 }
@@ -2,9 +2,11 @@ package storagereader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/spacemeshos/explorer-backend/model"
@@ -12,7 +14,7 @@ import (
 
 // CountBlocks returns the number of blocks matching the query.
 func (s *Reader) CountBlocks(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("blocks").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "blocks", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count blocks: %w", err)
 	}
@@ -21,14 +23,22 @@ func (s *Reader) CountBlocks(ctx context.Context, query *bson.D, opts ...*option
 
 // GetBlocks returns the blocks matching the query.
 func (s *Reader) GetBlocks(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Block, error) {
-	cursor, err := s.db.Collection("blocks").Find(ctx, query, opts...)
+	blocks, err := findTiered[*model.Block](ctx, s, "blocks", query, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("error get blocks: %w", err)
 	}
+	return blocks, nil
+}
 
-	var blocks []*model.Block
-	if err = cursor.All(ctx, &blocks); err != nil {
-		return nil, fmt.Errorf("error decode blocks: %w", err)
+// GetBlockCertificate returns the certificate of the block, or nil if the block was not certified.
+func (s *Reader) GetBlockCertificate(ctx context.Context, blockID string) (*model.BlockCertificate, error) {
+	var cert model.BlockCertificate
+	err := s.findOne(ctx, "certificates", bson.D{{Key: "blockId", Value: blockID}}, &cert)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error get block certificate: %w", err)
 	}
-	return blocks, nil
+	return &cert, nil
 }
@@ -2,8 +2,10 @@ package storagereader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	bson "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/spacemeshos/explorer-backend/model"
@@ -11,7 +13,7 @@ import (
 
 // CountEpochs returns the number of epochs matching the query.
 func (s *Reader) CountEpochs(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("epochs").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "epochs", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count epochs: %w", err)
 	}
@@ -20,19 +22,13 @@ func (s *Reader) CountEpochs(ctx context.Context, query *bson.D, opts ...*option
 
 // GetEpochs returns the epochs matching the query.
 func (s *Reader) GetEpochs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Epoch, error) {
-	cursor, err := s.db.Collection("epochs").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("error get epochs: %w", err)
-	}
 	var epochs []*model.Epoch
-	if err = cursor.All(ctx, &epochs); err != nil {
-		return nil, err
+	if err := s.findAll(ctx, "epochs", query, &epochs, opts...); err != nil {
+		return nil, fmt.Errorf("error get epochs: %w", err)
 	}
 
 	for _, epoch := range epochs {
-		total, count, err := s.GetTotalRewards(context.TODO(), &bson.D{{Key: "layer", Value: bson.D{
-			{Key: "$gte", Value: epoch.LayerStart}, {Key: "$lte", Value: epoch.LayerEnd}}},
-		})
+		total, count, err := s.getEpochRewards(ctx, epoch.Number)
 		if err != nil {
 			return nil, fmt.Errorf("error get total rewards for epoch %d: %w", epoch.Number, err)
 		}
@@ -48,21 +44,19 @@ func (s *Reader) GetEpochs(ctx context.Context, query *bson.D, opts ...*options.
 
 // GetEpoch returns the epoch matching the query.
 func (s *Reader) GetEpoch(ctx context.Context, epochNumber int) (*model.Epoch, error) {
-	cursor, err := s.db.Collection("epochs").Find(ctx, bson.D{{Key: "number", Value: epochNumber}})
-	if err != nil {
-		return nil, fmt.Errorf("error get epoch `%d`: %w", epochNumber, err)
-	}
-	if !cursor.Next(ctx) {
+	var epoch *model.Epoch
+	err := s.findOne(ctx, "epochs", bson.D{{Key: "number", Value: epochNumber}}, &epoch)
+	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, nil
 	}
-	var epoch *model.Epoch
-	if err = cursor.Decode(&epoch); err != nil {
-		return nil, fmt.Errorf("error decode epoch `%d`: %w", epochNumber, err)
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch `%d`: %w", epochNumber, err)
 	}
 
-	total, count, err := s.GetTotalRewards(context.TODO(), &bson.D{{Key: "layer", Value: bson.D{
-		{Key: "$gte", Value: epoch.LayerStart}, {Key: "$lte", Value: epoch.LayerEnd}}},
-	})
+	total, count, err := s.getEpochRewards(ctx, epoch.Number)
+	if err != nil {
+		return nil, fmt.Errorf("error get total rewards for epoch %d: %w", epoch.Number, err)
+	}
 
 	epoch.Stats.Current.Rewards = total
 	epoch.Stats.Current.RewardsNumber = count
@@ -71,3 +65,37 @@ func (s *Reader) GetEpoch(ctx context.Context, epochNumber int) (*model.Epoch, e
 
 	return epoch, nil
 }
+
+// CountEpochStats returns the number of epoch stats versions matching the query.
+func (s *Reader) CountEpochStats(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.countDocuments(ctx, "epoch_stats", query, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("error count epoch stats: %w", err)
+	}
+	return count, nil
+}
+
+// GetEpochStats returns the epoch stats versions matching the query.
+func (s *Reader) GetEpochStats(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.EpochStats, error) {
+	var stats []*model.EpochStats
+	if err := s.findAll(ctx, "epoch_stats", query, &stats, opts...); err != nil {
+		return nil, fmt.Errorf("error get epoch stats: %w", err)
+	}
+	return stats, nil
+}
+
+// getEpochRewards returns the sum and the number of rewards of the epoch, maintained by the collector.
+func (s *Reader) getEpochRewards(ctx context.Context, epochNumber int32) (total, count int64, err error) {
+	var stats struct {
+		Total int64 `bson:"total"`
+		Count int64 `bson:"count"`
+	}
+	err = s.findOne(ctx, "stats_epoch_rewards", bson.D{{Key: "epoch", Value: epochNumber}}, &stats)
+	if err == mongo.ErrNoDocuments {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return stats.Total, stats.Count, nil
+}
@@ -0,0 +1,24 @@
+package storagereader
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// SearchLabels returns the labels whose name contains a word of the text, best matches first.
+func (s *Reader) SearchLabels(ctx context.Context, text string, limit int64) ([]*model.Label, error) {
+	score := bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}
+	var labels []*model.Label
+	err := s.findAll(ctx, "labels",
+		bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: text}}}}, &labels,
+		options.Find().SetProjection(append(bson.D{{Key: "_id", Value: 0}}, score...)).SetSort(score).SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("error search labels: %w", err)
+	}
+	return labels, nil
+}
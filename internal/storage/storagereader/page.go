@@ -0,0 +1,101 @@
+package storagereader
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// FindPage decodes the page of the documents of the collection matching the query into out, a
+// pointer to a slice, and returns the number of documents matching the query.
+//
+// A filtered list is read with a single `$facet` aggregation instead of a count and a find, which
+// includes the archive of a tiered collection. The unfiltered lists, and the lists which only hide
+// the orphaned documents, count from the collection metadata, which is cheaper than the facet. When
+// the tiering is enabled, the tiered collections count and find separately, so that their pages
+// continue in the archive.
+func (s *Reader) FindPage(ctx context.Context, collection string, query *bson.D, opts *options.FindOptions, out any) (int64, error) {
+	if query == nil {
+		query = &bson.D{}
+	}
+	if opts == nil {
+		opts = options.Find()
+	}
+	_, tiered := storage.TierArchive(collection)
+	if (tiered && s.tiering) || len(*query) == 0 || storage.OnlyNotOrphaned(query) {
+		total, err := s.countDocuments(ctx, collection, query)
+		if err != nil {
+			return 0, fmt.Errorf("error count %s: %w", collection, err)
+		}
+		if total == 0 {
+			return 0, storage.DecodePage[bson.Raw](out, nil, nil)
+		}
+		docs, err := findTiered[bson.Raw](ctx, s, collection, query, opts)
+		if err != nil {
+			return 0, fmt.Errorf("error get %s: %w", collection, err)
+		}
+		return total, storage.DecodePage(out, docs, bson.Unmarshal)
+	}
+
+	var page struct {
+		Data  []bson.Raw `bson:"data"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}
+	err := s.retryPolicy.Do(ctx, collection, storage.OperationAggregate, storage.IsTransient, func(ctx context.Context) error {
+		cursor, err := s.collection(collection).Aggregate(ctx, s.facetPipeline(collection, query, opts), options.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		if !cursor.Next(ctx) {
+			return cursor.Err()
+		}
+		return cursor.Decode(&page)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error get %s: %w", collection, err)
+	}
+	var total int64
+	if len(page.Total) > 0 {
+		total = page.Total[0].Count
+	}
+	return total, storage.DecodePage(out, page.Data, bson.Unmarshal)
+}
+
+// facetPipeline returns the aggregation of the page and the count of the documents of the
+// collection, and of its archive, matching the query. The sort precedes the `$facet`, whose
+// sub-pipelines cannot use the indexes.
+func (s *Reader) facetPipeline(collection string, query *bson.D, opts *options.FindOptions) mongo.Pipeline {
+	match := bson.D{{Key: "$match", Value: *query}}
+	pipeline := mongo.Pipeline{match}
+	if _, ok := storage.TierArchive(collection); ok {
+		pipeline = append(pipeline, s.unionArchive(collection, match))
+	}
+	if opts.Sort != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: opts.Sort}})
+	}
+	var data bson.A
+	if opts.Skip != nil && *opts.Skip > 0 {
+		data = append(data, bson.D{{Key: "$skip", Value: *opts.Skip}})
+	}
+	if opts.Limit != nil && *opts.Limit > 0 {
+		data = append(data, bson.D{{Key: "$limit", Value: *opts.Limit}})
+	}
+	if opts.Projection != nil {
+		data = append(data, bson.D{{Key: "$project", Value: opts.Projection}})
+	}
+	if data == nil {
+		data = bson.A{}
+	}
+	return append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "data", Value: data},
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}}})
+}
@@ -0,0 +1,23 @@
+package storagereader
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetPrices returns the prices recorded in [from, to], by timestamp.
+func (s *Reader) GetPrices(ctx context.Context, from, to uint32) ([]*model.Price, error) {
+	prices := []*model.Price{}
+	err := s.findAll(ctx, "prices",
+		bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}}}, &prices,
+		options.Find().SetProjection(bson.D{{Key: "_id", Value: 0}}).SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get prices: %w", err)
+	}
+	return prices, nil
+}
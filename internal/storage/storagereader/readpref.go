@@ -0,0 +1,28 @@
+package storagereader
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// ReadPreference returns the client options directing the reads to the members of the replica set
+// selected by mode (primary, primaryPreferred, secondary, secondaryPreferred or nearest). Reads
+// from secondaries are served from members lagging the primary by at most maxStaleness, if set.
+func ReadPreference(mode string, maxStaleness time.Duration) (*options.ClientOptions, error) {
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference `%s`: %w", mode, err)
+	}
+	var opts []readpref.Option
+	if maxStaleness > 0 {
+		opts = append(opts, readpref.WithMaxStaleness(maxStaleness))
+	}
+	rp, err := readpref.New(m, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid read preference `%s`: %w", mode, err)
+	}
+	return options.Client().SetReadPreference(rp), nil
+}
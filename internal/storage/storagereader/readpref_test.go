@@ -0,0 +1,46 @@
+package storagereader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestReadPreference(t *testing.T) {
+	for _, tc := range []struct {
+		mode         string
+		maxStaleness time.Duration
+		expected     readpref.Mode
+		err          bool
+	}{
+		{mode: "primary", expected: readpref.PrimaryMode},
+		{mode: "secondaryPreferred", expected: readpref.SecondaryPreferredMode},
+		{mode: "secondary", maxStaleness: 2 * time.Minute, expected: readpref.SecondaryMode},
+		{mode: "nearest", maxStaleness: 90 * time.Second, expected: readpref.NearestMode},
+		{mode: "secondaries", err: true},
+		// the primary has no replication lag to bound
+		{mode: "primary", maxStaleness: 2 * time.Minute, err: true},
+	} {
+		t.Run(tc.mode+"/"+tc.maxStaleness.String(), func(t *testing.T) {
+			opt, err := ReadPreference(tc.mode, tc.maxStaleness)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, opt.ReadPreference.Mode())
+			maxStaleness, ok := opt.ReadPreference.MaxStaleness()
+			require.Equal(t, tc.maxStaleness > 0, ok)
+			require.Equal(t, tc.maxStaleness, maxStaleness)
+		})
+	}
+
+	// the flag overrides the read preference of the url
+	opt, err := ReadPreference("secondary", 0)
+	require.NoError(t, err)
+	opts := options.MergeClientOptions(options.Client().ApplyURI("mongodb://localhost:27017/?readPreference=primary"), opt)
+	require.Equal(t, readpref.SecondaryMode, opts.ReadPreference.Mode())
+}
@@ -2,6 +2,7 @@ package storagereader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/spacemeshos/explorer-backend/utils"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,7 +17,7 @@ import (
 
 // CountRewards returns the number of rewards matching the query.
 func (s *Reader) CountRewards(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("rewards").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "rewards", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count transactions: %w", err)
 	}
@@ -25,14 +26,9 @@ func (s *Reader) CountRewards(ctx context.Context, query *bson.D, opts ...*optio
 
 // GetRewards returns the rewards matching the query.
 func (s *Reader) GetRewards(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Reward, error) {
-	cursor, err := s.db.Collection("rewards").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("error get rewards: %w", err)
-	}
-
 	var rewards []*model.Reward
-	if err = cursor.All(ctx, &rewards); err != nil {
-		return nil, fmt.Errorf("error decode rewards: %w", err)
+	if err := s.findAll(ctx, "rewards", query, &rewards, opts...); err != nil {
+		return nil, fmt.Errorf("error get rewards: %w", err)
 	}
 	return rewards, nil
 }
@@ -43,31 +39,25 @@ func (s *Reader) GetReward(ctx context.Context, rewardID string) (*model.Reward,
 	if err != nil {
 		return nil, fmt.Errorf("error create objectID from string `%s`: %w", rewardID, err)
 	}
-	cursor, err := s.db.Collection("rewards").Find(ctx, &bson.D{{Key: "_id", Value: id}})
-	if err != nil {
-		return nil, fmt.Errorf("error get reward `%s`: %w", rewardID, err)
-	}
-	if !cursor.Next(ctx) {
+	var reward *model.Reward
+	err = s.findOne(ctx, "rewards", &bson.D{{Key: "_id", Value: id}}, &reward)
+	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, nil
 	}
-	var reward *model.Reward
-	if err = cursor.Decode(&reward); err != nil {
-		return nil, fmt.Errorf("error decode reward `%s`: %w", rewardID, err)
+	if err != nil {
+		return nil, fmt.Errorf("error get reward `%s`: %w", rewardID, err)
 	}
 	return reward, nil
 }
 
 func (s *Reader) GetRewardV2(ctx context.Context, smesherID string, layer uint32) (*model.Reward, error) {
-	cursor, err := s.db.Collection("rewards").Find(ctx, &bson.D{{Key: "smesher", Value: smesherID}, {Key: "layer", Value: layer}})
-	if err != nil {
-		return nil, fmt.Errorf("error while getting reward by smesher `%s` and layer `%d`: %w", smesherID, layer, err)
-	}
-	if !cursor.Next(ctx) {
+	var reward *model.Reward
+	err := s.findOne(ctx, "rewards", &bson.D{{Key: "smesher", Value: smesherID}, {Key: "layer", Value: layer}}, &reward)
+	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, nil
 	}
-	var reward *model.Reward
-	if err = cursor.Decode(&reward); err != nil {
-		return nil, fmt.Errorf("error while decoding reward smesher `%s` layer `%d`: %w", smesherID, layer, err)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting reward by smesher `%s` and layer `%d`: %w", smesherID, layer, err)
 	}
 	return reward, nil
 }
@@ -103,8 +93,14 @@ func (s *Reader) CountCoinbaseRewards(ctx context.Context, coinbase string) (tot
 	return utils.GetAsInt64(doc.Lookup("total")), utils.GetAsInt64(doc.Lookup("count")), nil
 }
 
-// GetTotalRewards returns the total number of rewards.
+// GetTotalRewards returns the total number of rewards. Totals over all rewards are summed from the
+// per-epoch sums maintained by the collector.
 func (s *Reader) GetTotalRewards(ctx context.Context, filter *bson.D) (total, count int64, err error) {
+	collection := "rewards"
+	countExpr := any(1)
+	if filter == nil || len(*filter) == 0 {
+		collection, countExpr, filter = "stats_epoch_rewards", "$count", nil
+	}
 	groupStage := bson.D{
 		{Key: "$group", Value: bson.D{
 			{Key: "_id", Value: ""},
@@ -112,7 +108,7 @@ func (s *Reader) GetTotalRewards(ctx context.Context, filter *bson.D) (total, co
 				{Key: "$sum", Value: "$total"},
 			}},
 			{Key: "count", Value: bson.D{
-				{Key: "$sum", Value: 1},
+				{Key: "$sum", Value: countExpr},
 			}},
 		}},
 	}
@@ -127,7 +123,7 @@ func (s *Reader) GetTotalRewards(ctx context.Context, filter *bson.D) (total, co
 		}, pipeline...)
 	}
 
-	cursor, err := s.db.Collection("rewards").Aggregate(ctx, pipeline)
+	cursor, err := s.collection(collection).Aggregate(ctx, pipeline)
 	if err != nil {
 		return 0, 0, fmt.Errorf("error get total rewards: %w", err)
 	}
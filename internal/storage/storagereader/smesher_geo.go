@@ -0,0 +1,60 @@
+package storagereader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetSmeshersNear returns the smeshers within radius meters of the point, nearest first.
+func (s *Reader) GetSmeshersNear(ctx context.Context, lon, lat, radius float64, limit int64) ([]*model.Smesher, error) {
+	filter := bson.D{{Key: "geo.coordinates", Value: bson.D{{Key: "$nearSphere", Value: bson.D{
+		{Key: "$geometry", Value: bson.D{{Key: "type", Value: "Point"}, {Key: "coordinates", Value: bson.A{lon, lat}}}},
+		{Key: "$maxDistance", Value: radius},
+	}}}}}
+	smeshers, err := s.GetSmeshers(ctx, &filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers near [%f, %f]: %w", lon, lat, err)
+	}
+	return smeshers, nil
+}
+
+// GetSmeshersWithin returns the smeshers in the bounding box. The edges of the box are geodesics,
+// so the box is slightly larger than the area between the latitudes away from the equator.
+func (s *Reader) GetSmeshersWithin(ctx context.Context, box model.GeoBox, limit int64) ([]*model.Smesher, error) {
+	ring := bson.A{
+		bson.A{box.MinLon, box.MinLat},
+		bson.A{box.MaxLon, box.MinLat},
+		bson.A{box.MaxLon, box.MaxLat},
+		bson.A{box.MinLon, box.MaxLat},
+		bson.A{box.MinLon, box.MinLat},
+	}
+	filter := bson.D{{Key: "geo.coordinates", Value: bson.D{{Key: "$geoWithin", Value: bson.D{
+		{Key: "$geometry", Value: bson.D{{Key: "type", Value: "Polygon"}, {Key: "coordinates", Value: bson.A{ring}}}},
+	}}}}}
+	smeshers, err := s.GetSmeshers(ctx, &filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers within %v: %w", box, err)
+	}
+	return smeshers, nil
+}
+
+// GetGeoHeatmap returns the last aggregated heat-map of the smeshers, an empty one if the
+// aggregation did not run yet.
+func (s *Reader) GetGeoHeatmap(ctx context.Context) (*model.GeoHeatmap, error) {
+	var heatmap model.GeoHeatmap
+	err := s.findOne(ctx, "stats_geo_heatmap", bson.D{}, &heatmap, options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 0}}))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.NewGeoHeatmap(nil, 0), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get geo heat-map: %w", err)
+	}
+	return &heatmap, nil
+}
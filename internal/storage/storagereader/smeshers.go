@@ -13,7 +13,7 @@ import (
 
 // CountSmeshers returns the number of smeshers matching the query.
 func (s *Reader) CountSmeshers(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("smeshers").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "smeshers", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count transactions: %w", err)
 	}
@@ -22,14 +22,9 @@ func (s *Reader) CountSmeshers(ctx context.Context, query *bson.D, opts ...*opti
 
 // GetSmeshers returns the smeshers matching the query.
 func (s *Reader) GetSmeshers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Smesher, error) {
-	cursor, err := s.db.Collection("smeshers").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("error get smeshers: %w", err)
-	}
-
 	var smeshers []*model.Smesher
-	if err = cursor.All(ctx, &smeshers); err != nil {
-		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	if err := s.findAll(ctx, "smeshers", query, &smeshers, opts...); err != nil {
+		return nil, fmt.Errorf("error get smeshers: %w", err)
 	}
 
 	return smeshers, nil
@@ -37,7 +32,7 @@ func (s *Reader) GetSmeshers(ctx context.Context, query *bson.D, opts ...*option
 
 // GetEpochSmeshers returns the smeshers for specific epoch
 func (s *Reader) CountEpochSmeshers(ctx context.Context, query *bson.D) (int64, error) {
-	count, err := s.db.Collection("smeshers").CountDocuments(ctx, query)
+	count, err := s.countDocuments(ctx, "smeshers", query)
 	if err != nil {
 		return 0, fmt.Errorf("error get smeshers: %w", err)
 	}
@@ -46,14 +41,9 @@ func (s *Reader) CountEpochSmeshers(ctx context.Context, query *bson.D) (int64,
 
 // GetEpochSmeshers returns the smeshers for specific epoch
 func (s *Reader) GetEpochSmeshers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Smesher, error) {
-	cursor, err := s.db.Collection("smeshers").Find(ctx, query, opts...)
-	if err != nil {
-		return nil, fmt.Errorf("error get smeshers: %w", err)
-	}
-
 	var smeshers []*model.Smesher
-	if err = cursor.All(ctx, &smeshers); err != nil {
-		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	if err := s.findAll(ctx, "smeshers", query, &smeshers, opts...); err != nil {
+		return nil, fmt.Errorf("error get smeshers: %w", err)
 	}
 
 	return smeshers, nil
@@ -65,7 +55,7 @@ func (s *Reader) GetSmesher(ctx context.Context, smesherID string) (*model.Smesh
 	lookupStage := bson.D{
 		{Key: "$lookup",
 			Value: bson.D{
-				{Key: "from", Value: "malfeasance_proofs"},
+				{Key: "from", Value: s.db.CollectionName("malfeasance_proofs")},
 				{Key: "localField", Value: "id"},
 				{Key: "foreignField", Value: "smesher"},
 				{Key: "as", Value: "proofs"},
@@ -91,33 +81,146 @@ func (s *Reader) GetSmesher(ctx context.Context, smesherID string) (*model.Smesh
 	return smesher, nil
 }
 
-// CountSmesherRewards returns the number of smesher rewards matching the query.
+// CountSmesherRewards returns the sum and the number of rewards of the smesher, maintained by the collector.
 func (s *Reader) CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error) {
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "smesher", Value: smesherID}}}}
-	groupStage := bson.D{
-		{Key: "$group", Value: bson.D{
+	var stats struct {
+		Total int64 `bson:"total"`
+		Count int64 `bson:"count"`
+	}
+	err = s.findOne(ctx, "stats_smeshers", bson.D{{Key: "smesher", Value: smesherID}}, &stats)
+	if err == mongo.ErrNoDocuments {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get smesher rewards: %w", err)
+	}
+	return stats.Total, stats.Count, nil
+}
+
+// CountSmesherHistory returns the number of smesher changes matching the query.
+func (s *Reader) CountSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.countDocuments(ctx, "smesher_history", query, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("error count smesher history: %w", err)
+	}
+	return count, nil
+}
+
+// GetSmesherHistory returns the smesher changes matching the query.
+func (s *Reader) GetSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.SmesherChange, error) {
+	var changes []*model.SmesherChange
+	if err := s.findAll(ctx, "smesher_history", query, &changes, opts...); err != nil {
+		return nil, fmt.Errorf("error get smesher history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// GetMalfeasanceProofs returns the malfeasance proofs matching the query.
+func (s *Reader) GetMalfeasanceProofs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.MalfeasanceProof, error) {
+	var proofs []*model.MalfeasanceProof
+	if err := s.findAll(ctx, "malfeasance_proofs", query, &proofs, opts...); err != nil {
+		return nil, fmt.Errorf("error get malfeasance proofs: %w", err)
+	}
+
+	return proofs, nil
+}
+
+// GetSmesherParticipation returns the participation of the smesher in the layers of the epoch, with
+// the totals of all the smeshers. The expected values are left to the caller.
+func (s *Reader) GetSmesherParticipation(ctx context.Context, smesherID string, epoch, layerStart, layerEnd uint32) (*model.SmesherParticipation, error) {
+	own := func(value any) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$smesher", smesherID}}}, value, 0,
+		}}}}}
+	}
+	aggregate := func(collection string, pipeline mongo.Pipeline) (bson.Raw, error) {
+		cursor, err := s.db.Collection(collection).Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		if !cursor.Next(ctx) {
+			return bson.Raw{}, cursor.Err()
+		}
+		return cursor.Current, nil
+	}
+	layers := bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}
+	participation := &model.SmesherParticipation{Epoch: epoch}
+
+	doc, err := aggregate("activations", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: epoch}}}},
+		{{Key: "$group", Value: bson.D{
 			{Key: "_id", Value: ""},
-			{Key: "total", Value: bson.D{
-				{Key: "$sum", Value: "$total"},
-			}},
-			{Key: "layerReward", Value: bson.D{
-				{Key: "$sum", Value: "$layerReward"},
-			}},
-			{Key: "count", Value: bson.D{
-				{Key: "$sum", Value: 1},
-			}},
-		}},
-	}
-	cursor, err := s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
-		matchStage,
-		groupStage,
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$weight"}}},
+			{Key: "weight", Value: own("$weight")},
+		}}},
 	})
 	if err != nil {
-		return 0, 0, fmt.Errorf("error get smesher rewards: %w", err)
+		return nil, fmt.Errorf("error get smesher weight: %w", err)
 	}
-	if !cursor.Next(ctx) {
-		return 0, 0, nil
+	participation.Weight = utils.GetAsInt64(doc.Lookup("weight"))
+	participation.TotalWeight = utils.GetAsInt64(doc.Lookup("total"))
+
+	doc, err = aggregate("rewards", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "layer", Value: layers}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "rewards", Value: own("$total")},
+			{Key: "ballots", Value: own(1)},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher rewards: %w", err)
+	}
+	participation.Rewards = utils.GetAsInt64(doc.Lookup("rewards"))
+	participation.Ballots = utils.GetAsInt64(doc.Lookup("ballots"))
+	participation.TotalRewards = utils.GetAsInt64(doc.Lookup("total"))
+	participation.TotalBallots = utils.GetAsInt64(doc.Lookup("count"))
+
+	doc, err = aggregate("certificates", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "layer", Value: layers}, {Key: "valid", Value: true}}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "eligibilities", Value: 1},
+			{Key: "signers", Value: bson.D{{Key: "$filter", Value: bson.D{
+				{Key: "input", Value: "$signers"},
+				{Key: "as", Value: "signer"},
+				{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$signer.smesher", smesherID}}}},
+			}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "certificates", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "total", Value: bson.D{{Key: "$sum", Value: "$eligibilities"}}},
+			{Key: "signed", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$size", Value: "$signers"}}}}},
+			{Key: "eligibilities", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$sum", Value: "$signers.eligibilities"}}}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher certificates: %w", err)
 	}
-	doc := cursor.Current
-	return utils.GetAsInt64(doc.Lookup("total")), utils.GetAsInt64(doc.Lookup("count")), nil
+	participation.Certificates = utils.GetAsInt64(doc.Lookup("certificates"))
+	participation.Signed = utils.GetAsInt64(doc.Lookup("signed"))
+	participation.Eligibilities = utils.GetAsInt64(doc.Lookup("eligibilities"))
+	participation.TotalEligibilities = utils.GetAsInt64(doc.Lookup("total"))
+
+	doc, err = aggregate("ballots", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "smesher", Value: smesherID}, {Key: "layer", Value: layers}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "voted", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "votes", Value: bson.D{{Key: "$sum", Value: "$eligibilities"}}},
+			{Key: "eligibilities", Value: bson.D{{Key: "$sum", Value: "$epochEligibilities"}}},
+		}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher ballots: %w", err)
+	}
+	participation.Voted = utils.GetAsInt64(doc.Lookup("voted"))
+	participation.Votes = utils.GetAsInt64(doc.Lookup("votes"))
+	participation.EpochEligibilities = utils.GetAsInt64(doc.Lookup("eligibilities"))
+
+	return participation, nil
 }
@@ -0,0 +1,280 @@
+package storagereader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// CountDailyTransactions returns the number of days with transactions.
+func (s *Reader) CountDailyTransactions(ctx context.Context) (int64, error) {
+	count, err := s.countDocuments(ctx, "stats_daily_txs", &bson.D{})
+	if err != nil {
+		return 0, fmt.Errorf("error count daily transactions: %w", err)
+	}
+	return count, nil
+}
+
+// GetDailyTransactions returns the number and the amount of transactions by day, maintained by the collector.
+func (s *Reader) GetDailyTransactions(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyTransactions, error) {
+	var days []*model.DailyTransactions
+	if err := s.findAll(ctx, "stats_daily_txs", bson.D{}, &days, opts...); err != nil {
+		return nil, fmt.Errorf("error get daily transactions: %w", err)
+	}
+	return days, nil
+}
+
+// CountDailyAccounts returns the number of days with new accounts.
+func (s *Reader) CountDailyAccounts(ctx context.Context) (int64, error) {
+	count, err := s.countDocuments(ctx, "stats_daily_accounts", &bson.D{})
+	if err != nil {
+		return 0, fmt.Errorf("error count daily accounts: %w", err)
+	}
+	return count, nil
+}
+
+// GetDailyAccounts returns the number of new accounts by day, maintained by the collector.
+func (s *Reader) GetDailyAccounts(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyAccounts, error) {
+	var days []*model.DailyAccounts
+	if err := s.findAll(ctx, "stats_daily_accounts", bson.D{}, &days, opts...); err != nil {
+		return nil, fmt.Errorf("error get daily accounts: %w", err)
+	}
+	return days, nil
+}
+
+// SumDailyAccounts returns the number of new accounts of the days matching the query.
+func (s *Reader) SumDailyAccounts(ctx context.Context, query *bson.D) (int64, error) {
+	cursor, err := s.collection("stats_daily_accounts").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: *query}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: "$count"}}},
+		}}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error sum daily accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return 0, cursor.Err()
+	}
+	return utils.GetAsInt64(cursor.Current.Lookup("count")), nil
+}
+
+// GetTransactionTypesStats returns the number and the amount of transactions by day, epoch and type
+// matching the query, maintained by the collector.
+func (s *Reader) GetTransactionTypesStats(ctx context.Context, query *bson.D) ([]*model.TransactionTypeStats, error) {
+	var stats []*model.TransactionTypeStats
+	if err := s.findAll(ctx, "stats_tx_types", query, &stats); err != nil {
+		return nil, fmt.Errorf("error get transaction types: %w", err)
+	}
+	return stats, nil
+}
+
+// GetBlockStats returns the number, the transactions and the size of the blocks by epoch and
+// bucket matching the query, maintained by the collector.
+func (s *Reader) GetBlockStats(ctx context.Context, query *bson.D) ([]*model.BlockStats, error) {
+	var stats []*model.BlockStats
+	if err := s.findAll(ctx, "stats_blocks", query, &stats); err != nil {
+		return nil, fmt.Errorf("error get blocks stats: %w", err)
+	}
+	return stats, nil
+}
+
+// GetEpochCoinbases returns the effective num units of the activations targeting the epoch and the
+// rewards of its layers, by coinbase.
+func (s *Reader) GetEpochCoinbases(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.CoinbaseShare, error) {
+	cursor, err := s.db.Collection("activations").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: epoch}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$coinbase"},
+			{Key: "smeshers", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "units", Value: bson.D{{Key: "$sum", Value: "$effectiveNumUnits"}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error get coinbases space: %w", err)
+	}
+	defer cursor.Close(ctx)
+	coinbases := make(map[string]*model.CoinbaseShare)
+	for cursor.Next(ctx) {
+		coinbase, _ := cursor.Current.Lookup("_id").StringValueOK()
+		coinbases[coinbase] = &model.CoinbaseShare{
+			Coinbase: coinbase,
+			Smeshers: utils.GetAsInt64(cursor.Current.Lookup("smeshers")),
+			Units:    utils.GetAsInt64(cursor.Current.Lookup("units")),
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error get coinbases space: %w", err)
+	}
+
+	cursor, err = s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$coinbase"},
+			{Key: "rewards", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error get coinbases rewards: %w", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		coinbase, _ := cursor.Current.Lookup("_id").StringValueOK()
+		if coinbases[coinbase] == nil {
+			coinbases[coinbase] = &model.CoinbaseShare{Coinbase: coinbase}
+		}
+		coinbases[coinbase].Rewards = utils.GetAsInt64(cursor.Current.Lookup("rewards"))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error get coinbases rewards: %w", err)
+	}
+
+	shares := make([]*model.CoinbaseShare, 0, len(coinbases))
+	for _, share := range coinbases {
+		shares = append(shares, share)
+	}
+	return shares, nil
+}
+
+// GetEpochUnits returns the number of activations targeting the epoch by number of units.
+func (s *Reader) GetEpochUnits(ctx context.Context, epoch uint32) ([]*model.UnitsCount, error) {
+	cursor, err := s.db.Collection("activations").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: epoch}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$numunits"},
+			{Key: "activations", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "numunits", Value: "$_id"},
+			{Key: "activations", Value: 1},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch units: %w", err)
+	}
+	var units []*model.UnitsCount
+	if err = cursor.All(ctx, &units); err != nil {
+		return nil, fmt.Errorf("error decode epoch units: %w", err)
+	}
+	return units, nil
+}
+
+// GetRollups returns the rollups of the period starting in [from, to], maintained by the collector.
+func (s *Reader) GetRollups(ctx context.Context, period string, from, to uint32) ([]*model.Rollup, error) {
+	collection := "stats_rollup_daily"
+	if period == model.PeriodWeek {
+		collection = "stats_rollup_weekly"
+	}
+	var rollups []*model.Rollup
+	err := s.findAll(ctx, collection,
+		bson.D{{Key: "start", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}}}, &rollups,
+		options.Find().SetSort(bson.D{{Key: "start", Value: 1}}).SetProjection(bson.D{{Key: "_id", Value: 0}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups: %w", err)
+	}
+	return rollups, nil
+}
+
+// GetBalanceCohorts returns the cohorts of the balances of the epochs up to the last one, from the
+// balance changes read in the order of their index.
+func (s *Reader) GetBalanceCohorts(ctx context.Context, epochNumLayers, lastEpoch uint32) ([]*model.BalanceCohorts, error) {
+	cursor, err := s.db.Collection("balance_changes").Find(ctx, bson.D{}, options.Find().
+		SetSort(bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: -1}}).
+		SetProjection(bson.D{{Key: "_id", Value: 0}, {Key: "address", Value: 1}, {Key: "layer", Value: 1}, {Key: "balance", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get balance changes: %w", err)
+	}
+	defer cursor.Close(ctx)
+	builder := model.NewBalanceCohortsBuilder(epochNumLayers, lastEpoch)
+	for cursor.Next(ctx) {
+		var change model.BalanceChange
+		if err := cursor.Decode(&change); err != nil {
+			return nil, fmt.Errorf("error decode balance change: %w", err)
+		}
+		builder.Add(&change)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error get balance changes: %w", err)
+	}
+	return builder.Cohorts(), nil
+}
+
+// GetEpochSmesherRewards returns the rewards of the smeshers in the layers [layerStart, layerEnd],
+// with the commitments of their activations targeting the epoch.
+func (s *Reader) GetEpochSmesherRewards(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.SmesherEpochRewards, error) {
+	cursor, err := s.db.Collection("activations").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: epoch}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "numunits", Value: bson.D{{Key: "$sum", Value: "$numunits"}}},
+			{Key: "weight", Value: bson.D{{Key: "$sum", Value: "$weight"}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers weight: %w", err)
+	}
+	defer cursor.Close(ctx)
+	smeshers := make(map[string]*model.SmesherEpochRewards)
+	for cursor.Next(ctx) {
+		smesher, _ := cursor.Current.Lookup("_id").StringValueOK()
+		smeshers[smesher] = &model.SmesherEpochRewards{
+			Smesher:  smesher,
+			NumUnits: uint32(utils.GetAsInt64(cursor.Current.Lookup("numunits"))),
+			Weight:   utils.GetAsInt64(cursor.Current.Lookup("weight")),
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error get smeshers weight: %w", err)
+	}
+
+	cursor, err = s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "rewards", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers rewards: %w", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		smesher, _ := cursor.Current.Lookup("_id").StringValueOK()
+		if smeshers[smesher] == nil {
+			smeshers[smesher] = &model.SmesherEpochRewards{Smesher: smesher}
+		}
+		smeshers[smesher].Rewards = utils.GetAsInt64(cursor.Current.Lookup("rewards"))
+		smeshers[smesher].Count = utils.GetAsInt64(cursor.Current.Lookup("count"))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("error get smeshers rewards: %w", err)
+	}
+
+	rewards := make([]*model.SmesherEpochRewards, 0, len(smeshers))
+	for _, smesher := range smeshers {
+		rewards = append(rewards, smesher)
+	}
+	return rewards, nil
+}
+
+// GetTotals returns the global totals, maintained by the collector. The totals are empty until the
+// collector stores data.
+func (s *Reader) GetTotals(ctx context.Context) (*model.Totals, error) {
+	totals := &model.Totals{}
+	err := s.findOne(ctx, "stats_totals", bson.D{{Key: "id", Value: "totals"}}, totals)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("error get totals: %w", err)
+	}
+	return totals, nil
+}
@@ -9,21 +9,35 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
 )
 
 // Reader is a wrapper around a mongo client. This client is read-only.
 type Reader struct {
 	client *mongo.Client
-	db     *mongo.Database
+	db     *storage.Database
+	// statsDB holds the stats collections, it is db unless set by OpenStatsDatabase.
+	statsDB *storage.Database
+	// retryPolicy bounds the retries of the counts failing with a transient error.
+	retryPolicy storage.RetryPolicy
+	// tiering is set if the collector moves the old documents to the archive collections, see
+	// SetTiering.
+	tiering bool
 }
 
-// NewStorageReader creates a new storage reader.
-func NewStorageReader(ctx context.Context, dbURL string, dbName string) (*Reader, error) {
+// NewStorageReader creates a new storage reader. The options override the ones of the url, e.g.
+// the read preference. The collection names start with the prefix, see storage.Database.
+func NewStorageReader(ctx context.Context, dbURL string, dbName string, prefix string, opts ...*options.ClientOptions) (*Reader, error) {
+	if err := storage.ValidatePrefix(prefix); err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dbURL))
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{options.Client().ApplyURI(dbURL).SetMonitor(storage.NewCommandMonitor())}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("error connect to db: %s", err)
 	}
@@ -32,26 +46,149 @@ func NewStorageReader(ctx context.Context, dbURL string, dbName string) (*Reader
 		return nil, fmt.Errorf("error ping to db: %s", err)
 	}
 	reader := &Reader{
-		client: client,
-		db:     client.Database(dbName),
+		client:      client,
+		db:          storage.NewDatabase(client, dbName, prefix),
+		retryPolicy: storage.DefaultRetryPolicy,
 	}
+	reader.statsDB = reader.db
+	reader.auditIndexes(ctx)
 	return reader, nil
 }
 
+// OpenStatsDatabase reads the stats collections from the database `name` of the deployment at url,
+// see storage.Storage.OpenStatsDatabase. An empty url keeps the deployment of the raw data, an
+// empty name its database name. The collections keep the prefix of the raw data.
+func (s *Reader) OpenStatsDatabase(ctx context.Context, url, name string, opts ...*options.ClientOptions) error {
+	if name == "" {
+		name = s.db.Name()
+	}
+	if url == "" {
+		s.statsDB = storage.NewDatabase(s.client, name, s.db.Prefix())
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{options.Client().ApplyURI(url).SetMonitor(storage.NewCommandMonitor())}, opts...)...)
+	if err != nil {
+		return fmt.Errorf("error connect to stats db: %s", err)
+	}
+	if err = client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("error ping to stats db: %s", err)
+	}
+	s.statsDB = storage.NewDatabase(client, name, s.db.Prefix())
+	return nil
+}
+
+// SetTiering sets whether the collector moves the old layers, transactions and blocks to their
+// archive collections, see storage.Storage.SetTiering. The deep pages of their lists are then
+// continued in the archive, otherwise the archive is read in the same query as the hot documents.
+func (s *Reader) SetTiering(enabled bool) {
+	s.tiering = enabled
+}
+
+// collection returns the collection from the database holding it.
+func (s *Reader) collection(name string) *mongo.Collection {
+	if storage.IsStatsCollection(name) {
+		return s.statsDB.Collection(name)
+	}
+	return s.db.Collection(name)
+}
+
+// auditIndexes logs the API queries which would run without an index, e.g. if the collector did not
+// apply the migrations.
+func (s *Reader) auditIndexes(ctx context.Context) {
+	unindexed, err := storage.UnindexedQueries(ctx, s.db)
+	if err != nil {
+		logging.Error("audit indexes", err)
+		return
+	}
+	for _, q := range unindexed {
+		logging.Warn("query is not indexed", zap.String("query", q.Name), logging.Collection(q.Collection), zap.Any("index", q.Index()))
+	}
+}
+
 // GetNetworkInfo returns the network info matching the query.
 func (s *Reader) GetNetworkInfo(ctx context.Context) (*model.NetworkInfo, error) {
-	cursor, err := s.db.Collection("networkinfo").Find(ctx, bson.D{{Key: "id", Value: 1}})
+	var result model.NetworkInfo
+	err := s.findOne(ctx, "networkinfo", bson.D{{Key: "id", Value: 1}}, &result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("error get network info: %s", errors.New("empty result"))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error get network info: %s", err)
 	}
-	if !cursor.Next(ctx) {
-		return nil, fmt.Errorf("error get network info: %s", errors.New("empty result"))
+	return &result, nil
+}
+
+// countDocuments returns the number of documents of the collection matching the query. Counting a
+// whole collection scans it, so unfiltered counts are read from the collection metadata instead,
+// which can be slightly off after an unclean shutdown or during chunk migrations. The count of a
+// tiered collection includes its archive. The documents which were not orphaned are counted from
+// the metadata too, minus the orphaned counter of the totals.
+func (s *Reader) countDocuments(ctx context.Context, collection string, query *bson.D, opts ...*options.CountOptions) (count int64, err error) {
+	colls := []*mongo.Collection{s.collection(collection)}
+	if archive, ok := storage.TierArchive(collection); ok {
+		colls = append(colls, s.db.Collection(archive))
 	}
-	var result model.NetworkInfo
-	if err = cursor.Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decode network info: %s", err)
+	unfiltered := (query == nil || len(*query) == 0) && len(opts) == 0
+	notOrphaned := storage.OnlyNotOrphaned(query) && len(opts) == 0
+	err = s.retryPolicy.Do(ctx, collection, storage.OperationCount, storage.IsTransient, func(ctx context.Context) error {
+		count = 0
+		for _, coll := range colls {
+			var n int64
+			var err error
+			if unfiltered || notOrphaned {
+				n, err = coll.EstimatedDocumentCount(ctx)
+			} else {
+				n, err = coll.CountDocuments(ctx, query, opts...)
+			}
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		return nil
+	})
+	if err != nil || !notOrphaned {
+		return count, err
 	}
-	return &result, nil
+	totals, err := s.GetTotals(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return max(count-totals.Orphaned[collection], 0), nil
+}
+
+// findAll decodes the documents of the collection matching the query into out, retrying the
+// transient errors.
+func (s *Reader) findAll(ctx context.Context, collection string, query any, out any, opts ...*options.FindOptions) error {
+	return s.retryPolicy.Do(ctx, collection, storage.OperationFind, storage.IsTransient, func(ctx context.Context) error {
+		cursor, err := s.collection(collection).Find(ctx, query, opts...)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, out)
+	})
+}
+
+// findOne decodes the first document of the collection matching the query into out, retrying the
+// transient errors. It returns mongo.ErrNoDocuments if no document matches.
+func (s *Reader) findOne(ctx context.Context, collection string, query any, out any, opts ...*options.FindOneOptions) error {
+	return s.retryPolicy.Do(ctx, collection, storage.OperationFind, storage.IsTransient, func(ctx context.Context) error {
+		return s.collection(collection).FindOne(ctx, query, opts...).Decode(out)
+	})
+}
+
+// aggregateAll decodes the results of the aggregation of the collection into out, retrying the
+// transient errors.
+func (s *Reader) aggregateAll(ctx context.Context, collection string, pipeline any, out any, opts ...*options.AggregateOptions) error {
+	return s.retryPolicy.Do(ctx, collection, storage.OperationAggregate, storage.IsTransient, func(ctx context.Context) error {
+		cursor, err := s.collection(collection).Aggregate(ctx, pipeline, opts...)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, out)
+	})
 }
 
 // Ping checks if the database is reachable.
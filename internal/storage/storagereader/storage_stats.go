@@ -0,0 +1,108 @@
+package storagereader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// GetStorageStats returns the size of every collection, the stats collections included. The oldest
+// and newest timestamps are the creation times of the object ids of the first and the last inserted
+// documents.
+func (s *Reader) GetStorageStats(ctx context.Context) ([]*model.CollectionStats, error) {
+	filter := bson.D{{Key: "type", Value: "collection"}}
+	names, err := s.db.ListCollectionNames(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("error list collections: %w", err)
+	}
+	if s.statsDB != s.db {
+		statsNames, err := s.statsDB.ListCollectionNames(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("error list stats collections: %w", err)
+		}
+		for _, name := range statsNames {
+			if storage.IsStatsCollection(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	stats := make([]*model.CollectionStats, 0, len(names))
+	for i, name := range names {
+		// a stats collection may be listed by both databases
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		collStats, err := collectionStats(ctx, s.collection(name))
+		if err != nil {
+			return nil, fmt.Errorf("error get `%s` stats: %w", name, err)
+		}
+		collStats.Collection = name
+		stats = append(stats, collStats)
+	}
+	return stats, nil
+}
+
+func collectionStats(ctx context.Context, coll *mongo.Collection) (*model.CollectionStats, error) {
+	cursor, err := coll.Aggregate(ctx, bson.A{
+		bson.D{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []struct {
+		StorageStats struct {
+			Count          int64 `bson:"count"`
+			StorageSize    int64 `bson:"storageSize"`
+			TotalIndexSize int64 `bson:"totalIndexSize"`
+		} `bson:"storageStats"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	stats := &model.CollectionStats{}
+	// a sharded collection has a result per shard
+	for _, shard := range result {
+		stats.Documents += shard.StorageStats.Count
+		stats.StorageSize += shard.StorageStats.StorageSize
+		stats.IndexSize += shard.StorageStats.TotalIndexSize
+	}
+	if stats.Oldest, err = insertionTime(ctx, coll, 1); err != nil {
+		return nil, err
+	}
+	if stats.Newest, err = insertionTime(ctx, coll, -1); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// insertionTime returns the creation time of the object id of the first (order 1) or the last
+// (order -1) inserted document, zero if the collection is empty or the ids are not object ids.
+func insertionTime(ctx context.Context, coll *mongo.Collection, order int) (int64, error) {
+	var doc struct {
+		ID any `bson:"_id"`
+	}
+	err := coll.FindOne(ctx, bson.D{}, options.FindOne().
+		SetSort(bson.D{{Key: "_id", Value: order}}).
+		SetProjection(bson.D{{Key: "_id", Value: 1}})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if id, ok := doc.ID.(primitive.ObjectID); ok {
+		return id.Timestamp().Unix(), nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,65 @@
+package storagereader
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+const readerDB = "explorer_reader"
+
+// TestCountDocuments runs against the mongo deployment at EXPLORER_TEST_MONGO_URL.
+func TestCountDocuments(t *testing.T) {
+	url := os.Getenv("EXPLORER_TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("EXPLORER_TEST_MONGO_URL is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(ctx) })
+	db := client.Database(readerDB)
+	require.NoError(t, db.Drop(ctx))
+
+	_, err = db.Collection("txs").InsertMany(ctx, []any{
+		bson.D{{Key: "id", Value: "0x01"}, {Key: "layer", Value: 1}},
+		bson.D{{Key: "id", Value: "0x02"}, {Key: "layer", Value: 1}},
+		bson.D{{Key: "id", Value: "0x03"}, {Key: "layer", Value: 2}},
+		bson.D{{Key: "id", Value: "0x04"}, {Key: "layer", Value: 2}, {Key: "orphaned", Value: true}},
+	})
+	require.NoError(t, err)
+	_, err = db.Collection("stats_totals").InsertOne(ctx, bson.D{
+		{Key: "id", Value: "totals"},
+		{Key: "orphaned", Value: bson.D{{Key: "txs", Value: 1}}},
+	})
+	require.NoError(t, err)
+
+	reader, err := NewStorageReader(ctx, url, readerDB, "")
+	require.NoError(t, err)
+	for _, tc := range []struct {
+		name  string
+		query *bson.D
+		opts  []*options.CountOptions
+		count int64
+	}{
+		{name: "unfiltered", count: 4},
+		{name: "empty query", query: &bson.D{}, count: 4},
+		{name: "not orphaned", query: &bson.D{storage.NotOrphaned}, count: 3},
+		{name: "filtered", query: &bson.D{{Key: "layer", Value: 2}}, count: 2},
+		// the options are only applied by the exact count
+		{name: "limited", opts: []*options.CountOptions{options.Count().SetLimit(2)}, count: 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			count, err := reader.CountTransactions(ctx, tc.query, tc.opts...)
+			require.NoError(t, err)
+			require.Equal(t, tc.count, count)
+		})
+	}
+}
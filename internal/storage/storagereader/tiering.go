@@ -0,0 +1,91 @@
+package storagereader
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// readTiered reads a page of the collection, continued in its archive when the page goes past the
+// hot documents, see storage.Storage.SetTiering. The archived documents are older than the hot
+// ones, so they follow them in the descending layer order of the API lists. fetch reads a page of
+// the given collection, a zero limit reads all the matching documents.
+func readTiered[T any](ctx context.Context, s *Reader, collection string, query *bson.D, skip, limit int64,
+	fetch func(coll *mongo.Collection, skip, limit int64) ([]T, error),
+) ([]T, error) {
+	docs, err := fetch(s.collection(collection), skip, limit)
+	if err != nil {
+		return nil, err
+	}
+	archive, ok := storage.TierArchive(collection)
+	if !ok || (limit > 0 && int64(len(docs)) >= limit) || (limit == 0 && len(docs) > 0) {
+		return docs, nil
+	}
+	var archiveSkip int64
+	if len(docs) == 0 && skip > 0 {
+		filter := bson.D{}
+		if query != nil {
+			filter = *query
+		}
+		hot, err := s.collection(collection).CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		archiveSkip = max(skip-hot, 0)
+	}
+	if limit > 0 {
+		limit -= int64(len(docs))
+	}
+	archived, err := fetch(s.db.Collection(archive), archiveSkip, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(docs, archived...), nil
+}
+
+// findTiered finds the documents of the collection matching the query, continued in its archive,
+// see readTiered.
+func findTiered[T any](ctx context.Context, s *Reader, collection string, query *bson.D, opts ...*options.FindOptions) ([]T, error) {
+	merged := options.MergeFindOptions(opts...)
+	var skip, limit int64
+	if merged.Skip != nil {
+		skip = *merged.Skip
+	}
+	if merged.Limit != nil {
+		limit = *merged.Limit
+	}
+	return readTiered(ctx, s, collection, query, skip, limit, func(coll *mongo.Collection, skip, limit int64) ([]T, error) {
+		page := *merged
+		page.SetSkip(skip)
+		if limit > 0 {
+			page.SetLimit(limit)
+		}
+		cursor, err := coll.Find(ctx, query, &page)
+		if err != nil {
+			return nil, err
+		}
+		var docs []T
+		if err = cursor.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		return docs, nil
+	})
+}
+
+// unionArchive returns the stage adding the archived documents of the collection matching the
+// match stage to an aggregation starting with the match stage. It returns the match stage again,
+// a no-op, if the collection is not tiered.
+func (s *Reader) unionArchive(collection string, match bson.D) bson.D {
+	archive, ok := storage.TierArchive(collection)
+	if !ok {
+		return match
+	}
+	return bson.D{{Key: "$unionWith", Value: bson.D{
+		{Key: "coll", Value: s.db.CollectionName(archive)},
+		{Key: "pipeline", Value: bson.A{match}},
+	}}}
+}
@@ -14,7 +14,7 @@ import (
 
 // CountTransactions returns the number of transactions matching the query.
 func (s *Reader) CountTransactions(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
-	count, err := s.db.Collection("txs").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(ctx, "txs", query, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("error count transactions: %w", err)
 	}
@@ -23,15 +23,10 @@ func (s *Reader) CountTransactions(ctx context.Context, query *bson.D, opts ...*
 
 // GetTransactions returns the transactions matching the query.
 func (s *Reader) GetTransactions(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Transaction, error) {
-	cursor, err := s.db.Collection("txs").Find(ctx, query, opts...)
+	txs, err := findTiered[*model.Transaction](ctx, s, "txs", query, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("error get txs: %w", err)
 	}
-
-	var txs []*model.Transaction
-	if err = cursor.All(ctx, &txs); err != nil {
-		return nil, fmt.Errorf("error decode txs: %w", err)
-	}
 	return txs, nil
 }
 
@@ -53,6 +48,7 @@ func (s *Reader) CountSentTransactions(ctx context.Context, address string) (amo
 	}
 	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
 		matchStage,
+		s.unionArchive("txs", matchStage),
 		groupStage,
 	})
 	if err != nil {
@@ -84,6 +80,7 @@ func (s *Reader) CountReceivedTransactions(ctx context.Context, address string)
 	}
 	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
 		matchStage,
+		s.unionArchive("txs", matchStage),
 		groupStage,
 	})
 	if err != nil {
@@ -113,7 +110,7 @@ func (s *Reader) GetLatestTransaction(ctx context.Context, address string) (*mod
 		}},
 	}
 
-	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{matchStage, s.unionArchive("txs", matchStage), groupStage})
 	if err != nil {
 		return nil, fmt.Errorf("error occured while getting latest reward: %w", err)
 	}
@@ -140,7 +137,7 @@ func (s *Reader) GetFirstSentTransaction(ctx context.Context, address string) (*
 		}},
 	}
 
-	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{matchStage, s.unionArchive("txs", matchStage), groupStage})
 	if err != nil {
 		return nil, fmt.Errorf("error occured while getting latest reward: %w", err)
 	}
@@ -0,0 +1,19 @@
+package storagereader
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetVaults returns the vesting schedules of all the vaults.
+func (s *Reader) GetVaults(ctx context.Context) ([]*model.Vault, error) {
+	var vaults []*model.Vault
+	if err := s.findAll(ctx, "vaults", bson.D{}, &vaults); err != nil {
+		return nil, fmt.Errorf("error get vaults: %w", err)
+	}
+	return vaults, nil
+}
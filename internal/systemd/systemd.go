@@ -0,0 +1,77 @@
+// Package systemd writes the pid file of the collector and notifies systemd of its state with
+// sd_notify, so a unit of Type=notify with WatchdogSec= restarts a hung collector.
+//
+// Outside of systemd, NOTIFY_SOCKET and WATCHDOG_USEC are not set and the notifications do nothing.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// WritePidFile writes the pid of the process to the file at path, it does nothing if the path is
+// empty. The returned function removes the file.
+func WritePidFile(path string) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("error write pid file: %w", err)
+	}
+	return func() {
+		if err := os.Remove(path); err != nil {
+			logging.Error("remove pid file", err)
+		}
+	}, nil
+}
+
+// Ready notifies systemd that the process started.
+func Ready() {
+	notify(daemon.SdNotifyReady)
+}
+
+// Stopping notifies systemd that the process is stopping.
+func Stopping() {
+	notify(daemon.SdNotifyStopping)
+}
+
+// Watchdog pings the watchdog of systemd at half of its timeout until ctx is done, as long as
+// healthy returns true. It returns at once if the watchdog is not enabled for the process.
+func Watchdog(ctx context.Context, healthy func() bool) {
+	timeout, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		logging.Error("systemd watchdog", err)
+		return
+	}
+	if timeout == 0 {
+		return
+	}
+	logging.Info("systemd watchdog enabled", logging.Duration(timeout))
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		if healthy() {
+			notify(daemon.SdNotifyWatchdog)
+		} else {
+			logging.Warn("unhealthy, skip systemd watchdog ping", logging.Duration(timeout))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func notify(state string) {
+	if _, err := daemon.SdNotify(false, state); err != nil {
+		logging.Error("systemd notify", err)
+	}
+}
@@ -0,0 +1,95 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "explorer-collector.pid")
+	remove, err := WritePidFile(path)
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(content))
+	remove()
+	require.NoFileExists(t, path)
+
+	remove, err = WritePidFile("")
+	require.NoError(t, err)
+	remove()
+
+	_, err = WritePidFile(filepath.Join(t.TempDir(), "missing", "explorer-collector.pid"))
+	require.ErrorContains(t, err, "error write pid file")
+}
+
+// listen returns the socket of the notifications, set as NOTIFY_SOCKET.
+func listen(t *testing.T) *net.UnixConn {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	conn := listen(t)
+	Ready()
+	require.Equal(t, "READY=1", receive(t, conn))
+	Stopping()
+	require.Equal(t, "STOPPING=1", receive(t, conn))
+}
+
+func TestWatchdog(t *testing.T) {
+	conn := listen(t)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	healthy := make(chan bool, 1)
+	healthy <- true
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Watchdog(ctx, func() bool {
+			select {
+			case h := <-healthy:
+				return h
+			default:
+				return false
+			}
+		})
+	}()
+	require.Equal(t, "WATCHDOG=1", receive(t, conn))
+
+	// no ping while unhealthy
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err := conn.Read(make([]byte, 64))
+	require.Error(t, err)
+
+	healthy <- true
+	require.Equal(t, "WATCHDOG=1", receive(t, conn))
+	cancel()
+	<-done
+
+	// the watchdog is not enabled for another pid
+	t.Setenv("WATCHDOG_PID", "1")
+	Watchdog(context.Background(), func() bool { return true })
+}
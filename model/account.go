@@ -11,6 +11,10 @@ type Account struct {
 	Balance uint64 `json:"balance" bson:"balance"` // known account balance
 	Counter uint64 `json:"counter" bson:"counter"`
 	Created uint64 `json:"created" bson:"created"`
+	// Template is the name of the template the account was spawned with, empty until it is spawned.
+	Template string `json:"template,omitempty" bson:"template,omitempty"`
+	// Version is incremented by every balance write, the balance refreshes compare and set it.
+	Version uint64 `json:"-" bson:"version"`
 	// get from ledger collection
 	Sent         uint64 `json:"sent" bson:"-"`
 	Received     uint64 `json:"received" bson:"-"`
@@ -32,8 +36,11 @@ type AccountSummary struct {
 type AccountService interface {
 	GetAccount(ctx context.Context, accountID string) (*Account, error)
 	GetAccounts(ctx context.Context, page, perPage int64) ([]*Account, int64, error)
+	GetTemplateAccounts(ctx context.Context, template string, page, perPage int64) ([]*Account, int64, error)
 	GetAccountTransactions(ctx context.Context, accountID string, page, perPage int64) ([]*Transaction, int64, error)
 	GetAccountRewards(ctx context.Context, accountID string, page, perPage int64) ([]*Reward, int64, error)
+	GetAccountBalanceAt(ctx context.Context, accountID string, layer uint32) (*AccountSnapshot, error)
+	GetAccountSmeshers(ctx context.Context, accountID string, page, perPage int64) ([]*SmesherCoinbase, int64, error)
 }
 
 func NewAccount(in *pb.Account) *Account {
@@ -20,11 +20,18 @@ type Activation struct {
 	Weight            uint64 `json:"weight" bson:"weight"`
 	EffectiveNumUnits uint32 `json:"effectiveNumUnits" bson:"effectiveNumUnits"`
 	Received          int64  `json:"received" bson:"received"`
+	// SmesherName is the current label of the smesher, kept up to date when the label changes.
+	SmesherName string `json:"smesherName,omitempty" bson:"smesherName,omitempty"`
+	// Rewards and RewardsCount are the sum and the number of the rewards earned by the activation,
+	// computed by the API.
+	Rewards      int64 `json:"rewards,omitempty" bson:"-"`
+	RewardsCount int64 `json:"rewardsCount,omitempty" bson:"-"`
 }
 
 type ActivationService interface {
 	GetActivations(ctx context.Context, page, perPage int64) (atxs []*Activation, total int64, err error)
 	GetActivation(ctx context.Context, activationID string) (*Activation, error)
+	GetActivationRewards(ctx context.Context, activationID string, page, perPage int64) ([]*Reward, int64, error)
 }
 
 func NewActivation(atx *types.VerifiedActivationTx) *Activation {
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"math"
+	"slices"
+)
+
+// BalanceChange is the change of the balance of an account in a layer. Layers in which the
+// balance of the account did not change have no balance change.
+type BalanceChange struct {
+	Address string `json:"address" bson:"address"`
+	Layer   uint32 `json:"layer" bson:"layer"`
+	Delta   int64  `json:"delta" bson:"delta"`     // difference with the balance of the previous change
+	Balance uint64 `json:"balance" bson:"balance"` // balance of the account after the layer
+}
+
+// NewBalanceChange returns the change from previous to balance, or nil if the balance did not change.
+func NewBalanceChange(address string, layer uint32, previous, balance uint64) *BalanceChange {
+	if previous == balance {
+		return nil
+	}
+	return &BalanceChange{
+		Address: address,
+		Layer:   layer,
+		Delta:   int64(balance) - int64(previous),
+		Balance: balance,
+	}
+}
+
+// AccountSnapshot is the balance of an account after a layer. The snapshots of all the accounts are
+// taken every few layers, so that the balance at a layer is the balance of the last snapshot before
+// it, unless a balance change follows the snapshot.
+type AccountSnapshot struct {
+	Address string `json:"address" bson:"address"`
+	Layer   uint32 `json:"layer" bson:"layer"`
+	Balance uint64 `json:"balance" bson:"balance"`
+}
+
+// BalanceAt returns the balance of the account after the layer from its last snapshot and its last
+// balance change before the layer, either of which may be nil.
+func BalanceAt(address string, layer uint32, snapshot *AccountSnapshot, change *BalanceChange) *AccountSnapshot {
+	balance := &AccountSnapshot{Address: address, Layer: layer}
+	switch {
+	case change != nil && (snapshot == nil || change.Layer >= snapshot.Layer):
+		balance.Balance = change.Balance
+	case snapshot != nil:
+		balance.Balance = snapshot.Balance
+	}
+	return balance
+}
+
+// CohortBounds are the lower bounds of the ages of the cohorts of the balances, in epochs since the
+// last change of the balance. The last cohort has no upper bound.
+var CohortBounds = []uint32{0, 1, 10, 50, 100}
+
+// BalanceCohort is the balance of the accounts whose balance last changed between From and To
+// epochs before the end of an epoch.
+type BalanceCohort struct {
+	From     uint32 `json:"from"`
+	To       uint32 `json:"to,omitempty"` // excluded, 0 for the last cohort
+	Accounts int64  `json:"accounts"`     // accounts with a balance
+	Balance  uint64 `json:"balance"`
+	Share    int64  `json:"share"` // part of the balance of all the accounts, in basis points
+}
+
+// BalanceCohorts split the balances of the accounts at the end of an epoch by the age of their
+// last change, see CohortBounds. The age of a balance is the age of the account's last change, as
+// the coins of an account are not told apart.
+type BalanceCohorts struct {
+	Epoch   uint32           `json:"epoch"`
+	Balance uint64           `json:"balance"`
+	Cohorts []*BalanceCohort `json:"cohorts"`
+}
+
+// BalanceCohortsBuilder computes the cohorts of the epochs up to the last one from the balance
+// changes, which must be added grouped by account.
+type BalanceCohortsBuilder struct {
+	epochNumLayers uint32
+	lastEpoch      uint32
+	// accounts and balances are the differences between the values of an epoch and of the
+	// previous one, by cohort.
+	accounts [][]int64
+	balances [][]int64
+	changes  []*BalanceChange
+}
+
+// NewBalanceCohortsBuilder returns a builder of the cohorts of the epochs up to the last one.
+func NewBalanceCohortsBuilder(epochNumLayers, lastEpoch uint32) *BalanceCohortsBuilder {
+	b := &BalanceCohortsBuilder{epochNumLayers: epochNumLayers, lastEpoch: lastEpoch}
+	for range CohortBounds {
+		b.accounts = append(b.accounts, make([]int64, lastEpoch+2))
+		b.balances = append(b.balances, make([]int64, lastEpoch+2))
+	}
+	return b
+}
+
+// Add accounts the balance change, after the changes of the previous accounts.
+func (b *BalanceCohortsBuilder) Add(change *BalanceChange) {
+	if len(b.changes) > 0 && b.changes[0].Address != change.Address {
+		b.flush()
+	}
+	b.changes = append(b.changes, change)
+}
+
+// flush accounts the balances of the changes of an account in the epochs they were the last
+// change at the end of.
+func (b *BalanceCohortsBuilder) flush() {
+	slices.SortFunc(b.changes, func(x, y *BalanceChange) int {
+		return int(int64(x.Layer) - int64(y.Layer))
+	})
+	for i, change := range b.changes {
+		epoch := change.Layer / b.epochNumLayers
+		next := b.lastEpoch + 1
+		if i+1 < len(b.changes) {
+			next = min(next, b.changes[i+1].Layer/b.epochNumLayers)
+		}
+		if change.Balance == 0 || epoch >= next {
+			continue
+		}
+		for j, from := range CohortBounds {
+			to := next
+			if j+1 < len(CohortBounds) {
+				to = min(to, epoch+CohortBounds[j+1])
+			}
+			if start := epoch + from; start < to {
+				b.accounts[j][start]++
+				b.accounts[j][to]--
+				b.balances[j][start] += int64(change.Balance)
+				b.balances[j][to] -= int64(change.Balance)
+			}
+		}
+	}
+	b.changes = b.changes[:0]
+}
+
+// Cohorts returns the cohorts of the epochs, from the first one.
+func (b *BalanceCohortsBuilder) Cohorts() []*BalanceCohorts {
+	b.flush()
+	series := make([]*BalanceCohorts, 0, b.lastEpoch+1)
+	accounts := make([]int64, len(CohortBounds))
+	balances := make([]int64, len(CohortBounds))
+	for epoch := uint32(0); epoch <= b.lastEpoch; epoch++ {
+		cohorts := &BalanceCohorts{Epoch: epoch}
+		for j, from := range CohortBounds {
+			accounts[j] += b.accounts[j][epoch]
+			balances[j] += b.balances[j][epoch]
+			cohort := &BalanceCohort{From: from, Accounts: accounts[j], Balance: uint64(balances[j])}
+			if j+1 < len(CohortBounds) {
+				cohort.To = CohortBounds[j+1]
+			}
+			cohorts.Balance += cohort.Balance
+			cohorts.Cohorts = append(cohorts.Cohorts, cohort)
+		}
+		for _, cohort := range cohorts.Cohorts {
+			if cohorts.Balance > 0 {
+				cohort.Share = int64(math.Round(1e4 * float64(cohort.Balance) / float64(cohorts.Balance)))
+			}
+		}
+		series = append(series, cohorts)
+	}
+	return series
+}
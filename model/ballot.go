@@ -0,0 +1,100 @@
+package model
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// Ballot is the vote of a smesher in a layer, cast with the eligibilities of the smesher in the
+// layer.
+type Ballot struct {
+	Id      string `json:"id" bson:"id"` // nolint will fix it later
+	Layer   uint32 `json:"layer" bson:"layer"`
+	Epoch   uint32 `json:"epoch" bson:"epoch"`
+	Smesher string `json:"smesher" bson:"smesher"`
+	AtxId   string `json:"atxId" bson:"atxId"` //nolint will fix it later
+	// Eligibilities are the eligibilities of the smesher in the layer the ballot votes with.
+	Eligibilities uint32 `json:"eligibilities" bson:"eligibilities"`
+	// EpochEligibilities are the eligibilities of the smesher in the whole epoch, declared by its
+	// first ballot of the epoch only.
+	EpochEligibilities uint32 `json:"epochEligibilities,omitempty" bson:"epochEligibilities,omitempty"`
+	Malicious          bool   `json:"malicious" bson:"malicious"`
+}
+
+func NewBallot(b *types.Ballot, epochNumLayers uint32) *Ballot {
+	ballot := &Ballot{
+		Id:            utils.NBytesToHex(b.ID().Bytes(), 20),
+		Layer:         b.Layer.Uint32(),
+		Smesher:       utils.BytesToHex(b.SmesherID.Bytes()),
+		AtxId:         utils.BytesToHex(b.AtxID.Bytes()),
+		Eligibilities: uint32(len(b.EligibilityProofs)),
+		Malicious:     b.IsMalicious(),
+	}
+	if epochNumLayers > 0 {
+		ballot.Epoch = ballot.Layer / epochNumLayers
+	}
+	if b.EpochData != nil {
+		ballot.EpochEligibilities = b.EpochData.EligibilityCount
+	}
+	return ballot
+}
+
+// BallotStats are the participation of the smeshers in the votes of a range of layers.
+type BallotStats struct {
+	Voters  int64 // smeshers with a ballot
+	Ballots int64
+	// Votes are the eligibilities the ballots vote with, and Eligibilities the eligibilities of the
+	// voters in their epochs.
+	Votes         int64
+	Eligibilities int64
+}
+
+// NewBallotStats returns the stats of the ballots, given in any order.
+func NewBallotStats(ballots []*Ballot) BallotStats {
+	var stats BallotStats
+	voters := make(map[string]struct{})
+	for _, ballot := range ballots {
+		voters[ballot.Smesher] = struct{}{}
+		stats.Ballots++
+		stats.Votes += int64(ballot.Eligibilities)
+		stats.Eligibilities += int64(ballot.EpochEligibilities)
+	}
+	stats.Voters = int64(len(voters))
+	return stats
+}
+
+// BallotParticipation is the participation of the smeshers of an epoch in the votes of its layers,
+// see Statistics. The rates are in basis points.
+type BallotParticipation struct {
+	Epoch    int32 `json:"epoch"`
+	Eligible int64 `json:"eligible"` // smeshers with an activation targeting the epoch
+	Voters   int64 `json:"voters"`   // smeshers with a ballot in the layers of the epoch
+	Rate     int64 `json:"rate"`     // voters over eligible smeshers
+	Ballots  int64 `json:"ballots"`
+	// Votes are the eligibilities used by the ballots, over the eligibilities of the voters in the
+	// epoch for VoteRate.
+	Eligibilities int64 `json:"eligibilities"`
+	Votes         int64 `json:"votes"`
+	VoteRate      int64 `json:"voteRate"`
+}
+
+// NewBallotParticipation returns the ballot participation of the epoch from its stats.
+func NewBallotParticipation(epoch *Epoch) *BallotParticipation {
+	stats := epoch.Stats.Current
+	participation := &BallotParticipation{
+		Epoch:         epoch.Number,
+		Eligible:      stats.Smeshers,
+		Voters:        stats.Voters,
+		Ballots:       stats.Ballots,
+		Eligibilities: stats.BallotEligibilities,
+		Votes:         stats.Votes,
+	}
+	if stats.Smeshers > 0 {
+		participation.Rate = basisPoints(stats.Voters, stats.Smeshers)
+	}
+	if stats.BallotEligibilities > 0 {
+		participation.VoteRate = basisPoints(stats.Votes, stats.BallotEligibilities)
+	}
+	return participation
+}
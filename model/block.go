@@ -12,6 +12,14 @@ type Block struct {
 	End       uint32 `json:"end" bson:"end"`
 	TxsNumber uint32 `json:"txsnumber" bson:"txsnumber"`
 	TxsValue  uint64 `json:"txsvalue" bson:"txsvalue"`
+	Size      uint64 `json:"size" bson:"size"` // raw size of the transactions of the block, in bytes
+
+	// Orphaned blocks were removed from their layer by a reorg, SupersededBy is the hash of the
+	// layer which replaced them.
+	Orphaned     bool   `json:"orphaned,omitempty" bson:"orphaned,omitempty"`
+	SupersededBy string `json:"supersededBy,omitempty" bson:"supersededBy,omitempty"`
+
+	Certificate *BlockCertificate `json:"certificate,omitempty" bson:"-"`
 }
 
 type BlockService interface {
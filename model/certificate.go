@@ -0,0 +1,43 @@
+package model
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
+
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// BlockCertificate is the hare output certificate of a layer: the block agreed on
+// and the identities which signed off on it.
+type BlockCertificate struct {
+	Layer         uint32              `json:"layer" bson:"layer"`
+	BlockId       string              `json:"blockId" bson:"blockId"` //nolint will fix it later
+	Valid         bool                `json:"valid" bson:"valid"`
+	Eligibilities uint32              `json:"eligibilities" bson:"eligibilities"` // total eligibility count of all signers
+	Signers       []CertificateSigner `json:"signers" bson:"signers"`
+}
+
+type CertificateSigner struct {
+	Smesher       string `json:"smesher" bson:"smesher"`
+	Eligibilities uint16 `json:"eligibilities" bson:"eligibilities"`
+}
+
+func NewBlockCertificate(layer types.LayerID, in certificates.CertValidity) *BlockCertificate {
+	cert := &BlockCertificate{
+		Layer:   layer.Uint32(),
+		BlockId: utils.NBytesToHex(in.Block.Bytes(), 20),
+		Valid:   in.Valid,
+		Signers: []CertificateSigner{},
+	}
+	if in.Cert == nil {
+		return cert
+	}
+	for _, sig := range in.Cert.Signatures {
+		cert.Signers = append(cert.Signers, CertificateSigner{
+			Smesher:       utils.BytesToHex(sig.SmesherID.Bytes()),
+			Eligibilities: sig.EligibilityCnt,
+		})
+		cert.Eligibilities += uint32(sig.EligibilityCnt)
+	}
+	return cert
+}
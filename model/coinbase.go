@@ -0,0 +1,15 @@
+package model
+
+// SmesherCoinbase is a coinbase used by a smesher, with the target epochs of the activations
+// published with it.
+type SmesherCoinbase struct {
+	Smesher  string   `json:"smesher" bson:"smesherId"`
+	Coinbase string   `json:"coinbase" bson:"coinbase"`
+	From     uint32   `json:"from" bson:"from"` // first epoch with the coinbase
+	To       uint32   `json:"to" bson:"to"`     // last epoch with the coinbase
+	Epochs   []uint32 `json:"epochs" bson:"epochs"`
+	// TotalRewards and RewardsCount are the sum and the number of the rewards of the smesher paid
+	// to the coinbase.
+	TotalRewards int64 `json:"totalRewards" bson:"totalRewards"`
+	RewardsCount int64 `json:"rewardsCount" bson:"rewardsCount"`
+}
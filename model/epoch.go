@@ -5,16 +5,46 @@ import (
 )
 
 type Statistics struct {
-	Capacity      int64 `json:"capacity" bson:"capacity"`         // Average tx/s rate over capacity considering all layers in the current epoch.
-	Decentral     int64 `json:"decentral" bson:"decentral"`       // Distribution of storage between all active smeshers.
-	Smeshers      int64 `json:"smeshers" bson:"smeshers"`         // Number of active smeshers in the current epoch.
-	Transactions  int64 `json:"transactions" bson:"transactions"` // Total number of transactions processed by the state transition function.
-	Accounts      int64 `json:"accounts" bson:"accounts"`         // Total number of on-mesh accounts with a non-zero coin balance as of the current epoch.
-	Circulation   int64 `json:"circulation" bson:"circulation"`   // Total number of Smesh coins in circulation. This is the total balances of all on-mesh accounts.
-	Rewards       int64 `json:"rewards" bson:"rewards"`           // Total amount of Smesh minted as mining rewards as of the last known reward distribution event.
-	RewardsNumber int64 `json:"rewardsnumber" bson:"rewardsnumber"`
-	Security      int64 `json:"security" bson:"security"`   // Total amount of storage committed to the network based on the ATXs in the previous epoch.
-	TxsAmount     int64 `json:"txsamount" bson:"txsamount"` // Total amount of coin transferred between accounts in the epoch. Incl coin transactions and smart wallet transactions.
+	Capacity            int64 `json:"capacity" bson:"capacity"`         // Average tx/s rate over capacity considering all layers in the current epoch.
+	Decentral           int64 `json:"decentral" bson:"decentral"`       // Distribution of storage between all active smeshers.
+	Smeshers            int64 `json:"smeshers" bson:"smeshers"`         // Number of active smeshers in the current epoch.
+	Transactions        int64 `json:"transactions" bson:"transactions"` // Total number of transactions processed by the state transition function.
+	Accounts            int64 `json:"accounts" bson:"accounts"`         // Total number of on-mesh accounts with a non-zero coin balance as of the current epoch.
+	Circulation         int64 `json:"circulation" bson:"circulation"`   // Total number of Smesh coins in circulation. This is the total balances of all on-mesh accounts.
+	Rewards             int64 `json:"rewards" bson:"rewards"`           // Total amount of Smesh minted as mining rewards as of the last known reward distribution event.
+	RewardsNumber       int64 `json:"rewardsnumber" bson:"rewardsnumber"`
+	Security            int64 `json:"security" bson:"security"`                       // Total amount of storage committed to the network based on the ATXs in the previous epoch.
+	TxsAmount           int64 `json:"txsamount" bson:"txsamount"`                     // Total amount of coin transferred between accounts in the epoch. Incl coin transactions and smart wallet transactions.
+	FeesDistributed     int64 `json:"feesdistributed" bson:"feesdistributed"`         // Transaction fees paid to smeshers as part of their rewards.
+	FeesBurned          int64 `json:"feesburned" bson:"feesburned"`                   // Transaction fees removed from the supply.
+	SpaceGini           int64 `json:"spacegini" bson:"spacegini"`                     // Gini coefficient of the storage committed by the smeshers, in basis points.
+	SpaceNakamoto       int64 `json:"spacenakamoto" bson:"spacenakamoto"`             // Least number of smeshers committing more than half of the storage.
+	RewardsGini         int64 `json:"rewardsgini" bson:"rewardsgini"`                 // Gini coefficient of the rewards of the smeshers in the epoch, in basis points.
+	RewardsNakamoto     int64 `json:"rewardsnakamoto" bson:"rewardsnakamoto"`         // Least number of smeshers earning more than half of the rewards in the epoch.
+	NewSmeshers         int64 `json:"newsmeshers" bson:"newsmeshers"`                 // Number of smeshers with their first activation in the epoch.
+	ReturningSmeshers   int64 `json:"returningsmeshers" bson:"returningsmeshers"`     // Number of smeshers active again after missing the previous epoch.
+	StoppedSmeshers     int64 `json:"stoppedsmeshers" bson:"stoppedsmeshers"`         // Number of smeshers of the previous epoch without an activation in the epoch.
+	Space               int64 `json:"space" bson:"space"`                             // Storage committed by the activations targeting the epoch, as their effective num units times the unit size.
+	SpaceDelta          int64 `json:"spacedelta" bson:"spacedelta"`                   // Change of the committed storage since the previous epoch.
+	NewAccounts         int64 `json:"newaccounts" bson:"newaccounts"`                 // Number of accounts first seen in the epoch.
+	FeesCollected       int64 `json:"feescollected" bson:"feescollected"`             // Transaction fees paid by the processed transactions.
+	FeeMin              int64 `json:"feemin" bson:"feemin"`                           // Lowest fee paid by a processed transaction in the epoch.
+	FeeMedian           int64 `json:"feemedian" bson:"feemedian"`                     // Median fee paid by the processed transactions in the epoch.
+	FeeMax              int64 `json:"feemax" bson:"feemax"`                           // Highest fee paid by a processed transaction in the epoch.
+	EmptyLayers         int64 `json:"emptylayers" bson:"emptylayers"`                 // Number of layers of the epoch without a block.
+	LayersWithoutTxs    int64 `json:"layerswithouttxs" bson:"layerswithouttxs"`       // Number of layers of the epoch without a transaction, empty ones included.
+	RewardedSmeshers    int64 `json:"rewardedsmeshers" bson:"rewardedsmeshers"`       // Number of smeshers rewarded in the layers of the epoch.
+	EffectiveNumUnits   int64 `json:"effectivenumunits" bson:"effectivenumunits"`     // Sum of the effective num units of the activations targeting the epoch.
+	Weight              int64 `json:"weight" bson:"weight"`                           // Sum of the weights of the activations targeting the epoch, which the eligibilities are computed from.
+	InclusionTxs        int64 `json:"inclusiontxs" bson:"inclusiontxs"`               // Number of transactions of the epoch first seen by the node before their layer.
+	InclusionMean       int64 `json:"inclusionmean" bson:"inclusionmean"`             // Mean delay in seconds from the first sighting of these transactions to their layer.
+	InclusionMedian     int64 `json:"inclusionmedian" bson:"inclusionmedian"`         // Median delay in seconds from the first sighting of these transactions to their layer.
+	InclusionP90        int64 `json:"inclusionp90" bson:"inclusionp90"`               // 90th percentile of the delays in seconds from the first sighting of these transactions to their layer.
+	InclusionP99        int64 `json:"inclusionp99" bson:"inclusionp99"`               // 99th percentile of the delays in seconds from the first sighting of these transactions to their layer.
+	Voters              int64 `json:"voters" bson:"voters"`                           // Number of smeshers with a ballot in the layers of the epoch.
+	Ballots             int64 `json:"ballots" bson:"ballots"`                         // Number of ballots cast in the layers of the epoch.
+	Votes               int64 `json:"votes" bson:"votes"`                             // Eligibilities the ballots of the epoch vote with.
+	BallotEligibilities int64 `json:"balloteligibilities" bson:"balloteligibilities"` // Eligibilities of the voters in the epoch, declared by their first ballot.
 }
 
 type Stats struct {
@@ -29,7 +59,13 @@ type Epoch struct {
 	LayerStart uint32 `json:"layerstart" bson:"layerstart"`
 	LayerEnd   uint32 `json:"layerend" bson:"layerend"`
 	Layers     uint32 `json:"layers" bson:"layers"`
-	Stats      Stats  `json:"stats"`
+	Stats      Stats  `json:"stats" bson:"stats"`
+	// ActiveSetSize is the number of identities eligible to participate in the epoch.
+	ActiveSetSize uint32 `json:"activeSetSize" bson:"activeSetSize"`
+	// Beacon is the beacon of the epoch in the node, missing if the node had none at its start.
+	Beacon string `json:"beacon,omitempty" bson:"beacon,omitempty"`
+	// StatsVersion is the version of the formulas which computed Stats, see EpochStatsVersion.
+	StatsVersion uint32 `json:"statsVersion" bson:"statsVersion"`
 }
 
 type EpochService interface {
@@ -40,4 +76,7 @@ type EpochService interface {
 	GetEpochSmeshers(ctx context.Context, epochNum int, page, perPage int64) (smeshers []*Smesher, total int64, err error)
 	GetEpochRewards(ctx context.Context, epochNum int, page, perPage int64) (rewards []*Reward, total int64, err error)
 	GetEpochActivations(ctx context.Context, epochNum int, page, perPage int64) (atxs []*Activation, total int64, err error)
+	GetEpochStatsHistory(ctx context.Context, epochNum int, page, perPage int64) (history []*EpochStats, total int64, err error)
+	GetEpochStats(ctx context.Context, epochNum int, version uint32) (*EpochStats, error)
+	CompareEpochs(ctx context.Context, a, b int) (*EpochComparison, error)
 }
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"math"
+)
+
+// EpochDelta is a stat of two epochs side by side and its change from the first to the second.
+type EpochDelta struct {
+	Field  string `json:"field"` // e.g. `current.smeshers`, see StatsChange
+	A      int64  `json:"a"`
+	B      int64  `json:"b"`
+	Delta  int64  `json:"delta"`  // B - A
+	Change int64  `json:"change"` // delta over A, in basis points, 0 when A is 0
+}
+
+// EpochComparison compares the stats of two epochs, for the epoch reports of the community. The
+// stats of the epochs may be computed by different versions of the formulas, see StatsVersions.
+type EpochComparison struct {
+	A             int32         `json:"a"`
+	B             int32         `json:"b"`
+	StatsVersions [2]uint32     `json:"statsVersions"`
+	Deltas        []*EpochDelta `json:"deltas"`
+}
+
+// NewEpochComparison compares the active set size and every current and cumulative stat of the
+// epochs, in the order of the fields of Statistics.
+func NewEpochComparison(a, b *Epoch) *EpochComparison {
+	comparison := &EpochComparison{
+		A:             a.Number,
+		B:             b.Number,
+		StatsVersions: [2]uint32{a.StatsVersion, b.StatsVersion},
+	}
+	add := func(field string, a, b int64) {
+		delta := &EpochDelta{Field: field, A: a, B: b, Delta: b - a}
+		if a != 0 {
+			delta.Change = int64(math.Round(1e4 * float64(delta.Delta) / math.Abs(float64(a))))
+		}
+		comparison.Deltas = append(comparison.Deltas, delta)
+	}
+	add("activeSetSize", int64(a.ActiveSetSize), int64(b.ActiveSetSize))
+	eachStatistic("current", a.Stats.Current, b.Stats.Current, add)
+	eachStatistic("cumulative", a.Stats.Cumulative, b.Stats.Cumulative, add)
+	return comparison
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+)
+
+// EpochStatsVersion is the version of the formulas computing the epoch stats. Bump it when they
+// change, so that the stats of the previous formulas are kept next to the new ones.
+const EpochStatsVersion uint32 = 11
+
+// EpochStats are the stats of an epoch computed by a version of the formulas.
+type EpochStats struct {
+	Epoch    int32  `json:"epoch" bson:"epoch"`
+	Version  uint32 `json:"version" bson:"version"`
+	Computed int64  `json:"computed" bson:"computed"` // unix time of the last computation
+	Stats    Stats  `json:"stats" bson:"stats"`
+}
+
+// StatsChange is a field of the stats which differs between two versions.
+type StatsChange struct {
+	Field string `json:"field"` // e.g. `current.capacity`
+	From  int64  `json:"from"`
+	To    int64  `json:"to"`
+}
+
+// DiffStats returns the fields which differ from `from` to `to`.
+func DiffStats(from, to Stats) []StatsChange {
+	var changes []StatsChange
+	changes = diffStatistics(changes, "current", from.Current, to.Current)
+	return diffStatistics(changes, "cumulative", from.Cumulative, to.Cumulative)
+}
+
+func diffStatistics(changes []StatsChange, prefix string, from, to Statistics) []StatsChange {
+	eachStatistic(prefix, from, to, func(field string, a, b int64) {
+		if a != b {
+			changes = append(changes, StatsChange{Field: field, From: a, To: b})
+		}
+	})
+	return changes
+}
+
+// eachStatistic calls fn with the name of every field of the statistics, prefixed, and its values.
+func eachStatistic(prefix string, from, to Statistics, fn func(field string, a, b int64)) {
+	f, t := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < f.NumField(); i++ {
+		name, _, _ := strings.Cut(f.Type().Field(i).Tag.Get("bson"), ",")
+		fn(prefix+"."+name, f.Field(i).Int(), t.Field(i).Int())
+	}
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+)
+
+// GeoBucket is the located smeshers of a country, or of a region of a country.
+type GeoBucket struct {
+	Country  string `json:"country" bson:"country"`
+	Region   string `json:"region,omitempty" bson:"region,omitempty"`
+	Smeshers int64  `json:"smeshers" bson:"smeshers"`
+	Space    uint64 `json:"space" bson:"space"` // committed space of the smeshers, in bytes
+	Share    int64  `json:"share" bson:"share"` // part of the space of the located smeshers, in basis points
+}
+
+// GeoHeatmap is the committed space of the located smeshers by country and by region, aggregated
+// periodically so that the map is served from a single document.
+type GeoHeatmap struct {
+	Updated  uint32 `json:"updated" bson:"updated"` // unix time of the aggregation
+	Smeshers int64  `json:"smeshers" bson:"smeshers"`
+	Space    uint64 `json:"space" bson:"space"`
+	// Unknown are the located smeshers without a country, counted in the totals only.
+	Unknown   int64        `json:"unknown" bson:"unknown"`
+	Countries []*GeoBucket `json:"countries" bson:"countries"`
+	Regions   []*GeoBucket `json:"regions" bson:"regions"`
+}
+
+// NewGeoHeatmap buckets the located smeshers by the country and the region of their location, the
+// buckets of most space first.
+func NewGeoHeatmap(smeshers []*Smesher, updated uint32) *GeoHeatmap {
+	heatmap := &GeoHeatmap{Updated: updated, Countries: []*GeoBucket{}, Regions: []*GeoBucket{}}
+	countries := make(map[string]*GeoBucket)
+	regions := make(map[[2]string]*GeoBucket)
+	add := func(bucket *GeoBucket, smesher *Smesher) {
+		bucket.Smeshers++
+		bucket.Space += smesher.CommitmentSize
+	}
+	for _, smesher := range smeshers {
+		if smesher.Geo == nil {
+			continue
+		}
+		heatmap.Smeshers++
+		heatmap.Space += smesher.CommitmentSize
+		country := smesher.Geo.Country
+		if country == "" {
+			heatmap.Unknown++
+			continue
+		}
+		bucket, ok := countries[country]
+		if !ok {
+			bucket = &GeoBucket{Country: country}
+			countries[country] = bucket
+			heatmap.Countries = append(heatmap.Countries, bucket)
+		}
+		add(bucket, smesher)
+		if smesher.Geo.Region == "" {
+			continue
+		}
+		key := [2]string{country, smesher.Geo.Region}
+		bucket, ok = regions[key]
+		if !ok {
+			bucket = &GeoBucket{Country: country, Region: smesher.Geo.Region}
+			regions[key] = bucket
+			heatmap.Regions = append(heatmap.Regions, bucket)
+		}
+		add(bucket, smesher)
+	}
+	for _, buckets := range [][]*GeoBucket{heatmap.Countries, heatmap.Regions} {
+		for _, bucket := range buckets {
+			if heatmap.Space > 0 {
+				bucket.Share = int64(math.Round(1e4 * float64(bucket.Space) / float64(heatmap.Space)))
+			}
+		}
+		slices.SortFunc(buckets, func(a, b *GeoBucket) int {
+			if c := cmp.Compare(b.Space, a.Space); c != 0 {
+				return c
+			}
+			if c := strings.Compare(a.Country, b.Country); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Region, b.Region)
+		})
+	}
+	return heatmap
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"math"
+)
+
+// The components of the network health, see NetworkHealth.
+const (
+	// HealthSync is the sync of the node and of the collector, its value is the number of layers
+	// between the current layer of the clock and the last collected layer.
+	HealthSync = "sync"
+	// HealthEmptyLayers is the part of the collected layers of the current epoch without a block,
+	// its value, in basis points.
+	HealthEmptyLayers = "emptyLayers"
+	// HealthParticipation is the part of the smeshers of the last complete epoch rewarded in its
+	// layers, its value, in basis points.
+	HealthParticipation = "participation"
+	// HealthBeacon is the availability of the beacon of the current epoch, its value is 1 if the
+	// node has one.
+	HealthBeacon = "beacon"
+)
+
+// The statuses of the network by its health score.
+const (
+	HealthStatusHealthy   = "healthy"   // score of at least HealthyScore
+	HealthStatusDegraded  = "degraded"  // score of at least DegradedScore
+	HealthStatusUnhealthy = "unhealthy" // lower score
+)
+
+const (
+	HealthyScore  = 9000
+	DegradedScore = 6000
+	// maxSyncLag is the number of layers behind the clock, past the one in progress, at which the
+	// sync score is 0.
+	maxSyncLag = 10
+)
+
+// healthWeights are the weights of the components in the health score, in percent.
+var healthWeights = []struct {
+	name   string
+	weight int64
+}{
+	{HealthSync, 40},
+	{HealthEmptyLayers, 25},
+	{HealthParticipation, 20},
+	{HealthBeacon, 15},
+}
+
+// HealthComponent is a component of the network health.
+type HealthComponent struct {
+	Name   string `json:"name"`
+	Score  int64  `json:"score"`  // in basis points, 10000 is healthy
+	Weight int64  `json:"weight"` // part of the health score, in percent
+	Value  int64  `json:"value"`  // measure the score is derived from, see the components
+}
+
+// NetworkHealth is a composite score of the health of the network, for status pages. The score, in
+// basis points, is the weighted mean of the scores of the components.
+type NetworkHealth struct {
+	Score      int64              `json:"score"`
+	Status     string             `json:"status"`
+	Layer      uint32             `json:"layer"` // current layer of the clock
+	Epoch      int32              `json:"epoch"` // current epoch, -1 before the first one is stored
+	Components []*HealthComponent `json:"components"`
+}
+
+// NewNetworkHealth scores the health of the network at the unix time now from the network info,
+// the current epoch and the previous one, the epochs are nil if they are not stored yet.
+func NewNetworkHealth(info *NetworkInfo, current, previous *Epoch, now uint32) *NetworkHealth {
+	health := &NetworkHealth{Epoch: -1}
+	if info.LayerDuration > 0 && now > info.GenesisTime {
+		health.Layer = (now - info.GenesisTime) / info.LayerDuration
+	}
+	if current != nil {
+		health.Epoch = current.Number
+	}
+
+	components := make(map[string]*HealthComponent, len(healthWeights))
+	for _, w := range healthWeights {
+		component := &HealthComponent{Name: w.name, Weight: w.weight}
+		components[w.name] = component
+		health.Components = append(health.Components, component)
+	}
+
+	sync := components[HealthSync]
+	sync.Value = int64(health.Layer) - int64(info.LastLayer)
+	if info.IsSynced {
+		sync.Score = max(0, 1e4-max(0, sync.Value-1)*1e4/maxSyncLag)
+	}
+
+	empty := components[HealthEmptyLayers]
+	if epoch := current; epoch != nil {
+		if layers := min(info.LastLayer, epoch.LayerEnd) + 1 - min(info.LastLayer+1, epoch.LayerStart); layers > 0 {
+			empty.Value = basisPoints(epoch.Stats.Current.EmptyLayers, int64(layers))
+		}
+	}
+	empty.Score = 1e4 - empty.Value
+
+	participation := components[HealthParticipation]
+	if epoch := previous; epoch != nil && epoch.Stats.Current.Smeshers > 0 {
+		participation.Value = basisPoints(epoch.Stats.Current.RewardedSmeshers, epoch.Stats.Current.Smeshers)
+	}
+	participation.Score = min(1e4, participation.Value)
+
+	beacon := components[HealthBeacon]
+	if current != nil && current.Beacon != "" {
+		beacon.Value = 1
+		beacon.Score = 1e4
+	}
+
+	var score float64
+	for _, component := range health.Components {
+		score += float64(component.Score*component.Weight) / 100
+	}
+	health.Score = int64(math.Round(score))
+	switch {
+	case health.Score >= HealthyScore:
+		health.Status = HealthStatusHealthy
+	case health.Score >= DegradedScore:
+		health.Status = HealthStatusDegraded
+	default:
+		health.Status = HealthStatusUnhealthy
+	}
+	return health
+}
+
+func basisPoints(part, total int64) int64 {
+	return int64(math.Round(1e4 * float64(part) / float64(total)))
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kinds of the labeled entities.
+const (
+	LabelAccount = "account"
+	LabelSmesher = "smesher"
+)
+
+// Label is a display name of an account or a smesher, e.g. an exchange or a pool, searched by
+// the API when the search is not an id.
+type Label struct {
+	Kind string `json:"kind" bson:"kind"`
+	Id   string `json:"id" bson:"id"` //nolint will fix it later
+	Name string `json:"name" bson:"name"`
+}
+
+// Validate checks the label can be stored.
+func (l *Label) Validate() error {
+	if l.Kind != LabelAccount && l.Kind != LabelSmesher {
+		return fmt.Errorf("unknown label kind `%s` of `%s`", l.Kind, l.Id)
+	}
+	if l.Id == "" || l.Name == "" {
+		return fmt.Errorf("label %s `%s` requires an id and a name", l.Kind, l.Name)
+	}
+	return nil
+}
+
+// URL returns the path of the labeled entity in the API.
+func (l *Label) URL() string {
+	if l.Kind == LabelSmesher {
+		return "/smeshers/" + l.Id
+	}
+	return "/accounts/" + l.Id
+}
+
+type LabelService interface {
+	SearchLabels(ctx context.Context, text string) ([]*Label, error)
+}
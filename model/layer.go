@@ -2,10 +2,8 @@ package model
 
 import (
 	"context"
-	"fmt"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
@@ -20,6 +18,13 @@ type Layer struct {
 	Epoch        uint32 `json:"epoch" bson:"epoch"`
 	Hash         string `json:"hash" bson:"hash"`
 	BlocksNumber uint32 `json:"blocksnumber" bson:"blocksnumber"`
+
+	FeesCollected   uint64 `json:"feescollected" bson:"feescollected"`     // fees paid by processed transactions
+	FeesDistributed uint64 `json:"feesdistributed" bson:"feesdistributed"` // part of the fees paid to smeshers
+	FeesBurned      uint64 `json:"feesburned" bson:"feesburned"`           // part of the fees removed from supply
+	FeeMin          uint64 `json:"feemin" bson:"feemin"`                   // lowest fee paid by a processed transaction
+	FeeMedian       uint64 `json:"feemedian" bson:"feemedian"`             // median fee paid by the processed transactions
+	FeeMax          uint64 `json:"feemax" bson:"feemax"`                   // highest fee paid by a processed transaction
 }
 
 type LayerService interface {
@@ -39,7 +44,7 @@ func NewLayer(in *pb.Layer, networkInfo *NetworkInfo) (*Layer, []*Block, []*Acti
 	layer := &Layer{
 		Number:       in.Number.Number,
 		Status:       int(in.GetStatus()),
-		Epoch:        in.Number.Number / networkInfo.EpochNumLayers,
+		Epoch:        utils.LayerEpoch(in.Number.Number, networkInfo.EpochNumLayers),
 		BlocksNumber: uint32(len(pbBlocks)),
 		Hash:         utils.BytesToHex(in.Hash),
 	}
@@ -64,9 +69,10 @@ func NewLayer(in *pb.Layer, networkInfo *NetworkInfo) (*Layer, []*Block, []*Acti
 			TxsNumber: uint32(len(b.Transactions)),
 		}
 		for j, t := range b.Transactions {
+			blocks[i].Size += uint64(len(t.GetRaw()))
 			tx, err := NewTransaction(t, layer.Number, blocks[i].Id, layer.Start, uint32(j))
 			if err != nil {
-				log.Err(fmt.Errorf("cannot create transaction: %v", err))
+				logging.Error("cannot create transaction", err)
 				continue
 			}
 			txs[tx.Id] = tx
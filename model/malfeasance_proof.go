@@ -20,3 +20,46 @@ func NewMalfeasanceProof(in *pb.MalfeasanceProof) *MalfeasanceProof {
 		DebugInfo: in.DebugInfo,
 	}
 }
+
+// MalfeasanceStats are the malfeasance proofs of the layers of an epoch.
+type MalfeasanceStats struct {
+	Epoch  uint32           `json:"epoch"`
+	Proofs int64            `json:"proofs"`
+	Kinds  map[string]int64 `json:"kinds"` // proofs by kind, e.g. MALFEASANCE_ATX
+	// Smeshers are the smeshers proven malicious for the first time in the epoch, removed from the
+	// active set with Space, the storage committed by their activations targeting the epoch.
+	Smeshers int64 `json:"smeshers"`
+	Space    int64 `json:"space"`
+}
+
+// NewMalfeasanceStats groups the proofs, sorted by layer, by epoch. The activations are the ones of
+// the smeshers of the proofs, the epochs without a proof are left out.
+func NewMalfeasanceStats(proofs []*MalfeasanceProof, atxs []*Activation, epochNumLayers uint32, unitSize uint64) []*MalfeasanceStats {
+	var series []*MalfeasanceStats
+	if epochNumLayers == 0 {
+		return series
+	}
+	units := make(map[string]map[uint32]int64)
+	for _, atx := range atxs {
+		if units[atx.SmesherId] == nil {
+			units[atx.SmesherId] = make(map[uint32]int64)
+		}
+		units[atx.SmesherId][atx.TargetEpoch] += int64(atx.EffectiveNumUnits)
+	}
+	removed := make(map[string]struct{})
+	for _, proof := range proofs {
+		epoch := proof.Layer / epochNumLayers
+		if len(series) == 0 || series[len(series)-1].Epoch != epoch {
+			series = append(series, &MalfeasanceStats{Epoch: epoch, Kinds: map[string]int64{}})
+		}
+		stats := series[len(series)-1]
+		stats.Proofs++
+		stats.Kinds[proof.Kind]++
+		if _, ok := removed[proof.Smesher]; !ok {
+			removed[proof.Smesher] = struct{}{}
+			stats.Smeshers++
+			stats.Space += units[proof.Smesher][epoch] * int64(unitSize)
+		}
+	}
+	return series
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SmidgePerSmesh is the number of smidge, the unit of the amounts, in a SMH.
+const SmidgePerSmesh = 1e9
+
+// PriceLookback is how long a price stays the price of the periods without a more recent one, see
+// Prices.Mean.
+const PriceLookback = 60 * 60
+
+// Price is a SMH price in USD recorded from a price feed, see pricefeed.Record.
+type Price struct {
+	Timestamp uint32  `json:"timestamp" bson:"timestamp"` // unix time of the recording
+	USD       float64 `json:"usd" bson:"usd"`
+	Source    string  `json:"source" bson:"source"` // name of the price feed
+}
+
+// Validate checks the price is a positive finite value.
+func (p *Price) Validate() error {
+	if p.Timestamp == 0 {
+		return fmt.Errorf("missing timestamp")
+	}
+	if math.IsNaN(p.USD) || math.IsInf(p.USD, 0) || p.USD <= 0 {
+		return fmt.Errorf("invalid price %v", p.USD)
+	}
+	return nil
+}
+
+// Prices are recorded prices, by timestamp.
+type Prices []*Price
+
+// Mean returns the mean of the prices recorded in [from, to), or the last price recorded in the
+// PriceLookback before from if there is none, e.g. for the periods shorter than the interval of
+// the feed. It returns 0 without any price.
+func (p Prices) Mean(from, to uint32) float64 {
+	i := sort.Search(len(p), func(i int) bool { return p[i].Timestamp >= from })
+	var sum float64
+	n := 0
+	for j := i; j < len(p) && p[j].Timestamp < to; j++ {
+		sum += p[j].USD
+		n++
+	}
+	if n > 0 {
+		return sum / float64(n)
+	}
+	if i > 0 && p[i-1].Timestamp+PriceLookback >= from {
+		return p[i-1].USD
+	}
+	return 0
+}
+
+// Fiat returns the value in USD of an amount in smidge at the price.
+func Fiat(amount int64, usd float64) float64 {
+	return float64(amount) / SmidgePerSmesh * usd
+}
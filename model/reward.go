@@ -19,6 +19,9 @@ type Reward struct {
 	Coinbase  string `json:"coinbase" bson:"coinbase"` // account awarded this reward
 	Smesher   string `json:"smesher" bson:"smesher"`
 	Timestamp uint32 `json:"timestamp" bson:"timestamp"`
+	// Atx is the id of the activation of the smesher targeting the epoch of the layer, which earned
+	// the reward. It is empty until the activation is stored.
+	Atx string `json:"atx,omitempty" bson:"atx,omitempty"`
 }
 
 type RewardService interface {
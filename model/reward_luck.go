@@ -0,0 +1,122 @@
+package model
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// SmesherEpochRewards are the rewards of a smesher in the layers of an epoch, with the commitment
+// of its activation targeting the epoch. The smeshers rewarded without such an activation have no
+// units nor weight.
+type SmesherEpochRewards struct {
+	Smesher  string `json:"smesher" bson:"smesher"`
+	NumUnits uint32 `json:"numUnits" bson:"numunits"`
+	Weight   int64  `json:"weight" bson:"weight"`
+	Rewards  int64  `json:"rewards" bson:"rewards"`
+	Count    int64  `json:"count" bson:"count"` // number of rewards, one per rewarded layer
+}
+
+// LuckBucket is the luck of the smeshers with a number of units in [From, To), the rewards they
+// received over the rewards expected from the weight of their activations, in basis points.
+type LuckBucket struct {
+	From            uint32 `json:"from"`
+	To              uint32 `json:"to"`
+	Smeshers        int64  `json:"smeshers"`
+	Rewards         int64  `json:"rewards"`
+	ExpectedRewards int64  `json:"expectedRewards"`
+	Luck            int64  `json:"luck"` // rewards of the bucket over its expected rewards
+	// distribution of the luck of the smeshers of the bucket
+	MeanLuck   int64 `json:"meanLuck"`
+	StdDevLuck int64 `json:"stdDevLuck"`
+	P10Luck    int64 `json:"p10Luck"`
+	MedianLuck int64 `json:"medianLuck"`
+	P90Luck    int64 `json:"p90Luck"`
+	// Dry are the smeshers without any reward in the epoch, and ExpectedDry the number of them
+	// expected by chance, the rewards of a smesher following a Poisson distribution.
+	Dry         int64 `json:"dry"`
+	ExpectedDry int64 `json:"expectedDry"`
+}
+
+// RewardLuck is the distribution of the luck of the smeshers of an epoch by the size of their
+// commitment, so that small smeshers can tell whether their dry spells are statistically normal.
+type RewardLuck struct {
+	Epoch    int32         `json:"epoch"`
+	Smeshers int64         `json:"smeshers"` // with an activation targeting the epoch
+	Rewards  int64         `json:"rewards"`  // of all the smeshers
+	Buckets  []*LuckBucket `json:"buckets"`
+}
+
+// NewRewardLuck buckets the smeshers of the epoch by their number of units, like the commitment
+// histogram. The expected rewards and number of rewards of a smesher are the parts of the ones of
+// all the smeshers matching its weight share.
+func NewRewardLuck(epoch int32, smeshers []*SmesherEpochRewards) *RewardLuck {
+	luck := &RewardLuck{Epoch: epoch, Buckets: []*LuckBucket{}}
+	var totalWeight, totalCount int64
+	lowest, highest := bits.UintSize, 0
+	for _, smesher := range smeshers {
+		luck.Rewards += smesher.Rewards
+		totalCount += smesher.Count
+		if smesher.Weight == 0 {
+			continue
+		}
+		luck.Smeshers++
+		totalWeight += smesher.Weight
+		lowest = min(lowest, bits.Len32(smesher.NumUnits))
+		highest = max(highest, bits.Len32(smesher.NumUnits))
+	}
+	if totalWeight == 0 {
+		return luck
+	}
+	for i := lowest; i <= highest; i++ {
+		from, to := commitmentBucketBounds(i)
+		luck.Buckets = append(luck.Buckets, &LuckBucket{From: from, To: to})
+	}
+
+	lucks := make([][]int64, len(luck.Buckets))
+	expectedDry := make([]float64, len(luck.Buckets))
+	for _, smesher := range smeshers {
+		if smesher.Weight == 0 {
+			continue
+		}
+		i := bits.Len32(smesher.NumUnits) - lowest
+		bucket := luck.Buckets[i]
+		share := float64(smesher.Weight) / float64(totalWeight)
+		expected := int64(math.Round(share * float64(luck.Rewards)))
+		bucket.Smeshers++
+		bucket.Rewards += smesher.Rewards
+		bucket.ExpectedRewards += expected
+		if expected > 0 {
+			lucks[i] = append(lucks[i], int64(math.Round(1e4*float64(smesher.Rewards)/float64(expected))))
+		}
+		if smesher.Count == 0 {
+			bucket.Dry++
+		}
+		expectedDry[i] += math.Exp(-share * float64(totalCount))
+	}
+	for i, bucket := range luck.Buckets {
+		bucket.ExpectedDry = int64(math.Round(expectedDry[i]))
+		if bucket.ExpectedRewards > 0 {
+			bucket.Luck = int64(math.Round(1e4 * float64(bucket.Rewards) / float64(bucket.ExpectedRewards)))
+		}
+		if len(lucks[i]) == 0 {
+			continue
+		}
+		slices.Sort(lucks[i])
+		var sum float64
+		for _, l := range lucks[i] {
+			sum += float64(l)
+		}
+		mean := sum / float64(len(lucks[i]))
+		var squares float64
+		for _, l := range lucks[i] {
+			squares += (float64(l) - mean) * (float64(l) - mean)
+		}
+		bucket.MeanLuck = int64(math.Round(mean))
+		bucket.StdDevLuck = int64(math.Round(math.Sqrt(squares / float64(len(lucks[i])))))
+		bucket.P10Luck = percentile(lucks[i], 10)
+		bucket.MedianLuck = percentile(lucks[i], 50)
+		bucket.P90Luck = percentile(lucks[i], 90)
+	}
+	return luck
+}
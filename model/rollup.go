@@ -0,0 +1,108 @@
+package model
+
+import (
+	"slices"
+)
+
+const (
+	secondsPerDay  = 24 * 60 * 60
+	secondsPerWeek = 7 * secondsPerDay
+)
+
+// Rollup aggregates the activity of the network in a day or a week, so that the series over long
+// ranges read a document per period instead of the raw documents.
+type Rollup struct {
+	Start       uint32 `json:"start" bson:"start"` // unix timestamp of the start of the period (UTC)
+	Txs         int64  `json:"txs" bson:"txs"`
+	Volume      int64  `json:"volume" bson:"volume"` // amount of the transactions
+	Fees        int64  `json:"fees" bson:"fees"`
+	Rewards     int64  `json:"rewards" bson:"rewards"`
+	NewAccounts int64  `json:"newAccounts" bson:"newAccounts"` // accounts first seen in the period
+	// NewSmeshers are the smeshers whose first activation targets an epoch starting in the period.
+	NewSmeshers int64 `json:"newSmeshers" bson:"newSmeshers"`
+	// values of the volume, of the fees and of the rewards at the mean price of the period, only
+	// set with recorded prices, see Prices.Mean
+	VolumeUSD  float64 `json:"volumeUsd,omitempty" bson:"-"`
+	FeesUSD    float64 `json:"feesUsd,omitempty" bson:"-"`
+	RewardsUSD float64 `json:"rewardsUsd,omitempty" bson:"-"`
+}
+
+// IsRollupPeriod reports whether the rollups are maintained for the period.
+func IsRollupPeriod(period string) bool {
+	return period == PeriodDay || period == PeriodWeek
+}
+
+// PeriodSeconds returns the length of a rollup period.
+func PeriodSeconds(period string) uint32 {
+	if period == PeriodWeek {
+		return secondsPerWeek
+	}
+	return secondsPerDay
+}
+
+// PeriodStart returns the start of the rollup period including the timestamp. Weeks start on
+// monday, the unix epoch being a thursday.
+func PeriodStart(period string, timestamp uint32) uint32 {
+	if period == PeriodWeek {
+		return timestamp - min(timestamp, (timestamp+3*secondsPerDay)%secondsPerWeek)
+	}
+	return timestamp - timestamp%secondsPerDay
+}
+
+// Rollups accumulates the rollups of a period.
+type Rollups struct {
+	period  string
+	info    *NetworkInfo
+	rollups map[uint32]*Rollup
+}
+
+// NewRollups returns empty rollups of the period, the network info dates the layers and the epochs.
+func NewRollups(period string, info *NetworkInfo) *Rollups {
+	return &Rollups{period: period, info: info, rollups: make(map[uint32]*Rollup)}
+}
+
+// At returns the rollup of the period including the timestamp.
+func (r *Rollups) At(timestamp uint32) *Rollup {
+	start := PeriodStart(r.period, timestamp)
+	rollup, ok := r.rollups[start]
+	if !ok {
+		rollup = &Rollup{Start: start}
+		r.rollups[start] = rollup
+	}
+	return rollup
+}
+
+// AtLayer returns the rollup of the period including the layer.
+func (r *Rollups) AtLayer(layer uint32) *Rollup {
+	return r.At(r.info.GenesisTime + layer*r.info.LayerDuration)
+}
+
+// AtEpoch returns the rollup of the period including the first layer of the epoch.
+func (r *Rollups) AtEpoch(epoch uint32) *Rollup {
+	return r.AtLayer(epoch * r.info.EpochNumLayers)
+}
+
+// Add accounts the rollup of a shorter period, e.g. a day in its week.
+func (r *Rollups) Add(in *Rollup) {
+	rollup := r.At(in.Start)
+	rollup.Txs += in.Txs
+	rollup.Volume += in.Volume
+	rollup.Fees += in.Fees
+	rollup.Rewards += in.Rewards
+	rollup.NewAccounts += in.NewAccounts
+	rollup.NewSmeshers += in.NewSmeshers
+}
+
+// Range returns the rollups starting in [from, to], by start.
+func (r *Rollups) Range(from, to uint32) []*Rollup {
+	rollups := make([]*Rollup, 0, len(r.rollups))
+	for start, rollup := range r.rollups {
+		if start >= from && start <= to {
+			rollups = append(rollups, rollup)
+		}
+	}
+	slices.SortFunc(rollups, func(a, b *Rollup) int {
+		return int(int64(a.Start) - int64(b.Start))
+	})
+	return rollups
+}
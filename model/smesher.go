@@ -2,11 +2,52 @@ package model
 
 import (
 	"context"
+	"fmt"
+	"strings"
 )
 
+// Geo is the location of a smesher, e.g. the city of its node.
 type Geo struct {
-	Name        string     `json:"name"`
-	Coordinates [2]float64 `json:"coordinates"`
+	Name string `json:"name" bson:"name,omitempty"`
+	// Coordinates are the longitude and the latitude in degrees, the order of GeoJSON.
+	Coordinates [2]float64 `json:"coordinates" bson:"coordinates"`
+	// Country is the ISO 3166-1 alpha-2 code of the country, and Region the name of the region in
+	// it, e.g. a state. Both are optional, the smeshers are bucketed by them in the heat-map.
+	Country string `json:"country,omitempty" bson:"country,omitempty"`
+	Region  string `json:"region,omitempty" bson:"region,omitempty"`
+}
+
+// Validate checks the coordinates are a longitude and a latitude, and the country a code.
+func (g *Geo) Validate() error {
+	if lon, lat := g.Coordinates[0], g.Coordinates[1]; lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return fmt.Errorf("invalid coordinates %v, expected [longitude, latitude]", g.Coordinates)
+	}
+	if g.Country != "" && (len(g.Country) != 2 || strings.Trim(g.Country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "") {
+		return fmt.Errorf("invalid country `%s`, expected an ISO 3166-1 alpha-2 code", g.Country)
+	}
+	if g.Region != "" && g.Country == "" {
+		return fmt.Errorf("region `%s` without a country", g.Region)
+	}
+	return nil
+}
+
+// SmesherLocation is the location of a smesher, set by the operator of the explorer.
+type SmesherLocation struct {
+	Smesher string `json:"smesher"`
+	Geo
+}
+
+// GeoBox is a bounding box in degrees, e.g. the area displayed by a map.
+type GeoBox struct {
+	MinLon, MinLat, MaxLon, MaxLat float64
+}
+
+// Validate checks the box is in the coordinates range and does not cross the antimeridian.
+func (b GeoBox) Validate() error {
+	if b.MinLon < -180 || b.MaxLon > 180 || b.MinLat < -90 || b.MaxLat > 90 || b.MinLon >= b.MaxLon || b.MinLat >= b.MaxLat {
+		return fmt.Errorf("invalid bounding box %v, expected minLon,minLat,maxLon,maxLat", b)
+	}
+	return nil
 }
 
 type Smesher struct {
@@ -16,15 +57,24 @@ type Smesher struct {
 	AtxCount       uint32             `json:"atxcount" bson:"atxcount"`
 	Timestamp      uint64             `json:"timestamp" bson:"timestamp"`
 	Rewards        int64              `json:"rewards" bson:"-"`
+	TotalRewards   int64              `json:"totalRewards" bson:"totalRewards"` // sum of the stored rewards
+	RewardsCount   int64              `json:"rewardsCount" bson:"rewardsCount"` // number of the stored rewards
 	AtxLayer       uint32             `json:"atxLayer" bson:"atxLayer"`
 	Proofs         []MalfeasanceProof `json:"proofs,omitempty" bson:"proofs,omitempty"`
 	Epochs         []uint32           `json:"epochs,omitempty" bson:"epochs,omitempty"`
+	Geo            *Geo               `json:"geo,omitempty" bson:"geo,omitempty"`
 }
 
 type SmesherService interface {
 	GetSmesher(ctx context.Context, smesherID string) (*Smesher, error)
 	GetSmeshers(ctx context.Context, page, perPage int64) (smeshers []*Smesher, total int64, err error)
+	GetTopSmeshers(ctx context.Context, page, perPage int64) (smeshers []*Smesher, total int64, err error)
 	GetSmesherActivations(ctx context.Context, smesherID string, page, perPage int64) (atxs []*Activation, total int64, err error)
 	GetSmesherRewards(ctx context.Context, smesherID string, page, perPage int64) (rewards []*Reward, total int64, err error)
 	CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error)
+	GetSmesherHistory(ctx context.Context, smesherID string, page, perPage int64) (changes []*SmesherChange, total int64, err error)
+	GetSmesherParticipation(ctx context.Context, smesherID string, page, perPage int64) (participation []*SmesherParticipation, total int64, err error)
+	GetSmeshersNear(ctx context.Context, lon, lat, radius float64, limit int64) ([]*Smesher, error)
+	GetSmeshersWithin(ctx context.Context, box GeoBox, limit int64) ([]*Smesher, error)
+	GetGeoHeatmap(ctx context.Context) (*GeoHeatmap, error)
 }
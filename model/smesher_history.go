@@ -0,0 +1,51 @@
+package model
+
+// SmesherChange records the fields of a smesher that changed with an activation, or its name that
+// changed with its label. The first activation of a smesher records all the fields.
+type SmesherChange struct {
+	Smesher        string   `json:"smesher" bson:"smesher"`
+	Epoch          uint32   `json:"epoch" bson:"epoch"`
+	Timestamp      uint64   `json:"timestamp" bson:"timestamp"`
+	Coinbase       string   `json:"coinbase" bson:"coinbase"`
+	CommitmentSize uint64   `json:"cSize" bson:"cSize"`
+	Name           string   `json:"name,omitempty" bson:"name,omitempty"`
+	Changed        []string `json:"changed" bson:"changed"` // bson names of the changed fields
+}
+
+// NewSmesherChange returns the change from previous to current, previous is nil for a new smesher.
+// It returns nil if no field changed.
+func NewSmesherChange(previous, current *Smesher, epoch uint32) *SmesherChange {
+	var changed []string
+	if previous == nil || previous.Coinbase != current.Coinbase {
+		changed = append(changed, "coinbase")
+	}
+	if previous == nil || previous.CommitmentSize != current.CommitmentSize {
+		changed = append(changed, "cSize")
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return &SmesherChange{
+		Smesher:        current.Id,
+		Epoch:          epoch,
+		Timestamp:      current.Timestamp,
+		Coinbase:       current.Coinbase,
+		CommitmentSize: current.CommitmentSize,
+		Changed:        changed,
+	}
+}
+
+// NewSmesherNameChange returns the change of the name of the smesher in the epoch, previous is
+// empty for a smesher without label. It returns nil if the name did not change.
+func NewSmesherNameChange(smesher, previous, name string, epoch uint32, timestamp uint64) *SmesherChange {
+	if previous == name {
+		return nil
+	}
+	return &SmesherChange{
+		Smesher:   smesher,
+		Epoch:     epoch,
+		Timestamp: timestamp,
+		Name:      name,
+		Changed:   []string{"name"},
+	}
+}
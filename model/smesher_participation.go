@@ -0,0 +1,33 @@
+package model
+
+// SmesherParticipation compares the participation of a smesher in the layers of an epoch to the
+// participation expected from the weight of its activation targeting the epoch. The expected values
+// are the part of the totals of all the smeshers matching the weight share of the smesher.
+type SmesherParticipation struct {
+	Epoch       uint32 `json:"epoch"`
+	Weight      int64  `json:"weight"`      // weight of the activation of the smesher targeting the epoch
+	WeightShare int64  `json:"weightShare"` // part of the weight of all the activations of the epoch, in basis points
+	// Eligibilities are the hare eligibilities of the smesher in the certificates of the layers.
+	Eligibilities         int64 `json:"eligibilities"`
+	ExpectedEligibilities int64 `json:"expectedEligibilities"`
+	Certificates          int64 `json:"certificates"` // certificates of the layers
+	Signed                int64 `json:"signed"`       // certificates signed by the smesher
+	// Ballots are the ballots of the smesher counted in the blocks, one per rewarded layer.
+	Ballots         int64 `json:"ballots"`
+	ExpectedBallots int64 `json:"expectedBallots"`
+	// Voted are the ballots cast by the smesher in the layers, rewarded or not, and Votes the
+	// eligibilities they vote with, over the eligibilities of the smesher in the epoch for VoteRate.
+	Voted              int64 `json:"voted"`
+	Votes              int64 `json:"votes"`
+	EpochEligibilities int64 `json:"epochEligibilities"`
+	VoteRate           int64 `json:"voteRate"` // in basis points
+	Rewards            int64 `json:"rewards"`
+	ExpectedRewards    int64 `json:"expectedRewards"`
+	Score              int64 `json:"score"` // rewards received over the expected rewards, in basis points
+
+	// totals of all the smeshers of the epoch, from which the expected values are computed
+	TotalWeight        int64 `json:"-"`
+	TotalEligibilities int64 `json:"-"`
+	TotalBallots       int64 `json:"-"`
+	TotalRewards       int64 `json:"-"`
+}
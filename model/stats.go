@@ -0,0 +1,467 @@
+package model
+
+import (
+	"context"
+	"math/bits"
+	"slices"
+)
+
+// DailyTransactions is the number and the amount of the transactions of a day.
+type DailyTransactions struct {
+	Day    uint32 `json:"day" bson:"day"` // unix timestamp of the start of the day (UTC)
+	Count  int64  `json:"count" bson:"count"`
+	Amount int64  `json:"amount" bson:"amount"`
+	// AmountUSD is the value of the amount at the mean price of the day, only set with recorded
+	// prices, see Prices.Mean.
+	AmountUSD float64 `json:"amountUsd,omitempty" bson:"-"`
+}
+
+// The periods of the transaction types, of the fees stats and of the rollups.
+const (
+	PeriodLayer = "layer"
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodEpoch = "epoch"
+)
+
+// TransactionTypeStats are the number and the amount of the transactions of a decoded type in a day
+// of an epoch, see TransactionTypeName.
+type TransactionTypeStats struct {
+	Day    uint32 `json:"day" bson:"day"` // unix timestamp of the start of the day (UTC)
+	Epoch  uint32 `json:"epoch" bson:"epoch"`
+	Type   int    `json:"type" bson:"type"`
+	Count  int64  `json:"count" bson:"count"`
+	Amount int64  `json:"amount" bson:"amount"`
+}
+
+// TransactionTypeCount are the number and the amount of the transactions of a type.
+type TransactionTypeCount struct {
+	Count  int64 `json:"count"`
+	Amount int64 `json:"amount"`
+}
+
+// TransactionTypes is the breakdown of the transactions of a day or of an epoch by decoded type.
+type TransactionTypes struct {
+	Period uint32                           `json:"period"` // start of the day (unix timestamp) or epoch number
+	Types  map[string]*TransactionTypeCount `json:"types"`
+}
+
+// FeeStats are the lowest, median and highest fees paid by the processed transactions of a layer
+// or of an epoch.
+type FeeStats struct {
+	Min    uint64
+	Median uint64
+	Max    uint64
+}
+
+// NewFeeStats returns the stats of the fees, given in any order.
+func NewFeeStats(fees []uint64) FeeStats {
+	if len(fees) == 0 {
+		return FeeStats{}
+	}
+	sorted := slices.Clone(fees)
+	slices.Sort(sorted)
+	return FeeStats{Min: sorted[0], Median: FeeMedian(sorted), Max: sorted[len(sorted)-1]}
+}
+
+// FeeMedian returns the median of the sorted fees, the mean of both middle ones if their number is
+// even. The fees can also be only the middle one or both middle ones.
+func FeeMedian(sorted []uint64) uint64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	middle := (len(sorted) - 1) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[middle]
+	}
+	return sorted[middle]/2 + sorted[middle+1]/2 + (sorted[middle]%2+sorted[middle+1]%2)/2
+}
+
+// InclusionStats are the delays in seconds between the first sighting of transactions by the node
+// and the start of the layers including them.
+type InclusionStats struct {
+	Txs    int64
+	Mean   int64
+	Median int64
+	P90    int64
+	P99    int64
+}
+
+// NewInclusionStats returns the stats of the inclusion delays, given in any order.
+func NewInclusionStats(delays []int64) InclusionStats {
+	if len(delays) == 0 {
+		return InclusionStats{}
+	}
+	sorted := slices.Clone(delays)
+	slices.Sort(sorted)
+	var sum int64
+	for _, delay := range sorted {
+		sum += delay
+	}
+	return InclusionStats{
+		Txs:    int64(len(sorted)),
+		Mean:   sum / int64(len(sorted)),
+		Median: percentile(sorted, 50),
+		P90:    percentile(sorted, 90),
+		P99:    percentile(sorted, 99),
+	}
+}
+
+// percentile returns the nearest rank percentile p of the sorted values, not empty.
+func percentile(sorted []int64, p int) int64 {
+	return sorted[(len(sorted)*p+99)/100-1]
+}
+
+// InclusionTime are the delays between the first sighting of the transactions of an epoch and their
+// inclusion, see Statistics.
+type InclusionTime struct {
+	Epoch  int32 `json:"epoch"`
+	Txs    int64 `json:"txs"`
+	Mean   int64 `json:"mean"`
+	Median int64 `json:"median"`
+	P90    int64 `json:"p90"`
+	P99    int64 `json:"p99"`
+}
+
+// BlockTxsBuckets and BlockSizeBuckets are the lower bounds of the buckets of the blocks by number
+// of transactions and by size in bytes.
+var (
+	BlockTxsBuckets  = []uint64{0, 1, 10, 50, 100, 500}
+	BlockSizeBuckets = []uint64{0, 1, 1 << 10, 10 << 10, 100 << 10, 1 << 20}
+)
+
+// Bucket returns the lower bound of the bucket of the value, the bounds are in ascending order.
+func Bucket(bounds []uint64, value uint64) uint64 {
+	bucket := bounds[0]
+	for _, bound := range bounds {
+		if value >= bound {
+			bucket = bound
+		}
+	}
+	return bucket
+}
+
+// BlockStats are the blocks of an epoch falling in the same buckets of transactions and of size,
+// see BlockTxsBuckets and BlockSizeBuckets.
+type BlockStats struct {
+	Epoch      uint32 `json:"epoch" bson:"epoch"`
+	TxsBucket  uint64 `json:"txsBucket" bson:"txsBucket"`
+	SizeBucket uint64 `json:"sizeBucket" bson:"sizeBucket"`
+	Blocks     int64  `json:"blocks" bson:"blocks"`
+	Txs        int64  `json:"txs" bson:"txs"`
+	Size       int64  `json:"size" bson:"size"`
+	MaxTxs     int64  `json:"maxTxs" bson:"maxTxs"`
+	MaxSize    int64  `json:"maxSize" bson:"maxSize"`
+}
+
+// NewBlockStats groups the blocks by epoch and buckets.
+func NewBlockStats(blocks []*Block) []*BlockStats {
+	type key struct {
+		epoch     uint32
+		txs, size uint64
+	}
+	groups := make(map[key]*BlockStats)
+	var stats []*BlockStats
+	for _, block := range blocks {
+		k := key{block.Epoch, Bucket(BlockTxsBuckets, uint64(block.TxsNumber)), Bucket(BlockSizeBuckets, block.Size)}
+		group, ok := groups[k]
+		if !ok {
+			group = &BlockStats{Epoch: k.epoch, TxsBucket: k.txs, SizeBucket: k.size}
+			groups[k] = group
+			stats = append(stats, group)
+		}
+		group.Blocks++
+		group.Txs += int64(block.TxsNumber)
+		group.Size += int64(block.Size)
+		group.MaxTxs = max(group.MaxTxs, int64(block.TxsNumber))
+		group.MaxSize = max(group.MaxSize, int64(block.Size))
+	}
+	return stats
+}
+
+// BucketCount is the number of blocks in a bucket starting at From.
+type BucketCount struct {
+	From   uint64 `json:"from"`
+	Blocks int64  `json:"blocks"`
+}
+
+// BlockFill is the number of transactions and the size of the blocks of an epoch, on average and
+// by bucket.
+type BlockFill struct {
+	Epoch            uint32         `json:"epoch"`
+	Blocks           int64          `json:"blocks"`
+	Txs              int64          `json:"txs"`
+	Size             int64          `json:"size"`
+	AvgTxs           float64        `json:"avgTxs"`
+	AvgSize          float64        `json:"avgSize"`
+	MaxTxs           int64          `json:"maxTxs"`
+	MaxSize          int64          `json:"maxSize"`
+	TxsDistribution  []*BucketCount `json:"txsDistribution"`
+	SizeDistribution []*BucketCount `json:"sizeDistribution"`
+}
+
+// NewBlockFill returns the fill of the blocks of the epoch from its stats.
+func NewBlockFill(epoch uint32, stats []*BlockStats) *BlockFill {
+	fill := &BlockFill{Epoch: epoch}
+	distribution := func(bounds []uint64) []*BucketCount {
+		counts := make([]*BucketCount, 0, len(bounds))
+		for _, bound := range bounds {
+			counts = append(counts, &BucketCount{From: bound})
+		}
+		return counts
+	}
+	fill.TxsDistribution = distribution(BlockTxsBuckets)
+	fill.SizeDistribution = distribution(BlockSizeBuckets)
+	for _, s := range stats {
+		fill.Blocks += s.Blocks
+		fill.Txs += s.Txs
+		fill.Size += s.Size
+		fill.MaxTxs = max(fill.MaxTxs, s.MaxTxs)
+		fill.MaxSize = max(fill.MaxSize, s.MaxSize)
+		// the stats of buckets which are no longer defined are only counted in the totals
+		if i := slices.Index(BlockTxsBuckets, s.TxsBucket); i >= 0 {
+			fill.TxsDistribution[i].Blocks += s.Blocks
+		}
+		if i := slices.Index(BlockSizeBuckets, s.SizeBucket); i >= 0 {
+			fill.SizeDistribution[i].Blocks += s.Blocks
+		}
+	}
+	if fill.Blocks > 0 {
+		fill.AvgTxs = float64(fill.Txs) / float64(fill.Blocks)
+		fill.AvgSize = float64(fill.Size) / float64(fill.Blocks)
+	}
+	return fill
+}
+
+// FeeSeries are the fees paid by the processed transactions of a layer or of an epoch.
+type FeeSeries struct {
+	Period uint32 `json:"period"` // layer or epoch number
+	Total  uint64 `json:"total"`
+	Min    uint64 `json:"min"`
+	Median uint64 `json:"median"`
+	Max    uint64 `json:"max"`
+	// TotalUSD is the value of the total at the mean price of the period, only set with recorded
+	// prices, see Prices.Mean.
+	TotalUSD float64 `json:"totalUsd,omitempty"`
+}
+
+// DailyAccounts is the number of accounts first seen in a day, and the number of accounts seen by
+// the end of the day.
+type DailyAccounts struct {
+	Day   uint32 `json:"day" bson:"day"` // unix timestamp of the start of the day (UTC)
+	New   int64  `json:"new" bson:"count"`
+	Total int64  `json:"total" bson:"-"`
+}
+
+// EpochAccounts is the number of accounts first seen in an epoch, and the number of accounts seen
+// by the end of the epoch, see Statistics.
+type EpochAccounts struct {
+	Epoch int32 `json:"epoch"`
+	New   int64 `json:"new"`
+	Total int64 `json:"total"`
+}
+
+// Decentralization are the decentralization metrics of an epoch, see Statistics.
+type Decentralization struct {
+	Epoch           int32 `json:"epoch"`
+	SpaceGini       int64 `json:"spacegini"`
+	SpaceNakamoto   int64 `json:"spacenakamoto"`
+	RewardsGini     int64 `json:"rewardsgini"`
+	RewardsNakamoto int64 `json:"rewardsnakamoto"`
+}
+
+// SmeshersChurn are the smeshers which joined, came back to and left the network in an epoch, see
+// Statistics.
+type SmeshersChurn struct {
+	Epoch     int32 `json:"epoch"`
+	New       int64 `json:"new"`
+	Returning int64 `json:"returning"`
+	Stopped   int64 `json:"stopped"`
+}
+
+// SpaceGrowth is the storage committed to the network in an epoch and its change since the previous
+// epoch, see Statistics.
+type SpaceGrowth struct {
+	Epoch int32 `json:"epoch"`
+	Space int64 `json:"space"`
+	Delta int64 `json:"delta"`
+}
+
+// ActiveSmeshers is the number of distinct smeshers with an activation targeting an epoch, and of
+// the ones rewarded in its layers, see Statistics.
+type ActiveSmeshers struct {
+	Epoch     int32 `json:"epoch"`
+	Activated int64 `json:"activated"`
+	Rewarded  int64 `json:"rewarded"`
+}
+
+// EmptyLayers are the layers of an epoch without a block, and without a transaction, see Statistics.
+// A spike of empty layers is an early sign of a consensus problem.
+type EmptyLayers struct {
+	Epoch      int32  `json:"epoch"`
+	Layers     uint32 `json:"layers"`
+	Empty      int64  `json:"empty"`
+	WithoutTxs int64  `json:"withoutTxs"`
+}
+
+// Issuance is the subsidy the issuance curve of the protocol expects for an epoch, and the subsidy
+// of the rewards collected for it. A divergence in a complete epoch is flagged: the rewards are
+// missing or counted twice, or layers of the epoch were empty and issued no subsidy.
+type Issuance struct {
+	Epoch      int32  `json:"epoch"`
+	Expected   uint64 `json:"expected"`
+	Observed   uint64 `json:"observed"`   // layer rewards, without the fees
+	Divergence int64  `json:"divergence"` // observed minus expected
+	Complete   bool   `json:"complete"`   // all the layers of the epoch are collected
+	Diverged   bool   `json:"diverged"`
+}
+
+// SupplyBreakdown decomposes the supply by the end of an epoch. The genesis allocations are vaulted,
+// the funds of the vaults which are not spawned yet are counted as locked.
+type SupplyBreakdown struct {
+	Epoch       int32  `json:"epoch"`
+	Genesis     uint64 `json:"genesis"`     // allocated at genesis
+	Vested      uint64 `json:"vested"`      // part of the genesis allocations vested
+	Locked      uint64 `json:"locked"`      // part of the genesis allocations still locked
+	Rewards     uint64 `json:"rewards"`     // subsidy issued as layer rewards, without the fees
+	FeesBurned  uint64 `json:"feesBurned"`  // fees removed from the supply
+	Total       uint64 `json:"total"`       // genesis allocations and rewards, without the burned fees
+	Circulating uint64 `json:"circulating"` // total without the locked funds
+}
+
+// CoinbaseShare is the storage committed by the activations of a coinbase targeting an epoch, and
+// the rewards it earned in the epoch. The shares are in basis points of all the coinbases.
+type CoinbaseShare struct {
+	Coinbase     string `json:"coinbase" bson:"coinbase"`
+	Smeshers     int64  `json:"smeshers" bson:"smeshers"`
+	Units        int64  `json:"units" bson:"units"` // effective num units
+	Space        int64  `json:"space" bson:"-"`
+	Rewards      int64  `json:"rewards" bson:"rewards"`
+	SpaceShare   int64  `json:"spaceShare" bson:"-"`
+	RewardsShare int64  `json:"rewardsShare" bson:"-"`
+}
+
+// CoinbaseConcentration is the part of the storage and of the rewards of an epoch held by its top
+// coinbases, which may each gather many smeshers.
+type CoinbaseConcentration struct {
+	Epoch           int32            `json:"epoch"`
+	Coinbases       int64            `json:"coinbases"`
+	Space           int64            `json:"space"`
+	Rewards         int64            `json:"rewards"`
+	TopSpaceShare   int64            `json:"topSpaceShare"`   // basis points of the top coinbases by space
+	TopRewardsShare int64            `json:"topRewardsShare"` // basis points of the top coinbases by rewards
+	Top             []*CoinbaseShare `json:"top"`             // by space
+}
+
+// UnitsCount is the number of activations targeting an epoch with a number of units.
+type UnitsCount struct {
+	NumUnits    uint32 `json:"numUnits" bson:"numunits"`
+	Activations int64  `json:"activations" bson:"activations"`
+}
+
+// CommitmentBucket is the number of activations with a number of units in [From, To).
+type CommitmentBucket struct {
+	From        uint32 `json:"from"`
+	To          uint32 `json:"to"`
+	Activations int64  `json:"activations"`
+	Units       int64  `json:"units"`
+	Space       int64  `json:"space"`
+}
+
+// CommitmentHistogram is the distribution of the activations targeting an epoch by their number of
+// units, in buckets doubling in size so that small and industrial smeshers fit in the same chart.
+type CommitmentHistogram struct {
+	Epoch       int32               `json:"epoch"`
+	Activations int64               `json:"activations"`
+	Units       int64               `json:"units"`
+	Space       int64               `json:"space"`
+	Buckets     []*CommitmentBucket `json:"buckets"`
+}
+
+// NewCommitmentHistogram buckets the activations of the epoch by their number of units, from the
+// bucket of the smallest commitment to the one of the largest, empty buckets included.
+func NewCommitmentHistogram(epoch int32, counts []*UnitsCount, unitSize uint64) *CommitmentHistogram {
+	histogram := &CommitmentHistogram{Epoch: epoch, Buckets: []*CommitmentBucket{}}
+	if len(counts) == 0 {
+		return histogram
+	}
+	// bucket i holds the commitments in [2^(i-1), 2^i), bucket 0 the ones without units
+	lowest, highest := bits.UintSize, 0
+	for _, c := range counts {
+		lowest = min(lowest, bits.Len32(c.NumUnits))
+		highest = max(highest, bits.Len32(c.NumUnits))
+	}
+	for i := lowest; i <= highest; i++ {
+		from, to := commitmentBucketBounds(i)
+		histogram.Buckets = append(histogram.Buckets, &CommitmentBucket{From: from, To: to})
+	}
+	for _, c := range counts {
+		bucket := histogram.Buckets[bits.Len32(c.NumUnits)-lowest]
+		units := int64(c.NumUnits) * c.Activations
+		bucket.Activations += c.Activations
+		bucket.Units += units
+		bucket.Space += units * int64(unitSize)
+		histogram.Activations += c.Activations
+		histogram.Units += units
+		histogram.Space += units * int64(unitSize)
+	}
+	return histogram
+}
+
+// commitmentBucketBounds returns the range of number of units of the commitment bucket i, holding
+// the commitments in [2^(i-1), 2^i), bucket 0 the ones without units.
+func commitmentBucketBounds(i int) (from, to uint32) {
+	if i > 0 {
+		from = 1 << (i - 1)
+	}
+	if i < 32 {
+		to = 1 << i
+	} else {
+		to = ^uint32(0)
+	}
+	return from, to
+}
+
+// RewardsProjection is the subsidy expected for a commitment of num units in an epoch, from its share
+// of the storage committed to the network and the issuance of the epoch. The storage of the network
+// is the last one stored at the epoch, it includes the commitment as if it joined the network.
+type RewardsProjection struct {
+	Epoch        int32  `json:"epoch"`
+	NumUnits     uint32 `json:"numUnits"`
+	Space        int64  `json:"space"`        // storage of the commitment
+	NetworkSpace int64  `json:"networkSpace"` // storage of the network, with the commitment
+	SpaceEpoch   int32  `json:"spaceEpoch"`   // epoch of the storage of the network
+	Subsidy      uint64 `json:"subsidy"`      // issued by the layers of the epoch
+	Rewards      uint64 `json:"rewards"`      // expected for the commitment in the epoch
+	DailyRewards uint64 `json:"dailyRewards"`
+}
+
+type StatsService interface {
+	GetDailyTransactions(ctx context.Context, page, perPage int64) ([]*DailyTransactions, int64, error)
+	GetDecentralization(ctx context.Context, page, perPage int64) ([]*Decentralization, int64, error)
+	GetSmeshersChurn(ctx context.Context, page, perPage int64) ([]*SmeshersChurn, int64, error)
+	GetVestingUnlocks(ctx context.Context, page, perPage int64) ([]*VestingUnlock, int64, error)
+	GetVaultDrawdown(ctx context.Context, page, perPage int64) ([]*VaultDrawdown, int64, error)
+	GetSpaceGrowth(ctx context.Context, page, perPage int64) ([]*SpaceGrowth, int64, error)
+	GetActiveSmeshers(ctx context.Context, page, perPage int64) ([]*ActiveSmeshers, int64, error)
+	GetEmptyLayers(ctx context.Context, page, perPage int64) ([]*EmptyLayers, int64, error)
+	GetDailyAccounts(ctx context.Context, page, perPage int64) ([]*DailyAccounts, int64, error)
+	GetEpochAccounts(ctx context.Context, page, perPage int64) ([]*EpochAccounts, int64, error)
+	GetTransactionTypes(ctx context.Context, period string, page, perPage int64) ([]*TransactionTypes, int64, error)
+	GetBlockFill(ctx context.Context, page, perPage int64) ([]*BlockFill, int64, error)
+	GetFeeSeries(ctx context.Context, period string, page, perPage int64) ([]*FeeSeries, int64, error)
+	GetIssuance(ctx context.Context, page, perPage int64) ([]*Issuance, int64, error)
+	GetSupplyBreakdown(ctx context.Context, page, perPage int64) ([]*SupplyBreakdown, int64, error)
+	GetCoinbaseConcentration(ctx context.Context, epoch int32, top int) (*CoinbaseConcentration, error)
+	GetCommitmentHistogram(ctx context.Context, epoch int32) (*CommitmentHistogram, error)
+	GetRewardLuck(ctx context.Context, epoch int32) (*RewardLuck, error)
+	GetRollups(ctx context.Context, period string, from, to uint32) ([]*Rollup, error)
+	GetPrices(ctx context.Context, from, to uint32) ([]*Price, error)
+	GetNetworkHealth(ctx context.Context) (*NetworkHealth, error)
+	GetBalanceCohorts(ctx context.Context, page, perPage int64) ([]*BalanceCohorts, int64, error)
+	GetInclusionTime(ctx context.Context, page, perPage int64) ([]*InclusionTime, int64, error)
+	GetBallotParticipation(ctx context.Context, page, perPage int64) ([]*BallotParticipation, int64, error)
+	GetMalfeasanceStats(ctx context.Context, page, perPage int64) ([]*MalfeasanceStats, int64, error)
+	GetRewardsProjection(ctx context.Context, numUnits uint32, epoch int32) (*RewardsProjection, error)
+	GetTotals(ctx context.Context) (*Totals, error)
+}
@@ -0,0 +1,13 @@
+package model
+
+// CollectionStats is the size of a collection, for the capacity planning and the retention policies.
+type CollectionStats struct {
+	Collection  string `json:"collection"`
+	Documents   int64  `json:"documents"`
+	StorageSize int64  `json:"storageSize"` // bytes allocated to the documents
+	IndexSize   int64  `json:"indexSize"`   // bytes allocated to the indexes
+	// Oldest and Newest are the unix timestamps of the first and the last inserted documents, zero
+	// if the backend does not record the insertion times.
+	Oldest int64 `json:"oldest,omitempty"`
+	Newest int64 `json:"newest,omitempty"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+)
+
+// MethodSpawn is the method of the transactions spawning their principal account.
+const MethodSpawn = 0
+
+// Names of the account templates.
+const (
+	TemplateWallet   = "wallet"
+	TemplateMultisig = "multisig"
+	TemplateVesting  = "vesting"
+	TemplateVault    = "vault"
+)
+
+var templates = map[types.Address]string{
+	wallet.TemplateAddress:   TemplateWallet,
+	multisig.TemplateAddress: TemplateMultisig,
+	vesting.TemplateAddress:  TemplateVesting,
+	vault.TemplateAddress:    TemplateVault,
+}
+
+// TemplateName returns the name of the template at the address, empty if the template is unknown.
+func TemplateName(address string) string {
+	addr, err := types.StringToAddress(address)
+	if err != nil {
+		return ""
+	}
+	return templates[addr]
+}
+
+// TemplateAddress returns the address of the template with the name, empty if the template is
+// unknown.
+func TemplateAddress(name string) string {
+	for addr, t := range templates {
+		if t == name {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+// IsTemplate reports whether name is the name of a known template.
+func IsTemplate(name string) bool {
+	return TemplateAddress(name) != ""
+}
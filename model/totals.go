@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strconv"
+)
+
+// Totals are the global totals of the network. The mongo storage maintains them as counters
+// incremented as the data is stored, so they are served without counting the raw collections.
+type Totals struct {
+	Txs          int64 `json:"txs" bson:"txs"`
+	Accounts     int64 `json:"accounts" bson:"accounts"`
+	Smeshers     int64 `json:"smeshers" bson:"smeshers"`
+	Rewards      int64 `json:"rewards" bson:"rewards"` // amount of the rewards
+	RewardsCount int64 `json:"rewardsCount" bson:"rewardsCount"`
+	// Space is the storage committed by the activations targeting SpaceEpoch, the last epoch with
+	// activations up to the current one, from the storage of EpochSpace by target epoch.
+	Space      int64            `json:"space" bson:"-"`
+	SpaceEpoch uint32           `json:"spaceEpoch" bson:"-"`
+	EpochSpace map[string]int64 `json:"-" bson:"space"`
+	// Orphaned is the number of blocks and transactions orphaned by reorgs, by collection.
+	Orphaned map[string]int64 `json:"-" bson:"orphaned"`
+	// Updated is the unix time the counters were last updated, Age the number of seconds since then
+	// when they are served, and LastLayer the last layer stored by the collector.
+	Updated   uint32 `json:"updated" bson:"updated"`
+	Age       uint32 `json:"age" bson:"-"`
+	LastLayer uint32 `json:"lastLayer" bson:"-"`
+}
+
+// Resolve sets the space of the totals at the epoch and their age at now.
+func (t *Totals) Resolve(epoch, now uint32) {
+	t.Space, t.SpaceEpoch = 0, 0
+	for key, space := range t.EpochSpace {
+		target, err := strconv.ParseUint(key, 10, 32)
+		if err != nil || uint32(target) > epoch || space == 0 {
+			continue
+		}
+		if t.Space == 0 || uint32(target) > t.SpaceEpoch {
+			t.Space, t.SpaceEpoch = space, uint32(target)
+		}
+	}
+	if now > t.Updated {
+		t.Age = now - t.Updated
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/spacemeshos/go-scale"
 
 	"github.com/spacemeshos/explorer-backend/pkg/transactionparser"
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
@@ -23,6 +24,7 @@ type Transaction struct {
 	State      int    `json:"state" bson:"state"`
 	Result     int    `json:"result" bson:"result"`
 	Timestamp  uint32 `json:"timestamp" bson:"timestamp"`
+	Received   uint32 `json:"received,omitempty" bson:"received,omitempty"` // unix time the node first saw the tx, e.g. in its mempool
 
 	MaxGas   uint64 `json:"maxGas" bson:"maxGas"`
 	GasPrice uint64 `json:"gasPrice" bson:"gasPrice"`
@@ -42,6 +44,13 @@ type Transaction struct {
 
 	Message          string   `json:"message" bson:"message"`
 	TouchedAddresses []string `json:"touchedAddresses" bson:"touchedAddresses"`
+
+	Method   uint32 `json:"method" bson:"method"`
+	Template string `json:"template,omitempty" bson:"template,omitempty"` // name of the template of the principal
+	Raw      []byte `json:"-" bson:"raw,omitempty"`                       // raw tx payload, kept to re-decode transactions when the parser improves
+
+	Orphaned     bool   `json:"orphaned,omitempty" bson:"orphaned,omitempty"`         // removed from its layer by a reorg
+	SupersededBy string `json:"supersededBy,omitempty" bson:"supersededBy,omitempty"` // hash of the layer which replaced it
 }
 
 type TransactionReceipt struct {
@@ -55,9 +64,21 @@ type TransactionReceipt struct {
 	TouchedAddresses []string
 }
 
+// TransactionFilter selects transactions by their decoded fields, the unset fields match all the
+// transactions.
+type TransactionFilter struct {
+	Method      *uint32
+	Template    string // template name
+	Principal   string
+	Destination string
+	MinAmount   uint64
+	MaxAmount   uint64
+}
+
 type TransactionService interface {
 	GetTransaction(ctx context.Context, txID string) (*Transaction, error)
 	GetTransactions(ctx context.Context, page, perPage int64) (txs []*Transaction, total int64, err error)
+	GetFilteredTransactions(ctx context.Context, filter *TransactionFilter, page, perPage int64) (txs []*Transaction, total int64, err error)
 }
 
 func NewTransactionResult(res *pb.TransactionResult, state *pb.TransactionState, networkInfo NetworkInfo) (*Transaction, error) {
@@ -79,32 +100,96 @@ func NewTransactionResult(res *pb.TransactionResult, state *pb.TransactionState,
 
 // NewTransaction try to parse the transaction and return a new Transaction struct.
 func NewTransaction(in *pb.Transaction, layer uint32, blockID string, timestamp uint32, blockIndex uint32) (*Transaction, error) {
-	txDecoded, err := transactionparser.Parse(scale.NewDecoder(bytes.NewReader(in.GetRaw())), in.GetRaw(), in.Method)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse transaction: %w", err)
-	}
 	tx := &Transaction{
 		Id:         utils.BytesToHex(in.GetId()),
-		Sender:     txDecoded.GetPrincipal().String(),
-		Amount:     txDecoded.GetAmount(),
-		Counter:    txDecoded.GetCounter(),
 		Layer:      layer,
 		Block:      blockID,
 		BlockIndex: blockIndex,
 		State:      int(pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED),
 		Timestamp:  timestamp,
 		MaxGas:     in.GetMaxGas(),
-		GasPrice:   txDecoded.GetGasPrice(),
-		Fee:        in.GetMaxGas() * txDecoded.GetGasPrice(),
-		Type:       int(txDecoded.GetType()),
-		Signature:  utils.BytesToHex(txDecoded.GetSignature()),
-		Receiver:   txDecoded.GetReceiver().String(),
+		Method:     in.GetMethod(),
+		Template:   TemplateName(in.GetTemplate().GetAddress()),
+		Raw:        in.GetRaw(),
+	}
+	if err := tx.Redecode(); err != nil {
+		return nil, err
 	}
+
+	return tx, nil
+}
+
+// InclusionDelay returns the seconds from the first sighting of the transaction to the start of its
+// layer, and false if the sighting is unknown or after the start of the layer.
+func (tx *Transaction) InclusionDelay() (int64, bool) {
+	if tx.Received == 0 || tx.Received > tx.Timestamp {
+		return 0, false
+	}
+	return int64(tx.Timestamp - tx.Received), true
+}
+
+// SpawnedTemplate returns the name of the template the transaction spawns its principal with, empty
+// if it is not a spawn transaction.
+func (tx *Transaction) SpawnedTemplate() string {
+	if tx.Method != MethodSpawn {
+		return ""
+	}
+	return tx.Template
+}
+
+// Decode parses the raw payload of the transaction and fills the decoded fields.
+func (tx *Transaction) Decode() error {
+	txDecoded, err := transactionparser.Parse(scale.NewDecoder(bytes.NewReader(tx.Raw)), tx.Raw, tx.Method)
+	if err != nil {
+		return fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	tx.Sender = txDecoded.GetPrincipal().String()
+	tx.Amount = txDecoded.GetAmount()
+	tx.Counter = txDecoded.GetCounter()
+	tx.GasPrice = txDecoded.GetGasPrice()
+	tx.Type = int(txDecoded.GetType())
+	tx.Signature = utils.BytesToHex(txDecoded.GetSignature())
+	tx.Receiver = txDecoded.GetReceiver().String()
+
 	keys := make([]string, 0, len(txDecoded.GetPublicKeys()))
 	for i := range txDecoded.GetPublicKeys() {
 		keys = append(keys, utils.BytesToHex(txDecoded.GetPublicKeys()[i]))
 	}
 	tx.PublicKey = strings.Join(keys, ",")
 
-	return tx, nil
+	return nil
+}
+
+// Redecode parses the raw payload of the transaction like Decode and updates the fee to the decoded
+// gas price: the gas used is charged once the result of the transaction is known, the max gas before.
+func (tx *Transaction) Redecode() error {
+	if err := tx.Decode(); err != nil {
+		return err
+	}
+	if tx.GasUsed > 0 {
+		tx.Fee = tx.GasUsed * tx.GasPrice
+	} else {
+		tx.Fee = tx.MaxGas * tx.GasPrice
+	}
+	return nil
+}
+
+// TransactionTypeName returns the name of the decoded type of the transactions, see Transaction.Type.
+func TransactionTypeName(t int) string {
+	switch t {
+	case transaction.TypeSpawn:
+		return "spawn"
+	case transaction.TypeMultisigSpawn:
+		return "multisig_spawn"
+	case transaction.TypeSpend:
+		return "spend"
+	case transaction.TypeVaultSpawn:
+		return "vault_spawn"
+	case transaction.TypeMultisigSpend:
+		return "multisig_spend"
+	case transaction.TypeDrainVault:
+		return "vault_drain"
+	default:
+		return "unknown"
+	}
 }
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+
+	v0 "github.com/spacemeshos/explorer-backend/pkg/transactionparser/v0"
+)
+
+// Vault is the vesting schedule of a vault account, decoded from the transaction spawning it.
+type Vault struct {
+	Address             string `json:"address" bson:"address"`
+	Owner               string `json:"owner" bson:"owner"`
+	TotalAmount         uint64 `json:"totalAmount" bson:"totalAmount"`
+	InitialUnlockAmount uint64 `json:"initialUnlockAmount" bson:"initialUnlockAmount"`
+	VestingStart        uint32 `json:"vestingStart" bson:"vestingStart"` // first layer of the vesting
+	VestingEnd          uint32 `json:"vestingEnd" bson:"vestingEnd"`     // layer from which the total amount is vested
+	Layer               uint32 `json:"layer" bson:"layer"`               // layer of the spawn transaction
+	Tx                  string `json:"tx" bson:"tx"`
+}
+
+// VestingUnlock is the amount of the vaults vested by the end of an epoch.
+type VestingUnlock struct {
+	Epoch    uint32 `json:"epoch"`
+	Unlocked uint64 `json:"unlocked"` // vested during the epoch
+	Vested   uint64 `json:"vested"`
+	Locked   uint64 `json:"locked"`
+}
+
+// NewVault decodes the vault spawned by the transaction, nil if the transaction does not spawn a
+// vault. The principal of the spawn transaction is the vesting account owning the vault.
+func NewVault(tx *Transaction) (*Vault, error) {
+	if tx.SpawnedTemplate() != TemplateVault {
+		return nil, nil
+	}
+	spawn, err := v0.DecodeSpawnVault(tx.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode vault spawn: %w", err)
+	}
+	return &Vault{
+		Address:             spawn.GetReceiver().String(),
+		Owner:               spawn.Arguments.Owner.String(),
+		TotalAmount:         spawn.Arguments.TotalAmount,
+		InitialUnlockAmount: spawn.Arguments.InitialUnlockAmount,
+		VestingStart:        spawn.Arguments.VestingStart.Uint32(),
+		VestingEnd:          spawn.Arguments.VestingEnd.Uint32(),
+		Layer:               tx.Layer,
+		Tx:                  tx.Id,
+	}, nil
+}
+
+// Vested returns the amount of the vault vested at the layer, as the vault template computes it.
+func (v *Vault) Vested(layer uint32) uint64 {
+	schedule := vault.Vault{
+		TotalAmount:  v.TotalAmount,
+		VestingStart: types.LayerID(v.VestingStart),
+		VestingEnd:   types.LayerID(v.VestingEnd),
+	}
+	return schedule.Vested(types.LayerID(layer))
+}
+
+// VestingUnlocks returns the amounts of the vaults vested by the end of each epoch from the given
+// one, until the end of the last vesting.
+func VestingUnlocks(vaults []*Vault, from uint32, epochNumLayers uint32) []*VestingUnlock {
+	if epochNumLayers == 0 {
+		return nil
+	}
+	var total uint64
+	var end uint32
+	for _, v := range vaults {
+		total += v.TotalAmount
+		end = max(end, v.VestingEnd)
+	}
+	vestedAt := func(layer uint32) uint64 {
+		var vested uint64
+		for _, v := range vaults {
+			vested += v.Vested(layer)
+		}
+		return vested
+	}
+	previous := vestedAt(from * epochNumLayers)
+	var unlocks []*VestingUnlock
+	for epoch := from; epoch*epochNumLayers < end; epoch++ {
+		vested := vestedAt((epoch + 1) * epochNumLayers)
+		unlocks = append(unlocks, &VestingUnlock{
+			Epoch:    epoch,
+			Unlocked: vested - previous,
+			Vested:   vested,
+			Locked:   total - vested,
+		})
+		previous = vested
+	}
+	return unlocks
+}
+
+// VaultDrawdown is the amount drained from the vaults by the end of an epoch, the vested coins the
+// vesting accounts moved out of their vaults.
+type VaultDrawdown struct {
+	Epoch      uint32 `json:"epoch"`
+	Drains     int64  `json:"drains"`     // drain transactions of the epoch
+	Drained    uint64 `json:"drained"`    // drained during the epoch
+	Cumulative uint64 `json:"cumulative"` // drained by the end of the epoch
+	Vested     uint64 `json:"vested"`
+	Undrained  uint64 `json:"undrained"` // vested but still in the vaults
+	Remaining  uint64 `json:"remaining"` // total amount of the vaults not drained yet
+}
+
+// VaultDrawdowns returns the drawdown of the vaults by epoch, from the first one until the given
+// one. The drains are the successful drain transactions of the vaults.
+func VaultDrawdowns(vaults []*Vault, drains []*Transaction, to uint32, epochNumLayers uint32) []*VaultDrawdown {
+	if epochNumLayers == 0 {
+		return nil
+	}
+	var total uint64
+	for _, v := range vaults {
+		total += v.TotalAmount
+	}
+	series := make([]*VaultDrawdown, 0, to+1)
+	for epoch := uint32(0); epoch <= to; epoch++ {
+		series = append(series, &VaultDrawdown{Epoch: epoch})
+	}
+	for _, tx := range drains {
+		if epoch := tx.Layer / epochNumLayers; epoch <= to {
+			series[epoch].Drains++
+			series[epoch].Drained += tx.Amount
+		}
+	}
+	var drained uint64
+	for _, drawdown := range series {
+		drained += drawdown.Drained
+		drawdown.Cumulative = drained
+		for _, v := range vaults {
+			drawdown.Vested += v.Vested((drawdown.Epoch + 1) * epochNumLayers)
+		}
+		drawdown.Undrained = drawdown.Vested - min(drained, drawdown.Vested)
+		drawdown.Remaining = total - min(drained, total)
+	}
+	return series
+}
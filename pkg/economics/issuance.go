@@ -0,0 +1,130 @@
+// Package economics implements the subsidy issuance curve of the protocol, the same way as
+// github.com/spacemeshos/economics which the node uses to compute the layer rewards.
+package economics
+
+import (
+	"math/big"
+)
+
+const (
+	OneSmesh = 1_000_000_000 // smidge per smesh
+
+	OneYear       = 105120 // layers of 5 minutes in 365 days
+	TotalVaulted  = 150_000_000 * OneSmesh
+	TenYearTarget = 600_000_000 * OneSmesh
+	TotalIssuance = 2_400_000_000 * OneSmesh
+	// TotalSubsidy is the amount issued as layer rewards, the rest of the issuance is vaulted.
+	TotalSubsidy = TotalIssuance - TotalVaulted
+)
+
+// precision of the computations in bits, well above the 34 decimal digits of the node's library.
+const precision = 256
+
+var (
+	totalSubsidy = newFloat().SetUint64(TotalSubsidy)
+	// lambda is the decay rate of the subsidy, such that the ten year target is issued after ten
+	// years: exp(-lambda * (10*OneYear + 1)) = 1 - (TenYearTarget - TotalVaulted) / TotalSubsidy.
+	lambda = func() *big.Float {
+		frac := newFloat().Quo(newFloat().SetUint64(TenYearTarget-TotalVaulted), totalSubsidy)
+		decay := log(newFloat().Sub(newFloat().SetInt64(1), frac))
+		return decay.Quo(decay.Neg(decay), newFloat().SetUint64(10*OneYear+1))
+	}()
+)
+
+// EffectiveGenesis returns the last layer of the genesis, the subsidy is issued from the next layer.
+func EffectiveGenesis(epochNumLayers uint32) uint32 {
+	return 2*epochNumLayers - 1
+}
+
+// AccumulatedSubsidy returns the subsidy issued by the curve from the effective genesis until the
+// given number of layers after it, both included.
+func AccumulatedSubsidy(layersAfterGenesis uint32) uint64 {
+	x := newFloat().SetUint64(uint64(layersAfterGenesis) + 1)
+	x.Mul(x, lambda)
+	remaining := exp(x.Neg(x))
+	issued := newFloat().Sub(newFloat().SetInt64(1), remaining)
+	issued.Mul(issued, totalSubsidy)
+	// the node's library rounds the product to its 34 significant digits before truncating it
+	rounded, _, err := big.ParseFloat(issued.Text('e', 33), 10, precision, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	subsidy, _ := rounded.Uint64()
+	return subsidy
+}
+
+// LayerSubsidy returns the subsidy issued by the layer, zero until the effective genesis.
+func LayerSubsidy(layer, epochNumLayers uint32) uint64 {
+	return IssuedBetween(layer, layer, epochNumLayers)
+}
+
+// IssuedBetween returns the subsidy issued by the layers in the range [from, to].
+func IssuedBetween(from, to, epochNumLayers uint32) uint64 {
+	if to < from {
+		return 0
+	}
+	issued := IssuedBy(to, epochNumLayers)
+	if from > 0 {
+		issued -= IssuedBy(from-1, epochNumLayers)
+	}
+	return issued
+}
+
+// IssuedBy returns the subsidy issued by the end of the layer. The layer of the effective genesis
+// is not applied, so its share of the curve is never issued.
+func IssuedBy(layer, epochNumLayers uint32) uint64 {
+	genesis := EffectiveGenesis(epochNumLayers)
+	if layer <= genesis {
+		return 0
+	}
+	return AccumulatedSubsidy(layer-genesis) - AccumulatedSubsidy(0)
+}
+
+func newFloat() *big.Float {
+	return new(big.Float).SetPrec(precision)
+}
+
+// exp returns e^x, from its Taylor series on x reduced below 1/2 and squared back.
+func exp(x *big.Float) *big.Float {
+	half := big.NewFloat(0.5)
+	reduced := newFloat().Abs(x)
+	squarings := 0
+	for reduced.Cmp(half) > 0 {
+		reduced.Quo(reduced, big.NewFloat(2))
+		squarings++
+	}
+	sum := newFloat().SetInt64(1)
+	term := newFloat().SetInt64(1)
+	epsilon := newFloat().SetMantExp(big.NewFloat(1), -precision)
+	for n := int64(1); term.Cmp(epsilon) > 0; n++ {
+		term.Mul(term, reduced)
+		term.Quo(term, newFloat().SetInt64(n))
+		sum.Add(sum, term)
+	}
+	for ; squarings > 0; squarings-- {
+		sum.Mul(sum, sum)
+	}
+	if x.Sign() < 0 {
+		return sum.Quo(newFloat().SetInt64(1), sum)
+	}
+	return sum
+}
+
+// log returns the natural logarithm of x > 0, as 2 atanh((x-1)/(x+1)).
+func log(x *big.Float) *big.Float {
+	one := newFloat().SetInt64(1)
+	z := newFloat().Quo(newFloat().Sub(x, one), newFloat().Add(x, one))
+	z2 := newFloat().Mul(z, z)
+	sum := newFloat()
+	power := newFloat().Set(z)
+	epsilon := newFloat().SetMantExp(big.NewFloat(1), -precision)
+	for n := int64(1); ; n += 2 {
+		term := newFloat().Quo(power, newFloat().SetInt64(n))
+		if newFloat().Abs(term).Cmp(epsilon) < 0 {
+			break
+		}
+		sum.Add(sum, term)
+		power.Mul(power, z2)
+	}
+	return sum.Mul(sum, big.NewFloat(2))
+}
@@ -0,0 +1,54 @@
+package economics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the vectors of the tests of github.com/spacemeshos/economics
+func TestAccumulatedSubsidy(t *testing.T) {
+	for _, tc := range []struct {
+		layersAfterGenesis uint32
+		layer              uint64
+		total              uint64
+	}{
+		{0, 477618397593, 477618397593},
+		{1, 477618296206, 955236693799},
+		{10, 477617383730, 5253796797276},
+		{100, 477608259062, 48238946159315},
+		{1000, 477517021972, 478045275698980},
+		{10000, 476605609108, 4771595353929638},
+		{100000, 467586610967, 47258948463689552},
+		{1000000, 386270235450, 430330050824675272},
+		{10000000, 57171951699, 1980670207293862126},
+		{100000000, 289, 2249999998641054202},
+		{1000000000, 0, 2250000000000000000},
+	} {
+		total := AccumulatedSubsidy(tc.layersAfterGenesis)
+		require.Equal(t, tc.total, total, "layer %d", tc.layersAfterGenesis)
+		if tc.layersAfterGenesis > 0 {
+			require.Equal(t, tc.layer, total-AccumulatedSubsidy(tc.layersAfterGenesis-1), "layer %d", tc.layersAfterGenesis)
+		}
+	}
+	require.Equal(t, uint64(TenYearTarget-TotalVaulted), AccumulatedSubsidy(10*OneYear))
+	// the last smidge is never issued
+	require.Equal(t, uint64(TotalSubsidy-1), AccumulatedSubsidy(199069549))
+	require.Equal(t, uint64(TotalSubsidy-1), AccumulatedSubsidy(199069550))
+}
+
+func TestIssuedBetween(t *testing.T) {
+	const epochNumLayers = 4032
+	genesis := EffectiveGenesis(epochNumLayers)
+	require.Equal(t, uint64(0), IssuedBy(genesis, epochNumLayers))
+	require.Equal(t, uint64(0), LayerSubsidy(genesis, epochNumLayers))
+	require.Equal(t, uint64(477618296206), LayerSubsidy(genesis+1, epochNumLayers))
+	require.Equal(t, uint64(477618296206), IssuedBetween(0, genesis+1, epochNumLayers))
+
+	// the epochs add up to the issuance by their end
+	var issued uint64
+	for epoch := uint32(0); epoch < 5; epoch++ {
+		issued += IssuedBetween(epoch*epochNumLayers, (epoch+1)*epochNumLayers-1, epochNumLayers)
+	}
+	require.Equal(t, IssuedBy(5*epochNumLayers-1, epochNumLayers), issued)
+}
@@ -7,4 +7,10 @@ const (
 	TypeMultisigSpawn
 	// TypeSpend is type of the spend transaction.
 	TypeSpend
+	// TypeVaultSpawn is type of the vault spawn transaction.
+	TypeVaultSpawn
+	// TypeMultisigSpend is type of the spend transaction of a multisig or a vesting account.
+	TypeMultisigSpend
+	// TypeDrainVault is type of the drain vault transaction.
+	TypeDrainVault
 )
@@ -6,13 +6,17 @@ import (
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/genvm/core"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	sdkMultisig "github.com/spacemeshos/go-spacemesh/genvm/sdk/multisig"
+	sdkVesting "github.com/spacemeshos/go-spacemesh/genvm/sdk/vesting"
 	sdkWallet "github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
 	"github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
 	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/stretchr/testify/require"
 	"testing"
 
 	"github.com/spacemeshos/explorer-backend/pkg/transactionparser"
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
 )
 
 func TestSpawn(t *testing.T) {
@@ -51,6 +55,67 @@ func TestSpawn(t *testing.T) {
 	}
 }
 
+func TestSpawnVault(t *testing.T) {
+	signer, _ := signing.NewEdSigner()
+	vesting := types.GenerateAddress(generatePublicKey())
+	args := vault.SpawnArguments{
+		Owner:               vesting,
+		TotalAmount:         1000,
+		InitialUnlockAmount: 100,
+		VestingStart:        types.LayerID(20),
+		VestingEnd:          types.LayerID(40),
+	}
+	rawTx := sdkMultisig.Spawn(0, signer.PrivateKey(), vesting, vault.TemplateAddress, &args, core.Nonce(3),
+		sdk.WithGasPrice(2)).Raw()
+
+	decodedTx, err := transactionparser.Parse(scale.NewDecoder(bytes.NewReader(rawTx)), rawTx, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, uint8(transaction.TypeVaultSpawn), decodedTx.GetType())
+	require.Equal(t, uint64(2), decodedTx.GetGasPrice())
+	require.Equal(t, uint64(3), decodedTx.GetCounter())
+	require.Equal(t, vesting.String(), decodedTx.GetPrincipal().String())
+	require.Equal(t, core.ComputePrincipal(vault.TemplateAddress, &args).String(), decodedTx.GetReceiver().String())
+	require.Len(t, decodedTx.GetSignature(), 64)
+}
+
+func TestSpendMultisig(t *testing.T) {
+	signer, _ := signing.NewEdSigner()
+	principal := types.GenerateAddress(generatePublicKey())
+	to := types.GenerateAddress(generatePublicKey())
+	aggregator := sdkMultisig.Spend(0, signer.PrivateKey(), principal, to, 500, types.Nonce(2))
+	other, _ := signing.NewEdSigner()
+	aggregator.Add(*sdkMultisig.Spend(1, other.PrivateKey(), principal, to, 500, types.Nonce(2)).Part(1))
+	rawTx := aggregator.Raw()
+
+	decodedTx, err := transactionparser.Parse(scale.NewDecoder(bytes.NewReader(rawTx)), rawTx, 16)
+	require.NoError(t, err)
+
+	require.Equal(t, uint8(transaction.TypeMultisigSpend), decodedTx.GetType())
+	require.Equal(t, uint64(500), decodedTx.GetAmount())
+	require.Equal(t, uint64(2), decodedTx.GetCounter())
+	require.Equal(t, principal.String(), decodedTx.GetPrincipal().String())
+	require.Equal(t, to.String(), decodedTx.GetReceiver().String())
+	require.Len(t, decodedTx.GetSignature(), 2*64)
+}
+
+func TestDrainVault(t *testing.T) {
+	signer, _ := signing.NewEdSigner()
+	principal := types.GenerateAddress(generatePublicKey())
+	vaultAddress := types.GenerateAddress(generatePublicKey())
+	to := types.GenerateAddress(generatePublicKey())
+	rawTx := sdkVesting.DrainVault(0, signer.PrivateKey(), principal, vaultAddress, to, 700, types.Nonce(4)).Raw()
+
+	decodedTx, err := transactionparser.Parse(scale.NewDecoder(bytes.NewReader(rawTx)), rawTx, 17)
+	require.NoError(t, err)
+
+	require.Equal(t, uint8(transaction.TypeDrainVault), decodedTx.GetType())
+	require.Equal(t, uint64(700), decodedTx.GetAmount())
+	require.Equal(t, uint64(4), decodedTx.GetCounter())
+	require.Equal(t, principal.String(), decodedTx.GetPrincipal().String())
+	require.Equal(t, to.String(), decodedTx.GetReceiver().String())
+}
+
 func TestSpend(t *testing.T) {
 	table := []struct {
 		name     string
@@ -0,0 +1,255 @@
+package v0
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spacemeshos/address"
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
+)
+
+// methodDrainVault is the method of the vesting accounts draining one of their vaults.
+const methodDrainVault = 17
+
+// decodeParts decodes the signature parts of a multisig transaction. The parts are not length
+// prefixed, they are decoded up to the end of the transaction.
+func decodeParts(reader *bytes.Reader, dec *scale.Decoder) (multisig.Signatures, error) {
+	var signatures multisig.Signatures
+	for reader.Len() > 0 {
+		var part multisig.Part
+		if _, err := part.DecodeScale(dec); err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, part)
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("%w: multisig transaction is not signed", core.ErrMalformed)
+	}
+	return signatures, nil
+}
+
+// partsSignature returns the signature parts concatenated.
+func partsSignature(signatures multisig.Signatures) []byte {
+	result := make([]byte, 0, len(signatures)*len(core.Signature{}))
+	for i := range signatures {
+		result = append(result, signatures[i].Sig[:]...)
+	}
+	return result
+}
+
+// SpendMultisigTransaction coin transfer transaction of a multisig or a vesting account.
+type SpendMultisigTransaction struct {
+	Type       uint8
+	Principal  address.Address
+	Method     uint8
+	Payload    SpendPayload
+	Signatures multisig.Signatures
+}
+
+// DecodeScale implements scale codec interface. The signature parts are decoded by
+// DecodeSpendMultisig, see decodeParts.
+func (t *SpendMultisigTransaction) DecodeScale(dec *scale.Decoder) (total int, err error) {
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Type = uint8(field)
+	}
+	{
+		n, err := scale.DecodeByteArray(dec, t.Principal[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Method = uint8(field)
+	}
+	{
+		n, err := t.Payload.DecodeScale(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// DecodeSpendMultisig decodes a spend followed by the signature parts of the principal.
+func DecodeSpendMultisig(rawTx []byte) (*SpendMultisigTransaction, error) {
+	reader := bytes.NewReader(rawTx)
+	dec := scale.NewDecoder(reader)
+	var tx SpendMultisigTransaction
+	if _, err := tx.DecodeScale(dec); err != nil {
+		return nil, err
+	}
+	signatures, err := decodeParts(reader, dec)
+	if err != nil {
+		return nil, err
+	}
+	tx.Signatures = signatures
+	return &tx, nil
+}
+
+// GetType returns transaction type.
+func (t *SpendMultisigTransaction) GetType() uint8 {
+	return transaction.TypeMultisigSpend
+}
+
+// GetAmount returns the amount of the transaction.
+func (t *SpendMultisigTransaction) GetAmount() uint64 {
+	return t.Payload.Arguments.Amount
+}
+
+// GetCounter returns the counter of the transaction.
+func (t *SpendMultisigTransaction) GetCounter() uint64 {
+	return t.Payload.Nonce
+}
+
+// GetReceiver returns receiver address.
+func (t *SpendMultisigTransaction) GetReceiver() address.Address {
+	return t.Payload.Arguments.Destination
+}
+
+// GetGasPrice returns gas price of the transaction.
+func (t *SpendMultisigTransaction) GetGasPrice() uint64 {
+	return t.Payload.GasPrice
+}
+
+// GetPrincipal return address which spend gas.
+func (t *SpendMultisigTransaction) GetPrincipal() address.Address {
+	return t.Principal
+}
+
+// GetPublicKeys returns nil, the keys of the multisig account are not part of its spends.
+func (t *SpendMultisigTransaction) GetPublicKeys() [][]byte {
+	return nil
+}
+
+// GetSignature returns the signature parts of the transaction, concatenated.
+func (t *SpendMultisigTransaction) GetSignature() []byte {
+	return partsSignature(t.Signatures)
+}
+
+// DrainVaultTransaction transfers the vested coins of a vault, it is sent by the vesting account
+// owning the vault.
+type DrainVaultTransaction struct {
+	Type       uint8
+	Principal  address.Address
+	Method     uint8
+	Payload    core.Payload
+	Arguments  vesting.DrainVaultArguments
+	Signatures multisig.Signatures
+}
+
+// DecodeScale implements scale codec interface. The signature parts are decoded by
+// DecodeDrainVault, see decodeParts.
+func (t *DrainVaultTransaction) DecodeScale(dec *scale.Decoder) (total int, err error) {
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Type = uint8(field)
+	}
+	{
+		n, err := scale.DecodeByteArray(dec, t.Principal[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Method = uint8(field)
+	}
+	{
+		n, err := t.Payload.DecodeScale(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := t.Arguments.DecodeScale(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// DecodeDrainVault decodes the drain of a vault followed by the signature parts of the vesting
+// account.
+func DecodeDrainVault(rawTx []byte) (*DrainVaultTransaction, error) {
+	reader := bytes.NewReader(rawTx)
+	dec := scale.NewDecoder(reader)
+	var tx DrainVaultTransaction
+	if _, err := tx.DecodeScale(dec); err != nil {
+		return nil, err
+	}
+	signatures, err := decodeParts(reader, dec)
+	if err != nil {
+		return nil, err
+	}
+	tx.Signatures = signatures
+	return &tx, nil
+}
+
+// GetType returns transaction type.
+func (t *DrainVaultTransaction) GetType() uint8 {
+	return transaction.TypeDrainVault
+}
+
+// GetAmount returns the amount drained from the vault.
+func (t *DrainVaultTransaction) GetAmount() uint64 {
+	return t.Arguments.Amount
+}
+
+// GetCounter returns the counter of the transaction.
+func (t *DrainVaultTransaction) GetCounter() uint64 {
+	return t.Payload.Nonce
+}
+
+// GetReceiver returns the address receiving the drained coins.
+func (t *DrainVaultTransaction) GetReceiver() address.Address {
+	return address.Address(t.Arguments.Destination)
+}
+
+// GetGasPrice returns gas price of the transaction.
+func (t *DrainVaultTransaction) GetGasPrice() uint64 {
+	return t.Payload.GasPrice
+}
+
+// GetPrincipal return address which spend gas.
+func (t *DrainVaultTransaction) GetPrincipal() address.Address {
+	return t.Principal
+}
+
+// GetPublicKeys returns nil, the keys of the vesting account are not part of its drains.
+func (t *DrainVaultTransaction) GetPublicKeys() [][]byte {
+	return nil
+}
+
+// GetSignature returns the signature parts of the transaction, concatenated.
+func (t *DrainVaultTransaction) GetSignature() []byte {
+	return partsSignature(t.Signatures)
+}
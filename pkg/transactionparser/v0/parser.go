@@ -15,10 +15,12 @@ const (
 )
 
 // ParseTransaction parses a transaction encoded in version 0.
-// possible two types of transaction:
+// possible types of transaction:
 // 1. spawn transaction - `&sdk.TxVersion, &principal, &sdk.MethodSpawn, &wallet.TemplateAddress, &wallet.SpawnPayload`
 // 2. spend transaction - `&sdk.TxVersion, &principal, &sdk.MethodSpend, &wallet.SpendPayload.
-// every transaction can be multisig also.
+// 3. vault spawn transaction - `&sdk.TxVersion, &principal, &sdk.MethodSpawn, &vault.TemplateAddress, &payload, &vault.SpawnArguments`.
+// 4. drain vault transaction - `&sdk.TxVersion, &principal, &vesting.MethodDrainVault, &payload, &vesting.DrainVaultArguments`.
+// every transaction can be multisig also, the signature parts of multisig transactions follow the payload.
 func ParseTransaction(rawTx []byte, method uint32) (transaction.DecodedTransactioner, error) {
 	switch method {
 	case methodSpawn:
@@ -30,11 +32,21 @@ func ParseTransaction(rawTx []byte, method uint32) (transaction.DecodedTransacti
 		if err := codec.Decode(rawTx, &spawnTx); err == nil {
 			return &spawnTx, nil
 		}
+		if spawnVaultTx, err := DecodeSpawnVault(rawTx); err == nil {
+			return spawnVaultTx, nil
+		}
 	case methodSend:
 		var spendTx SpendTransaction
 		if err := codec.Decode(rawTx, &spendTx); err == nil {
 			return &spendTx, nil
 		}
+		if spendMultisigTx, err := DecodeSpendMultisig(rawTx); err == nil {
+			return spendMultisigTx, nil
+		}
+	case methodDrainVault:
+		if drainVaultTx, err := DecodeDrainVault(rawTx); err == nil {
+			return drainVaultTx, nil
+		}
 	default:
 		return nil, fmt.Errorf("%w: unsupported method %d", core.ErrMalformed, method)
 	}
@@ -0,0 +1,135 @@
+package v0
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spacemeshos/address"
+	"github.com/spacemeshos/go-scale"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/multisig"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
+)
+
+// SpawnVaultTransaction spawns a vault, it is sent by the vesting multisig account owning the vault.
+type SpawnVaultTransaction struct {
+	Type       uint8
+	Principal  address.Address
+	Method     uint8
+	Template   address.Address
+	Payload    core.Payload
+	Arguments  vault.SpawnArguments
+	Signatures multisig.Signatures
+}
+
+// DecodeScale implements scale codec interface. The signature parts are decoded by DecodeSpawnVault,
+// see decodeParts.
+func (t *SpawnVaultTransaction) DecodeScale(dec *scale.Decoder) (total int, err error) {
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Type = uint8(field)
+	}
+	{
+		n, err := scale.DecodeByteArray(dec, t.Principal[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		field, n, err := scale.DecodeCompact8(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		t.Method = uint8(field)
+	}
+	{
+		n, err := scale.DecodeByteArray(dec, t.Template[:])
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := t.Payload.DecodeScale(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	{
+		n, err := t.Arguments.DecodeScale(dec)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// DecodeSpawnVault decodes the spawn of a vault, followed by the signature parts of the vesting account.
+func DecodeSpawnVault(rawTx []byte) (*SpawnVaultTransaction, error) {
+	reader := bytes.NewReader(rawTx)
+	dec := scale.NewDecoder(reader)
+	var tx SpawnVaultTransaction
+	if _, err := tx.DecodeScale(dec); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tx.Template[:], vault.TemplateAddress[:]) {
+		return nil, fmt.Errorf("%w: not a vault spawn", core.ErrMalformed)
+	}
+	signatures, err := decodeParts(reader, dec)
+	if err != nil {
+		return nil, err
+	}
+	tx.Signatures = signatures
+	return &tx, nil
+}
+
+// GetType returns type of the transaction.
+func (t *SpawnVaultTransaction) GetType() uint8 {
+	return transaction.TypeVaultSpawn
+}
+
+// GetAmount returns amount of the transaction. Always zero for spawn transaction.
+func (t *SpawnVaultTransaction) GetAmount() uint64 {
+	return 0
+}
+
+// GetCounter returns counter of the transaction.
+func (t *SpawnVaultTransaction) GetCounter() uint64 {
+	return t.Payload.Nonce
+}
+
+// GetReceiver returns the address of the spawned vault.
+func (t *SpawnVaultTransaction) GetReceiver() address.Address {
+	return address.Address(core.ComputePrincipal(vault.TemplateAddress, &t.Arguments))
+}
+
+// GetGasPrice returns gas price of the transaction.
+func (t *SpawnVaultTransaction) GetGasPrice() uint64 {
+	return t.Payload.GasPrice
+}
+
+// GetPrincipal returns the principal address who pay for gas for this transaction.
+func (t *SpawnVaultTransaction) GetPrincipal() address.Address {
+	return t.Principal
+}
+
+// GetPublicKeys returns public keys of the transaction. The keys of the vesting account are not
+// part of the vault spawn.
+func (t *SpawnVaultTransaction) GetPublicKeys() [][]byte {
+	return nil
+}
+
+// GetSignature returns the signature parts of the transaction, concatenated.
+func (t *SpawnVaultTransaction) GetSignature() []byte {
+	return partsSignature(t.Signatures)
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+var _ StorageWriter = (*Storage)(nil)
+
+// StorageWriter is the storage the collector writes to. It is implemented by the mongo Storage
+// and by the postgres backend, and satisfies collector.Listener.
+type StorageWriter interface {
+	OnNetworkInfo(genesisId string, genesisTime uint64, epochNumLayers uint32, maxTransactionsPerSecond uint64, layerDuration uint64, postUnitSize uint64)
+	OnNodeStatus(connectedPeers uint64, isSynced bool, syncedLayer uint32, topLayer uint32, verifiedLayer uint32)
+	OnLayer(layer *pb.Layer)
+	OnTransactionsReceived(received map[string]uint32)
+	OnAccounts(accounts []*types.Account)
+	OnReward(reward *pb.Reward)
+	OnRewards(rewards []*pb.Reward)
+	OnCertificates(certs []*model.BlockCertificate)
+	OnBallots(ballots []*model.Ballot)
+	OnActiveSet(epoch uint32, size uint32)
+	OnBeacon(epoch uint32, beacon string)
+	OnMalfeasanceProof(proof *pb.MalfeasanceProof)
+	OnTransactionResult(res *pb.TransactionResult, state *pb.TransactionState)
+	GetLastLayer(parent context.Context) (uint32, error)
+	GetLayersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error)
+	GetLayerByNumber(parent context.Context, layerNumber uint32) (*model.Layer, error)
+	LayersInQueue() int
+	IsLayerInQueue(layer *pb.Layer) bool
+	GetEpochNumLayers() uint32
+	GetTransactions(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]model.Transaction, error)
+	UpdateTransactionState(parent context.Context, id string, state int32) error
+	UpdateEpochStats(layer uint32)
+	OnActivation(atx *types.VerifiedActivationTx)
+	GetLastActivationReceived() int64
+	GetLayerHashCheckpoint(parent context.Context) (uint32, error)
+	SetLayerHashCheckpoint(parent context.Context, layer uint32) error
+	RecalculateEpochStats()
+	OnActivations(atxs []*model.Activation)
+	RedecodeTransactions(parent context.Context) error
+	UpsertLabels(parent context.Context, labels []*model.Label) error
+	UpsertSmesherLocations(parent context.Context, locations []*model.SmesherLocation) error
+	UpsertPrice(parent context.Context, price *model.Price) error
+
+	SetAccountUpdater(updater AccountUpdaterService)
+	SetWatchedAccounts(addresses []string)
+	AddSink(snk sink.Sink)
+	SetCache(c *cache.Cache)
+	SetRetention(policies []RetentionPolicy)
+	SetTimeouts(t Timeouts)
+	SetRetryPolicy(p RetryPolicy)
+	SetArchive(enabled bool)
+	Close()
+}
+
+// SetAccountUpdater sets the service used to refresh account balances.
+func (s *Storage) SetAccountUpdater(updater AccountUpdaterService) {
+	s.AccountUpdater = updater
+}
+
+// SetCache enables the invalidation of the API documents cache on writes.
+func (s *Storage) SetCache(c *cache.Cache) {
+	s.cache = c
+}
+
+// invalidate removes the cached documents, failures only delay the refresh until the keys expire.
+func (s *Storage) invalidate(keys ...string) {
+	if s.cache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.cache.Invalidate(ctx, keys...); err != nil {
+		logging.Error("invalidate cache", err, zap.Strings("keys", keys))
+	}
+}
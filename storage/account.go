@@ -3,97 +3,68 @@ package storage
 import (
 	"context"
 	"errors"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/explorer-backend/utils"
 )
 
-func (s *Storage) InitAccountsStorage(ctx context.Context) error {
-	if _, err := s.db.Collection("accounts").Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "address", Value: 1}},
-		Options: options.Index().SetName("addressIndex").SetUnique(true)}); err != nil {
-		return err
-	}
+// ErrStaleAccount is returned by the compare-and-set of an account balance if the account was
+// written after its version was read.
+var ErrStaleAccount = errors.New("stale account version")
 
-	if _, err := s.db.Collection("accounts").Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "created", Value: 1}},
-		Options: options.Index().SetName("createIndex").SetUnique(false)}); err != nil {
-		return err
-	}
+// AccountUpdateAttempts bounds the compare-and-set attempts of an account balance refresh.
+const AccountUpdateAttempts = 3
 
-	if _, err := s.db.Collection("accounts").Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "layer", Value: -1}},
-		Options: options.Index().SetName("modifiedIndex").SetUnique(false)}); err != nil {
+func (s *Storage) InitAccountsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, "accounts"); err != nil {
 		return err
 	}
-
-	return nil
+	return applyValidator(ctx, s.db, "accounts")
 }
 
 func (s *Storage) GetAccount(parent context.Context, query *bson.D) (*model.Account, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("accounts").Find(ctx, query)
-	if err != nil {
-		log.Info("GetAccount: %v", err)
+	account := &model.Account{}
+	if err := s.findOne(parent, "accounts", query, account); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetAccount: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetAccount", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetAccount: Empty result", err)
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	account := &model.Account{
-		Address: utils.GetAsString(doc.Lookup("address")),
-		Balance: utils.GetAsUInt64(doc.Lookup("balance")),
-		Counter: utils.GetAsUInt64(doc.Lookup("counter")),
-	}
 	return account, nil
 }
 
 func (s *Storage) GetAccountsCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("accounts").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(parent, "accounts", query, opts...)
 	if err != nil {
-		log.Info("GetAccountsCount: %v", err)
+		logging.Error("GetAccountsCount", err)
 		return 0
 	}
 	return count
 }
 
 func (s *Storage) GetAccounts(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("accounts").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetAccounts: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "accounts", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetAccounts: %v", err)
+		logging.Error("GetAccounts", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
-		log.Info("GetAccounts: Empty result")
+		logging.Info("GetAccounts: Empty result")
 		return nil, nil
 	}
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) AddAccount(parent context.Context, layer uint32, address string, balance uint64) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-
+// UpsertAccountQuery returns the upsert of the account seen at the layer, the account keeps the
+// layer it was first seen at.
+func (s *Storage) UpsertAccountQuery(layer uint32, address string, balance uint64) *mongo.UpdateOneModel {
 	acc := bson.D{
 		{Key: "$set",
 			Value: bson.D{
@@ -101,89 +72,118 @@ func (s *Storage) AddAccount(parent context.Context, layer uint32, address strin
 				{Key: "layer", Value: layer},
 				{Key: "balance", Value: balance},
 				{Key: "counter", Value: uint64(0)},
+				// 0 is the genesis layer, so only a missing layer is replaced
 				{Key: "created",
-					Value: bson.D{{Key: "$cond", Value: bson.D{{Key: "if",
-						Value: bson.D{{Key: "$eq", Value: bson.A{0, "$created"}}}},
-						{Key: "then", Value: layer},
-						{Key: "else", Value: "$created"},
+					Value: bson.D{{Key: "$min", Value: bson.A{
+						bson.D{{Key: "$ifNull", Value: bson.A{"$created", layer}}},
+						layer,
 					}}},
 				},
+				{Key: "version", Value: bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$version", 0}}}, 1}}}},
 			},
 		},
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: address}}, bson.A{acc}, opts)
-	if err != nil {
-		log.Info("AddAccount: %v", err)
-	}
-	return nil
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "address", Value: address}}).
+		SetUpdate(bson.A{acc}).
+		SetUpsert(true)
 }
 
-func (s *Storage) AddAccountQuery(layer uint32, address string, balance uint64) *mongo.UpdateOneModel {
-	filter := bson.D{{Key: "address", Value: address}}
-	acc := bson.D{
-		{Key: "$set",
-			Value: bson.D{
-				{Key: "address", Value: address},
-				{Key: "layer", Value: layer},
-				{Key: "balance", Value: balance},
-				{Key: "counter", Value: uint64(0)},
-				{Key: "created",
-					Value: bson.D{{Key: "$cond", Value: bson.D{{Key: "if",
-						Value: bson.D{{Key: "$eq", Value: bson.A{0, "$created"}}}},
-						{Key: "then", Value: layer},
-						{Key: "else", Value: "$created"},
-					}}},
-				},
-			},
-		},
-	}
-
-	accountModel := mongo.NewUpdateOneModel()
-	accountModel.SetFilter(filter)
-	accountModel.SetUpdate(acc)
-	accountModel.SetUpsert(true)
-
-	return accountModel
+// AccountTemplateQuery sets the template the account was spawned with.
+func (s *Storage) AccountTemplateQuery(address string, template string) *mongo.UpdateOneModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "address", Value: address}}).
+		SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "template", Value: template}}}}).
+		SetUpsert(true)
 }
 
-func (s *Storage) SaveAccount(parent context.Context, layer uint32, in *model.Account) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertAccount stores the account seen at the layer with its balance and counter, and its template
+// if it is known. The account keeps the first layer it was seen at.
+func (s *Storage) UpsertAccount(parent context.Context, layer uint32, in *model.Account) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
-	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: in.Address}}, bson.D{{
-		Key: "$set",
-		Value: bson.D{
-			{Key: "address", Value: in.Address},
-			{Key: "created", Value: layer},
-			{Key: "layer", Value: layer},
-			{Key: "balance", Value: in.Balance},
-			{Key: "counter", Value: in.Counter},
-		},
+	fields := bson.D{
+		{Key: "address", Value: in.Address},
+		{Key: "layer", Value: layer},
+		{Key: "balance", Value: in.Balance},
+		{Key: "counter", Value: in.Counter},
+	}
+	if in.Template != "" {
+		fields = append(fields, bson.E{Key: "template", Value: in.Template})
+	}
+	res, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: in.Address}}, bson.D{{
+		Key:   "$set",
+		Value: fields,
+	}, {
+		Key:   "$min",
+		Value: bson.D{{Key: "created", Value: layer}},
+	}, {
+		Key:   "$inc",
+		Value: bson.D{{Key: "version", Value: 1}},
 	}}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveAccount: %v", err)
+		logging.Error("UpsertAccount", err)
+	} else if res.UpsertedCount > 0 {
+		s.incAccountsStats(parent, []uint32{layer})
 	}
 	return nil
 }
 
-func (s *Storage) UpdateAccount(parent context.Context, address string, balance uint64, counter uint64) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// GetAccountVersion returns the version of the account, 0 if the account is unknown or was written
+// before the versioning.
+func (s *Storage) GetAccountVersion(parent context.Context, address string) (uint64, error) {
+	var account model.Account
+	err := s.findOne(parent, "accounts", bson.D{{Key: "address", Value: address}}, &account,
+		options.FindOne().SetProjection(bson.D{{Key: "version", Value: 1}}))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	return account.Version, err
+}
+
+// UpdateAccount sets the balance and the counter of the account if it is still at the version,
+// read before the state was requested from the node, and increments the version. It returns
+// ErrStaleAccount if the account was written since, e.g. by the accounts stream. An unknown account
+// at version 0 is inserted.
+func (s *Storage) UpdateAccount(parent context.Context, address string, version uint64, balance uint64, counter uint64) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
-	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: address}}, bson.D{
+	filter := bson.D{{Key: "address", Value: address}, {Key: "version", Value: version}}
+	if version == 0 {
+		filter[1].Value = bson.D{{Key: "$in", Value: bson.A{nil, 0}}}
+	}
+	res, err := s.db.Collection("accounts").UpdateOne(ctx, filter, bson.D{
 		{Key: "$set", Value: bson.D{
 			{Key: "balance", Value: balance},
 			{Key: "counter", Value: counter},
 		}},
-	}, options.Update().SetUpsert(true))
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	})
 	if err != nil {
-		log.Info("UpdateAccount: %v", err)
+		return err
 	}
-	return nil
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	if version > 0 {
+		return ErrStaleAccount
+	}
+	_, err = s.db.Collection("accounts").InsertOne(ctx, bson.D{
+		{Key: "address", Value: address},
+		{Key: "balance", Value: balance},
+		{Key: "counter", Value: counter},
+		{Key: "version", Value: 1},
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// the account was inserted since it was found unknown
+		return ErrStaleAccount
+	}
+	return err
 }
 
 func (s *Storage) AddAccountSent(parent context.Context, layer uint32, address string, amount uint64, fee uint64) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: address}}, bson.D{
 		{Key: "$set", Value: bson.D{
@@ -191,13 +191,13 @@ func (s *Storage) AddAccountSent(parent context.Context, layer uint32, address s
 		}},
 	})
 	if err != nil {
-		log.Info("AddAccountSent: update account touch error %v", err)
+		logging.Error("AddAccountSent: update account touch", err)
 	}
 	return nil
 }
 
 func (s *Storage) AddAccountReceived(parent context.Context, layer uint32, address string, amount uint64) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: address}}, bson.D{
 		{Key: "$set", Value: bson.D{
@@ -205,13 +205,13 @@ func (s *Storage) AddAccountReceived(parent context.Context, layer uint32, addre
 		}},
 	})
 	if err != nil {
-		log.Info("AddAccountReceived: update account touch error %v", err)
+		logging.Error("AddAccountReceived: update account touch", err)
 	}
 	return nil
 }
 
 func (s *Storage) AddAccountReward(parent context.Context, layer uint32, address string, reward uint64, fee uint64) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("accounts").UpdateOne(ctx, bson.D{{Key: "address", Value: address}}, bson.D{
 		{Key: "$set", Value: bson.D{
@@ -219,7 +219,7 @@ func (s *Storage) AddAccountReward(parent context.Context, layer uint32, address
 		}},
 	})
 	if err != nil {
-		log.Info("AddAccountReward: update account touch error %v", err)
+		logging.Error("AddAccountReward: update account touch", err)
 	}
 	return nil
 }
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// accountSnapshotsCollection holds the balances of all the accounts every few layers, one document
+// per account and snapshot layer, see SetAccountSnapshots.
+const accountSnapshotsCollection = "account_snapshots"
+
+// AccountSnapshotInterval is the period of the account snapshot runs.
+const AccountSnapshotInterval = 10 * time.Minute
+
+func (s *Storage) initAccountSnapshotsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, accountSnapshotsCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, accountSnapshotsCollection)
+}
+
+// AccountSnapshotLayers returns the snapshot layers following the last one, every given number of
+// layers. A layer is snapshotted once a full interval of layers is confirmed after it, so that the
+// balance changes requested for its confirmation are written.
+func AccountSnapshotLayers(every uint32, last uint32, lastConfirmed uint32) []uint32 {
+	if every == 0 || lastConfirmed < every {
+		return nil
+	}
+	var layers []uint32
+	for layer := last - last%every + every; layer <= lastConfirmed-every; layer += every {
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// SetAccountSnapshots starts snapshotting the balances of all the accounts every given number of
+// layers, checked every AccountSnapshotInterval. A snapshot bounds the balance changes read to
+// answer the balance of an account at a layer, see GetBalanceAt.
+func (s *Storage) SetAccountSnapshots(every uint32) {
+	if every == 0 {
+		return
+	}
+	s.snapshotsDone = make(chan struct{})
+	go s.runAccountSnapshots(every)
+}
+
+func (s *Storage) runAccountSnapshots(every uint32) {
+	ticker := time.NewTicker(AccountSnapshotInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.takeAccountSnapshots(context.Background(), every); err != nil {
+			logging.Error("account snapshots", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.snapshotsDone:
+			return
+		}
+	}
+}
+
+// takeAccountSnapshots takes the snapshots missing since the last one, in layer order.
+func (s *Storage) takeAccountSnapshots(ctx context.Context, every uint32) error {
+	last, found, err := s.lastAccountSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	for _, layer := range AccountSnapshotLayers(every, last, s.NetworkInfo.LastConfirmedLayer) {
+		n, err := s.snapshotAccounts(ctx, last, found, layer)
+		if err != nil {
+			return fmt.Errorf("snapshot of layer %d: %w", layer, err)
+		}
+		logging.Info("account snapshot", logging.Layer(layer), zap.Int64("accounts", n))
+		last, found = layer, true
+	}
+	return nil
+}
+
+// lastAccountSnapshot returns the layer of the last snapshot, or false if no snapshot was taken.
+func (s *Storage) lastAccountSnapshot(parent context.Context) (uint32, bool, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	var snapshot model.AccountSnapshot
+	err := s.db.Collection(accountSnapshotsCollection).FindOne(ctx, bson.D{},
+		options.FindOne().SetSort(bson.D{{Key: "layer", Value: -1}}).SetProjection(bson.D{{Key: "layer", Value: 1}})).Decode(&snapshot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error get last account snapshot: %w", err)
+	}
+	return snapshot.Layer, true, nil
+}
+
+// snapshotAccounts writes the snapshot of the layer from the previous snapshot and the balance
+// changes since, or from all the balance changes if there is no previous snapshot. Taking a
+// snapshot again replaces it.
+func (s *Storage) snapshotAccounts(parent context.Context, previous uint32, hasPrevious bool, layer uint32) (int64, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	changed := bson.D{{Key: "$lte", Value: layer}}
+	if hasPrevious {
+		changed = append(changed, bson.E{Key: "$gt", Value: previous})
+	}
+	project := bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "address", Value: 1}, {Key: "layer", Value: 1}, {Key: "balance", Value: 1}}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "layer", Value: changed}}}},
+		project,
+	}
+	if hasPrevious {
+		pipeline = append(pipeline, bson.D{{Key: "$unionWith", Value: bson.D{
+			{Key: "coll", Value: s.db.CollectionName(accountSnapshotsCollection)},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "layer", Value: previous}}}},
+				project,
+			}},
+		}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: 1}}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$address"},
+			{Key: "balance", Value: bson.D{{Key: "$last", Value: "$balance"}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "address", Value: "$_id"},
+			{Key: "layer", Value: bson.D{{Key: "$literal", Value: layer}}},
+			{Key: "balance", Value: 1},
+		}}},
+		bson.D{{Key: "$merge", Value: bson.D{
+			{Key: "into", Value: s.db.CollectionName(accountSnapshotsCollection)},
+			{Key: "on", Value: bson.A{"address", "layer"}},
+			{Key: "whenMatched", Value: "replace"},
+			{Key: "whenNotMatched", Value: "insert"},
+		}}},
+	)
+	if _, err := s.db.Collection(balanceChangesCollection).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true)); err != nil {
+		return 0, fmt.Errorf("error snapshot accounts: %w", err)
+	}
+	return s.db.Collection(accountSnapshotsCollection).CountDocuments(ctx, bson.D{{Key: "layer", Value: layer}})
+}
+
+// getAccountSnapshot returns the last snapshot of the account up to the layer, nil if there is none.
+func (s *Storage) getAccountSnapshot(ctx context.Context, address string, layer uint32) (*model.AccountSnapshot, error) {
+	var snapshot model.AccountSnapshot
+	err := s.db.Collection(accountSnapshotsCollection).FindOne(ctx,
+		bson.D{{Key: "address", Value: address}, {Key: "layer", Value: bson.D{{Key: "$lte", Value: layer}}}},
+		options.FindOne().SetSort(bson.D{{Key: "layer", Value: -1}})).Decode(&snapshot)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestAccountSnapshotLayers(t *testing.T) {
+	require.Empty(t, AccountSnapshotLayers(0, 0, 100))
+	require.Empty(t, AccountSnapshotLayers(10, 0, 19))
+	require.Equal(t, []uint32{10}, AccountSnapshotLayers(10, 0, 20))
+	require.Equal(t, []uint32{20, 30}, AccountSnapshotLayers(10, 10, 45))
+	require.Empty(t, AccountSnapshotLayers(10, 30, 45))
+}
+
+func TestBalanceAt(t *testing.T) {
+	snapshot := &model.AccountSnapshot{Address: "sm1", Layer: 10, Balance: 50}
+	require.EqualValues(t, 0, model.BalanceAt("sm1", 12, nil, nil).Balance)
+	require.EqualValues(t, 50, model.BalanceAt("sm1", 12, snapshot, nil).Balance)
+	require.EqualValues(t, 70, model.BalanceAt("sm1", 12, snapshot, &model.BalanceChange{Layer: 11, Balance: 70}).Balance)
+	require.EqualValues(t, 70, model.BalanceAt("sm1", 12, nil, &model.BalanceChange{Layer: 3, Balance: 70}).Balance)
+	// the change of the snapshot layer is the source of the snapshot
+	require.EqualValues(t, 60, model.BalanceAt("sm1", 12, snapshot, &model.BalanceChange{Layer: 10, Balance: 60}).Balance)
+}
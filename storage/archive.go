@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// archiveCollection holds the raw node responses when the archive is enabled, see SetArchive.
+const archiveCollection = "archive"
+
+// Kinds of the archived node responses, each entry is keyed by the id of the entity.
+const (
+	ArchiveLayer             = "layer"       // pb.Layer by layer number
+	ArchiveReward            = "reward"      // pb.Reward by `<smesher>-<layer>`
+	ArchiveTransactionResult = "txresult"    // pb.TransactionResult by transaction id
+	ArchiveMalfeasanceProof  = "malfeasance" // pb.MalfeasanceProof by `<smesher>-<layer>`
+)
+
+// ArchiveEntry is a raw node response, serialized in the protobuf wire format.
+type ArchiveEntry struct {
+	Kind     string `bson:"kind"`
+	Id       string `bson:"id"` //nolint will fix it later
+	Data     []byte `bson:"data"`
+	Received int64  `bson:"received"`
+}
+
+// NewArchiveMessage returns an empty message of the kind, to unmarshal the archived data into.
+func NewArchiveMessage(kind string) (proto.Message, error) {
+	switch kind {
+	case ArchiveLayer:
+		return &pb.Layer{}, nil
+	case ArchiveReward:
+		return &pb.Reward{}, nil
+	case ArchiveTransactionResult:
+		return &pb.TransactionResult{}, nil
+	case ArchiveMalfeasanceProof:
+		return &pb.MalfeasanceProof{}, nil
+	}
+	return nil, fmt.Errorf("unknown archive kind `%s`", kind)
+}
+
+func (s *Storage) initArchiveStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, archiveCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, archiveCollection)
+}
+
+// SetArchive enables the archive of the raw node responses, so that they can be audited and
+// decoded again without access to the node.
+func (s *Storage) SetArchive(enabled bool) {
+	s.archiveEnabled = enabled
+	if enabled {
+		logging.Info("archive of the node responses enabled")
+	}
+}
+
+// archive stores the raw messages with their ids, the last response of an entity replaces the
+// previous ones. Failures are logged, the archive never blocks the ingestion.
+func (s *Storage) archive(kind string, ids []string, msgs []proto.Message) {
+	if !s.archiveEnabled || len(msgs) == 0 {
+		return
+	}
+	received := time.Now().Unix()
+	models := make([]mongo.WriteModel, 0, len(msgs))
+	for i, msg := range msgs {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			logging.Error(fmt.Sprintf("archive %s `%s`", kind, ids[i]), err)
+			continue
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "kind", Value: kind}, {Key: "id", Value: ids[i]}}).
+			SetReplacement(ArchiveEntry{Kind: kind, Id: ids[i], Data: data, Received: received}).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return
+	}
+	ctx, cancel := s.bulkContext(context.Background())
+	defer cancel()
+	if _, err := s.db.Collection(archiveCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		logging.Error(fmt.Sprintf("archive %s", kind), err)
+	}
+}
+
+// GetArchive returns the archived response of the entity decoded into its message, nil if it is
+// not archived.
+func (s *Storage) GetArchive(parent context.Context, kind, id string) (proto.Message, *ArchiveEntry, error) {
+	msg, err := NewArchiveMessage(kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	var entry ArchiveEntry
+	err = s.db.Collection(archiveCollection).FindOne(ctx, bson.D{{Key: "kind", Value: kind}, {Key: "id", Value: id}}).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("error get archive %s `%s`: %w", kind, id, err)
+	}
+	if err := proto.Unmarshal(entry.Data, msg); err != nil {
+		return nil, nil, fmt.Errorf("error decode archive %s `%s`: %w", kind, id, err)
+	}
+	return msg, &entry, nil
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestArchiveMessage(t *testing.T) {
+	data, err := proto.Marshal(&pb.Reward{Layer: &pb.LayerNumber{Number: 42}, Total: &pb.Amount{Value: 100}})
+	require.NoError(t, err)
+
+	msg, err := NewArchiveMessage(ArchiveReward)
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(data, msg))
+	reward := msg.(*pb.Reward)
+	require.EqualValues(t, 42, reward.GetLayer().GetNumber())
+	require.EqualValues(t, 100, reward.GetTotal().GetValue())
+
+	_, err = NewArchiveMessage("atx")
+	require.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// balanceChangesCollection holds the balance history of the accounts, one document per account and
+// layer in which its balance changed.
+const balanceChangesCollection = "balance_changes"
+
+func (s *Storage) initBalanceChangesStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, balanceChangesCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, balanceChangesCollection)
+}
+
+// getBalances returns the stored balances of the accounts, the unknown accounts are missing.
+func (s *Storage) getBalances(ctx context.Context, addresses []string) (map[string]uint64, error) {
+	cursor, err := s.db.Collection("accounts").Find(ctx,
+		bson.D{{Key: "address", Value: bson.D{{Key: "$in", Value: addresses}}}},
+		options.Find().SetProjection(bson.D{{Key: "address", Value: 1}, {Key: "balance", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var accounts []model.Account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	balances := make(map[string]uint64, len(accounts))
+	for _, acc := range accounts {
+		balances[acc.Address] = acc.Balance
+	}
+	return balances, nil
+}
+
+// saveBalanceChanges records the balance changes. Changes of the same account and layer are summed.
+func (s *Storage) saveBalanceChanges(ctx context.Context, changes []*model.BalanceChange) error {
+	models := make([]mongo.WriteModel, 0, len(changes))
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "address", Value: change.Address}, {Key: "layer", Value: change.Layer}}).
+			SetUpdate(bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "delta", Value: change.Delta}}},
+				{Key: "$set", Value: bson.D{{Key: "balance", Value: change.Balance}}},
+			}).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := s.db.Collection(balanceChangesCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error save balance changes: %w", err)
+	}
+	return nil
+}
+
+// GetBalanceChanges returns the balance changes matching the query.
+func (s *Storage) GetBalanceChanges(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.BalanceChange, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection(balanceChangesCollection).Find(ctx, query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get balance changes: %w", err)
+	}
+	var changes []*model.BalanceChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("error decode balance changes: %w", err)
+	}
+	return changes, nil
+}
+
+// GetBalanceChangesCount returns the number of balance changes matching the query.
+func (s *Storage) GetBalanceChangesCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.countDocuments(parent, balanceChangesCollection, query, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("error count balance changes: %w", err)
+	}
+	return count, nil
+}
+
+// GetBalanceAt returns the balance of the account after the layer, 0 if it had no balance yet. It
+// reads the last snapshot of the account before the layer and the balance changes since.
+func (s *Storage) GetBalanceAt(parent context.Context, address string, layer uint32) (uint64, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	snapshot, err := s.getAccountSnapshot(ctx, address, layer)
+	if err != nil {
+		return 0, fmt.Errorf("error get snapshot of `%s` at layer %d: %w", address, layer, err)
+	}
+	changed := bson.D{{Key: "$lte", Value: layer}}
+	if snapshot != nil {
+		changed = append(changed, bson.E{Key: "$gte", Value: snapshot.Layer})
+	}
+	var change model.BalanceChange
+	err = s.db.Collection(balanceChangesCollection).FindOne(ctx,
+		bson.D{{Key: "address", Value: address}, {Key: "layer", Value: changed}},
+		options.FindOne().SetSort(bson.D{{Key: "layer", Value: -1}})).Decode(&change)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.BalanceAt(address, layer, snapshot, nil).Balance, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error get balance of `%s` at layer %d: %w", address, layer, err)
+	}
+	return model.BalanceAt(address, layer, snapshot, &change).Balance, nil
+}
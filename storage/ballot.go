@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// ballotsCollection holds the ballots of the collected layers, which the participation of the
+// smeshers in the votes is computed from.
+const ballotsCollection = "ballots"
+
+func (s *Storage) initBallotsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, ballotsCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, ballotsCollection)
+}
+
+func (s *Storage) UpsertBallots(parent context.Context, ballots []*model.Ballot) error {
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+
+	var updateOps []mongo.WriteModel
+	for _, ballot := range ballots {
+		updateModel := mongo.NewReplaceOneModel()
+		updateModel.SetFilter(bson.D{{Key: "id", Value: ballot.Id}})
+		updateModel.SetReplacement(ballot)
+		updateModel.SetUpsert(true)
+		updateOps = append(updateOps, updateModel)
+	}
+	if len(updateOps) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Collection(ballotsCollection).BulkWrite(ctx, updateOps)
+	if err != nil {
+		logging.Error("UpsertBallots", err)
+	}
+	return err
+}
+
+// getBallotStats returns the participation of the smeshers in the ballots of the layers in the range
+// [from, to].
+func (s *Storage) getBallotStats(parent context.Context, from, to uint32) (model.BallotStats, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+
+	cursor, err := s.db.Collection(ballotsCollection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "ballots", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "votes", Value: bson.D{{Key: "$sum", Value: "$eligibilities"}}},
+			{Key: "eligibilities", Value: bson.D{{Key: "$sum", Value: "$epochEligibilities"}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "voters", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "ballots", Value: bson.D{{Key: "$sum", Value: "$ballots"}}},
+			{Key: "votes", Value: bson.D{{Key: "$sum", Value: "$votes"}}},
+			{Key: "eligibilities", Value: bson.D{{Key: "$sum", Value: "$eligibilities"}}},
+		}}},
+	})
+	if err != nil {
+		return model.BallotStats{}, fmt.Errorf("error aggregate ballots: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return model.BallotStats{}, cursor.Err()
+	}
+	return model.BallotStats{
+		Voters:        utils.GetAsInt64(cursor.Current.Lookup("voters")),
+		Ballots:       utils.GetAsInt64(cursor.Current.Lookup("ballots")),
+		Votes:         utils.GetAsInt64(cursor.Current.Lookup("votes")),
+		Eligibilities: utils.GetAsInt64(cursor.Current.Lookup("eligibilities")),
+	}, nil
+}
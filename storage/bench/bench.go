@@ -0,0 +1,270 @@
+// Package bench measures the ingest throughput and the query latencies of a storage backend with a
+// synthetic chain, for the capacity planning of the explorer deployments.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// Config is the scale of the synthetic chain and the number of runs of every measured query.
+type Config struct {
+	Layers         uint32 // number of layers generated
+	EpochNumLayers uint32 // number of layers per epoch
+	BlocksPerLayer int    // blocks per layer, each one rewarded
+	TxsPerBlock    int    // spend transactions per block
+	Accounts       int    // accounts sending and receiving the transactions
+	Smeshers       int    // smeshers activated every epoch
+	Runs           int    // runs of every measured query
+}
+
+// DefaultConfig is a chain of ten epochs of a small network.
+var DefaultConfig = Config{
+	Layers:         1000,
+	EpochNumLayers: 100,
+	BlocksPerLayer: 1,
+	TxsPerBlock:    10,
+	Accounts:       1000,
+	Smeshers:       100,
+	Runs:           100,
+}
+
+func (c Config) validate() error {
+	if c.Layers == 0 || c.EpochNumLayers == 0 {
+		return fmt.Errorf("layers and layers per epoch must be positive")
+	}
+	if c.BlocksPerLayer <= 0 || c.Accounts <= 0 || c.Smeshers <= 0 {
+		return fmt.Errorf("blocks per layer, accounts and smeshers must be positive")
+	}
+	if c.TxsPerBlock < 0 || c.Runs < 0 {
+		return fmt.Errorf("transactions per block and runs must not be negative")
+	}
+	return nil
+}
+
+// pageSize is the page size of the measured list queries, the default one of the API.
+const pageSize = 20
+
+// Throughput is the ingest rate of an entity.
+type Throughput struct {
+	Entity    string        `json:"entity"`
+	Count     int           `json:"count"`
+	Duration  time.Duration `json:"duration"`
+	PerSecond float64       `json:"perSecond"`
+}
+
+// Latency is the latency distribution of a query, in milliseconds.
+type Latency struct {
+	Query string  `json:"query"`
+	Runs  int     `json:"runs"`
+	P50   float64 `json:"p50Ms"`
+	P95   float64 `json:"p95Ms"`
+	P99   float64 `json:"p99Ms"`
+	Max   float64 `json:"maxMs"`
+}
+
+// Report is the result of a benchmark run.
+type Report struct {
+	Config  Config       `json:"config"`
+	Ingest  []Throughput `json:"ingest"`
+	Queries []Latency    `json:"queries"`
+}
+
+// Run writes a synthetic chain of the configured scale with w, then measures the queries of the
+// API with r, which must read the storage written by w. The storage should be empty, the
+// benchmark data is left in it.
+func Run(ctx context.Context, w storage.StorageWriter, r storagereader.StorageReader, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	c, err := newChain(cfg)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Config: cfg}
+	w.OnNetworkInfo("bench", c.genesis, cfg.EpochNumLayers, 10, layerDuration, 1<<30)
+
+	// Activations are written before the layers, like the collector does on sync.
+	var atxs int
+	start := time.Now()
+	for epoch := uint32(1); epoch <= (cfg.Layers-1)/cfg.EpochNumLayers+1; epoch++ {
+		batch := c.activations(epoch)
+		w.OnActivations(batch)
+		atxs += len(batch)
+	}
+	report.Ingest = append(report.Ingest, throughput("activations", atxs, time.Since(start)))
+	logging.Info("bench: activations written", zap.Int("activations", atxs))
+
+	// The layers are written with their blocks and transactions, so both rates share the duration.
+	start = time.Now()
+	for layer := uint32(1); layer <= cfg.Layers; layer++ {
+		w.OnLayer(c.layer(layer))
+	}
+	if err := waitQueue(ctx, w); err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	txs := int(cfg.Layers) * cfg.BlocksPerLayer * cfg.TxsPerBlock
+	report.Ingest = append(report.Ingest,
+		throughput("layers", int(cfg.Layers), elapsed),
+		throughput("transactions", txs, elapsed))
+	logging.Info("bench: layers written", zap.Uint32("layers", cfg.Layers), zap.Int("transactions", txs))
+
+	var rewards int
+	start = time.Now()
+	for layer := uint32(1); layer <= cfg.Layers; layer++ {
+		batch := c.rewards(layer)
+		w.OnRewards(batch)
+		rewards += len(batch)
+	}
+	report.Ingest = append(report.Ingest, throughput("rewards", rewards, time.Since(start)))
+	logging.Info("bench: rewards written", zap.Int("rewards", rewards))
+
+	for _, q := range queries(r, c) {
+		latency, err := measure(ctx, q, cfg.Runs)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", q.name, err)
+		}
+		report.Queries = append(report.Queries, latency)
+	}
+	return report, nil
+}
+
+// waitQueue waits until the layers queued by the writer are stored.
+func waitQueue(ctx context.Context, w storage.StorageWriter) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for w.LayersInQueue() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func throughput(entity string, count int, d time.Duration) Throughput {
+	t := Throughput{Entity: entity, Count: count, Duration: d}
+	if d > 0 {
+		t.PerSecond = float64(count) / d.Seconds()
+	}
+	return t
+}
+
+// query is a measured query, run with a random page or entity on every run.
+type query struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// queries returns the queries of the main API pages.
+func queries(r storagereader.StorageReader, c *chain) []query {
+	page := func(sort string) *options.FindOptions {
+		return options.Find().
+			SetSort(bson.D{{Key: sort, Value: -1}}).
+			SetSkip(int64(c.rand.Intn(10) * pageSize)).
+			SetLimit(pageSize)
+	}
+	address := func() string {
+		return c.accounts[c.rand.Intn(len(c.accounts))].address.String()
+	}
+	smesher := func() string {
+		return utils.BytesToHex(c.smeshers[c.rand.Intn(len(c.smeshers))])
+	}
+	epochs := int((c.cfg.Layers-1)/c.cfg.EpochNumLayers + 1)
+
+	return []query{
+		{"layers", func(ctx context.Context) error {
+			_, err := r.GetLayers(ctx, &bson.D{}, page("number"))
+			return err
+		}},
+		{"layer", func(ctx context.Context) error {
+			_, err := r.GetLayer(ctx, c.rand.Intn(int(c.cfg.Layers))+1)
+			return err
+		}},
+		{"transactions", func(ctx context.Context) error {
+			var txs []*model.Transaction
+			_, err := r.FindPage(ctx, "txs", &bson.D{}, page("layer"), &txs)
+			return err
+		}},
+		{"account transactions", func(ctx context.Context) error {
+			addr := address()
+			var txs []*model.Transaction
+			_, err := r.FindPage(ctx, "txs", &bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "sender", Value: addr}},
+				bson.D{{Key: "receiver", Value: addr}},
+			}}}, page("layer").SetSkip(0), &txs)
+			return err
+		}},
+		{"account summary", func(ctx context.Context) error {
+			_, err := r.GetAccountSummary(ctx, address())
+			return err
+		}},
+		{"smesher rewards", func(ctx context.Context) error {
+			var rewards []*model.Reward
+			_, err := r.FindPage(ctx, "rewards", &bson.D{{Key: "smesher", Value: smesher()}}, page("layer").SetSkip(0), &rewards)
+			return err
+		}},
+		{"top smeshers", func(ctx context.Context) error {
+			var smeshers []*model.Smesher
+			_, err := r.FindPage(ctx, "smeshers", &bson.D{}, page("totalRewards").SetSkip(0), &smeshers)
+			return err
+		}},
+		{"epoch", func(ctx context.Context) error {
+			_, err := r.GetEpoch(ctx, c.rand.Intn(epochs)+1)
+			return err
+		}},
+		{"total rewards", func(ctx context.Context) error {
+			_, _, err := r.GetTotalRewards(ctx, &bson.D{})
+			return err
+		}},
+	}
+}
+
+// measure runs the query and returns its latency distribution.
+func measure(ctx context.Context, q query, runs int) (Latency, error) {
+	durations := make([]time.Duration, 0, runs)
+	for i := 0; i < runs; i++ {
+		start := time.Now()
+		if err := q.run(ctx); err != nil {
+			return Latency{}, err
+		}
+		durations = append(durations, time.Since(start))
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	latency := Latency{Query: q.name, Runs: runs}
+	if runs == 0 {
+		return latency, nil
+	}
+	latency.P50 = milliseconds(percentile(durations, 50))
+	latency.P95 = milliseconds(percentile(durations, 95))
+	latency.P99 = milliseconds(percentile(durations, 99))
+	latency.Max = milliseconds(durations[runs-1])
+	return latency, nil
+}
+
+// percentile returns the nearest rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
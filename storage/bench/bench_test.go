@@ -0,0 +1,64 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/storage/memory"
+)
+
+func TestRun(t *testing.T) {
+	s := memory.New()
+	r := memory.NewReader(s)
+	cfg := Config{
+		Layers:         20,
+		EpochNumLayers: 10,
+		BlocksPerLayer: 2,
+		TxsPerBlock:    3,
+		Accounts:       5,
+		Smeshers:       3,
+		Runs:           4,
+	}
+	report, err := Run(context.Background(), s, r, cfg)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for _, ingest := range report.Ingest {
+		counts[ingest.Entity] = ingest.Count
+	}
+	require.Equal(t, map[string]int{"activations": 6, "layers": 20, "transactions": 120, "rewards": 40}, counts)
+	require.Len(t, report.Queries, 9)
+	for _, latency := range report.Queries {
+		require.Equal(t, cfg.Runs, latency.Runs, latency.Query)
+		require.LessOrEqual(t, latency.P50, latency.P95, latency.Query)
+		require.LessOrEqual(t, latency.P99, latency.Max, latency.Query)
+	}
+
+	txs, err := r.CountTransactions(context.Background(), &bson.D{})
+	require.NoError(t, err)
+	require.EqualValues(t, 120, txs)
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 1; i <= 100; i++ {
+		durations = append(durations, time.Duration(i))
+	}
+	require.Equal(t, time.Duration(50), percentile(durations, 50))
+	require.Equal(t, time.Duration(99), percentile(durations, 99))
+	require.Equal(t, time.Duration(1), percentile(durations[:1], 95))
+}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, DefaultConfig.validate())
+	cfg := DefaultConfig
+	cfg.EpochNumLayers = 0
+	require.Error(t, cfg.validate())
+	cfg = DefaultConfig
+	cfg.Smeshers = 0
+	require.Error(t, cfg.validate())
+}
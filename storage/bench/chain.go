@@ -0,0 +1,152 @@
+package bench
+
+import (
+	"crypto/rand"
+	mrand "math/rand"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	sdkWallet "github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/spacemeshos/go-spacemesh/signing"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	v0 "github.com/spacemeshos/explorer-backend/pkg/transactionparser/v0"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+const (
+	methodSend    = 16
+	layerDuration = 300
+	layerReward   = 1_000_000
+)
+
+type account struct {
+	address types.Address
+	signer  *signing.EdSigner
+	nonce   uint64
+}
+
+// chain generates the synthetic layers, activations and rewards.
+type chain struct {
+	cfg      Config
+	rand     *mrand.Rand
+	accounts []*account
+	smeshers [][]byte
+	genesis  uint64
+}
+
+func newChain(cfg Config) (*chain, error) {
+	c := &chain{
+		cfg:     cfg,
+		rand:    mrand.New(mrand.NewSource(time.Now().UnixNano())),
+		genesis: uint64(time.Now().Unix()) - uint64(cfg.Layers)*layerDuration,
+	}
+	for i := 0; i < cfg.Accounts; i++ {
+		signer, err := signing.NewEdSigner()
+		if err != nil {
+			return nil, err
+		}
+		var key v0.PublicKey
+		copy(key[:], signer.PublicKey().Bytes())
+		c.accounts = append(c.accounts, &account{
+			address: types.Address(v0.ComputePrincipal(v0.TemplateAddress, &v0.SpawnArguments{PublicKey: key})),
+			signer:  signer,
+		})
+	}
+	for i := 0; i < cfg.Smeshers; i++ {
+		c.smeshers = append(c.smeshers, randomBytes(32))
+	}
+	return c, nil
+}
+
+// coinbase returns the account rewarded for the smesher.
+func (c *chain) coinbase(smesher int) *account {
+	return c.accounts[smesher%len(c.accounts)]
+}
+
+// activations returns the activations of every smesher targeting the epoch.
+func (c *chain) activations(epoch uint32) []*model.Activation {
+	atxs := make([]*model.Activation, 0, len(c.smeshers))
+	for i, smesher := range c.smeshers {
+		atxs = append(atxs, &model.Activation{
+			Id:                utils.BytesToHex(randomBytes(32)),
+			SmesherId:         utils.BytesToHex(smesher),
+			Coinbase:          c.coinbase(i).address.String(),
+			NumUnits:          4,
+			EffectiveNumUnits: 4,
+			PublishEpoch:      epoch - 1,
+			TargetEpoch:       epoch,
+			TickCount:         1,
+			Weight:            4,
+			Received:          time.Now().UnixNano(),
+		})
+	}
+	return atxs
+}
+
+// layer returns the layer with its blocks of spend transactions between random accounts.
+func (c *chain) layer(number uint32) *pb.Layer {
+	blocks := make([]*pb.Block, 0, c.cfg.BlocksPerLayer)
+	for b := 0; b < c.cfg.BlocksPerLayer; b++ {
+		txs := make([]*pb.Transaction, 0, c.cfg.TxsPerBlock)
+		for t := 0; t < c.cfg.TxsPerBlock; t++ {
+			txs = append(txs, c.transaction())
+		}
+		blocks = append(blocks, &pb.Block{
+			Id:           randomBytes(20),
+			Transactions: txs,
+			SmesherId:    &pb.SmesherId{Id: c.smeshers[c.rand.Intn(len(c.smeshers))]},
+		})
+	}
+	return &pb.Layer{
+		Number: &pb.LayerNumber{Number: number},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   randomBytes(32),
+		Blocks: blocks,
+	}
+}
+
+func (c *chain) transaction() *pb.Transaction {
+	sender := c.accounts[c.rand.Intn(len(c.accounts))]
+	receiver := c.accounts[c.rand.Intn(len(c.accounts))]
+	amount := uint64(c.rand.Intn(1000) + 1)
+	gasPrice := uint64(1)
+	sender.nonce++
+	return &pb.Transaction{
+		Id:        randomBytes(32),
+		Method:    methodSend,
+		Principal: &pb.AccountId{Address: sender.address.String()},
+		GasPrice:  gasPrice,
+		MaxGas:    100,
+		Nonce:     &pb.Nonce{Counter: sender.nonce},
+		Template:  &pb.AccountId{Address: wallet.TemplateAddress.String()},
+		Raw:       sdkWallet.Spend(sender.signer.PrivateKey(), receiver.address, amount, types.Nonce(sender.nonce), sdk.WithGasPrice(gasPrice)),
+	}
+}
+
+// rewards returns a reward per block of the layer, paid to random smeshers.
+func (c *chain) rewards(number uint32) []*pb.Reward {
+	rewards := make([]*pb.Reward, 0, c.cfg.BlocksPerLayer)
+	for b := 0; b < c.cfg.BlocksPerLayer; b++ {
+		smesher := c.rand.Intn(len(c.smeshers))
+		fees := uint64(c.cfg.TxsPerBlock) * 100
+		rewards = append(rewards, &pb.Reward{
+			Layer:         &pb.LayerNumber{Number: number},
+			Total:         &pb.Amount{Value: layerReward + fees},
+			LayerReward:   &pb.Amount{Value: layerReward},
+			LayerComputed: &pb.LayerNumber{Number: number},
+			Coinbase:      &pb.AccountId{Address: c.coinbase(smesher).address.String()},
+			Smesher:       &pb.SmesherId{Id: c.smeshers[smesher]},
+		})
+	}
+	return rewards
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return b
+}
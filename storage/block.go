@@ -3,120 +3,95 @@ package storage
 import (
 	"context"
 	"errors"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) InitBlocksStorage(ctx context.Context) error {
-	_, err := s.db.Collection("blocks").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("idIndex").SetUnique(true)})
-	return err
+	if err := s.createIndexes(ctx, "blocks"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "blocks")
 }
 
 func (s *Storage) GetBlock(parent context.Context, query *bson.D) (*model.Block, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("blocks").Find(ctx, query)
-	if err != nil {
-		log.Info("GetBlock: %v", err)
+	account := &model.Block{}
+	if err := s.findOne(parent, "blocks", query, account); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetBlock: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetBlock", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetBlock: Empty result", err)
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	account := &model.Block{
-		Id:        utils.GetAsString(doc.Lookup("id")),
-		Layer:     utils.GetAsUInt32(doc.Lookup("layer")),
-		Epoch:     utils.GetAsUInt32(doc.Lookup("epoch")),
-		Start:     utils.GetAsUInt32(doc.Lookup("start")),
-		End:       utils.GetAsUInt32(doc.Lookup("end")),
-		TxsNumber: utils.GetAsUInt32(doc.Lookup("txsnumber")),
-		TxsValue:  utils.GetAsUInt64(doc.Lookup("txsvalue")),
-	}
 	return account, nil
 }
 
 func (s *Storage) GetBlocksCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("blocks").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(parent, "blocks", query, opts...)
 	if err != nil {
-		log.Info("GetBlocksCount: %v", err)
+		logging.Error("GetBlocksCount", err)
 		return 0
 	}
 	return count
 }
 
 func (s *Storage) GetBlocks(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("blocks").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetBlocks: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "blocks", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetBlocks: %v", err)
+		logging.Error("GetBlocks", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
-		log.Info("GetBlocks: Empty result")
+		logging.Info("GetBlocks: Empty result")
 		return nil, nil
 	}
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) SaveBlock(parent context.Context, in *model.Block) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	_, err := s.db.Collection("blocks").UpdateOne(ctx, bson.D{{Key: "id", Value: in.Id}}, bson.D{{
-		Key: "$set",
-		Value: bson.D{
-			{Key: "id", Value: in.Id},
-			{Key: "layer", Value: in.Layer},
-			{Key: "epoch", Value: in.Epoch},
-			{Key: "start", Value: in.Start},
-			{Key: "end", Value: in.End},
-			{Key: "txsnumber", Value: in.TxsNumber},
-			{Key: "txsvalue", Value: in.TxsValue},
-		},
-	}}, options.Update().SetUpsert(true))
-	if err != nil {
-		log.Info("SaveBlock: %v", err)
-	}
-	return err
+// UpsertBlock stores the block, see UpsertBlocks.
+func (s *Storage) UpsertBlock(parent context.Context, in *model.Block) error {
+	return s.UpsertBlocks(parent, []*model.Block{in})
 }
 
-func (s *Storage) SaveOrUpdateBlocks(parent context.Context, in []*model.Block) error {
-	ctx, cancel := context.WithTimeout(parent, 10*time.Second)
+// UpsertBlocks stores the blocks keyed on their id with a single unordered bulk write, storing a
+// block again overwrites it.
+func (s *Storage) UpsertBlocks(parent context.Context, in []*model.Block) error {
+	if len(in) == 0 {
+		return nil
+	}
+	ctx, cancel := s.bulkContext(parent)
 	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(in))
 	for _, block := range in {
-		_, err := s.db.Collection("blocks").UpdateOne(ctx, bson.D{{Key: "id", Value: block.Id}}, bson.D{
-			{Key: "$set", Value: bson.D{
-				{Key: "id", Value: block.Id},
-				{Key: "layer", Value: block.Layer},
-				{Key: "epoch", Value: block.Epoch},
-				{Key: "start", Value: block.Start},
-				{Key: "end", Value: block.End},
-				{Key: "txsnumber", Value: block.TxsNumber},
-				{Key: "txsvalue", Value: block.TxsValue},
-			}},
-		}, options.Update().SetUpsert(true))
-		if err != nil {
-			log.Info("SaveOrUpdateBlocks: %v", err)
-			return err
-		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: block.Id}}).
+			SetUpdate(bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "id", Value: block.Id},
+					{Key: "layer", Value: block.Layer},
+					{Key: "epoch", Value: block.Epoch},
+					{Key: "start", Value: block.Start},
+					{Key: "end", Value: block.End},
+					{Key: "txsnumber", Value: block.TxsNumber},
+					{Key: "txsvalue", Value: block.TxsValue},
+					{Key: "size", Value: block.Size},
+				}},
+			}).
+			SetUpsert(true))
+	}
+	res, err := s.db.Collection("blocks").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("UpsertBlocks", err)
+		return err
 	}
+	s.incBlocksStats(parent, upserted(in, res))
 	return nil
 }
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func (s *Storage) InitCertificatesStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, "certificates"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "certificates")
+}
+
+func (s *Storage) UpsertCertificates(parent context.Context, certs []*model.BlockCertificate) error {
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+
+	var updateOps []mongo.WriteModel
+	for _, cert := range certs {
+		updateModel := mongo.NewUpdateOneModel()
+		updateModel.SetFilter(bson.D{{Key: "blockId", Value: cert.BlockId}})
+		updateModel.SetUpdate(bson.D{{Key: "$set", Value: bson.D{
+			{Key: "layer", Value: cert.Layer},
+			{Key: "blockId", Value: cert.BlockId},
+			{Key: "valid", Value: cert.Valid},
+			{Key: "eligibilities", Value: cert.Eligibilities},
+			{Key: "signers", Value: cert.Signers},
+		}}})
+		updateModel.SetUpsert(true)
+		updateOps = append(updateOps, updateModel)
+	}
+	if len(updateOps) == 0 {
+		return nil
+	}
+
+	_, err := s.db.Collection("certificates").BulkWrite(ctx, updateOps)
+	if err != nil {
+		logging.Error("UpsertCertificates", err)
+	}
+	return err
+}
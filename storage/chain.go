@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// In watch mode the chain collections hold every activation and reward of the network, whatever
+// their coinbase, and the smeshers they were seen for, so the epoch stats and the global totals
+// are computed from the whole chain. The watched ones are stored in the regular collections too.
+const (
+	chainActivationsCollection = "chain_activations"
+	chainRewardsCollection     = "chain_rewards"
+	chainSmeshersCollection    = "chain_smeshers"
+)
+
+// chainCollections are the chain collections by the regular collection they mirror.
+var chainCollections = map[string]string{
+	"activations": chainActivationsCollection,
+	"rewards":     chainRewardsCollection,
+	"smeshers":    chainSmeshersCollection,
+}
+
+// initChainStorage creates the indexes of the chain collections.
+func (s *Storage) initChainStorage(ctx context.Context) error {
+	return s.createIndexes(ctx, chainActivationsCollection, chainRewardsCollection, chainSmeshersCollection)
+}
+
+// aggregateSource returns the collection the stats of the collection are aggregated from: its
+// chain collection in watch mode, the collection itself otherwise.
+func (s *Storage) aggregateSource(collection string) string {
+	if chain, ok := chainCollections[collection]; ok && s.watched != nil {
+		return chain
+	}
+	return collection
+}
+
+// saveChainActivations stores the activations in the chain collections in watch mode, and accounts
+// the ones stored for the first time and their new smeshers in the global totals.
+func (s *Storage) saveChainActivations(parent context.Context, atxs []*model.Activation) {
+	if s.watched == nil || len(atxs) == 0 {
+		return
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(atxs))
+	smesherModels := make([]mongo.WriteModel, 0, len(atxs))
+	for _, atx := range atxs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: atx.Id}}).
+			SetUpdate(s.activationUpdate(atx)).
+			SetUpsert(true))
+		smesherModels = append(smesherModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: atx.SmesherId}}).
+			SetUpdate(bson.D{{Key: "$setOnInsert", Value: bson.D{{Key: "id", Value: atx.SmesherId}}}}).
+			SetUpsert(true))
+	}
+	res, err := s.db.Collection(chainActivationsCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("saveChainActivations", err)
+		return
+	}
+	s.incSpaceTotals(parent, upserted(atxs, res))
+
+	res, err = s.db.Collection(chainSmeshersCollection).BulkWrite(ctx, smesherModels, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("saveChainActivations: smeshers", err)
+		return
+	}
+	s.incSmeshersTotals(parent, res.UpsertedCount)
+}
+
+// saveChainRewards stores the rewards in the chain collections in watch mode, accounts the ones
+// stored for the first time in the stats and updates the rewards of their layers.
+func (s *Storage) saveChainRewards(parent context.Context, rewards []*model.Reward) {
+	if s.watched == nil || len(rewards) == 0 {
+		return
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(rewards))
+	for _, reward := range rewards {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(rewardFilter(reward)).
+			SetUpdate(rewardUpdate(reward)).
+			SetUpsert(true))
+	}
+	res, err := s.db.Collection(chainRewardsCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("saveChainRewards", err)
+	}
+	added := upserted(rewards, res)
+	s.incRewardsStats(parent, added)
+	for _, layer := range RewardLayers(added) {
+		s.updateLayerSummary(layer)
+	}
+}
+
+// incChainTransactions accounts the transactions of the layer in the global totals in watch mode,
+// where the stored transactions are only the watched ones. The layer is counted by the difference
+// with its stored transactions, so a layer updated again is not counted twice.
+func (s *Storage) incChainTransactions(parent context.Context, layer *model.Layer) {
+	if s.watched == nil {
+		return
+	}
+	var stored struct {
+		Txs uint32 `bson:"txs"`
+	}
+	err := s.findOne(parent, "layers", bson.D{{Key: "number", Value: layer.Number}}, &stored,
+		options.FindOne().SetProjection(bson.D{{Key: "txs", Value: 1}}))
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		logging.Error("incChainTransactions", err, logging.Layer(layer.Number))
+		return
+	}
+	if delta := int64(layer.Txs) - int64(stored.Txs); delta != 0 {
+		s.incTotals(parent, bson.D{{Key: "txs", Value: delta}})
+	}
+}
+
+// getLayersTransactionsStats returns the number of transactions of the layers in the range and
+// their amount, from the layers summaries rather than the stored transactions.
+func (s *Storage) getLayersTransactionsStats(parent context.Context, layerStart, layerEnd uint32) (txs, amount int64, err error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection("layers").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "number", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "txs", Value: bson.D{{Key: "$sum", Value: "$txs"}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$txsamount"}}},
+		}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return 0, 0, cursor.Err()
+	}
+	var sum struct {
+		Txs    int64 `bson:"txs"`
+		Amount int64 `bson:"amount"`
+	}
+	err = cursor.Decode(&sum)
+	return sum.Txs, sum.Amount, err
+}
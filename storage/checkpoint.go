@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+const (
+	checkpointVersion      = 1
+	checkpointManifest     = "manifest.json"
+	checkpointBatchSize    = 1000
+	checkpointEntrySuffix  = ".jsonl"
+	checkpointQueryTimeout = 30 * time.Minute
+)
+
+// checkpointCollections are exported in full: they hold state which can't be rebuilt without
+// replaying the whole mesh.
+var checkpointCollections = []string{"accounts", "smeshers", "coinbases", "epochs", "networkinfo"}
+
+// CheckpointManifest describes a checkpoint archive.
+type CheckpointManifest struct {
+	Version     int              `json:"version"`
+	Created     int64            `json:"created"`
+	LastLayer   uint32           `json:"lastLayer"`
+	Collections map[string]int64 `json:"collections"`
+}
+
+// ExportCheckpoint writes a gzipped tar archive with the explorer state: the checkpoint collections
+// and the last stored layer, which is the position the collector resumes syncing from.
+// Every collection is stored as `<name>.jsonl` with one canonical extended JSON document per line.
+func (s *Storage) ExportCheckpoint(parent context.Context, w io.Writer) (*CheckpointManifest, error) {
+	ctx, cancel := context.WithTimeout(parent, checkpointQueryTimeout)
+	defer cancel()
+
+	lastLayer, err := s.GetLastLayer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &CheckpointManifest{
+		Version:     checkpointVersion,
+		Created:     time.Now().Unix(),
+		LastLayer:   lastLayer,
+		Collections: make(map[string]int64),
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range checkpointCollections {
+		count, err := s.exportCollection(ctx, tw, name, bson.D{})
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", name, err)
+		}
+		manifest.Collections[name] = count
+	}
+	count, err := s.exportCollection(ctx, tw, "layers", bson.D{{Key: "number", Value: manifest.LastLayer}})
+	if err != nil {
+		return nil, fmt.Errorf("export layers: %w", err)
+	}
+	manifest.Collections["layers"] = count
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarEntry(tw, checkpointManifest, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportCollection spools the matching documents to a temporary file, since the size of a tar
+// entry must be known before its content is written.
+func (s *Storage) exportCollection(ctx context.Context, tw *tar.Writer, name string, filter bson.D) (int64, error) {
+	tmp, err := os.CreateTemp("", "explorer-checkpoint-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	cursor, err := s.db.Collection(name).Find(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	buf := bufio.NewWriter(tmp)
+	var count int64
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := buf.Write(append(line, '\n')); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	logging.Info("export checkpoint", logging.Collection(name), zap.Int64("documents", count))
+	return count, writeTarEntry(tw, name+checkpointEntrySuffix, size, tmp)
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, r)
+	return err
+}
+
+// ImportCheckpoint loads an archive written by ExportCheckpoint. The database must not contain
+// any layer yet, so a checkpoint is never mixed with existing state.
+func (s *Storage) ImportCheckpoint(parent context.Context, r io.Reader) (*CheckpointManifest, error) {
+	ctx, cancel := context.WithTimeout(parent, checkpointQueryTimeout)
+	defer cancel()
+
+	layers, err := s.GetLayersCount(ctx, &bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	if layers > 0 {
+		return nil, errors.New("database is not empty")
+	}
+
+	return readCheckpoint(r, func(name string, r io.Reader) error {
+		count, err := s.importCollection(ctx, r, name)
+		if err != nil {
+			return fmt.Errorf("import %s: %w", name, err)
+		}
+		logging.Info("import checkpoint", logging.Collection(name), zap.Int64("documents", count))
+		return nil
+	})
+}
+
+// readCheckpoint reads an archive written by ExportCheckpoint, passing the content of every
+// collection to load, and returns its manifest.
+func readCheckpoint(r io.Reader, load func(name string, r io.Reader) error) (*CheckpointManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	allowed := map[string]bool{"layers": true}
+	for _, name := range checkpointCollections {
+		allowed[name] = true
+	}
+
+	var manifest *CheckpointManifest
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Name == checkpointManifest {
+			manifest = &CheckpointManifest{}
+			if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("invalid manifest: %w", err)
+			}
+			if manifest.Version != checkpointVersion {
+				return nil, fmt.Errorf("unsupported checkpoint version %d", manifest.Version)
+			}
+			continue
+		}
+
+		name := strings.TrimSuffix(hdr.Name, checkpointEntrySuffix)
+		if !allowed[name] {
+			logging.Warn("import checkpoint: skipping unknown entry", zap.String("entry", hdr.Name))
+			continue
+		}
+		if err := load(name, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest == nil {
+		return nil, errors.New("checkpoint manifest is missing")
+	}
+	return manifest, nil
+}
+
+func (s *Storage) importCollection(ctx context.Context, r io.Reader, name string) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var count int64
+	docs := make([]interface{}, 0, checkpointBatchSize)
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		_, err := s.db.Collection(name).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		count += int64(len(docs))
+		docs = docs[:0]
+		return nil
+	}
+
+	for scanner.Scan() {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc); err != nil {
+			return count, err
+		}
+		docs = append(docs, doc)
+		if len(docs) == checkpointBatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
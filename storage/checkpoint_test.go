@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checkpointArchive writes the entries, in order, as an archive of ExportCheckpoint.
+func checkpointArchive(t *testing.T, entries ...[2]string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		require.NoError(t, writeTarEntry(tw, entry[0], int64(len(entry[1])), strings.NewReader(entry[1])))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func checkpointManifestEntry(t *testing.T, manifest *CheckpointManifest) [2]string {
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	return [2]string{checkpointManifest, string(data)}
+}
+
+func TestReadCheckpoint(t *testing.T) {
+	manifest := &CheckpointManifest{
+		Version:     checkpointVersion,
+		Created:     1700000000,
+		LastLayer:   42,
+		Collections: map[string]int64{"accounts": 2, "layers": 1},
+	}
+	archive := checkpointArchive(t,
+		[2]string{"accounts.jsonl", "{\"address\":\"a\"}\n{\"address\":\"b\"}\n"},
+		[2]string{"txs.jsonl", "{\"id\":\"1\"}\n"},
+		[2]string{"layers.jsonl", "{\"number\":42}\n"},
+		checkpointManifestEntry(t, manifest),
+	)
+
+	loaded := make(map[string]string)
+	read, err := readCheckpoint(archive, func(name string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		loaded[name] = string(data)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, manifest, read)
+	// the collections which are not part of a checkpoint are skipped
+	require.Equal(t, map[string]string{
+		"accounts": "{\"address\":\"a\"}\n{\"address\":\"b\"}\n",
+		"layers":   "{\"number\":42}\n",
+	}, loaded)
+}
+
+func TestReadCheckpointInvalid(t *testing.T) {
+	load := func(string, io.Reader) error { return nil }
+
+	_, err := readCheckpoint(checkpointArchive(t, [2]string{"accounts.jsonl", "{}\n"}), load)
+	require.ErrorContains(t, err, "manifest is missing")
+
+	_, err = readCheckpoint(checkpointArchive(t, checkpointManifestEntry(t, &CheckpointManifest{Version: checkpointVersion + 1})), load)
+	require.ErrorContains(t, err, "unsupported checkpoint version")
+
+	_, err = readCheckpoint(strings.NewReader("not an archive"), load)
+	require.Error(t, err)
+}
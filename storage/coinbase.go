@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// coinbaseQuery records that the smesher used the coinbase in the epoch. The coinbases hold one
+// document per smesher and coinbase, so a smesher switching coinbases keeps the epochs of each.
+func coinbaseQuery(smesher, coinbase string, epoch uint32) *mongo.UpdateOneModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "coinbase", Value: coinbase}, {Key: "smesherId", Value: smesher}}).
+		SetUpdate(bson.D{
+			{Key: "$min", Value: bson.D{{Key: "from", Value: epoch}}},
+			{Key: "$max", Value: bson.D{{Key: "to", Value: epoch}}},
+			{Key: "$addToSet", Value: bson.D{{Key: "epochs", Value: epoch}}},
+		}).
+		SetUpsert(true)
+}
+
+// coinbaseEpochIndexes replaces the unique smesher index of the coinbases by a unique coinbase and
+// smesher index, and fills the epochs of the existing coinbases with the epochs of their smesher.
+func (s *Storage) coinbaseEpochIndexes(ctx context.Context) error {
+	coinbases := s.db.Collection("coinbases")
+	if err := s.dropIndex(ctx, "coinbases", "smesherIdIndex"); err != nil {
+		return err
+	}
+	if err := s.createIndexes(ctx, "coinbases"); err != nil {
+		return err
+	}
+
+	cursor, err := coinbases.Find(ctx, bson.D{{Key: "epochs", Value: bson.D{{Key: "$exists", Value: false}}}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var coinbase model.SmesherCoinbase
+		if err := cursor.Decode(&coinbase); err != nil {
+			return err
+		}
+		var smesher model.Smesher
+		err := s.db.Collection("smeshers").FindOne(ctx, bson.D{{Key: "id", Value: coinbase.Smesher}}).Decode(&smesher)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("error get smesher `%s`: %w", coinbase.Smesher, err)
+		}
+		update := bson.D{{Key: "epochs", Value: bson.A{}}}
+		if len(smesher.Epochs) > 0 {
+			from, to := smesher.Epochs[0], smesher.Epochs[0]
+			for _, epoch := range smesher.Epochs {
+				from, to = min(from, epoch), max(to, epoch)
+			}
+			update = bson.D{{Key: "epochs", Value: smesher.Epochs}, {Key: "from", Value: from}, {Key: "to", Value: to}}
+		}
+		if _, err := coinbases.UpdateByID(ctx, cursor.Current.Lookup("_id"), bson.D{{Key: "$set", Value: update}}); err != nil {
+			return fmt.Errorf("error update coinbase of `%s`: %w", coinbase.Smesher, err)
+		}
+	}
+	return cursor.Err()
+}
+
+// GetSmesherCoinbases returns the coinbases used by the smesher, latest first.
+func (s *Storage) GetSmesherCoinbases(parent context.Context, smesher string) ([]*model.SmesherCoinbase, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection("coinbases").Find(ctx, bson.D{{Key: "smesherId", Value: smesher}},
+		options.Find().SetSort(bson.D{{Key: "from", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get coinbases of `%s`: %w", smesher, err)
+	}
+	var coinbases []*model.SmesherCoinbase
+	if err := cursor.All(ctx, &coinbases); err != nil {
+		return nil, fmt.Errorf("error decode coinbases of `%s`: %w", smesher, err)
+	}
+	return coinbases, nil
+}
+
+// GetSmesherCoinbase returns the coinbase the smesher used in the epoch: the coinbase of its
+// activation targeting the epoch, or else the latest coinbase used before the epoch. It returns an
+// empty coinbase if the smesher was not active yet.
+func (s *Storage) GetSmesherCoinbase(parent context.Context, smesher string, epoch uint32) (string, error) {
+	coinbases, err := s.GetSmesherCoinbases(parent, smesher)
+	if err != nil {
+		return "", err
+	}
+	return coinbaseAt(coinbases, epoch), nil
+}
+
+// coinbaseAt returns the coinbase used in the epoch, the coinbases are sorted latest first.
+func coinbaseAt(coinbases []*model.SmesherCoinbase, epoch uint32) string {
+	for _, c := range coinbases {
+		for _, e := range c.Epochs {
+			if e == epoch {
+				return c.Coinbase
+			}
+		}
+	}
+	var (
+		found  string
+		latest uint32
+	)
+	for _, c := range coinbases {
+		for _, e := range c.Epochs {
+			if e < epoch && (found == "" || e > latest) {
+				found, latest = c.Coinbase, e
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestCoinbaseAt(t *testing.T) {
+	coinbases := []*model.SmesherCoinbase{
+		{Coinbase: "sm1b", From: 5, To: 6, Epochs: []uint32{5, 6}},
+		{Coinbase: "sm1a", From: 2, To: 7, Epochs: []uint32{2, 3, 4, 7}},
+	}
+	require.Equal(t, "", coinbaseAt(coinbases, 1))
+	require.Equal(t, "sm1a", coinbaseAt(coinbases, 2))
+	require.Equal(t, "sm1a", coinbaseAt(coinbases, 4))
+	require.Equal(t, "sm1b", coinbaseAt(coinbases, 5))
+	require.Equal(t, "sm1b", coinbaseAt(coinbases, 6))
+	require.Equal(t, "sm1a", coinbaseAt(coinbases, 7))
+	require.Equal(t, "sm1a", coinbaseAt(coinbases, 9))
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// readConcernLevels are the read concern levels accepted by ReadConcern.
+var readConcernLevels = map[string]bool{
+	"local":        true,
+	"available":    true,
+	"majority":     true,
+	"linearizable": true,
+	"snapshot":     true,
+}
+
+// WriteConcern returns the client options acknowledging the writes once applied by `w` members of
+// the replica set, a number or `majority`, and once journaled if journal is set. The write concern
+// of the url is kept if neither is set. A lower write concern speeds up the initial sync at the
+// cost of the writes lost by a failover.
+func WriteConcern(w string, journal bool) (*options.ClientOptions, error) {
+	var opts []writeconcern.Option
+	switch n, err := strconv.Atoi(w); {
+	case w == "":
+	case w == "majority":
+		opts = append(opts, writeconcern.WMajority())
+	case err == nil && n == 0 && journal:
+		return nil, fmt.Errorf("unacknowledged write concern `%s` cannot be journaled", w)
+	case err == nil && n >= 0:
+		opts = append(opts, writeconcern.W(n))
+	default:
+		return nil, fmt.Errorf("invalid write concern `%s`", w)
+	}
+	if journal {
+		opts = append(opts, writeconcern.J(true))
+	}
+	if len(opts) == 0 {
+		return options.Client(), nil
+	}
+	return options.Client().SetWriteConcern(writeconcern.New(opts...)), nil
+}
+
+// ReadConcern returns the client options reading the data with the given isolation level: local,
+// available, majority, linearizable or snapshot. An empty level keeps the read concern of the url.
+func ReadConcern(level string) (*options.ClientOptions, error) {
+	if level == "" {
+		return options.Client(), nil
+	}
+	if !readConcernLevels[level] {
+		return nil, fmt.Errorf("invalid read concern `%s`", level)
+	}
+	return options.Client().SetReadConcern(readconcern.New(readconcern.Level(level))), nil
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteConcern(t *testing.T) {
+	opts, err := WriteConcern("", false)
+	require.NoError(t, err)
+	require.Nil(t, opts.WriteConcern)
+
+	opts, err = WriteConcern("majority", true)
+	require.NoError(t, err)
+	require.True(t, opts.WriteConcern.Acknowledged())
+	require.True(t, opts.WriteConcern.GetJ())
+	require.Equal(t, "majority", opts.WriteConcern.GetW())
+
+	opts, err = WriteConcern("0", false)
+	require.NoError(t, err)
+	require.False(t, opts.WriteConcern.Acknowledged())
+
+	_, err = WriteConcern("0", true)
+	require.Error(t, err)
+	_, err = WriteConcern("all", false)
+	require.Error(t, err)
+	_, err = WriteConcern("-1", false)
+	require.Error(t, err)
+}
+
+func TestReadConcern(t *testing.T) {
+	opts, err := ReadConcern("")
+	require.NoError(t, err)
+	require.Nil(t, opts.ReadConcern)
+
+	opts, err = ReadConcern("majority")
+	require.NoError(t, err)
+	require.Equal(t, "majority", opts.ReadConcern.GetLevel())
+
+	_, err = ReadConcern("strong")
+	require.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/test/storagetest"
+)
+
+const conformanceDB = "explorer_conformance"
+
+// TestConformance runs the storage suite against the mongo deployment at EXPLORER_TEST_MONGO_URL.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("EXPLORER_TEST_MONGO_URL")
+	if url == "" {
+		t.Skip("EXPLORER_TEST_MONGO_URL is not set")
+	}
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(ctx) })
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		require.NoError(t, client.Database(conformanceDB).Drop(ctx))
+		s, err := storage.New(ctx, url, conformanceDB, "")
+		require.NoError(t, err)
+		t.Cleanup(s.Close)
+		require.NoError(t, s.Migrate(ctx))
+		reader, err := storagereader.NewStorageReader(ctx, url, conformanceDB, "")
+		require.NoError(t, err)
+		return storagetest.Backend{Writer: s, Reader: reader}
+	})
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// compressors are the wire compressors supported by the driver.
+var compressors = map[string]bool{
+	"snappy": true,
+	"zlib":   true,
+	"zstd":   true,
+}
+
+// Authentication mechanisms supported by Connection.
+const (
+	AuthSCRAMSHA1   = "SCRAM-SHA-1"
+	AuthSCRAMSHA256 = "SCRAM-SHA-256"
+	AuthX509        = "MONGODB-X509"
+	AuthAWS         = "MONGODB-AWS"
+)
+
+var authMechanisms = map[string]bool{
+	AuthSCRAMSHA1:   true,
+	AuthSCRAMSHA256: true,
+	AuthX509:        true,
+	AuthAWS:         true,
+}
+
+// Connection tunes the MongoDB client, the unset fields keep the value of the url or the driver
+// default.
+type Connection struct {
+	// MaxPoolSize and MinPoolSize bound the number of connections to each server.
+	MaxPoolSize uint64
+	MinPoolSize uint64
+	// ConnectTimeout bounds the establishment of a connection.
+	ConnectTimeout time.Duration
+	// ServerSelectionTimeout bounds the wait for a server available for an operation.
+	ServerSelectionTimeout time.Duration
+	// Compressors are the compressors of the messages, in order of preference: snappy, zlib or zstd.
+	Compressors []string
+
+	// TLS enables TLS, it is implied by the other TLS settings. The TLS settings replace the ones
+	// of the url if set.
+	TLS bool
+	// TLSCAFile is the PEM bundle of the authorities verifying the server certificate, the system
+	// ones if empty, e.g. the bundle of a DocumentDB cluster.
+	TLSCAFile string
+	// TLSCertificateKeyFile is the PEM file holding the client certificate and its private key,
+	// required by the x509 authentication.
+	TLSCertificateKeyFile string
+	// TLSInsecure skips the verification of the server certificate, for the tests only.
+	TLSInsecure bool
+
+	// AuthMechanism is the authentication mechanism: SCRAM-SHA-1, SCRAM-SHA-256, MONGODB-X509 or
+	// MONGODB-AWS, negotiated with the server if empty. The authentication settings replace the
+	// credentials of the url if set.
+	AuthMechanism string
+	// AuthSource is the database of the user, `admin` or `$external` by default.
+	AuthSource string
+	// Username and Password are the credentials of the SCRAM authentication. The x509 username is
+	// the subject of the client certificate if empty.
+	Username string
+	Password string
+}
+
+// Options returns the client options of the connection settings.
+func (c Connection) Options() (*options.ClientOptions, error) {
+	opts := options.Client()
+	if c.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(c.MaxPoolSize)
+	}
+	if c.MinPoolSize > 0 {
+		opts.SetMinPoolSize(c.MinPoolSize)
+	}
+	if c.MaxPoolSize > 0 && c.MinPoolSize > c.MaxPoolSize {
+		return nil, fmt.Errorf("min pool size %d above max pool size %d", c.MinPoolSize, c.MaxPoolSize)
+	}
+	if c.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(c.ConnectTimeout)
+	}
+	if c.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(c.ServerSelectionTimeout)
+	}
+	for _, compressor := range c.Compressors {
+		if !compressors[compressor] {
+			return nil, fmt.Errorf("unknown compressor `%s`", compressor)
+		}
+	}
+	if len(c.Compressors) > 0 {
+		opts.SetCompressors(c.Compressors)
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	auth, err := c.credential()
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		opts.SetAuth(*auth)
+	}
+	return opts, nil
+}
+
+// tlsConfig returns the TLS configuration of the connection, nil if TLS is not enabled.
+func (c Connection) tlsConfig() (*tls.Config, error) {
+	if !c.TLS && c.TLSCAFile == "" && c.TLSCertificateKeyFile == "" && !c.TLSInsecure {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: c.TLSInsecure}
+	if c.TLSCAFile != "" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error read tls ca file: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in tls ca file `%s`", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertificateKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertificateKeyFile, c.TLSCertificateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error load tls certificate key file: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// credential returns the authentication settings of the connection, nil if none is set.
+func (c Connection) credential() (*options.Credential, error) {
+	if c.AuthMechanism == "" && c.AuthSource == "" && c.Username == "" && c.Password == "" {
+		return nil, nil
+	}
+	if c.AuthMechanism != "" && !authMechanisms[c.AuthMechanism] {
+		return nil, fmt.Errorf("unknown auth mechanism `%s`", c.AuthMechanism)
+	}
+	switch c.AuthMechanism {
+	case AuthX509:
+		if c.TLSCertificateKeyFile == "" {
+			return nil, fmt.Errorf("auth mechanism %s requires a tls certificate key file", AuthX509)
+		}
+		if c.Password != "" {
+			return nil, fmt.Errorf("auth mechanism %s does not take a password", AuthX509)
+		}
+	case AuthAWS:
+	default:
+		if c.Username == "" {
+			return nil, fmt.Errorf("password authentication requires a username")
+		}
+	}
+	return &options.Credential{
+		AuthMechanism: c.AuthMechanism,
+		AuthSource:    c.AuthSource,
+		Username:      c.Username,
+		Password:      c.Password,
+		PasswordSet:   c.Password != "",
+	}, nil
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestConnectionOptions(t *testing.T) {
+	opts, err := Connection{}.Options()
+	require.NoError(t, err)
+	require.Nil(t, opts.MaxPoolSize)
+	require.Nil(t, opts.Compressors)
+
+	opts, err = Connection{
+		MaxPoolSize:            200,
+		MinPoolSize:            10,
+		ConnectTimeout:         5 * time.Second,
+		ServerSelectionTimeout: 10 * time.Second,
+		Compressors:            []string{"zstd", "snappy"},
+	}.Options()
+	require.NoError(t, err)
+	require.EqualValues(t, 200, *opts.MaxPoolSize)
+	require.EqualValues(t, 10, *opts.MinPoolSize)
+	require.Equal(t, 5*time.Second, *opts.ConnectTimeout)
+	require.Equal(t, 10*time.Second, *opts.ServerSelectionTimeout)
+	require.Equal(t, []string{"zstd", "snappy"}, opts.Compressors)
+
+	_, err = Connection{MaxPoolSize: 5, MinPoolSize: 10}.Options()
+	require.Error(t, err)
+	_, err = Connection{Compressors: []string{"gzip"}}.Options()
+	require.Error(t, err)
+}
+
+func TestClientOptionsReadPrimary(t *testing.T) {
+	// the read preference of the url and of the options is replaced
+	opts := options.MergeClientOptions(clientOptions(
+		"mongodb://localhost:27017/?readPreference=secondary",
+		options.Client().SetReadPreference(readpref.Nearest()),
+		options.Client().SetMaxPoolSize(20),
+	)...)
+	require.Equal(t, readpref.PrimaryMode, opts.ReadPreference.Mode())
+	require.EqualValues(t, 20, *opts.MaxPoolSize)
+	require.Equal(t, []string{"localhost:27017"}, opts.Hosts)
+}
+
+// writeCertificate writes a self-signed certificate to `cert.pem` and the certificate followed by
+// its key to `cert-key.pem` in a temporary directory.
+func writeCertificate(t *testing.T) (certFile, certKeyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "explorer"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	certKeyFile = filepath.Join(dir, "cert-key.pem")
+	require.NoError(t, os.WriteFile(certFile, cert, 0o600))
+	require.NoError(t, os.WriteFile(certKeyFile, append(cert, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...), 0o600))
+	return certFile, certKeyFile
+}
+
+func TestConnectionSecurityOptions(t *testing.T) {
+	opts, err := Connection{}.Options()
+	require.NoError(t, err)
+	require.Nil(t, opts.TLSConfig)
+	require.Nil(t, opts.Auth)
+
+	opts, err = Connection{TLS: true}.Options()
+	require.NoError(t, err)
+	require.NotNil(t, opts.TLSConfig)
+	require.Nil(t, opts.TLSConfig.RootCAs)
+
+	certFile, certKeyFile := writeCertificate(t)
+	opts, err = Connection{
+		TLSCAFile:             certFile,
+		TLSCertificateKeyFile: certKeyFile,
+		AuthMechanism:         AuthX509,
+	}.Options()
+	require.NoError(t, err)
+	require.NotNil(t, opts.TLSConfig.RootCAs)
+	require.Len(t, opts.TLSConfig.Certificates, 1)
+	require.Equal(t, AuthX509, opts.Auth.AuthMechanism)
+	require.False(t, opts.Auth.PasswordSet)
+
+	opts, err = Connection{AuthMechanism: AuthSCRAMSHA256, AuthSource: "admin", Username: "explorer", Password: "secret"}.Options()
+	require.NoError(t, err)
+	require.Equal(t, options.Credential{
+		AuthMechanism: AuthSCRAMSHA256,
+		AuthSource:    "admin",
+		Username:      "explorer",
+		Password:      "secret",
+		PasswordSet:   true,
+	}, *opts.Auth)
+
+	_, err = Connection{TLSCAFile: certKeyFile + ".missing"}.Options()
+	require.Error(t, err)
+	_, err = Connection{TLSCAFile: filepath.Join(t.TempDir())}.Options()
+	require.Error(t, err)
+	_, err = Connection{TLSCertificateKeyFile: certFile}.Options()
+	require.Error(t, err, "the certificate file has no key")
+	_, err = Connection{AuthMechanism: "PLAIN", Username: "explorer"}.Options()
+	require.Error(t, err)
+	_, err = Connection{AuthMechanism: AuthX509}.Options()
+	require.Error(t, err, "x509 requires a client certificate")
+	_, err = Connection{AuthMechanism: AuthSCRAMSHA256, Password: "secret"}.Options()
+	require.Error(t, err, "scram requires a username")
+}
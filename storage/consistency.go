@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// repairBatchSize is the number of repairs sent in a bulk write.
+const repairBatchSize = 1000
+
+// Inconsistency is a broken invariant between collections, with the write repairing it.
+type Inconsistency struct {
+	Check   string `json:"check"`
+	Subject string `json:"subject"`
+	Detail  string `json:"detail"`
+	// Repair describes the fix, it is empty when the database alone cannot repair the invariant.
+	Repair string `json:"repair,omitempty"`
+
+	collection string
+	write      mongo.WriteModel
+}
+
+// Repairable reports whether RepairConsistency can fix the inconsistency.
+func (i *Inconsistency) Repairable() bool {
+	return i.write != nil
+}
+
+var consistencyChecks = []struct {
+	name  string
+	check func(s *Storage, ctx context.Context) ([]*Inconsistency, error)
+}{
+	{"transaction layers", (*Storage).checkTransactionLayers},
+	{"account balances", (*Storage).checkAccountBalances},
+	{"smesher activations", (*Storage).checkSmesherActivations},
+}
+
+// CheckConsistency validates the invariants between the collections and returns the broken ones.
+// The checks scan whole collections, so they are only bounded by the context.
+func (s *Storage) CheckConsistency(ctx context.Context) ([]*Inconsistency, error) {
+	var found []*Inconsistency
+	for _, c := range consistencyChecks {
+		inconsistencies, err := c.check(s, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("check %s: %w", c.name, err)
+		}
+		logging.Info("consistency check", zap.String("check", c.name), zap.Int("inconsistencies", len(inconsistencies)))
+		found = append(found, inconsistencies...)
+	}
+	return found, nil
+}
+
+// RepairConsistency applies the repairs of the inconsistencies and returns the number of applied
+// repairs, the inconsistencies without repair are skipped.
+func (s *Storage) RepairConsistency(ctx context.Context, inconsistencies []*Inconsistency) (int, error) {
+	writes := make(map[string][]mongo.WriteModel)
+	for _, i := range inconsistencies {
+		if i.Repairable() {
+			writes[i.collection] = append(writes[i.collection], i.write)
+		}
+	}
+	var applied int
+	for collection, models := range writes {
+		for start := 0; start < len(models); start += repairBatchSize {
+			end := min(start+repairBatchSize, len(models))
+			if _, err := s.db.Collection(collection).BulkWrite(ctx, models[start:end], options.BulkWrite().SetOrdered(false)); err != nil {
+				return applied, fmt.Errorf("error repair `%s`: %w", collection, err)
+			}
+			applied += end - start
+		}
+	}
+	return applied, nil
+}
+
+// checkTransactionLayers finds the layers of the transactions missing from `layers`. They must be
+// synced again from the node.
+func (s *Storage) checkTransactionLayers(ctx context.Context) ([]*Inconsistency, error) {
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, bson.A{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$layer"},
+			{Key: "txs", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: s.db.CollectionName("layers")},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "number"},
+			{Key: "as", Value: "layers"},
+		}}},
+		bson.D{{Key: "$match", Value: bson.D{{Key: "layers", Value: bson.D{{Key: "$size", Value: 0}}}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var missing []struct {
+		Layer uint32 `bson:"_id"`
+		Txs   int64  `bson:"txs"`
+	}
+	if err := cursor.All(ctx, &missing); err != nil {
+		return nil, err
+	}
+	inconsistencies := make([]*Inconsistency, 0, len(missing))
+	for _, m := range missing {
+		inconsistencies = append(inconsistencies, &Inconsistency{
+			Check:   "transaction layers",
+			Subject: fmt.Sprintf("layer %d", m.Layer),
+			Detail:  fmt.Sprintf("%d transactions in a missing layer, sync the layer again from the node", m.Txs),
+		})
+	}
+	return inconsistencies, nil
+}
+
+type accountBalance struct {
+	Address string `bson:"address"`
+	Balance uint64 `bson:"balance"`
+	Layer   uint32 `bson:"layer"`
+}
+
+type ledgerBalance struct {
+	Address string `bson:"_id"`
+	Sum     int64  `bson:"sum"`
+	Layer   uint32 `bson:"layer"`
+}
+
+// checkAccountBalances compares the balances of the accounts with the sum of their balance changes.
+func (s *Storage) checkAccountBalances(ctx context.Context) ([]*Inconsistency, error) {
+	cursor, err := s.db.Collection(balanceChangesCollection).Aggregate(ctx, bson.A{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$address"},
+			{Key: "sum", Value: bson.D{{Key: "$sum", Value: "$delta"}}},
+			{Key: "layer", Value: bson.D{{Key: "$max", Value: "$layer"}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ledger := make(map[string]ledgerBalance)
+	for cursor.Next(ctx) {
+		var l ledgerBalance
+		if err := cursor.Decode(&l); err != nil {
+			return nil, err
+		}
+		ledger[l.Address] = l
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	cursor, err = s.db.Collection("accounts").Find(ctx, bson.D{},
+		options.Find().SetProjection(bson.D{{Key: "address", Value: 1}, {Key: "balance", Value: 1}, {Key: "layer", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var inconsistencies []*Inconsistency
+	for cursor.Next(ctx) {
+		var acc accountBalance
+		if err := cursor.Decode(&acc); err != nil {
+			return nil, err
+		}
+		l, ok := ledger[acc.Address]
+		delete(ledger, acc.Address)
+		if i := checkAccountBalance(acc, l, ok); i != nil {
+			inconsistencies = append(inconsistencies, i)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	for _, l := range ledger {
+		if l.Sum != 0 {
+			inconsistencies = append(inconsistencies, &Inconsistency{
+				Check:   "account balances",
+				Subject: l.Address,
+				Detail:  fmt.Sprintf("balance changes sum to %d for a missing account", l.Sum),
+			})
+		}
+	}
+	return inconsistencies, nil
+}
+
+// checkAccountBalance returns the inconsistency between the account and the sum of its balance
+// changes, nil if they match. The repair records the difference as a balance change at the last
+// layer of the account.
+func checkAccountBalance(acc accountBalance, ledger ledgerBalance, ok bool) *Inconsistency {
+	diff := int64(acc.Balance) - ledger.Sum
+	if diff == 0 {
+		return nil
+	}
+	layer := max(acc.Layer, ledger.Layer)
+	detail := fmt.Sprintf("balance %d, balance changes sum to %d", acc.Balance, ledger.Sum)
+	if !ok {
+		detail = fmt.Sprintf("balance %d without balance changes", acc.Balance)
+	}
+	return &Inconsistency{
+		Check:      "account balances",
+		Subject:    acc.Address,
+		Detail:     detail,
+		Repair:     fmt.Sprintf("record a balance change of %d at layer %d", diff, layer),
+		collection: balanceChangesCollection,
+		write: mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "address", Value: acc.Address}, {Key: "layer", Value: layer}}).
+			SetUpdate(bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "delta", Value: diff}}},
+				{Key: "$set", Value: bson.D{{Key: "balance", Value: acc.Balance}}},
+			}).
+			SetUpsert(true),
+	}
+}
+
+// checkSmesherActivations compares the activation count of the smeshers with their activations.
+func (s *Storage) checkSmesherActivations(ctx context.Context) ([]*Inconsistency, error) {
+	cursor, err := s.db.Collection("activations").Aggregate(ctx, bson.A{
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]uint32)
+	for cursor.Next(ctx) {
+		var c struct {
+			Smesher string `bson:"_id"`
+			Count   uint32 `bson:"count"`
+		}
+		if err := cursor.Decode(&c); err != nil {
+			return nil, err
+		}
+		counts[c.Smesher] = c.Count
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	cursor, err = s.db.Collection("smeshers").Find(ctx, bson.D{},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "atxcount", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	var inconsistencies []*Inconsistency
+	for cursor.Next(ctx) {
+		var smesher struct {
+			Id       string `bson:"id"`
+			AtxCount uint32 `bson:"atxcount"`
+		}
+		if err := cursor.Decode(&smesher); err != nil {
+			return nil, err
+		}
+		count := counts[smesher.Id]
+		if smesher.AtxCount == count {
+			continue
+		}
+		inconsistencies = append(inconsistencies, &Inconsistency{
+			Check:      "smesher activations",
+			Subject:    smesher.Id,
+			Detail:     fmt.Sprintf("atxcount %d, %d activations", smesher.AtxCount, count),
+			Repair:     fmt.Sprintf("set atxcount to %d", count),
+			collection: "smeshers",
+			write: mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: "id", Value: smesher.Id}}).
+				SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "atxcount", Value: count}}}}),
+		})
+	}
+	return inconsistencies, cursor.Err()
+}
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAccountBalance(t *testing.T) {
+	acc := accountBalance{Address: "sm1", Balance: 100, Layer: 10}
+	require.Nil(t, checkAccountBalance(acc, ledgerBalance{Address: "sm1", Sum: 100, Layer: 8}, true))
+
+	i := checkAccountBalance(acc, ledgerBalance{Address: "sm1", Sum: 130, Layer: 12}, true)
+	require.NotNil(t, i)
+	require.True(t, i.Repairable())
+	require.Equal(t, "record a balance change of -30 at layer 12", i.Repair)
+
+	i = checkAccountBalance(acc, ledgerBalance{}, false)
+	require.NotNil(t, i)
+	require.Equal(t, "balance 100 without balance changes", i.Detail)
+	require.Equal(t, "record a balance change of 100 at layer 10", i.Repair)
+
+	require.Nil(t, checkAccountBalance(accountBalance{Address: "sm2"}, ledgerBalance{}, false))
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestDecodeEpoch(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{
+		{Key: "number", Value: int32(3)},
+		{Key: "layerstart", Value: int64(12)},
+		{Key: "layerend", Value: int64(15)},
+		{Key: "activeSetSize", Value: int32(7)},
+		{Key: "stats", Value: bson.D{
+			{Key: "current", Value: bson.D{{Key: "transactions", Value: int32(5)}, {Key: "feesburned", Value: int64(9)}}},
+			{Key: "cumulative", Value: bson.D{{Key: "transactions", Value: int64(11)}}},
+		}},
+	})
+	require.NoError(t, err)
+
+	var epoch model.Epoch
+	require.NoError(t, bson.Unmarshal(raw, &epoch))
+	require.Equal(t, int32(3), epoch.Number)
+	require.Equal(t, uint32(12), epoch.LayerStart)
+	require.Equal(t, uint32(15), epoch.LayerEnd)
+	require.Equal(t, uint32(7), epoch.ActiveSetSize)
+	require.Equal(t, int64(5), epoch.Stats.Current.Transactions)
+	require.Equal(t, int64(9), epoch.Stats.Current.FeesBurned)
+	require.Equal(t, int64(11), epoch.Stats.Cumulative.Transactions)
+}
+
+func TestDecodeReward(t *testing.T) {
+	id := primitive.NewObjectID()
+	raw, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: id},
+		{Key: "layer", Value: int32(10)},
+		{Key: "total", Value: int64(100)},
+		{Key: "layerReward", Value: int64(90)},
+		{Key: "coinbase", Value: "sm1coinbase"},
+		{Key: "smesher", Value: "0xsmesher"},
+	})
+	require.NoError(t, err)
+
+	var reward model.Reward
+	require.NoError(t, bson.Unmarshal(raw, &reward))
+	require.Equal(t, id.Hex(), reward.ID)
+	require.Equal(t, uint32(10), reward.Layer)
+	require.Equal(t, uint64(100), reward.Total)
+	require.Equal(t, uint64(90), reward.LayerReward)
+	require.Equal(t, "sm1coinbase", reward.Coinbase)
+	require.Equal(t, "0xsmesher", reward.Smesher)
+}
+
+func TestDecodeTransaction(t *testing.T) {
+	tx := model.Transaction{
+		Id:               "0x01",
+		Layer:            4,
+		Amount:           1000,
+		Fee:              5,
+		Sender:           "sm1sender",
+		Receiver:         "sm1receiver",
+		TouchedAddresses: []string{"sm1sender", "sm1receiver"},
+	}
+	raw, err := bson.Marshal(tx)
+	require.NoError(t, err)
+
+	var decoded model.Transaction
+	require.NoError(t, bson.Unmarshal(raw, &decoded))
+	require.Equal(t, tx, decoded)
+}
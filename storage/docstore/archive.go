@@ -0,0 +1,69 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// SetArchive enables the archive of the raw node responses, see storage.Storage.SetArchive.
+func (s *Storage) SetArchive(enabled bool) {
+	s.archiveEnabled = enabled
+	if enabled {
+		logging.Info("archive of the node responses enabled")
+	}
+}
+
+// archive stores the raw messages with their ids, see storage.Storage.archive.
+func (s *Storage) archive(kind string, ids []string, msgs []proto.Message) {
+	if !s.archiveEnabled || len(msgs) == 0 {
+		return
+	}
+	received := time.Now().Unix()
+	keys := make([]string, 0, len(msgs))
+	docs := make([]bson.D, 0, len(msgs))
+	for i, msg := range msgs {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			logging.Error(fmt.Sprintf("archive %s `%s`", kind, ids[i]), err)
+			continue
+		}
+		keys = append(keys, kind+"/"+ids[i])
+		docs = append(docs, bson.D{
+			{Key: "kind", Value: kind},
+			{Key: "id", Value: ids[i]},
+			{Key: "data", Value: data},
+			{Key: "received", Value: received},
+		})
+	}
+	if err := s.db.UpsertBatch(context.Background(), "archive", keys, docs); err != nil {
+		logging.Error(fmt.Sprintf("archive %s", kind), err)
+	}
+}
+
+// GetArchive returns the archived response of the entity decoded into its message, nil if it is
+// not archived.
+func (s *Storage) GetArchive(ctx context.Context, kind, id string) (proto.Message, *storage.ArchiveEntry, error) {
+	msg, err := storage.NewArchiveMessage(kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry storage.ArchiveEntry
+	found, err := findOne(ctx, s.db, "archive", &bson.D{{Key: "kind", Value: kind}, {Key: "id", Value: id}}, &entry)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error get archive %s `%s`: %w", kind, id, err)
+	}
+	if !found {
+		return nil, nil, nil
+	}
+	if err := proto.Unmarshal(entry.Data, msg); err != nil {
+		return nil, nil, fmt.Errorf("error decode archive %s `%s`: %w", kind, id, err)
+	}
+	return msg, &entry, nil
+}
@@ -0,0 +1,68 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// balances returns the stored balances of the accounts, the unknown accounts are missing.
+func (s *Storage) balances(ctx context.Context, addresses []string) (map[string]uint64, error) {
+	docs, err := s.db.Find(ctx, "accounts", &bson.D{{Key: "address", Value: bson.D{{Key: "$in", Value: addresses}}}})
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := decodeAll[model.Account](docs)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]uint64, len(accounts))
+	for _, acc := range accounts {
+		balances[acc.Address] = acc.Balance
+	}
+	return balances, nil
+}
+
+// saveBalanceChanges records the balance changes, see storage.Storage.saveBalanceChanges. Changes
+// of the same account and layer are summed.
+func (s *Storage) saveBalanceChanges(ctx context.Context, changes []*model.BalanceChange) error {
+	for _, change := range changes {
+		if change == nil {
+			continue
+		}
+		fields, err := toFields(change, "delta")
+		if err != nil {
+			return err
+		}
+		err = s.db.Apply(ctx, "balance_changes", fmt.Sprintf("%s-%d", change.Address, change.Layer), Update{
+			Set:    fields,
+			Inc:    bson.D{{Key: "delta", Value: change.Delta}},
+			Upsert: true,
+		})
+		if err != nil {
+			return fmt.Errorf("error save balance changes: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetBalanceAt returns the balance of the account after the layer from its last balance change
+// before the layer. The account snapshots are not taken with this backend, see
+// storage.Storage.SetAccountSnapshots.
+func (r *Reader) GetBalanceAt(ctx context.Context, address string, layer uint32) (*model.AccountSnapshot, error) {
+	var change model.BalanceChange
+	found, err := findOne(ctx, r.db, "balance_changes",
+		&bson.D{{Key: "address", Value: address}, {Key: "layer", Value: bson.D{{Key: "$lte", Value: layer}}}},
+		&change, options.Find().SetSort(bson.D{{Key: "layer", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get balance of `%s` at layer %d: %w", address, layer, err)
+	}
+	if !found {
+		return model.BalanceAt(address, layer, nil, nil), nil
+	}
+	return model.BalanceAt(address, layer, nil, &change), nil
+}
@@ -0,0 +1,119 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// chainTables are the tables holding every activation and reward of the network and their
+// smeshers in watch mode, by the table they mirror, see storage.Storage.saveChainActivations. The
+// reader reads them when the network info records the watch mode, see Reader.source.
+var chainTables = map[string]string{
+	"activations": "chain_activations",
+	"rewards":     "chain_rewards",
+	"smeshers":    "chain_smeshers",
+}
+
+// aggregateSource returns the table the stats of the table are computed from: its chain table in
+// watch mode, the table itself otherwise.
+func (s *Storage) aggregateSource(table string) string {
+	if chain, ok := chainTables[table]; ok && s.watched != nil {
+		return chain
+	}
+	return table
+}
+
+// saveChainActivations stores the activations and their smeshers in the chain tables in watch mode.
+func (s *Storage) saveChainActivations(ctx context.Context, atxs []*model.Activation) {
+	if s.watched == nil {
+		return
+	}
+	for _, atx := range atxs {
+		fields, err := toFields(atx)
+		if err == nil {
+			err = s.db.Upsert(ctx, chainTables["activations"], atx.Id, fields)
+		}
+		if err == nil {
+			err = s.db.Upsert(ctx, chainTables["smeshers"], atx.SmesherId, bson.D{{Key: "id", Value: atx.SmesherId}})
+		}
+		if err != nil {
+			logging.Error("saveChainActivations", err)
+		}
+	}
+}
+
+// saveChainRewards stores the rewards in the chain tables in watch mode and returns the ones
+// stored for the first time.
+func (s *Storage) saveChainRewards(ctx context.Context, rewards []*model.Reward) []*model.Reward {
+	if s.watched == nil || len(rewards) == 0 {
+		return nil
+	}
+	saved := make([]*model.Reward, 0, len(rewards))
+	keys := make([]string, 0, len(rewards))
+	docs := make([]bson.D, 0, len(rewards))
+	for _, reward := range rewards {
+		fields, err := toFields(reward)
+		if err != nil {
+			logging.Error("saveChainRewards", err)
+			continue
+		}
+		saved = append(saved, reward)
+		keys = append(keys, fmt.Sprintf("%s-%d", reward.Smesher, reward.Layer))
+		docs = append(docs, fields)
+	}
+	stored, err := s.db.Existing(ctx, chainTables["rewards"], keys)
+	if err == nil {
+		err = s.db.UpsertBatch(ctx, chainTables["rewards"], keys, docs)
+	}
+	if err != nil {
+		logging.Error("saveChainRewards", err)
+		return nil
+	}
+	added := make([]*model.Reward, 0, len(saved))
+	for i, reward := range saved {
+		if !stored[keys[i]] {
+			added = append(added, reward)
+		}
+	}
+	return added
+}
+
+// source returns the chain table of the table in watch mode, the table itself otherwise.
+func (r *Reader) source(ctx context.Context, table string) (string, error) {
+	chain, ok := chainTables[table]
+	if !ok {
+		return table, nil
+	}
+	watchMode, err := r.isWatchMode(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !watchMode {
+		return table, nil
+	}
+	return chain, nil
+}
+
+// isWatchMode reports whether the collector runs in watch mode. The mode is read from the network
+// info once it is stored there by Storage.SetWatchedAccounts, before that the reader is in full mode.
+func (r *Reader) isWatchMode(ctx context.Context) (bool, error) {
+	if mode := r.watchMode.Load(); mode != nil {
+		return *mode, nil
+	}
+	var info struct {
+		WatchMode *bool `bson:"watchmode"`
+	}
+	if _, err := findOne(ctx, r.db, "networkinfo", &bson.D{{Key: "id", Value: 1}}, &info); err != nil {
+		return false, fmt.Errorf("error get watch mode: %w", err)
+	}
+	if info.WatchMode == nil {
+		return false, nil
+	}
+	r.watchMode.Store(info.WatchMode)
+	return *info.WatchMode, nil
+}
@@ -0,0 +1,38 @@
+package docstore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// findOne decodes the first document matching the filter into out and reports whether one was found.
+func findOne(ctx context.Context, db Engine, table string, filter *bson.D, out any, opts ...*options.FindOptions) (bool, error) {
+	opt := options.Find().SetLimit(1)
+	if len(opts) > 0 && opts[0].Sort != nil {
+		opt.SetSort(opts[0].Sort)
+	}
+	docs, err := db.Find(ctx, table, filter, opt)
+	if err != nil || len(docs) == 0 {
+		return false, err
+	}
+	return true, decode(docs[0], out)
+}
+
+func decode(doc []byte, out any) error {
+	return bson.UnmarshalExtJSON(doc, false, out)
+}
+
+// decodeAll decodes raw documents into models.
+func decodeAll[T any](docs [][]byte) ([]*T, error) {
+	result := make([]*T, 0, len(docs))
+	for _, doc := range docs {
+		var item T
+		if err := decode(doc, &item); err != nil {
+			return nil, err
+		}
+		result = append(result, &item)
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+// Package docstore stores explorer data as the documents of the mongo collections in the databases
+// other than mongo. The collector writes and the API reads them the same way whatever the database,
+// through an Engine which keeps the documents in their extended JSON form and evaluates the mongo
+// filters: the memory package for the tests, the postgres package for PostgreSQL.
+package docstore
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// Engine stores the documents of the collections, called tables, by key. The filters and the find
+// options are the ones of mongo, the documents are returned raw, in extended JSON.
+type Engine interface {
+	// Ping checks if the database is reachable.
+	Ping(ctx context.Context) error
+	Close()
+	// SetTimeouts bounds the statements, the unset classes are not bounded.
+	SetTimeouts(t storage.Timeouts)
+	// SetRetryPolicy bounds the retries of the reads failing with a transient error.
+	SetRetryPolicy(p storage.RetryPolicy)
+
+	// Upsert merges the fields into the document stored under key, like a mongo `$set` upsert.
+	Upsert(ctx context.Context, table, key string, fields bson.D) error
+	// UpsertBatch is Upsert for several documents.
+	UpsertBatch(ctx context.Context, table string, keys []string, docs []bson.D) error
+	// UpsertBatchVersioned is UpsertBatch incrementing the `version` field of the documents, see
+	// CompareAndSet.
+	UpsertBatchVersioned(ctx context.Context, table string, keys []string, docs []bson.D) error
+	// Insert stores the document unless the key already exists, like a mongo `$setOnInsert` upsert.
+	Insert(ctx context.Context, table, key string, fields bson.D) error
+	// Update merges the fields into an existing document, it does nothing if the key is unknown.
+	Update(ctx context.Context, table, key string, fields bson.D) error
+	// Apply applies the update operators to the document stored under key.
+	Apply(ctx context.Context, table, key string, u Update) error
+	// CompareAndSet merges the fields into the document if its `version` field, missing in the
+	// documents written before the versioning, is the given one, and increments the version. It
+	// reports whether the document was updated, it is not if it changed since or is unknown.
+	CompareAndSet(ctx context.Context, table, key string, version uint64, fields bson.D) (bool, error)
+	// UpdateMany merges the fields into the documents matching the filter and returns the number of
+	// updated documents.
+	UpdateMany(ctx context.Context, table string, filter *bson.D, fields bson.D) (int64, error)
+	// Remove deletes up to limit documents matching the filter, all of them if limit is 0, and
+	// returns their number.
+	Remove(ctx context.Context, table string, filter *bson.D, limit int64) (int64, error)
+	// Unset removes the fields from up to limit documents matching the filter, all of them if limit
+	// is 0, and returns their number.
+	Unset(ctx context.Context, table string, filter *bson.D, limit int64, fields ...string) (int64, error)
+
+	// Existing returns the keys stored in the table.
+	Existing(ctx context.Context, table string, keys []string) (map[string]bool, error)
+	// Find returns the documents matching the filter.
+	Find(ctx context.Context, table string, filter *bson.D, opts ...*options.FindOptions) ([][]byte, error)
+	// FindPage returns the documents of the page and the number of documents matching the filter.
+	FindPage(ctx context.Context, table string, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error)
+	// Count returns the number of documents matching the filter.
+	Count(ctx context.Context, table string, filter *bson.D) (int64, error)
+	// Sum returns the sums of the numeric fields over the documents matching the filter, followed
+	// by the number of matching documents.
+	Sum(ctx context.Context, table string, filter *bson.D, fields ...string) ([]int64, error)
+	// Max returns the highest numeric field of the documents matching the filter, false if there
+	// is none.
+	Max(ctx context.Context, table, field string, filter *bson.D) (int64, bool, error)
+	// Group returns the page of the groups of the documents of the table matching the filter and
+	// the number of groups, see Group. The groups are ordered by their buckets by default.
+	Group(ctx context.Context, table string, g Group, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error)
+	// FindByRef returns the page of the documents matching the filter ordered by their reference,
+	// see Ref.
+	FindByRef(ctx context.Context, table string, filter *bson.D, ref Ref, opts *options.FindOptions) ([][]byte, error)
+	// Search returns up to limit documents whose text field contains the words of the text, best
+	// matches first.
+	Search(ctx context.Context, table, field, text string, limit int64) ([][]byte, error)
+	// FindNear returns up to limit documents whose [longitude, latitude] field is within radius
+	// meters of the point, nearest first.
+	FindNear(ctx context.Context, table, field string, lon, lat, radius float64, limit int64) ([][]byte, error)
+	// FindWithin returns up to limit documents whose [longitude, latitude] field is in the box.
+	FindWithin(ctx context.Context, table, field string, box model.GeoBox, limit int64) ([][]byte, error)
+	// Stats returns the size of every table, the sizes the engine does not know are zero.
+	Stats(ctx context.Context) ([]*model.CollectionStats, error)
+}
+
+// Update is an update of a document with the mongo update operators, of its top-level fields.
+type Update struct {
+	// Set sets the fields, like `$set`.
+	Set bson.D
+	// SetOnInsert sets the fields of a new document, like `$setOnInsert`.
+	SetOnInsert bson.D
+	// Inc adds the integers to the fields, the missing fields are 0, like `$inc`.
+	Inc bson.D
+	// Min and Max keep the lowest or the highest of the stored and the given integers, like `$min`
+	// and `$max`.
+	Min, Max bson.D
+	// AddToSet adds the elements of the arrays missing from the stored arrays, like `$addToSet`
+	// with `$each`.
+	AddToSet bson.D
+	// Upsert inserts the document if the key is unknown, the update is ignored otherwise.
+	Upsert bool
+}
+
+// Bucket is a key of a Group, the integer floor((Field*Scale + Offset) / Width) * Unit, e.g. the
+// day of a timestamp with a Width and a Unit of 86400. A zero Scale, Width or Unit is 1.
+type Bucket struct {
+	Name   string
+	Field  string
+	Scale  int64
+	Offset int64
+	Width  int64
+	Unit   int64
+}
+
+// Group groups the documents by the values of the buckets. The groups are documents with the
+// buckets, the `count` of the documents and the sums of their Sums fields, under the same names.
+type Group struct {
+	By   []Bucket
+	Sums []string
+}
+
+// Ref orders the documents by the lowest Field of the documents of Table whose Keys fields equal
+// their Local field, the highest first. The documents without references come last.
+type Ref struct {
+	Local string
+	Table string
+	Keys  []string
+	Field string
+}
+
+// Value returns the bucket of the number.
+func (b Bucket) Value(v int64) int64 {
+	scale, width, unit := orOne(b.Scale), orOne(b.Width), orOne(b.Unit)
+	n := v*scale + b.Offset
+	q := n / width
+	if n%width != 0 && n < 0 {
+		q--
+	}
+	return q * unit
+}
+
+func orOne(v int64) int64 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,27 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// saveEpochStats records the stats of the epoch under their version, see
+// storage.Storage.saveEpochStats.
+func (s *Storage) saveEpochStats(ctx context.Context, epoch *model.Epoch) error {
+	fields, err := toFields(&model.EpochStats{
+		Epoch:    epoch.Number,
+		Version:  epoch.StatsVersion,
+		Computed: time.Now().Unix(),
+		Stats:    epoch.Stats,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "epoch_stats", fmt.Sprintf("%d/%d", epoch.Number, epoch.StatsVersion), fields); err != nil {
+		return fmt.Errorf("error save epoch %d stats: %w", epoch.Number, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// UpsertLabels stores the names of the accounts and smeshers, see storage.Storage.UpsertLabels.
+func (s *Storage) UpsertLabels(ctx context.Context, labels []*model.Label) error {
+	keys := make([]string, 0, len(labels))
+	docs := make([]bson.D, 0, len(labels))
+	for _, label := range labels {
+		if err := label.Validate(); err != nil {
+			return err
+		}
+		fields, err := toFields(label)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, label.Kind+"/"+label.Id)
+		docs = append(docs, fields)
+	}
+	names := make(map[string]string)
+	for _, label := range labels {
+		if label.Kind != model.LabelSmesher {
+			continue
+		}
+		var stored model.Label
+		found, err := findOne(ctx, s.db, "labels", &bson.D{{Key: "kind", Value: label.Kind}, {Key: "id", Value: label.Id}}, &stored)
+		if err != nil {
+			return fmt.Errorf("error get smesher names: %w", err)
+		}
+		if found {
+			names[label.Id] = stored.Name
+		}
+	}
+	if err := s.db.UpsertBatch(ctx, "labels", keys, docs); err != nil {
+		return fmt.Errorf("error save labels: %w", err)
+	}
+	epoch := utils.LayerEpoch(s.NetworkInfo.LastLayer, s.NetworkInfo.EpochNumLayers)
+	now := uint64(time.Now().Unix())
+	for _, label := range labels {
+		if label.Kind != model.LabelSmesher {
+			continue
+		}
+		_, err := s.db.UpdateMany(ctx, "activations", &bson.D{{Key: "smesher", Value: label.Id}}, bson.D{{Key: "smesherName", Value: label.Name}})
+		if err != nil {
+			return fmt.Errorf("error save smesher names: %w", err)
+		}
+		if err := s.saveSmesherNameChange(ctx, model.NewSmesherNameChange(label.Id, names[label.Id], label.Name, epoch, now)); err != nil {
+			return err
+		}
+		names[label.Id] = label.Name
+	}
+	return nil
+}
+
+// SearchLabels returns the labels whose name contains the words of the text, best matches first.
+func (r *Reader) SearchLabels(ctx context.Context, text string, limit int64) ([]*model.Label, error) {
+	if strings.TrimSpace(text) == "" {
+		return []*model.Label{}, nil
+	}
+	docs, err := r.db.Search(ctx, "labels", "name", text, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error search labels: %w", err)
+	}
+	labels, err := decodeAll[model.Label](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode labels: %w", err)
+	}
+	return labels, nil
+}
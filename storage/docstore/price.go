@@ -0,0 +1,42 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// UpsertPrice stores a price recorded from the price feed, see storage.Storage.UpsertPrice.
+func (s *Storage) UpsertPrice(ctx context.Context, price *model.Price) error {
+	if err := price.Validate(); err != nil {
+		return err
+	}
+	fields, err := toFields(price)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "prices", strconv.FormatUint(uint64(price.Timestamp), 10), fields); err != nil {
+		return fmt.Errorf("error save price: %w", err)
+	}
+	return nil
+}
+
+// GetPrices returns the prices recorded in [from, to], by timestamp.
+func (r *Reader) GetPrices(ctx context.Context, from, to uint32) ([]*model.Price, error) {
+	docs, err := r.db.Find(ctx, "prices",
+		&bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}}},
+		options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get prices: %w", err)
+	}
+	prices, err := decodeAll[model.Price](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode prices: %w", err)
+	}
+	return prices, nil
+}
@@ -0,0 +1,1084 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+var _ storagereader.StorageReader = (*Reader)(nil)
+
+// Reader is the implementation of storagereader.StorageReader on an Engine, it reads the documents
+// written by a Storage.
+type Reader struct {
+	db Engine
+	// watchMode is the mode of the collector once read, see Reader.isWatchMode.
+	watchMode atomic.Pointer[bool]
+}
+
+// NewReader creates a reader of the engine.
+func NewReader(db Engine) *Reader {
+	return &Reader{db: db}
+}
+
+// Ping checks if the database is reachable.
+func (r *Reader) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+func (r *Reader) Close() {
+	r.db.Close()
+}
+
+// GetNetworkInfo returns the network info.
+func (r *Reader) GetNetworkInfo(ctx context.Context) (*model.NetworkInfo, error) {
+	var info model.NetworkInfo
+	found, err := findOne(ctx, r.db, "networkinfo", &bson.D{{Key: "id", Value: 1}}, &info)
+	if err != nil {
+		return nil, fmt.Errorf("error get network info: %w", err)
+	}
+	if !found {
+		return nil, fmt.Errorf("error get network info: empty result")
+	}
+	return &info, nil
+}
+
+// GetLayerTimestamp returns the timestamp of the layer, the same way storagereader.Reader does.
+func (r *Reader) GetLayerTimestamp(layer uint32) uint32 {
+	networkInfo, err := r.GetNetworkInfo(context.TODO())
+	if err != nil {
+		logging.Error("getLayerTimestamp", err)
+		return 0
+	}
+	if layer == 0 {
+		return networkInfo.GenesisTime
+	}
+	return networkInfo.GenesisTime + (layer-1)*networkInfo.LayerDuration
+}
+
+// maxLayer returns the highest `layer` of the documents matching the filter, false if there is none.
+func (r *Reader) maxLayer(ctx context.Context, table string, filter *bson.D) (uint32, bool, error) {
+	layer, found, err := r.db.Max(ctx, table, "layer", filter)
+	return uint32(layer), found, err
+}
+
+// FindPage decodes the page of the documents of the collection matching the query into out and
+// returns the number of documents matching the query, see storagereader.Reader.FindPage.
+func (r *Reader) FindPage(ctx context.Context, collection string, query *bson.D, opts *options.FindOptions, out any) (int64, error) {
+	docs, total, err := r.db.FindPage(ctx, collection, query, opts)
+	if err != nil {
+		return 0, fmt.Errorf("error get %s: %w", collection, err)
+	}
+	return total, storage.DecodePage(out, docs, decode)
+}
+
+// CountTransactions returns the number of transactions matching the query.
+func (r *Reader) CountTransactions(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "txs", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count transactions: %w", err)
+	}
+	return count, nil
+}
+
+// GetTransactions returns the transactions matching the query.
+func (r *Reader) GetTransactions(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Transaction, error) {
+	docs, err := r.db.Find(ctx, "txs", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get txs: %w", err)
+	}
+	txs, err := decodeAll[model.Transaction](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode txs: %w", err)
+	}
+	return txs, nil
+}
+
+func (r *Reader) CountSentTransactions(ctx context.Context, address string) (amount, fees, count int64, err error) {
+	sums, err := r.db.Sum(ctx, "txs", &bson.D{{Key: "sender", Value: address}}, "amount", "fee")
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("error get sent txs: %w", err)
+	}
+	return sums[0], sums[1], sums[2], nil
+}
+
+func (r *Reader) CountReceivedTransactions(ctx context.Context, address string) (amount, count int64, err error) {
+	sums, err := r.db.Sum(ctx, "txs", &bson.D{{Key: "receiver", Value: address}}, "amount")
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get received txs: %w", err)
+	}
+	return sums[0], sums[1], nil
+}
+
+// GetLatestTransaction returns the latest tx for given address, only the layer is set.
+func (r *Reader) GetLatestTransaction(ctx context.Context, address string) (*model.Transaction, error) {
+	layer, found, err := r.maxLayer(ctx, "txs", &bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "sender", Value: address}},
+		bson.D{{Key: "receiver", Value: address}},
+	}}})
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting latest tx: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &model.Transaction{Layer: layer}, nil
+}
+
+// GetFirstSentTransaction returns the first sent tx for given address, only the layer is set.
+func (r *Reader) GetFirstSentTransaction(ctx context.Context, address string) (*model.Transaction, error) {
+	var tx model.Transaction
+	found, err := findOne(ctx, r.db, "txs", &bson.D{{Key: "sender", Value: address}}, &tx,
+		options.Find().SetSort(bson.D{{Key: "layer", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting first sent tx: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &model.Transaction{Layer: tx.Layer}, nil
+}
+
+// CountApps returns the number of apps matching the query.
+func (r *Reader) CountApps(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "apps", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count apps: %w", err)
+	}
+	return count, nil
+}
+
+// GetApps returns the apps matching the query.
+func (r *Reader) GetApps(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.App, error) {
+	docs, err := r.db.Find(ctx, "apps", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get apps: %w", err)
+	}
+	return decodeAll[model.App](docs)
+}
+
+// CountAccounts returns the number of accounts matching the query.
+func (r *Reader) CountAccounts(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	return r.db.Count(ctx, "accounts", query)
+}
+
+// GetAccounts returns the accounts matching the query, the most recently created first.
+// The creation layer is the layer of the first transaction of the account.
+func (r *Reader) GetAccounts(ctx context.Context, filter *bson.D, opts ...*options.FindOptions) ([]*model.Account, error) {
+	var opt *options.FindOptions
+	if len(opts) > 0 {
+		opt = options.Find()
+		opt.Skip, opt.Limit = opts[0].Skip, opts[0].Limit
+	}
+	docs, err := r.db.FindByRef(ctx, "accounts", filter, Ref{
+		Local: "address",
+		Table: "txs",
+		Keys:  []string{"sender", "receiver"},
+		Field: "layer",
+	}, opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get accounts: %w", err)
+	}
+	accounts, err := decodeAll[model.Account](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode accounts: %w", err)
+	}
+
+	for _, acc := range accounts {
+		summary, err := r.GetAccountSummary(ctx, acc.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account summary: %w", err)
+		}
+		acc.Sent = summary.Sent
+		acc.Received = summary.Received
+		acc.Awards = summary.Awards
+		acc.Fees = summary.Fees
+		acc.LastActivity = summary.LastActivity
+	}
+	return accounts, nil
+}
+
+// GetAccountSummary returns the summary of the account.
+func (r *Reader) GetAccountSummary(ctx context.Context, address string) (*model.AccountSummary, error) {
+	var summary model.AccountSummary
+
+	totalRewards, _, err := r.CountCoinbaseRewards(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting sum of rewards: %w", err)
+	}
+	summary.Awards = uint64(totalRewards)
+
+	received, _, err := r.CountReceivedTransactions(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting sum of received txs: %w", err)
+	}
+	summary.Received = uint64(received)
+
+	sent, fees, _, err := r.CountSentTransactions(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting sum of sent txs: %w", err)
+	}
+	summary.Sent = uint64(sent)
+	summary.Fees = uint64(fees)
+
+	latestTx, err := r.GetLatestTransaction(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	latestReward, err := r.GetLatestReward(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case latestTx != nil && latestReward != nil && latestReward.Layer > latestTx.Layer:
+		summary.LastActivity = int32(r.GetLayerTimestamp(latestReward.Layer))
+	case latestTx != nil:
+		summary.LastActivity = int32(r.GetLayerTimestamp(latestTx.Layer))
+	case latestReward != nil:
+		summary.LastActivity = int32(r.GetLayerTimestamp(latestReward.Layer))
+	}
+	return &summary, nil
+}
+
+// CountActivations returns the number of activations matching the query.
+func (r *Reader) CountActivations(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "activations", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count activations: %w", err)
+	}
+	return count, nil
+}
+
+// GetActivations returns the activations matching the query.
+func (r *Reader) GetActivations(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Activation, error) {
+	docs, err := r.db.Find(ctx, "activations", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get activations: %w", err)
+	}
+	return decodeAll[model.Activation](docs)
+}
+
+// CountBlocks returns the number of blocks matching the query.
+func (r *Reader) CountBlocks(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "blocks", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count blocks: %w", err)
+	}
+	return count, nil
+}
+
+// GetBlocks returns the blocks matching the query.
+func (r *Reader) GetBlocks(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Block, error) {
+	docs, err := r.db.Find(ctx, "blocks", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get blocks: %w", err)
+	}
+	return decodeAll[model.Block](docs)
+}
+
+// GetBlockCertificate returns the certificate of the block, or nil if the block was not certified.
+func (r *Reader) GetBlockCertificate(ctx context.Context, blockID string) (*model.BlockCertificate, error) {
+	var cert model.BlockCertificate
+	found, err := findOne(ctx, r.db, "certificates", &bson.D{{Key: "blockId", Value: blockID}}, &cert)
+	if err != nil {
+		return nil, fmt.Errorf("error get block certificate: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &cert, nil
+}
+
+// CountEpochs returns the number of epochs matching the query.
+func (r *Reader) CountEpochs(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "epochs", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count epochs: %w", err)
+	}
+	return count, nil
+}
+
+// GetEpochs returns the epochs matching the query.
+func (r *Reader) GetEpochs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Epoch, error) {
+	docs, err := r.db.Find(ctx, "epochs", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get epochs: %w", err)
+	}
+	epochs, err := decodeAll[model.Epoch](docs)
+	if err != nil {
+		return nil, err
+	}
+	for _, epoch := range epochs {
+		if err := r.setEpochRewards(ctx, epoch); err != nil {
+			return nil, err
+		}
+	}
+	return epochs, nil
+}
+
+// GetEpoch returns the epoch with the given number.
+func (r *Reader) GetEpoch(ctx context.Context, epochNumber int) (*model.Epoch, error) {
+	var epoch model.Epoch
+	found, err := findOne(ctx, r.db, "epochs", &bson.D{{Key: "number", Value: epochNumber}}, &epoch)
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch `%d`: %w", epochNumber, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	if err := r.setEpochRewards(ctx, &epoch); err != nil {
+		return nil, err
+	}
+	return &epoch, nil
+}
+
+// CountEpochStats returns the number of epoch stats versions matching the query.
+func (r *Reader) CountEpochStats(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "epoch_stats", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count epoch stats: %w", err)
+	}
+	return count, nil
+}
+
+// GetEpochStats returns the epoch stats versions matching the query.
+func (r *Reader) GetEpochStats(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.EpochStats, error) {
+	docs, err := r.db.Find(ctx, "epoch_stats", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch stats: %w", err)
+	}
+	stats, err := decodeAll[model.EpochStats](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode epoch stats: %w", err)
+	}
+	return stats, nil
+}
+
+// setEpochRewards sums the rewards of the layers of the epoch, of the whole chain in watch mode.
+func (r *Reader) setEpochRewards(ctx context.Context, epoch *model.Epoch) error {
+	rewards, err := r.source(ctx, "rewards")
+	if err != nil {
+		return err
+	}
+	sums, err := r.db.Sum(ctx, rewards, &bson.D{{Key: "layer", Value: bson.D{
+		{Key: "$gte", Value: epoch.LayerStart}, {Key: "$lte", Value: epoch.LayerEnd}}},
+	}, "total")
+	if err != nil {
+		return fmt.Errorf("error get total rewards for epoch %d: %w", epoch.Number, err)
+	}
+	epoch.Stats.Current.Rewards = sums[0]
+	epoch.Stats.Current.RewardsNumber = sums[1]
+	epoch.Stats.Cumulative.Rewards = sums[0]
+	epoch.Stats.Cumulative.RewardsNumber = sums[1]
+	return nil
+}
+
+// CountLayers returns the number of layers matching the query.
+func (r *Reader) CountLayers(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "layers", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count layers: %w", err)
+	}
+	return count, nil
+}
+
+// GetLayers returns the layers matching the query, the newest first, with the sum of their rewards.
+func (r *Reader) GetLayers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Layer, error) {
+	page := options.Find().SetSort(bson.D{{Key: "number", Value: -1}})
+	if len(opts) > 0 {
+		page.Skip, page.Limit = opts[0].Skip, opts[0].Limit
+	}
+	layers, err := r.layers(ctx, query, page)
+	if err != nil {
+		return nil, fmt.Errorf("error get layers: %w", err)
+	}
+	return layers, nil
+}
+
+// GetLayer returns the layer with the given number.
+func (r *Reader) GetLayer(ctx context.Context, layerNumber int) (*model.Layer, error) {
+	layers, err := r.layers(ctx, &bson.D{{Key: "number", Value: layerNumber}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error get layer `%d`: %w", layerNumber, err)
+	}
+	if len(layers) == 0 {
+		return nil, nil
+	}
+	return layers[0], nil
+}
+
+func (r *Reader) layers(ctx context.Context, filter *bson.D, opts *options.FindOptions) ([]*model.Layer, error) {
+	docs, err := r.db.Find(ctx, "layers", filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := decodeAll[model.Layer](docs)
+	if err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// CountRewards returns the number of rewards matching the query.
+func (r *Reader) CountRewards(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "rewards", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count rewards: %w", err)
+	}
+	return count, nil
+}
+
+// CountCoinbaseRewards returns the sum and the number of rewards for given coinbase address.
+func (r *Reader) CountCoinbaseRewards(ctx context.Context, coinbase string) (total, count int64, err error) {
+	total, count, err = r.GetTotalRewards(ctx, &bson.D{{Key: "coinbase", Value: coinbase}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get coinbase rewards: %w", err)
+	}
+	return total, count, nil
+}
+
+// GetRewards returns the rewards matching the query.
+func (r *Reader) GetRewards(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Reward, error) {
+	docs, err := r.db.Find(ctx, "rewards", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get rewards: %w", err)
+	}
+	rewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	return rewards, nil
+}
+
+// GetReward returns the reward with the given id, `<smesher>-<layer>` with this backend.
+func (r *Reader) GetReward(ctx context.Context, rewardID string) (*model.Reward, error) {
+	var reward model.Reward
+	found, err := findOne(ctx, r.db, "rewards", &bson.D{{Key: "_id", Value: rewardID}}, &reward)
+	if err != nil {
+		return nil, fmt.Errorf("error get reward `%s`: %w", rewardID, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &reward, nil
+}
+
+func (r *Reader) GetRewardV2(ctx context.Context, smesherID string, layer uint32) (*model.Reward, error) {
+	var reward model.Reward
+	found, err := findOne(ctx, r.db, "rewards", &bson.D{{Key: "smesher", Value: smesherID}, {Key: "layer", Value: layer}}, &reward)
+	if err != nil {
+		return nil, fmt.Errorf("error while getting reward by smesher `%s` and layer `%d`: %w", smesherID, layer, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &reward, nil
+}
+
+// GetLatestReward returns the latest reward for given coinbase, only the layer is set.
+func (r *Reader) GetLatestReward(ctx context.Context, coinbase string) (*model.Reward, error) {
+	layer, found, err := r.maxLayer(ctx, "rewards", &bson.D{{Key: "coinbase", Value: coinbase}})
+	if err != nil {
+		return nil, fmt.Errorf("error occured while getting latest reward: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &model.Reward{Layer: layer}, nil
+}
+
+// GetTotalRewards returns the sum and the number of rewards matching the filter.
+func (r *Reader) GetTotalRewards(ctx context.Context, filter *bson.D) (total, count int64, err error) {
+	sums, err := r.db.Sum(ctx, "rewards", filter, "total")
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get total rewards: %w", err)
+	}
+	return sums[0], sums[1], nil
+}
+
+// CountSmeshers returns the number of smeshers matching the query.
+func (r *Reader) CountSmeshers(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "smeshers", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count smeshers: %w", err)
+	}
+	return count, nil
+}
+
+// GetSmeshers returns the smeshers matching the query.
+func (r *Reader) GetSmeshers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Smesher, error) {
+	docs, err := r.db.Find(ctx, "smeshers", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers: %w", err)
+	}
+	smeshers, err := decodeAll[model.Smesher](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	}
+	return smeshers, nil
+}
+
+// CountEpochSmeshers returns the number of smeshers matching the query.
+func (r *Reader) CountEpochSmeshers(ctx context.Context, query *bson.D) (int64, error) {
+	return r.CountSmeshers(ctx, query)
+}
+
+// GetEpochSmeshers returns the smeshers matching the query.
+func (r *Reader) GetEpochSmeshers(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.Smesher, error) {
+	return r.GetSmeshers(ctx, query, opts...)
+}
+
+// GetSmesher returns the smesher with its malfeasance proofs.
+func (r *Reader) GetSmesher(ctx context.Context, smesherID string) (*model.Smesher, error) {
+	var smesher model.Smesher
+	found, err := findOne(ctx, r.db, "smeshers", &bson.D{{Key: "id", Value: smesherID}}, &smesher)
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher `%s`: %w", smesherID, err)
+	}
+	if !found {
+		return nil, nil
+	}
+	docs, err := r.db.Find(ctx, "malfeasance_proofs", &bson.D{{Key: "smesher", Value: smesherID}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher `%s` proofs: %w", smesherID, err)
+	}
+	proofs, err := decodeAll[model.MalfeasanceProof](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smesher `%s` proofs: %w", smesherID, err)
+	}
+	for _, proof := range proofs {
+		smesher.Proofs = append(smesher.Proofs, *proof)
+	}
+	return &smesher, nil
+}
+
+// CountSmesherHistory returns the number of smesher changes matching the query.
+func (r *Reader) CountSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := r.db.Count(ctx, "smesher_history", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count smesher history: %w", err)
+	}
+	return count, nil
+}
+
+// GetSmesherHistory returns the smesher changes matching the query.
+func (r *Reader) GetSmesherHistory(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.SmesherChange, error) {
+	docs, err := r.db.Find(ctx, "smesher_history", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher history: %w", err)
+	}
+	changes, err := decodeAll[model.SmesherChange](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smesher history: %w", err)
+	}
+	return changes, nil
+}
+
+// CountSmesherRewards returns the sum and the number of rewards of the smesher.
+func (r *Reader) CountSmesherRewards(ctx context.Context, smesherID string) (total, count int64, err error) {
+	total, count, err = r.GetTotalRewards(ctx, &bson.D{{Key: "smesher", Value: smesherID}})
+	if err != nil {
+		return 0, 0, fmt.Errorf("error get smesher rewards: %w", err)
+	}
+	return total, count, nil
+}
+
+// GetMalfeasanceProofs returns the malfeasance proofs matching the query.
+func (r *Reader) GetMalfeasanceProofs(ctx context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.MalfeasanceProof, error) {
+	docs, err := r.db.Find(ctx, "malfeasance_proofs", query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get malfeasance proofs: %w", err)
+	}
+	proofs, err := decodeAll[model.MalfeasanceProof](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode malfeasance proofs: %w", err)
+	}
+	return proofs, nil
+}
+
+// GetSmesherParticipation returns the participation of the smesher in the layers of the epoch, with
+// the totals of all the smeshers. The expected values are left to the caller.
+func (r *Reader) GetSmesherParticipation(ctx context.Context, smesherID string, epoch, layerStart, layerEnd uint32) (*model.SmesherParticipation, error) {
+	docs, err := r.db.Find(ctx, "activations", &bson.D{{Key: "targetEpoch", Value: epoch}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher weight: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	layers := bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}
+	docs, err = r.db.Find(ctx, "rewards", &bson.D{{Key: "layer", Value: layers}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher rewards: %w", err)
+	}
+	rewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	docs, err = r.db.Find(ctx, "certificates", &bson.D{{Key: "layer", Value: layers}, {Key: "valid", Value: true}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher certificates: %w", err)
+	}
+	certs, err := decodeAll[model.BlockCertificate](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode certificates: %w", err)
+	}
+	docs, err = r.db.Find(ctx, "ballots", &bson.D{{Key: "smesher", Value: smesherID}, {Key: "layer", Value: layers}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher ballots: %w", err)
+	}
+	ballots, err := decodeAll[model.Ballot](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode ballots: %w", err)
+	}
+
+	participation := &model.SmesherParticipation{Epoch: epoch}
+	for _, atx := range atxs {
+		participation.TotalWeight += int64(atx.Weight)
+		if atx.SmesherId == smesherID {
+			participation.Weight += int64(atx.Weight)
+		}
+	}
+	for _, reward := range rewards {
+		participation.TotalRewards += int64(reward.Total)
+		participation.TotalBallots++
+		if reward.Smesher == smesherID {
+			participation.Rewards += int64(reward.Total)
+			participation.Ballots++
+		}
+	}
+	for _, cert := range certs {
+		participation.Certificates++
+		participation.TotalEligibilities += int64(cert.Eligibilities)
+		for _, signer := range cert.Signers {
+			if signer.Smesher == smesherID {
+				participation.Signed++
+				participation.Eligibilities += int64(signer.Eligibilities)
+			}
+		}
+	}
+	for _, ballot := range ballots {
+		participation.Voted++
+		participation.Votes += int64(ballot.Eligibilities)
+		participation.EpochEligibilities += int64(ballot.EpochEligibilities)
+	}
+	return participation, nil
+}
+
+// dailyTxs groups the transactions by day. The stats collections are maintained by the mongo
+// storage only, the other databases compute them on read.
+var dailyTxs = Group{
+	By:   []Bucket{{Name: "day", Field: "timestamp", Width: 86400, Unit: 86400}},
+	Sums: []string{"amount"},
+}
+
+func (r *Reader) CountDailyTransactions(ctx context.Context) (int64, error) {
+	_, count, err := r.db.Group(ctx, "txs", dailyTxs, nil, options.Find().SetLimit(1))
+	if err != nil {
+		return 0, fmt.Errorf("error count daily transactions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Reader) GetDailyTransactions(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyTransactions, error) {
+	var opt *options.FindOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	docs, _, err := r.db.Group(ctx, "txs", dailyTxs, nil, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error get daily transactions: %w", err)
+	}
+	days, err := decodeAll[model.DailyTransactions](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode daily transactions: %w", err)
+	}
+	return days, nil
+}
+
+// dailyAccounts groups the accounts by the day they were first seen at, the same way as dailyTxs.
+func dailyAccounts(info *model.NetworkInfo) Group {
+	return Group{By: []Bucket{{
+		Name:   "day",
+		Field:  "created",
+		Scale:  int64(info.LayerDuration),
+		Offset: int64(info.GenesisTime),
+		Width:  86400,
+		Unit:   86400,
+	}}}
+}
+
+func (r *Reader) CountDailyAccounts(ctx context.Context) (int64, error) {
+	info, err := r.GetNetworkInfo(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error count daily accounts: %w", err)
+	}
+	_, count, err := r.db.Group(ctx, "accounts", dailyAccounts(info), nil, options.Find().SetLimit(1))
+	if err != nil {
+		return 0, fmt.Errorf("error count daily accounts: %w", err)
+	}
+	return count, nil
+}
+
+func (r *Reader) GetDailyAccounts(ctx context.Context, opts ...*options.FindOptions) ([]*model.DailyAccounts, error) {
+	info, err := r.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get daily accounts: %w", err)
+	}
+	var opt *options.FindOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	docs, _, err := r.db.Group(ctx, "accounts", dailyAccounts(info), nil, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error get daily accounts: %w", err)
+	}
+	days, err := decodeAll[model.DailyAccounts](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode daily accounts: %w", err)
+	}
+	return days, nil
+}
+
+func (r *Reader) SumDailyAccounts(ctx context.Context, query *bson.D) (int64, error) {
+	info, err := r.GetNetworkInfo(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error sum daily accounts: %w", err)
+	}
+	docs, _, err := r.db.Group(ctx, "accounts", dailyAccounts(info), query, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error sum daily accounts: %w", err)
+	}
+	days, err := decodeAll[model.DailyAccounts](docs)
+	if err != nil {
+		return 0, fmt.Errorf("error decode daily accounts: %w", err)
+	}
+	var count int64
+	for _, day := range days {
+		count += day.New
+	}
+	return count, nil
+}
+
+// txTypes groups the transactions by day, epoch and type, the same way as dailyTxs.
+func txTypes(info *model.NetworkInfo) Group {
+	g := Group{
+		By:   []Bucket{{Name: "day", Field: "timestamp", Width: 86400, Unit: 86400}},
+		Sums: []string{"amount"},
+	}
+	if info.EpochNumLayers > 0 {
+		g.By = append(g.By, Bucket{Name: "epoch", Field: "layer", Width: int64(info.EpochNumLayers)})
+	}
+	g.By = append(g.By, Bucket{Name: "type", Field: "type"})
+	return g
+}
+
+func (r *Reader) GetTransactionTypesStats(ctx context.Context, query *bson.D) ([]*model.TransactionTypeStats, error) {
+	info, err := r.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get transaction types: %w", err)
+	}
+	docs, _, err := r.db.Group(ctx, "txs", txTypes(info), query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error get transaction types: %w", err)
+	}
+	stats, err := decodeAll[model.TransactionTypeStats](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode transaction types: %w", err)
+	}
+	return stats, nil
+}
+
+// GetBlockStats groups the blocks which were not orphaned by epoch and bucket, the query matches
+// the epoch of the blocks.
+func (r *Reader) GetBlockStats(ctx context.Context, query *bson.D) ([]*model.BlockStats, error) {
+	filter := append(bson.D{storage.NotOrphaned}, *query...)
+	docs, err := r.db.Find(ctx, "blocks", &filter)
+	if err != nil {
+		return nil, fmt.Errorf("error get blocks stats: %w", err)
+	}
+	blocks, err := decodeAll[model.Block](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode blocks: %w", err)
+	}
+	return model.NewBlockStats(blocks), nil
+}
+
+// GetEpochCoinbases returns the effective num units of the activations targeting the epoch and the
+// rewards of its layers, by coinbase.
+func (r *Reader) GetEpochCoinbases(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.CoinbaseShare, error) {
+	docs, err := r.db.Find(ctx, "activations", &bson.D{{Key: "targetEpoch", Value: epoch}})
+	if err != nil {
+		return nil, fmt.Errorf("error get coinbases space: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	docs, err = r.db.Find(ctx, "rewards", &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}})
+	if err != nil {
+		return nil, fmt.Errorf("error get coinbases rewards: %w", err)
+	}
+	rewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	coinbases := make(map[string]*model.CoinbaseShare)
+	share := func(coinbase string) *model.CoinbaseShare {
+		if coinbases[coinbase] == nil {
+			coinbases[coinbase] = &model.CoinbaseShare{Coinbase: coinbase}
+		}
+		return coinbases[coinbase]
+	}
+	for _, atx := range atxs {
+		share(atx.Coinbase).Smeshers++
+		share(atx.Coinbase).Units += int64(atx.EffectiveNumUnits)
+	}
+	for _, reward := range rewards {
+		share(reward.Coinbase).Rewards += int64(reward.Total)
+	}
+	shares := make([]*model.CoinbaseShare, 0, len(coinbases))
+	for _, s := range coinbases {
+		shares = append(shares, s)
+	}
+	return shares, nil
+}
+
+// GetEpochUnits returns the number of activations targeting the epoch by number of units.
+func (r *Reader) GetEpochUnits(ctx context.Context, epoch uint32) ([]*model.UnitsCount, error) {
+	docs, err := r.db.Find(ctx, "activations", &bson.D{{Key: "targetEpoch", Value: epoch}})
+	if err != nil {
+		return nil, fmt.Errorf("error get epoch units: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	counts := make(map[uint32]*model.UnitsCount)
+	units := make([]*model.UnitsCount, 0)
+	for _, atx := range atxs {
+		if counts[atx.NumUnits] == nil {
+			counts[atx.NumUnits] = &model.UnitsCount{NumUnits: atx.NumUnits}
+			units = append(units, counts[atx.NumUnits])
+		}
+		counts[atx.NumUnits].Activations++
+	}
+	return units, nil
+}
+
+// GetRollups computes the rollups of the period starting in [from, to], the rollups collections are
+// maintained by the mongo storage only.
+func (r *Reader) GetRollups(ctx context.Context, period string, from, to uint32) ([]*model.Rollup, error) {
+	info, err := r.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups: %w", err)
+	}
+	rollups := model.NewRollups(period, info)
+	inRange := bson.D{{Key: "timestamp", Value: bson.D{
+		{Key: "$gte", Value: model.PeriodStart(period, from)},
+		{Key: "$lt", Value: uint64(model.PeriodStart(period, to)) + uint64(model.PeriodSeconds(period))},
+	}}}
+
+	filter := append(bson.D{storage.NotOrphaned}, inRange...)
+	docs, err := r.db.Find(ctx, "txs", &filter)
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups transactions: %w", err)
+	}
+	txs, err := decodeAll[model.Transaction](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode transactions: %w", err)
+	}
+	for _, tx := range txs {
+		rollup := rollups.At(tx.Timestamp)
+		rollup.Txs++
+		rollup.Volume += int64(tx.Amount)
+		rollup.Fees += int64(tx.Fee)
+	}
+
+	docs, err = r.db.Find(ctx, "rewards", &inRange)
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups rewards: %w", err)
+	}
+	rewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	for _, reward := range rewards {
+		rollups.At(reward.Timestamp).Rewards += int64(reward.Total)
+	}
+
+	docs, err = r.db.Find(ctx, "accounts", &bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups accounts: %w", err)
+	}
+	accounts, err := decodeAll[model.Account](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode accounts: %w", err)
+	}
+	for _, account := range accounts {
+		rollups.AtLayer(uint32(account.Created)).NewAccounts++
+	}
+
+	docs, err = r.db.Find(ctx, "activations", &bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error get rollups activations: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	first := make(map[string]uint32)
+	for _, atx := range atxs {
+		if epoch, ok := first[atx.SmesherId]; !ok || atx.TargetEpoch < epoch {
+			first[atx.SmesherId] = atx.TargetEpoch
+		}
+	}
+	for _, epoch := range first {
+		rollups.AtEpoch(epoch).NewSmeshers++
+	}
+	return rollups.Range(from, to), nil
+}
+
+// GetBalanceCohorts returns the cohorts of the balances of the epochs up to the last one.
+func (r *Reader) GetBalanceCohorts(ctx context.Context, epochNumLayers, lastEpoch uint32) ([]*model.BalanceCohorts, error) {
+	docs, err := r.db.Find(ctx, "balance_changes", &bson.D{}, options.Find().SetSort(bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("error get balance changes: %w", err)
+	}
+	changes, err := decodeAll[model.BalanceChange](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode balance changes: %w", err)
+	}
+	builder := model.NewBalanceCohortsBuilder(epochNumLayers, lastEpoch)
+	for _, change := range changes {
+		builder.Add(change)
+	}
+	return builder.Cohorts(), nil
+}
+
+// GetEpochSmesherRewards returns the rewards of the smeshers in the layers [layerStart, layerEnd],
+// with the commitments of their activations targeting the epoch.
+func (r *Reader) GetEpochSmesherRewards(ctx context.Context, epoch, layerStart, layerEnd uint32) ([]*model.SmesherEpochRewards, error) {
+	docs, err := r.db.Find(ctx, "activations", &bson.D{{Key: "targetEpoch", Value: epoch}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers weight: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	smeshers := make(map[string]*model.SmesherEpochRewards)
+	rewards := make([]*model.SmesherEpochRewards, 0, len(atxs))
+	get := func(smesher string) *model.SmesherEpochRewards {
+		if smeshers[smesher] == nil {
+			smeshers[smesher] = &model.SmesherEpochRewards{Smesher: smesher}
+			rewards = append(rewards, smeshers[smesher])
+		}
+		return smeshers[smesher]
+	}
+	for _, atx := range atxs {
+		smesher := get(atx.SmesherId)
+		smesher.NumUnits += atx.NumUnits
+		smesher.Weight += int64(atx.Weight)
+	}
+
+	docs, err = r.db.Find(ctx, "rewards", &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}})
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers rewards: %w", err)
+	}
+	layerRewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	for _, reward := range layerRewards {
+		smesher := get(reward.Smesher)
+		smesher.Rewards += int64(reward.Total)
+		smesher.Count++
+	}
+	return rewards, nil
+}
+
+// GetVaults returns the vesting schedules of all the vaults.
+func (r *Reader) GetVaults(ctx context.Context) ([]*model.Vault, error) {
+	docs, err := r.db.Find(ctx, "vaults", &bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error get vaults: %w", err)
+	}
+	vaults, err := decodeAll[model.Vault](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode vaults: %w", err)
+	}
+	return vaults, nil
+}
+
+// GetTotals returns the global totals, counted from the raw data as the stats collections are
+// maintained by the mongo storage only, so they are never stale. In watch mode the transactions,
+// smeshers, rewards and space are the ones of the whole chain, the accounts the watched ones.
+func (r *Reader) GetTotals(ctx context.Context) (*model.Totals, error) {
+	totals := &model.Totals{EpochSpace: make(map[string]int64), Updated: uint32(time.Now().Unix())}
+	smeshersTable, err := r.source(ctx, "smeshers")
+	if err != nil {
+		return nil, err
+	}
+	rewardsTable, err := r.source(ctx, "rewards")
+	if err != nil {
+		return nil, err
+	}
+	activationsTable, err := r.source(ctx, "activations")
+	if err != nil {
+		return nil, err
+	}
+	if smeshersTable == "smeshers" {
+		totals.Txs, err = r.db.Count(ctx, "txs", &bson.D{storage.NotOrphaned})
+	} else {
+		// the stored transactions are only the watched ones, the layers count all of them
+		var sums []int64
+		if sums, err = r.db.Sum(ctx, "layers", &bson.D{}, "txs"); err == nil {
+			totals.Txs = sums[0]
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error count transactions: %w", err)
+	}
+	if totals.Accounts, err = r.db.Count(ctx, "accounts", &bson.D{}); err != nil {
+		return nil, fmt.Errorf("error count accounts: %w", err)
+	}
+	if totals.Smeshers, err = r.db.Count(ctx, smeshersTable, &bson.D{}); err != nil {
+		return nil, fmt.Errorf("error count smeshers: %w", err)
+	}
+	docs, err := r.db.Find(ctx, rewardsTable, &bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error get rewards: %w", err)
+	}
+	rewards, err := decodeAll[model.Reward](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode rewards: %w", err)
+	}
+	for _, reward := range rewards {
+		totals.Rewards += int64(reward.Total)
+		totals.RewardsCount++
+	}
+	docs, err = r.db.Find(ctx, activationsTable, &bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("error get activations: %w", err)
+	}
+	atxs, err := decodeAll[model.Activation](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode activations: %w", err)
+	}
+	for _, atx := range atxs {
+		totals.EpochSpace[strconv.FormatUint(uint64(atx.TargetEpoch), 10)] += int64(atx.CommitmentSize)
+	}
+	return totals, nil
+}
@@ -0,0 +1,50 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// layerHash returns the stored hash of the layer, empty if the layer is not stored.
+func (s *Storage) layerHash(ctx context.Context, number uint32) (string, error) {
+	var layer model.Layer
+	if _, err := findOne(ctx, s.db, "layers", &bson.D{{Key: "number", Value: number}}, &layer); err != nil {
+		return "", err
+	}
+	return layer.Hash, nil
+}
+
+// tombstoneLayer marks the blocks and transactions no longer included in the reorged layer as
+// orphaned instead of deleting them, and restores the ones included again.
+func (s *Storage) tombstoneLayer(ctx context.Context, layer *model.Layer, blocks []*model.Block, txs map[string]*model.Transaction) error {
+	blockIds := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		blockIds = append(blockIds, block.Id)
+	}
+	txIds := make([]string, 0, len(txs))
+	for id := range txs {
+		txIds = append(txIds, id)
+	}
+	tombstone := bson.D{{Key: "orphaned", Value: true}, {Key: "supersededBy", Value: layer.Hash}}
+	for table, ids := range map[string][]string{"blocks": blockIds, "txs": txIds} {
+		orphanedFilter := storage.OrphanedFilter(layer.Number, ids)
+		orphaned, err := s.db.UpdateMany(ctx, table, &orphanedFilter, tombstone)
+		if err != nil {
+			return fmt.Errorf("error tombstone `%s`: %w", table, err)
+		}
+		restoredFilter := storage.RestoredFilter(ids)
+		restored, err := s.db.Unset(ctx, table, &restoredFilter, 0, "orphaned", "supersededBy")
+		if err != nil {
+			return fmt.Errorf("error restore `%s`: %w", table, err)
+		}
+		logging.Info("layer reorged", logging.Layer(layer.Number), zap.String("hash", layer.Hash), logging.Collection(table), zap.Int64("orphaned", orphaned), zap.Int64("restored", restored))
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/storage"
+)
+
+// SetRetention starts pruning the documents outside the retention policies, see
+// storage.Storage.SetRetention.
+func (s *Storage) SetRetention(policies []storage.RetentionPolicy) {
+	if len(policies) == 0 {
+		return
+	}
+	s.retentionDone = make(chan struct{})
+	go s.runRetention(policies)
+}
+
+func (s *Storage) runRetention(policies []storage.RetentionPolicy) {
+	ticker := time.NewTicker(storage.RetentionInterval)
+	defer ticker.Stop()
+	for {
+		for _, p := range policies {
+			before, ok := storage.RetentionCutoff(p, s.NetworkInfo.LastLayer, s.NetworkInfo.LayerDuration)
+			if !ok {
+				continue
+			}
+			n, err := s.prune(p, before)
+			if err != nil {
+				logging.Error(fmt.Sprintf("retention %s", p), err)
+			}
+			if n > 0 {
+				logging.Info("retention", zap.Stringer("policy", p), zap.Int64("pruned", n), logging.Layer(before))
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-s.retentionDone:
+			return
+		}
+	}
+}
+
+// pruneBatchSize is the number of documents removed or stripped by a statement of the pruning.
+const pruneBatchSize = 1000
+
+// prune removes or strips the documents of layers before the given one by batches, pausing
+// between the batches.
+func (s *Storage) prune(p storage.RetentionPolicy, before uint32) (int64, error) {
+	filter := bson.D{{Key: "layer", Value: bson.D{{Key: "$lt", Value: before}}}}
+	if p.Field != "" {
+		filter = append(filter, bson.E{Key: p.Field, Value: bson.D{{Key: "$exists", Value: true}}})
+	}
+
+	var total int64
+	for {
+		n, err := s.pruneBatch(p, filter)
+		total += n
+		storage.RecordPruned(p.Collection, n)
+		if err != nil || n == 0 {
+			return total, err
+		}
+		select {
+		case <-time.After(storage.RetentionPause):
+		case <-s.retentionDone:
+			return total, nil
+		}
+	}
+}
+
+func (s *Storage) pruneBatch(p storage.RetentionPolicy, filter bson.D) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if p.Field == "" {
+		return s.db.Remove(ctx, p.Collection, &filter, pruneBatchSize)
+	}
+	return s.db.Unset(ctx, p.Collection, &filter, pruneBatchSize, p.Field)
+}
@@ -0,0 +1,67 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// EarthRadius is the mean radius of the Earth in meters, the one of the MongoDB spherical queries.
+const EarthRadius = 6378100.0
+
+// UpsertSmesherLocations sets the locations of the smeshers, see storage.Storage.UpsertSmesherLocations.
+func (s *Storage) UpsertSmesherLocations(ctx context.Context, locations []*model.SmesherLocation) error {
+	for _, location := range locations {
+		if err := location.Validate(); err != nil {
+			return fmt.Errorf("smesher `%s`: %w", location.Smesher, err)
+		}
+		if err := s.db.Update(ctx, "smeshers", location.Smesher, bson.D{{Key: "geo", Value: location.Geo}}); err != nil {
+			return fmt.Errorf("error save smesher locations: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetSmeshersNear returns the smeshers within radius meters of the point, nearest first, measured
+// with the haversine formula.
+func (r *Reader) GetSmeshersNear(ctx context.Context, lon, lat, radius float64, limit int64) ([]*model.Smesher, error) {
+	docs, err := r.db.FindNear(ctx, "smeshers", "geo.coordinates", lon, lat, radius, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers near [%f, %f]: %w", lon, lat, err)
+	}
+	smeshers, err := decodeAll[model.Smesher](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	}
+	return smeshers, nil
+}
+
+// GetSmeshersWithin returns the smeshers in the bounding box.
+func (r *Reader) GetSmeshersWithin(ctx context.Context, box model.GeoBox, limit int64) ([]*model.Smesher, error) {
+	docs, err := r.db.FindWithin(ctx, "smeshers", "geo.coordinates", box, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers within %v: %w", box, err)
+	}
+	smeshers, err := decodeAll[model.Smesher](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	}
+	return smeshers, nil
+}
+
+// GetGeoHeatmap returns the heat-map of the smeshers located now, see storage.Storage.SetGeoHeatmap.
+func (r *Reader) GetGeoHeatmap(ctx context.Context) (*model.GeoHeatmap, error) {
+	docs, err := r.db.Find(ctx, "smeshers", &bson.D{{Key: "geo.coordinates", Value: bson.D{{Key: "$exists", Value: true}}}})
+	if err != nil {
+		return nil, fmt.Errorf("error get located smeshers: %w", err)
+	}
+	smeshers, err := decodeAll[model.Smesher](docs)
+	if err != nil {
+		return nil, fmt.Errorf("error decode smeshers: %w", err)
+	}
+	return model.NewGeoHeatmap(smeshers, uint32(time.Now().Unix())), nil
+}
@@ -0,0 +1,63 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// saveSmesherChange records the change of the smesher from its stored state, see
+// storage.Storage.smesherChanges. Replaying the change of an epoch merges the changed fields.
+func (s *Storage) saveSmesherChange(ctx context.Context, smesher *model.Smesher, epoch uint32) error {
+	var stored model.Smesher
+	found, err := findOne(ctx, s.db, "smeshers", &bson.D{{Key: "id", Value: smesher.Id}}, &stored)
+	if err != nil {
+		return err
+	}
+	var previous *model.Smesher
+	if found {
+		previous = &stored
+	}
+	change := model.NewSmesherChange(previous, smesher, epoch)
+	if change == nil {
+		return nil
+	}
+	fields, err := toFields(change, "changed")
+	if err != nil {
+		return err
+	}
+	err = s.db.Apply(ctx, "smesher_history", fmt.Sprintf("%s-%d", change.Smesher, change.Epoch), Update{
+		Set:      fields,
+		AddToSet: bson.D{{Key: "changed", Value: change.Changed}},
+		Upsert:   true,
+	})
+	if err != nil {
+		return fmt.Errorf("error save smesher history: %w", err)
+	}
+	return nil
+}
+
+// saveSmesherNameChange records the change of the name of the smesher, see
+// storage.Storage.saveSmesherNameChanges. A nil change is skipped.
+func (s *Storage) saveSmesherNameChange(ctx context.Context, change *model.SmesherChange) error {
+	if change == nil {
+		return nil
+	}
+	err := s.db.Apply(ctx, "smesher_history", fmt.Sprintf("%s-%d", change.Smesher, change.Epoch), Update{
+		Set: bson.D{
+			{Key: "smesher", Value: change.Smesher},
+			{Key: "epoch", Value: change.Epoch},
+			{Key: "name", Value: change.Name},
+		},
+		SetOnInsert: bson.D{{Key: "timestamp", Value: change.Timestamp}},
+		AddToSet:    bson.D{{Key: "changed", Value: change.Changed}},
+		Upsert:      true,
+	})
+	if err != nil {
+		return fmt.Errorf("error save smesher history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1344 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+var _ storage.StorageWriter = (*Storage)(nil)
+
+// Storage is the collector storage on an Engine. Layers are processed synchronously, account
+// balances are refreshed in the background.
+type Storage struct {
+	db Engine
+
+	NetworkInfo  model.NetworkInfo
+	postUnitSize uint64
+
+	accountUpdater storage.AccountUpdaterService
+	watched        map[string]struct{}
+	sinks          sink.Multi
+	cache          *cache.Cache
+	retentionDone  chan struct{}
+	// archiveEnabled is set if the raw node responses are archived, see SetArchive.
+	archiveEnabled bool
+
+	// layersLock serializes layer processing and epoch statistics updates.
+	layersLock   sync.Mutex
+	changedEpoch int32
+	lastEpoch    int32
+
+	// summaryLock serializes the recomputes of the layer summaries, see updateLayerSummary.
+	summaryLock sync.Mutex
+
+	accountsLock  sync.Mutex
+	accountsQueue map[string]uint32
+	accountsReady chan struct{}
+	// accountsDone stops updateAccounts, which drains the queue first, see Close.
+	accountsDone chan struct{}
+	accountsLoop sync.WaitGroup
+	closeOnce    sync.Once
+
+	// received holds the received times of the transactions until their layer is stored.
+	received storage.ReceivedTimes
+}
+
+// New creates a storage writing to the engine.
+func New(db Engine) *Storage {
+	s := &Storage{
+		db:            db,
+		changedEpoch:  -1,
+		accountsQueue: make(map[string]uint32),
+		accountsReady: make(chan struct{}, 1),
+		accountsDone:  make(chan struct{}),
+	}
+	s.accountsLoop.Add(1)
+	go s.updateAccounts()
+	return s
+}
+
+// Close updates the queued accounts, stops the background runs and closes the engine.
+func (s *Storage) Close() {
+	s.closeOnce.Do(func() {
+		close(s.accountsDone)
+		s.accountsLoop.Wait()
+	})
+	if s.retentionDone != nil {
+		close(s.retentionDone)
+	}
+	if err := s.sinks.Close(); err != nil {
+		logging.Error("error while closing sinks", err)
+	}
+	s.db.Close()
+}
+
+// Engine returns the engine of the storage, to read it back with NewReader.
+func (s *Storage) Engine() Engine {
+	return s.db
+}
+
+func (s *Storage) SetAccountUpdater(updater storage.AccountUpdaterService) {
+	s.accountUpdater = updater
+}
+
+// SetWatchedAccounts enables watch mode, see storage.Storage.SetWatchedAccounts. The mode is stored
+// in the network info, where the reader takes it from, and the chain tables are kept when it is
+// disabled.
+func (s *Storage) SetWatchedAccounts(addresses []string) {
+	if len(addresses) == 0 {
+		s.watched = nil
+	} else {
+		s.watched = make(map[string]struct{}, len(addresses))
+		for _, address := range addresses {
+			s.watched[address] = struct{}{}
+		}
+		logging.Info("watch mode enabled", zap.Int("accounts", len(s.watched)))
+	}
+	err := s.db.Upsert(context.Background(), "networkinfo", "1", bson.D{{Key: "id", Value: 1}, {Key: "watchmode", Value: s.watched != nil}})
+	if err != nil {
+		logging.Error("SetWatchedAccounts", err)
+	}
+}
+
+func (s *Storage) isWatched(addresses ...string) bool {
+	if s.watched == nil {
+		return true
+	}
+	for _, address := range addresses {
+		if _, ok := s.watched[address]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) AddSink(snk sink.Sink) {
+	s.sinks = append(s.sinks, snk)
+}
+
+// SetTimeouts sets the timeouts of the statements of the engine.
+func (s *Storage) SetTimeouts(t storage.Timeouts) {
+	s.db.SetTimeouts(t)
+}
+
+// SetRetryPolicy sets the retries of the reads failing with a transient error.
+func (s *Storage) SetRetryPolicy(p storage.RetryPolicy) {
+	s.db.SetRetryPolicy(p)
+}
+
+// SetCache enables the invalidation of the API documents cache on writes.
+func (s *Storage) SetCache(c *cache.Cache) {
+	s.cache = c
+}
+
+func (s *Storage) invalidate(keys ...string) {
+	if s.cache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.cache.Invalidate(ctx, keys...); err != nil {
+		logging.Error("invalidate cache", err, zap.Strings("keys", keys))
+	}
+}
+
+func (s *Storage) OnNetworkInfo(genesisId string, genesisTime uint64, epochNumLayers uint32, maxTransactionsPerSecond uint64, layerDuration uint64, postUnitSize uint64) {
+	s.NetworkInfo.GenesisId = genesisId
+	s.NetworkInfo.GenesisTime = uint32(genesisTime)
+	s.NetworkInfo.EpochNumLayers = epochNumLayers
+	s.NetworkInfo.MaxTransactionsPerSecond = uint32(maxTransactionsPerSecond)
+	s.NetworkInfo.LayerDuration = uint32(layerDuration)
+	s.NetworkInfo.PostUnitSize = postUnitSize
+	s.postUnitSize = postUnitSize
+	s.saveNetworkInfo()
+}
+
+func (s *Storage) OnNodeStatus(connectedPeers uint64, isSynced bool, syncedLayer uint32, topLayer uint32, verifiedLayer uint32) {
+	s.NetworkInfo.ConnectedPeers = connectedPeers
+	s.NetworkInfo.IsSynced = isSynced
+	s.NetworkInfo.SyncedLayer = syncedLayer
+	s.NetworkInfo.TopLayer = topLayer
+	s.NetworkInfo.VerifiedLayer = verifiedLayer
+	s.saveNetworkInfo()
+}
+
+func (s *Storage) saveNetworkInfo() {
+	fields, err := toFields(&s.NetworkInfo)
+	if err == nil {
+		err = s.db.Upsert(context.Background(), "networkinfo", "1", append(fields, bson.E{Key: "id", Value: 1}))
+	}
+	if err != nil {
+		logging.Error("saveNetworkInfo", err)
+		return
+	}
+	s.invalidate(cache.KeyNetworkInfo)
+}
+
+func (s *Storage) GetEpochNumLayers() uint32 {
+	return s.NetworkInfo.EpochNumLayers
+}
+
+func (s *Storage) getLayerTimestamp(layer uint32) uint32 {
+	if layer == 0 {
+		return s.NetworkInfo.GenesisTime
+	}
+	return s.NetworkInfo.GenesisTime + layer*s.NetworkInfo.LayerDuration
+}
+
+func (s *Storage) OnLayer(in *pb.Layer) {
+	s.archive(storage.ArchiveLayer, []string{fmt.Sprint(in.GetNumber().GetNumber())}, []proto.Message{in})
+	s.layersLock.Lock()
+	defer s.layersLock.Unlock()
+
+	layer, blocks, _, txs := model.NewLayer(in, &s.NetworkInfo)
+	logging.Info("updateLayer", logging.Layer(in.Number.Number), zap.Int("blocks", len(blocks)), zap.Int("txs", len(txs)), zap.String("hash", utils.BytesToHex(in.Hash)))
+	s.received.Apply(txs)
+	ctx := context.Background()
+
+	s.NetworkInfo.LastLayer = layer.Number
+	s.NetworkInfo.LastLayerTimestamp = uint32(time.Now().Unix())
+	if layer.Status == int(pb.Layer_LAYER_STATUS_APPROVED) {
+		s.NetworkInfo.LastApprovedLayer = layer.Number
+	} else if layer.Status == int(pb.Layer_LAYER_STATUS_CONFIRMED) {
+		s.NetworkInfo.LastConfirmedLayer = layer.Number
+	}
+	s.saveNetworkInfo()
+
+	previous, err := s.layerHash(ctx, layer.Number)
+	if err != nil {
+		logging.Error("OnLayer", err)
+	}
+
+	keys := make([]string, 0, len(blocks))
+	docs := make([]bson.D, 0, len(blocks))
+	for _, block := range blocks {
+		fields, err := toFields(block)
+		if err != nil {
+			logging.Error("OnLayer", err)
+			continue
+		}
+		keys = append(keys, block.Id)
+		docs = append(docs, fields)
+	}
+	if err := s.db.UpsertBatch(ctx, "blocks", keys, docs); err != nil {
+		logging.Error("OnLayer: blocks write", err)
+	} else {
+		for _, block := range blocks {
+			s.sinks.Publish(ctx, sink.EntityBlock, block.Id, block)
+		}
+	}
+
+	for _, tx := range txs {
+		if !s.isWatched(tx.Sender, tx.Receiver) && !s.isWatched(tx.TouchedAddresses...) {
+			continue
+		}
+		if err := s.saveTransaction(ctx, tx, false); err != nil {
+			logging.Error("OnLayer: tx write", err)
+			continue
+		}
+		s.sinks.Publish(ctx, sink.EntityTransaction, tx.Id, tx)
+		for _, address := range []string{tx.Sender, tx.Receiver} {
+			if address != "" {
+				s.touchAccount(ctx, layer.Number, address)
+				s.requestBalanceUpdate(layer.Number, address)
+			}
+		}
+		if template := tx.SpawnedTemplate(); template != "" {
+			if err := s.db.Update(ctx, "accounts", tx.Sender, bson.D{{Key: "template", Value: template}}); err != nil {
+				logging.Error("OnLayer: account template write", err)
+			}
+		}
+		if err := s.saveVault(ctx, tx); err != nil {
+			logging.Error("OnLayer: vault write", err)
+		}
+	}
+
+	if storage.LayerReorged(previous, layer.Hash) {
+		if err := s.tombstoneLayer(ctx, layer, blocks, txs); err != nil {
+			logging.Error("OnLayer", err)
+		}
+	}
+
+	fields, err := toFields(layer, "feescollected", "feesdistributed", "feesburned", "feemin", "feemedian", "feemax")
+	if err == nil {
+		err = s.db.Upsert(ctx, "layers", fmt.Sprint(layer.Number), fields)
+	}
+	if err != nil {
+		logging.Error("OnLayer", err)
+	} else {
+		s.sinks.Publish(ctx, sink.EntityLayer, fmt.Sprint(layer.Number), layer)
+		s.invalidate(cache.KeyCurrentLayer, cache.KeyTopAccounts)
+	}
+	s.updateLayerSummary(layer.Number)
+
+	s.setChangedEpoch(layer.Number)
+	s.updateEpochs()
+}
+
+// LayersInQueue always returns 0: layers are written synchronously by OnLayer.
+func (s *Storage) LayersInQueue() int {
+	return 0
+}
+
+func (s *Storage) IsLayerInQueue(layer *pb.Layer) bool {
+	return false
+}
+
+func (s *Storage) GetLastLayer(parent context.Context) (uint32, error) {
+	var layer model.Layer
+	found, err := findOne(parent, s.db, "layers", nil, &layer, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}))
+	if err != nil {
+		return 0, fmt.Errorf("error get last layer: %w", err)
+	}
+	if !found {
+		return 0, nil
+	}
+	return layer.Number, nil
+}
+
+func (s *Storage) GetLayersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.db.Count(parent, "layers", query)
+	if err != nil {
+		return 0, fmt.Errorf("error count layers: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Storage) GetLayerByNumber(parent context.Context, layerNumber uint32) (*model.Layer, error) {
+	var layer model.Layer
+	found, err := findOne(parent, s.db, "layers", &bson.D{{Key: "number", Value: layerNumber}}, &layer)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("layer %d not found", layerNumber)
+	}
+	return &layer, nil
+}
+
+func (s *Storage) OnAccounts(accounts []*types.Account) {
+	ctx := context.Background()
+	keys := make([]string, 0, len(accounts))
+	published := make([]*model.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		if !s.isWatched(acc.Address.String()) {
+			continue
+		}
+		keys = append(keys, acc.Address.String())
+		published = append(published, &model.Account{
+			Address: acc.Address.String(),
+			Balance: acc.Balance,
+			Counter: acc.NextNonce,
+			Created: uint64(acc.Layer.Uint32()),
+		})
+	}
+	previous, err := s.balances(ctx, keys)
+	if err != nil {
+		logging.Error("OnAccounts: get balances", err)
+		return
+	}
+	for _, acc := range published {
+		// the account keeps the first layer it was updated at, see storage.Storage.OnAccounts
+		err := s.db.Apply(ctx, "accounts", acc.Address, Update{
+			Set: bson.D{
+				{Key: "address", Value: acc.Address},
+				{Key: "balance", Value: acc.Balance},
+				{Key: "counter", Value: acc.Counter},
+			},
+			Min:    bson.D{{Key: "created", Value: uint32(acc.Created)}},
+			Inc:    bson.D{{Key: "version", Value: 1}},
+			Upsert: true,
+		})
+		if err != nil {
+			logging.Error("OnAccounts: accounts write", err, logging.Address(acc.Address))
+			return
+		}
+	}
+	changes := make([]*model.BalanceChange, 0, len(published))
+	for _, acc := range published {
+		changes = append(changes, model.NewBalanceChange(acc.Address, uint32(acc.Created), previous[acc.Address], acc.Balance))
+	}
+	if err := s.saveBalanceChanges(ctx, changes); err != nil {
+		logging.Error("OnAccounts", err)
+	}
+	s.invalidate(cache.KeyTopAccounts)
+	for _, acc := range published {
+		s.sinks.Publish(ctx, sink.EntityAccount, acc.Address, acc)
+	}
+}
+
+// saveVault stores the vault spawned by the transaction, if any, see storage.Storage.saveVaults.
+func (s *Storage) saveVault(ctx context.Context, tx *model.Transaction) error {
+	vault, err := model.NewVault(tx)
+	if err != nil || vault == nil {
+		return err
+	}
+	fields, err := toFields(vault)
+	if err != nil {
+		return err
+	}
+	return s.db.Upsert(ctx, "vaults", vault.Address, fields)
+}
+
+// touchAccount creates the account if needed and records the last layer it was seen in.
+func (s *Storage) touchAccount(ctx context.Context, layer uint32, address string) {
+	// balance and counter are refreshed from the node, created keeps the first layer seen
+	err := s.db.Apply(ctx, "accounts", address, Update{
+		Set: bson.D{{Key: "layer", Value: layer}},
+		SetOnInsert: bson.D{
+			{Key: "address", Value: address},
+			{Key: "balance", Value: 0},
+			{Key: "counter", Value: 0},
+			{Key: "created", Value: layer},
+		},
+		Upsert: true,
+	})
+	if err != nil {
+		logging.Error("touchAccount", err)
+	}
+}
+
+func (s *Storage) OnReward(in *pb.Reward) {
+	s.OnRewards([]*pb.Reward{in})
+}
+
+func (s *Storage) OnRewards(in []*pb.Reward) {
+	ctx := context.Background()
+	rewards := make([]*model.Reward, 0, len(in))
+	keys := make([]string, 0, len(in))
+	docs := make([]bson.D, 0, len(in))
+	archived := make([]proto.Message, 0, len(in))
+	chain := make([]*model.Reward, 0, len(in))
+	for _, r := range in {
+		reward := model.NewReward(r)
+		if reward == nil {
+			continue
+		}
+		reward.Timestamp = s.getLayerTimestamp(reward.Layer)
+		chain = append(chain, reward)
+		if !s.isWatched(reward.Coinbase) {
+			continue
+		}
+		reward.ID = fmt.Sprintf("%s-%d", reward.Smesher, reward.Layer)
+		if err := s.linkRewardActivation(ctx, reward); err != nil {
+			logging.Error("OnRewards", err)
+			continue
+		}
+		fields, err := toFields(reward)
+		if err != nil {
+			logging.Error("OnRewards", err)
+			continue
+		}
+		rewards = append(rewards, reward)
+		keys = append(keys, reward.ID)
+		docs = append(docs, fields)
+		archived = append(archived, r)
+	}
+	chainAdded := s.saveChainRewards(ctx, chain)
+	s.archive(storage.ArchiveReward, keys, archived)
+	stored, err := s.db.Existing(ctx, "rewards", keys)
+	if err != nil {
+		logging.Error("OnRewards save", err)
+		return
+	}
+	if err := s.db.UpsertBatch(ctx, "rewards", keys, docs); err != nil {
+		logging.Error("OnRewards save", err)
+		return
+	}
+	added := make([]*model.Reward, 0, len(rewards))
+	for _, reward := range rewards {
+		if !stored[reward.ID] {
+			s.incRewardCounters(ctx, reward)
+			added = append(added, reward)
+		}
+	}
+	// in watch mode the rewards of the layers are summed from the whole chain
+	if s.watched != nil {
+		added = chainAdded
+	}
+	for _, layer := range storage.RewardLayers(added) {
+		s.updateLayerSummary(layer)
+	}
+	for _, reward := range rewards {
+		s.sinks.Publish(ctx, sink.EntityReward, reward.ID, reward)
+		s.touchAccount(ctx, reward.Layer, reward.Coinbase)
+		s.requestBalanceUpdate(reward.Layer, reward.Coinbase)
+	}
+	s.invalidate(cache.KeyTopAccounts)
+}
+
+// incRewardCounters counts the reward stored for the first time on its smesher and coinbase
+// documents, see storage.Storage.incRewardCounters.
+func (s *Storage) incRewardCounters(ctx context.Context, reward *model.Reward) {
+	for table, key := range map[string]string{
+		"smeshers":  reward.Smesher,
+		"coinbases": reward.Smesher + "-" + reward.Coinbase,
+	} {
+		err := s.db.Apply(ctx, table, key, Update{Inc: bson.D{
+			{Key: "totalRewards", Value: reward.Total},
+			{Key: "rewardsCount", Value: 1},
+		}})
+		if err != nil {
+			logging.Error(fmt.Sprintf("OnRewards: %s rewards counters", table), err)
+		}
+	}
+}
+
+func (s *Storage) OnCertificates(certs []*model.BlockCertificate) {
+	ctx := context.Background()
+	keys := make([]string, 0, len(certs))
+	docs := make([]bson.D, 0, len(certs))
+	for _, cert := range certs {
+		fields, err := toFields(cert)
+		if err != nil {
+			logging.Error("OnCertificates", err)
+			continue
+		}
+		keys = append(keys, cert.BlockId)
+		docs = append(docs, fields)
+	}
+	if err := s.db.UpsertBatch(ctx, "certificates", keys, docs); err != nil {
+		logging.Error("OnCertificates", err)
+		return
+	}
+	for _, cert := range certs {
+		s.sinks.Publish(ctx, sink.EntityCertificate, cert.BlockId, cert)
+	}
+}
+
+func (s *Storage) OnBallots(ballots []*model.Ballot) {
+	ctx := context.Background()
+	keys := make([]string, 0, len(ballots))
+	docs := make([]bson.D, 0, len(ballots))
+	for _, ballot := range ballots {
+		fields, err := toFields(ballot)
+		if err != nil {
+			logging.Error("OnBallots", err)
+			continue
+		}
+		keys = append(keys, ballot.Id)
+		docs = append(docs, fields)
+	}
+	if err := s.db.UpsertBatch(ctx, "ballots", keys, docs); err != nil {
+		logging.Error("OnBallots", err)
+	}
+}
+
+func (s *Storage) OnActiveSet(epoch uint32, size uint32) {
+	err := s.db.Upsert(context.Background(), "epochs", fmt.Sprint(epoch), bson.D{
+		{Key: "number", Value: int32(epoch)},
+		{Key: "activeSetSize", Value: size},
+	})
+	if err != nil {
+		logging.Error("OnActiveSet", err)
+	}
+}
+
+func (s *Storage) OnBeacon(epoch uint32, beacon string) {
+	err := s.db.Upsert(context.Background(), "epochs", fmt.Sprint(epoch), bson.D{
+		{Key: "number", Value: int32(epoch)},
+		{Key: "beacon", Value: beacon},
+	})
+	if err != nil {
+		logging.Error("OnBeacon", err)
+	}
+}
+
+func (s *Storage) OnMalfeasanceProof(in *pb.MalfeasanceProof) {
+	proof := model.NewMalfeasanceProof(in)
+	if proof == nil {
+		return
+	}
+	ctx := context.Background()
+	s.archive(storage.ArchiveMalfeasanceProof, []string{fmt.Sprintf("%s-%d", proof.Smesher, proof.Layer)}, []proto.Message{in})
+	key := fmt.Sprintf("%s-%d-%s", proof.Smesher, proof.Layer, proof.Kind)
+	fields, err := toFields(proof)
+	if err == nil {
+		err = s.db.Insert(ctx, "malfeasance_proofs", key, fields)
+	}
+	if err != nil {
+		logging.Error("OnMalfeasanceProof", err)
+		return
+	}
+	s.sinks.Publish(ctx, sink.EntityMalfeasanceProof, proof.Smesher, proof)
+}
+
+// OnTransactionsReceived holds the times the node first received the transactions, until their
+// layer is stored.
+func (s *Storage) OnTransactionsReceived(received map[string]uint32) {
+	s.received.Add(received)
+}
+
+func (s *Storage) OnTransactionResult(res *pb.TransactionResult, state *pb.TransactionState) {
+	tx, err := model.NewTransactionResult(res, state, s.NetworkInfo)
+	if err != nil {
+		logging.Error("OnTransactionResult", err)
+		return
+	}
+	if !s.isWatched(tx.Sender, tx.Receiver) && !s.isWatched(tx.TouchedAddresses...) {
+		return
+	}
+	s.archive(storage.ArchiveTransactionResult, []string{tx.Id}, []proto.Message{res})
+	ctx := context.Background()
+	if err := s.saveTransaction(ctx, tx, true); err != nil {
+		logging.Error("OnTransactionResult", err)
+		return
+	}
+	s.sinks.Publish(ctx, sink.EntityTransaction, tx.Id, tx)
+}
+
+// saveTransaction stores the transaction. Fields known only from the transaction result are not
+// overwritten by the mesh copy of the transaction, and the other way around.
+func (s *Storage) saveTransaction(ctx context.Context, tx *model.Transaction, result bool) error {
+	var existing model.Transaction
+	found, err := findOne(ctx, s.db, "txs", &bson.D{{Key: "id", Value: tx.Id}}, &existing)
+	if err != nil {
+		return err
+	}
+
+	var fields bson.D
+	switch {
+	case !found && result:
+		fields, err = toFields(tx)
+	case !found:
+		fields, err = toFields(tx, "result")
+	case result:
+		fields = bson.D{
+			{Key: "id", Value: tx.Id},
+			{Key: "state", Value: tx.State},
+			{Key: "gasUsed", Value: tx.GasUsed},
+			{Key: "fee", Value: tx.Fee},
+			{Key: "message", Value: tx.Message},
+			{Key: "touchedAddresses", Value: tx.TouchedAddresses},
+			{Key: "result", Value: tx.Result},
+		}
+		if tx.Template != "" {
+			fields = append(fields, bson.E{Key: "template", Value: tx.Template})
+		}
+	default:
+		fields, err = toFields(tx, "state", "gasUsed", "message", "touchedAddresses", "result")
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "txs", tx.Id, fields); err != nil {
+		return err
+	}
+	if !result {
+		return nil
+	}
+	// the result does not move the stored transaction, nor orphan it
+	stored, previous := *tx, (*model.Transaction)(nil)
+	if found {
+		stored.Layer, stored.Orphaned = existing.Layer, existing.Orphaned
+		previous = &existing
+	}
+	if storage.TransactionFee(&stored) != storage.TransactionFee(previous) {
+		s.updateLayerSummary(stored.Layer)
+	}
+	return nil
+}
+
+func (s *Storage) GetTransactions(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]model.Transaction, error) {
+	docs, err := s.db.Find(parent, "txs", query, opts...)
+	if err != nil {
+		logging.Error("GetTransactions", err)
+		return nil, err
+	}
+	txs := make([]model.Transaction, len(docs))
+	for i, doc := range docs {
+		if err := decode(doc, &txs[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	return txs, nil
+}
+
+func (s *Storage) UpdateTransactionState(parent context.Context, id string, state int32) error {
+	var before model.Transaction
+	found, err := findOne(parent, s.db, "txs", &bson.D{{Key: "id", Value: id}}, &before)
+	if err != nil || !found {
+		return err
+	}
+	if err := s.db.Update(parent, "txs", id, bson.D{{Key: "state", Value: state}}); err != nil {
+		logging.Error("UpdateTransactionState", err)
+		return err
+	}
+	after := before
+	after.State = int(state)
+	if storage.TransactionFee(&after) != storage.TransactionFee(&before) {
+		s.updateLayerSummary(before.Layer)
+	}
+	return nil
+}
+
+// RedecodeTransactions re-parses the stored raw payload of every transaction, see
+// storage.Storage.RedecodeTransactions.
+func (s *Storage) RedecodeTransactions(parent context.Context) error {
+	docs, err := s.db.Find(parent, "txs", &bson.D{{Key: "raw", Value: bson.D{{Key: "$exists", Value: true}}}})
+	if err != nil {
+		return fmt.Errorf("error get transactions for re-decoding: %w", err)
+	}
+	var keys []string
+	var updates []bson.D
+	for _, doc := range docs {
+		var tx model.Transaction
+		if err := decode(doc, &tx); err != nil {
+			return fmt.Errorf("error decode transaction: %w", err)
+		}
+		if err := tx.Redecode(); err != nil {
+			logging.Warn("redecode transaction", zap.String("tx", tx.Id), zap.Error(err))
+			continue
+		}
+		keys = append(keys, tx.Id)
+		updates = append(updates, storage.TransactionDecodedFields(&tx))
+	}
+	if err := s.db.UpsertBatch(parent, "txs", keys, updates); err != nil {
+		return fmt.Errorf("error update re-decoded transactions: %w", err)
+	}
+	logging.Info("transactions re-decoded", zap.Int("txs", len(keys)))
+	return nil
+}
+
+func (s *Storage) OnActivation(atx *types.VerifiedActivationTx) {
+	s.OnActivations([]*model.Activation{model.NewActivation(atx)})
+}
+
+func (s *Storage) OnActivations(atxs []*model.Activation) {
+	ctx := context.Background()
+	epochNumLayers := s.GetEpochNumLayers()
+	for _, atx := range atxs {
+		atx.CommitmentSize = uint64(atx.NumUnits) * s.postUnitSize
+	}
+	s.saveChainActivations(ctx, atxs)
+	for _, atx := range atxs {
+		if !s.isWatched(atx.Coinbase) {
+			continue
+		}
+		var label model.Label
+		found, err := findOne(ctx, s.db, "labels", &bson.D{{Key: "kind", Value: model.LabelSmesher}, {Key: "id", Value: atx.SmesherId}}, &label)
+		if err != nil {
+			logging.Error("OnActivations", err)
+			continue
+		}
+		if found {
+			atx.SmesherName = label.Name
+		}
+		fields, err := toFields(atx)
+		if err == nil {
+			err = s.db.Upsert(ctx, "activations", atx.Id, fields)
+		}
+		if err != nil {
+			logging.Error("OnActivations", err)
+			continue
+		}
+		s.sinks.Publish(ctx, sink.EntityActivation, atx.Id, atx)
+		if err := s.linkActivationRewards(ctx, atx); err != nil {
+			logging.Error("OnActivations: link rewards", err)
+		}
+
+		if err := s.saveSmesher(ctx, atx.GetSmesher(s.postUnitSize), atx.TargetEpoch); err != nil {
+			logging.Error("OnActivations: smeshers write", err)
+		}
+		s.touchAccount(ctx, epochNumLayers*atx.PublishEpoch, atx.Coinbase)
+	}
+}
+
+// linkRewardActivation sets the activation which earned the reward, see
+// storage.Storage.linkRewardActivations.
+func (s *Storage) linkRewardActivation(ctx context.Context, reward *model.Reward) error {
+	epochNumLayers := s.GetEpochNumLayers()
+	if epochNumLayers == 0 {
+		return nil
+	}
+	var atx model.Activation
+	found, err := findOne(ctx, s.db, "activations", &bson.D{
+		{Key: "smesher", Value: reward.Smesher},
+		{Key: "targetEpoch", Value: reward.Layer / epochNumLayers},
+	}, &atx)
+	if err != nil || !found {
+		return err
+	}
+	reward.Atx = atx.Id
+	return nil
+}
+
+// linkActivationRewards links the rewards stored before the activation, see
+// storage.Storage.linkActivationRewards.
+func (s *Storage) linkActivationRewards(ctx context.Context, atx *model.Activation) error {
+	epochNumLayers := s.GetEpochNumLayers()
+	if epochNumLayers == 0 {
+		return nil
+	}
+	_, err := s.db.UpdateMany(ctx, "rewards", &bson.D{
+		{Key: "smesher", Value: atx.SmesherId},
+		{Key: "layer", Value: bson.D{
+			{Key: "$gte", Value: atx.TargetEpoch * epochNumLayers},
+			{Key: "$lt", Value: (atx.TargetEpoch + 1) * epochNumLayers},
+		}},
+		{Key: "atx", Value: bson.D{{Key: "$exists", Value: false}}},
+	}, bson.D{{Key: "atx", Value: atx.Id}})
+	return err
+}
+
+// saveSmesher stores the smesher, its coinbase and adds the epoch to the epochs it was active in.
+// The changes of the smesher fields are recorded in smesher_history.
+func (s *Storage) saveSmesher(ctx context.Context, smesher *model.Smesher, epoch uint32) error {
+	if err := s.saveSmesherChange(ctx, smesher, epoch); err != nil {
+		return err
+	}
+	if err := s.saveCoinbase(ctx, smesher.Id, smesher.Coinbase, epoch); err != nil {
+		return err
+	}
+
+	atxCount, err := s.db.Count(ctx, "activations", &bson.D{{Key: "smesher", Value: smesher.Id}})
+	if err != nil {
+		return err
+	}
+	return s.db.Apply(ctx, "smeshers", smesher.Id, Update{
+		Set: bson.D{
+			{Key: "id", Value: smesher.Id},
+			{Key: "cSize", Value: smesher.CommitmentSize},
+			{Key: "coinbase", Value: smesher.Coinbase},
+			{Key: "timestamp", Value: smesher.Timestamp},
+			{Key: "atxcount", Value: atxCount},
+		},
+		AddToSet: bson.D{{Key: "epochs", Value: bson.A{epoch}}},
+		Upsert:   true,
+	})
+}
+
+// saveCoinbase records that the smesher used the coinbase in the epoch, see storage.coinbaseQuery.
+func (s *Storage) saveCoinbase(ctx context.Context, smesher, coinbase string, epoch uint32) error {
+	return s.db.Apply(ctx, "coinbases", smesher+"-"+coinbase, Update{
+		SetOnInsert: bson.D{{Key: "smesherId", Value: smesher}, {Key: "coinbase", Value: coinbase}},
+		Min:         bson.D{{Key: "from", Value: epoch}},
+		Max:         bson.D{{Key: "to", Value: epoch}},
+		AddToSet:    bson.D{{Key: "epochs", Value: bson.A{epoch}}},
+		Upsert:      true,
+	})
+}
+
+func (s *Storage) GetLastActivationReceived() int64 {
+	var atx model.Activation
+	_, err := findOne(context.Background(), s.db, "activations", nil, &atx, options.Find().SetSort(bson.D{{Key: "received", Value: -1}}))
+	if err != nil {
+		logging.Error("GetLastActivationReceived", err)
+	}
+	return atx.Received
+}
+
+// GetLayerHashCheckpoint returns the last layer whose hash was verified, see
+// storage.Storage.GetLayerHashCheckpoint.
+func (s *Storage) GetLayerHashCheckpoint(parent context.Context) (uint32, error) {
+	var info struct {
+		Layer uint32 `bson:"layerhashcheckpoint"`
+	}
+	if _, err := findOne(parent, s.db, "networkinfo", &bson.D{{Key: "id", Value: 1}}, &info); err != nil {
+		return 0, fmt.Errorf("error get layer hash checkpoint: %w", err)
+	}
+	return info.Layer, nil
+}
+
+// SetLayerHashCheckpoint stores the last layer whose hash was verified, see
+// storage.Storage.SetLayerHashCheckpoint.
+func (s *Storage) SetLayerHashCheckpoint(parent context.Context, layer uint32) error {
+	err := s.db.Upsert(parent, "networkinfo", "1", bson.D{{Key: "id", Value: 1}, {Key: "layerhashcheckpoint", Value: layer}})
+	if err != nil {
+		return fmt.Errorf("error set layer hash checkpoint: %w", err)
+	}
+	return nil
+}
+
+// requestBalanceUpdate queues the account for a balance refresh, with the last layer it was
+// touched in.
+func (s *Storage) requestBalanceUpdate(layer uint32, address string) {
+	s.accountsLock.Lock()
+	s.accountsQueue[address] = max(s.accountsQueue[address], layer)
+	s.accountsLock.Unlock()
+	select {
+	case s.accountsReady <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Storage) updateAccounts() {
+	defer s.accountsLoop.Done()
+	for {
+		select {
+		case <-s.accountsReady:
+			s.processAccountsQueue()
+		case <-s.accountsDone:
+			s.processAccountsQueue()
+			return
+		}
+	}
+}
+
+// processAccountsQueue updates the balances of the queued accounts.
+func (s *Storage) processAccountsQueue() {
+	s.accountsLock.Lock()
+	accounts := s.accountsQueue
+	s.accountsQueue = make(map[string]uint32)
+	s.accountsLock.Unlock()
+
+	if s.accountUpdater == nil {
+		return
+	}
+	for address, layer := range accounts {
+		if err := s.updateAccount(context.Background(), address, layer); err != nil {
+			logging.Error("updateAccounts", err)
+		}
+	}
+	s.invalidate(cache.KeyTopAccounts)
+}
+
+// updateAccount refreshes the balance of the account from the node, compared and set on the
+// version of the account read before the node request, see storage.Storage.updateAccount.
+func (s *Storage) updateAccount(ctx context.Context, address string, layer uint32) error {
+	for attempt := 0; attempt < storage.AccountUpdateAttempts; attempt++ {
+		var account model.Account
+		found, err := findOne(ctx, s.db, "accounts", &bson.D{{Key: "address", Value: address}}, &account)
+		if err != nil || !found {
+			return err
+		}
+		balance, counter, err := s.accountUpdater.GetAccountState(address)
+		if err != nil {
+			return nil
+		}
+		updated, err := s.db.CompareAndSet(ctx, "accounts", address, account.Version, bson.D{
+			{Key: "balance", Value: balance},
+			{Key: "counter", Value: counter},
+		})
+		if err != nil {
+			return err
+		}
+		if updated {
+			return s.saveBalanceChanges(ctx, []*model.BalanceChange{
+				model.NewBalanceChange(address, layer, account.Balance, balance),
+			})
+		}
+		logging.Info("update account: changed during the update, retrying", logging.Address(address))
+	}
+	return storage.ErrStaleAccount
+}
+
+// updateLayerSummary recomputes the fee accounting and the rewards sum of the layer, see
+// storage.Storage.updateLayerSummary.
+func (s *Storage) updateLayerSummary(layer uint32) {
+	s.summaryLock.Lock()
+	defer s.summaryLock.Unlock()
+
+	collected, distributed := s.getLayersFees(context.Background(), layer, layer)
+	rewards, err := s.db.Sum(context.Background(), s.aggregateSource("rewards"), &bson.D{{Key: "layer", Value: layer}}, "total")
+	if err != nil {
+		logging.Error("updateLayerSummary", err)
+		return
+	}
+	burned := uint64(0)
+	if collected > distributed {
+		burned = collected - distributed
+	}
+	fees, err := s.getFeeStats(context.Background(), layer, layer)
+	if err != nil {
+		logging.Error("updateLayerSummary", err)
+	}
+	err = s.db.Update(context.Background(), "layers", fmt.Sprint(layer), bson.D{
+		{Key: "feescollected", Value: collected},
+		{Key: "feesdistributed", Value: distributed},
+		{Key: "feesburned", Value: burned},
+		{Key: "feemin", Value: fees.Min},
+		{Key: "feemedian", Value: fees.Median},
+		{Key: "feemax", Value: fees.Max},
+		{Key: "rewards", Value: uint64(rewards[0])},
+	})
+	if err != nil {
+		logging.Error("updateLayerSummary", err)
+	}
+}
+
+func (s *Storage) getLayersFees(ctx context.Context, from, to uint32) (collected, distributed uint64) {
+	layerRange := bson.E{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}}
+	fees, err := s.db.Sum(ctx, "txs", &bson.D{layerRange, {Key: "state", Value: int(pb.TransactionState_TRANSACTION_STATE_PROCESSED)}, storage.NotOrphaned}, "fee")
+	if err != nil {
+		logging.Error("getLayersFees", err)
+		return 0, 0
+	}
+	rewards, err := s.db.Sum(ctx, "rewards", &bson.D{layerRange}, "total", "layerReward")
+	if err != nil {
+		logging.Error("getLayersFees", err)
+		return uint64(fees[0]), 0
+	}
+	return uint64(fees[0]), uint64(rewards[0] - rewards[1])
+}
+
+// getFeeStats returns the lowest, median and highest fees of the processed transactions of the
+// layers in the range [from, to], see storage.Storage.getFeeStats.
+func (s *Storage) getFeeStats(ctx context.Context, from, to uint32) (model.FeeStats, error) {
+	docs, err := s.db.Find(ctx, "txs", &bson.D{
+		{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+		{Key: "state", Value: int(pb.TransactionState_TRANSACTION_STATE_PROCESSED)},
+		storage.NotOrphaned,
+	})
+	if err != nil {
+		return model.FeeStats{}, err
+	}
+	txs, err := decodeAll[model.Transaction](docs)
+	if err != nil {
+		return model.FeeStats{}, err
+	}
+	fees := make([]uint64, 0, len(txs))
+	for _, tx := range txs {
+		fees = append(fees, tx.Fee)
+	}
+	return model.NewFeeStats(fees), nil
+}
+
+// getInclusionStats returns the stats of the delays between the first sighting and the inclusion of
+// the transactions of the layers in the range [from, to] whose received time is known.
+func (s *Storage) getInclusionStats(ctx context.Context, from, to uint32) (model.InclusionStats, error) {
+	docs, err := s.db.Find(ctx, "txs", &bson.D{
+		{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+		storage.NotOrphaned,
+	})
+	if err != nil {
+		return model.InclusionStats{}, err
+	}
+	txs, err := decodeAll[model.Transaction](docs)
+	if err != nil {
+		return model.InclusionStats{}, err
+	}
+	delays := make([]int64, 0, len(txs))
+	for _, tx := range txs {
+		if delay, ok := tx.InclusionDelay(); ok {
+			delays = append(delays, delay)
+		}
+	}
+	return model.NewInclusionStats(delays), nil
+}
+
+// getBallotStats returns the participation of the smeshers in the ballots of the layers in the range
+// [from, to].
+func (s *Storage) getBallotStats(ctx context.Context, from, to uint32) (model.BallotStats, error) {
+	docs, err := s.db.Find(ctx, "ballots", &bson.D{
+		{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+	})
+	if err != nil {
+		return model.BallotStats{}, err
+	}
+	ballots, err := decodeAll[model.Ballot](docs)
+	if err != nil {
+		return model.BallotStats{}, err
+	}
+	return model.NewBallotStats(ballots), nil
+}
+
+func (s *Storage) UpdateEpochStats(layer uint32) {
+	s.layersLock.Lock()
+	defer s.layersLock.Unlock()
+	s.updateLayerSummary(layer)
+	s.setChangedEpoch(layer)
+	s.updateEpochs()
+}
+
+func (s *Storage) RecalculateEpochStats() {
+	currentEpoch := utils.LayerEpoch(s.NetworkInfo.VerifiedLayer, s.NetworkInfo.EpochNumLayers)
+	for i := 0; i <= int(currentEpoch+1); i++ {
+		s.UpdateEpochStats(uint32(i) * s.NetworkInfo.EpochNumLayers)
+	}
+}
+
+// setChangedEpoch must be called with layersLock held.
+func (s *Storage) setChangedEpoch(layer uint32) {
+	if s.NetworkInfo.EpochNumLayers == 0 {
+		return
+	}
+	epoch := int32(utils.LayerEpoch(layer, s.NetworkInfo.EpochNumLayers))
+	if s.changedEpoch < 0 || s.changedEpoch > epoch {
+		s.changedEpoch = epoch
+	}
+	if epoch > s.lastEpoch {
+		s.lastEpoch = epoch
+	}
+}
+
+// updateEpochs must be called with layersLock held.
+func (s *Storage) updateEpochs() {
+	epochNumber := s.changedEpoch
+	if epochNumber < 0 {
+		return
+	}
+	s.changedEpoch = -1
+
+	var prev *model.Epoch
+	if epochNumber > 0 {
+		var epoch model.Epoch
+		found, err := findOne(context.Background(), s.db, "epochs", &bson.D{{Key: "number", Value: epochNumber - 1}}, &epoch)
+		if err != nil {
+			logging.Error("updateEpochs", err)
+		}
+		if found {
+			prev = &epoch
+		}
+	}
+	for i := epochNumber; i <= s.lastEpoch; i++ {
+		prev = s.updateEpoch(i, prev)
+	}
+}
+
+// updateEpoch mirrors storage.Storage.updateEpoch.
+func (s *Storage) updateEpoch(epochNumber int32, prev *model.Epoch) *model.Epoch {
+	epoch := &model.Epoch{Number: epochNumber, StatsVersion: model.EpochStatsVersion}
+	s.computeStatistics(epoch)
+	if prev != nil {
+		epoch.Stats.Cumulative.Capacity = epoch.Stats.Current.Capacity
+		epoch.Stats.Cumulative.Decentral = prev.Stats.Current.Decentral
+		epoch.Stats.Cumulative.Smeshers = epoch.Stats.Current.Smeshers
+		epoch.Stats.Cumulative.Transactions = prev.Stats.Cumulative.Transactions + epoch.Stats.Current.Transactions
+		epoch.Stats.Cumulative.Accounts = epoch.Stats.Current.Accounts
+		epoch.Stats.Cumulative.Rewards = prev.Stats.Cumulative.Rewards + epoch.Stats.Current.Rewards
+		epoch.Stats.Cumulative.RewardsNumber = prev.Stats.Cumulative.RewardsNumber + epoch.Stats.Current.RewardsNumber
+		epoch.Stats.Cumulative.Security = prev.Stats.Current.Security
+		epoch.Stats.Cumulative.TxsAmount = prev.Stats.Cumulative.TxsAmount + epoch.Stats.Current.TxsAmount
+		epoch.Stats.Cumulative.FeesDistributed = prev.Stats.Cumulative.FeesDistributed + epoch.Stats.Current.FeesDistributed
+		epoch.Stats.Cumulative.FeesBurned = prev.Stats.Cumulative.FeesBurned + epoch.Stats.Current.FeesBurned
+		epoch.Stats.Cumulative.SpaceGini = epoch.Stats.Current.SpaceGini
+		epoch.Stats.Cumulative.SpaceNakamoto = epoch.Stats.Current.SpaceNakamoto
+		epoch.Stats.Cumulative.RewardsGini = epoch.Stats.Current.RewardsGini
+		epoch.Stats.Cumulative.RewardsNakamoto = epoch.Stats.Current.RewardsNakamoto
+		epoch.Stats.Cumulative.NewSmeshers = epoch.Stats.Current.NewSmeshers
+		epoch.Stats.Cumulative.ReturningSmeshers = epoch.Stats.Current.ReturningSmeshers
+		epoch.Stats.Cumulative.StoppedSmeshers = epoch.Stats.Current.StoppedSmeshers
+		epoch.Stats.Current.SpaceDelta = epoch.Stats.Current.Space - prev.Stats.Current.Space
+		epoch.Stats.Cumulative.Space = epoch.Stats.Current.Space
+		epoch.Stats.Cumulative.SpaceDelta = epoch.Stats.Current.SpaceDelta
+		epoch.Stats.Current.NewAccounts = epoch.Stats.Current.Accounts - prev.Stats.Current.Accounts
+		epoch.Stats.Cumulative.NewAccounts = epoch.Stats.Current.NewAccounts
+		epoch.Stats.Cumulative.FeesCollected = prev.Stats.Cumulative.FeesCollected + epoch.Stats.Current.FeesCollected
+		epoch.Stats.Cumulative.FeeMin = epoch.Stats.Current.FeeMin
+		epoch.Stats.Cumulative.FeeMedian = epoch.Stats.Current.FeeMedian
+		epoch.Stats.Cumulative.FeeMax = epoch.Stats.Current.FeeMax
+		epoch.Stats.Cumulative.EmptyLayers = prev.Stats.Cumulative.EmptyLayers + epoch.Stats.Current.EmptyLayers
+		epoch.Stats.Cumulative.LayersWithoutTxs = prev.Stats.Cumulative.LayersWithoutTxs + epoch.Stats.Current.LayersWithoutTxs
+		epoch.Stats.Cumulative.RewardedSmeshers = epoch.Stats.Current.RewardedSmeshers
+		epoch.Stats.Cumulative.EffectiveNumUnits = epoch.Stats.Current.EffectiveNumUnits
+		epoch.Stats.Cumulative.Weight = epoch.Stats.Current.Weight
+		epoch.Stats.Cumulative.InclusionTxs = epoch.Stats.Current.InclusionTxs
+		epoch.Stats.Cumulative.InclusionMean = epoch.Stats.Current.InclusionMean
+		epoch.Stats.Cumulative.InclusionMedian = epoch.Stats.Current.InclusionMedian
+		epoch.Stats.Cumulative.InclusionP90 = epoch.Stats.Current.InclusionP90
+		epoch.Stats.Cumulative.InclusionP99 = epoch.Stats.Current.InclusionP99
+		epoch.Stats.Cumulative.Voters = epoch.Stats.Current.Voters
+		epoch.Stats.Cumulative.Ballots = epoch.Stats.Current.Ballots
+		epoch.Stats.Cumulative.Votes = epoch.Stats.Current.Votes
+		epoch.Stats.Cumulative.BallotEligibilities = epoch.Stats.Current.BallotEligibilities
+		epoch.Stats.Current.Circulation = epoch.Stats.Cumulative.Rewards
+		epoch.Stats.Cumulative.Circulation = epoch.Stats.Current.Circulation
+	} else {
+		epoch.Stats.Current.Circulation = epoch.Stats.Current.Rewards
+		epoch.Stats.Current.SpaceDelta = epoch.Stats.Current.Space
+		epoch.Stats.Current.NewAccounts = epoch.Stats.Current.Accounts
+		epoch.Stats.Cumulative = epoch.Stats.Current
+	}
+
+	fields, err := toFields(epoch, "activeSetSize", "beacon")
+	if err == nil {
+		err = s.db.Upsert(context.Background(), "epochs", fmt.Sprint(epochNumber), fields)
+	}
+	if err == nil {
+		err = s.saveEpochStats(context.Background(), epoch)
+	}
+	if err != nil {
+		logging.Error("updateEpoch", err, logging.Epoch(uint32(epochNumber)))
+	} else {
+		s.invalidate(cache.KeyCurrentEpoch)
+	}
+	return epoch
+}
+
+// computeStatistics mirrors storage.Storage.computeStatistics.
+func (s *Storage) computeStatistics(epoch *model.Epoch) {
+	ctx := context.Background()
+	layerStart, layerEnd := utils.EpochLayers(uint32(epoch.Number), s.NetworkInfo.EpochNumLayers)
+	epoch.LayerStart = layerStart
+	epoch.Start = s.getLayerTimestamp(layerStart)
+	epoch.LayerEnd = layerEnd
+	epoch.End = s.getLayerTimestamp(layerEnd) + s.NetworkInfo.LayerDuration - 1
+	epoch.Layers = epoch.LayerEnd - epoch.LayerStart + 1
+
+	layersFilter := bson.E{Key: "number", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}
+	layers, err := s.db.Count(ctx, "layers", &bson.D{layersFilter})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	}
+	epoch.Stats.Current.EmptyLayers, err = s.db.Count(ctx, "layers", &bson.D{layersFilter, {Key: "blocksnumber", Value: 0}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	}
+	epoch.Stats.Current.LayersWithoutTxs, err = s.db.Count(ctx, "layers", &bson.D{layersFilter, {Key: "txs", Value: 0}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	}
+	duration := float64(s.NetworkInfo.LayerDuration) * float64(layers)
+
+	var txs []int64
+	if s.watched != nil {
+		// the stored transactions are only the watched ones, the layers count all of them
+		txs, err = s.db.Sum(ctx, "layers", &bson.D{layersFilter}, "txsamount", "txs")
+	} else {
+		txs, err = s.db.Sum(ctx, "txs", &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}}, "amount")
+	}
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		epoch.Stats.Current.TxsAmount, epoch.Stats.Current.Transactions = txs[0], txs[1]
+	}
+	if duration > 0 && s.NetworkInfo.MaxTransactionsPerSecond > 0 {
+		epoch.Stats.Current.Capacity = int64(math.Round(((float64(epoch.Stats.Current.Transactions) / duration) / float64(s.NetworkInfo.MaxTransactionsPerSecond)) * 100.0))
+	}
+
+	docs, err := s.db.Find(ctx, s.aggregateSource("activations"), &bson.D{{Key: "targetEpoch", Value: epoch.Number}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else if atxs, err := decodeAll[model.Activation](docs); err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		smeshers := make(map[string]int64)
+		for _, atx := range atxs {
+			if atx.SmesherId != "" {
+				smeshers[atx.SmesherId] += int64(atx.CommitmentSize)
+				epoch.Stats.Current.Security += int64(atx.CommitmentSize)
+				epoch.Stats.Current.Space += int64(atx.EffectiveNumUnits) * int64(s.postUnitSize)
+				epoch.Stats.Current.EffectiveNumUnits += int64(atx.EffectiveNumUnits)
+				epoch.Stats.Current.Weight += int64(atx.Weight)
+			}
+		}
+		epoch.Stats.Current.Smeshers = int64(len(smeshers))
+		a := math.Min(float64(epoch.Stats.Current.Smeshers), 1e4)
+		epoch.Stats.Current.Decentral = int64(100.0 * (0.5*(a*a)/1e8 + 0.5*(1.0-utils.Gini(smeshers))))
+		if len(smeshers) > 0 {
+			epoch.Stats.Current.SpaceGini = utils.GiniBasisPoints(smeshers)
+			epoch.Stats.Current.SpaceNakamoto = utils.Nakamoto(smeshers)
+		}
+	}
+
+	docs, err = s.db.Find(ctx, s.aggregateSource("rewards"), &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else if rewards, err := decodeAll[model.Reward](docs); err != nil {
+		logging.Error("computeStatistics", err)
+	} else if len(rewards) > 0 {
+		smeshers := make(map[string]int64)
+		for _, reward := range rewards {
+			smeshers[reward.Smesher] += int64(reward.Total)
+		}
+		epoch.Stats.Current.RewardedSmeshers = int64(len(smeshers))
+		epoch.Stats.Current.RewardsGini = utils.GiniBasisPoints(smeshers)
+		epoch.Stats.Current.RewardsNakamoto = utils.Nakamoto(smeshers)
+	}
+
+	docs, err = s.db.Find(ctx, s.aggregateSource("activations"), &bson.D{{Key: "targetEpoch", Value: bson.D{{Key: "$lte", Value: epoch.Number}}}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else if atxs, err := decodeAll[model.Activation](docs); err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		current, previous, earlier := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+		for _, atx := range atxs {
+			switch {
+			case atx.SmesherId == "":
+			case int32(atx.TargetEpoch) == epoch.Number:
+				current[atx.SmesherId] = true
+			case int32(atx.TargetEpoch) == epoch.Number-1:
+				previous[atx.SmesherId] = true
+			default:
+				earlier[atx.SmesherId] = true
+			}
+		}
+		epoch.Stats.Current.NewSmeshers, epoch.Stats.Current.ReturningSmeshers, epoch.Stats.Current.StoppedSmeshers = utils.SmeshersChurn(current, previous, earlier)
+	}
+
+	collected, distributed := s.getLayersFees(ctx, layerStart, layerEnd)
+	epoch.Stats.Current.FeesCollected = int64(collected)
+	epoch.Stats.Current.FeesDistributed = int64(distributed)
+	if collected > distributed {
+		epoch.Stats.Current.FeesBurned = int64(collected - distributed)
+	}
+	fees, err := s.getFeeStats(ctx, layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		epoch.Stats.Current.FeeMin = int64(fees.Min)
+		epoch.Stats.Current.FeeMedian = int64(fees.Median)
+		epoch.Stats.Current.FeeMax = int64(fees.Max)
+	}
+	inclusion, err := s.getInclusionStats(ctx, layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		epoch.Stats.Current.InclusionTxs = inclusion.Txs
+		epoch.Stats.Current.InclusionMean = inclusion.Mean
+		epoch.Stats.Current.InclusionMedian = inclusion.Median
+		epoch.Stats.Current.InclusionP90 = inclusion.P90
+		epoch.Stats.Current.InclusionP99 = inclusion.P99
+	}
+	ballots, err := s.getBallotStats(ctx, layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	} else {
+		epoch.Stats.Current.Voters = ballots.Voters
+		epoch.Stats.Current.Ballots = ballots.Ballots
+		epoch.Stats.Current.Votes = ballots.Votes
+		epoch.Stats.Current.BallotEligibilities = ballots.Eligibilities
+	}
+	epoch.Stats.Current.Accounts, err = s.db.Count(ctx, "accounts", &bson.D{{Key: "created", Value: bson.D{{Key: "$lte", Value: layerEnd}}}})
+	if err != nil {
+		logging.Error("computeStatistics", err)
+	}
+}
+
+// toFields returns the bson fields of a model, without the given keys.
+func toFields(v any, without ...string) (bson.D, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.D
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if len(without) == 0 {
+		return fields, nil
+	}
+	skip := make(map[string]bool, len(without))
+	for _, key := range without {
+		skip[key] = true
+	}
+	result := fields[:0]
+	for _, e := range fields {
+		if !skip[e.Key] {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
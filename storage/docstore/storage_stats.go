@@ -0,0 +1,20 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// GetStorageStats returns the number of documents of every collection and its size when the engine
+// knows it, see storagereader.Reader.GetStorageStats.
+func (r *Reader) GetStorageStats(ctx context.Context) ([]*model.CollectionStats, error) {
+	stats, err := r.db.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error get storage stats: %w", err)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Collection < stats[j].Collection })
+	return stats, nil
+}
@@ -0,0 +1,125 @@
+package docstore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// UpsertEpoch stores the epoch as is, see storage.Storage.UpsertEpoch.
+func (s *Storage) UpsertEpoch(ctx context.Context, epoch *model.Epoch) error {
+	fields, err := toFields(epoch, "activeSetSize", "beacon")
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "epochs", fmt.Sprint(epoch.Number), fields); err != nil {
+		return fmt.Errorf("error save epoch: %w", err)
+	}
+	return nil
+}
+
+// UpsertLayer stores the layer as is, see storage.Storage.UpsertLayer.
+func (s *Storage) UpsertLayer(ctx context.Context, layer *model.Layer) error {
+	fields, err := toFields(layer)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "layers", fmt.Sprint(layer.Number), fields); err != nil {
+		return fmt.Errorf("error save layer: %w", err)
+	}
+	return nil
+}
+
+// UpsertTransaction stores the transaction from the mesh, see storage.Storage.UpsertTransaction.
+func (s *Storage) UpsertTransaction(ctx context.Context, tx *model.Transaction) error {
+	return s.saveTransaction(ctx, tx, false)
+}
+
+// UpsertTransactionResult stores the result of the transaction, see
+// storage.Storage.UpsertTransactionResult.
+func (s *Storage) UpsertTransactionResult(ctx context.Context, tx *model.Transaction) error {
+	return s.saveTransaction(ctx, tx, true)
+}
+
+// UpsertReward stores the reward as is, see storage.Storage.UpsertReward.
+func (s *Storage) UpsertReward(ctx context.Context, reward *model.Reward) error {
+	key := fmt.Sprintf("%s-%d", reward.Smesher, reward.Layer)
+	// the id is the key, as for the rewards from the node
+	keyed := *reward
+	keyed.ID = key
+	fields, err := toFields(&keyed)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "rewards", key, fields); err != nil {
+		return fmt.Errorf("error save reward: %w", err)
+	}
+	return nil
+}
+
+// UpsertActivation stores the activation as is, see storage.Storage.UpsertActivation.
+func (s *Storage) UpsertActivation(ctx context.Context, atx *model.Activation) error {
+	fields, err := toFields(atx)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "activations", atx.Id, fields); err != nil {
+		return fmt.Errorf("error save activation: %w", err)
+	}
+	return nil
+}
+
+// UpsertSmesher stores the smesher active in the epoch, see storage.Storage.UpsertSmesher.
+func (s *Storage) UpsertSmesher(ctx context.Context, smesher *model.Smesher, epoch uint32) error {
+	return s.saveSmesher(ctx, smesher, epoch)
+}
+
+// UpsertBlock stores the block as is, see storage.Storage.UpsertBlock.
+func (s *Storage) UpsertBlock(ctx context.Context, block *model.Block) error {
+	fields, err := toFields(block)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "blocks", block.Id, fields); err != nil {
+		return fmt.Errorf("error save block: %w", err)
+	}
+	return nil
+}
+
+// UpsertAccount stores the account seen at the layer, see storage.Storage.UpsertAccount.
+func (s *Storage) UpsertAccount(ctx context.Context, layer uint32, account *model.Account) error {
+	fields := bson.D{
+		{Key: "address", Value: account.Address},
+		{Key: "layer", Value: layer},
+		{Key: "balance", Value: account.Balance},
+		{Key: "counter", Value: account.Counter},
+	}
+	if account.Template != "" {
+		fields = append(fields, bson.E{Key: "template", Value: account.Template})
+	}
+	err := s.db.Apply(ctx, "accounts", account.Address, Update{
+		Set:    fields,
+		Min:    bson.D{{Key: "created", Value: layer}},
+		Inc:    bson.D{{Key: "version", Value: 1}},
+		Upsert: true,
+	})
+	if err != nil {
+		return fmt.Errorf("error save account: %w", err)
+	}
+	return nil
+}
+
+// UpsertVault stores the vault as is, see storage.Storage.UpsertVault.
+func (s *Storage) UpsertVault(ctx context.Context, vault *model.Vault) error {
+	fields, err := toFields(vault)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Upsert(ctx, "vaults", vault.Address, fields); err != nil {
+		return fmt.Errorf("error save vault: %w", err)
+	}
+	return nil
+}
@@ -6,21 +6,21 @@ import (
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"math"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) InitEpochsStorage(ctx context.Context) error {
-	_, err := s.db.Collection("epochs").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "number", Value: 1}}, Options: options.Index().SetName("numberIndex").SetUnique(true)})
-	return err
+	if err := s.createIndexes(ctx, "epochs"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "epochs")
 }
 
 func (s *Storage) GetEpochByNumber(parent context.Context, epochNumber int32) (*model.Epoch, error) {
@@ -28,49 +28,15 @@ func (s *Storage) GetEpochByNumber(parent context.Context, epochNumber int32) (*
 }
 
 func (s *Storage) GetEpoch(parent context.Context, query *bson.D) (*model.Epoch, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("epochs").Find(ctx, query)
-	if err != nil {
-		log.Info("GetEpoch: %v", err)
+	epoch := &model.Epoch{}
+	if err := s.findOne(parent, "epochs", query, epoch); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetEpoch: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetEpoch", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetEpoch: Empty result", err)
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	epoch := &model.Epoch{
-		Number:     utils.GetAsInt32(doc.Lookup("number")),
-		Start:      utils.GetAsUInt32(doc.Lookup("start")),
-		End:        utils.GetAsUInt32(doc.Lookup("end")),
-		LayerStart: utils.GetAsUInt32(doc.Lookup("layerstart")),
-		LayerEnd:   utils.GetAsUInt32(doc.Lookup("layerend")),
-		Layers:     utils.GetAsUInt32(doc.Lookup("layers")),
-	}
-	stats := doc.Lookup("stats").Document()
-	current := stats.Lookup("current").Document()
-	epoch.Stats.Current.Capacity = utils.GetAsInt64(current.Lookup("capacity"))
-	epoch.Stats.Current.Decentral = utils.GetAsInt64(current.Lookup("decentral"))
-	epoch.Stats.Current.Smeshers = utils.GetAsInt64(current.Lookup("smeshers"))
-	epoch.Stats.Current.Transactions = utils.GetAsInt64(current.Lookup("transactions"))
-	epoch.Stats.Current.Accounts = utils.GetAsInt64(current.Lookup("accounts"))
-	epoch.Stats.Current.Circulation = utils.GetAsInt64(current.Lookup("circulation"))
-	epoch.Stats.Current.Rewards = utils.GetAsInt64(current.Lookup("rewards"))
-	epoch.Stats.Current.RewardsNumber = utils.GetAsInt64(current.Lookup("rewardsnumber"))
-	epoch.Stats.Current.Security = utils.GetAsInt64(current.Lookup("security"))
-	epoch.Stats.Current.TxsAmount = utils.GetAsInt64(current.Lookup("txsamount"))
-	cumulative := stats.Lookup("cumulative").Document()
-	epoch.Stats.Cumulative.Capacity = utils.GetAsInt64(cumulative.Lookup("capacity"))
-	epoch.Stats.Cumulative.Decentral = utils.GetAsInt64(cumulative.Lookup("decentral"))
-	epoch.Stats.Cumulative.Smeshers = utils.GetAsInt64(cumulative.Lookup("smeshers"))
-	epoch.Stats.Cumulative.Transactions = utils.GetAsInt64(cumulative.Lookup("transactions"))
-	epoch.Stats.Cumulative.Accounts = utils.GetAsInt64(cumulative.Lookup("accounts"))
-	epoch.Stats.Cumulative.Circulation = utils.GetAsInt64(cumulative.Lookup("circulation"))
-	epoch.Stats.Cumulative.Rewards = utils.GetAsInt64(cumulative.Lookup("rewards"))
-	epoch.Stats.Cumulative.RewardsNumber = utils.GetAsInt64(cumulative.Lookup("rewardsnumber"))
-	epoch.Stats.Cumulative.Security = utils.GetAsInt64(cumulative.Lookup("security"))
-	epoch.Stats.Cumulative.TxsAmount = utils.GetAsInt64(cumulative.Lookup("txsamount"))
 	return epoch, nil
 }
 
@@ -87,7 +53,7 @@ func (s *Storage) GetEpochsData(parent context.Context, query *bson.D, opts ...*
 		bson.D{
 			{Key: "$lookup",
 				Value: bson.D{
-					{Key: "from", Value: "rewards"},
+					{Key: "from", Value: s.db.CollectionName("rewards")},
 					{Key: "let",
 						Value: bson.D{
 							{Key: "start", Value: "$layerstart"},
@@ -237,49 +203,41 @@ func (s *Storage) GetCirculation(parent context.Context) (int64, error) {
 }
 
 func (s *Storage) GetEpochsCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("epochs").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(parent, "epochs", query, opts...)
 	if err != nil {
-		log.Info("GetEpochsCount: %v", err)
+		logging.Error("GetEpochsCount", err)
 		return 0
 	}
 	return count
 }
 
 func (s *Storage) GetEpochs(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("epochs").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetEpochs: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "epochs", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetEpochs: %v", err)
+		logging.Error("GetEpochs", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
-		log.Info("GetEpochs: Empty result", err)
+		logging.Info("GetEpochs: Empty result")
 		return nil, nil
 	}
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) SaveEpoch(parent context.Context, epoch *model.Epoch) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertEpoch stores the epoch and its statistics keyed on the epoch number.
+func (s *Storage) UpsertEpoch(parent context.Context, epoch *model.Epoch) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
-	_, err := s.db.Collection("epochs").UpdateOne(ctx, bson.D{{Key: "number", Value: epoch.Number}}, bson.D{{
-		Key: "$set",
-		Value: bson.D{
+	_, err := s.db.Collection("epochs").UpdateOne(ctx, bson.D{{Key: "number", Value: epoch.Number}}, bson.D{
+		{Key: "$set", Value: bson.D{
 			{Key: "number", Value: epoch.Number},
 			{Key: "start", Value: epoch.Start},
 			{Key: "end", Value: epoch.End},
 			{Key: "layerstart", Value: epoch.LayerStart},
 			{Key: "layerend", Value: epoch.LayerEnd},
 			{Key: "layers", Value: epoch.Layers},
+			{Key: "statsVersion", Value: epoch.StatsVersion},
 			{Key: "stats", Value: bson.D{
 				{Key: "current", Value: bson.D{
 					{Key: "capacity", Value: epoch.Stats.Current.Capacity},
@@ -292,6 +250,36 @@ func (s *Storage) SaveEpoch(parent context.Context, epoch *model.Epoch) error {
 					{Key: "rewardsnumber", Value: epoch.Stats.Current.RewardsNumber},
 					{Key: "security", Value: epoch.Stats.Current.Security},
 					{Key: "txsamount", Value: epoch.Stats.Current.TxsAmount},
+					{Key: "feesdistributed", Value: epoch.Stats.Current.FeesDistributed},
+					{Key: "feesburned", Value: epoch.Stats.Current.FeesBurned},
+					{Key: "spacegini", Value: epoch.Stats.Current.SpaceGini},
+					{Key: "spacenakamoto", Value: epoch.Stats.Current.SpaceNakamoto},
+					{Key: "rewardsgini", Value: epoch.Stats.Current.RewardsGini},
+					{Key: "rewardsnakamoto", Value: epoch.Stats.Current.RewardsNakamoto},
+					{Key: "newsmeshers", Value: epoch.Stats.Current.NewSmeshers},
+					{Key: "returningsmeshers", Value: epoch.Stats.Current.ReturningSmeshers},
+					{Key: "stoppedsmeshers", Value: epoch.Stats.Current.StoppedSmeshers},
+					{Key: "space", Value: epoch.Stats.Current.Space},
+					{Key: "spacedelta", Value: epoch.Stats.Current.SpaceDelta},
+					{Key: "newaccounts", Value: epoch.Stats.Current.NewAccounts},
+					{Key: "feescollected", Value: epoch.Stats.Current.FeesCollected},
+					{Key: "feemin", Value: epoch.Stats.Current.FeeMin},
+					{Key: "feemedian", Value: epoch.Stats.Current.FeeMedian},
+					{Key: "feemax", Value: epoch.Stats.Current.FeeMax},
+					{Key: "emptylayers", Value: epoch.Stats.Current.EmptyLayers},
+					{Key: "layerswithouttxs", Value: epoch.Stats.Current.LayersWithoutTxs},
+					{Key: "rewardedsmeshers", Value: epoch.Stats.Current.RewardedSmeshers},
+					{Key: "effectivenumunits", Value: epoch.Stats.Current.EffectiveNumUnits},
+					{Key: "weight", Value: epoch.Stats.Current.Weight},
+					{Key: "inclusiontxs", Value: epoch.Stats.Current.InclusionTxs},
+					{Key: "inclusionmean", Value: epoch.Stats.Current.InclusionMean},
+					{Key: "inclusionmedian", Value: epoch.Stats.Current.InclusionMedian},
+					{Key: "inclusionp90", Value: epoch.Stats.Current.InclusionP90},
+					{Key: "inclusionp99", Value: epoch.Stats.Current.InclusionP99},
+					{Key: "voters", Value: epoch.Stats.Current.Voters},
+					{Key: "ballots", Value: epoch.Stats.Current.Ballots},
+					{Key: "votes", Value: epoch.Stats.Current.Votes},
+					{Key: "balloteligibilities", Value: epoch.Stats.Current.BallotEligibilities},
 				}},
 				{Key: "cumulative", Value: bson.D{
 					{Key: "capacity", Value: epoch.Stats.Cumulative.Capacity},
@@ -304,57 +292,74 @@ func (s *Storage) SaveEpoch(parent context.Context, epoch *model.Epoch) error {
 					{Key: "rewardsnumber", Value: epoch.Stats.Cumulative.RewardsNumber},
 					{Key: "security", Value: epoch.Stats.Cumulative.Security},
 					{Key: "txsamount", Value: epoch.Stats.Cumulative.TxsAmount},
+					{Key: "feesdistributed", Value: epoch.Stats.Cumulative.FeesDistributed},
+					{Key: "feesburned", Value: epoch.Stats.Cumulative.FeesBurned},
+					{Key: "spacegini", Value: epoch.Stats.Cumulative.SpaceGini},
+					{Key: "spacenakamoto", Value: epoch.Stats.Cumulative.SpaceNakamoto},
+					{Key: "rewardsgini", Value: epoch.Stats.Cumulative.RewardsGini},
+					{Key: "rewardsnakamoto", Value: epoch.Stats.Cumulative.RewardsNakamoto},
+					{Key: "newsmeshers", Value: epoch.Stats.Cumulative.NewSmeshers},
+					{Key: "returningsmeshers", Value: epoch.Stats.Cumulative.ReturningSmeshers},
+					{Key: "stoppedsmeshers", Value: epoch.Stats.Cumulative.StoppedSmeshers},
+					{Key: "space", Value: epoch.Stats.Cumulative.Space},
+					{Key: "spacedelta", Value: epoch.Stats.Cumulative.SpaceDelta},
+					{Key: "newaccounts", Value: epoch.Stats.Cumulative.NewAccounts},
+					{Key: "feescollected", Value: epoch.Stats.Cumulative.FeesCollected},
+					{Key: "feemin", Value: epoch.Stats.Cumulative.FeeMin},
+					{Key: "feemedian", Value: epoch.Stats.Cumulative.FeeMedian},
+					{Key: "feemax", Value: epoch.Stats.Cumulative.FeeMax},
+					{Key: "emptylayers", Value: epoch.Stats.Cumulative.EmptyLayers},
+					{Key: "layerswithouttxs", Value: epoch.Stats.Cumulative.LayersWithoutTxs},
+					{Key: "rewardedsmeshers", Value: epoch.Stats.Cumulative.RewardedSmeshers},
+					{Key: "effectivenumunits", Value: epoch.Stats.Cumulative.EffectiveNumUnits},
+					{Key: "weight", Value: epoch.Stats.Cumulative.Weight},
+					{Key: "inclusiontxs", Value: epoch.Stats.Cumulative.InclusionTxs},
+					{Key: "inclusionmean", Value: epoch.Stats.Cumulative.InclusionMean},
+					{Key: "inclusionmedian", Value: epoch.Stats.Cumulative.InclusionMedian},
+					{Key: "inclusionp90", Value: epoch.Stats.Cumulative.InclusionP90},
+					{Key: "inclusionp99", Value: epoch.Stats.Cumulative.InclusionP99},
+					{Key: "voters", Value: epoch.Stats.Cumulative.Voters},
+					{Key: "ballots", Value: epoch.Stats.Cumulative.Ballots},
+					{Key: "votes", Value: epoch.Stats.Cumulative.Votes},
+					{Key: "balloteligibilities", Value: epoch.Stats.Cumulative.BallotEligibilities},
 				}},
 			}},
-		},
-	}}, options.Update().SetUpsert(true))
+		}},
+	}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveEpoch: %v", err)
+		logging.Error("UpsertEpoch", err, logging.Epoch(uint32(epoch.Number)))
 	}
 	return err
 }
 
-func (s *Storage) SaveOrUpdateEpoch(parent context.Context, epoch *model.Epoch) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertEpochActiveSetSize stores the active set size of the epoch.
+func (s *Storage) UpsertEpochActiveSetSize(parent context.Context, epoch int32, size uint32) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
-	status, err := s.db.Collection("epochs").UpdateOne(ctx, bson.D{{Key: "number", Value: epoch.Number}}, bson.D{
+	_, err := s.db.Collection("epochs").UpdateOne(ctx, bson.D{{Key: "number", Value: epoch}}, bson.D{
 		{Key: "$set", Value: bson.D{
-			{Key: "number", Value: epoch.Number},
-			{Key: "start", Value: epoch.Start},
-			{Key: "end", Value: epoch.End},
-			{Key: "layerstart", Value: epoch.LayerStart},
-			{Key: "layerend", Value: epoch.LayerEnd},
-			{Key: "layers", Value: epoch.Layers},
-			{Key: "stats", Value: bson.D{
-				{Key: "current", Value: bson.D{
-					{Key: "capacity", Value: epoch.Stats.Current.Capacity},
-					{Key: "decentral", Value: epoch.Stats.Current.Decentral},
-					{Key: "smeshers", Value: epoch.Stats.Current.Smeshers},
-					{Key: "transactions", Value: epoch.Stats.Current.Transactions},
-					{Key: "accounts", Value: epoch.Stats.Current.Accounts},
-					{Key: "circulation", Value: epoch.Stats.Current.Circulation},
-					{Key: "rewards", Value: epoch.Stats.Current.Rewards},
-					{Key: "rewardsnumber", Value: epoch.Stats.Current.RewardsNumber},
-					{Key: "security", Value: epoch.Stats.Current.Security},
-					{Key: "txsamount", Value: epoch.Stats.Current.TxsAmount},
-				}},
-				{Key: "cumulative", Value: bson.D{
-					{Key: "capacity", Value: epoch.Stats.Cumulative.Capacity},
-					{Key: "decentral", Value: epoch.Stats.Cumulative.Decentral},
-					{Key: "smeshers", Value: epoch.Stats.Cumulative.Smeshers},
-					{Key: "transactions", Value: epoch.Stats.Cumulative.Transactions},
-					{Key: "accounts", Value: epoch.Stats.Cumulative.Accounts},
-					{Key: "circulation", Value: epoch.Stats.Cumulative.Circulation},
-					{Key: "rewards", Value: epoch.Stats.Cumulative.Rewards},
-					{Key: "rewardsnumber", Value: epoch.Stats.Cumulative.RewardsNumber},
-					{Key: "security", Value: epoch.Stats.Cumulative.Security},
-					{Key: "txsamount", Value: epoch.Stats.Cumulative.TxsAmount},
-				}},
-			}},
+			{Key: "number", Value: epoch},
+			{Key: "activeSetSize", Value: size},
 		}},
 	}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveOrUpdateEpoch: %+v, %v", status, err)
+		logging.Error("UpsertEpochActiveSetSize", err)
+	}
+	return err
+}
+
+// UpsertEpochBeacon stores the beacon of the epoch.
+func (s *Storage) UpsertEpochBeacon(parent context.Context, epoch int32, beacon string) error {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	_, err := s.db.Collection("epochs").UpdateOne(ctx, bson.D{{Key: "number", Value: epoch}}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "number", Value: epoch},
+			{Key: "beacon", Value: beacon},
+		}},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		logging.Error("UpsertEpochBeacon", err)
 	}
 	return err
 }
@@ -368,43 +373,105 @@ func (s *Storage) computeStatistics(epoch *model.Epoch) {
 	epoch.LayerEnd = layerEnd
 	epoch.End = s.getLayerTimestamp(layerEnd) + s.NetworkInfo.LayerDuration - 1
 	epoch.Layers = epoch.LayerEnd - epoch.LayerStart + 1
-	duration := float64(s.NetworkInfo.LayerDuration) * float64(s.GetLayersCount(context.Background(), s.GetEpochLayersFilter(epoch.Number, "number")))
-	layerFilter := s.GetEpochLayersFilter(epoch.Number, "layer")
-	epoch.Stats.Current.Transactions = s.GetTransactionsCount(context.Background(), layerFilter)
-	epoch.Stats.Current.TxsAmount = s.GetTransactionsAmount(context.Background(), layerFilter)
+	layersFilter := *s.GetEpochLayersFilter(epoch.Number, "number")
+	layers, err := s.GetLayersCount(context.Background(), &layersFilter)
+	if err != nil {
+		logging.Error("computeStatistics: layers", err)
+	}
+	duration := float64(s.NetworkInfo.LayerDuration) * float64(layers)
+	epoch.Stats.Current.EmptyLayers, err = s.GetLayersCount(context.Background(), &bson.D{layersFilter[0], {Key: "blocksnumber", Value: 0}})
+	if err != nil {
+		logging.Error("computeStatistics: empty layers", err)
+	}
+	epoch.Stats.Current.LayersWithoutTxs, err = s.GetLayersCount(context.Background(), &bson.D{layersFilter[0], {Key: "txs", Value: 0}})
+	if err != nil {
+		logging.Error("computeStatistics: layers without transactions", err)
+	}
+	var txs, amount int64
+	if s.watched != nil {
+		// the stored transactions are only the watched ones
+		txs, amount, err = s.getLayersTransactionsStats(context.Background(), layerStart, layerEnd)
+	} else {
+		txs, amount, err = s.GetTransactionsStats(context.Background(), s.GetEpochLayersFilter(epoch.Number, "layer"))
+	}
+	if err != nil {
+		logging.Error("computeStatistics: transactions", err)
+	}
+	epoch.Stats.Current.Transactions = txs
+	epoch.Stats.Current.TxsAmount = amount
 	if duration > 0 && s.NetworkInfo.MaxTransactionsPerSecond > 0 {
 		// todo replace to utils.CalcEpochCapacity
 		epoch.Stats.Current.Capacity = int64(math.Round(((float64(epoch.Stats.Current.Transactions) / duration) / float64(s.NetworkInfo.MaxTransactionsPerSecond)) * 100.0))
 	}
-	atxs, _ := s.GetActivations(context.Background(), &bson.D{{Key: "targetEpoch", Value: epoch.Number}})
-	if atxs != nil {
-		smeshers := make(map[string]int64)
-		for _, atx := range atxs {
-			var commitmentSize int64
-			var smesher string
-			for _, e := range atx {
-				if e.Key == "smesher" {
-					smesher, _ = e.Value.(string)
-					continue
-				}
-				if e.Key == "commitmentSize" {
-					if value, ok := e.Value.(int64); ok {
-						commitmentSize = value
-					} else if value, ok := e.Value.(int32); ok {
-						commitmentSize = int64(value)
-					}
-				}
-			}
-			if smesher != "" {
-				smeshers[smesher] += commitmentSize
-				epoch.Stats.Current.Security += commitmentSize
-			}
-		}
-		epoch.Stats.Current.Smeshers = int64(len(smeshers))
+	smeshers, err := s.getEpochSmeshersStats(context.Background(), epoch.Number)
+	if err != nil {
+		logging.Error("computeStatistics: activations", err)
+	} else {
+		epoch.Stats.Current.Smeshers = smeshers.Smeshers
+		epoch.Stats.Current.Security = smeshers.Security
 		// degree_of_decentralization is defined as: 0.5 * (min(n,1e4)^2/1e8) + 0.5 * (1 - gini_coeff(last_100_epochs))
 		a := math.Min(float64(epoch.Stats.Current.Smeshers), 1e4)
 		// todo replace to utils.CalcDecentralCoefficient
-		epoch.Stats.Current.Decentral = int64(100.0 * (0.5*(a*a)/1e8 + 0.5*(1.0-utils.Gini(smeshers))))
+		epoch.Stats.Current.Decentral = int64(100.0 * (0.5*(a*a)/1e8 + 0.5*(1.0-smeshers.gini())))
+		if smeshers.Smeshers > 0 {
+			epoch.Stats.Current.SpaceGini = int64(math.Round(1e4 * smeshers.gini()))
+			epoch.Stats.Current.SpaceNakamoto = smeshers.Nakamoto
+		}
+	}
+	units, weight, err := s.getEpochSpace(context.Background(), epoch.Number)
+	if err != nil {
+		logging.Error("computeStatistics: space", err)
+	} else {
+		epoch.Stats.Current.EffectiveNumUnits = units
+		epoch.Stats.Current.Weight = weight
+		epoch.Stats.Current.Space = units * int64(s.postUnitSize)
+	}
+	rewards, err := s.getEpochRewardsStats(context.Background(), layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics: rewards", err)
+	} else if rewards.Smeshers > 0 {
+		epoch.Stats.Current.RewardedSmeshers = rewards.Smeshers
+		epoch.Stats.Current.RewardsGini = int64(math.Round(1e4 * rewards.gini()))
+		epoch.Stats.Current.RewardsNakamoto = rewards.Nakamoto
+	}
+	churn, err := s.getEpochSmeshersChurn(context.Background(), epoch.Number)
+	if err != nil {
+		logging.Error("computeStatistics: churn", err)
+	} else {
+		epoch.Stats.Current.NewSmeshers = churn.New
+		epoch.Stats.Current.ReturningSmeshers = churn.Returning
+		epoch.Stats.Current.StoppedSmeshers = churn.Stopped
+	}
+	feesCollected, feesDistributed := s.GetLayersFees(context.Background(), layerStart, layerEnd)
+	epoch.Stats.Current.FeesCollected = int64(feesCollected)
+	epoch.Stats.Current.FeesDistributed = int64(feesDistributed)
+	epoch.Stats.Current.FeesBurned = int64(burnedFees(feesCollected, feesDistributed))
+	fees, err := s.getFeeStats(context.Background(), layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics: fees", err)
+	} else {
+		epoch.Stats.Current.FeeMin = int64(fees.Min)
+		epoch.Stats.Current.FeeMedian = int64(fees.Median)
+		epoch.Stats.Current.FeeMax = int64(fees.Max)
+	}
+	inclusion, err := s.getInclusionStats(context.Background(), layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics: inclusion", err)
+	} else {
+		epoch.Stats.Current.InclusionTxs = inclusion.Txs
+		epoch.Stats.Current.InclusionMean = inclusion.Mean
+		epoch.Stats.Current.InclusionMedian = inclusion.Median
+		epoch.Stats.Current.InclusionP90 = inclusion.P90
+		epoch.Stats.Current.InclusionP99 = inclusion.P99
+	}
+	ballots, err := s.getBallotStats(context.Background(), layerStart, layerEnd)
+	if err != nil {
+		logging.Error("computeStatistics: ballots", err)
+	} else {
+		epoch.Stats.Current.Voters = ballots.Voters
+		epoch.Stats.Current.Ballots = ballots.Ballots
+		epoch.Stats.Current.Votes = ballots.Votes
+		epoch.Stats.Current.BallotEligibilities = ballots.Eligibilities
 	}
 	epoch.Stats.Current.Accounts = s.GetAccountsCount(context.Background(), &bson.D{{Key: "created", Value: bson.D{{Key: "$lte", Value: layerEnd}}}})
 	//epoch.Stats.Cumulative.Circulation, _ = s.GetLayersRewards(context.Background(), 0, layerEnd)
@@ -412,8 +479,209 @@ func (s *Storage) computeStatistics(epoch *model.Epoch) {
 }
 
 func (s *Storage) RecalculateEpochStats() {
-	currentEpoch := s.NetworkInfo.VerifiedLayer / s.NetworkInfo.EpochNumLayers
+	currentEpoch := utils.LayerEpoch(s.NetworkInfo.VerifiedLayer, s.NetworkInfo.EpochNumLayers)
 	for i := 0; i <= int(currentEpoch+1); i++ {
 		s.UpdateEpochStats(uint32(i) * s.NetworkInfo.EpochNumLayers)
 	}
 }
+
+// epochSmeshersStats are the commitment sizes of the smeshers of an epoch, or their rewards,
+// aggregated by the database.
+type epochSmeshersStats struct {
+	Smeshers int64 `bson:"smeshers"`
+	Security int64 `bson:"security"`
+	// Weights is the sum of the commitment sizes counted by the gini coefficient, where empty
+	// commitments weigh 1, and Ranked the sum of the weights multiplied by their rank in
+	// ascending order.
+	Weights float64 `bson:"weights"`
+	Ranked  float64 `bson:"ranked"`
+	// Nakamoto is the least number of smeshers holding more than half of the weights, see
+	// utils.Nakamoto.
+	Nakamoto int64 `bson:"nakamoto"`
+}
+
+// gini returns the gini coefficient of the commitment sizes, see utils.Gini.
+func (st *epochSmeshersStats) gini() float64 {
+	if st.Smeshers == 0 || st.Weights == 0 {
+		return 1
+	}
+	n := float64(st.Smeshers)
+	return (2*st.Ranked/st.Weights - n - 1) / n
+}
+
+// getEpochSmeshersStats sums the commitment sizes of the activations targeting the epoch by
+// smesher on the database side, so that the activations of an epoch are never loaded in memory.
+func (s *Storage) getEpochSmeshersStats(parent context.Context, epoch int32) (*epochSmeshersStats, error) {
+	return s.aggregateSmeshersStats(parent, s.aggregateSource("activations"), bson.D{
+		{Key: "targetEpoch", Value: epoch},
+		{Key: "smesher", Value: bson.D{{Key: "$nin", Value: bson.A{"", nil}}}},
+	}, "$commitmentSize")
+}
+
+// getEpochSpace returns the sums of the effective num units and of the weights of the activations
+// targeting the epoch, the storage they commit is the effective num units times the unit size.
+func (s *Storage) getEpochSpace(parent context.Context, epoch int32) (units, weight int64, err error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection(s.aggregateSource("activations")).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "targetEpoch", Value: epoch},
+			{Key: "smesher", Value: bson.D{{Key: "$nin", Value: bson.A{"", nil}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "units", Value: bson.D{{Key: "$sum", Value: "$effectiveNumUnits"}}},
+			{Key: "weight", Value: bson.D{{Key: "$sum", Value: "$weight"}}},
+		}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return 0, 0, cursor.Err()
+	}
+	return utils.GetAsInt64(cursor.Current.Lookup("units")), utils.GetAsInt64(cursor.Current.Lookup("weight")), nil
+}
+
+// getEpochRewardsStats sums the rewards of the layers in the range by smesher, see
+// getEpochSmeshersStats.
+func (s *Storage) getEpochRewardsStats(parent context.Context, layerStart, layerEnd uint32) (*epochSmeshersStats, error) {
+	return s.aggregateSmeshersStats(parent, s.aggregateSource("rewards"), bson.D{
+		{Key: "layer", Value: bson.D{{Key: "$gte", Value: layerStart}, {Key: "$lte", Value: layerEnd}}},
+	}, "$total")
+}
+
+// aggregateSmeshersStats sums the field of the documents of the collection matching the filter by
+// smesher, then aggregates the distribution of the sums.
+func (s *Storage) aggregateSmeshersStats(parent context.Context, collection string, match bson.D, field string) (*epochSmeshersStats, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection(collection).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "size", Value: bson.D{{Key: "$sum", Value: field}}},
+		}}},
+		{{Key: "$set", Value: bson.D{
+			{Key: "weight", Value: bson.D{{Key: "$toDouble", Value: bson.D{{Key: "$max", Value: bson.A{"$size", 1}}}}}},
+		}}},
+		{{Key: "$setWindowFields", Value: bson.D{
+			{Key: "sortBy", Value: bson.D{{Key: "weight", Value: 1}}},
+			{Key: "output", Value: bson.D{{Key: "rank", Value: bson.D{{Key: "$documentNumber", Value: bson.D{}}}}}},
+		}}},
+		// the weights held by the heavier smeshers, and by all of them
+		{{Key: "$setWindowFields", Value: bson.D{
+			{Key: "sortBy", Value: bson.D{{Key: "weight", Value: -1}}},
+			{Key: "output", Value: bson.D{
+				{Key: "above", Value: bson.D{
+					{Key: "$sum", Value: "$weight"},
+					{Key: "window", Value: bson.D{{Key: "documents", Value: bson.A{"unbounded", -1}}}},
+				}},
+				{Key: "all", Value: bson.D{
+					{Key: "$sum", Value: "$weight"},
+					{Key: "window", Value: bson.D{{Key: "documents", Value: bson.A{"unbounded", "unbounded"}}}},
+				}},
+			}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "smeshers", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "security", Value: bson.D{{Key: "$sum", Value: "$size"}}},
+			{Key: "weights", Value: bson.D{{Key: "$sum", Value: "$weight"}}},
+			{Key: "ranked", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{"$rank", "$weight"}}}}}},
+			{Key: "nakamoto", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$lte", Value: bson.A{bson.D{{Key: "$multiply", Value: bson.A{"$above", 2}}}, "$all"}}},
+				1, 0,
+			}}}}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	stats := &epochSmeshersStats{}
+	if cursor.Next(ctx) {
+		err = cursor.Decode(stats)
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	return stats, err
+}
+
+// epochSmeshersChurn are the smeshers which joined, came back to and left the network in an epoch.
+type epochSmeshersChurn struct {
+	New       int64 `bson:"new"`
+	Returning int64 `bson:"returning"`
+	Stopped   int64 `bson:"stopped"`
+}
+
+// getEpochSmeshersChurn compares the smeshers of the activations targeting the epoch to the ones
+// targeting the previous epoch, and looks up the earlier activations of the smeshers missing the
+// previous epoch only, see utils.SmeshersChurn.
+func (s *Storage) getEpochSmeshersChurn(parent context.Context, epoch int32) (*epochSmeshersChurn, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	in := func(target int32) bson.D {
+		return bson.D{{Key: "$max", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$targetEpoch", target}}}, 1, 0,
+		}}}}}
+	}
+	count := func(cond bson.A) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$and", Value: cond}}, 1, 0,
+		}}}}}
+	}
+	cursor, err := s.db.Collection(s.aggregateSource("activations")).Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "targetEpoch", Value: bson.D{{Key: "$in", Value: bson.A{epoch - 1, epoch}}}},
+			{Key: "smesher", Value: bson.D{{Key: "$nin", Value: bson.A{"", nil}}}},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "current", Value: in(epoch)},
+			{Key: "previous", Value: in(epoch - 1)},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: s.db.CollectionName(s.aggregateSource("activations"))},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "smesher"},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: bson.D{{Key: "$lt", Value: epoch - 1}}}}}},
+				{{Key: "$limit", Value: 1}},
+				{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "earlier"},
+		}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "new", Value: count(bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$current", 1}}},
+				bson.D{{Key: "$eq", Value: bson.A{"$previous", 0}}},
+				bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$size", Value: "$earlier"}}, 0}}},
+			})},
+			{Key: "returning", Value: count(bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$current", 1}}},
+				bson.D{{Key: "$eq", Value: bson.A{"$previous", 0}}},
+				bson.D{{Key: "$gt", Value: bson.A{bson.D{{Key: "$size", Value: "$earlier"}}, 0}}},
+			})},
+			{Key: "stopped", Value: count(bson.A{
+				bson.D{{Key: "$eq", Value: bson.A{"$current", 0}}},
+				bson.D{{Key: "$eq", Value: bson.A{"$previous", 1}}},
+			})},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	churn := &epochSmeshersChurn{}
+	if cursor.Next(ctx) {
+		err = cursor.Decode(churn)
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	return churn, err
+}
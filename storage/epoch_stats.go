@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// epochStatsCollection holds the stats of the epochs by version of the formulas, the epochs
+// collection only keeps the stats of the running version.
+const epochStatsCollection = "epoch_stats"
+
+func (s *Storage) initEpochStatsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, epochStatsCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.statsDB, epochStatsCollection)
+}
+
+// saveEpochStats records the stats of the epoch under their version, recomputing the stats with
+// the same version replaces them.
+func (s *Storage) saveEpochStats(parent context.Context, epoch *model.Epoch) error {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	_, err := s.statsDB.Collection(epochStatsCollection).UpdateOne(ctx,
+		bson.D{{Key: "epoch", Value: epoch.Number}, {Key: "version", Value: epoch.StatsVersion}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "epoch", Value: epoch.Number},
+			{Key: "version", Value: epoch.StatsVersion},
+			{Key: "computed", Value: time.Now().Unix()},
+			{Key: "stats", Value: epoch.Stats},
+		}}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error save epoch %d stats: %w", epoch.Number, err)
+	}
+	return nil
+}
+
+// GetEpochStatsHistory returns the stats versions matching the query.
+func (s *Storage) GetEpochStatsHistory(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.EpochStats, error) {
+	var history []*model.EpochStats
+	if err := s.findAll(parent, epochStatsCollection, query, &history, opts...); err != nil {
+		return nil, fmt.Errorf("error get epoch stats: %w", err)
+	}
+	return history, nil
+}
+
+// EpochStatsDiff lists the changes of the stats of an epoch between two versions.
+type EpochStatsDiff struct {
+	Epoch   int32               `json:"epoch"`
+	Changes []model.StatsChange `json:"changes"`
+}
+
+// DiffEpochStats compares the stats of the epochs computed by the versions `from` and `to`, the
+// epochs missing a version and the epochs without changes are skipped.
+func (s *Storage) DiffEpochStats(parent context.Context, from, to uint32) ([]EpochStatsDiff, error) {
+	history, err := s.GetEpochStatsHistory(parent,
+		&bson.D{{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{from, to}}}}},
+		options.Find().SetSort(bson.D{{Key: "epoch", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int32]map[uint32]model.Stats)
+	var epochs []int32
+	for _, h := range history {
+		if versions[h.Epoch] == nil {
+			versions[h.Epoch] = make(map[uint32]model.Stats, 2)
+			epochs = append(epochs, h.Epoch)
+		}
+		versions[h.Epoch][h.Version] = h.Stats
+	}
+	var diffs []EpochStatsDiff
+	for _, epoch := range epochs {
+		a, okA := versions[epoch][from]
+		b, okB := versions[epoch][to]
+		if !okA || !okB {
+			continue
+		}
+		if changes := model.DiffStats(a, b); len(changes) > 0 {
+			diffs = append(diffs, EpochStatsDiff{Epoch: epoch, Changes: changes})
+		}
+	}
+	return diffs, nil
+}
+
+// RollbackEpochStats serves the stats of the given version again, for the epochs which have them.
+// It returns the number of epochs rolled back. A collector running a later version recomputes the
+// stats of the epochs it updates, so it must be downgraded first.
+func (s *Storage) RollbackEpochStats(parent context.Context, version uint32) (int, error) {
+	history, err := s.GetEpochStatsHistory(parent, &bson.D{{Key: "version", Value: version}})
+	if err != nil {
+		return 0, err
+	}
+	if len(history) == 0 {
+		return 0, nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(history))
+	for _, h := range history {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "number", Value: h.Epoch}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{
+				{Key: "stats", Value: h.Stats},
+				{Key: "statsVersion", Value: h.Version},
+			}}}))
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	res, err := s.db.Collection("epochs").BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("error rollback epoch stats to version %d: %w", version, err)
+	}
+	s.invalidate(cache.KeyCurrentEpoch)
+	return int(res.MatchedCount), nil
+}
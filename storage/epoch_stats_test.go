@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestDiffEpochStats(t *testing.T) {
+	from := model.Stats{
+		Current:    model.Statistics{Capacity: 10, Decentral: 50, Transactions: 3},
+		Cumulative: model.Statistics{Transactions: 7},
+	}
+	require.Empty(t, model.DiffStats(from, from))
+
+	to := from
+	to.Current.Decentral = 60
+	to.Cumulative.FeesBurned = 2
+	require.Equal(t, []model.StatsChange{
+		{Field: "current.decentral", From: 50, To: 60},
+		{Field: "cumulative.feesburned", From: 0, To: 2},
+	}, model.DiffStats(from, to))
+}
+
+func TestNewEpochComparison(t *testing.T) {
+	a := &model.Epoch{Number: 12, ActiveSetSize: 4, StatsVersion: 9, Stats: model.Stats{
+		Current:    model.Statistics{Smeshers: 200, Space: 1000, SpaceGini: 3000},
+		Cumulative: model.Statistics{Rewards: 50},
+	}}
+	b := &model.Epoch{Number: 13, ActiveSetSize: 5, StatsVersion: 10, Stats: model.Stats{
+		Current:    model.Statistics{Smeshers: 150, Space: 1500, SpaceGini: 3000, Transactions: 7},
+		Cumulative: model.Statistics{Rewards: 80},
+	}}
+	comparison := model.NewEpochComparison(a, b)
+	require.Equal(t, int32(12), comparison.A)
+	require.Equal(t, int32(13), comparison.B)
+	require.Equal(t, [2]uint32{9, 10}, comparison.StatsVersions)
+
+	deltas := make(map[string]model.EpochDelta)
+	for _, delta := range comparison.Deltas {
+		deltas[delta.Field] = *delta
+	}
+	// every stat once, the current ones then the cumulative ones
+	require.Len(t, deltas, len(comparison.Deltas))
+	require.Equal(t, "activeSetSize", comparison.Deltas[0].Field)
+	require.Equal(t, "current.capacity", comparison.Deltas[1].Field)
+	require.Equal(t, "cumulative.balloteligibilities", comparison.Deltas[len(comparison.Deltas)-1].Field)
+	require.Equal(t, model.EpochDelta{Field: "activeSetSize", A: 4, B: 5, Delta: 1, Change: 2500}, deltas["activeSetSize"])
+	require.Equal(t, model.EpochDelta{Field: "current.smeshers", A: 200, B: 150, Delta: -50, Change: -2500}, deltas["current.smeshers"])
+	require.Equal(t, model.EpochDelta{Field: "current.space", A: 1000, B: 1500, Delta: 500, Change: 5000}, deltas["current.space"])
+	require.Equal(t, model.EpochDelta{Field: "current.spacegini", A: 3000, B: 3000}, deltas["current.spacegini"])
+	// no change without a value in the first epoch
+	require.Equal(t, model.EpochDelta{Field: "current.transactions", B: 7, Delta: 7}, deltas["current.transactions"])
+	require.Equal(t, model.EpochDelta{Field: "cumulative.rewards", A: 50, B: 80, Delta: 30, Change: 6000}, deltas["cumulative.rewards"])
+}
+
+func TestEpochLayers(t *testing.T) {
+	s := &Storage{}
+	// the number of layers per epoch is unknown before the network info
+	require.Equal(t, uint32(0), s.GetEpochForLayer(25))
+	start, end := s.GetEpochLayers(2)
+	require.Equal(t, uint32(0), start)
+	require.Equal(t, uint32(0), end)
+
+	s.NetworkInfo.EpochNumLayers = 10
+	require.Equal(t, uint32(0), s.GetEpochForLayer(0))
+	require.Equal(t, uint32(0), s.GetEpochForLayer(9))
+	require.Equal(t, uint32(1), s.GetEpochForLayer(10))
+	start, end = s.GetEpochLayers(0)
+	require.Equal(t, uint32(0), start)
+	require.Equal(t, uint32(9), end)
+	start, end = s.GetEpochLayers(2)
+	require.Equal(t, uint32(20), start)
+	require.Equal(t, uint32(29), end)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+func TestEpochSmeshersStatsGini(t *testing.T) {
+	for _, smeshers := range []map[string]int64{
+		{},
+		{"a": 10},
+		{"a": 10, "b": 10, "c": 10},
+		{"a": 1, "b": 2, "c": 3, "d": 100},
+		{"a": 0, "b": 0, "c": 64, "d": 64, "e": 1 << 40},
+	} {
+		// mirror the aggregation pipeline of getEpochSmeshersStats
+		stats := epochSmeshersStats{Smeshers: int64(len(smeshers))}
+		weights := make([]float64, 0, len(smeshers))
+		for _, size := range smeshers {
+			stats.Security += size
+			weights = append(weights, float64(max(size, 1)))
+		}
+		sort.Float64s(weights)
+		for i, weight := range weights {
+			stats.Weights += weight
+			stats.Ranked += float64(i+1) * weight
+		}
+		require.InDelta(t, utils.Gini(smeshers), stats.gini(), 1e-9, "%v", smeshers)
+	}
+}
+
+func TestEpochSmeshersStatsNakamoto(t *testing.T) {
+	for _, tc := range []struct {
+		smeshers map[string]int64
+		nakamoto int64
+	}{
+		{smeshers: map[string]int64{}, nakamoto: 0},
+		{smeshers: map[string]int64{"a": 10}, nakamoto: 1},
+		{smeshers: map[string]int64{"a": 10, "b": 10}, nakamoto: 2},
+		{smeshers: map[string]int64{"a": 10, "b": 10, "c": 10}, nakamoto: 2},
+		{smeshers: map[string]int64{"a": 1, "b": 2, "c": 3, "d": 100}, nakamoto: 1},
+		{smeshers: map[string]int64{"a": 0, "b": 0, "c": 1, "d": 1}, nakamoto: 3},
+	} {
+		// mirror the aggregation pipeline of aggregateSmeshersStats
+		weights := make([]float64, 0, len(tc.smeshers))
+		var all float64
+		for _, size := range tc.smeshers {
+			weights = append(weights, float64(max(size, 1)))
+			all += float64(max(size, 1))
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(weights)))
+		stats := epochSmeshersStats{}
+		var above float64
+		for _, weight := range weights {
+			if 2*above <= all {
+				stats.Nakamoto++
+			}
+			above += weight
+		}
+		require.Equal(t, tc.nakamoto, stats.Nakamoto, "%v", tc.smeshers)
+		require.Equal(t, tc.nakamoto, utils.Nakamoto(tc.smeshers), "%v", tc.smeshers)
+	}
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// GetLayersFees returns the fees paid by processed transactions and the part of them distributed
+// to smeshers as rewards, for layers in the range [from, to]. The difference between both is burned.
+func (s *Storage) GetLayersFees(parent context.Context, from, to uint32) (collected, distributed uint64) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+
+	layerFilter := bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}}}
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: append(layerFilter, bson.E{Key: "state", Value: int(pb.TransactionState_TRANSACTION_STATE_PROCESSED)}, NotOrphaned)}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "fees", Value: bson.D{{Key: "$sum", Value: "$fee"}}},
+		}}},
+	})
+	if err != nil {
+		logging.Error("GetLayersFees", err)
+		return 0, 0
+	}
+	if cursor.Next(ctx) {
+		collected = utils.GetAsUInt64(cursor.Current.Lookup("fees"))
+	}
+	cursor.Close(ctx)
+
+	cursor, err = s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: layerFilter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "fees", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$subtract", Value: bson.A{"$total", "$layerReward"}}}}}},
+		}}},
+	})
+	if err != nil {
+		logging.Error("GetLayersFees", err)
+		return collected, 0
+	}
+	defer cursor.Close(ctx)
+	if cursor.Next(ctx) {
+		distributed = utils.GetAsUInt64(cursor.Current.Lookup("fees"))
+	}
+
+	return collected, distributed
+}
+
+// getFeeStats returns the lowest, median and highest fees of the processed transactions of the
+// layers in the range [from, to]. Only the middle fees are read to compute the median.
+func (s *Storage) getFeeStats(parent context.Context, from, to uint32) (model.FeeStats, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+		{Key: "state", Value: int(pb.TransactionState_TRANSACTION_STATE_PROCESSED)},
+		NotOrphaned,
+	}
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "min", Value: bson.D{{Key: "$min", Value: "$fee"}}},
+			{Key: "max", Value: bson.D{{Key: "$max", Value: "$fee"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	})
+	if err != nil {
+		return model.FeeStats{}, fmt.Errorf("error aggregate fees: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if !cursor.Next(ctx) {
+		return model.FeeStats{}, cursor.Err()
+	}
+	stats := model.FeeStats{
+		Min: utils.GetAsUInt64(cursor.Current.Lookup("min")),
+		Max: utils.GetAsUInt64(cursor.Current.Lookup("max")),
+	}
+	count := utils.GetAsInt64(cursor.Current.Lookup("count"))
+
+	middle, err := s.db.Collection("txs").Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "fee", Value: 1}}).
+		SetSkip((count-1)/2).
+		SetLimit(2-count%2).
+		SetProjection(bson.D{{Key: "_id", Value: 0}, {Key: "fee", Value: 1}}))
+	if err != nil {
+		return model.FeeStats{}, fmt.Errorf("error get median fee: %w", err)
+	}
+	defer middle.Close(ctx)
+	var fees []uint64
+	for middle.Next(ctx) {
+		fees = append(fees, utils.GetAsUInt64(middle.Current.Lookup("fee")))
+	}
+	if err := middle.Err(); err != nil {
+		return model.FeeStats{}, fmt.Errorf("error get median fee: %w", err)
+	}
+	stats.Median = model.FeeMedian(fees)
+	return stats, nil
+}
+
+// burnedFees returns the part of collected fees which was not distributed to smeshers.
+func burnedFees(collected, distributed uint64) uint64 {
+	if distributed >= collected {
+		return 0
+	}
+	return collected - distributed
+}
+
+// TransactionFee returns the fee the transaction adds to the fees collected by its layer, only the
+// processed transactions which were not orphaned pay their fee. A nil transaction adds nothing.
+func TransactionFee(tx *model.Transaction) int64 {
+	if tx == nil || tx.State != int(pb.TransactionState_TRANSACTION_STATE_PROCESSED) || tx.Orphaned {
+		return 0
+	}
+	return int64(tx.Fee)
+}
+
+// updateLayerSummary recomputes the fee accounting and the rewards sum of the layer, so that the
+// layers are listed without reading their rewards. It is called when the layer is stored, and again
+// when transaction results or rewards of the layer are stored later. The recomputes are serialized
+// and each one follows the writes which triggered it, so the last one reads all of them.
+func (s *Storage) updateLayerSummary(layer uint32) {
+	s.summaryLock.Lock()
+	defer s.summaryLock.Unlock()
+
+	collected, distributed := s.GetLayersFees(context.Background(), layer, layer)
+	rewards, _ := s.GetLayersRewards(context.Background(), layer, layer)
+	fees, err := s.getFeeStats(context.Background(), layer, layer)
+	if err != nil {
+		logging.Error("updateLayerSummary", err)
+	}
+
+	ctx, cancel := s.queryContext(context.Background())
+	defer cancel()
+	_, err = s.db.Collection("layers").UpdateOne(ctx, bson.D{{Key: "number", Value: layer}}, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "feescollected", Value: collected},
+			{Key: "feesdistributed", Value: distributed},
+			{Key: "feesburned", Value: burnedFees(collected, distributed)},
+			{Key: "feemin", Value: fees.Min},
+			{Key: "feemedian", Value: fees.Median},
+			{Key: "feemax", Value: fees.Max},
+			{Key: "rewards", Value: rewards},
+		}},
+	})
+	if err != nil {
+		logging.Error("updateLayerSummary", err)
+	}
+}
+
+// backfillLayerRewards sets the rewards sum of the layers, hot or archived, stored before the
+// summary was kept on the layer documents, see updateLayerSummary.
+func (s *Storage) backfillLayerRewards(parent context.Context) error {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	archive, _ := TierArchive("layers")
+	for _, collection := range []string{"layers", archive} {
+		_, err := s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+			{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$layer"},
+				{Key: "rewards", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+			}}},
+			{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "number", Value: "$_id"},
+				{Key: "rewards", Value: 1},
+			}}},
+			{{Key: "$merge", Value: bson.D{
+				{Key: "into", Value: s.db.CollectionName(collection)},
+				{Key: "on", Value: "number"},
+				{Key: "whenMatched", Value: "merge"},
+				{Key: "whenNotMatched", Value: "discard"},
+			}}},
+		}, options.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return fmt.Errorf("error backfill `%s` rewards: %w", collection, err)
+		}
+	}
+	return nil
+}
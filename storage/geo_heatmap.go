@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// SetGeoHeatmap starts aggregating the committed space of the located smeshers by country and by
+// region every interval, into the single document of the heat-map collection.
+func (s *Storage) SetGeoHeatmap(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.geoHeatmapDone = make(chan struct{})
+	go s.runGeoHeatmap(interval)
+}
+
+func (s *Storage) runGeoHeatmap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.updateGeoHeatmap(context.Background()); err != nil {
+			logging.Error("geo heat-map", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.geoHeatmapDone:
+			return
+		}
+	}
+}
+
+// updateGeoHeatmap replaces the heat-map by the one of the smeshers located now.
+func (s *Storage) updateGeoHeatmap(parent context.Context) error {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection("smeshers").Find(ctx,
+		bson.D{{Key: "geo.coordinates", Value: bson.D{{Key: "$exists", Value: true}}}},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "cSize", Value: 1}, {Key: "geo", Value: 1}}))
+	if err != nil {
+		return fmt.Errorf("error get located smeshers: %w", err)
+	}
+	var smeshers []*model.Smesher
+	if err := cursor.All(ctx, &smeshers); err != nil {
+		return fmt.Errorf("error decode located smeshers: %w", err)
+	}
+	heatmap := model.NewGeoHeatmap(smeshers, uint32(time.Now().Unix()))
+	_, err = s.statsDB.Collection(statsGeoHeatmapCollection).ReplaceOne(ctx, bson.D{}, heatmap, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error update `%s`: %w", statsGeoHeatmapCollection, err)
+	}
+	return nil
+}
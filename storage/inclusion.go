@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// getInclusionStats returns the stats of the delays between the first sighting and the inclusion of
+// the transactions of the layers in the range [from, to] whose received time is known.
+func (s *Storage) getInclusionStats(parent context.Context, from, to uint32) (model.InclusionStats, error) {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+
+	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "layer", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lte", Value: to}}},
+			{Key: "received", Value: bson.D{{Key: "$gt", Value: 0}}},
+			NotOrphaned,
+			{Key: "$expr", Value: bson.D{{Key: "$lte", Value: bson.A{"$received", "$timestamp"}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "delay", Value: bson.D{{Key: "$subtract", Value: bson.A{"$timestamp", "$received"}}}},
+		}}},
+	})
+	if err != nil {
+		return model.InclusionStats{}, fmt.Errorf("error aggregate inclusion delays: %w", err)
+	}
+	defer cursor.Close(ctx)
+	var delays []int64
+	for cursor.Next(ctx) {
+		delays = append(delays, utils.GetAsInt64(cursor.Current.Lookup("delay")))
+	}
+	if err := cursor.Err(); err != nil {
+		return model.InclusionStats{}, err
+	}
+	return model.NewInclusionStats(delays), nil
+}
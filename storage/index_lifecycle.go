@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// IndexDriftKind is the kind of a difference between the managed indexes and the database.
+type IndexDriftKind string
+
+const (
+	// IndexMissing is a managed index not in the database.
+	IndexMissing IndexDriftKind = "missing"
+	// IndexChanged is a managed index with other keys or options in the database.
+	IndexChanged IndexDriftKind = "changed"
+	// IndexUnmanaged is an index of the database neither managed nor serving an API query, e.g.
+	// created by a DBA or left by an old version.
+	IndexUnmanaged IndexDriftKind = "unmanaged"
+	// QueryUnindexed is an API query shape no index serves.
+	QueryUnindexed IndexDriftKind = "unindexed"
+)
+
+// IndexDrift is a difference between the managed indexes and the database. Name is the name of
+// the index, or of the query shape for QueryUnindexed.
+type IndexDrift struct {
+	Kind       IndexDriftKind
+	Collection string
+	Name       string
+	Keys       bson.D
+}
+
+func (d IndexDrift) String() string {
+	return fmt.Sprintf("%s index `%s` on `%s` %v", d.Kind, d.Name, d.Collection, d.Keys)
+}
+
+// indexSpec is an index listed by the database.
+type indexSpec struct {
+	Name     string `bson:"name"`
+	Key      bson.D `bson:"key"`
+	Unique   bool   `bson:"unique"`
+	Language string `bson:"default_language"`
+	Weights  bson.M `bson:"weights"`
+}
+
+// SetCreateIndexes disables the creation and the removal of the indexes by the migrations and at
+// startup, for the deployments whose indexes are managed and built by the DBAs. The differences
+// with the managed indexes are still reported, see IndexDrift.
+func (s *Storage) SetCreateIndexes(enabled bool) {
+	s.externalIndexes = !enabled
+}
+
+// createIndexes creates the managed indexes of the collections.
+func (s *Storage) createIndexes(ctx context.Context, collections ...string) error {
+	if s.externalIndexes {
+		return nil
+	}
+	for _, collection := range collections {
+		indexes := managedIndexes(collection)
+		if len(indexes) == 0 {
+			continue
+		}
+		models := make([]mongo.IndexModel, 0, len(indexes))
+		for _, index := range indexes {
+			models = append(models, index.Model())
+		}
+		if _, err := s.collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("error init `%s` collection: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// dropIndex drops the index of the collection replaced by a managed index, if it exists.
+func (s *Storage) dropIndex(ctx context.Context, collection, name string) error {
+	if s.externalIndexes {
+		return nil
+	}
+	if _, err := s.collection(collection).Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("error drop `%s` %s: %w", collection, name, err)
+	}
+	return nil
+}
+
+// EnsureIndexes creates the missing managed indexes, unless disabled by SetCreateIndexes, then
+// logs the remaining differences with the database.
+func (s *Storage) EnsureIndexes(ctx context.Context) error {
+	collections := managedCollections()
+	if err := s.createIndexes(ctx, collections...); err != nil {
+		return err
+	}
+	if err := s.createQueryIndexes(ctx, collections...); err != nil {
+		return err
+	}
+	s.LogIndexDrift(ctx)
+	return nil
+}
+
+// LogIndexDrift logs the differences between the managed indexes and the database.
+func (s *Storage) LogIndexDrift(ctx context.Context) {
+	drift, err := s.IndexDrift(ctx)
+	if err != nil {
+		logging.Error("detect index drift", err)
+		return
+	}
+	for _, d := range drift {
+		logging.Warn("index drift", zap.Stringer("drift", d))
+	}
+}
+
+// IndexDrift returns the differences between the managed indexes and the indexes of the database:
+// the missing and changed managed indexes, the unmanaged indexes and the unindexed API queries.
+// The `_id` indexes, the shard key indexes and the indexes serving an API query are not reported
+// as unmanaged.
+func (s *Storage) IndexDrift(ctx context.Context) ([]IndexDrift, error) {
+	var drift []IndexDrift
+	for _, collection := range managedCollections() {
+		specs, err := s.listIndexes(ctx, collection)
+		if err != nil {
+			return nil, err
+		}
+		live := make(map[string]indexSpec, len(specs))
+		for _, spec := range specs {
+			live[spec.Name] = spec
+		}
+
+		managed := make(map[string]bool)
+		for _, index := range managedIndexes(collection) {
+			managed[index.Name] = true
+			spec, ok := live[index.Name]
+			switch {
+			case !ok:
+				drift = append(drift, IndexDrift{Kind: IndexMissing, Collection: collection, Name: index.Name, Keys: index.Keys})
+			case !index.matches(spec):
+				drift = append(drift, IndexDrift{Kind: IndexChanged, Collection: collection, Name: index.Name, Keys: spec.Key})
+			}
+		}
+
+		shapes := shapesOf(collection)
+	specs:
+		for _, spec := range specs {
+			if spec.Name == "_id_" || managed[spec.Name] || isShardKey(collection, spec.Key) {
+				continue
+			}
+			for _, q := range shapes {
+				if q.IndexedBy(spec.Key) {
+					continue specs
+				}
+			}
+			drift = append(drift, IndexDrift{Kind: IndexUnmanaged, Collection: collection, Name: spec.Name, Keys: spec.Key})
+		}
+	shapes:
+		for _, q := range shapes {
+			for _, spec := range specs {
+				if q.IndexedBy(spec.Key) {
+					continue shapes
+				}
+			}
+			drift = append(drift, IndexDrift{Kind: QueryUnindexed, Collection: collection, Name: q.Name, Keys: q.Index()})
+		}
+	}
+	return drift, nil
+}
+
+// listIndexes returns the indexes of the collection, none if the collection does not exist.
+func (s *Storage) listIndexes(ctx context.Context, collection string) ([]indexSpec, error) {
+	cursor, err := s.collection(collection).Indexes().List(ctx)
+	if isIndexNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error list `%s` indexes: %w", collection, err)
+	}
+	var specs []indexSpec
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, fmt.Errorf("error list `%s` indexes: %w", collection, err)
+	}
+	return specs, nil
+}
+
+// managedCollections returns the collections with managed indexes or API queries, sorted.
+func managedCollections() []string {
+	set := make(map[string]bool)
+	for _, index := range ManagedIndexes {
+		set[index.Collection] = true
+	}
+	for _, q := range APIQueryShapes {
+		set[q.Collection] = true
+	}
+	collections := make([]string, 0, len(set))
+	for collection := range set {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+	return collections
+}
+
+// matches reports whether the index of the database has the keys and the options of the managed
+// index. The keys of a text index are the weights of its fields in the database.
+func (i Index) matches(spec indexSpec) bool {
+	if spec.Unique != i.Unique {
+		return false
+	}
+	if i.Language != "" {
+		if spec.Language != i.Language || len(spec.Weights) != len(i.Keys) {
+			return false
+		}
+		for _, key := range i.Keys {
+			if _, ok := spec.Weights[key.Key]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return keysEqual(i.Keys, spec.Key)
+}
+
+// keysEqual reports whether the index keys have the same fields in the same order with the same
+// directions or types.
+func keysEqual(a, b bson.D) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Key != b[i].Key {
+			return false
+		}
+		if kind, ok := a[i].Value.(string); ok {
+			if kind != b[i].Value {
+				return false
+			}
+		} else if sign(a[i].Value) != sign(b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// isShardKey reports whether the keys are the shard key of the collection, see ShardKeys.
+func isShardKey(collection string, keys bson.D) bool {
+	key, ok := ShardKeys[collection]
+	return ok && keysEqual(key, keys)
+}
@@ -0,0 +1,333 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index is an index managed by the explorer. The name identifies the index in the database, so a
+// change of the keys or the options of an index requires a new name.
+type Index struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+	// Language is the default language of a text index, `none` indexes the words as they are.
+	Language string
+}
+
+// Model returns the model creating the index.
+func (i Index) Model() mongo.IndexModel {
+	opts := options.Index().SetName(i.Name)
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Language != "" {
+		opts.SetDefaultLanguage(i.Language)
+	}
+	return mongo.IndexModel{Keys: i.Keys, Options: opts}
+}
+
+// ManagedIndexes are the named indexes of the collections, the indexes of the API list queries
+// are derived from APIQueryShapes. The indexes of the stats collections are created in the stats
+// database. Add an index here and it is created at the next start, unless the indexes are managed
+// out of the explorer, see SetCreateIndexes.
+var ManagedIndexes = []Index{
+	{Collection: "accounts", Name: "addressIndex", Keys: bson.D{{Key: "address", Value: 1}}, Unique: true},
+	{Collection: "accounts", Name: "createIndex", Keys: bson.D{{Key: "created", Value: 1}}},
+	{Collection: "accounts", Name: "modifiedIndex", Keys: bson.D{{Key: "layer", Value: -1}}},
+
+	{Collection: "activations", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "activations", Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: "activations", Name: "smesherIndex", Keys: bson.D{{Key: "smesher", Value: 1}}},
+	{Collection: "activations", Name: "coinbaseIndex", Keys: bson.D{{Key: "coinbase", Value: 1}}},
+	{Collection: "activations", Name: "targetEpochIndex", Keys: bson.D{{Key: "targetEpoch", Value: 1}}},
+
+	{Collection: ballotsCollection, Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: ballotsCollection, Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: ballotsCollection, Name: "smesherEpochIndex", Keys: bson.D{{Key: "smesher", Value: 1}, {Key: "epoch", Value: 1}}},
+
+	{Collection: "blocks", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "blocks_archive", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+
+	{Collection: "certificates", Name: "blockIdIndex", Keys: bson.D{{Key: "blockId", Value: 1}}, Unique: true},
+	{Collection: "certificates", Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: "certificates", Name: "signersIndex", Keys: bson.D{{Key: "signers.smesher", Value: 1}}},
+
+	{Collection: "coinbases", Name: "coinbaseSmesherIndex", Keys: bson.D{{Key: "coinbase", Value: 1}, {Key: "smesherId", Value: 1}}, Unique: true},
+	{Collection: "coinbases", Name: "smesherFromIndex", Keys: bson.D{{Key: "smesherId", Value: 1}, {Key: "from", Value: -1}}},
+
+	{Collection: "epochs", Name: "numberIndex", Keys: bson.D{{Key: "number", Value: 1}}, Unique: true},
+
+	{Collection: "layers", Name: "numberIndex", Keys: bson.D{{Key: "number", Value: 1}}, Unique: true},
+	{Collection: "layers_archive", Name: "numberIndex", Keys: bson.D{{Key: "number", Value: 1}}, Unique: true},
+
+	{Collection: "malfeasance_proofs", Name: "smesherIndex", Keys: bson.D{{Key: "smesher", Value: 1}}},
+	{Collection: "malfeasance_proofs", Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+
+	{Collection: "migrations", Name: "versionIndex", Keys: bson.D{{Key: "version", Value: 1}}, Unique: true},
+
+	// the unique index starts with the layer, the shard key of the rewards, see ShardKeys
+	{Collection: "rewards", Name: "layerSmesherIndex", Keys: bson.D{{Key: "layer", Value: 1}, {Key: "smesher", Value: 1}}, Unique: true},
+	{Collection: "rewards", Name: "smesherLayerIndex", Keys: bson.D{{Key: "smesher", Value: 1}, {Key: "layer", Value: 1}}},
+	{Collection: "rewards", Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: "rewards", Name: "smesherIndex", Keys: bson.D{{Key: "smesher", Value: 1}}},
+	{Collection: "rewards", Name: "coinbaseIndex", Keys: bson.D{{Key: "coinbase", Value: 1}}},
+	{Collection: "rewards", Name: "rewardIndex", Keys: bson.D{{Key: "layer", Value: 1}, {Key: "smesher", Value: 1}, {Key: "coinbase", Value: 1}}},
+	{Collection: "rewards", Name: "layerRewards", Keys: bson.D{{Key: "layer", Value: 1}, {Key: "total", Value: 1}, {Key: "layerReward", Value: 1}}},
+
+	{Collection: "smeshers", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	// the coordinates are legacy [longitude, latitude] pairs, the smeshers without a location are
+	// not indexed
+	{Collection: "smeshers", Name: "geoIndex", Keys: bson.D{{Key: "geo.coordinates", Value: "2dsphere"}}},
+
+	{Collection: "txs", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: "txs", Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: "txs", Name: "blockIndex", Keys: bson.D{{Key: "block", Value: 1}}},
+	{Collection: "txs", Name: "senderIndex", Keys: bson.D{{Key: "sender", Value: 1}}},
+	{Collection: "txs", Name: "receiverIndex", Keys: bson.D{{Key: "receiver", Value: 1}}},
+	{Collection: "txs", Name: "timestampIndex", Keys: bson.D{{Key: "timestamp", Value: -1}}},
+	{Collection: "txs", Name: "counterIndex", Keys: bson.D{{Key: "counter", Value: -1}}},
+	{Collection: "txs_archive", Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+
+	{Collection: archiveCollection, Name: "kindIdIndex", Keys: bson.D{{Key: "kind", Value: 1}, {Key: "id", Value: 1}}, Unique: true},
+	{Collection: balanceChangesCollection, Name: "addressLayerIndex", Keys: bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: -1}}, Unique: true},
+	{Collection: balanceChangesCollection, Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: accountSnapshotsCollection, Name: "addressLayerIndex", Keys: bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: -1}}, Unique: true},
+	{Collection: accountSnapshotsCollection, Name: "layerIndex", Keys: bson.D{{Key: "layer", Value: 1}}},
+	{Collection: vaultsCollection, Name: "addressIndex", Keys: bson.D{{Key: "address", Value: 1}}, Unique: true},
+	// the names are proper names, so they are indexed without stemming and stop words
+	{Collection: labelsCollection, Name: "kindIdIndex", Keys: bson.D{{Key: "kind", Value: 1}, {Key: "id", Value: 1}}, Unique: true},
+	{Collection: labelsCollection, Name: "nameTextIndex", Keys: bson.D{{Key: "name", Value: "text"}}, Language: "none"},
+	{Collection: pricesCollection, Name: "timestampIndex", Keys: bson.D{{Key: "timestamp", Value: 1}}, Unique: true},
+	{Collection: smesherHistoryCollection, Name: "smesherEpochIndex", Keys: bson.D{{Key: "smesher", Value: 1}, {Key: "epoch", Value: -1}}, Unique: true},
+	{Collection: chainActivationsCollection, Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+	{Collection: chainActivationsCollection, Name: "smesherIndex", Keys: bson.D{{Key: "smesher", Value: 1}}},
+	{Collection: chainActivationsCollection, Name: "targetEpochIndex", Keys: bson.D{{Key: "targetEpoch", Value: 1}}},
+	{Collection: chainRewardsCollection, Name: "layerSmesherIndex", Keys: bson.D{{Key: "layer", Value: 1}, {Key: "smesher", Value: 1}}, Unique: true},
+	{Collection: chainSmeshersCollection, Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+
+	{Collection: epochStatsCollection, Name: "epochVersionIndex", Keys: bson.D{{Key: "epoch", Value: 1}, {Key: "version", Value: -1}}, Unique: true},
+	{Collection: statsDailyTxsCollection, Name: "dayIndex", Keys: bson.D{{Key: "day", Value: 1}}, Unique: true},
+	{Collection: statsDailyAccountsCollection, Name: "dayIndex", Keys: bson.D{{Key: "day", Value: 1}}, Unique: true},
+	{Collection: statsTxTypesCollection, Name: "dayEpochTypeIndex", Keys: bson.D{{Key: "day", Value: 1}, {Key: "epoch", Value: 1}, {Key: "type", Value: 1}}, Unique: true},
+	{Collection: statsEpochRewardsCollection, Name: "epochIndex", Keys: bson.D{{Key: "epoch", Value: 1}}, Unique: true},
+	{Collection: statsSmeshersCollection, Name: "smesherIndex", Keys: bson.D{{Key: "smesher", Value: 1}}, Unique: true},
+	{Collection: statsBlocksCollection, Name: "epochBucketsIndex", Keys: bson.D{{Key: "epoch", Value: 1}, {Key: "txsBucket", Value: 1}, {Key: "sizeBucket", Value: 1}}, Unique: true},
+	{Collection: statsRollupDailyCollection, Name: "startIndex", Keys: bson.D{{Key: "start", Value: 1}}, Unique: true},
+	{Collection: statsRollupWeeklyCollection, Name: "startIndex", Keys: bson.D{{Key: "start", Value: 1}}, Unique: true},
+	{Collection: statsTotalsCollection, Name: "idIndex", Keys: bson.D{{Key: "id", Value: 1}}, Unique: true},
+}
+
+// managedIndexes returns the managed indexes of the collection.
+func managedIndexes(collection string) []Index {
+	var indexes []Index
+	for _, index := range ManagedIndexes {
+		if index.Collection == collection {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+// QueryShape is the shape of a list query of the API: equality filters on Equality and sort by Sort,
+// range filters are on the sort fields.
+type QueryShape struct {
+	Name       string
+	Collection string
+	Equality   []string
+	Sort       bson.D
+}
+
+// APIQueryShapes are the list queries served by the API, see internal/service.
+var APIQueryShapes = []QueryShape{
+	{Name: "transactions", Collection: "txs", Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}},
+	{Name: "account transactions sent", Collection: "txs", Equality: []string{"sender"}, Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}},
+	{Name: "account transactions received", Collection: "txs", Equality: []string{"receiver"}, Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}},
+	{Name: "method transactions", Collection: "txs", Equality: []string{"method"}, Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}},
+	{Name: "template transactions", Collection: "txs", Equality: []string{"template"}, Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}},
+	{Name: "layer transactions", Collection: "txs", Equality: []string{"layer"}, Sort: bson.D{{Key: "blockIndex", Value: 1}}},
+	{Name: "rewards", Collection: "rewards", Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "account rewards", Collection: "rewards", Equality: []string{"coinbase"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "smesher rewards", Collection: "rewards", Equality: []string{"smesher"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "activation rewards", Collection: "rewards", Equality: []string{"atx"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "account smeshers", Collection: "coinbases", Equality: []string{"coinbase"}, Sort: bson.D{{Key: "totalRewards", Value: -1}}},
+	{Name: "activations", Collection: "activations", Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "smesher activations", Collection: "activations", Equality: []string{"smesher"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "epoch activations", Collection: "activations", Equality: []string{"targetEpoch"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "layer activations", Collection: "activations", Equality: []string{"layer"}, Sort: bson.D{{Key: "id", Value: -1}}},
+	{Name: "layer blocks", Collection: "blocks", Equality: []string{"layer"}, Sort: bson.D{{Key: "id", Value: -1}}},
+	{Name: "smeshers", Collection: "smeshers", Sort: bson.D{{Key: "timestamp", Value: -1}}},
+	{Name: "top smeshers", Collection: "smeshers", Sort: bson.D{{Key: "totalRewards", Value: -1}}},
+	{Name: "epoch smeshers", Collection: "smeshers", Equality: []string{"epochs"}, Sort: bson.D{{Key: "timestamp", Value: -1}}},
+	{Name: "accounts", Collection: "accounts", Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "template accounts", Collection: "accounts", Equality: []string{"template"}, Sort: bson.D{{Key: "layer", Value: -1}}},
+	{Name: "account", Collection: "accounts", Equality: []string{"address"}},
+}
+
+// Index returns the keys of the index serving the query: the equality fields followed by the sort.
+func (q QueryShape) Index() bson.D {
+	keys := make(bson.D, 0, len(q.Equality)+len(q.Sort))
+	for _, field := range q.Equality {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return append(keys, q.Sort...)
+}
+
+// IndexedBy reports whether the index with the given keys serves the query without an in-memory
+// sort: the index starts with the equality fields in any order, followed by the sort fields in
+// the sort order or in the reverse order.
+func (q QueryShape) IndexedBy(keys bson.D) bool {
+	if len(keys) < len(q.Equality)+len(q.Sort) {
+		return false
+	}
+	equality := make(map[string]bool, len(q.Equality))
+	for _, field := range q.Equality {
+		equality[field] = true
+	}
+	for _, key := range keys[:len(q.Equality)] {
+		if !equality[key.Key] {
+			return false
+		}
+	}
+	var direction int
+	for i, sort := range q.Sort {
+		key := keys[len(q.Equality)+i]
+		if key.Key != sort.Key {
+			return false
+		}
+		d := sign(key.Value) * sign(sort.Value)
+		if d == 0 || (direction != 0 && d != direction) {
+			return false
+		}
+		direction = d
+	}
+	return true
+}
+
+func sign(v any) int {
+	var f float64
+	switch n := v.(type) {
+	case int:
+		f = float64(n)
+	case int32:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case float64:
+		f = n
+	}
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+// shapesOf returns the API query shapes of the collection, the ones of the hot collection for an
+// archive collection.
+func shapesOf(collection string) []QueryShape {
+	for _, t := range tiers {
+		if t.Archive == collection {
+			collection = t.Collection
+		}
+	}
+	var shapes []QueryShape
+	for _, q := range APIQueryShapes {
+		if q.Collection == collection {
+			shapes = append(shapes, q)
+		}
+	}
+	return shapes
+}
+
+// UnindexedQueries returns the API query shapes no index of the database serves.
+func UnindexedQueries(ctx context.Context, db *Database) ([]QueryShape, error) {
+	indexes := make(map[string][]bson.D)
+	var unindexed []QueryShape
+	for _, q := range APIQueryShapes {
+		keys, ok := indexes[q.Collection]
+		if !ok {
+			cursor, err := db.Collection(q.Collection).Indexes().List(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error list `%s` indexes: %w", q.Collection, err)
+			}
+			var specs []struct {
+				Key bson.D `bson:"key"`
+			}
+			if err := cursor.All(ctx, &specs); err != nil {
+				return nil, fmt.Errorf("error list `%s` indexes: %w", q.Collection, err)
+			}
+			for _, spec := range specs {
+				keys = append(keys, spec.Key)
+			}
+			indexes[q.Collection] = keys
+		}
+		indexed := false
+		for _, key := range keys {
+			if q.IndexedBy(key) {
+				indexed = true
+				break
+			}
+		}
+		if !indexed {
+			unindexed = append(unindexed, q)
+		}
+	}
+	return unindexed, nil
+}
+
+// createQueryIndexes creates the indexes of the API query shapes of the collections not served by
+// an existing index.
+func (s *Storage) createQueryIndexes(ctx context.Context, collections ...string) error {
+	if s.externalIndexes {
+		return nil
+	}
+	for _, collection := range collections {
+		shapes := shapesOf(collection)
+		if len(shapes) == 0 {
+			continue
+		}
+		specs, err := s.listIndexes(ctx, collection)
+		if err != nil {
+			return err
+		}
+		keys := make([]bson.D, 0, len(specs))
+		for _, spec := range specs {
+			keys = append(keys, spec.Key)
+		}
+	next:
+		for _, q := range shapes {
+			for _, k := range keys {
+				if q.IndexedBy(k) {
+					continue next
+				}
+			}
+			index := q.Index()
+			if _, err := s.collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: index}); err != nil {
+				return fmt.Errorf("error index %s: %w", q.Name, err)
+			}
+			keys = append(keys, index)
+		}
+	}
+	return nil
+}
+
+// queryCollections returns the collections of the API query shapes, without the archives.
+func queryCollections() []string {
+	var collections []string
+	seen := make(map[string]bool)
+	for _, q := range APIQueryShapes {
+		if !seen[q.Collection] {
+			seen[q.Collection] = true
+			collections = append(collections, q.Collection)
+		}
+	}
+	return collections
+}
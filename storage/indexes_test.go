@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryShapeIndexedBy(t *testing.T) {
+	sent := QueryShape{Collection: "txs", Equality: []string{"sender"}, Sort: bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}}}
+	require.True(t, sent.IndexedBy(sent.Index()))
+	require.True(t, sent.IndexedBy(bson.D{{Key: "sender", Value: int32(1)}, {Key: "layer", Value: int32(1)}, {Key: "blockIndex", Value: int32(1)}}))
+	require.False(t, sent.IndexedBy(bson.D{{Key: "sender", Value: 1}}))
+	require.False(t, sent.IndexedBy(bson.D{{Key: "sender", Value: 1}, {Key: "layer", Value: -1}, {Key: "blockIndex", Value: 1}}))
+	require.False(t, sent.IndexedBy(bson.D{{Key: "layer", Value: -1}, {Key: "blockIndex", Value: -1}, {Key: "sender", Value: 1}}))
+
+	layers := QueryShape{Collection: "txs", Sort: bson.D{{Key: "layer", Value: -1}}}
+	require.True(t, layers.IndexedBy(bson.D{{Key: "layer", Value: 1}, {Key: "blockIndex", Value: 1}}))
+
+	account := QueryShape{Collection: "accounts", Equality: []string{"address"}}
+	require.True(t, account.IndexedBy(bson.D{{Key: "address", Value: 1}}))
+	require.False(t, account.IndexedBy(bson.D{{Key: "created", Value: 1}}))
+}
+
+func TestManagedIndexes(t *testing.T) {
+	names := make(map[string]bool)
+	for _, index := range ManagedIndexes {
+		key := index.Collection + "." + index.Name
+		require.False(t, names[key], "duplicate index %s", key)
+		names[key] = true
+
+		// the unique indexes of a sharded collection must start with its shard key
+		if shardKey, ok := ShardKeys[index.Collection]; ok && index.Unique && shardKey[0].Value != "hashed" {
+			require.Equal(t, shardKey[0].Key, index.Keys[0].Key, "index %s", key)
+		}
+	}
+	require.Contains(t, managedCollections(), "txs_archive")
+	require.Equal(t, shapesOf("txs"), shapesOf("txs_archive"))
+	require.NotContains(t, queryCollections(), "txs_archive")
+}
+
+func TestIndexMatches(t *testing.T) {
+	address := Index{Collection: "accounts", Name: "addressIndex", Keys: bson.D{{Key: "address", Value: 1}}, Unique: true}
+	require.True(t, address.matches(indexSpec{Name: "addressIndex", Key: bson.D{{Key: "address", Value: int32(1)}}, Unique: true}))
+	require.False(t, address.matches(indexSpec{Name: "addressIndex", Key: bson.D{{Key: "address", Value: int32(1)}}}))
+	require.False(t, address.matches(indexSpec{Name: "addressIndex", Key: bson.D{{Key: "address", Value: int32(-1)}}, Unique: true}))
+	require.False(t, address.matches(indexSpec{Name: "addressIndex", Key: bson.D{{Key: "address", Value: 1}, {Key: "layer", Value: 1}}, Unique: true}))
+
+	geo := Index{Collection: "smeshers", Name: "geoIndex", Keys: bson.D{{Key: "geo.coordinates", Value: "2dsphere"}}}
+	require.True(t, geo.matches(indexSpec{Name: "geoIndex", Key: bson.D{{Key: "geo.coordinates", Value: "2dsphere"}}}))
+	require.False(t, geo.matches(indexSpec{Name: "geoIndex", Key: bson.D{{Key: "geo.coordinates", Value: "2d"}}}))
+
+	text := Index{Collection: "labels", Name: "nameTextIndex", Keys: bson.D{{Key: "name", Value: "text"}}, Language: "none"}
+	live := indexSpec{
+		Name:     "nameTextIndex",
+		Key:      bson.D{{Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}},
+		Language: "none",
+		Weights:  bson.M{"name": int32(1)},
+	}
+	require.True(t, text.matches(live))
+	live.Language = "english"
+	require.False(t, text.matches(live))
+}
+
+func TestIsShardKey(t *testing.T) {
+	require.True(t, isShardKey("txs", bson.D{{Key: "id", Value: "hashed"}}))
+	require.True(t, isShardKey("rewards", bson.D{{Key: "layer", Value: int32(1)}}))
+	require.False(t, isShardKey("rewards", bson.D{{Key: "layer", Value: 1}, {Key: "smesher", Value: 1}}))
+	require.False(t, isShardKey("layers", bson.D{{Key: "number", Value: 1}}))
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// labelsCollection holds the names of the accounts and smeshers, see UpsertLabels.
+const labelsCollection = "labels"
+
+// initLabelsStorage creates the text index of the label names, see ManagedIndexes.
+func (s *Storage) initLabelsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, labelsCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, labelsCollection)
+}
+
+// UpsertLabels stores the names of the accounts and smeshers, a label replaces the previous name of
+// its entity. The names of the smeshers are copied to their activations, and their changes are
+// recorded in the smesher history in the current epoch.
+func (s *Storage) UpsertLabels(parent context.Context, labels []*model.Label) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(labels))
+	smeshers := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if err := label.Validate(); err != nil {
+			return err
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "kind", Value: label.Kind}, {Key: "id", Value: label.Id}}).
+			SetReplacement(label).
+			SetUpsert(true))
+		if label.Kind == model.LabelSmesher {
+			smeshers = append(smeshers, label.Id)
+		}
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	names, err := s.getSmesherNames(ctx, smeshers)
+	if err != nil {
+		return fmt.Errorf("error get smesher names: %w", err)
+	}
+	if _, err := s.db.Collection(labelsCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("error save labels: %w", err)
+	}
+	if err := s.setSmesherNames(ctx, labels); err != nil {
+		return fmt.Errorf("error save smesher names: %w", err)
+	}
+	if err := s.saveSmesherNameChanges(ctx, labels, names); err != nil {
+		return fmt.Errorf("error save smesher history: %w", err)
+	}
+	return nil
+}
+
+// saveSmesherNameChanges records the changes of the names of the smesher labels from their previous
+// names, the other labels are skipped.
+func (s *Storage) saveSmesherNameChanges(ctx context.Context, labels []*model.Label, names map[string]string) error {
+	epoch := utils.LayerEpoch(s.NetworkInfo.LastLayer, s.NetworkInfo.EpochNumLayers)
+	now := uint64(time.Now().Unix())
+	var models []mongo.WriteModel
+	for _, label := range labels {
+		if label.Kind != model.LabelSmesher {
+			continue
+		}
+		if change := model.NewSmesherNameChange(label.Id, names[label.Id], label.Name, epoch, now); change != nil {
+			models = append(models, smesherNameChangeQuery(change))
+		}
+		names[label.Id] = label.Name
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := s.db.Collection(smesherHistoryCollection).BulkWrite(ctx, models)
+	return err
+}
+
+// getSmesherNames returns the names of the smeshers, the unlabeled smeshers are missing.
+func (s *Storage) getSmesherNames(ctx context.Context, ids []string) (map[string]string, error) {
+	cursor, err := s.db.Collection(labelsCollection).Find(ctx, bson.D{
+		{Key: "kind", Value: model.LabelSmesher},
+		{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var labels []*model.Label
+	if err := cursor.All(ctx, &labels); err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(labels))
+	for _, label := range labels {
+		names[label.Id] = label.Name
+	}
+	return names, nil
+}
+
+// setSmesherNames copies the names of the smesher labels to the activations of the smeshers, the
+// other labels are skipped.
+func (s *Storage) setSmesherNames(ctx context.Context, labels []*model.Label) error {
+	var models []mongo.WriteModel
+	for _, label := range labels {
+		if label.Kind != model.LabelSmesher {
+			continue
+		}
+		models = append(models, mongo.NewUpdateManyModel().
+			SetFilter(bson.D{{Key: "smesher", Value: label.Id}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "smesherName", Value: label.Name}}}}))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	_, err := s.db.Collection("activations").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// backfillSmesherNames copies the names of all the smesher labels to the activations, by batches of
+// bulkWriteBatchSize labels.
+func (s *Storage) backfillSmesherNames(ctx context.Context) error {
+	cursor, err := s.db.Collection(labelsCollection).Find(ctx, bson.D{{Key: "kind", Value: model.LabelSmesher}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	labels := make([]*model.Label, 0, bulkWriteBatchSize)
+	for cursor.Next(ctx) {
+		var label model.Label
+		if err := cursor.Decode(&label); err != nil {
+			return err
+		}
+		labels = append(labels, &label)
+		if len(labels) == bulkWriteBatchSize {
+			if err := s.setSmesherNames(ctx, labels); err != nil {
+				return err
+			}
+			labels = labels[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return s.setSmesherNames(ctx, labels)
+}
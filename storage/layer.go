@@ -3,22 +3,22 @@ package storage
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) InitLayersStorage(ctx context.Context) error {
-	_, err := s.db.Collection("layers").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "number", Value: 1}}, Options: options.Index().SetName("numberIndex").SetUnique(true)})
-	//_, err = s.db.Collection("layers").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetName("hashIndex").SetUnique(true)})
-	return err
+	if err := s.createIndexes(ctx, "layers"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "layers")
 }
 
 func (s *Storage) GetLayerByNumber(parent context.Context, layerNumber uint32) (*model.Layer, error) {
@@ -26,107 +26,57 @@ func (s *Storage) GetLayerByNumber(parent context.Context, layerNumber uint32) (
 }
 
 func (s *Storage) GetLayer(parent context.Context, query *bson.D) (*model.Layer, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("layers").Find(ctx, query)
-	if err != nil {
-		log.Info("GetLayer: %v", err)
+	account := &model.Layer{}
+	if err := s.findOne(parent, "layers", query, account); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetLayer: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetLayer", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetLayer: Empty result", err)
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	account := &model.Layer{
-		Number:       utils.GetAsUInt32(doc.Lookup("number")),
-		Status:       utils.GetAsInt(doc.Lookup("status")),
-		Txs:          utils.GetAsUInt32(doc.Lookup("txs")),
-		Start:        utils.GetAsUInt32(doc.Lookup("start")),
-		End:          utils.GetAsUInt32(doc.Lookup("end")),
-		TxsAmount:    utils.GetAsUInt64(doc.Lookup("txsamount")),
-		Rewards:      utils.GetAsUInt64(doc.Lookup("rewards")),
-		Epoch:        utils.GetAsUInt32(doc.Lookup("epoch")),
-		Hash:         utils.GetAsString(doc.Lookup("hash")),
-		BlocksNumber: utils.GetAsUInt32(doc.Lookup("blocksnumber")),
-	}
 	return account, nil
 }
 
-func (s *Storage) GetLayersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("layers").CountDocuments(ctx, query, opts...)
+// GetLayersCount returns the number of layers matching the query.
+func (s *Storage) GetLayersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.countDocuments(parent, "layers", query, opts...)
 	if err != nil {
-		log.Info("GetLayersCount: %v", err)
-		return 0
+		return 0, fmt.Errorf("error count layers: %w", err)
 	}
-	return count
+	return count, nil
 }
 
-func (s *Storage) GetLastLayer(parent context.Context) uint32 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("layers").Find(ctx, bson.D{}, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1))
+// GetLastLayer returns the number of the last stored layer, 0 when no layer is stored.
+func (s *Storage) GetLastLayer(parent context.Context) (uint32, error) {
+	var docs []bson.Raw
+	err := s.findAll(parent, "layers", bson.D{}, &docs, options.Find().SetSort(bson.D{{Key: "number", Value: -1}}).SetLimit(1))
 	if err != nil {
-		log.Info("GetLastLayer: %v", err)
-		return 0
+		return 0, fmt.Errorf("error get last layer: %w", err)
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetLastLayer: Empty result", err)
-		return 0
+	if len(docs) == 0 {
+		return 0, nil
 	}
-	doc := cursor.Current
-	return utils.GetAsUInt32(doc.Lookup("number"))
+	return utils.GetAsUInt32(docs[0].Lookup("number")), nil
 }
 
 func (s *Storage) GetLayers(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("layers").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetLayers: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "layers", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetLayers: %v", err)
+		logging.Error("GetLayers", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
-		log.Info("GetLayers: Empty result", err)
+		logging.Info("GetLayers: Empty result")
 		return nil, nil
 	}
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) SaveLayer(parent context.Context, in *model.Layer) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	_, err := s.db.Collection("layers").UpdateOne(ctx, bson.D{{Key: "number", Value: in.Number}}, bson.D{{
-		Key: "$set",
-		Value: bson.D{
-			{Key: "number", Value: in.Number},
-			{Key: "status", Value: in.Status},
-			{Key: "txs", Value: in.Txs},
-			{Key: "start", Value: in.Start},
-			{Key: "end", Value: in.End},
-			{Key: "txsamount", Value: in.TxsAmount},
-			{Key: "rewards", Value: in.Rewards},
-			{Key: "epoch", Value: in.Epoch},
-			{Key: "hash", Value: in.Hash},
-			{Key: "blocksnumber", Value: in.BlocksNumber},
-		},
-	}}, options.Update().SetUpsert(true))
-	if err != nil {
-		log.Info("SaveLayer: %v", err)
-	}
-	return err
-}
-
-func (s *Storage) SaveOrUpdateLayer(parent context.Context, in *model.Layer) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertLayer stores the layer keyed on its number.
+func (s *Storage) UpsertLayer(parent context.Context, in *model.Layer) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("layers").UpdateOne(ctx, bson.D{{Key: "number", Value: in.Number}}, bson.D{
 		{Key: "$set", Value: bson.D{
@@ -143,7 +93,7 @@ func (s *Storage) SaveOrUpdateLayer(parent context.Context, in *model.Layer) err
 		}},
 	}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveOrUpdateLayer: %v", err)
+		logging.Error("UpsertLayer", err)
 	}
 	return err
 }
@@ -2,15 +2,14 @@ package storage
 
 import (
 	"context"
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/go-spacemesh/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"time"
 )
 
-func (s *Storage) SaveMalfeasanceProof(parent context.Context, in *model.MalfeasanceProof) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+func (s *Storage) UpsertMalfeasanceProof(parent context.Context, in *model.MalfeasanceProof) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("malfeasance_proofs").UpdateOne(ctx, bson.D{
 		{Key: "smesher", Value: in.Smesher},
@@ -25,7 +24,7 @@ func (s *Storage) SaveMalfeasanceProof(parent context.Context, in *model.Malfeas
 		}},
 	}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveMalfeasanceProof: %v", err)
+		logging.Error("UpsertMalfeasanceProof", err)
 	}
 	return err
 }
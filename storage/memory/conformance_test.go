@@ -0,0 +1,15 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/spacemeshos/explorer-backend/test/storagetest"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		s := New()
+		t.Cleanup(s.Close)
+		return storagetest.Backend{Writer: s, Reader: NewReader(s)}
+	})
+}
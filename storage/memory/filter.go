@@ -0,0 +1,393 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// matches reports whether the document matches the mongo style filter. The operators are the ones
+// of the postgres backend: $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $and and $or.
+func matches(doc document, filter *bson.D) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	return and(doc, *filter)
+}
+
+func and(doc document, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		ok, err := element(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func element(doc document, e bson.E) (bool, error) {
+	switch e.Key {
+	case "$and", "$or":
+		filters, ok := e.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s expects an array", e.Key)
+		}
+		if len(filters) == 0 {
+			return true, nil
+		}
+		for _, f := range filters {
+			d, ok := f.(bson.D)
+			if !ok {
+				return false, fmt.Errorf("%s expects an array of documents", e.Key)
+			}
+			ok, err := and(doc, d)
+			if err != nil {
+				return false, err
+			}
+			if ok == (e.Key == "$or") {
+				return ok, nil
+			}
+		}
+		return e.Key == "$and", nil
+	}
+
+	ops, ok := e.Value.(bson.D)
+	if !ok || len(ops) == 0 || !strings.HasPrefix(ops[0].Key, "$") {
+		return eq(doc, e.Key, e.Value)
+	}
+	for _, op := range ops {
+		ok, err := operator(doc, e.Key, op)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func operator(doc document, field string, op bson.E) (bool, error) {
+	switch op.Key {
+	case "$eq":
+		return eq(doc, field, op.Value)
+	case "$ne":
+		ok, err := eq(doc, field, op.Value)
+		return !ok, err
+	case "$gt", "$gte", "$lt", "$lte":
+		value, err := normalize(op.Value)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range candidates(doc, field) {
+			cmp, ok := compare(v, value)
+			if !ok {
+				continue
+			}
+			switch {
+			case op.Key == "$gt" && cmp > 0, op.Key == "$gte" && cmp >= 0,
+				op.Key == "$lt" && cmp < 0, op.Key == "$lte" && cmp <= 0:
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$in", "$nin":
+		values := reflect.ValueOf(op.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return false, fmt.Errorf("%s expects an array", op.Key)
+		}
+		found := false
+		for i := 0; i < values.Len() && !found; i++ {
+			ok, err := eq(doc, field, values.Index(i).Interface())
+			if err != nil {
+				return false, err
+			}
+			found = ok
+		}
+		return found == (op.Key == "$in"), nil
+	case "$exists":
+		exists, ok := op.Value.(bool)
+		if !ok {
+			return false, fmt.Errorf("$exists expects a boolean")
+		}
+		return (len(lookup(doc, strings.Split(field, "."))) > 0) == exists, nil
+	}
+	return false, fmt.Errorf("unsupported operator `%s`", op.Key)
+}
+
+// eq matches the field value or, like mongo, an array field containing the value.
+func eq(doc document, field string, value any) (bool, error) {
+	v, err := normalize(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for `%s`: %w", field, err)
+	}
+	for _, c := range lookup(doc, strings.Split(field, ".")) {
+		if equal(c, v) {
+			return true, nil
+		}
+		if items, ok := c.([]any); ok {
+			for _, item := range items {
+				if equal(item, v) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// lookup returns the values of the dotted field, through the arrays of documents.
+func lookup(v any, parts []string) []any {
+	if len(parts) == 0 {
+		return []any{v}
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		child, ok := v[parts[0]]
+		if !ok {
+			return nil
+		}
+		return lookup(child, parts[1:])
+	case []any:
+		var values []any
+		for _, item := range v {
+			values = append(values, lookup(item, parts)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// candidates returns the values of the field and the items of its array values, which are
+// compared by the range operators.
+func candidates(doc document, field string) []any {
+	var values []any
+	for _, v := range lookup(doc, strings.Split(field, ".")) {
+		if items, ok := v.([]any); ok {
+			values = append(values, items...)
+		} else {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// field1 returns the first value of the dotted field, nil if it is missing.
+func field1(doc document, field string) any {
+	values := lookup(doc, strings.Split(field, "."))
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// normalized is a filter value already in the form of the stored documents, see prepare.
+type normalized struct{ v any }
+
+// prepare normalizes the values of the filter once, rather than for every matched document.
+func prepare(filter *bson.D) (*bson.D, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	out := make(bson.D, 0, len(*filter))
+	for _, e := range *filter {
+		switch e.Key {
+		case "$and", "$or":
+			filters, ok := e.Value.(bson.A)
+			if !ok {
+				return nil, fmt.Errorf("%s expects an array", e.Key)
+			}
+			prepared := make(bson.A, 0, len(filters))
+			for _, f := range filters {
+				d, ok := f.(bson.D)
+				if !ok {
+					return nil, fmt.Errorf("%s expects an array of documents", e.Key)
+				}
+				p, err := prepare(&d)
+				if err != nil {
+					return nil, err
+				}
+				prepared = append(prepared, *p)
+			}
+			out = append(out, bson.E{Key: e.Key, Value: prepared})
+			continue
+		}
+		ops, ok := e.Value.(bson.D)
+		if !ok || len(ops) == 0 || !strings.HasPrefix(ops[0].Key, "$") {
+			v, err := normalize(e.Value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for `%s`: %w", e.Key, err)
+			}
+			out = append(out, bson.E{Key: e.Key, Value: normalized{v}})
+			continue
+		}
+		prepared := make(bson.D, 0, len(ops))
+		for _, op := range ops {
+			switch op.Key {
+			case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+				v, err := normalize(op.Value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for `%s`: %w", e.Key, err)
+				}
+				op.Value = normalized{v}
+			case "$in", "$nin":
+				values := reflect.ValueOf(op.Value)
+				if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+					return nil, fmt.Errorf("%s expects an array", op.Key)
+				}
+				items := make(bson.A, 0, values.Len())
+				for i := 0; i < values.Len(); i++ {
+					v, err := normalize(values.Index(i).Interface())
+					if err != nil {
+						return nil, fmt.Errorf("invalid value for `%s`: %w", e.Key, err)
+					}
+					items = append(items, normalized{v})
+				}
+				op.Value = items
+			}
+			prepared = append(prepared, op)
+		}
+		out = append(out, bson.E{Key: e.Key, Value: prepared})
+	}
+	return &out, nil
+}
+
+// normalize returns the value in the form of the stored documents.
+func normalize(v any) (any, error) {
+	if n, ok := v.(normalized); ok {
+		return n.v, nil
+	}
+	doc, err := encode(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return nil, err
+	}
+	return doc["v"], nil
+}
+
+func equal(a, b any) bool {
+	if cmp, ok := compare(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compare orders two numbers, strings or booleans, it returns false for the other values and the
+// values of different types.
+func compare(a, b any) (int, bool) {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		x, okA := new(big.Float).SetString(a.String())
+		y, okB := new(big.Float).SetString(b.String())
+		if !okA || !okB {
+			return 0, false
+		}
+		return x.Cmp(y), true
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(a, b), true
+	case bool:
+		b, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a == b:
+			return 0, true
+		case b:
+			return -1, true
+		}
+		return 1, true
+	}
+	return 0, false
+}
+
+// rank orders the values of different types like mongo: missing and null values first, then
+// numbers, strings, documents, arrays and booleans.
+func rank(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case json.Number:
+		return 1
+	case string:
+		return 2
+	case map[string]any:
+		return 3
+	case []any:
+		return 4
+	case bool:
+		return 5
+	}
+	return 6
+}
+
+// sortDocuments sorts the documents by the keys of the sort document, 1 for ascending and -1 for
+// descending.
+func sortDocuments(docs []document, keys bson.D) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range keys {
+			a, b := field1(docs[i], key.Key), field1(docs[j], key.Key)
+			cmp := rank(a) - rank(b)
+			if cmp == 0 {
+				cmp, _ = compare(a, b)
+			}
+			if cmp == 0 {
+				continue
+			}
+			if v := reflect.ValueOf(key.Value); v.CanInt() && v.Int() < 0 {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// intValue returns the integer value of a stored number, 0 for the other values.
+func intValue(v any) int64 {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0
+	}
+	f, ok := new(big.Float).SetString(n.String())
+	if !ok {
+		return 0
+	}
+	i, _ := f.Int64()
+	return i
+}
+
+// number returns the stored form of an integer.
+func number(i int64) json.Number {
+	return json.Number(fmt.Sprint(i))
+}
+
+// union returns the distinct items of the arrays, in the order they are first seen.
+func union(arrays ...any) []any {
+	var items []any
+	for _, array := range arrays {
+		values, _ := array.([]any)
+		for _, v := range values {
+			found := false
+			for _, item := range items {
+				if equal(item, v) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				items = append(items, v)
+			}
+		}
+	}
+	return items
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMatches(t *testing.T) {
+	doc, err := encode(bson.D{
+		{Key: "sender", Value: "sm1"},
+		{Key: "layer", Value: uint32(15)},
+		{Key: "touched", Value: bson.A{"sm1", "sm2"}},
+		{Key: "geo", Value: bson.D{{Key: "coordinates", Value: bson.A{2.35, 48.85}}}},
+		{Key: "orphaned", Value: false},
+	})
+	require.NoError(t, err)
+
+	table := []struct {
+		name   string
+		filter *bson.D
+		match  bool
+	}{
+		{name: "nil", filter: nil, match: true},
+		{name: "empty", filter: &bson.D{}, match: true},
+		{name: "equality", filter: &bson.D{{Key: "sender", Value: "sm1"}}, match: true},
+		{name: "other type", filter: &bson.D{{Key: "layer", Value: "15"}}, match: false},
+		{name: "number types", filter: &bson.D{{Key: "layer", Value: int64(15)}}, match: true},
+		{name: "array", filter: &bson.D{{Key: "touched", Value: "sm2"}}, match: true},
+		{
+			name:   "range",
+			filter: &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: 10}, {Key: "$lt", Value: 15}}}},
+			match:  false,
+		},
+		{name: "in", filter: &bson.D{{Key: "sender", Value: bson.D{{Key: "$in", Value: []string{"sm0", "sm1"}}}}}, match: true},
+		{name: "nin", filter: &bson.D{{Key: "touched", Value: bson.D{{Key: "$nin", Value: bson.A{"sm2"}}}}}, match: false},
+		{name: "ne", filter: &bson.D{{Key: "orphaned", Value: bson.D{{Key: "$ne", Value: true}}}}, match: true},
+		{name: "exists", filter: &bson.D{{Key: "geo.coordinates", Value: bson.D{{Key: "$exists", Value: true}}}}, match: true},
+		{name: "missing", filter: &bson.D{{Key: "receiver", Value: bson.D{{Key: "$exists", Value: true}}}}, match: false},
+		{
+			name: "or",
+			filter: &bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "receiver", Value: "sm1"}},
+				bson.D{{Key: "sender", Value: "sm1"}},
+			}}},
+			match: true,
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, err := matches(doc, tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.match, ok)
+		})
+	}
+
+	_, err = matches(doc, &bson.D{{Key: "layer", Value: bson.D{{Key: "$regex", Value: "1"}}}})
+	require.Error(t, err)
+}
+
+func TestPage(t *testing.T) {
+	var docs []document
+	for _, fields := range []bson.D{
+		{{Key: "layer", Value: 3}},
+		{{Key: "layer", Value: 1}},
+		{{Key: "id", Value: "0x01"}},
+		{{Key: "layer", Value: 2}},
+	} {
+		doc, err := encode(fields)
+		require.NoError(t, err)
+		docs = append(docs, doc)
+	}
+
+	var layers []int
+	for _, skip := range []int64{0, 2, 4} {
+		raw, err := page(docs, options.Find().SetSort(bson.D{{Key: "layer", Value: -1}}).SetSkip(skip).SetLimit(2))
+		require.NoError(t, err)
+		for _, doc := range raw {
+			var l struct {
+				Layer int `bson:"layer"`
+			}
+			require.NoError(t, bson.UnmarshalExtJSON(doc, false, &l))
+			layers = append(layers, l.Layer)
+		}
+	}
+	// the documents without the field sort first in ascending order, so last in descending order
+	require.Equal(t, []int{3, 2, 1, 0}, layers)
+}
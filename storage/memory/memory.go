@@ -0,0 +1,648 @@
+// Package memory stores explorer data in memory, for the tests of the collector and of the API
+// without a database. Every mongo collection is a map of documents by key, kept in the extended
+// JSON form of the docstore engines, so the models are encoded and decoded the same way.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+)
+
+var _ docstore.Engine = (*engine)(nil)
+
+// document is a stored document, decoded from extended JSON with the numbers kept as json.Number.
+type document = map[string]any
+
+// engine holds the collections, it is the in-memory docstore.Engine.
+type engine struct {
+	mu     sync.RWMutex
+	tables map[string]map[string]document
+}
+
+func newEngine() *engine {
+	return &engine{tables: make(map[string]map[string]document)}
+}
+
+// New creates an empty storage.
+func New() *docstore.Storage {
+	return docstore.New(newEngine())
+}
+
+// NewReader creates a reader of the storage.
+func NewReader(s *docstore.Storage) *docstore.Reader {
+	return docstore.NewReader(s.Engine())
+}
+
+// Ping always succeeds, the storage is in the process.
+func (e *engine) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close keeps the documents, a reader of the storage can still read them.
+func (e *engine) Close() {}
+
+// SetTimeouts does nothing, the in-memory operations are not bounded.
+func (e *engine) SetTimeouts(t storage.Timeouts) {}
+
+// SetRetryPolicy does nothing, the in-memory operations do not fail transiently.
+func (e *engine) SetRetryPolicy(p storage.RetryPolicy) {}
+
+// table returns the documents of the collection, it must be called with mu held.
+func (e *engine) table(name string) map[string]document {
+	t, ok := e.tables[name]
+	if !ok {
+		t = make(map[string]document)
+		e.tables[name] = t
+	}
+	return t
+}
+
+// encode returns the stored form of a document.
+func encode(doc any) (document, error) {
+	if fields, ok := doc.(bson.D); ok && fields == nil {
+		return document{}, nil
+	}
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var out document
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (e *engine) Upsert(ctx context.Context, table, key string, fields bson.D) error {
+	return e.UpsertBatch(ctx, table, []string{key}, []bson.D{fields})
+}
+
+func (e *engine) UpsertBatch(ctx context.Context, table string, keys []string, docs []bson.D) error {
+	encoded, err := encodeAll(docs)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := e.table(table)
+	for i, doc := range encoded {
+		t[keys[i]] = merge(t[keys[i]], doc)
+	}
+	return nil
+}
+
+func (e *engine) UpsertBatchVersioned(ctx context.Context, table string, keys []string, docs []bson.D) error {
+	encoded, err := encodeAll(docs)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := e.table(table)
+	for i, doc := range encoded {
+		stored := t[keys[i]]
+		merged := merge(stored, doc)
+		merged["version"] = number(intValue(stored["version"]) + 1)
+		t[keys[i]] = merged
+	}
+	return nil
+}
+
+func encodeAll(docs []bson.D) ([]document, error) {
+	encoded := make([]document, len(docs))
+	for i := range docs {
+		doc, err := encode(docs[i])
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = doc
+	}
+	return encoded, nil
+}
+
+func (e *engine) CompareAndSet(ctx context.Context, table, key string, version uint64, fields bson.D) (bool, error) {
+	doc, err := encode(fields)
+	if err != nil {
+		return false, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := e.table(table)
+	stored, ok := t[key]
+	if !ok || intValue(stored["version"]) != int64(version) {
+		return false, nil
+	}
+	stored = merge(stored, doc)
+	stored["version"] = number(int64(version) + 1)
+	t[key] = stored
+	return true, nil
+}
+
+func (e *engine) Existing(ctx context.Context, table string, keys []string) (map[string]bool, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	found := make(map[string]bool)
+	for _, key := range keys {
+		if _, ok := e.tables[table][key]; ok {
+			found[key] = true
+		}
+	}
+	return found, nil
+}
+
+func (e *engine) Insert(ctx context.Context, table, key string, fields bson.D) error {
+	return e.Apply(ctx, table, key, docstore.Update{SetOnInsert: fields, Upsert: true})
+}
+
+func (e *engine) Update(ctx context.Context, table, key string, fields bson.D) error {
+	return e.Apply(ctx, table, key, docstore.Update{Set: fields})
+}
+
+func (e *engine) Apply(ctx context.Context, table, key string, u docstore.Update) error {
+	set, err := encode(u.Set)
+	if err != nil {
+		return err
+	}
+	setOnInsert, err := encode(u.SetOnInsert)
+	if err != nil {
+		return err
+	}
+	addToSet, err := encode(u.AddToSet)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	t := e.table(table)
+	stored, found := t[key]
+	if !found && !u.Upsert {
+		return nil
+	}
+	doc := merge(stored, set)
+	if !found {
+		doc = merge(setOnInsert, set)
+	}
+	for _, f := range u.Inc {
+		doc[f.Key] = number(intValue(stored[f.Key]) + toInt(f.Value))
+	}
+	for _, f := range u.Min {
+		v := toInt(f.Value)
+		if _, ok := stored[f.Key]; ok {
+			v = min(v, intValue(stored[f.Key]))
+		}
+		doc[f.Key] = number(v)
+	}
+	for _, f := range u.Max {
+		v := toInt(f.Value)
+		if _, ok := stored[f.Key]; ok {
+			v = max(v, intValue(stored[f.Key]))
+		}
+		doc[f.Key] = number(v)
+	}
+	for field, values := range addToSet {
+		doc[field] = union(stored[field], values)
+	}
+	t[key] = doc
+	return nil
+}
+
+// toInt returns the integer value of an update operand.
+func toInt(v any) int64 {
+	doc, err := encode(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return 0
+	}
+	return intValue(doc["v"])
+}
+
+func (e *engine) UpdateMany(ctx context.Context, table string, filter *bson.D, fields bson.D) (int64, error) {
+	doc, err := encode(fields)
+	if err != nil {
+		return 0, err
+	}
+	return e.each(table, filter, 0, func(t map[string]document, key string, stored document) {
+		t[key] = merge(stored, doc)
+	})
+}
+
+func (e *engine) Remove(ctx context.Context, table string, filter *bson.D, limit int64) (int64, error) {
+	return e.each(table, filter, limit, func(t map[string]document, key string, stored document) {
+		delete(t, key)
+	})
+}
+
+func (e *engine) Unset(ctx context.Context, table string, filter *bson.D, limit int64, fields ...string) (int64, error) {
+	return e.each(table, filter, limit, func(t map[string]document, key string, stored document) {
+		stored = clone(stored)
+		for _, field := range fields {
+			delete(stored, field)
+		}
+		t[key] = stored
+	})
+}
+
+// each calls fn on up to limit documents matching the filter, all of them if limit is 0, in the
+// order of their keys, and returns their number.
+func (e *engine) each(table string, filter *bson.D, limit int64, fn func(t map[string]document, key string, stored document)) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// like mongo, updating or removing documents does not create the collection
+	t := e.tables[table]
+	filter, err := prepare(filter)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, key := range sortedKeys(t) {
+		if limit > 0 && n == limit {
+			break
+		}
+		ok, err := matches(t[key], filter)
+		if err != nil {
+			return n, err
+		}
+		if ok {
+			fn(t, key, t[key])
+			n++
+		}
+	}
+	return n, nil
+}
+
+// documents returns the documents of the table matching the filter, in the order of their keys.
+// It must be called with mu held.
+func (e *engine) documents(table string, filter *bson.D) ([]document, error) {
+	t := e.tables[table]
+	filter, err := prepare(filter)
+	if err != nil {
+		return nil, err
+	}
+	var docs []document
+	for _, key := range sortedKeys(t) {
+		ok, err := matches(t[key], filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			docs = append(docs, t[key])
+		}
+	}
+	return docs, nil
+}
+
+// Find ignores the projections, like the postgres engine.
+func (e *engine) Find(ctx context.Context, table string, filter *bson.D, opts ...*options.FindOptions) ([][]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	if err != nil {
+		return nil, err
+	}
+	var opt *options.FindOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	return page(docs, opt)
+}
+
+func (e *engine) FindPage(ctx context.Context, table string, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	raw, err := page(docs, opts)
+	return raw, int64(len(docs)), err
+}
+
+func (e *engine) Count(ctx context.Context, table string, filter *bson.D) (int64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	return int64(len(docs)), err
+}
+
+func (e *engine) Sum(ctx context.Context, table string, filter *bson.D, fields ...string) ([]int64, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]int64, len(fields)+1)
+	for _, doc := range docs {
+		for i, field := range fields {
+			results[i] += intValue(field1(doc, field))
+		}
+	}
+	results[len(fields)] = int64(len(docs))
+	return results, nil
+}
+
+func (e *engine) Max(ctx context.Context, table, field string, filter *bson.D) (int64, bool, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	if err != nil {
+		return 0, false, err
+	}
+	var result int64
+	found := false
+	for _, doc := range docs {
+		v, ok := field1(doc, field).(json.Number)
+		if !ok {
+			continue
+		}
+		if !found || intValue(v) > result {
+			result = intValue(v)
+		}
+		found = true
+	}
+	return result, found, nil
+}
+
+func (e *engine) Group(ctx context.Context, table string, g docstore.Group, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error) {
+	e.mu.RLock()
+	docs, err := e.documents(table, nil)
+	e.mu.RUnlock()
+	if err != nil {
+		return nil, 0, err
+	}
+	groups := make(map[string]document)
+	for _, doc := range docs {
+		keys := make([]int64, len(g.By))
+		for i, b := range g.By {
+			keys[i] = b.Value(intValue(field1(doc, b.Field)))
+		}
+		id := fmt.Sprint(keys)
+		group, ok := groups[id]
+		if !ok {
+			group = make(document, len(g.By)+len(g.Sums)+1)
+			for i, b := range g.By {
+				group[b.Name] = number(keys[i])
+			}
+			groups[id] = group
+		}
+		group["count"] = number(intValue(group["count"]) + 1)
+		for _, field := range g.Sums {
+			group[field] = number(intValue(group[field]) + intValue(field1(doc, field)))
+		}
+	}
+	filter, err = prepare(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	var matched []document
+	for _, group := range groups {
+		ok, err := matches(group, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			matched = append(matched, group)
+		}
+	}
+	order := make(bson.D, 0, len(g.By))
+	for _, b := range g.By {
+		order = append(order, bson.E{Key: b.Name, Value: 1})
+	}
+	sortDocuments(matched, order)
+	raw, err := page(matched, opts)
+	return raw, int64(len(matched)), err
+}
+
+func (e *engine) FindByRef(ctx context.Context, table string, filter *bson.D, ref docstore.Ref, opts *options.FindOptions) ([][]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, filter)
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]int64)
+	for _, doc := range e.tables[ref.Table] {
+		v := intValue(field1(doc, ref.Field))
+		for _, key := range ref.Keys {
+			local, ok := field1(doc, key).(string)
+			if !ok {
+				continue
+			}
+			if stored, ok := refs[local]; !ok || v < stored {
+				refs[local] = v
+			}
+		}
+	}
+	value := func(doc document) (int64, bool) {
+		local, _ := field1(doc, ref.Local).(string)
+		v, ok := refs[local]
+		return v, ok
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		vi, oki := value(docs[i])
+		vj, okj := value(docs[j])
+		if oki != okj {
+			return oki
+		}
+		return vi > vj
+	})
+	var page *options.FindOptions
+	if opts != nil {
+		page = options.Find()
+		page.Skip, page.Limit = opts.Skip, opts.Limit
+	}
+	return pageOf(docs, page)
+}
+
+// Search ranks the documents by the number of the other words of their field.
+func (e *engine) Search(ctx context.Context, table, field, text string, limit int64) ([][]byte, error) {
+	words := strings.Fields(strings.ToLower(text))
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, nil)
+	if err != nil {
+		return nil, err
+	}
+	found := docs[:0:0]
+	for _, doc := range docs {
+		value, _ := field1(doc, field).(string)
+		if containsWords(value, words) {
+			found = append(found, doc)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		vi, _ := field1(found[i], field).(string)
+		vj, _ := field1(found[j], field).(string)
+		return len(strings.Fields(vi)) < len(strings.Fields(vj))
+	})
+	return pageOf(found, options.Find().SetLimit(limit))
+}
+
+// containsWords reports whether the text contains all the lower case words.
+func containsWords(text string, words []string) bool {
+	names := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		names[w] = true
+	}
+	for _, w := range words {
+		if !names[w] {
+			return false
+		}
+	}
+	return true
+}
+
+// FindNear measures the distances with the haversine formula.
+func (e *engine) FindNear(ctx context.Context, table, field string, lon, lat, radius float64, limit int64) ([][]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, nil)
+	if err != nil {
+		return nil, err
+	}
+	distances := make(map[int]float64)
+	var near []document
+	for _, doc := range docs {
+		x, y, ok := coordinates(doc, field)
+		if !ok {
+			continue
+		}
+		if d := distance(lon, lat, x, y); d <= radius {
+			distances[len(near)] = d
+			near = append(near, doc)
+		}
+	}
+	index := make([]int, len(near))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return distances[index[i]] < distances[index[j]] })
+	sorted := make([]document, len(near))
+	for i, n := range index {
+		sorted[i] = near[n]
+	}
+	return pageOf(sorted, options.Find().SetLimit(limit))
+}
+
+func (e *engine) FindWithin(ctx context.Context, table, field string, box model.GeoBox, limit int64) ([][]byte, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	docs, err := e.documents(table, nil)
+	if err != nil {
+		return nil, err
+	}
+	var within []document
+	for _, doc := range docs {
+		lon, lat, ok := coordinates(doc, field)
+		if ok && lon >= box.MinLon && lon <= box.MaxLon && lat >= box.MinLat && lat <= box.MaxLat {
+			within = append(within, doc)
+		}
+	}
+	return pageOf(within, options.Find().SetLimit(limit))
+}
+
+// coordinates returns the [longitude, latitude] field of the document.
+func coordinates(doc document, field string) (lon, lat float64, ok bool) {
+	values, _ := field1(doc, field).([]any)
+	if len(values) != 2 {
+		return 0, 0, false
+	}
+	x, xok := values[0].(json.Number)
+	y, yok := values[1].(json.Number)
+	if !xok || !yok {
+		return 0, 0, false
+	}
+	lon, errLon := x.Float64()
+	lat, errLat := y.Float64()
+	return lon, lat, errLon == nil && errLat == nil
+}
+
+// distance returns the distance in meters between two points on the Earth.
+func distance(lon1, lat1, lon2, lat2 float64) float64 {
+	rad := math.Pi / 180
+	a := math.Pow(math.Sin((lat2-lat1)*rad/2), 2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin((lon2-lon1)*rad/2), 2)
+	return 2 * docstore.EarthRadius * math.Asin(math.Sqrt(a))
+}
+
+// Stats counts the documents, their sizes are not known.
+func (e *engine) Stats(ctx context.Context) ([]*model.CollectionStats, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	stats := make([]*model.CollectionStats, 0, len(e.tables))
+	for name, table := range e.tables {
+		stats = append(stats, &model.CollectionStats{Collection: name, Documents: int64(len(table))})
+	}
+	return stats, nil
+}
+
+// page sorts the documents and returns the raw documents of the page of the find options.
+func page(docs []document, opts *options.FindOptions) ([][]byte, error) {
+	if opts != nil && opts.Sort != nil {
+		keys, ok := opts.Sort.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("unsupported sort %T", opts.Sort)
+		}
+		docs = append([]document(nil), docs...)
+		sortDocuments(docs, keys)
+	}
+	return pageOf(docs, opts)
+}
+
+// pageOf returns the raw documents of the page of the find options, in their order.
+func pageOf(docs []document, opts *options.FindOptions) ([][]byte, error) {
+	if opts != nil && opts.Skip != nil && *opts.Skip > 0 {
+		docs = docs[min(*opts.Skip, int64(len(docs))):]
+	}
+	if opts != nil && opts.Limit != nil && *opts.Limit > 0 && *opts.Limit < int64(len(docs)) {
+		docs = docs[:*opts.Limit]
+	}
+	raw := make([][]byte, 0, len(docs))
+	for _, doc := range docs {
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		raw = append(raw, data)
+	}
+	return raw, nil
+}
+
+// merge sets the fields of doc on a copy of the stored document, like the jsonb `||` operator.
+func merge(stored, doc document) document {
+	merged := make(document, len(stored)+len(doc))
+	for k, v := range stored {
+		merged[k] = v
+	}
+	for k, v := range doc {
+		merged[k] = v
+	}
+	return merged
+}
+
+func clone(doc document) document {
+	return merge(doc, nil)
+}
+
+func sortedKeys(t map[string]document) []string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
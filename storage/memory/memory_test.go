@@ -0,0 +1,1825 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk/multisig"
+	sdkvesting "github.com/spacemeshos/go-spacemesh/genvm/sdk/vesting"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vault"
+	"github.com/spacemeshos/go-spacemesh/genvm/templates/vesting"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache/cachetest"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/pkg/economics"
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 12; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+			Blocks: []*pb.Block{{Id: blockID(i, 1)}, {Id: blockID(i, 2)}},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 1, Received: 10},
+	})
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 90},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(11), reward(12)})
+	// a reward received again is not counted twice
+	s.OnReward(reward(12))
+	s.UpdateEpochStats(12)
+
+	svc := service.NewService(NewReader(s), time.Second)
+
+	info, err := svc.GetNetworkInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(12), info.LastLayer)
+
+	layers, total, err := svc.GetLayers(ctx, 1, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), total)
+	require.Len(t, layers, 5)
+	require.Equal(t, uint32(12), layers[0].Number)
+	require.Equal(t, uint64(100), layers[0].Rewards)
+	require.Equal(t, uint64(10), layers[0].FeesDistributed)
+	require.Equal(t, uint32(2), layers[0].BlocksNumber)
+	// the rewards sum is stored with the rewards, before the epoch stats are updated
+	require.Equal(t, uint64(100), layers[1].Rewards)
+
+	blocks, total, err := svc.GetLayerBlocks(ctx, 3, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, blocks, 2)
+
+	rewards, total, err := svc.GetRewards(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, rewards, 1)
+	require.Equal(t, uint32(11), rewards[0].Layer)
+
+	smesher, err := svc.GetSmesher(ctx, "0x51")
+	require.NoError(t, err)
+	require.Equal(t, int64(200), smesher.Rewards)
+	require.Equal(t, uint64(2048), smesher.CommitmentSize)
+
+	epoch, err := svc.GetEpoch(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), epoch.Stats.Current.Smeshers)
+	require.Equal(t, int64(2048), epoch.Stats.Current.Security)
+	require.Equal(t, int64(200), epoch.Stats.Current.Rewards)
+	require.Equal(t, int64(0), epoch.Stats.Current.SpaceGini)
+	require.Equal(t, int64(1), epoch.Stats.Current.SpaceNakamoto)
+	require.Equal(t, int64(0), epoch.Stats.Current.RewardsGini)
+	require.Equal(t, int64(1), epoch.Stats.Current.RewardsNakamoto)
+
+	series, total, err := svc.GetDecentralization(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, series, int(total))
+	require.Contains(t, series, &model.Decentralization{Epoch: 1, SpaceNakamoto: 1, RewardsNakamoto: 1})
+
+	_, err = svc.GetSmesher(ctx, "0x52")
+	require.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func blockID(layer uint32, i byte) []byte {
+	id := make([]byte, 20)
+	id[0], id[1] = byte(layer), i
+	return id
+}
+
+// racingUpdater returns a stale state on its first request, the accounts stream writing a newer
+// one meanwhile.
+type racingUpdater struct {
+	s        *docstore.Storage
+	account  types.Account
+	requests atomic.Int32
+}
+
+func (u *racingUpdater) GetAccountState(address string) (uint64, uint64, error) {
+	if u.requests.Add(1) == 1 {
+		u.s.OnAccounts([]*types.Account{&u.account})
+		return 10, 1, nil
+	}
+	return u.account.Balance, u.account.NextNonce, nil
+}
+
+func TestUpdateAccountCompareAndSet(t *testing.T) {
+	ctx := context.Background()
+	e := newEngine()
+	s := docstore.New(e)
+	defer s.Close()
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+
+	address := types.GenerateAddress([]byte{1})
+	updater := &racingUpdater{s: s, account: types.Account{Address: address, Balance: 20, NextNonce: 2, Layer: 1}}
+	s.SetAccountUpdater(updater)
+	// the reward creates the account and requests its balance
+	s.OnReward(&pb.Reward{
+		Layer:    &pb.LayerNumber{Number: 2},
+		Total:    &pb.Amount{Value: 100},
+		Coinbase: &pb.AccountId{Address: address.String()},
+		Smesher:  &pb.SmesherId{Id: []byte{0x51}},
+	})
+
+	var account model.Account
+	require.Eventually(t, func() bool {
+		docs, err := e.Find(ctx, "accounts", &bson.D{{Key: "address", Value: address.String()}})
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		require.NoError(t, bson.UnmarshalExtJSON(docs[0], false, &account))
+		return account.Version == 2
+	}, time.Second, 10*time.Millisecond)
+	require.EqualValues(t, 2, updater.requests.Load())
+	require.EqualValues(t, 20, account.Balance)
+	require.EqualValues(t, 2, account.Counter)
+
+	updated, err := e.CompareAndSet(ctx, "accounts", address.String(), 1, bson.D{{Key: "balance", Value: 5}})
+	require.NoError(t, err)
+	require.False(t, updated)
+}
+
+func TestUpsertAccountCreated(t *testing.T) {
+	ctx := context.Background()
+	e := newEngine()
+	s := docstore.New(e)
+	defer s.Close()
+
+	address := types.GenerateAddress([]byte{1}).String()
+	account := func() *model.Account {
+		docs, err := e.Find(ctx, "accounts", &bson.D{{Key: "address", Value: address}})
+		require.NoError(t, err)
+		require.Len(t, docs, 1)
+		var account model.Account
+		require.NoError(t, bson.UnmarshalExtJSON(docs[0], false, &account))
+		return &account
+	}
+
+	require.NoError(t, s.UpsertAccount(ctx, 20, &model.Account{Address: address, Balance: 100, Counter: 1}))
+	// the account keeps the first layer it was seen at
+	require.NoError(t, s.UpsertAccount(ctx, 30, &model.Account{Address: address, Balance: 50, Counter: 2}))
+	stored := account()
+	require.EqualValues(t, 20, stored.Created)
+	require.EqualValues(t, 50, stored.Balance)
+	require.EqualValues(t, 2, stored.Version)
+
+	// an earlier layer processed late moves the creation back
+	require.NoError(t, s.UpsertAccount(ctx, 10, &model.Account{Address: address, Balance: 50, Counter: 2}))
+	require.EqualValues(t, 10, account().Created)
+}
+
+func TestBalanceAt(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	address := types.GenerateAddress([]byte{2})
+	s.OnAccounts([]*types.Account{{Address: address, Balance: 100, NextNonce: 1, Layer: 5}})
+	s.OnAccounts([]*types.Account{{Address: address, Balance: 40, NextNonce: 2, Layer: 9}})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	for layer, balance := range map[uint32]uint64{4: 0, 5: 100, 8: 100, 9: 40, 20: 40} {
+		at, err := svc.GetAccountBalanceAt(ctx, address.String(), layer)
+		require.NoError(t, err)
+		require.Equal(t, &model.AccountSnapshot{Address: address.String(), Layer: layer, Balance: balance}, at)
+	}
+
+	_, err := svc.GetAccountBalanceAt(ctx, types.GenerateAddress([]byte{3}).String(), 9)
+	require.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestBalanceCohorts(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 125},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{125},
+	})
+	a, b, c := types.GenerateAddress([]byte{1}), types.GenerateAddress([]byte{2}), types.GenerateAddress([]byte{3})
+	s.OnAccounts([]*types.Account{{Address: a, Balance: 100, Layer: 5}, {Address: c, Balance: 20, Layer: 7}})
+	s.OnAccounts([]*types.Account{{Address: b, Balance: 50, Layer: 15}})
+	s.OnAccounts([]*types.Account{{Address: c, Balance: 0, Layer: 25}})
+	s.OnAccounts([]*types.Account{{Address: b, Balance: 30, Layer: 105}})
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetBalanceCohorts(ctx, 1, 100)
+	require.NoError(t, err)
+	require.Equal(t, int64(13), total)
+	require.Len(t, series, 13)
+	cohorts := func(epoch uint32) *model.BalanceCohorts {
+		require.Equal(t, epoch, series[12-epoch].Epoch)
+		return series[12-epoch]
+	}
+	require.Equal(t, &model.BalanceCohorts{Epoch: 1, Balance: 170, Cohorts: []*model.BalanceCohort{
+		{From: 0, To: 1, Accounts: 1, Balance: 50, Share: 2941},
+		{From: 1, To: 10, Accounts: 2, Balance: 120, Share: 7059},
+		{From: 10, To: 50},
+		{From: 50, To: 100},
+		{From: 100},
+	}}, cohorts(1))
+	// the account emptied in epoch 2 is not counted anymore
+	require.Equal(t, int64(2), cohorts(2).Cohorts[1].Accounts)
+	require.Equal(t, uint64(150), cohorts(2).Cohorts[1].Balance)
+	require.Equal(t, &model.BalanceCohorts{Epoch: 12, Balance: 130, Cohorts: []*model.BalanceCohort{
+		{From: 0, To: 1},
+		{From: 1, To: 10, Accounts: 1, Balance: 30, Share: 2308},
+		{From: 10, To: 50, Accounts: 1, Balance: 100, Share: 7692},
+		{From: 50, To: 100},
+		{From: 100},
+	}}, cohorts(12))
+}
+
+func TestSmesherNames(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	require.NoError(t, s.UpsertLabels(ctx, []*model.Label{{Kind: model.LabelSmesher, Id: "0x51", Name: "pool"}}))
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", TargetEpoch: 1},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", TargetEpoch: 1},
+	})
+	svc := service.NewService(NewReader(s), time.Second)
+	atx, err := svc.GetActivation(ctx, "0xa1")
+	require.NoError(t, err)
+	require.Equal(t, "pool", atx.SmesherName)
+	atx, err = svc.GetActivation(ctx, "0xa2")
+	require.NoError(t, err)
+	require.Empty(t, atx.SmesherName)
+
+	// renaming a smesher renames its activations
+	require.NoError(t, s.UpsertLabels(ctx, []*model.Label{
+		{Kind: model.LabelSmesher, Id: "0x51", Name: "new pool"},
+		{Kind: model.LabelAccount, Id: "0x52", Name: "exchange"},
+	}))
+	atx, err = svc.GetActivation(ctx, "0xa1")
+	require.NoError(t, err)
+	require.Equal(t, "new pool", atx.SmesherName)
+	atx, err = svc.GetActivation(ctx, "0xa2")
+	require.NoError(t, err)
+	require.Empty(t, atx.SmesherName)
+}
+
+func TestSmesherHistoryNames(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	svc := service.NewService(NewReader(s), time.Second)
+	history := func() []*model.SmesherChange {
+		changes, total, err := svc.GetSmesherHistory(ctx, "0x51", 1, 100)
+		require.NoError(t, err)
+		require.Len(t, changes, int(total))
+		return changes
+	}
+
+	require.NoError(t, s.UpsertLabels(ctx, []*model.Label{{Kind: model.LabelSmesher, Id: "0x51", Name: "pool"}}))
+	changes := history()
+	require.Len(t, changes, 1)
+	require.Equal(t, uint32(0), changes[0].Epoch)
+	require.Equal(t, "pool", changes[0].Name)
+	require.Equal(t, []string{"name"}, changes[0].Changed)
+
+	// an unchanged name and the labels of the accounts add no entry
+	require.NoError(t, s.UpsertLabels(ctx, []*model.Label{
+		{Kind: model.LabelSmesher, Id: "0x51", Name: "pool"},
+		{Kind: model.LabelAccount, Id: "0x51", Name: "exchange"},
+	}))
+	require.Len(t, history(), 1)
+
+	// a rename in the epoch of an activation change is merged into its entry
+	s.OnLayer(&pb.Layer{Number: &pb.LayerNumber{Number: 12}, Hash: []byte{12}})
+	s.OnActivations([]*model.Activation{{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1}})
+	require.NoError(t, s.UpsertLabels(ctx, []*model.Label{{Kind: model.LabelSmesher, Id: "0x51", Name: "new pool"}}))
+	changes = history()
+	require.Len(t, changes, 2)
+	require.Equal(t, uint32(1), changes[0].Epoch)
+	require.Equal(t, "new pool", changes[0].Name)
+	require.Equal(t, "sm1", changes[0].Coinbase)
+	require.ElementsMatch(t, []string{"coinbase", "cSize", "name"}, changes[0].Changed)
+	require.Equal(t, "pool", changes[1].Name)
+}
+
+// analytics serves fixed rewards totals, or fails with err.
+type analytics struct {
+	err error
+}
+
+func (a *analytics) GetTotalRewards(context.Context, *bson.D) (int64, int64, error) {
+	return 1000, 10, a.err
+}
+
+func (a *analytics) CountCoinbaseRewards(context.Context, string) (int64, int64, error) {
+	return 1000, 10, a.err
+}
+
+func (a *analytics) CountSmesherRewards(context.Context, string) (int64, int64, error) {
+	return 1000, 10, a.err
+}
+
+func TestAnalyticsFallback(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnRewards([]*pb.Reward{{
+		Layer:    &pb.LayerNumber{Number: 11},
+		Total:    &pb.Amount{Value: 100},
+		Coinbase: &pb.AccountId{Address: "sm1"},
+		Smesher:  &pb.SmesherId{Id: []byte{0x51}},
+	}})
+	rewards := func(svc *service.Service) (int64, int64) {
+		total, count, err := svc.GetTotalRewards(ctx, &bson.D{{Key: "smesher", Value: "0x51"}})
+		require.NoError(t, err)
+		smesherTotal, smesherCount, err := svc.CountSmesherRewards(ctx, "0x51")
+		require.NoError(t, err)
+		require.Equal(t, total, smesherTotal)
+		require.Equal(t, count, smesherCount)
+		return total, count
+	}
+
+	// without an analytics store the rewards are read from the storage
+	svc := service.NewService(NewReader(s), time.Second)
+	total, count := rewards(svc)
+	require.Equal(t, int64(100), total)
+	require.Equal(t, int64(1), count)
+
+	svc = service.NewService(NewReader(s), time.Second)
+	svc.SetAnalytics(&analytics{})
+	total, count = rewards(svc)
+	require.Equal(t, int64(1000), total)
+	require.Equal(t, int64(10), count)
+
+	// a failing analytics store falls back to the storage
+	svc = service.NewService(NewReader(s), time.Second)
+	svc.SetAnalytics(&analytics{err: fmt.Errorf("clickhouse unavailable")})
+	total, count = rewards(svc)
+	require.Equal(t, int64(100), total)
+	require.Equal(t, int64(1), count)
+}
+
+func TestRewardActivations(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	coinbase := types.GenerateAddress([]byte{4}).String()
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:    &pb.LayerNumber{Number: layer},
+			Total:    &pb.Amount{Value: 100},
+			Coinbase: &pb.AccountId{Address: coinbase},
+			Smesher:  &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnActivations([]*model.Activation{{Id: "0xa2", SmesherId: "0x51", Coinbase: coinbase, NumUnits: 1, TargetEpoch: 2}})
+	// a reward stored before its activation is linked when the activation is stored
+	s.OnRewards([]*pb.Reward{reward(12)})
+	s.OnActivations([]*model.Activation{{Id: "0xa1", SmesherId: "0x51", Coinbase: coinbase, NumUnits: 1, TargetEpoch: 1}})
+	s.OnRewards([]*pb.Reward{reward(13), reward(21)})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	rewards, total, err := svc.GetActivationRewards(ctx, "0xa1", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	for _, r := range rewards {
+		require.Equal(t, "0xa1", r.Atx)
+	}
+	atx, err := svc.GetActivation(ctx, "0xa2")
+	require.NoError(t, err)
+	require.Equal(t, int64(100), atx.Rewards)
+	require.Equal(t, int64(1), atx.RewardsCount)
+
+	smeshers, total, err := svc.GetAccountSmeshers(ctx, coinbase, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, "0x51", smeshers[0].Smesher)
+	require.Equal(t, int64(300), smeshers[0].TotalRewards)
+	require.Equal(t, int64(3), smeshers[0].RewardsCount)
+}
+
+func TestSmeshersChurn(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 45; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	var atxs []*model.Activation
+	for smesher, epochs := range map[string][]uint32{
+		"0x51": {1, 2, 4},
+		"0x52": {1},
+		"0x53": {2},
+	} {
+		for _, epoch := range epochs {
+			atxs = append(atxs, &model.Activation{
+				Id: fmt.Sprintf("%s-%d", smesher, epoch), SmesherId: smesher, Coinbase: "sm1", NumUnits: 1, TargetEpoch: epoch,
+			})
+		}
+	}
+	s.OnActivations(atxs)
+	s.UpdateEpochStats(1)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetSmeshersChurn(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), total)
+	require.Equal(t, []*model.SmeshersChurn{
+		{Epoch: 4, Returning: 1},
+		{Epoch: 3, Stopped: 2},
+		{Epoch: 2, New: 1, Stopped: 1},
+		{Epoch: 1, New: 2},
+		{Epoch: 0},
+	}, series)
+}
+
+func TestSpaceGrowth(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 35; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0x01", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 4, EffectiveNumUnits: 2, TargetEpoch: 1},
+		{Id: "0x02", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 4, EffectiveNumUnits: 4, TargetEpoch: 2},
+		{Id: "0x03", SmesherId: "0x52", Coinbase: "sm1", NumUnits: 3, EffectiveNumUnits: 3, TargetEpoch: 2},
+		{Id: "0x04", SmesherId: "0x52", Coinbase: "sm1", NumUnits: 3, EffectiveNumUnits: 3, TargetEpoch: 3},
+	})
+	s.UpdateEpochStats(1)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetSpaceGrowth(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), total)
+	require.Equal(t, []*model.SpaceGrowth{
+		{Epoch: 3, Space: 3 * 1024, Delta: -4 * 1024},
+		{Epoch: 2, Space: 7 * 1024, Delta: 5 * 1024},
+		{Epoch: 1, Space: 2 * 1024, Delta: 2 * 1024},
+		{Epoch: 0},
+	}, series)
+}
+
+func TestActiveSmeshers(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 25; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0x01", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1},
+		{Id: "0x02", SmesherId: "0x52", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1},
+		{Id: "0x03", SmesherId: "0x53", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1},
+		{Id: "0x04", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 2},
+	})
+	reward := func(layer uint32, smesher byte) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 100},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{smesher}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(11, 0x51), reward(12, 0x51), reward(12, 0x52), reward(21, 0x51)})
+	s.UpdateEpochStats(1)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetActiveSmeshers(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, []*model.ActiveSmeshers{
+		{Epoch: 2, Activated: 1, Rewarded: 1},
+		{Epoch: 1, Activated: 3, Rewarded: 2},
+		{Epoch: 0},
+	}, series)
+}
+
+func TestEpochUnits(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 25; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0x01", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 4, EffectiveNumUnits: 2, Weight: 200, TargetEpoch: 2},
+		{Id: "0x02", SmesherId: "0x52", Coinbase: "sm1", NumUnits: 3, EffectiveNumUnits: 3, Weight: 450, TargetEpoch: 2},
+		{Id: "0x03", SmesherId: "0x52", Coinbase: "sm1", NumUnits: 3, EffectiveNumUnits: 3, Weight: 300, TargetEpoch: 1},
+	})
+	s.UpdateEpochStats(1)
+
+	epoch, err := service.NewService(NewReader(s), time.Second).GetEpoch(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), epoch.Stats.Current.EffectiveNumUnits)
+	require.Equal(t, int64(650), epoch.Stats.Current.Weight)
+	require.Equal(t, int64(5*1024), epoch.Stats.Current.Space)
+	require.Equal(t, epoch.Stats.Current.Weight, epoch.Stats.Cumulative.Weight)
+}
+
+func TestAccountsGrowth(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	// 4 layers a day from the start of day 1
+	s.OnNetworkInfo("0x01", 86400, 10, 100, 21600, 1024)
+	var accounts []*types.Account
+	for i, layer := range []uint32{0, 1, 5, 6, 13} {
+		accounts = append(accounts, &types.Account{Address: types.GenerateAddress([]byte{byte(i)}), Layer: types.LayerID(layer)})
+	}
+	s.OnAccounts(accounts)
+
+	svc := service.NewService(NewReader(s), time.Second)
+	days, total, err := svc.GetDailyAccounts(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, []*model.DailyAccounts{
+		{Day: 4 * 86400, New: 1, Total: 5},
+		{Day: 2 * 86400, New: 2, Total: 4},
+		{Day: 86400, New: 2, Total: 2},
+	}, days)
+
+	days, _, err = svc.GetDailyAccounts(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, []*model.DailyAccounts{{Day: 2 * 86400, New: 2, Total: 4}}, days)
+
+	s.UpdateEpochStats(13)
+	s.UpdateEpochStats(0)
+	epochs, total, err := svc.GetEpochAccounts(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.EpochAccounts{
+		{Epoch: 1, New: 1, Total: 5},
+		{Epoch: 0, New: 4, Total: 4},
+	}, epochs)
+}
+
+func TestVestingUnlocks(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	args := &vault.SpawnArguments{
+		Owner:               types.GenerateAddress([]byte{1}),
+		TotalAmount:         1000,
+		InitialUnlockAmount: 100,
+		VestingStart:        20,
+		VestingEnd:          40,
+	}
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	vesting := types.GenerateAddress([]byte{2})
+	raw := multisig.Spawn(0, signer.PrivateKey(), vesting, vault.TemplateAddress, args, 1).Raw()
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 12},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{12},
+		Blocks: []*pb.Block{{Id: blockID(12, 1), Transactions: []*pb.Transaction{{
+			Id:       []byte{1},
+			Method:   model.MethodSpawn,
+			Template: &pb.AccountId{Address: vault.TemplateAddress.String()},
+			Raw:      raw,
+		}}}},
+	})
+
+	vaults, err := NewReader(s).GetVaults(ctx)
+	require.NoError(t, err)
+	require.Len(t, vaults, 1)
+	require.Equal(t, core.ComputePrincipal(vault.TemplateAddress, args).String(), vaults[0].Address)
+	require.Equal(t, args.Owner.String(), vaults[0].Owner)
+	require.Equal(t, uint32(20), vaults[0].VestingStart)
+	require.Equal(t, uint32(40), vaults[0].VestingEnd)
+
+	unlocks, total, err := service.NewService(NewReader(s), time.Second).GetVestingUnlocks(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, []*model.VestingUnlock{
+		{Epoch: 1, Unlocked: 0, Vested: 0, Locked: 1000},
+		{Epoch: 2, Unlocked: 500, Vested: 500, Locked: 500},
+		{Epoch: 3, Unlocked: 500, Vested: 1000, Locked: 0},
+	}, unlocks)
+}
+
+func TestVaultDrawdown(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	args := &vault.SpawnArguments{Owner: types.GenerateAddress([]byte{1}), TotalAmount: 1000, VestingStart: 20, VestingEnd: 40}
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	principal := types.GenerateAddress([]byte{2})
+	vaultAddress := core.ComputePrincipal(vault.TemplateAddress, args)
+	receiver := types.GenerateAddress([]byte{3})
+	txs := map[uint32]*pb.Transaction{
+		12: {
+			Id:       []byte{12},
+			Method:   model.MethodSpawn,
+			Template: &pb.AccountId{Address: vault.TemplateAddress.String()},
+			Raw:      multisig.Spawn(0, signer.PrivateKey(), principal, vault.TemplateAddress, args, 1).Raw(),
+		},
+		25: {Id: []byte{25}, Method: vesting.MethodDrainVault, Raw: sdkvesting.DrainVault(0, signer.PrivateKey(), principal, vaultAddress, receiver, 200, 2).Raw()},
+		26: {Id: []byte{26}, Method: vesting.MethodDrainVault, Raw: sdkvesting.DrainVault(0, signer.PrivateKey(), principal, vaultAddress, receiver, 900, 3).Raw()},
+	}
+	for _, layer := range []uint32{12, 25, 26} {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+			Blocks: []*pb.Block{{Id: blockID(layer, 1), Transactions: []*pb.Transaction{txs[layer]}}},
+		})
+	}
+	// the drain of more than the vested amount fails
+	processed := &pb.TransactionState{State: pb.TransactionState_TRANSACTION_STATE_PROCESSED}
+	for layer, status := range map[uint32]pb.TransactionResult_Status{25: pb.TransactionResult_SUCCESS, 26: pb.TransactionResult_FAILURE} {
+		s.OnTransactionResult(&pb.TransactionResult{
+			Tx:               txs[layer],
+			Status:           status,
+			Layer:            layer,
+			Block:            blockID(layer, 1),
+			TouchedAddresses: []string{principal.String(), vaultAddress.String(), receiver.String()},
+		}, processed)
+	}
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetVaultDrawdown(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), total)
+	require.Equal(t, []*model.VaultDrawdown{
+		{Epoch: 2, Drains: 1, Drained: 200, Cumulative: 200, Vested: 500, Undrained: 300, Remaining: 800},
+		{Epoch: 1, Vested: 0, Remaining: 1000},
+		{Epoch: 0, Vested: 0, Remaining: 1000},
+	}, series)
+}
+
+func TestTransactionTypes(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	args := &vault.SpawnArguments{Owner: types.GenerateAddress([]byte{1}), TotalAmount: 1000, VestingEnd: 40}
+	txs := map[uint32][]byte{
+		12: multisig.Spawn(0, signer.PrivateKey(), types.GenerateAddress([]byte{2}), vault.TemplateAddress, args, 1).Raw(),
+		13: wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, 1),
+		25: wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 20, 2),
+	}
+	for layer, raw := range txs {
+		tx := &pb.Transaction{Id: []byte{byte(layer)}, Method: core.MethodSpend, Raw: raw}
+		if layer == 12 {
+			tx.Method = model.MethodSpawn
+			tx.Template = &pb.AccountId{Address: vault.TemplateAddress.String()}
+		}
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+			Blocks: []*pb.Block{{Id: blockID(layer, 1), Transactions: []*pb.Transaction{tx}}},
+		})
+	}
+
+	svc := service.NewService(NewReader(s), time.Second)
+	days, total, err := svc.GetTransactionTypes(ctx, model.PeriodDay, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, []*model.TransactionTypes{{Period: 0, Types: map[string]*model.TransactionTypeCount{
+		"vault_spawn": {Count: 1},
+		"spend":       {Count: 2, Amount: 30},
+	}}}, days)
+
+	epochs, total, err := svc.GetTransactionTypes(ctx, model.PeriodEpoch, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.TransactionTypes{
+		{Period: 2, Types: map[string]*model.TransactionTypeCount{"spend": {Count: 1, Amount: 20}}},
+		{Period: 1, Types: map[string]*model.TransactionTypeCount{
+			"vault_spawn": {Count: 1},
+			"spend":       {Count: 1, Amount: 10},
+		}},
+	}, epochs)
+}
+
+func TestFeeSeries(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for _, layer := range []uint32{5, 15} {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		})
+	}
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	processed := &pb.TransactionState{State: pb.TransactionState_TRANSACTION_STATE_PROCESSED}
+	for layer, fees := range map[uint32][]uint64{5: {10, 30, 25, 20}, 15: {7}} {
+		for i, fee := range fees {
+			nonce := uint64(layer)*10 + uint64(i)
+			s.OnTransactionResult(&pb.TransactionResult{
+				Tx: &pb.Transaction{
+					Id:     []byte{byte(nonce)},
+					Method: core.MethodSpend,
+					Raw:    wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{1}), 1, nonce),
+				},
+				Layer: layer,
+				Block: blockID(layer, 1),
+				Fee:   fee,
+			}, processed)
+		}
+	}
+	s.UpdateEpochStats(15)
+	s.UpdateEpochStats(0)
+
+	svc := service.NewService(NewReader(s), time.Second)
+	layers, total, err := svc.GetFeeSeries(ctx, model.PeriodLayer, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.FeeSeries{
+		{Period: 15, Total: 7, Min: 7, Median: 7, Max: 7},
+		{Period: 5, Total: 85, Min: 10, Median: 22, Max: 30},
+	}, layers)
+
+	epochs, total, err := svc.GetFeeSeries(ctx, model.PeriodEpoch, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.FeeSeries{
+		{Period: 1, Total: 7, Min: 7, Median: 7, Max: 7},
+		{Period: 0, Total: 85, Min: 10, Median: 22, Max: 30},
+	}, epochs)
+}
+
+func TestIssuance(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	var rewards []*pb.Reward
+	for layer := uint32(1); layer <= 32; layer++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		})
+		subsidy := economics.LayerSubsidy(layer, 10)
+		if layer == 15 {
+			// a reward before the effective genesis
+			subsidy = 100
+		}
+		if subsidy == 0 {
+			continue
+		}
+		rewards = append(rewards, &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: subsidy + 5},
+			LayerReward: &pb.Amount{Value: subsidy},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		})
+	}
+	s.OnRewards(rewards)
+	s.UpdateEpochStats(32)
+	s.UpdateEpochStats(0)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetIssuance(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), total)
+	expected := economics.IssuedBetween(20, 29, 10)
+	partial := economics.IssuedBetween(30, 32, 10)
+	require.Equal(t, []*model.Issuance{
+		{Epoch: 3, Expected: economics.IssuedBetween(30, 39, 10), Observed: partial, Divergence: int64(partial) - int64(economics.IssuedBetween(30, 39, 10))},
+		{Epoch: 2, Expected: expected, Observed: expected, Complete: true},
+		{Epoch: 1, Observed: 100, Divergence: 100, Complete: true, Diverged: true},
+		{Epoch: 0, Complete: true},
+	}, series)
+}
+
+func TestSupplyBreakdown(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	args := &vault.SpawnArguments{
+		Owner:               types.GenerateAddress([]byte{1}),
+		TotalAmount:         1000,
+		InitialUnlockAmount: 100,
+		VestingStart:        20,
+		VestingEnd:          40,
+	}
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	raw := multisig.Spawn(0, signer.PrivateKey(), types.GenerateAddress([]byte{2}), vault.TemplateAddress, args, 1).Raw()
+	for layer := uint32(1); layer <= 35; layer++ {
+		in := &pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		}
+		if layer == 12 {
+			in.Blocks = []*pb.Block{{Id: blockID(layer, 1), Transactions: []*pb.Transaction{{
+				Id:       []byte{1},
+				Method:   model.MethodSpawn,
+				Template: &pb.AccountId{Address: vault.TemplateAddress.String()},
+				Raw:      raw,
+			}}}}
+		}
+		s.OnLayer(in)
+	}
+	reward := func(layer uint32, total uint64) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: total},
+			LayerReward: &pb.Amount{Value: total},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(21, 300), reward(31, 200)})
+	s.UpdateEpochStats(35)
+	s.UpdateEpochStats(0)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetSupplyBreakdown(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), total)
+	const genesis = economics.TotalVaulted
+	require.Equal(t, []*model.SupplyBreakdown{
+		// the ongoing epoch is vested until its last collected layer
+		{Epoch: 3, Genesis: genesis, Vested: 750, Locked: genesis - 750, Rewards: 500, Total: genesis + 500, Circulating: 1250},
+		{Epoch: 2, Genesis: genesis, Vested: 500, Locked: genesis - 500, Rewards: 300, Total: genesis + 300, Circulating: 800},
+		{Epoch: 1, Genesis: genesis, Locked: genesis, Total: genesis},
+		{Epoch: 0, Genesis: genesis, Locked: genesis, Total: genesis},
+	}, series)
+}
+
+func TestCoinbaseConcentration(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", EffectiveNumUnits: 4, TargetEpoch: 2},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm1", EffectiveNumUnits: 2, TargetEpoch: 2},
+		{Id: "0xa3", SmesherId: "0x53", Coinbase: "sm2", EffectiveNumUnits: 3, TargetEpoch: 2},
+		{Id: "0xa4", SmesherId: "0x54", Coinbase: "sm3", EffectiveNumUnits: 1, TargetEpoch: 2},
+		{Id: "0xa5", SmesherId: "0x51", Coinbase: "sm1", EffectiveNumUnits: 4, TargetEpoch: 3},
+	})
+	reward := func(layer uint32, coinbase string, smesher byte, total uint64) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: total},
+			LayerReward: &pb.Amount{Value: total},
+			Coinbase:    &pb.AccountId{Address: coinbase},
+			Smesher:     &pb.SmesherId{Id: []byte{smesher}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{
+		reward(21, "sm2", 0x53, 100),
+		reward(21, "sm1", 0x51, 50),
+		reward(25, "sm3", 0x54, 50),
+		reward(31, "sm1", 0x51, 1000),
+	})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	concentration, err := svc.GetCoinbaseConcentration(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, &model.CoinbaseConcentration{
+		Epoch:           2,
+		Coinbases:       3,
+		Space:           10 * 1024,
+		Rewards:         200,
+		TopSpaceShare:   6000,
+		TopRewardsShare: 5000,
+		Top: []*model.CoinbaseShare{
+			{Coinbase: "sm1", Smeshers: 2, Units: 6, Space: 6 * 1024, Rewards: 50, SpaceShare: 6000, RewardsShare: 2500},
+		},
+	}, concentration)
+
+	concentration, err = svc.GetCoinbaseConcentration(ctx, 2, 10)
+	require.NoError(t, err)
+	require.Len(t, concentration.Top, 3)
+	require.Equal(t, int64(10000), concentration.TopSpaceShare)
+	require.Equal(t, "sm3", concentration.Top[2].Coinbase)
+}
+
+func TestCommitmentHistogram(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0x01", SmesherId: "0x51", NumUnits: 4, TargetEpoch: 2},
+		{Id: "0x02", SmesherId: "0x52", NumUnits: 5, TargetEpoch: 2},
+		{Id: "0x03", SmesherId: "0x53", NumUnits: 20, TargetEpoch: 2},
+		{Id: "0x04", SmesherId: "0x51", NumUnits: 4, TargetEpoch: 3},
+	})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	histogram, err := svc.GetCommitmentHistogram(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, &model.CommitmentHistogram{
+		Epoch:       2,
+		Activations: 3,
+		Units:       29,
+		Space:       29 * 1024,
+		Buckets: []*model.CommitmentBucket{
+			{From: 4, To: 8, Activations: 2, Units: 9, Space: 9 * 1024},
+			{From: 8, To: 16},
+			{From: 16, To: 32, Activations: 1, Units: 20, Space: 20 * 1024},
+		},
+	}, histogram)
+
+	histogram, err = svc.GetCommitmentHistogram(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, &model.CommitmentHistogram{Epoch: 5, Buckets: []*model.CommitmentBucket{}}, histogram)
+}
+
+func TestRewardLuck(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", NumUnits: 4, Weight: 100, TargetEpoch: 2},
+		{Id: "0xa2", SmesherId: "0x52", NumUnits: 5, Weight: 100, TargetEpoch: 2},
+		{Id: "0xa3", SmesherId: "0x53", NumUnits: 16, Weight: 200, TargetEpoch: 2},
+		{Id: "0xa4", SmesherId: "0x52", NumUnits: 5, Weight: 100, TargetEpoch: 3},
+	})
+	reward := func(layer uint32, smesher byte, total uint64) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: total},
+			LayerReward: &pb.Amount{Value: total},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{smesher}},
+		}
+	}
+	// the smesher 0x54 is rewarded without an activation targeting the epoch
+	s.OnRewards([]*pb.Reward{
+		reward(21, 0x51, 50),
+		reward(22, 0x51, 50),
+		reward(21, 0x53, 100),
+		reward(23, 0x53, 100),
+		reward(24, 0x54, 100),
+		reward(31, 0x52, 500),
+	})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	luck, err := svc.GetRewardLuck(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, &model.RewardLuck{Epoch: 2, Smeshers: 3, Rewards: 400, Buckets: []*model.LuckBucket{
+		{
+			From: 4, To: 8, Smeshers: 2, Rewards: 100, ExpectedRewards: 200, Luck: 5000,
+			MeanLuck: 5000, StdDevLuck: 5000, P10Luck: 0, MedianLuck: 0, P90Luck: 10000,
+			Dry: 1, ExpectedDry: 1, // 2 * e^-1.25
+		},
+		{From: 8, To: 16},
+		{
+			From: 16, To: 32, Smeshers: 1, Rewards: 200, ExpectedRewards: 200, Luck: 10000,
+			MeanLuck: 10000, P10Luck: 10000, MedianLuck: 10000, P90Luck: 10000,
+		},
+	}}, luck)
+
+	luck, err = svc.GetRewardLuck(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, &model.RewardLuck{Epoch: 5, Buckets: []*model.LuckBucket{}}, luck)
+}
+
+func TestSmesherParticipation(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Weight: 300, TargetEpoch: 2},
+		{Id: "0xa2", SmesherId: "0x52", Weight: 700, TargetEpoch: 2},
+		{Id: "0xa3", SmesherId: "0x51", Weight: 500, TargetEpoch: 3},
+	})
+	reward := func(layer uint32, smesher byte, total uint64) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: total},
+			LayerReward: &pb.Amount{Value: total},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{smesher}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{
+		reward(21, 0x51, 100),
+		reward(21, 0x52, 300),
+		reward(22, 0x52, 400),
+		reward(23, 0x52, 200),
+	})
+	s.OnCertificates([]*model.BlockCertificate{
+		{Layer: 21, BlockId: "0xb1", Valid: true, Eligibilities: 10, Signers: []model.CertificateSigner{
+			{Smesher: "0x51", Eligibilities: 2}, {Smesher: "0x52", Eligibilities: 8},
+		}},
+		{Layer: 22, BlockId: "0xb2", Valid: true, Eligibilities: 10, Signers: []model.CertificateSigner{
+			{Smesher: "0x52", Eligibilities: 10},
+		}},
+		{Layer: 22, BlockId: "0xb3", Valid: false, Eligibilities: 10, Signers: []model.CertificateSigner{
+			{Smesher: "0x51", Eligibilities: 10},
+		}},
+	})
+	s.OnBallots([]*model.Ballot{
+		{Id: "0xc1", Layer: 21, Epoch: 2, Smesher: "0x51", Eligibilities: 2, EpochEligibilities: 5},
+		{Id: "0xc2", Layer: 24, Epoch: 2, Smesher: "0x51", Eligibilities: 1},
+		{Id: "0xc3", Layer: 21, Epoch: 2, Smesher: "0x52", Eligibilities: 3, EpochEligibilities: 9},
+		{Id: "0xc4", Layer: 31, Epoch: 3, Smesher: "0x51", Eligibilities: 1, EpochEligibilities: 8},
+	})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	participation, total, err := svc.GetSmesherParticipation(ctx, "0x51", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, participation, 2)
+	require.Equal(t, uint32(3), participation[0].Epoch)
+	require.Equal(t, &model.SmesherParticipation{
+		Epoch:                 2,
+		Weight:                300,
+		WeightShare:           3000,
+		Eligibilities:         2,
+		ExpectedEligibilities: 6,
+		Certificates:          2,
+		Signed:                1,
+		Ballots:               1,
+		ExpectedBallots:       1,
+		Rewards:               100,
+		ExpectedRewards:       300,
+		Voted:                 2,
+		Votes:                 3,
+		EpochEligibilities:    5,
+		VoteRate:              6000,
+		Score:                 3333,
+		TotalWeight:           1000,
+		TotalEligibilities:    20,
+		TotalBallots:          4,
+		TotalRewards:          1000,
+	}, participation[1])
+}
+
+func TestRewardsProjection(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	svc := service.NewService(NewReader(s), time.Second)
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	_, err := svc.GetRewardsProjection(ctx, 2, 2)
+	require.ErrorIs(t, err, service.ErrNotFound)
+
+	for layer := uint32(1); layer <= 22; layer++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", EffectiveNumUnits: 4, TargetEpoch: 2},
+		{Id: "0xa2", SmesherId: "0x52", EffectiveNumUnits: 2, TargetEpoch: 2},
+	})
+	s.UpdateEpochStats(22)
+
+	projection, err := svc.GetRewardsProjection(ctx, 2, 2)
+	require.NoError(t, err)
+	subsidy := economics.IssuedBetween(20, 29, 10)
+	require.Equal(t, &model.RewardsProjection{
+		Epoch:        2,
+		NumUnits:     2,
+		Space:        2 * 1024,
+		NetworkSpace: 8 * 1024,
+		SpaceEpoch:   2,
+		Subsidy:      subsidy,
+		Rewards:      uint64(math.Round(float64(subsidy) / 4)),
+		DailyRewards: uint64(math.Round(float64(subsidy)/4)) * 86400 / 600,
+	}, projection)
+
+	// a future epoch is projected from the last storage of the network
+	projection, err = svc.GetRewardsProjection(ctx, 2, 5)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), projection.SpaceEpoch)
+	require.Equal(t, economics.IssuedBetween(50, 59, 10), projection.Subsidy)
+}
+
+func TestEmptyLayers(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	spend := &pb.Transaction{
+		Id:     []byte{1},
+		Method: core.MethodSpend,
+		Raw:    wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, 1),
+	}
+	for layer := uint32(10); layer <= 19; layer++ {
+		in := &pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		}
+		switch layer {
+		case 12:
+			in.Blocks = []*pb.Block{{Id: blockID(layer, 1), Transactions: []*pb.Transaction{spend}}}
+		case 13:
+			in.Blocks = []*pb.Block{{Id: blockID(layer, 1)}}
+		}
+		s.OnLayer(in)
+	}
+	s.UpdateEpochStats(19)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetEmptyLayers(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Equal(t, []*model.EmptyLayers{{Epoch: 1, Layers: 10, Empty: 8, WithoutTxs: 9}}, series)
+}
+
+func TestNetworkHealth(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	// the clock is in the layer 25, the 6th one of the epoch 2
+	s.OnNetworkInfo("0x01", uint64(time.Now().Unix())-25*60-30, 10, 100, 60, 1024)
+	for layer := uint32(10); layer <= 24; layer++ {
+		in := &pb.Layer{
+			Number: &pb.LayerNumber{Number: layer},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(layer)},
+		}
+		if layer < 23 {
+			in.Blocks = []*pb.Block{{Id: blockID(layer, 1)}}
+		}
+		s.OnLayer(in)
+	}
+	s.OnNodeStatus(10, true, 24, 24, 24)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 1, TargetEpoch: 1},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", NumUnits: 1, TargetEpoch: 1},
+	})
+	s.OnRewards([]*pb.Reward{{
+		Layer:       &pb.LayerNumber{Number: 15},
+		Total:       &pb.Amount{Value: 100},
+		LayerReward: &pb.Amount{Value: 100},
+		Coinbase:    &pb.AccountId{Address: "sm1"},
+		Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+	}})
+	s.UpdateEpochStats(19)
+	s.UpdateEpochStats(24)
+
+	svc := service.NewService(NewReader(s), time.Second)
+	health, err := svc.GetNetworkHealth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(25), health.Layer)
+	require.Equal(t, int32(2), health.Epoch)
+	require.Equal(t, []*model.HealthComponent{
+		{Name: model.HealthSync, Score: 10000, Weight: 40, Value: 1},
+		{Name: model.HealthEmptyLayers, Score: 6000, Weight: 25, Value: 4000}, // 2 of the 5 layers
+		{Name: model.HealthParticipation, Score: 5000, Weight: 20, Value: 5000},
+		{Name: model.HealthBeacon, Weight: 15},
+	}, health.Components)
+	require.Equal(t, int64(6500), health.Score)
+	require.Equal(t, model.HealthStatusDegraded, health.Status)
+
+	s.OnBeacon(2, "0x01020304")
+	epoch, err := svc.GetEpoch(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, "0x01020304", epoch.Beacon)
+	s.UpdateEpochStats(24) // the stats keep the beacon
+
+	svc = service.NewService(NewReader(s), time.Second)
+	health, err = svc.GetNetworkHealth(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(8000), health.Score)
+	require.Equal(t, int64(1), health.Components[3].Value)
+
+	s.OnNodeStatus(10, false, 24, 30, 24)
+	svc = service.NewService(NewReader(s), time.Second)
+	health, err = svc.GetNetworkHealth(ctx)
+	require.NoError(t, err)
+	require.Zero(t, health.Components[0].Score)
+	require.Equal(t, model.HealthStatusUnhealthy, health.Status)
+}
+
+func TestBlockFill(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	raw := wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, 1)
+	spend := &pb.Transaction{Id: []byte{1}, Method: core.MethodSpend, Raw: raw}
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 12},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{12},
+		Blocks: []*pb.Block{
+			{Id: blockID(12, 1), Transactions: []*pb.Transaction{spend}},
+			{Id: blockID(12, 2)},
+		},
+	})
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetBlockFill(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Len(t, series, 1)
+	fill := series[0]
+	require.Equal(t, uint32(1), fill.Epoch)
+	require.Equal(t, int64(2), fill.Blocks)
+	require.Equal(t, int64(1), fill.Txs)
+	require.Equal(t, int64(len(raw)), fill.Size)
+	require.Equal(t, 0.5, fill.AvgTxs)
+	require.Equal(t, int64(1), fill.MaxTxs)
+	require.Equal(t, int64(len(raw)), fill.MaxSize)
+	require.Equal(t, &model.BucketCount{From: 0, Blocks: 1}, fill.TxsDistribution[0])
+	require.Equal(t, &model.BucketCount{From: 1, Blocks: 1}, fill.TxsDistribution[1])
+	require.Equal(t, &model.BucketCount{From: 0, Blocks: 1}, fill.SizeDistribution[0])
+	require.Equal(t, &model.BucketCount{From: 1, Blocks: 1}, fill.SizeDistribution[1])
+}
+
+func TestRollups(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	// the layers from 5 are in the second day
+	s.OnNetworkInfo("0x01", 86400-300, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	raw := wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, 1)
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 3},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{3},
+		Blocks: []*pb.Block{{Id: blockID(3, 1), Transactions: []*pb.Transaction{{Id: []byte{1}, Method: core.MethodSpend, Raw: raw}}}},
+	})
+	s.OnAccounts([]*types.Account{{Address: types.GenerateAddress([]byte{4}), Balance: 100, Layer: 2}})
+	s.OnAccounts([]*types.Account{{Address: types.GenerateAddress([]byte{5}), Balance: 100, Layer: 8}})
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 1},
+		{Id: "0xa2", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 2},
+		{Id: "0xa3", SmesherId: "0x52", Coinbase: "sm2", NumUnits: 2, TargetEpoch: 2},
+	})
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 90},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(3), reward(11), reward(12)})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	accounts, _, err := svc.GetDailyAccounts(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2) // latest first
+
+	days, err := svc.GetRollups(ctx, model.PeriodDay, 0, 2*86400)
+	require.NoError(t, err)
+	require.Equal(t, []*model.Rollup{
+		{Start: 0, Txs: 1, Volume: 10, Rewards: 100, NewAccounts: accounts[1].New},
+		{Start: 86400, Rewards: 200, NewAccounts: accounts[0].New, NewSmeshers: 2},
+	}, days)
+
+	days, err = svc.GetRollups(ctx, model.PeriodDay, 86400, 2*86400)
+	require.NoError(t, err)
+	require.Len(t, days, 1)
+	require.Equal(t, uint32(86400), days[0].Start)
+
+	// the first week of the unix epoch ends on sunday 4 january 1970
+	weeks, err := svc.GetRollups(ctx, model.PeriodWeek, 0, 2*86400)
+	require.NoError(t, err)
+	require.Equal(t, []*model.Rollup{
+		{Start: 0, Txs: 1, Volume: 10, Rewards: 300, NewAccounts: accounts[0].New + accounts[1].New, NewSmeshers: 2},
+	}, weeks)
+	require.Equal(t, uint32(4*86400), model.PeriodStart(model.PeriodWeek, 10*86400))
+}
+
+func TestPrices(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	// the layer 3 is in the first day, the layers 11 and 12 in the second one
+	s.OnNetworkInfo("0x01", 86400-300, 10, 100, 60, 1024)
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 2 * model.SmidgePerSmesh},
+			LayerReward: &pb.Amount{Value: 2 * model.SmidgePerSmesh},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(3), reward(11), reward(12)})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	days, err := svc.GetRollups(ctx, model.PeriodDay, 0, 2*86400)
+	require.NoError(t, err)
+	require.Len(t, days, 2)
+	require.Zero(t, days[0].RewardsUSD) // without a price feed
+
+	require.Error(t, s.UpsertPrice(ctx, &model.Price{Timestamp: 100, USD: -1}))
+	for _, price := range []*model.Price{{Timestamp: 100, USD: 1}, {Timestamp: 100, USD: 2}, {Timestamp: 200, USD: 4}, {Timestamp: 84000, USD: 6}} {
+		require.NoError(t, s.UpsertPrice(ctx, price))
+	}
+	prices, err := svc.GetPrices(ctx, 0, 200)
+	require.NoError(t, err)
+	require.Equal(t, []*model.Price{{Timestamp: 100, USD: 2}, {Timestamp: 200, USD: 4}}, prices)
+
+	// the second day has no price, the last one of the first day is recent enough
+	days, err = svc.GetRollups(ctx, model.PeriodDay, 0, 2*86400)
+	require.NoError(t, err)
+	require.Equal(t, 2*4.0, days[0].RewardsUSD)
+	require.Equal(t, 4*6.0, days[1].RewardsUSD)
+
+	// a day without a recent price has no fiat value
+	require.Zero(t, model.Prices(prices).Mean(86400, 2*86400))
+}
+
+func TestInclusionTime(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	start := uint32(1000 + 12*60)
+	received := make(map[string]uint32)
+	var txs []*pb.Transaction
+	// the last transaction is received after the start of its layer, the one before never
+	for i, delay := range []int64{30, 10, 100, 20, 0, -5} {
+		tx := &pb.Transaction{
+			Id:     []byte{byte(i + 1)},
+			Method: core.MethodSpend,
+			Raw:    wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{1}), 1, uint64(i)),
+		}
+		txs = append(txs, tx)
+		if delay != 0 {
+			received[utils.BytesToHex(tx.Id)] = uint32(int64(start) - delay)
+		}
+	}
+	s.OnTransactionsReceived(received)
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 12},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{12},
+		Blocks: []*pb.Block{{Id: blockID(12, 1), Transactions: txs}},
+	})
+	s.UpdateEpochStats(0)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetInclusionTime(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.InclusionTime{
+		{Epoch: 1, Txs: 4, Mean: 40, Median: 20, P90: 100, P99: 100},
+		{Epoch: 0},
+	}, series)
+}
+
+func TestBallotParticipation(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", TargetEpoch: 1, NumUnits: 1},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", TargetEpoch: 1, NumUnits: 1},
+		{Id: "0xa3", SmesherId: "0x53", Coinbase: "sm3", TargetEpoch: 1, NumUnits: 1},
+	})
+	s.OnBallots([]*model.Ballot{
+		{Id: "0xc1", Layer: 10, Epoch: 1, Smesher: "0x51", Eligibilities: 2, EpochEligibilities: 4},
+		{Id: "0xc2", Layer: 11, Epoch: 1, Smesher: "0x52", Eligibilities: 2, EpochEligibilities: 2},
+		{Id: "0xc3", Layer: 12, Epoch: 1, Smesher: "0x51", Eligibilities: 1},
+		{Id: "0xc4", Layer: 20, Epoch: 2, Smesher: "0x53", Eligibilities: 1, EpochEligibilities: 1},
+	})
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 12},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{12},
+	})
+	s.UpdateEpochStats(0)
+
+	series, total, err := service.NewService(NewReader(s), time.Second).GetBallotParticipation(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.BallotParticipation{
+		{Epoch: 1, Eligible: 3, Voters: 2, Rate: 6667, Ballots: 3, Eligibilities: 6, Votes: 5, VoteRate: 8333},
+		{Epoch: 0},
+	}, series)
+}
+
+func TestMalfeasanceStats(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", TargetEpoch: 1, EffectiveNumUnits: 4},
+		{Id: "0xa2", SmesherId: "0x51", TargetEpoch: 2, EffectiveNumUnits: 4},
+		{Id: "0xa3", SmesherId: "0x52", TargetEpoch: 3, EffectiveNumUnits: 2},
+		{Id: "0xa4", SmesherId: "0x53", TargetEpoch: 3, EffectiveNumUnits: 8},
+	})
+	proof := func(smesher byte, layer uint32, kind pb.MalfeasanceProof_MalfeasanceType) *pb.MalfeasanceProof {
+		return &pb.MalfeasanceProof{
+			SmesherId: &pb.SmesherId{Id: []byte{smesher}},
+			Layer:     &pb.LayerNumber{Number: layer},
+			Kind:      kind,
+		}
+	}
+	s.OnMalfeasanceProof(proof(0x51, 12, pb.MalfeasanceProof_MALFEASANCE_ATX))
+	// a smesher proven malicious again is removed once
+	s.OnMalfeasanceProof(proof(0x51, 31, pb.MalfeasanceProof_MALFEASANCE_BALLOT))
+	s.OnMalfeasanceProof(proof(0x52, 32, pb.MalfeasanceProof_MALFEASANCE_HARE))
+	s.OnMalfeasanceProof(proof(0x53, 35, pb.MalfeasanceProof_MALFEASANCE_BALLOT))
+
+	svc := service.NewService(NewReader(s), time.Second)
+	series, total, err := svc.GetMalfeasanceStats(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Equal(t, []*model.MalfeasanceStats{
+		{Epoch: 3, Proofs: 3, Kinds: map[string]int64{"MALFEASANCE_BALLOT": 2, "MALFEASANCE_HARE": 1}, Smeshers: 2, Space: 10 * 1024},
+		{Epoch: 1, Proofs: 1, Kinds: map[string]int64{"MALFEASANCE_ATX": 1}, Smeshers: 1, Space: 4 * 1024},
+	}, series)
+
+	series, total, err = svc.GetMalfeasanceStats(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, series, 1)
+	require.Equal(t, uint32(1), series[0].Epoch)
+}
+
+func TestTotals(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for i := uint32(1); i <= 12; i++ {
+		s.OnLayer(&pb.Layer{
+			Number: &pb.LayerNumber{Number: i},
+			Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+			Hash:   []byte{byte(i)},
+		})
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 1},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", NumUnits: 3, TargetEpoch: 1},
+		// the activations of the next epoch do not count in the current space
+		{Id: "0xa3", SmesherId: "0x53", Coinbase: "sm3", NumUnits: 5, TargetEpoch: 2},
+	})
+	reward := func(layer uint32, total uint64) *pb.Reward {
+		return &pb.Reward{
+			Layer:    &pb.LayerNumber{Number: layer},
+			Total:    &pb.Amount{Value: total},
+			Coinbase: &pb.AccountId{Address: "sm1"},
+			Smesher:  &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward(11, 100), reward(12, 50)})
+
+	totals, err := service.NewService(NewReader(s), time.Second).GetTotals(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), totals.Smeshers)
+	require.Equal(t, int64(2), totals.RewardsCount)
+	require.Equal(t, int64(150), totals.Rewards)
+	require.Equal(t, int64(5*1024), totals.Space)
+	require.Equal(t, uint32(1), totals.SpaceEpoch)
+	require.Equal(t, uint32(12), totals.LastLayer)
+	require.NotZero(t, totals.Updated)
+	require.LessOrEqual(t, totals.Age, uint32(1))
+}
+
+func TestGeoHeatmap(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", TargetEpoch: 1, NumUnits: 3},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", TargetEpoch: 1, NumUnits: 1},
+		{Id: "0xa3", SmesherId: "0x53", Coinbase: "sm3", TargetEpoch: 1, NumUnits: 5},
+		{Id: "0xa4", SmesherId: "0x54", Coinbase: "sm4", TargetEpoch: 1, NumUnits: 1},
+		{Id: "0xa5", SmesherId: "0x55", Coinbase: "sm5", TargetEpoch: 1, NumUnits: 10},
+	})
+	require.NoError(t, s.UpsertSmesherLocations(ctx, []*model.SmesherLocation{
+		{Smesher: "0x51", Geo: model.Geo{Coordinates: [2]float64{-74, 40.7}, Country: "US", Region: "New York"}},
+		{Smesher: "0x52", Geo: model.Geo{Coordinates: [2]float64{-122.4, 37.8}, Country: "US", Region: "California"}},
+		{Smesher: "0x53", Geo: model.Geo{Coordinates: [2]float64{13.4, 52.5}, Country: "DE"}},
+		{Smesher: "0x54", Geo: model.Geo{Coordinates: [2]float64{0, 0}}},
+	}))
+	require.Error(t, s.UpsertSmesherLocations(ctx, []*model.SmesherLocation{
+		{Smesher: "0x55", Geo: model.Geo{Country: "usa"}},
+	}))
+
+	heatmap, err := service.NewService(NewReader(s), time.Second).GetGeoHeatmap(ctx)
+	require.NoError(t, err)
+	require.NotZero(t, heatmap.Updated)
+	require.Equal(t, int64(4), heatmap.Smeshers)
+	require.Equal(t, uint64(10*1024), heatmap.Space)
+	require.Equal(t, int64(1), heatmap.Unknown)
+	require.Equal(t, []*model.GeoBucket{
+		{Country: "DE", Smeshers: 1, Space: 5 * 1024, Share: 5000},
+		{Country: "US", Smeshers: 2, Space: 4 * 1024, Share: 4000},
+	}, heatmap.Countries)
+	require.Equal(t, []*model.GeoBucket{
+		{Country: "US", Region: "New York", Smeshers: 1, Space: 3 * 1024, Share: 3000},
+		{Country: "US", Region: "California", Smeshers: 1, Space: 1024, Share: 1000},
+	}, heatmap.Regions)
+}
+
+func TestWatchedAccounts(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+	s.SetWatchedAccounts([]string{"sm1"})
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	raw := wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, 1)
+	for i := uint32(1); i <= 12; i++ {
+		layer := &pb.Layer{Number: &pb.LayerNumber{Number: i}, Status: pb.Layer_LAYER_STATUS_CONFIRMED}
+		if i == 11 {
+			// a transaction between unwatched accounts
+			layer.Blocks = []*pb.Block{{Id: blockID(11, 1), Transactions: []*pb.Transaction{
+				{Id: []byte{11}, Method: core.MethodSpend, MaxGas: 100, Raw: raw},
+			}}}
+		}
+		s.OnLayer(layer)
+	}
+	s.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 1, Received: 10},
+		{Id: "0xa2", SmesherId: "0x52", Coinbase: "sm2", NumUnits: 3, TargetEpoch: 1, Received: 10},
+	})
+	reward := func(coinbase string, smesher byte) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: 11},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 90},
+			Coinbase:    &pb.AccountId{Address: coinbase},
+			Smesher:     &pb.SmesherId{Id: []byte{smesher}},
+		}
+	}
+	s.OnRewards([]*pb.Reward{reward("sm1", 0x51), reward("sm2", 0x52)})
+	s.UpdateEpochStats(12)
+
+	svc := service.NewService(NewReader(s), time.Second)
+	// the layers are stored whatever they touch
+	_, total, err := svc.GetLayers(ctx, 1, 20)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), total)
+
+	_, err = svc.GetSmesher(ctx, "0x52")
+	require.ErrorIs(t, err, service.ErrNotFound)
+	_, total, err = svc.GetRewards(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	_, total, err = svc.GetTransactions(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	// the epoch stats and the totals count the whole chain
+	epoch, err := svc.GetEpoch(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), epoch.Stats.Current.Smeshers)
+	require.Equal(t, int64((2+3)*1024), epoch.Stats.Current.Security)
+	require.Equal(t, int64(200), epoch.Stats.Current.Rewards)
+	require.Equal(t, int64(2), epoch.Stats.Current.RewardsNumber)
+	require.Equal(t, int64(1), epoch.Stats.Current.Transactions)
+	require.Equal(t, int64(2), epoch.Stats.Current.RewardedSmeshers)
+
+	totals, err := svc.GetTotals(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), totals.Txs)
+	require.Equal(t, int64(2), totals.Smeshers)
+	require.Equal(t, int64(200), totals.Rewards)
+	require.Equal(t, int64((2+3)*1024), totals.EpochSpace["1"])
+
+	// disabling watch mode keeps the chain tables, a restarted reader reads the regular tables
+	s.SetWatchedAccounts(nil)
+	chain, err := s.Engine().Count(ctx, "chain_rewards", &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), chain)
+	totals, err = service.NewService(NewReader(s), time.Second).GetTotals(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), totals.Smeshers)
+	require.Equal(t, int64(100), totals.Rewards)
+}
+
+func TestRedecodeTransactions(t *testing.T) {
+	ctx := context.Background()
+	e := newEngine()
+	s := docstore.New(e)
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	receiver := types.GenerateAddress([]byte{3})
+	raw := wallet.Spend(signer.PrivateKey(), receiver, 10, 1, sdk.WithGasPrice(2))
+	s.OnLayer(&pb.Layer{
+		Number: &pb.LayerNumber{Number: 12},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{12},
+		Blocks: []*pb.Block{{Id: blockID(12, 1), Transactions: []*pb.Transaction{
+			{Id: []byte{12}, Method: core.MethodSpend, MaxGas: 100, Raw: raw},
+		}}},
+	})
+	id := utils.BytesToHex([]byte{12})
+
+	// decoded fields stored by an older parser
+	require.NoError(t, e.Update(ctx, "txs", id, bson.D{
+		{Key: "gasPrice", Value: uint64(0)},
+		{Key: "fee", Value: uint64(0)},
+		{Key: "amount", Value: uint64(0)},
+		{Key: "receiver", Value: ""},
+	}))
+	require.NoError(t, s.RedecodeTransactions(ctx))
+
+	docs, err := e.Find(ctx, "txs", &bson.D{{Key: "id", Value: id}})
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	var tx model.Transaction
+	require.NoError(t, bson.UnmarshalExtJSON(docs[0], false, &tx))
+	require.Equal(t, uint64(2), tx.GasPrice)
+	require.Equal(t, uint64(200), tx.Fee)
+	require.Equal(t, uint64(10), tx.Amount)
+	require.Equal(t, receiver.String(), tx.Receiver)
+	require.Equal(t, uint64(100), tx.MaxGas)
+}
+
+func TestLayerHashCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	layer, err := s.GetLayerHashCheckpoint(ctx)
+	require.NoError(t, err)
+	require.Zero(t, layer)
+
+	require.NoError(t, s.SetLayerHashCheckpoint(ctx, 12))
+	// the network info updates keep the checkpoint
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	layer, err = s.GetLayerHashCheckpoint(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(12), layer)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	ctx := context.Background()
+	server := cachetest.NewServer(t)
+	c, err := cache.New(server.URL(), time.Minute)
+	require.NoError(t, err)
+	defer c.Close()
+
+	s := New()
+	defer s.Close()
+	s.SetCache(c)
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	// the API servers keep no copy of their own, every read goes through the shared cache
+	svc := service.NewService(NewReader(s), -time.Second)
+	svc.SetCache(c)
+
+	info, err := svc.GetNetworkInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", info.GenesisId)
+	require.Equal(t, 1, server.Keys())
+
+	// the cached document is served
+	require.NoError(t, c.Set(ctx, cache.KeyNetworkInfo, &model.NetworkInfo{GenesisId: "0x02"}))
+	info, err = svc.GetNetworkInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x02", info.GenesisId)
+
+	// the collector invalidates the document it writes
+	s.OnNodeStatus(5, true, 7, 8, 7)
+	require.Zero(t, server.Keys())
+	info, err = svc.GetNetworkInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", info.GenesisId)
+	require.Equal(t, uint32(7), info.SyncedLayer)
+}
+
+// failingCertificates fails the reads of the block certificates.
+type failingCertificates struct {
+	*docstore.Reader
+}
+
+func (failingCertificates) GetBlockCertificate(context.Context, string) (*model.BlockCertificate, error) {
+	return nil, fmt.Errorf("certificates unavailable")
+}
+
+func TestBlockCertificate(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	defer s.Close()
+
+	s.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	for _, id := range []string{"0xb1", "0xb2"} {
+		require.NoError(t, s.UpsertBlock(ctx, &model.Block{Id: id, Layer: 12, Epoch: 1}))
+	}
+	cert := &model.BlockCertificate{
+		Layer:         12,
+		BlockId:       "0xb1",
+		Valid:         true,
+		Eligibilities: 3,
+		Signers:       []model.CertificateSigner{{Smesher: "0x51", Eligibilities: 3}},
+	}
+	s.OnCertificates([]*model.BlockCertificate{cert})
+
+	svc := service.NewService(NewReader(s), time.Second)
+	block, err := svc.GetBlock(ctx, "0xb1")
+	require.NoError(t, err)
+	require.Equal(t, cert, block.Certificate)
+	// the certificate is optional, missing or unreadable the block is returned without it
+	block, err = svc.GetBlock(ctx, "0xb2")
+	require.NoError(t, err)
+	require.Nil(t, block.Certificate)
+	svc = service.NewService(failingCertificates{NewReader(s)}, time.Second)
+	block, err = svc.GetBlock(ctx, "0xb1")
+	require.NoError(t, err)
+	require.Equal(t, "0xb1", block.Id)
+	require.Nil(t, block.Certificate)
+}
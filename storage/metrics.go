@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+var (
+	metricOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "explorer_storage_operation_duration_seconds",
+		Help:    "Duration of the storage operations by collection and operation type",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 15),
+	}, []string{"collection", "operation"})
+	metricOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_storage_operation_errors",
+		Help: "Number of failed storage operations by collection and operation type",
+	}, []string{"collection", "operation"})
+
+	metricCollectionDocuments = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_storage_collection_documents",
+		Help: "Number of documents by collection",
+	}, []string{"collection"})
+	metricCollectionStorageSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_storage_collection_storage_bytes",
+		Help: "Storage allocated to the documents by collection",
+	}, []string{"collection"})
+	metricCollectionIndexSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_storage_collection_index_bytes",
+		Help: "Storage allocated to the indexes by collection",
+	}, []string{"collection"})
+	metricCollectionOldest = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_storage_collection_oldest_timestamp_seconds",
+		Help: "Insertion time of the oldest document by collection, if recorded by the backend",
+	}, []string{"collection"})
+	metricCollectionNewest = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "explorer_storage_collection_newest_timestamp_seconds",
+		Help: "Insertion time of the newest document by collection, if recorded by the backend",
+	}, []string{"collection"})
+)
+
+// StorageStatsInterval is the period of the refresh of the collection size metrics.
+const StorageStatsInterval = 5 * time.Minute
+
+// Operation types of the storage metrics.
+const (
+	OperationFind      = "find"
+	OperationCount     = "count"
+	OperationInsert    = "insert"
+	OperationUpdate    = "update"
+	OperationDelete    = "delete"
+	OperationAggregate = "aggregate"
+)
+
+// commandOperations maps the database commands to the operation types, other commands (handshakes,
+// index and transaction management) are not measured.
+var commandOperations = map[string]string{
+	"find":          OperationFind,
+	"getMore":       OperationFind,
+	"count":         OperationCount,
+	"distinct":      OperationCount,
+	"insert":        OperationInsert,
+	"update":        OperationUpdate,
+	"findAndModify": OperationUpdate,
+	"delete":        OperationDelete,
+	"aggregate":     OperationAggregate,
+}
+
+// ObserveOperation records the duration of a storage operation started at `start`, and its failure
+// if err is not nil.
+func ObserveOperation(collection, operation string, start time.Time, err error) {
+	observeOperation(collection, operation, time.Since(start), err != nil)
+}
+
+func observeOperation(collection, operation string, d time.Duration, failed bool) {
+	metricOperationDuration.WithLabelValues(collection, operation).Observe(d.Seconds())
+	if failed {
+		metricOperationErrors.WithLabelValues(collection, operation).Inc()
+	}
+}
+
+// RecordStorageStats sets the collection size metrics. The timestamps are not set if the backend
+// does not record them.
+func RecordStorageStats(stats []*model.CollectionStats) {
+	for _, c := range stats {
+		metricCollectionDocuments.WithLabelValues(c.Collection).Set(float64(c.Documents))
+		metricCollectionStorageSize.WithLabelValues(c.Collection).Set(float64(c.StorageSize))
+		metricCollectionIndexSize.WithLabelValues(c.Collection).Set(float64(c.IndexSize))
+		if c.Oldest > 0 {
+			metricCollectionOldest.WithLabelValues(c.Collection).Set(float64(c.Oldest))
+			metricCollectionNewest.WithLabelValues(c.Collection).Set(float64(c.Newest))
+		}
+	}
+}
+
+// NewCommandMonitor returns a mongo command monitor recording the storage operation metrics of a
+// client.
+func NewCommandMonitor() *event.CommandMonitor {
+	type command struct {
+		collection, operation string
+	}
+	var started sync.Map
+	finished := func(e event.CommandFinishedEvent, failed bool) {
+		if c, ok := started.LoadAndDelete(e.RequestID); ok {
+			c := c.(command)
+			observeOperation(c.collection, c.operation, time.Duration(e.DurationNanos), failed)
+		}
+	}
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			operation, ok := commandOperations[e.CommandName]
+			if !ok {
+				return
+			}
+			if collection := commandCollection(e); collection != "" {
+				started.Store(e.RequestID, command{collection: collection, operation: operation})
+			}
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finished(e.CommandFinishedEvent, false)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finished(e.CommandFinishedEvent, true)
+		},
+	}
+}
+
+// commandCollection returns the collection of the command, it is the value of the command name
+// field except for getMore.
+func commandCollection(e *event.CommandStartedEvent) string {
+	field := e.CommandName
+	if field == "getMore" {
+		field = "collection"
+	}
+	collection, _ := e.Command.Lookup(field).StringValueOK()
+	return collection
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestCommandMonitor(t *testing.T) {
+	monitor := NewCommandMonitor()
+	start := func(id int64, name string, command bson.D) {
+		raw, err := bson.Marshal(command)
+		require.NoError(t, err)
+		monitor.Started(context.Background(), &event.CommandStartedEvent{CommandName: name, RequestID: id, Command: raw})
+	}
+	finished := func(id int64) event.CommandFinishedEvent {
+		return event.CommandFinishedEvent{RequestID: id, DurationNanos: 1e6}
+	}
+
+	start(1, "find", bson.D{{Key: "find", Value: "metrics_test"}})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: finished(1)})
+	start(2, "getMore", bson.D{{Key: "getMore", Value: int64(42)}, {Key: "collection", Value: "metrics_test"}})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: finished(2)})
+	start(3, "update", bson.D{{Key: "update", Value: "metrics_test"}})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{CommandFinishedEvent: finished(3)})
+	start(4, "ping", bson.D{{Key: "ping", Value: 1}})
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{CommandFinishedEvent: finished(4)})
+
+	samples := func(operation string) uint64 {
+		var m dto.Metric
+		require.NoError(t, metricOperationDuration.WithLabelValues("metrics_test", operation).(prometheus.Histogram).Write(&m))
+		return m.GetHistogram().GetSampleCount()
+	}
+	require.EqualValues(t, 2, samples(OperationFind))
+	require.EqualValues(t, 1, samples(OperationUpdate))
+	require.EqualValues(t, 1, testutil.ToFloat64(metricOperationErrors.WithLabelValues("metrics_test", OperationUpdate)))
+	require.EqualValues(t, 0, testutil.ToFloat64(metricOperationErrors.WithLabelValues("metrics_test", OperationFind)))
+}
+
+func TestRecordStorageStats(t *testing.T) {
+	RecordStorageStats([]*model.CollectionStats{
+		{Collection: "metrics_test", Documents: 10, StorageSize: 4096, IndexSize: 1024, Oldest: 100, Newest: 200},
+		{Collection: "metrics_test_untimed", Documents: 5},
+	})
+	require.EqualValues(t, 10, testutil.ToFloat64(metricCollectionDocuments.WithLabelValues("metrics_test")))
+	require.EqualValues(t, 4096, testutil.ToFloat64(metricCollectionStorageSize.WithLabelValues("metrics_test")))
+	require.EqualValues(t, 1024, testutil.ToFloat64(metricCollectionIndexSize.WithLabelValues("metrics_test")))
+	require.EqualValues(t, 100, testutil.ToFloat64(metricCollectionOldest.WithLabelValues("metrics_test")))
+	require.EqualValues(t, 200, testutil.ToFloat64(metricCollectionNewest.WithLabelValues("metrics_test")))
+	require.EqualValues(t, 5, testutil.ToFloat64(metricCollectionDocuments.WithLabelValues("metrics_test_untimed")))
+	// the timestamps of the untimed collection are not set
+	require.Equal(t, 1, testutil.CollectAndCount(metricCollectionOldest))
+}
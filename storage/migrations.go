@@ -0,0 +1,407 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// Migration is a versioned change of the database: index creation or rewrite of existing documents.
+// Migrations must be idempotent, a migration interrupted before it is recorded runs again.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, s *Storage) error
+}
+
+// AppliedMigration is the record of an applied migration in the `migrations` collection.
+type AppliedMigration struct {
+	Version     int    `bson:"version"`
+	Description string `bson:"description"`
+	Applied     int64  `bson:"applied"`
+}
+
+// migrations are applied in order. Append new migrations with the next version, never change
+// or reorder the applied ones.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			for _, init := range []func(context.Context) error{
+				s.InitAccountsStorage,
+				s.InitActivationsStorage,
+				s.InitBlocksStorage,
+				s.InitEpochsStorage,
+				s.InitLayersStorage,
+				s.InitRewardsStorage,
+				s.InitSmeshersStorage,
+				s.InitTransactionsStorage,
+				s.InitCertificatesStorage,
+			} {
+				if err := init(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "index malfeasance proofs by smesher and layer",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.createIndexes(ctx, "malfeasance_proofs")
+		},
+	},
+	{
+		Version:     3,
+		Description: "backfill fees of layers stored before fee accounting",
+		Up: func(ctx context.Context, s *Storage) error {
+			cursor, err := s.db.Collection("layers").Find(ctx,
+				bson.D{{Key: "feescollected", Value: bson.D{{Key: "$exists", Value: false}}}},
+				options.Find().SetProjection(bson.D{{Key: "number", Value: 1}}))
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				s.updateLayerSummary(utils.GetAsUInt32(cursor.Current.Lookup("number")))
+			}
+			return cursor.Err()
+		},
+	},
+	{
+		Version:     4,
+		Description: "build materialized stats collections",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initStatsStorage(ctx); err != nil {
+				return err
+			}
+			return s.rebuildStats(ctx)
+		},
+	},
+	{
+		Version:     5,
+		Description: "create compound indexes of the API list queries",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.createQueryIndexes(ctx, queryCollections()...)
+		},
+	},
+	{
+		Version:     6,
+		Description: "use shard key compatible unique indexes and shard the large collections",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.shardKeyIndexes(ctx); err != nil {
+				return err
+			}
+			return s.shardCollections(ctx)
+		},
+	},
+	{
+		Version:     7,
+		Description: "create balance changes collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initBalanceChangesStorage(ctx)
+		},
+	},
+	{
+		Version:     8,
+		Description: "create smesher history collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initSmesherHistoryStorage(ctx)
+		},
+	},
+	{
+		Version:     9,
+		Description: "index accounts by template",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.createQueryIndexes(ctx, queryCollections()...)
+		},
+	},
+	{
+		Version:     10,
+		Description: "key coinbases by coinbase and smesher with their epochs",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.coinbaseEpochIndexes(ctx)
+		},
+	},
+	{
+		Version:     11,
+		Description: "count the rewards of the smeshers and coinbases",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.rebuildRewardCounters(ctx); err != nil {
+				return err
+			}
+			return s.createQueryIndexes(ctx, queryCollections()...)
+		},
+	},
+	{
+		Version:     12,
+		Description: "index transactions by method and template",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.createQueryIndexes(ctx, queryCollections()...)
+		},
+	},
+	{
+		Version:     13,
+		Description: "create archive collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initArchiveStorage(ctx)
+		},
+	},
+	{
+		Version:     14,
+		Description: "validate documents with json schemas",
+		Up: func(ctx context.Context, s *Storage) error {
+			return applyValidators(ctx, s.db)
+		},
+	},
+	{
+		Version:     15,
+		Description: "create epoch stats history collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initEpochStatsStorage(ctx)
+		},
+	},
+	{
+		Version:     16,
+		Description: "create compressed archive collections of the tiered layers, transactions and blocks",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initTieringStorage(ctx)
+		},
+	},
+	{
+		Version:     17,
+		Description: "create labels collection text index",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initLabelsStorage(ctx)
+		},
+	},
+	{
+		Version:     18,
+		Description: "index smeshers by location",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.createIndexes(ctx, "smeshers")
+		},
+	},
+	{
+		Version:     19,
+		Description: "create account snapshots collection indexes",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initAccountSnapshotsStorage(ctx)
+		},
+	},
+	{
+		Version:     20,
+		Description: "copy the smesher names to their activations",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.backfillSmesherNames(ctx)
+		},
+	},
+	{
+		Version:     21,
+		Description: "link the rewards to the activations which earned them",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.createQueryIndexes(ctx, "rewards", "coinbases"); err != nil {
+				return err
+			}
+			return s.backfillRewardActivations(ctx)
+		},
+	},
+	{
+		Version:     22,
+		Description: "backfill the rewards sum of the layers",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.backfillLayerRewards(ctx)
+		},
+	},
+	{
+		Version:     23,
+		Description: "decode the vesting schedules of the vaults",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initVaultsStorage(ctx); err != nil {
+				return err
+			}
+			return s.backfillVaults(ctx)
+		},
+	},
+	{
+		Version:     24,
+		Description: "build the daily accounts stats",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initStatsStorage(ctx); err != nil {
+				return err
+			}
+			info, err := s.GetNetworkInfo(ctx)
+			if err != nil || info.EpochNumLayers == 0 {
+				// the accounts are only collected after the network info
+				return nil
+			}
+			return s.rebuildDailyAccounts(ctx, info)
+		},
+	},
+	{
+		Version:     25,
+		Description: "build the transaction types stats",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initStatsStorage(ctx); err != nil {
+				return err
+			}
+			info, err := s.GetNetworkInfo(ctx)
+			if err != nil || info.EpochNumLayers == 0 {
+				// the transactions are only collected after the network info
+				return nil
+			}
+			return s.rebuildTransactionTypes(ctx, info)
+		},
+	},
+	{
+		Version:     26,
+		Description: "backfill fee stats of layers stored before they were computed",
+		Up: func(ctx context.Context, s *Storage) error {
+			cursor, err := s.db.Collection("layers").Find(ctx,
+				bson.D{{Key: "feemedian", Value: bson.D{{Key: "$exists", Value: false}}}},
+				options.Find().SetProjection(bson.D{{Key: "number", Value: 1}}))
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				s.updateLayerSummary(utils.GetAsUInt32(cursor.Current.Lookup("number")))
+			}
+			return cursor.Err()
+		},
+	},
+	{
+		Version:     27,
+		Description: "build the blocks stats",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initStatsStorage(ctx); err != nil {
+				return err
+			}
+			return s.rebuildBlockStats(ctx)
+		},
+	},
+	{
+		Version:     28,
+		Description: "create the prices collection",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initPricesStorage(ctx)
+		},
+	},
+	{
+		Version:     29,
+		Description: "create the ballots collection",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initBallotsStorage(ctx)
+		},
+	},
+	{
+		Version:     30,
+		Description: "build the global totals",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.initStatsStorage(ctx); err != nil {
+				return err
+			}
+			return s.rebuildTotals(ctx)
+		},
+	},
+	{
+		Version:     31,
+		Description: "count the orphaned blocks and transactions in the global totals",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.rebuildTotals(ctx)
+		},
+	},
+	{
+		Version:     32,
+		Description: "backfill the templates of the transactions",
+		Up: func(ctx context.Context, s *Storage) error {
+			if err := s.backfillTransactionTemplates(ctx); err != nil {
+				return err
+			}
+			// the vaults of the spawn transactions were not found without their template
+			return s.backfillVaults(ctx)
+		},
+	},
+	{
+		Version:     33,
+		Description: "create the chain collections of watch mode",
+		Up: func(ctx context.Context, s *Storage) error {
+			return s.initChainStorage(ctx)
+		},
+	},
+}
+
+// AppliedMigrations returns the migrations recorded in the database, by version.
+func (s *Storage) AppliedMigrations(parent context.Context) (map[int]AppliedMigration, error) {
+	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	defer cancel()
+	cursor, err := s.db.Collection("migrations").Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var records []AppliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// PendingMigrations returns the migrations not applied yet, in order.
+func (s *Storage) PendingMigrations(parent context.Context) ([]Migration, error) {
+	applied, err := s.AppliedMigrations(parent)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies the pending migrations in order and records each applied version, then ensures
+// the managed indexes, see EnsureIndexes. It stops at the first failing migration.
+func (s *Storage) Migrate(parent context.Context) error {
+	if err := s.createIndexes(parent, "migrations"); err != nil {
+		return err
+	}
+
+	pending, err := s.PendingMigrations(parent)
+	if err != nil {
+		return fmt.Errorf("error get applied migrations: %w", err)
+	}
+	for _, migration := range pending {
+		logging.Info("applying migration", zap.Int("version", migration.Version), zap.String("description", migration.Description))
+		if err := migration.Up(parent, s); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+		_, err := s.db.Collection("migrations").InsertOne(ctx, AppliedMigration{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Applied:     time.Now().Unix(),
+		})
+		cancel()
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("error record migration %d: %w", migration.Version, err)
+		}
+	}
+	return s.EnsureIndexes(parent)
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Database is a mongo database whose collection names start with a prefix, so that the explorers
+// of several networks share a database, e.g. the collections `testnet.layers` and
+// `mainnet.layers`. The methods take the collection names without the prefix, except the
+// embedded ones taking no collection name. An empty prefix is the plain database.
+type Database struct {
+	*mongo.Database
+	prefix string
+}
+
+// NewDatabase returns the database `name` of the client, its collections named with the prefix.
+func NewDatabase(client *mongo.Client, name, prefix string) *Database {
+	return &Database{Database: client.Database(name), prefix: prefix}
+}
+
+// ValidatePrefix checks that the collection prefix makes valid collection names.
+func ValidatePrefix(prefix string) error {
+	if strings.ContainsAny(prefix, "$\x00") || strings.HasPrefix(prefix, "system.") {
+		return fmt.Errorf("invalid collection prefix `%s`", prefix)
+	}
+	return nil
+}
+
+// Prefix returns the prefix of the collection names.
+func (d *Database) Prefix() string {
+	return d.prefix
+}
+
+// CollectionName returns the name of the collection in the database, for the pipeline stages
+// naming another collection, e.g. `$lookup`.
+func (d *Database) CollectionName(name string) string {
+	return d.prefix + name
+}
+
+// Collection returns the collection of the database.
+func (d *Database) Collection(name string, opts ...*options.CollectionOptions) *mongo.Collection {
+	return d.Database.Collection(d.prefix+name, opts...)
+}
+
+// CreateCollection creates the collection in the database.
+func (d *Database) CreateCollection(ctx context.Context, name string, opts ...*options.CreateCollectionOptions) error {
+	return d.Database.CreateCollection(ctx, d.prefix+name, opts...)
+}
+
+// ListCollectionNames returns the names, without the prefix, of the collections with the prefix
+// matching the filter.
+func (d *Database) ListCollectionNames(ctx context.Context, filter interface{}, opts ...*options.ListCollectionsOptions) ([]string, error) {
+	names, err := d.Database.ListCollectionNames(ctx, filter, opts...)
+	if err != nil || d.prefix == "" {
+		return names, err
+	}
+	prefixed := names[:0]
+	for _, name := range names {
+		if strings.HasPrefix(name, d.prefix) {
+			prefixed = append(prefixed, strings.TrimPrefix(name, d.prefix))
+		}
+	}
+	return prefixed, nil
+}
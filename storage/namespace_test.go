@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePrefix(t *testing.T) {
+	for _, prefix := range []string{"", "testnet.", "mainnet_", "devnet-412."} {
+		require.NoError(t, ValidatePrefix(prefix), prefix)
+	}
+	for _, prefix := range []string{"$testnet.", "test\x00net", "system."} {
+		require.Error(t, ValidatePrefix(prefix), prefix)
+	}
+}
+
+func TestDatabaseCollectionName(t *testing.T) {
+	db := &Database{prefix: "testnet."}
+	require.Equal(t, "testnet.layers", db.CollectionName("layers"))
+	require.Equal(t, "testnet.", db.Prefix())
+	require.Equal(t, "layers", (&Database{}).CollectionName("layers"))
+}
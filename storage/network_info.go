@@ -3,51 +3,31 @@ package storage
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) GetNetworkInfo(parent context.Context) (*model.NetworkInfo, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("networkinfo").Find(ctx, bson.D{{Key: "id", Value: 1}})
-	if err != nil {
-		log.Info("GetNetworkInfo: %v", err)
+	info := &model.NetworkInfo{}
+	if err := s.findOne(parent, "networkinfo", bson.D{{Key: "id", Value: 1}}, info); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetNetworkInfo: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetNetworkInfo", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetNetworkInfo: Empty result")
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	info := &model.NetworkInfo{
-		GenesisId:                utils.GetAsString(doc.Lookup("genesisid")),
-		GenesisTime:              utils.GetAsUInt32(doc.Lookup("genesis")),
-		EpochNumLayers:           utils.GetAsUInt32(doc.Lookup("layers")),
-		MaxTransactionsPerSecond: utils.GetAsUInt32(doc.Lookup("maxtx")),
-		LayerDuration:            utils.GetAsUInt32(doc.Lookup("duration")),
-		LastLayer:                utils.GetAsUInt32(doc.Lookup("lastlayer")),
-		LastLayerTimestamp:       utils.GetAsUInt32(doc.Lookup("lastlayerts")),
-		LastApprovedLayer:        utils.GetAsUInt32(doc.Lookup("lastapprovedlayer")),
-		LastConfirmedLayer:       utils.GetAsUInt32(doc.Lookup("lastconfirmedlayer")),
-		ConnectedPeers:           utils.GetAsUInt64(doc.Lookup("connectedpeers")),
-		IsSynced:                 utils.GetAsBool(doc.Lookup("issynced")),
-		SyncedLayer:              utils.GetAsUInt32(doc.Lookup("syncedlayer")),
-		TopLayer:                 utils.GetAsUInt32(doc.Lookup("toplayer")),
-		VerifiedLayer:            utils.GetAsUInt32(doc.Lookup("verifiedlayer")),
-	}
 	return info, nil
 }
 
-func (s *Storage) SaveOrUpdateNetworkInfo(parent context.Context, in *model.NetworkInfo) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+func (s *Storage) UpsertNetworkInfo(parent context.Context, in *model.NetworkInfo) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 	_, err := s.db.Collection("networkinfo").UpdateOne(ctx, bson.D{{Key: "id", Value: 1}}, bson.D{
 		{Key: "$set", Value: bson.D{
@@ -70,7 +50,41 @@ func (s *Storage) SaveOrUpdateNetworkInfo(parent context.Context, in *model.Netw
 		}},
 	}, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveOrUpdateNetworkInfo: %v", err)
+		logging.Error("UpsertNetworkInfo", err)
 	}
 	return err
 }
+
+// layerHashCheckpoint is the field of the network info holding the last layer whose hash was
+// verified against the node, see GetLayerHashCheckpoint.
+const layerHashCheckpoint = "layerhashcheckpoint"
+
+// GetLayerHashCheckpoint returns the last layer whose stored hash was verified against the mesh hash
+// of the node, 0 if none is verified yet.
+func (s *Storage) GetLayerHashCheckpoint(parent context.Context) (uint32, error) {
+	var info struct {
+		Layer uint32 `bson:"layerhashcheckpoint"`
+	}
+	err := s.findOne(parent, "networkinfo", bson.D{{Key: "id", Value: 1}}, &info,
+		options.FindOne().SetProjection(bson.D{{Key: layerHashCheckpoint, Value: 1}}))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error get layer hash checkpoint: %w", err)
+	}
+	return info.Layer, nil
+}
+
+// SetLayerHashCheckpoint stores the last layer whose hash was verified, see GetLayerHashCheckpoint.
+func (s *Storage) SetLayerHashCheckpoint(parent context.Context, layer uint32) error {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	_, err := s.db.Collection("networkinfo").UpdateOne(ctx, bson.D{{Key: "id", Value: 1}}, bson.D{
+		{Key: "$set", Value: bson.D{{Key: layerHashCheckpoint, Value: layer}}},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error set layer hash checkpoint: %w", err)
+	}
+	return nil
+}
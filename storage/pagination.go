@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidCursor is returned for a page cursor which was not produced by EncodeCursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position after the last document of a page: the values of its sort field and of
+// the unique field breaking the ties of the sort field.
+type Cursor struct {
+	Key any `bson:"k"`
+	ID  any `bson:"i,omitempty"`
+}
+
+// EncodeCursor returns the opaque representation of the cursor handed to the API clients.
+func EncodeCursor(c Cursor) (string, error) {
+	raw, err := bson.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("error encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor.
+func DecodeCursor(in string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(in)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := bson.Unmarshal(raw, &c); err != nil || c.Key == nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Page selects a page of a list with keyset pagination: the documents following the cursor in the
+// order of SortField then IDField. Unlike skip and limit, reading a deep page costs the same as
+// reading the first one as long as an index covers both fields.
+type Page struct {
+	// SortField is the field the list is sorted by, e.g. `layer`.
+	SortField string
+	// IDField is a unique field breaking the ties of SortField, e.g. `id`. It may be empty if
+	// SortField is unique.
+	IDField    string
+	Descending bool
+	// Size is the number of documents of the page.
+	Size int64
+	// After is the cursor of the previous page, nil for the first page.
+	After *Cursor
+}
+
+// Filter returns the filter restricted to the documents following the cursor.
+func (p Page) Filter(filter *bson.D) *bson.D {
+	var out bson.D
+	if filter != nil {
+		out = append(out, *filter...)
+	}
+	if p.After == nil {
+		return &out
+	}
+	op := "$gt"
+	if p.Descending {
+		op = "$lt"
+	}
+	var after bson.D
+	if p.IDField == "" || p.IDField == p.SortField {
+		after = bson.D{{Key: p.SortField, Value: bson.D{{Key: op, Value: p.After.Key}}}}
+	} else {
+		after = bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: p.SortField, Value: bson.D{{Key: op, Value: p.After.Key}}}},
+			bson.D{
+				{Key: p.SortField, Value: p.After.Key},
+				{Key: p.IDField, Value: bson.D{{Key: op, Value: p.After.ID}}},
+			},
+		}}}
+	}
+	// a condition on the same field or a second `$or` would replace the one of the filter
+	for _, e := range out {
+		if e.Key == after[0].Key {
+			return &bson.D{{Key: "$and", Value: bson.A{out, after}}}
+		}
+	}
+	out = append(out, after...)
+	return &out
+}
+
+// FindOptions returns the sort of the page and a limit fetching one extra document, which tells
+// whether a next page exists.
+func (p Page) FindOptions() *options.FindOptions {
+	order := 1
+	if p.Descending {
+		order = -1
+	}
+	sort := bson.D{{Key: p.SortField, Value: order}}
+	if p.IDField != "" && p.IDField != p.SortField {
+		sort = append(sort, bson.E{Key: p.IDField, Value: order})
+	}
+	return options.Find().SetSort(sort).SetLimit(p.Size + 1)
+}
+
+// PageResults trims the documents fetched with the FindOptions of the page to its size, and
+// returns the encoded cursor of the next page, empty if it is the last page. cursorOf returns the
+// cursor of a document.
+func PageResults[T any](p Page, docs []T, cursorOf func(T) Cursor) ([]T, string, error) {
+	if int64(len(docs)) <= p.Size {
+		return docs, "", nil
+	}
+	docs = docs[:p.Size]
+	if len(docs) == 0 {
+		return docs, "", nil
+	}
+	next, err := EncodeCursor(cursorOf(docs[len(docs)-1]))
+	if err != nil {
+		return nil, "", err
+	}
+	return docs, next, nil
+}
+
+// DecodePage decodes the raw documents of a page with decode into out, a pointer to a slice. The
+// slice is empty rather than nil without documents, so that the API serializes an empty list.
+func DecodePage[D ~[]byte](out any, docs []D, decode func([]byte, any) error) error {
+	slice := reflect.ValueOf(out).Elem()
+	items := reflect.MakeSlice(slice.Type(), 0, len(docs))
+	for _, doc := range docs {
+		item := reflect.New(slice.Type().Elem())
+		if err := decode(doc, item.Interface()); err != nil {
+			return fmt.Errorf("error decode page: %w", err)
+		}
+		items = reflect.Append(items, item.Elem())
+	}
+	slice.Set(items)
+	return nil
+}
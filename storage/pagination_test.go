@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	encoded, err := EncodeCursor(Cursor{Key: int64(12), ID: "0x01"})
+	require.NoError(t, err)
+
+	c, err := DecodeCursor(encoded)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), c.Key)
+	require.Equal(t, "0x01", c.ID)
+
+	_, err = DecodeCursor("not a cursor")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = DecodeCursor("")
+	require.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestPageFilter(t *testing.T) {
+	filter := &bson.D{{Key: "sender", Value: "sm1a"}}
+	p := Page{SortField: "layer", IDField: "id", Descending: true, Size: 2}
+	require.Equal(t, filter, p.Filter(filter))
+
+	p.After = &Cursor{Key: int64(12), ID: "0x01"}
+	require.Equal(t, &bson.D{
+		{Key: "sender", Value: "sm1a"},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "layer", Value: bson.D{{Key: "$lt", Value: int64(12)}}}},
+			bson.D{{Key: "layer", Value: int64(12)}, {Key: "id", Value: bson.D{{Key: "$lt", Value: "0x01"}}}},
+		}},
+	}, p.Filter(filter))
+	require.Len(t, *filter, 1)
+
+	p = Page{SortField: "number", Size: 2, After: &Cursor{Key: int64(3)}}
+	require.Equal(t, &bson.D{
+		{Key: "$and", Value: bson.A{
+			bson.D{{Key: "number", Value: bson.D{{Key: "$lte", Value: 10}}}},
+			bson.D{{Key: "number", Value: bson.D{{Key: "$gt", Value: int64(3)}}}},
+		}},
+	}, p.Filter(&bson.D{{Key: "number", Value: bson.D{{Key: "$lte", Value: 10}}}}))
+}
+
+func TestPageFindOptions(t *testing.T) {
+	opts := Page{SortField: "layer", IDField: "id", Descending: true, Size: 20}.FindOptions()
+	require.Equal(t, bson.D{{Key: "layer", Value: -1}, {Key: "id", Value: -1}}, opts.Sort)
+	require.Equal(t, int64(21), *opts.Limit)
+}
+
+func TestPageResults(t *testing.T) {
+	p := Page{SortField: "layer", IDField: "id", Size: 2}
+	cursorOf := func(tx *model.Transaction) Cursor {
+		return Cursor{Key: int64(tx.Layer), ID: tx.Id}
+	}
+	txs := []*model.Transaction{{Id: "0x01", Layer: 1}, {Id: "0x02", Layer: 1}, {Id: "0x03", Layer: 2}}
+
+	page, next, err := PageResults(p, txs, cursorOf)
+	require.NoError(t, err)
+	require.Equal(t, txs[:2], page)
+	c, err := DecodeCursor(next)
+	require.NoError(t, err)
+	require.Equal(t, &Cursor{Key: int64(1), ID: "0x02"}, c)
+
+	page, next, err = PageResults(p, txs[2:], cursorOf)
+	require.NoError(t, err)
+	require.Equal(t, txs[2:], page)
+	require.Empty(t, next)
+}
+
+func TestDecodePage(t *testing.T) {
+	var txs []*model.Transaction
+	require.NoError(t, DecodePage[bson.Raw](&txs, nil, nil))
+	require.NotNil(t, txs)
+	require.Empty(t, txs)
+
+	doc, err := bson.Marshal(bson.D{{Key: "id", Value: "0x01"}, {Key: "layer", Value: 12}})
+	require.NoError(t, err)
+	require.NoError(t, DecodePage(&txs, []bson.Raw{doc}, bson.Unmarshal))
+	require.Len(t, txs, 1)
+	require.Equal(t, "0x01", txs[0].Id)
+	require.Equal(t, uint32(12), txs[0].Layer)
+}
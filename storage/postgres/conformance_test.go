@@ -0,0 +1,38 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+	"github.com/spacemeshos/explorer-backend/test/storagetest"
+)
+
+// TestConformance runs the storage suite against the database at EXPLORER_TEST_POSTGRES_URL, its
+// tables are emptied before every test.
+func TestConformance(t *testing.T) {
+	url := os.Getenv("EXPLORER_TEST_POSTGRES_URL")
+	if url == "" {
+		t.Skip("EXPLORER_TEST_POSTGRES_URL is not set")
+	}
+	ctx := context.Background()
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Backend {
+		e, err := connect(ctx, url)
+		require.NoError(t, err)
+		for table := range tables {
+			_, err := e.pool.Exec(ctx, fmt.Sprintf("TRUNCATE %s", table))
+			require.NoError(t, err)
+		}
+		s := docstore.New(e)
+		t.Cleanup(s.Close)
+		reader, err := NewReader(ctx, url)
+		require.NoError(t, err)
+		t.Cleanup(reader.Close)
+		return storagetest.Backend{Writer: s, Reader: reader}
+	})
+}
@@ -0,0 +1,314 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+)
+
+// fieldPattern restricts document field names which are inlined into SQL.
+var fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// query translates mongo style filters and find options into SQL over the `doc` jsonb column,
+// so the API services can build their queries the same way for both backends.
+// Supported operators: $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $and and $or.
+type query struct {
+	args []any
+}
+
+func (q *query) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// where returns the SQL condition matching the filter, `TRUE` for an empty filter.
+func (q *query) where(filter *bson.D) (string, error) {
+	if filter == nil || len(*filter) == 0 {
+		return "TRUE", nil
+	}
+	return q.and(*filter)
+}
+
+func (q *query) and(filter bson.D) (string, error) {
+	conds := make([]string, 0, len(filter))
+	for _, e := range filter {
+		cond, err := q.element(e)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	if len(conds) == 0 {
+		return "TRUE", nil
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+func (q *query) element(e bson.E) (string, error) {
+	switch e.Key {
+	case "$and", "$or":
+		filters, ok := e.Value.(bson.A)
+		if !ok {
+			return "", fmt.Errorf("%s expects an array", e.Key)
+		}
+		conds := make([]string, 0, len(filters))
+		for _, f := range filters {
+			d, ok := f.(bson.D)
+			if !ok {
+				return "", fmt.Errorf("%s expects an array of documents", e.Key)
+			}
+			cond, err := q.and(d)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, cond)
+		}
+		if len(conds) == 0 {
+			return "TRUE", nil
+		}
+		op := " AND "
+		if e.Key == "$or" {
+			op = " OR "
+		}
+		return "(" + strings.Join(conds, op) + ")", nil
+	}
+
+	if !fieldPattern.MatchString(e.Key) {
+		return "", fmt.Errorf("unsupported field `%s`", e.Key)
+	}
+	ops, ok := e.Value.(bson.D)
+	if !ok || len(ops) == 0 || !strings.HasPrefix(ops[0].Key, "$") {
+		return q.eq(e.Key, e.Value)
+	}
+
+	conds := make([]string, 0, len(ops))
+	for _, op := range ops {
+		cond, err := q.operator(e.Key, op)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+func (q *query) operator(field string, op bson.E) (string, error) {
+	switch op.Key {
+	case "$eq":
+		return q.eq(field, op.Value)
+	case "$ne":
+		cond, err := q.eq(field, op.Value)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + cond, nil
+	case "$gt", "$gte", "$lt", "$lte":
+		value, err := jsonValue(op.Value)
+		if err != nil {
+			return "", err
+		}
+		cmp := map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op.Key]
+		return fmt.Sprintf("%s %s %s::jsonb", path(field), cmp, q.arg(string(value))), nil
+	case "$in", "$nin":
+		values := reflect.ValueOf(op.Value)
+		if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+			return "", fmt.Errorf("%s expects an array", op.Key)
+		}
+		conds := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			cond, err := q.eq(field, values.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, cond)
+		}
+		cond := "FALSE"
+		if len(conds) > 0 {
+			cond = "(" + strings.Join(conds, " OR ") + ")"
+		}
+		if op.Key == "$nin" {
+			return "NOT " + cond, nil
+		}
+		return cond, nil
+	case "$exists":
+		exists, ok := op.Value.(bool)
+		if !ok {
+			return "", fmt.Errorf("$exists expects a boolean")
+		}
+		if exists {
+			return path(field) + " IS NOT NULL", nil
+		}
+		return path(field) + " IS NULL", nil
+	}
+	return "", fmt.Errorf("unsupported operator `%s`", op.Key)
+}
+
+// eq matches the field value or, like mongo, an array field containing the value. It is expressed
+// with jsonb containment so the gin index on `doc` is used.
+func (q *query) eq(field string, value any) (string, error) {
+	leaf, err := jsonValue(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid value for `%s`: %w", field, err)
+	}
+	parts := strings.Split(field, ".")
+	variants := []string{string(leaf), "[" + string(leaf) + "]"}
+	for i := len(parts) - 1; i >= 0; i-- {
+		next := make([]string, 0, len(variants)*2)
+		for _, v := range variants {
+			d := fmt.Sprintf(`{%q:%s}`, parts[i], v)
+			next = append(next, d)
+			if i > 0 {
+				next = append(next, "["+d+"]")
+			}
+		}
+		variants = next
+	}
+
+	conds := make([]string, 0, len(variants))
+	for _, v := range variants {
+		conds = append(conds, fmt.Sprintf("doc @> %s::jsonb", q.arg(v)))
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", nil
+}
+
+// orderBy returns the ORDER BY, OFFSET and LIMIT clauses of the find options.
+func (q *query) orderBy(opts *options.FindOptions) (string, error) {
+	if opts == nil {
+		return "", nil
+	}
+	var clauses []string
+	if opts.Sort != nil {
+		sort, ok := opts.Sort.(bson.D)
+		if !ok {
+			return "", fmt.Errorf("unsupported sort %T", opts.Sort)
+		}
+		keys := make([]string, 0, len(sort))
+		for _, e := range sort {
+			if !fieldPattern.MatchString(e.Key) {
+				return "", fmt.Errorf("unsupported sort field `%s`", e.Key)
+			}
+			dir := "ASC"
+			if v := reflect.ValueOf(e.Value); v.CanInt() && v.Int() < 0 {
+				dir = "DESC"
+			}
+			keys = append(keys, path(e.Key)+" "+dir)
+		}
+		if len(keys) > 0 {
+			clauses = append(clauses, "ORDER BY "+strings.Join(keys, ", "))
+		}
+	}
+	if opts.Skip != nil && *opts.Skip > 0 {
+		clauses = append(clauses, "OFFSET "+q.arg(*opts.Skip))
+	}
+	if opts.Limit != nil && *opts.Limit > 0 {
+		clauses = append(clauses, "LIMIT "+q.arg(*opts.Limit))
+	}
+	return strings.Join(clauses, " "), nil
+}
+
+// keys returns the SQL condition selecting up to limit documents of the table matching the
+// filter, all of them if limit is 0.
+func (q *query) keys(table string, filter *bson.D, limit int64) (string, error) {
+	where, err := q.where(filter)
+	if err != nil || limit <= 0 {
+		return where, err
+	}
+	return fmt.Sprintf("key IN (SELECT key FROM %s WHERE %s LIMIT %s)", table, where, q.arg(limit)), nil
+}
+
+// update returns the jsonb expression of the document doc updated with the operators.
+func (q *query) update(doc string, u docstore.Update) (string, error) {
+	set, err := encode(u.Set)
+	if err != nil {
+		return "", err
+	}
+	fields := make([]string, 0, 2*(len(u.Inc)+len(u.Min)+len(u.Max)+len(u.AddToSet)))
+	for _, ops := range []struct {
+		fields bson.D
+		expr   string
+	}{
+		{u.Inc, "coalesce((%[1]s->>%[2]s)::numeric, 0) + %[3]s"},
+		{u.Min, "least(coalesce((%[1]s->>%[2]s)::numeric, %[3]s), %[3]s)"},
+		{u.Max, "greatest(coalesce((%[1]s->>%[2]s)::numeric, %[3]s), %[3]s)"},
+	} {
+		for _, f := range ops.fields {
+			v := reflect.ValueOf(f.Value)
+			var n int64
+			switch {
+			case v.CanInt():
+				n = v.Int()
+			case v.CanUint():
+				n = int64(v.Uint())
+			default:
+				return "", fmt.Errorf("invalid number for `%s`", f.Key)
+			}
+			key := q.arg(f.Key) + "::text"
+			fields = append(fields, key, fmt.Sprintf(ops.expr, doc, key, q.arg(n)+"::numeric"))
+		}
+	}
+	for _, f := range u.AddToSet {
+		values, err := jsonValue(f.Value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for `%s`: %w", f.Key, err)
+		}
+		key := q.arg(f.Key) + "::text"
+		fields = append(fields, key, fmt.Sprintf(
+			"(SELECT coalesce(jsonb_agg(DISTINCT e), '[]'::jsonb) FROM jsonb_array_elements(coalesce(%s->%s, '[]'::jsonb) || %s::jsonb) AS e)",
+			doc, key, q.arg(string(values))))
+	}
+	expr := "(" + doc + " || " + q.arg(set) + "::jsonb"
+	if len(fields) > 0 {
+		expr += " || jsonb_build_object(" + strings.Join(fields, ", ") + ")"
+	}
+	return expr + ")", nil
+}
+
+// path returns the jsonb expression of a validated, possibly dotted, field name.
+func path(field string) string {
+	var b strings.Builder
+	b.WriteString("doc")
+	for _, part := range strings.Split(field, ".") {
+		b.WriteString("->'")
+		b.WriteString(part)
+		b.WriteString("'")
+	}
+	return b.String()
+}
+
+// textPath returns the SQL expression of the document field as text.
+func textPath(field string) string {
+	parts := strings.Split(field, ".")
+	last := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return "doc->>'" + last + "'"
+	}
+	return path(strings.Join(parts[:len(parts)-1], ".")) + "->>'" + last + "'"
+}
+
+// coordinates returns the SQL expressions of the longitude and the latitude of a
+// [longitude, latitude] document field.
+func coordinates(field string) (lon, lat string) {
+	return "(" + path(field) + "->>0)::float8", "(" + path(field) + "->>1)::float8"
+}
+
+// jsonValue encodes a single value the same way documents are stored.
+func jsonValue(v any) (json.RawMessage, error) {
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return nil, err
+	}
+	var wrapper struct {
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, err
+	}
+	return wrapper.V, nil
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+)
+
+func TestWhere(t *testing.T) {
+	table := []struct {
+		name   string
+		filter *bson.D
+		sql    string
+		args   []any
+	}{
+		{
+			name:   "empty",
+			filter: &bson.D{},
+			sql:    "TRUE",
+		},
+		{
+			name:   "equality",
+			filter: &bson.D{{Key: "sender", Value: "sm1"}},
+			sql:    "((doc @> $1::jsonb OR doc @> $2::jsonb))",
+			args:   []any{`{"sender":"sm1"}`, `{"sender":["sm1"]}`},
+		},
+		{
+			name:   "range",
+			filter: &bson.D{{Key: "layer", Value: bson.D{{Key: "$gte", Value: 10}, {Key: "$lte", Value: 20}}}},
+			sql:    "((doc->'layer' >= $1::jsonb AND doc->'layer' <= $2::jsonb))",
+			args:   []any{"10", "20"},
+		},
+		{
+			name: "or",
+			filter: &bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "sender", Value: "sm1"}},
+				bson.D{{Key: "layer", Value: bson.D{{Key: "$exists", Value: false}}}},
+			}}},
+			sql:  "((((doc @> $1::jsonb OR doc @> $2::jsonb)) OR ((doc->'layer' IS NULL))))",
+			args: []any{`{"sender":"sm1"}`, `{"sender":["sm1"]}`},
+		},
+	}
+	for _, tc := range table {
+		t.Run(tc.name, func(t *testing.T) {
+			q := &query{}
+			sql, err := q.where(tc.filter)
+			require.NoError(t, err)
+			require.Equal(t, tc.sql, sql)
+			require.Equal(t, tc.args, q.args)
+		})
+	}
+}
+
+func TestWhereRejectsUnsafeInput(t *testing.T) {
+	_, err := (&query{}).where(&bson.D{{Key: "layer'; DROP TABLE layers; --", Value: 1}})
+	require.Error(t, err)
+	_, err = (&query{}).where(&bson.D{{Key: "layer", Value: bson.D{{Key: "$regex", Value: ".*"}}}})
+	require.Error(t, err)
+}
+
+func TestOrderBy(t *testing.T) {
+	q := &query{}
+	sql, err := q.orderBy(options.Find().SetSort(bson.D{{Key: "layer", Value: -1}, {Key: "counter", Value: 1}}).SetSkip(20).SetLimit(10))
+	require.NoError(t, err)
+	require.Equal(t, "ORDER BY doc->'layer' DESC, doc->'counter' ASC OFFSET $1 LIMIT $2", sql)
+	require.Equal(t, []any{int64(20), int64(10)}, q.args)
+}
+
+func TestTextPath(t *testing.T) {
+	require.Equal(t, "doc->>'name'", textPath("name"))
+	require.Equal(t, "doc->'stats'->'current'->>'name'", textPath("stats.current.name"))
+}
+
+func TestCoordinates(t *testing.T) {
+	lon, lat := coordinates("geo.coordinates")
+	require.Equal(t, "(doc->'geo'->'coordinates'->>0)::float8", lon)
+	require.Equal(t, "(doc->'geo'->'coordinates'->>1)::float8", lat)
+}
+
+func TestUpdate(t *testing.T) {
+	q := &query{}
+	sql, err := q.update("smeshers.doc", docstore.Update{
+		Set:      bson.D{{Key: "id", Value: "0x01"}},
+		Inc:      bson.D{{Key: "rewardsCount", Value: 1}},
+		Min:      bson.D{{Key: "from", Value: uint32(2)}},
+		AddToSet: bson.D{{Key: "epochs", Value: bson.A{2}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "(smeshers.doc || $7::jsonb || jsonb_build_object("+
+		"$1::text, coalesce((smeshers.doc->>$1::text)::numeric, 0) + $2::numeric, "+
+		"$3::text, least(coalesce((smeshers.doc->>$3::text)::numeric, $4::numeric), $4::numeric), "+
+		"$5::text, (SELECT coalesce(jsonb_agg(DISTINCT e), '[]'::jsonb) FROM jsonb_array_elements(coalesce(smeshers.doc->$5::text, '[]'::jsonb) || $6::jsonb) AS e)))", sql)
+	require.Equal(t, []any{"rewardsCount", int64(1), "from", int64(2), "epochs", "[2]", `{"id":"0x01"}`}, q.args)
+
+	_, err = q.update("doc", docstore.Update{Inc: bson.D{{Key: "total", Value: "1"}}})
+	require.Error(t, err)
+}
+
+func TestKeys(t *testing.T) {
+	q := &query{}
+	sql, err := q.keys("rewards", &bson.D{{Key: "layer", Value: bson.D{{Key: "$lt", Value: 10}}}}, 1000)
+	require.NoError(t, err)
+	require.Equal(t, "key IN (SELECT key FROM rewards WHERE ((doc->'layer' < $1::jsonb)) LIMIT $2)", sql)
+	require.Equal(t, []any{"10", int64(1000)}, q.args)
+}
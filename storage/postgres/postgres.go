@@ -0,0 +1,676 @@
+// Package postgres stores explorer data in PostgreSQL. Every mongo collection is mapped to a table
+// holding the same documents in a jsonb column, so the collector and the API produce and read the
+// same data with both backends. It is the PostgreSQL docstore.Engine.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/storage/docstore"
+)
+
+var _ docstore.Engine = (*engine)(nil)
+
+// tables maps every collection to the document fields indexed for range queries and sorting.
+// Equality lookups are served by the gin index on the whole document.
+var tables = map[string][]string{
+	"networkinfo":        nil,
+	"layers":             {"number"},
+	"blocks":             {"layer"},
+	"txs":                {"layer", "timestamp", "counter"},
+	"rewards":            {"layer"},
+	"activations":        {"layer", "received", "targetEpoch"},
+	"smeshers":           {"timestamp", "totalRewards"},
+	"smesher_history":    {"epoch"},
+	"coinbases":          nil,
+	"accounts":           {"created", "layer"},
+	"balance_changes":    {"layer"},
+	"epochs":             {"number", "start"},
+	"epoch_stats":        {"epoch"},
+	"malfeasance_proofs": {"layer"},
+	"certificates":       {"layer"},
+	"ballots":            {"layer"},
+	"apps":               nil,
+	"archive":            nil,
+	"labels":             nil,
+	"prices":             {"timestamp"},
+	"vaults":             nil,
+	"chain_activations":  {"targetEpoch"},
+	"chain_rewards":      {"layer"},
+	"chain_smeshers":     nil,
+}
+
+// textIndexes maps the collections to the document fields indexed for full-text search.
+var textIndexes = map[string][]string{
+	"labels": {"name"},
+}
+
+// geoIndexes maps the collections to the [longitude, latitude] fields indexed for the searches by
+// location.
+var geoIndexes = map[string][]string{
+	"smeshers": {"geo.coordinates"},
+}
+
+// engine wraps the connection pool, it is the PostgreSQL docstore.Engine.
+type engine struct {
+	pool *pgxpool.Pool
+	// timeouts bound the statements, unset classes are not bounded.
+	timeouts storage.Timeouts
+	// retryPolicy bounds the retries of the reads failing with a transient error.
+	retryPolicy storage.RetryPolicy
+}
+
+// New connects to the database, creates the missing tables and returns a storage writing to it.
+func New(ctx context.Context, dbURL string) (*docstore.Storage, error) {
+	e, err := connect(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	e.timeouts = storage.DefaultTimeouts
+	e.retryPolicy = storage.DefaultRetryPolicy
+	return docstore.New(e), nil
+}
+
+// NewReader connects to the database and returns a reader of it.
+func NewReader(ctx context.Context, dbURL string) (*docstore.Reader, error) {
+	e, err := connect(ctx, dbURL)
+	if err != nil {
+		return nil, err
+	}
+	return docstore.NewReader(e), nil
+}
+
+func connect(parent context.Context, dbURL string) (*engine, error) {
+	ctx, cancel := context.WithTimeout(parent, 10*time.Second)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("error connect to db: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("error ping to db: %w", err)
+	}
+	c := &engine{pool: pool}
+	if err := c.createTables(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *engine) createTables(ctx context.Context) error {
+	for table, fields := range tables {
+		stmts := []string{
+			fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (key text PRIMARY KEY, doc jsonb NOT NULL)`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_doc_idx ON %s USING gin (doc jsonb_path_ops)`, table, table),
+		}
+		for _, field := range fields {
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s_idx ON %s ((%s))`,
+				table, strings.ToLower(field), table, path(field)))
+		}
+		for _, field := range textIndexes[table] {
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s_text_idx ON %s USING gin (to_tsvector('simple', %s))`,
+				table, strings.ToLower(field), table, textPath(field)))
+		}
+		for _, field := range geoIndexes[table] {
+			lon, lat := coordinates(field)
+			stmts = append(stmts, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_%s_geo_idx ON %s ((%s), (%s))`,
+				table, strings.ReplaceAll(strings.ToLower(field), ".", "_"), table, lat, lon))
+		}
+		for _, stmt := range stmts {
+			if _, err := c.pool.Exec(ctx, stmt); err != nil {
+				return fmt.Errorf("error init `%s` table: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *engine) Close() {
+	c.pool.Close()
+}
+
+// Ping checks if the database is reachable.
+func (c *engine) Ping(ctx context.Context) error {
+	if c.pool == nil {
+		return errors.New("storage not initialized")
+	}
+	return c.pool.Ping(ctx)
+}
+
+// withTimeout bounds the context by d, if set.
+func withTimeout(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, d)
+}
+
+// SetTimeouts sets the timeouts of the statements, the unset classes keep storage.DefaultTimeouts.
+func (c *engine) SetTimeouts(t storage.Timeouts) {
+	c.timeouts = t.WithDefault(0)
+}
+
+// SetRetryPolicy sets the retries of the reads failing with a transient error.
+func (c *engine) SetRetryPolicy(p storage.RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// encode returns the jsonb representation of a document.
+func encode(doc any) (string, error) {
+	if fields, ok := doc.(bson.D); ok && fields == nil {
+		return "{}", nil
+	}
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (c *engine) Upsert(ctx context.Context, table, key string, fields bson.D) (err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	doc, err := encode(fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.pool.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %[1]s (key, doc) VALUES ($1, $2::jsonb) ON CONFLICT (key) DO UPDATE SET doc = %[1]s.doc || EXCLUDED.doc`,
+		table), key, doc)
+	return err
+}
+
+// UpsertBatch writes the documents in a single round trip.
+func (c *engine) UpsertBatch(ctx context.Context, table string, keys []string, docs []bson.D) error {
+	return c.execBatch(ctx, table, keys, docs,
+		`INSERT INTO %[1]s (key, doc) VALUES ($1, $2::jsonb) ON CONFLICT (key) DO UPDATE SET doc = %[1]s.doc || EXCLUDED.doc`)
+}
+
+func (c *engine) UpsertBatchVersioned(ctx context.Context, table string, keys []string, docs []bson.D) error {
+	return c.execBatch(ctx, table, keys, docs,
+		`INSERT INTO %[1]s (key, doc) VALUES ($1, $2::jsonb || '{"version": 1}'::jsonb) ON CONFLICT (key) DO UPDATE
+			SET doc = %[1]s.doc || EXCLUDED.doc || jsonb_build_object('version', COALESCE((%[1]s.doc->>'version')::bigint, 0) + 1)`)
+}
+
+// execBatch runs the statement, formatted with the table, for every key and document.
+func (c *engine) execBatch(ctx context.Context, table string, keys []string, docs []bson.D, sql string) (err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Bulk)
+	defer cancel()
+	batch := &pgx.Batch{}
+	for i := range docs {
+		doc, err := encode(docs[i])
+		if err != nil {
+			return err
+		}
+		batch.Queue(fmt.Sprintf(sql, table), keys[i], doc)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	return c.pool.SendBatch(ctx, batch).Close()
+}
+
+func (c *engine) Existing(ctx context.Context, table string, keys []string) (found map[string]bool, err error) {
+	defer observe(table, storage.OperationFind, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	rows, err := c.pool.Query(ctx, fmt.Sprintf(`SELECT key FROM %s WHERE key = ANY($1)`, table), keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found = make(map[string]bool)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		found[key] = true
+	}
+	return found, rows.Err()
+}
+
+func (c *engine) Insert(ctx context.Context, table, key string, fields bson.D) (err error) {
+	defer observe(table, storage.OperationInsert, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	doc, err := encode(fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.pool.Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (key, doc) VALUES ($1, $2::jsonb) ON CONFLICT (key) DO NOTHING`, table), key, doc)
+	return err
+}
+
+func (c *engine) Update(ctx context.Context, table, key string, fields bson.D) (err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	doc, err := encode(fields)
+	if err != nil {
+		return err
+	}
+	_, err = c.pool.Exec(ctx, fmt.Sprintf(`UPDATE %s SET doc = doc || $2::jsonb WHERE key = $1`, table), key, doc)
+	return err
+}
+
+// Apply runs the update in a single statement, an upsert inserts the update of the SetOnInsert
+// fields.
+func (c *engine) Apply(ctx context.Context, table, key string, u docstore.Update) (err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	q := &query{}
+	q.arg(key)
+	updated, err := q.update(table+".doc", u)
+	if err != nil {
+		return err
+	}
+	if !u.Upsert {
+		_, err = c.pool.Exec(ctx, fmt.Sprintf(`UPDATE %[1]s SET doc = %[2]s WHERE key = $1`, table, updated), q.args...)
+		return err
+	}
+	insert, err := encode(u.SetOnInsert)
+	if err != nil {
+		return err
+	}
+	inserted, err := q.update(q.arg(insert)+"::jsonb", u)
+	if err != nil {
+		return err
+	}
+	_, err = c.pool.Exec(ctx, fmt.Sprintf(`INSERT INTO %[1]s (key, doc) VALUES ($1, %[2]s)
+		ON CONFLICT (key) DO UPDATE SET doc = %[3]s`, table, inserted, updated), q.args...)
+	return err
+}
+
+func (c *engine) CompareAndSet(ctx context.Context, table, key string, version uint64, fields bson.D) (updated bool, err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	doc, err := encode(append(fields, bson.E{Key: "version", Value: version + 1}))
+	if err != nil {
+		return false, err
+	}
+	res, err := c.pool.Exec(ctx, fmt.Sprintf(
+		`UPDATE %s SET doc = doc || $2::jsonb WHERE key = $1 AND COALESCE((doc->>'version')::bigint, 0) = $3`, table),
+		key, doc, int64(version))
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+func (c *engine) UpdateMany(ctx context.Context, table string, filter *bson.D, fields bson.D) (updated int64, err error) {
+	doc, err := encode(fields)
+	if err != nil {
+		return 0, err
+	}
+	return c.updateWhere(ctx, table, filter, 0, func(q *query) string {
+		return "doc || " + q.arg(doc) + "::jsonb"
+	})
+}
+
+func (c *engine) Remove(ctx context.Context, table string, filter *bson.D, limit int64) (removed int64, err error) {
+	defer observe(table, storage.OperationDelete, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Bulk)
+	defer cancel()
+	q := &query{}
+	keys, err := q.keys(table, filter, limit)
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.pool.Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, table, keys), q.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (c *engine) Unset(ctx context.Context, table string, filter *bson.D, limit int64, fields ...string) (int64, error) {
+	return c.updateWhere(ctx, table, filter, limit, func(q *query) string {
+		return "doc - " + q.arg(fields) + "::text[]"
+	})
+}
+
+// updateWhere sets the documents matching the filter, up to limit of them if it is not 0, to the
+// expression and returns the number of updated documents.
+func (c *engine) updateWhere(ctx context.Context, table string, filter *bson.D, limit int64, set func(q *query) string) (updated int64, err error) {
+	defer observe(table, storage.OperationUpdate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Bulk)
+	defer cancel()
+	q := &query{}
+	keys, err := q.keys(table, filter, limit)
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.pool.Exec(ctx, fmt.Sprintf(`UPDATE %s SET doc = %s WHERE %s`, table, set(q), keys), q.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
+
+func (c *engine) Find(ctx context.Context, table string, filter *bson.D, opts ...*options.FindOptions) ([][]byte, error) {
+	var opt *options.FindOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	return c.find(ctx, table, table, filter, opt)
+}
+
+// find returns the raw documents of the relation matching the filter, the metrics are recorded for
+// the table.
+func (c *engine) find(parent context.Context, table, from string, filter *bson.D, opts *options.FindOptions) (docs [][]byte, err error) {
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return nil, err
+	}
+	tail, err := q.orderBy(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.findSQL(parent, table, fmt.Sprintf(`SELECT doc::text FROM %s WHERE %s %s`, from, where, tail), q.args...)
+}
+
+// FindPage counts the documents matching the filter by a window over the same statement. A page
+// past the last document counts separately.
+func (c *engine) FindPage(ctx context.Context, table string, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error) {
+	if _, ok := tables[table]; !ok {
+		return nil, 0, fmt.Errorf("unknown collection `%s`", table)
+	}
+	return c.findPage(ctx, table, table, filter, opts)
+}
+
+func (c *engine) findPage(parent context.Context, table, from string, filter *bson.D, opts *options.FindOptions) (docs [][]byte, total int64, err error) {
+	defer observe(table, storage.OperationFind, time.Now(), &err)
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	tail, err := q.orderBy(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = c.retryPolicy.Do(parent, table, storage.OperationFind, isTransient, func(parent context.Context) error {
+		ctx, cancel := withTimeout(parent, c.timeouts.Query)
+		defer cancel()
+		rows, err := c.pool.Query(ctx, fmt.Sprintf(`SELECT doc::text, count(*) OVER () FROM %s WHERE %s %s`, from, where, tail), q.args...)
+		if err != nil {
+			return err
+		}
+		var doc string
+		docs = docs[:0]
+		_, err = pgx.ForEachRow(rows, []any{&doc, &total}, func() error {
+			docs = append(docs, []byte(doc))
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(docs) == 0 {
+		total, err = c.count(parent, table, from, filter)
+	}
+	return docs, total, err
+}
+
+// findSQL returns the raw documents selected by the SQL statement, for the queries the filters do
+// not express. The statement selects the documents as text.
+func (c *engine) findSQL(parent context.Context, table, sql string, args ...any) (docs [][]byte, err error) {
+	defer observe(table, storage.OperationFind, time.Now(), &err)
+	err = c.retryPolicy.Do(parent, table, storage.OperationFind, isTransient, func(parent context.Context) error {
+		ctx, cancel := withTimeout(parent, c.timeouts.Query)
+		defer cancel()
+		rows, err := c.pool.Query(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		docs, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) ([]byte, error) {
+			var doc string
+			err := row.Scan(&doc)
+			return []byte(doc), err
+		})
+		return err
+	})
+	return docs, err
+}
+
+func (c *engine) Count(ctx context.Context, table string, filter *bson.D) (int64, error) {
+	return c.count(ctx, table, table, filter)
+}
+
+func (c *engine) count(parent context.Context, table, from string, filter *bson.D) (count int64, err error) {
+	defer observe(table, storage.OperationCount, time.Now(), &err)
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return 0, err
+	}
+	err = c.retryPolicy.Do(parent, table, storage.OperationCount, isTransient, func(parent context.Context) error {
+		ctx, cancel := withTimeout(parent, c.timeouts.Query)
+		defer cancel()
+		return c.pool.QueryRow(ctx, fmt.Sprintf(`SELECT count(*) FROM %s WHERE %s`, from, where), q.args...).Scan(&count)
+	})
+	return count, err
+}
+
+func (c *engine) Sum(ctx context.Context, table string, filter *bson.D, fields ...string) (results []int64, err error) {
+	defer observe(table, storage.OperationAggregate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Aggregate)
+	defer cancel()
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		if !fieldPattern.MatchString(field) {
+			return nil, fmt.Errorf("unsupported field `%s`", field)
+		}
+		cols = append(cols, fmt.Sprintf("coalesce(sum(%s), 0)::bigint", number(field)))
+	}
+	cols = append(cols, "count(*)")
+
+	results = make([]int64, len(cols))
+	dest := make([]any, len(cols))
+	for i := range results {
+		dest[i] = &results[i]
+	}
+	err = c.pool.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, strings.Join(cols, ", "), table, where), q.args...).Scan(dest...)
+	return results, err
+}
+
+func (c *engine) Max(ctx context.Context, table, field string, filter *bson.D) (result int64, found bool, err error) {
+	defer observe(table, storage.OperationAggregate, time.Now(), &err)
+	ctx, cancel := withTimeout(ctx, c.timeouts.Query)
+	defer cancel()
+	if !fieldPattern.MatchString(field) {
+		return 0, false, fmt.Errorf("unsupported field `%s`", field)
+	}
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return 0, false, err
+	}
+	var value *int64
+	err = c.pool.QueryRow(ctx, fmt.Sprintf(`SELECT max(%s)::bigint FROM %s WHERE %s`, number(field), table, where), q.args...).Scan(&value)
+	if err != nil || value == nil {
+		return 0, false, err
+	}
+	return *value, true, nil
+}
+
+// Group groups the documents in a subquery, filtered and paged like a table.
+func (c *engine) Group(ctx context.Context, table string, g docstore.Group, filter *bson.D, opts *options.FindOptions) ([][]byte, int64, error) {
+	fields := make([]string, 0, 2*(len(g.By)+len(g.Sums)+1))
+	keys := make([]string, 0, len(g.By))
+	order := make(bson.D, 0, len(g.By))
+	for _, b := range g.By {
+		if !fieldPattern.MatchString(b.Name) || !fieldPattern.MatchString(b.Field) {
+			return nil, 0, fmt.Errorf("unsupported bucket `%s`", b.Name)
+		}
+		key := fmt.Sprintf("(floor((%s * %d + %d) / %d) * %d)::bigint",
+			number(b.Field), orOne(b.Scale), b.Offset, orOne(b.Width), orOne(b.Unit))
+		fields = append(fields, "'"+b.Name+"'", key)
+		keys = append(keys, key)
+		order = append(order, bson.E{Key: b.Name, Value: 1})
+	}
+	fields = append(fields, "'count'", "count(*)")
+	for _, field := range g.Sums {
+		if !fieldPattern.MatchString(field) {
+			return nil, 0, fmt.Errorf("unsupported field `%s`", field)
+		}
+		fields = append(fields, "'"+field+"'", fmt.Sprintf("coalesce(sum(%s), 0)::bigint", number(field)))
+	}
+	from := fmt.Sprintf(`(SELECT jsonb_build_object(%s) AS doc FROM %s GROUP BY %s) AS grouped`,
+		strings.Join(fields, ", "), table, strings.Join(keys, ", "))
+	page := options.Find().SetSort(order)
+	if opts != nil {
+		page.Skip, page.Limit = opts.Skip, opts.Limit
+		if opts.Sort != nil {
+			page.Sort = opts.Sort
+		}
+	}
+	return c.findPage(ctx, table, from, filter, page)
+}
+
+func orOne(v int64) int64 {
+	if v == 0 {
+		return 1
+	}
+	return v
+}
+
+// FindByRef orders the documents by a correlated subquery on the referencing table.
+func (c *engine) FindByRef(ctx context.Context, table string, filter *bson.D, ref docstore.Ref, opts *options.FindOptions) ([][]byte, error) {
+	if !fieldPattern.MatchString(ref.Local) || !fieldPattern.MatchString(ref.Field) {
+		return nil, fmt.Errorf("unsupported reference `%s`", ref.Field)
+	}
+	q := &query{}
+	where, err := q.where(filter)
+	if err != nil {
+		return nil, err
+	}
+	conds := make([]string, 0, len(ref.Keys))
+	for _, key := range ref.Keys {
+		conds = append(conds, fmt.Sprintf("r.doc @> jsonb_build_object(%s::text, a.%s)", q.arg(key), path(ref.Local)))
+	}
+	var tail string
+	if opts != nil {
+		page := options.Find()
+		page.Skip, page.Limit = opts.Skip, opts.Limit
+		if tail, err = q.orderBy(page); err != nil {
+			return nil, err
+		}
+	}
+	return c.findSQL(ctx, table, fmt.Sprintf(`SELECT a.doc::text FROM %[1]s a WHERE %[2]s
+		ORDER BY (SELECT min((r.%[3]s)::numeric) FROM %[4]s r WHERE %[5]s) DESC NULLS LAST %[6]s`,
+		table, where, path(ref.Field), ref.Table, strings.Join(conds, " OR "), tail), q.args...)
+}
+
+// Search ranks the matches of the full-text index.
+func (c *engine) Search(ctx context.Context, table, field, text string, limit int64) ([][]byte, error) {
+	if !fieldPattern.MatchString(field) {
+		return nil, fmt.Errorf("unsupported field `%s`", field)
+	}
+	tsv := fmt.Sprintf("to_tsvector('simple', %s)", textPath(field))
+	return c.findSQL(ctx, table, fmt.Sprintf(`SELECT doc::text FROM %[1]s
+		WHERE %[2]s @@ plainto_tsquery('simple', $1)
+		ORDER BY ts_rank(%[2]s, plainto_tsquery('simple', $1)) DESC LIMIT $2`, table, tsv), text, limit)
+}
+
+// FindNear selects the candidates by the latitude index, then measures them with the haversine
+// formula.
+func (c *engine) FindNear(ctx context.Context, table, field string, lon, lat, radius float64, limit int64) ([][]byte, error) {
+	if !fieldPattern.MatchString(field) {
+		return nil, fmt.Errorf("unsupported field `%s`", field)
+	}
+	lonExpr, latExpr := coordinates(field)
+	delta := radius / docstore.EarthRadius * 180 / math.Pi
+	return c.findSQL(ctx, table, fmt.Sprintf(`SELECT doc::text FROM (
+			SELECT doc, 2 * $4 * asin(sqrt(power(sin(radians(%[2]s - $2) / 2), 2) +
+				cos(radians($2)) * cos(radians(%[2]s)) * power(sin(radians(%[1]s - $1) / 2), 2))) AS distance
+			FROM %[3]s WHERE %[2]s BETWEEN $2::float8 - $5 AND $2::float8 + $5
+		) AS measured WHERE distance <= $3 ORDER BY distance LIMIT $6`, lonExpr, latExpr, table),
+		lon, lat, radius, docstore.EarthRadius, delta, limit)
+}
+
+func (c *engine) FindWithin(ctx context.Context, table, field string, box model.GeoBox, limit int64) ([][]byte, error) {
+	if !fieldPattern.MatchString(field) {
+		return nil, fmt.Errorf("unsupported field `%s`", field)
+	}
+	lonExpr, latExpr := coordinates(field)
+	return c.findSQL(ctx, table, fmt.Sprintf(`SELECT doc::text FROM %[3]s
+		WHERE %[2]s BETWEEN $2 AND $4 AND %[1]s BETWEEN $1 AND $3 LIMIT $5`, lonExpr, latExpr, table),
+		box.MinLon, box.MinLat, box.MaxLon, box.MaxLat, limit)
+}
+
+// Stats returns the estimates of the statistics collector, the insertion times are not recorded.
+func (c *engine) Stats(parent context.Context) ([]*model.CollectionStats, error) {
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	ctx, cancel := withTimeout(parent, c.timeouts.Query)
+	defer cancel()
+	rows, err := c.pool.Query(ctx, `SELECT relname, n_live_tup, pg_table_size(relid), pg_indexes_size(relid)
+		FROM pg_stat_user_tables WHERE relname = ANY($1) ORDER BY relname`, names)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (*model.CollectionStats, error) {
+		var s model.CollectionStats
+		err := row.Scan(&s.Collection, &s.Documents, &s.StorageSize, &s.IndexSize)
+		return &s, err
+	})
+}
+
+// observe records the metrics of a statement on the table, see storage.ObserveOperation.
+func observe(table, operation string, start time.Time, err *error) {
+	storage.ObserveOperation(table, operation, start, *err)
+}
+
+// isTransient reports whether the statement failed because of the connection or a server
+// shutdown, and may succeed if retried.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// class 08 is connection exception, 57P01 to 57P03 are admin_shutdown, crash_shutdown and
+		// cannot_connect_now
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	return false
+}
+
+// number returns the SQL expression of a numeric document field.
+func number(field string) string {
+	return "(" + path(field) + ")::numeric"
+}
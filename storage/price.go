@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// pricesCollection holds the SMH prices recorded from the price feed, see pricefeed.Record.
+const pricesCollection = "prices"
+
+func (s *Storage) initPricesStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, pricesCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, pricesCollection)
+}
+
+// UpsertPrice stores a price recorded from the price feed, replacing the one of the same time.
+func (s *Storage) UpsertPrice(parent context.Context, price *model.Price) error {
+	if err := price.Validate(); err != nil {
+		return err
+	}
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	_, err := s.db.Collection(pricesCollection).ReplaceOne(ctx, bson.D{{Key: "timestamp", Value: price.Timestamp}}, price,
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error save price: %w", err)
+	}
+	return nil
+}
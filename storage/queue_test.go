@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacemeshos/explorer-backend/internal/pipeline"
+)
+
+func queueDepth(t *testing.T, stage string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "explorer_pipeline_queue_depth" {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "stage" && label.GetValue() == stage {
+					return m.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	t.Fatalf("no queue depth for stage %s", stage)
+	return 0
+}
+
+func TestLayersQueue(t *testing.T) {
+	s := &Storage{
+		layersQueue:   make(chan *pb.Layer, layersQueueSize),
+		layersPending: make(map[uint32]int),
+	}
+	layer := &pb.Layer{Number: &pb.LayerNumber{Number: 7}}
+	s.pushLayer(layer)
+	s.pushLayer(layer)
+	s.pushLayer(&pb.Layer{Number: &pb.LayerNumber{Number: 8}})
+	require.Equal(t, 3, s.LayersInQueue())
+	require.Equal(t, float64(3), testutil.ToFloat64(metricLayersQueueLen))
+	require.Equal(t, float64(3), queueDepth(t, pipeline.StageWriteLayer))
+
+	// the layer pushed again stays in the queue until its second copy is processed
+	s.layerProcessed(7)
+	require.True(t, s.IsLayerInQueue(layer))
+	require.Equal(t, 2, s.LayersInQueue())
+	s.layerProcessed(7)
+	require.False(t, s.IsLayerInQueue(layer))
+	s.layerProcessed(8)
+	require.Equal(t, 0, s.LayersInQueue())
+	require.Equal(t, float64(0), queueDepth(t, pipeline.StageWriteLayer))
+}
+
+func TestAccountsQueue(t *testing.T) {
+	s := &Storage{accountsQueue: make(map[uint32]map[string]bool)}
+	s.accountsReady = sync.NewCond(&sync.Mutex{})
+	s.NetworkInfo.LastConfirmedLayer = 10
+	s.requestBalanceUpdate(5, "a")
+	s.requestBalanceUpdate(9, "a")
+	s.requestBalanceUpdate(9, "b")
+	require.Equal(t, float64(3), queueDepth(t, pipeline.StageAccountBalances))
+
+	accounts := make(map[string]uint32)
+	require.Equal(t, 2, s.getAccountsQueue(accounts))
+	require.Equal(t, map[string]uint32{"a": 9, "b": 9}, accounts)
+	// the gauge reports what is left in the queue, not the batch just dequeued
+	require.Equal(t, float64(0), queueDepth(t, pipeline.StageAccountBalances))
+}
+
+func TestStopLoops(t *testing.T) {
+	s := &Storage{
+		layersQueue:   make(chan *pb.Layer, layersQueueSize),
+		layersPending: make(map[uint32]int),
+		accountsQueue: make(map[uint32]map[string]bool),
+		accountsReady: sync.NewCond(&sync.Mutex{}),
+		layersDone:    make(chan struct{}),
+	}
+	s.accountsLoop.Add(1)
+	go s.updateAccounts()
+	s.layersLoop.Add(1)
+	go s.updateLayers()
+	// not confirmed yet, so it is dropped from the queue without a database
+	s.requestBalanceUpdate(5, "a")
+
+	stopped := make(chan struct{})
+	go func() {
+		s.stopLoops()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the loops did not stop")
+	}
+	require.Zero(t, s.accountsQueueLen())
+	// a second Close does not stop them again
+	s.stopLoops()
+}
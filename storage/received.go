@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// ReceivedTimes holds the times the node first received the transactions of the layers not stored
+// yet, until the transactions are stored with them. The zero value is ready to use.
+type ReceivedTimes struct {
+	mu    sync.Mutex
+	times map[string]uint32
+}
+
+// Add holds the received times, by transaction id.
+func (r *ReceivedTimes) Add(received map[string]uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.times == nil {
+		r.times = make(map[string]uint32, len(received))
+	}
+	for id, timestamp := range received {
+		r.times[id] = timestamp
+	}
+}
+
+// Apply sets the held received times on the transactions and releases them.
+func (r *ReceivedTimes) Apply(txs map[string]*model.Transaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, tx := range txs {
+		if timestamp, ok := r.times[id]; ok {
+			tx.Received = timestamp
+			delete(r.times, id)
+		}
+	}
+}
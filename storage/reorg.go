@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// NotOrphaned matches the documents which were not removed from their layer by a reorg.
+var NotOrphaned = bson.E{Key: "orphaned", Value: bson.D{{Key: "$ne", Value: true}}}
+
+// OnlyNotOrphaned reports whether the query matches every document which was not orphaned, that
+// is whether it is NotOrphaned alone. Such queries are counted from the collection metadata minus
+// the orphaned counters of the totals.
+func OnlyNotOrphaned(query *bson.D) bool {
+	return query != nil && len(*query) == 1 && reflect.DeepEqual((*query)[0], NotOrphaned)
+}
+
+// LayerReorged reports whether a layer received again with the hash `current` replaces the stored
+// one with the hash `previous`. Layers without a hash yet are not reorged.
+func LayerReorged(previous, current string) bool {
+	return !emptyHash(previous) && !emptyHash(current) && previous != current
+}
+
+func emptyHash(hash string) bool {
+	return strings.Trim(strings.TrimPrefix(hash, "0x"), "0") == ""
+}
+
+// OrphanedFilter matches the documents of the layer which are not part of its new content.
+func OrphanedFilter(layer uint32, kept []string) bson.D {
+	return bson.D{
+		{Key: "layer", Value: layer},
+		{Key: "id", Value: bson.D{{Key: "$nin", Value: kept}}},
+		NotOrphaned,
+	}
+}
+
+// RestoredFilter matches the orphaned documents which are part of a layer again.
+func RestoredFilter(kept []string) bson.D {
+	return bson.D{
+		{Key: "id", Value: bson.D{{Key: "$in", Value: kept}}},
+		{Key: "orphaned", Value: true},
+	}
+}
+
+// layerHash returns the stored hash of the layer, empty if the layer is not stored.
+func (s *Storage) layerHash(parent context.Context, number uint32) (string, error) {
+	var layer struct {
+		Hash string `bson:"hash"`
+	}
+	err := s.findOne(parent, "layers", bson.D{{Key: "number", Value: number}}, &layer,
+		options.FindOne().SetProjection(bson.D{{Key: "hash", Value: 1}}))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	return layer.Hash, err
+}
+
+// tombstoneLayer marks the blocks and transactions no longer included in the reorged layer as
+// orphaned instead of deleting them, and restores the ones included again. The orphaned counters
+// of the totals follow the tombstones, see OnlyNotOrphaned.
+func (s *Storage) tombstoneLayer(parent context.Context, layer *model.Layer, blocks []*model.Block, txs map[string]*model.Transaction) error {
+	blockIds := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		blockIds = append(blockIds, block.Id)
+	}
+	txIds := make([]string, 0, len(txs))
+	for id := range txs {
+		txIds = append(txIds, id)
+	}
+	tombstone := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "orphaned", Value: true},
+		{Key: "supersededBy", Value: layer.Hash},
+	}}}
+	restore := bson.D{{Key: "$unset", Value: bson.D{
+		{Key: "orphaned", Value: ""},
+		{Key: "supersededBy", Value: ""},
+	}}}
+
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	orphanedTotals := bson.D{}
+	for collection, ids := range map[string][]string{"blocks": blockIds, "txs": txIds} {
+		res, err := s.db.Collection(collection).UpdateMany(ctx, OrphanedFilter(layer.Number, ids), tombstone)
+		if err != nil {
+			return fmt.Errorf("error tombstone `%s`: %w", collection, err)
+		}
+		orphaned := res.ModifiedCount
+		if res, err = s.db.Collection(collection).UpdateMany(ctx, RestoredFilter(ids), restore); err != nil {
+			return fmt.Errorf("error restore `%s`: %w", collection, err)
+		}
+		logging.Info("layer reorged", logging.Layer(layer.Number), zap.String("hash", layer.Hash), logging.Collection(collection), zap.Int64("orphaned", orphaned), zap.Int64("restored", res.ModifiedCount))
+		if n := orphaned - res.ModifiedCount; n != 0 {
+			orphanedTotals = append(orphanedTotals, bson.E{Key: "orphaned." + collection, Value: n})
+		}
+	}
+	if len(orphanedTotals) > 0 {
+		s.incTotals(parent, orphanedTotals)
+	}
+	return nil
+}
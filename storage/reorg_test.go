@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestLayerReorged(t *testing.T) {
+	for _, tc := range []struct {
+		previous, current string
+		reorged           bool
+	}{
+		{"", "0xaa", false},
+		{"0x0000", "0xaa", false},
+		{"0xaa", "0x", false},
+		{"0xaa", "0xaa", false},
+		{"0xaa", "0xbb", true},
+	} {
+		require.Equal(t, tc.reorged, LayerReorged(tc.previous, tc.current), "%s -> %s", tc.previous, tc.current)
+	}
+}
+
+func TestOnlyNotOrphaned(t *testing.T) {
+	require.False(t, OnlyNotOrphaned(nil))
+	require.False(t, OnlyNotOrphaned(&bson.D{}))
+	require.True(t, OnlyNotOrphaned(&bson.D{NotOrphaned}))
+	require.True(t, OnlyNotOrphaned(&bson.D{{Key: "orphaned", Value: bson.D{{Key: "$ne", Value: true}}}}))
+	require.False(t, OnlyNotOrphaned(&bson.D{{Key: "orphaned", Value: true}}))
+	require.False(t, OnlyNotOrphaned(&bson.D{{Key: "layer", Value: 12}, NotOrphaned}))
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+const (
+	// RetentionInterval is the period of the retention runs.
+	RetentionInterval = time.Hour
+	// RetentionPause is the pause between two pruning batches, so that the pruning does not
+	// starve the collector and the API queries.
+	RetentionPause = 100 * time.Millisecond
+)
+
+var metricRetentionPruned = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "explorer_retention_pruned_documents",
+	Help: "Number of documents removed or stripped by the retention policies",
+}, []string{"collection"})
+
+// RetentionCollections are the collections retention policies apply to. Aggregates (layers,
+// epochs, accounts, smeshers) are kept forever.
+var RetentionCollections = map[string]bool{
+	"txs":                true,
+	"rewards":            true,
+	"activations":        true,
+	"blocks":             true,
+	"malfeasance_proofs": true,
+	"certificates":       true,
+	"ballots":            true,
+}
+
+// RecordPruned counts the documents removed or stripped by a retention policy.
+func RecordPruned(collection string, n int64) {
+	metricRetentionPruned.WithLabelValues(collection).Add(float64(n))
+}
+
+// RetentionPolicy removes the documents of Collection older than MaxAge. When Field is set the
+// documents are kept and only the field is removed, e.g. the raw payload of old transactions.
+// The age of a document is the age of its layer.
+type RetentionPolicy struct {
+	Collection string
+	Field      string
+	MaxAge     time.Duration
+}
+
+func (p RetentionPolicy) String() string {
+	if p.Field != "" {
+		return fmt.Sprintf("%s.%s=%s", p.Collection, p.Field, p.MaxAge)
+	}
+	return fmt.Sprintf("%s=%s", p.Collection, p.MaxAge)
+}
+
+// ParseRetentionPolicy parses a policy in format <collection>[.<field>]=<age>, the age is a
+// duration with an optional `d` (days) unit, e.g. txs.raw=180d or rewards=8760h.
+func ParseRetentionPolicy(in string) (RetentionPolicy, error) {
+	target, age, ok := strings.Cut(in, "=")
+	if !ok {
+		return RetentionPolicy{}, fmt.Errorf("invalid retention policy `%s`", in)
+	}
+	var p RetentionPolicy
+	p.Collection, p.Field, _ = strings.Cut(target, ".")
+	if !RetentionCollections[p.Collection] {
+		return RetentionPolicy{}, fmt.Errorf("retention is not supported for `%s`", p.Collection)
+	}
+	var err error
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		p.MaxAge = time.Duration(n) * 24 * time.Hour
+	} else {
+		p.MaxAge, err = time.ParseDuration(age)
+	}
+	if err != nil || p.MaxAge <= 0 {
+		return RetentionPolicy{}, fmt.Errorf("invalid retention age `%s`", age)
+	}
+	return p, nil
+}
+
+// RetentionCutoff returns the first layer kept by the policy, or false if the policy does not
+// apply yet.
+func RetentionCutoff(p RetentionPolicy, lastLayer uint32, layerDuration uint32) (uint32, bool) {
+	if layerDuration == 0 {
+		return 0, false
+	}
+	layers := uint64(p.MaxAge / (time.Duration(layerDuration) * time.Second))
+	if layers >= uint64(lastLayer) {
+		return 0, false
+	}
+	return lastLayer - uint32(layers), true
+}
+
+// SetRetention starts pruning the documents outside the retention policies every RetentionInterval.
+func (s *Storage) SetRetention(policies []RetentionPolicy) {
+	if len(policies) == 0 {
+		return
+	}
+	s.retentionDone = make(chan struct{})
+	go s.runRetention(policies)
+}
+
+func (s *Storage) runRetention(policies []RetentionPolicy) {
+	ticker := time.NewTicker(RetentionInterval)
+	defer ticker.Stop()
+	for {
+		s.applyRetention(policies, s.NetworkInfo.LastLayer, s.NetworkInfo.LayerDuration)
+		select {
+		case <-ticker.C:
+		case <-s.retentionDone:
+			return
+		}
+	}
+}
+
+// Prune applies the retention policies once to the layers stored, for the prune command, and
+// returns the number of documents removed or stripped.
+func (s *Storage) Prune(ctx context.Context, policies []RetentionPolicy) (int64, error) {
+	info, err := s.GetNetworkInfo(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error get network info: %w", err)
+	}
+	return s.applyRetention(policies, info.LastLayer, info.LayerDuration), nil
+}
+
+// applyRetention prunes the documents outside the policies at the last layer, the errors are logged
+// and do not stop the other policies.
+func (s *Storage) applyRetention(policies []RetentionPolicy, lastLayer uint32, layerDuration uint32) int64 {
+	var total int64
+	for _, p := range policies {
+		before, ok := RetentionCutoff(p, lastLayer, layerDuration)
+		if !ok {
+			continue
+		}
+		n, err := s.prune(p, before)
+		if err != nil {
+			logging.Error(fmt.Sprintf("retention %s", p), err)
+		}
+		if n > 0 {
+			logging.Info("retention", zap.Stringer("policy", p), zap.Int64("pruned", n), logging.Layer(before))
+		}
+		total += n
+	}
+	return total
+}
+
+// prune removes or strips the documents of layers before the given one by batches of
+// bulkWriteBatchSize documents, pausing between the batches.
+func (s *Storage) prune(p RetentionPolicy, before uint32) (int64, error) {
+	coll := s.db.Collection(p.Collection)
+	filter := bson.D{{Key: "layer", Value: bson.D{{Key: "$lt", Value: before}}}}
+	if p.Field != "" {
+		filter = append(filter, bson.E{Key: p.Field, Value: bson.D{{Key: "$exists", Value: true}}})
+	}
+
+	var total int64
+	for {
+		n, err := pruneBatch(coll, filter, p.Field)
+		total += n
+		RecordPruned(p.Collection, n)
+		if err != nil || n == 0 {
+			return total, err
+		}
+		select {
+		case <-time.After(RetentionPause):
+		case <-s.retentionDone:
+			return total, nil
+		}
+	}
+}
+
+func pruneBatch(coll *mongo.Collection, filter bson.D, field string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}).SetLimit(bulkWriteBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var docs []struct {
+		ID any `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil || len(docs) == 0 {
+		return 0, err
+	}
+	ids := make(bson.A, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+
+	byIds := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+	if field == "" {
+		res, err := coll.DeleteMany(ctx, byIds)
+		if err != nil {
+			return 0, err
+		}
+		return res.DeletedCount, nil
+	}
+	res, err := coll.UpdateMany(ctx, byIds, bson.D{{Key: "$unset", Value: bson.D{{Key: field, Value: ""}}}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
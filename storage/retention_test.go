@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	table := []struct {
+		in     string
+		policy RetentionPolicy
+		err    bool
+	}{
+		{in: "rewards=8760h", policy: RetentionPolicy{Collection: "rewards", MaxAge: 8760 * time.Hour}},
+		{in: "txs.raw=180d", policy: RetentionPolicy{Collection: "txs", Field: "raw", MaxAge: 180 * 24 * time.Hour}},
+		{in: "layers=30d", err: true},
+		{in: "txs", err: true},
+		{in: "txs=0s", err: true},
+		{in: "txs=1w", err: true},
+	}
+	for _, tc := range table {
+		t.Run(tc.in, func(t *testing.T) {
+			policy, err := ParseRetentionPolicy(tc.in)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.policy, policy)
+		})
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	policy := RetentionPolicy{Collection: "txs", MaxAge: time.Hour}
+
+	_, ok := RetentionCutoff(policy, 100, 0)
+	require.False(t, ok)
+
+	_, ok = RetentionCutoff(policy, 10, 300)
+	require.False(t, ok)
+
+	before, ok := RetentionCutoff(policy, 100, 300)
+	require.True(t, ok)
+	require.Equal(t, uint32(88), before)
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	drivertopology "go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+var (
+	metricOperationRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_storage_operation_retries",
+		Help: "Number of storage operations retried after a transient error",
+	}, []string{"collection", "operation"})
+	metricOperationRetriesExhausted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "explorer_storage_operation_retries_exhausted",
+		Help: "Number of storage operations still failing with a transient error after the last retry",
+	}, []string{"collection", "operation"})
+)
+
+// RetryPolicy bounds the retries of the storage reads failing with a transient error: the counts,
+// the finds and the point reads going through countDocuments, findAll and findOne. The backoff
+// doubles after every attempt, from MinBackoff up to MaxBackoff, with a random jitter so that the
+// retries of concurrent operations are spread.
+//
+// The writes are not retried by the policy. Many of them are not idempotent, e.g. the $inc of the
+// totals and the version bump of UpdateAccount, and a write acknowledged by a primary stepping
+// down would be applied twice. The idempotent upserts rely on the retryable writes of the driver,
+// which retries a single write once on a replica set state change.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts of an operation, 1 disables the retries.
+	Attempts   int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy rides out a primary election, which usually completes within 10 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   6,
+	MinBackoff: 200 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// transientCodes are the server error codes of a replica set member stepping down, shutting down
+// or unreachable.
+var transientCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+// IsTransient reports whether the operation failed because of the network or a replica set state
+// change, and may succeed if retried. Timeouts of the operation itself are not transient: a retry
+// would only add load to a busy server.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var selection drivertopology.ServerSelectionError
+	if errors.As(err, &selection) {
+		return true
+	}
+	var server mongo.ServerError
+	if !errors.As(err, &server) {
+		return false
+	}
+	if server.HasErrorLabel("TransientTransactionError") || server.HasErrorLabel("RetryableWriteError") {
+		return true
+	}
+	for _, code := range transientCodes {
+		if server.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the pause before the retry following the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff << (attempt - 1)
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// equal jitter: at least half of the backoff, so that the pause keeps growing
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Do runs fn until it succeeds, fails with an error which is not transient or the attempts are
+// exhausted. It returns the last error of fn.
+func (p RetryPolicy) Do(ctx context.Context, collection, operation string, transient func(error) bool, fn func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !transient(err) {
+			return err
+		}
+		if attempt >= p.Attempts || ctx.Err() != nil {
+			if attempt > 1 {
+				metricOperationRetriesExhausted.WithLabelValues(collection, operation).Inc()
+			}
+			return err
+		}
+		backoff := p.backoff(attempt)
+		logging.Warn("transient error, retrying", zap.String("operation", operation), logging.Collection(collection),
+			zap.Int("attempt", attempt), zap.Int("retries", p.Attempts-1), zap.Duration("backoff", backoff), zap.Error(err))
+		metricOperationRetries.WithLabelValues(collection, operation).Inc()
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// SetRetryPolicy sets the retries of the reads failing with a transient error, see RetryPolicy.
+func (s *Storage) SetRetryPolicy(p RetryPolicy) {
+	s.retryPolicy = p
+}
+
+// retry runs fn with the retry policy, fn must bound each attempt with its own timeout.
+func (s *Storage) retry(parent context.Context, collection, operation string, fn func(ctx context.Context) error) error {
+	return s.retryPolicy.Do(parent, collection, operation, IsTransient, fn)
+}
+
+// countDocuments counts the documents of the collection matching the query.
+func (s *Storage) countDocuments(parent context.Context, collection string, query any, opts ...*options.CountOptions) (count int64, err error) {
+	err = s.retry(parent, collection, OperationCount, func(parent context.Context) error {
+		ctx, cancel := s.queryContext(parent)
+		defer cancel()
+		count, err = s.collection(collection).CountDocuments(ctx, query, opts...)
+		return err
+	})
+	return count, err
+}
+
+// findAll decodes the documents of the collection matching the query into out.
+func (s *Storage) findAll(parent context.Context, collection string, query any, out any, opts ...*options.FindOptions) error {
+	return s.retry(parent, collection, OperationFind, func(parent context.Context) error {
+		ctx, cancel := s.queryContext(parent)
+		defer cancel()
+		cursor, err := s.collection(collection).Find(ctx, query, opts...)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, out)
+	})
+}
+
+// findOne decodes the first document of the collection matching the query into out, it returns
+// mongo.ErrNoDocuments if no document matches.
+func (s *Storage) findOne(parent context.Context, collection string, query any, out any, opts ...*options.FindOneOptions) error {
+	return s.retry(parent, collection, OperationFind, func(parent context.Context) error {
+		ctx, cancel := s.queryContext(parent)
+		defer cancel()
+		return s.collection(collection).FindOne(ctx, query, opts...).Decode(out)
+	})
+}
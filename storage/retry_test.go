@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestIsTransient(t *testing.T) {
+	require.False(t, IsTransient(nil))
+	require.False(t, IsTransient(errors.New("boom")))
+	require.False(t, IsTransient(context.Canceled))
+	require.False(t, IsTransient(context.DeadlineExceeded))
+	require.True(t, IsTransient(mongo.CommandError{Code: 189, Name: "PrimarySteppedDown"}))
+	require.True(t, IsTransient(fmt.Errorf("error count: %w", mongo.CommandError{Code: 11602})))
+	require.True(t, IsTransient(mongo.CommandError{Code: 1, Labels: []string{"RetryableWriteError"}}))
+	require.False(t, IsTransient(mongo.CommandError{Code: 11000, Name: "DuplicateKey"}))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Attempts: 10, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000, 1000} {
+		max *= time.Millisecond
+		d := p.backoff(attempt + 1)
+		require.GreaterOrEqual(t, d, max/2)
+		require.LessOrEqual(t, d, max)
+	}
+	require.Zero(t, RetryPolicy{Attempts: 2}.backoff(1))
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	p := RetryPolicy{Attempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	transient := mongo.CommandError{Code: 91, Name: "ShutdownInProgress", Message: "shutdown in progress"}
+
+	var calls int
+	err := p.Do(context.Background(), "test", OperationFind, IsTransient, func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = p.Do(context.Background(), "test", OperationFind, IsTransient, func(context.Context) error {
+		calls++
+		return transient
+	})
+	require.Equal(t, transient, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	failed := errors.New("boom")
+	err = p.Do(context.Background(), "test", OperationFind, IsTransient, func(context.Context) error {
+		calls++
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+	require.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = p.Do(ctx, "test", OperationFind, IsTransient, func(context.Context) error {
+		calls++
+		return transient
+	})
+	require.Equal(t, transient, err)
+	require.Equal(t, 1, calls)
+}
@@ -3,69 +3,53 @@ package storage
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) InitRewardsStorage(ctx context.Context) error {
-	models := []mongo.IndexModel{
-		{Keys: bson.D{{Key: "layer", Value: 1}}, Options: options.Index().SetName("layerIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "smesher", Value: 1}}, Options: options.Index().SetName("smesherIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "coinbase", Value: 1}}, Options: options.Index().SetName("coinbaseIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "layer", Value: 1}, {Key: "smesher", Value: 1}, {Key: "coinbase", Value: 1}}, Options: options.Index().SetName("rewardIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "layer", Value: 1}, {Key: "total", Value: 1}, {Key: "layerReward", Value: 1}}, Options: options.Index().SetName("layerRewards").SetUnique(false)},
-		{Keys: bson.D{{Key: "smesher", Value: 1}, {Key: "layer", Value: 1}}, Options: options.Index().SetName("keyIndex").SetUnique(true)},
-	}
-	_, err := s.db.Collection("rewards").Indexes().CreateMany(ctx, models, options.CreateIndexes().SetMaxTime(20*time.Second))
-	return err
+	if err := s.createIndexes(ctx, "rewards"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "rewards")
 }
 
 func (s *Storage) GetReward(parent context.Context, query *bson.D) (*model.Reward, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("rewards").Find(ctx, query)
-	if err != nil {
-		log.Info("GetReward: %v", err)
+	account := &model.Reward{}
+	if err := s.findOne(parent, "rewards", query, account); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetReward: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetReward", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetReward: Empty result")
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	account := &model.Reward{
-		Layer:         utils.GetAsUInt32(doc.Lookup("layer")),
-		Total:         utils.GetAsUInt64(doc.Lookup("total")),
-		LayerReward:   utils.GetAsUInt64(doc.Lookup("layerReward")),
-		LayerComputed: utils.GetAsUInt32(doc.Lookup("layerComputed")),
-		Coinbase:      utils.GetAsString(doc.Lookup("coinbase")),
-		Smesher:       utils.GetAsString(doc.Lookup("smesher")),
-		Timestamp:     utils.GetAsUInt32(doc.Lookup("timestamp")),
-	}
 	return account, nil
 }
 
 func (s *Storage) GetRewardsCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("rewards").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(parent, "rewards", query, opts...)
 	if err != nil {
-		log.Info("GetRewardsCount: %v", err)
+		logging.Error("GetRewardsCount", err)
 		return 0
 	}
 	return count
 }
 
+// rewardsSum is the result of the rewards aggregations.
+type rewardsSum struct {
+	Total int64 `bson:"total"`
+	Count int64 `bson:"count"`
+}
+
 func (s *Storage) GetLayersRewards(parent context.Context, layerStart uint32, layerEnd uint32) (int64, int64) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.aggregateContext(parent)
 	defer cancel()
 	matchStage := bson.D{
 		{Key: "$match", Value: bson.D{
@@ -86,24 +70,32 @@ func (s *Storage) GetLayersRewards(parent context.Context, layerStart uint32, la
 			}},
 		}},
 	}
-	cursor, err := s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+	cursor, err := s.db.Collection(s.aggregateSource("rewards")).Aggregate(ctx, mongo.Pipeline{
 		matchStage,
 		groupStage,
 	})
 	if err != nil {
-		log.Info("GetLayersRewards: %v", err)
+		logging.Error("GetLayersRewards", err)
 		return 0, 0
 	}
 	if !cursor.Next(ctx) {
-		log.Info("GetLayersRewards: Empty result")
+		if err := cursor.Err(); err != nil {
+			logging.Error("GetLayersRewards", err)
+			return 0, 0
+		}
+		logging.Info("GetLayersRewards: Empty result")
 		return 0, 0
 	}
-	doc := cursor.Current
-	return utils.GetAsInt64(doc.Lookup("total")), utils.GetAsInt64(doc.Lookup("count"))
+	var sum rewardsSum
+	if err := cursor.Decode(&sum); err != nil {
+		logging.Error("GetLayersRewards", err)
+		return 0, 0
+	}
+	return sum.Total, sum.Count
 }
 
 func (s *Storage) GetSmesherRewards(parent context.Context, smesher string) (int64, int64) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.aggregateContext(parent)
 	defer cancel()
 	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "smesher", Value: smesher}}}}
 	groupStage := bson.D{
@@ -125,29 +117,30 @@ func (s *Storage) GetSmesherRewards(parent context.Context, smesher string) (int
 		groupStage,
 	})
 	if err != nil {
-		log.Info("GetSmesherRewards: %v", err)
+		logging.Error("GetSmesherRewards", err)
 		return 0, 0
 	}
 	if !cursor.Next(ctx) {
-		log.Info("GetSmesherRewards: Empty result")
+		if err := cursor.Err(); err != nil {
+			logging.Error("GetSmesherRewards", err)
+			return 0, 0
+		}
+		logging.Info("GetSmesherRewards: Empty result")
+		return 0, 0
+	}
+	var sum rewardsSum
+	if err := cursor.Decode(&sum); err != nil {
+		logging.Error("GetSmesherRewards", err)
 		return 0, 0
 	}
-	doc := cursor.Current
-	return utils.GetAsInt64(doc.Lookup("total")), utils.GetAsInt64(doc.Lookup("count"))
+	return sum.Total, sum.Count
 }
 
 func (s *Storage) GetRewards(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("rewards").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetRewards: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "rewards", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetRewards: %v", err)
+		logging.Error("GetRewards", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
@@ -156,23 +149,170 @@ func (s *Storage) GetRewards(parent context.Context, query *bson.D, opts ...*opt
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) SaveReward(parent context.Context, in *model.Reward) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertReward stores the reward, see UpsertRewards.
+func (s *Storage) UpsertReward(parent context.Context, in *model.Reward) error {
+	return s.UpsertRewards(parent, []*model.Reward{in})
+}
+
+// UpsertRewards stores the rewards keyed on their smesher and layer with a single unordered bulk
+// write, only the rewards not stored yet are counted in the statistics.
+func (s *Storage) UpsertRewards(parent context.Context, rewards []*model.Reward) error {
+	if len(rewards) == 0 {
+		return nil
+	}
+	ctx, cancel := s.bulkContext(parent)
 	defer cancel()
-	_, err := s.db.Collection("rewards").UpdateOne(ctx, bson.D{{Key: "smesher", Value: in.Smesher}, {Key: "layer", Value: in.Layer}}, bson.D{{
-		Key: "$set",
-		Value: bson.D{
-			{Key: "layer", Value: in.Layer},
-			{Key: "total", Value: in.Total},
-			{Key: "layerReward", Value: in.LayerReward},
-			{Key: "layerComputed", Value: in.LayerComputed},
-			{Key: "coinbase", Value: in.Coinbase},
-			{Key: "smesher", Value: in.Smesher},
-			{Key: "timestamp", Value: in.Timestamp},
-		},
-	}}, options.Update().SetUpsert(true))
+
+	models := make([]mongo.WriteModel, 0, len(rewards))
+	for _, reward := range rewards {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(rewardFilter(reward)).
+			SetUpdate(rewardUpdate(reward)).
+			SetUpsert(true))
+	}
+	res, err := s.db.Collection("rewards").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
 	if err != nil {
-		log.Info("SaveReward: %v", err)
+		logging.Error("UpsertRewards", err)
+	}
+	// in watch mode the rewards of the whole chain are counted by saveChainRewards
+	if s.watched == nil {
+		added := upserted(rewards, res)
+		s.incRewardsStats(parent, added)
+		for _, layer := range RewardLayers(added) {
+			s.updateLayerSummary(layer)
+		}
 	}
 	return err
 }
+
+func rewardFilter(in *model.Reward) bson.D {
+	return bson.D{{Key: "smesher", Value: in.Smesher}, {Key: "layer", Value: in.Layer}}
+}
+
+func rewardUpdate(in *model.Reward) bson.D {
+	set := bson.D{
+		{Key: "layer", Value: in.Layer},
+		{Key: "total", Value: in.Total},
+		{Key: "layerReward", Value: in.LayerReward},
+		{Key: "layerComputed", Value: in.LayerComputed},
+		{Key: "coinbase", Value: in.Coinbase},
+		{Key: "smesher", Value: in.Smesher},
+		{Key: "timestamp", Value: in.Timestamp},
+	}
+	// a reward stored before its activation keeps the link set by linkActivationRewards
+	if in.Atx != "" {
+		set = append(set, bson.E{Key: "atx", Value: in.Atx})
+	}
+	return bson.D{{Key: "$set", Value: set}}
+}
+
+// rewardActivationKey keys the activations by smesher and target epoch.
+func rewardActivationKey(smesher string, epoch uint32) string {
+	return fmt.Sprintf("%s-%d", smesher, epoch)
+}
+
+// RewardLayers returns the distinct layers of the rewards, in order of first appearance.
+func RewardLayers(rewards []*model.Reward) []uint32 {
+	seen := make(map[uint32]bool, len(rewards))
+	layers := make([]uint32, 0, len(rewards))
+	for _, reward := range rewards {
+		if !seen[reward.Layer] {
+			seen[reward.Layer] = true
+			layers = append(layers, reward.Layer)
+		}
+	}
+	return layers
+}
+
+// linkRewardActivations sets the activation which earned each reward, the activation of its
+// smesher targeting the epoch of its layer. The rewards of unknown activations are left unlinked.
+func (s *Storage) linkRewardActivations(ctx context.Context, rewards []*model.Reward) error {
+	epochNumLayers := s.GetEpochNumLayers()
+	if epochNumLayers == 0 || len(rewards) == 0 {
+		return nil
+	}
+	smeshers := make([]string, 0, len(rewards))
+	epochs := make([]uint32, 0, len(rewards))
+	for _, reward := range rewards {
+		smeshers = append(smeshers, reward.Smesher)
+		epochs = append(epochs, reward.Layer/epochNumLayers)
+	}
+	cursor, err := s.db.Collection("activations").Find(ctx, bson.D{
+		{Key: "smesher", Value: bson.D{{Key: "$in", Value: smeshers}}},
+		{Key: "targetEpoch", Value: bson.D{{Key: "$in", Value: epochs}}},
+	}, options.Find().SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "smesher", Value: 1}, {Key: "targetEpoch", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	var atxs []*model.Activation
+	if err := cursor.All(ctx, &atxs); err != nil {
+		return err
+	}
+	ids := make(map[string]string, len(atxs))
+	for _, atx := range atxs {
+		ids[rewardActivationKey(atx.SmesherId, atx.TargetEpoch)] = atx.Id
+	}
+	for i, reward := range rewards {
+		reward.Atx = ids[rewardActivationKey(reward.Smesher, epochs[i])]
+	}
+	return nil
+}
+
+// linkActivationRewards links the rewards stored before their activation, see linkRewardActivations.
+func (s *Storage) linkActivationRewards(ctx context.Context, atxs []*model.Activation, epochNumLayers uint32) error {
+	if epochNumLayers == 0 || len(atxs) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(atxs))
+	for _, atx := range atxs {
+		models = append(models, mongo.NewUpdateManyModel().
+			SetFilter(bson.D{
+				{Key: "smesher", Value: atx.SmesherId},
+				{Key: "layer", Value: bson.D{
+					{Key: "$gte", Value: atx.TargetEpoch * epochNumLayers},
+					{Key: "$lt", Value: (atx.TargetEpoch + 1) * epochNumLayers},
+				}},
+				{Key: "atx", Value: bson.D{{Key: "$exists", Value: false}}},
+			}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "atx", Value: atx.Id}}}}))
+	}
+	_, err := s.db.Collection("rewards").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// backfillRewardActivations links the stored rewards to their activations, by batches of
+// bulkWriteBatchSize activations. Without network info no reward is stored yet.
+func (s *Storage) backfillRewardActivations(ctx context.Context) error {
+	var info model.NetworkInfo
+	err := s.db.Collection("networkinfo").FindOne(ctx, bson.D{{Key: "id", Value: 1}}).Decode(&info)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cursor, err := s.db.Collection("activations").Find(ctx, bson.D{},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "smesher", Value: 1}, {Key: "targetEpoch", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	atxs := make([]*model.Activation, 0, bulkWriteBatchSize)
+	for cursor.Next(ctx) {
+		var atx model.Activation
+		if err := cursor.Decode(&atx); err != nil {
+			return err
+		}
+		atxs = append(atxs, &atx)
+		if len(atxs) == bulkWriteBatchSize {
+			if err := s.linkActivationRewards(ctx, atxs, info.EpochNumLayers); err != nil {
+				return err
+			}
+			atxs = atxs[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return s.linkActivationRewards(ctx, atxs, info.EpochNumLayers)
+}
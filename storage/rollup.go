@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// RollupLookback is the time before the last daily rollup recomputed by every run, so that the
+// rewards and the transactions of the previous days stored late are accounted.
+const RollupLookback = 2 * 24 * time.Hour
+
+// SetRollups starts maintaining the daily and the weekly rollups every interval. Only the recent
+// periods are recomputed, so the rollups of the documents removed by the retention policies are
+// kept.
+func (s *Storage) SetRollups(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.rollupsDone = make(chan struct{})
+	go s.runRollups(interval)
+}
+
+func (s *Storage) runRollups(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.rollup(context.Background()); err != nil {
+			logging.Error("rollups", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.rollupsDone:
+			return
+		}
+	}
+}
+
+// rollup recomputes the rollups from the start of the week RollupLookback before the last daily
+// rollup, or all of them if there is none yet. The weeks are summed from their days.
+func (s *Storage) rollup(parent context.Context) error {
+	ctx, cancel := s.aggregateContext(parent)
+	defer cancel()
+	info, err := s.GetNetworkInfo(ctx)
+	if err != nil || info.EpochNumLayers == 0 || info.LayerDuration == 0 {
+		return err
+	}
+
+	var since uint32
+	var last model.Rollup
+	err = s.statsDB.Collection(statsRollupDailyCollection).FindOne(ctx, bson.D{},
+		options.FindOne().SetSort(bson.D{{Key: "start", Value: -1}})).Decode(&last)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+	case err != nil:
+		return fmt.Errorf("error get last rollup: %w", err)
+	default:
+		since = model.PeriodStart(model.PeriodWeek, last.Start-min(last.Start, uint32(RollupLookback/time.Second)))
+	}
+
+	days, err := s.rollupDays(ctx, info, since)
+	if err != nil {
+		return err
+	}
+	weeks := model.NewRollups(model.PeriodWeek, info)
+	for _, day := range days {
+		weeks.Add(day)
+	}
+	if err := s.replaceRollups(ctx, statsRollupDailyCollection, days); err != nil {
+		return err
+	}
+	return s.replaceRollups(ctx, statsRollupWeeklyCollection, weeks.Range(since, ^uint32(0)))
+}
+
+// rollupDays computes the daily rollups from the day starting at since.
+func (s *Storage) rollupDays(ctx context.Context, info *model.NetworkInfo, since uint32) ([]*model.Rollup, error) {
+	days := model.NewRollups(model.PeriodDay, info)
+	day := func(timestamp any) bson.D {
+		return bson.D{{Key: "$subtract", Value: bson.A{timestamp, bson.D{{Key: "$mod", Value: bson.A{timestamp, secondsPerDay}}}}}}
+	}
+	sinceFilter := bson.D{{Key: "timestamp", Value: bson.D{{Key: "$gte", Value: since}}}}
+
+	txs := mongo.Pipeline{
+		{{Key: "$match", Value: append(bson.D{NotOrphaned}, sinceFilter...)}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: day("$timestamp")},
+			{Key: "txs", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "volume", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			{Key: "fees", Value: bson.D{{Key: "$sum", Value: "$fee"}}},
+		}}},
+	}
+	if archive, ok := TierArchive("txs"); ok {
+		txs = append(mongo.Pipeline{{{Key: "$unionWith", Value: bson.D{
+			{Key: "coll", Value: s.db.CollectionName(archive)},
+			{Key: "pipeline", Value: mongo.Pipeline{{{Key: "$match", Value: sinceFilter}}}},
+		}}}}, txs...)
+	}
+	err := s.aggregateRollups(ctx, "txs", txs, func(doc bson.Raw) {
+		rollup := days.At(uint32(utils.GetAsInt64(doc.Lookup("_id"))))
+		rollup.Txs = utils.GetAsInt64(doc.Lookup("txs"))
+		rollup.Volume = utils.GetAsInt64(doc.Lookup("volume"))
+		rollup.Fees = utils.GetAsInt64(doc.Lookup("fees"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error rollup transactions: %w", err)
+	}
+
+	err = s.aggregateRollups(ctx, "rewards", mongo.Pipeline{
+		{{Key: "$match", Value: sinceFilter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: day("$timestamp")},
+			{Key: "rewards", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+		}}},
+	}, func(doc bson.Raw) {
+		days.At(uint32(utils.GetAsInt64(doc.Lookup("_id")))).Rewards = utils.GetAsInt64(doc.Lookup("rewards"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error rollup rewards: %w", err)
+	}
+
+	var sinceLayer uint32
+	if since > info.GenesisTime {
+		sinceLayer = (since - info.GenesisTime) / info.LayerDuration
+	}
+	created := bson.D{{Key: "$add", Value: bson.A{info.GenesisTime, bson.D{{Key: "$multiply", Value: bson.A{"$created", info.LayerDuration}}}}}}
+	err = s.aggregateRollups(ctx, "accounts", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "created", Value: bson.D{{Key: "$gte", Value: sinceLayer}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: day(created)},
+			{Key: "accounts", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}, func(doc bson.Raw) {
+		if start := uint32(utils.GetAsInt64(doc.Lookup("_id"))); start >= since {
+			days.At(start).NewAccounts = utils.GetAsInt64(doc.Lookup("accounts"))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error rollup accounts: %w", err)
+	}
+
+	// the smeshers activating for an epoch of the range, without an activation for an earlier one
+	sinceEpoch := sinceLayer / info.EpochNumLayers
+	err = s.aggregateRollups(ctx, "activations", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: bson.D{{Key: "$gte", Value: sinceEpoch}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$smesher"},
+			{Key: "first", Value: bson.D{{Key: "$min", Value: "$targetEpoch"}}},
+		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: s.db.CollectionName("activations")},
+			{Key: "localField", Value: "_id"},
+			{Key: "foreignField", Value: "smesher"},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: bson.D{{Key: "targetEpoch", Value: bson.D{{Key: "$lt", Value: sinceEpoch}}}}}},
+				{{Key: "$limit", Value: 1}},
+				{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			}},
+			{Key: "as", Value: "earlier"},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "earlier", Value: bson.D{{Key: "$size", Value: 0}}}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$first"},
+			{Key: "smeshers", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}, func(doc bson.Raw) {
+		epoch := uint32(utils.GetAsInt64(doc.Lookup("_id")))
+		if rollup := days.AtEpoch(epoch); rollup.Start >= since {
+			rollup.NewSmeshers += utils.GetAsInt64(doc.Lookup("smeshers"))
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error rollup smeshers: %w", err)
+	}
+	return days.Range(since, ^uint32(0)), nil
+}
+
+// aggregateRollups runs the pipeline on the raw collection and passes every result to add.
+func (s *Storage) aggregateRollups(ctx context.Context, source string, pipeline mongo.Pipeline, add func(bson.Raw)) error {
+	cursor, err := s.db.Collection(source).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		add(cursor.Current)
+	}
+	return cursor.Err()
+}
+
+// replaceRollups replaces the rollups of the collection starting at the same time.
+func (s *Storage) replaceRollups(ctx context.Context, collection string, rollups []*model.Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(rollups))
+	for _, rollup := range rollups {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "start", Value: rollup.Start}}).
+			SetReplacement(rollup).
+			SetUpsert(true))
+	}
+	_, err := s.statsDB.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return fmt.Errorf("error update `%s`: %w", collection, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// topology returns whether the deployment is a replica set or a sharded cluster. Multi-document
+// transactions are only available on both.
+func topology(ctx context.Context, db *mongo.Database) (replicaSet, sharded bool) {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		logging.Error("detect deployment topology, transactions disabled", err)
+		return false, false
+	}
+	return hello.SetName != "", hello.Msg == "isdbgrid"
+}
+
+// inTransaction runs fn in a multi-document transaction, so that a crash or a concurrent collector
+// never observes only a part of its writes. fn can be retried on transient errors, so its writes
+// must be idempotent. Standalone servers don't support transactions, fn is run without one.
+func (s *Storage) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !s.transactions {
+		return fn(ctx)
+	}
+	session, err := s.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// ShardKeys are the shard keys of the large collections on a sharded cluster. Transactions and
+// activations are looked up by id, so they are spread by hashed id. Rewards are unique by layer and
+// smesher and read by layer ranges, so they are sharded by layer range.
+//
+// The unique indexes of a sharded collection must be prefixed by its shard key, and every upsert
+// must filter on the full shard key, which is the case for all the writes of these collections.
+var ShardKeys = map[string]bson.D{
+	"txs":         {{Key: "id", Value: "hashed"}},
+	"activations": {{Key: "id", Value: "hashed"}},
+	"rewards":     {{Key: "layer", Value: 1}},
+}
+
+// shardKeyIndexes rewrites the unique index of rewards with the layer first, so that it is compatible
+// with the layer range shard key, and keeps the smesher index used by the smesher rewards queries.
+func (s *Storage) shardKeyIndexes(ctx context.Context) error {
+	if err := s.createIndexes(ctx, "rewards"); err != nil {
+		return err
+	}
+	return s.dropIndex(ctx, "rewards", "keyIndex")
+}
+
+// shardCollections shards the large collections with ShardKeys, it does nothing unless the database
+// is a sharded cluster.
+func (s *Storage) shardCollections(ctx context.Context) error {
+	if !s.sharded {
+		return nil
+	}
+	admin := s.client.Database("admin")
+	if err := admin.RunCommand(ctx, bson.D{{Key: "enableSharding", Value: s.db.Name()}}).Err(); err != nil {
+		return fmt.Errorf("error enable sharding: %w", err)
+	}
+	for collection, key := range ShardKeys {
+		namespace := s.db.Name() + "." + s.db.CollectionName(collection)
+		n, err := s.client.Database("config").Collection("collections").CountDocuments(ctx, bson.D{{Key: "_id", Value: namespace}})
+		if err != nil {
+			return fmt.Errorf("error get `%s` sharding: %w", collection, err)
+		}
+		if n > 0 {
+			continue
+		}
+		// the collection may not be empty, the shard key must be indexed beforehand
+		if _, err := s.db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: key}); err != nil {
+			return fmt.Errorf("error index `%s` shard key: %w", collection, err)
+		}
+		if err := admin.RunCommand(ctx, bson.D{
+			{Key: "shardCollection", Value: namespace},
+			{Key: "key", Value: key},
+		}).Err(); err != nil {
+			return fmt.Errorf("error shard `%s`: %w", collection, err)
+		}
+		logging.Info("sharded", logging.Collection(collection), zap.Any("key", key))
+	}
+	return nil
+}
+
+// isIndexNotFound reports whether the error is NamespaceNotFound or IndexNotFound.
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestShardKeys(t *testing.T) {
+	for collection, key := range ShardKeys {
+		indexes := managedIndexes(collection)
+		require.NotEmpty(t, indexes, collection)
+
+		prefixed := false
+		for _, index := range indexes {
+			startsWithKey := index.Keys[0].Key == key[0].Key
+			prefixed = prefixed || startsWithKey
+			// a hashed shard key only allows the unique indexes starting with its field
+			if index.Unique {
+				require.True(t, startsWithKey, "unique index %s.%s", collection, index.Name)
+			}
+		}
+		require.True(t, prefixed, "no index of %s starts with its shard key", collection)
+	}
+
+	// the unique index of the rewards starting with the smesher is dropped by the migration
+	for _, index := range managedIndexes("rewards") {
+		require.NotEqual(t, "keyIndex", index.Name)
+	}
+	var sharding *Migration
+	for i := range migrations {
+		if migrations[i].Version == 6 {
+			sharding = &migrations[i]
+		}
+	}
+	require.NotNil(t, sharding)
+	require.Contains(t, sharding.Description, "shard")
+}
+
+func TestIsIndexNotFound(t *testing.T) {
+	require.True(t, isIndexNotFound(mongo.CommandError{Code: 26, Name: "NamespaceNotFound"}))
+	require.True(t, isIndexNotFound(fmt.Errorf("drop: %w", mongo.CommandError{Code: 27, Name: "IndexNotFound"})))
+	require.False(t, isIndexNotFound(mongo.CommandError{Code: 85, Name: "IndexOptionsConflict"}))
+	require.False(t, isIndexNotFound(errors.New("IndexNotFound")))
+}
@@ -0,0 +1,11 @@
+package storage
+
+import (
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+)
+
+// AddSink registers a sink receiving a copy of every layer, block, transaction, reward,
+// activation, account, malfeasance proof and certificate persisted to mongo.
+func (s *Storage) AddSink(snk sink.Sink) {
+	s.sinks = append(s.sinks, snk)
+}
@@ -4,86 +4,61 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
-	"github.com/spacemeshos/go-spacemesh/log"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/explorer-backend/utils"
 )
 
 func (s *Storage) InitSmeshersStorage(ctx context.Context) error {
-	_, err := s.db.Collection("smeshers").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("idIndex").SetUnique(true)})
-	if err != nil {
-		return fmt.Errorf("error init `smeshers` collection: %w", err)
+	if err := s.createIndexes(ctx, "smeshers", "coinbases"); err != nil {
+		return err
 	}
-	_, err = s.db.Collection("coinbases").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "smesherId", Value: 1}}, Options: options.Index().SetName("smesherIdIndex").SetUnique(true)})
-	if err != nil {
-		return fmt.Errorf("error init `coinbases` collection: %w", err)
+	if err := applyValidator(ctx, s.db, "smeshers"); err != nil {
+		return err
 	}
-	return nil
+	return applyValidator(ctx, s.db, "coinbases")
 }
 
 func (s *Storage) GetSmesher(parent context.Context, query *bson.D) (*model.Smesher, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("smeshers").Find(ctx, query)
-	if err != nil {
-		log.Info("GetSmesher: %v", err)
+	smesher := &model.Smesher{}
+	if err := s.findOne(parent, "smeshers", query, smesher); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetSmesher: Empty result")
+			return nil, errors.New("Empty result")
+		}
+		logging.Error("GetSmesher", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetSmesher: Empty result")
-		return nil, errors.New("Empty result")
-	}
-	doc := cursor.Current
-	smesher := &model.Smesher{
-		Id:             utils.GetAsString(doc.Lookup("id")),
-		CommitmentSize: utils.GetAsUInt64(doc.Lookup("cSize")),
-		Coinbase:       utils.GetAsString(doc.Lookup("coinbase")),
-		AtxCount:       utils.GetAsUInt32(doc.Lookup("atxcount")),
-		Timestamp:      utils.GetAsUInt64(doc.Lookup("timestamp")),
-	}
 	return smesher, nil
 }
 
-func (s *Storage) GetSmeshersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("smeshers").CountDocuments(ctx, query, opts...)
+// GetSmeshersCount returns the number of smeshers matching the query.
+func (s *Storage) GetSmeshersCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) (int64, error) {
+	count, err := s.countDocuments(parent, "smeshers", query, opts...)
 	if err != nil {
-		log.Info("GetSmeshersCount: %v", err)
-		return 0
+		return 0, fmt.Errorf("error count smeshers: %w", err)
 	}
-	return count
+	return count, nil
 }
 
-func (s *Storage) IsSmesherExists(parent context.Context, smesher string) bool {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("smeshers").CountDocuments(ctx, bson.D{{Key: "id", Value: smesher}})
+// IsSmesherExists reports whether the smesher is stored.
+func (s *Storage) IsSmesherExists(parent context.Context, smesher string) (bool, error) {
+	count, err := s.countDocuments(parent, "smeshers", bson.D{{Key: "id", Value: smesher}})
 	if err != nil {
-		log.Info("IsSmesherExists: %v", err)
-		return false
+		return false, fmt.Errorf("error count smeshers: %w", err)
 	}
-	return count > 0
+	return count > 0, nil
 }
 
 func (s *Storage) GetSmeshers(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]bson.D, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("smeshers").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetSmeshers: %v", err)
-		return nil, err
-	}
 	var docs interface{} = []bson.D{}
-	err = cursor.All(ctx, &docs)
+	err := s.findAll(parent, "smeshers", query, &docs, opts...)
 	if err != nil {
-		log.Info("GetSmeshers: %v", err)
+		logging.Error("GetSmeshers", err)
 		return nil, err
 	}
 	if len(docs.([]bson.D)) == 0 {
@@ -92,95 +67,66 @@ func (s *Storage) GetSmeshers(parent context.Context, query *bson.D, opts ...*op
 	return docs.([]bson.D), nil
 }
 
-func (s *Storage) SaveSmesher(parent context.Context, in *model.Smesher, epoch uint32) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertSmesher stores the coinbase and the smesher documents keyed on the smesher id and records
+// the smesher changes in a single transaction. Storing the smesher of an epoch again is a no-op.
+func (s *Storage) UpsertSmesher(parent context.Context, in *model.Smesher, epoch uint32) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
-	opts := options.Update().SetUpsert(true)
-	_, err := s.db.Collection("smeshers").UpdateOne(ctx, bson.D{{Key: "id", Value: in.Id}}, bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "id", Value: in.Id},
-			{Key: "cSize", Value: in.CommitmentSize},
-			{Key: "coinbase", Value: in.Coinbase},
-			{Key: "atxcount", Value: in.AtxCount},
-			{Key: "timestamp", Value: in.Timestamp},
-		}},
-		{Key: "$addToSet", Value: bson.M{"epochs": epoch}},
-	}, opts)
-	if err != nil {
-		return fmt.Errorf("error save smesher: %w", err)
-	}
-	return nil
-}
 
-func (s *Storage) SaveSmesherQuery(in *model.Smesher) *mongo.UpdateOneModel {
-	filter := bson.D{{Key: "id", Value: in.Id}}
-	update := bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "id", Value: in.Id},
-			{Key: "cSize", Value: in.CommitmentSize},
-			{Key: "coinbase", Value: in.Coinbase},
-			{Key: "atxcount", Value: in.AtxCount},
-			{Key: "timestamp", Value: in.Timestamp},
-		}},
-	}
-
-	updateModel := mongo.NewUpdateOneModel()
-	updateModel.Filter = filter
-	updateModel.Update = update
-	updateModel.SetUpsert(true)
-
-	return updateModel
-}
-
-func (s *Storage) UpdateSmesher(parent context.Context, in *model.Smesher, epoch uint32) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-
-	filter := bson.D{{Key: "smesherId", Value: in.Id}}
-	_, err := s.db.Collection("coinbases").UpdateOne(
-		ctx,
-		filter,
-		bson.D{{Key: "$set", Value: bson.D{{Key: "coinbase", Value: in.Coinbase}}}},
-		options.Update().SetUpsert(true))
-	if err != nil {
-		return fmt.Errorf("error insert smesher into `coinbases`: %w", err)
-	}
-
-	atxCount, err := s.db.Collection("activations").CountDocuments(ctx, &bson.D{{Key: "smesher", Value: in.Id}})
+	var created int64
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		changes, err := s.smesherChanges(ctx, []*model.Smesher{in}, []uint32{epoch})
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			if _, err := s.db.Collection(smesherHistoryCollection).BulkWrite(ctx, changes); err != nil {
+				return fmt.Errorf("error save smesher history: %w", err)
+			}
+		}
+
+		coinbase := coinbaseQuery(in.Id, in.Coinbase, epoch)
+		_, err = s.db.Collection("coinbases").UpdateOne(ctx, coinbase.Filter, coinbase.Update, options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("error insert smesher into `coinbases`: %w", err)
+		}
+
+		atxCount, err := s.db.Collection("activations").CountDocuments(ctx, &bson.D{{Key: "smesher", Value: in.Id}})
+		if err != nil {
+			logging.Error("UpsertSmesher: GetActivationsCount", err)
+		}
+
+		res, err := s.db.Collection("smeshers").UpdateOne(ctx, bson.D{{Key: "id", Value: in.Id}}, bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "id", Value: in.Id},
+				{Key: "cSize", Value: in.CommitmentSize},
+				{Key: "coinbase", Value: in.Coinbase},
+				{Key: "timestamp", Value: in.Timestamp},
+				{Key: "atxcount", Value: atxCount},
+			}},
+			{Key: "$addToSet", Value: bson.M{"epochs": epoch}},
+		}, options.Update().SetUpsert(true))
+		if err == nil {
+			created = res.UpsertedCount
+		}
+		return err
+	})
 	if err != nil {
-		log.Info("UpdateSmesher: GetActivationsCount: %v", err)
-	}
-
-	_, err = s.db.Collection("smeshers").UpdateOne(ctx, bson.D{{Key: "id", Value: in.Id}}, bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "id", Value: in.Id},
-			{Key: "cSize", Value: in.CommitmentSize},
-			{Key: "coinbase", Value: in.Coinbase},
-			{Key: "timestamp", Value: in.Timestamp},
-			{Key: "atxcount", Value: atxCount},
-		}},
-		{Key: "$addToSet", Value: bson.M{"epochs": epoch}},
-	}, options.Update().SetUpsert(true))
-	if err != nil {
-		log.Info("UpdateSmesher: %v", err)
+		logging.Error("UpsertSmesher", err)
+	} else if s.watched == nil {
+		// in watch mode the smeshers of the whole chain are counted by saveChainActivations
+		s.incSmeshersTotals(parent, created)
 	}
 	return err
 }
 
-func (s *Storage) UpdateSmesherQuery(in *model.Smesher, epoch uint32) (*mongo.UpdateOneModel, *mongo.UpdateOneModel) {
-	coinbaseFilter := bson.D{{Key: "smesherId", Value: in.Id}}
-	coinbaseUpdate := bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "coinbase", Value: in.Coinbase},
-		}}}
-	coinbaseModel := mongo.NewUpdateOneModel()
-	coinbaseModel.SetFilter(coinbaseFilter)
-	coinbaseModel.SetUpdate(coinbaseUpdate)
-	coinbaseModel.SetUpsert(true)
+// UpsertSmesherQuery returns the upserts of the coinbase and the smesher documents of UpsertSmesher.
+func (s *Storage) UpsertSmesherQuery(in *model.Smesher, epoch uint32) (*mongo.UpdateOneModel, *mongo.UpdateOneModel) {
+	coinbaseModel := coinbaseQuery(in.Id, in.Coinbase, epoch)
 
 	atxCount, err := s.db.Collection("activations").CountDocuments(context.TODO(), &bson.D{{Key: "smesher", Value: in.Id}})
 	if err != nil {
-		log.Info("UpdateSmesher: GetActivationsCount: %v", err)
+		logging.Error("UpsertSmesher: GetActivationsCount", err)
 	}
 
 	smesherFilter := bson.D{{Key: "id", Value: in.Id}}
@@ -198,6 +144,7 @@ func (s *Storage) UpdateSmesherQuery(in *model.Smesher, epoch uint32) (*mongo.Up
 	smesherModel := mongo.NewUpdateOneModel()
 	smesherModel.SetFilter(smesherFilter)
 	smesherModel.SetUpdate(smesherUpdate)
+	smesherModel.SetUpsert(true)
 
 	return coinbaseModel, smesherModel
 }
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// UpsertSmesherLocations sets the locations of the smeshers, the unknown smeshers are skipped.
+func (s *Storage) UpsertSmesherLocations(parent context.Context, locations []*model.SmesherLocation) error {
+	if len(locations) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, 0, len(locations))
+	for _, location := range locations {
+		if err := location.Validate(); err != nil {
+			return fmt.Errorf("smesher `%s`: %w", location.Smesher, err)
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: location.Smesher}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "geo", Value: location.Geo}}}}))
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	if _, err := s.db.Collection("smeshers").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("error save smesher locations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// smesherHistoryCollection holds the changes of the smesher fields, the smeshers collection only
+// keeps the current values.
+const smesherHistoryCollection = "smesher_history"
+
+func (s *Storage) initSmesherHistoryStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, smesherHistoryCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, smesherHistoryCollection)
+}
+
+// getSmesherStates returns the stored coinbase and commitment size of the smeshers, the unknown
+// smeshers are missing.
+func (s *Storage) getSmesherStates(ctx context.Context, ids []string) (map[string]*model.Smesher, error) {
+	cursor, err := s.db.Collection("smeshers").Find(ctx,
+		bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}, {Key: "coinbase", Value: 1}, {Key: "cSize", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var smeshers []*model.Smesher
+	if err := cursor.All(ctx, &smeshers); err != nil {
+		return nil, err
+	}
+	states := make(map[string]*model.Smesher, len(smeshers))
+	for _, smesher := range smeshers {
+		states[smesher.Id] = smesher
+	}
+	return states, nil
+}
+
+// smesherChangeQuery records the change, replaying the change of an epoch merges the changed fields.
+func smesherChangeQuery(change *model.SmesherChange) *mongo.UpdateOneModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "smesher", Value: change.Smesher}, {Key: "epoch", Value: change.Epoch}}).
+		SetUpdate(bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "timestamp", Value: change.Timestamp},
+				{Key: "coinbase", Value: change.Coinbase},
+				{Key: "cSize", Value: change.CommitmentSize},
+			}},
+			{Key: "$addToSet", Value: bson.D{{Key: "changed", Value: bson.D{{Key: "$each", Value: change.Changed}}}}},
+		}).
+		SetUpsert(true)
+}
+
+// smesherNameChangeQuery records the change of the name, the activation changes of the same epoch
+// keep their timestamp.
+func smesherNameChangeQuery(change *model.SmesherChange) *mongo.UpdateOneModel {
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "smesher", Value: change.Smesher}, {Key: "epoch", Value: change.Epoch}}).
+		SetUpdate(bson.D{
+			{Key: "$set", Value: bson.D{{Key: "name", Value: change.Name}}},
+			{Key: "$setOnInsert", Value: bson.D{{Key: "timestamp", Value: change.Timestamp}}},
+			{Key: "$addToSet", Value: bson.D{{Key: "changed", Value: bson.D{{Key: "$each", Value: change.Changed}}}}},
+		}).
+		SetUpsert(true)
+}
+
+// smesherChanges returns the writes recording the changes of the smeshers from their stored state.
+func (s *Storage) smesherChanges(ctx context.Context, smeshers []*model.Smesher, epochs []uint32) ([]mongo.WriteModel, error) {
+	ids := make([]string, 0, len(smeshers))
+	for _, smesher := range smeshers {
+		ids = append(ids, smesher.Id)
+	}
+	states, err := s.getSmesherStates(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error get smeshers: %w", err)
+	}
+	var writes []mongo.WriteModel
+	for i, smesher := range smeshers {
+		if change := model.NewSmesherChange(states[smesher.Id], smesher, epochs[i]); change != nil {
+			writes = append(writes, smesherChangeQuery(change))
+		}
+		states[smesher.Id] = smesher
+	}
+	return writes, nil
+}
+
+// GetSmesherHistory returns the smesher changes matching the query.
+func (s *Storage) GetSmesherHistory(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]*model.SmesherChange, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection(smesherHistoryCollection).Find(ctx, query, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error get smesher history: %w", err)
+	}
+	var changes []*model.SmesherChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("error decode smesher history: %w", err)
+	}
+	return changes, nil
+}
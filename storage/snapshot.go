@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+const (
+	snapshotVersion        = 1
+	snapshotManifest       = "manifest.json"
+	snapshotImportProgress = "import-progress.json"
+	snapshotSuffix         = ".ndjson"
+	// snapshotBatchSize is the number of documents between two progress records.
+	snapshotBatchSize = 1000
+)
+
+// SnapshotManifest describes a snapshot directory. It is rewritten after every batch of exported
+// documents, so an interrupted export resumes after the last recorded document.
+type SnapshotManifest struct {
+	Version     int                            `json:"version"`
+	Created     int64                          `json:"created"`
+	Collections map[string]*SnapshotCollection `json:"collections"`
+}
+
+// SnapshotCollection is the export progress of a collection, stored as `<name>.ndjson` with one
+// canonical extended JSON document per line, in `_id` order.
+type SnapshotCollection struct {
+	Documents int64 `json:"documents"`
+	// Size is the size of the exported documents, the file is truncated to it when the export resumes.
+	Size int64 `json:"size"`
+	// LastID is the `_id` of the last exported document, in canonical extended JSON.
+	LastID   json.RawMessage `json:"lastId,omitempty"`
+	Complete bool            `json:"complete"`
+}
+
+// Complete reports whether all the collections are exported.
+func (m *SnapshotManifest) Complete() bool {
+	for _, c := range m.Collections {
+		if !c.Complete {
+			return false
+		}
+	}
+	return true
+}
+
+// ExportSnapshot writes the collections, all if none is given, to the directory. An interrupted
+// export of the same directory resumes where it stopped.
+func (s *Storage) ExportSnapshot(ctx context.Context, dir string, collections []string) (*SnapshotManifest, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	manifest := &SnapshotManifest{
+		Version:     snapshotVersion,
+		Created:     time.Now().Unix(),
+		Collections: make(map[string]*SnapshotCollection),
+	}
+	if err := readJSONFile(filepath.Join(dir, snapshotManifest), manifest); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+	}
+
+	if len(collections) == 0 {
+		names, err := s.db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: bson.D{
+			{Key: "$not", Value: primitive.Regex{Pattern: `^system\.`}},
+		}}})
+		if err != nil {
+			return nil, err
+		}
+		collections = names
+	}
+	sort.Strings(collections)
+	for _, name := range collections {
+		if manifest.Collections[name] == nil {
+			manifest.Collections[name] = &SnapshotCollection{}
+		}
+	}
+
+	for _, name := range collections {
+		if manifest.Collections[name].Complete {
+			logging.Info("export snapshot: already exported", logging.Collection(name))
+			continue
+		}
+		if err := s.exportSnapshotCollection(ctx, dir, name, manifest); err != nil {
+			return nil, fmt.Errorf("export %s: %w", name, err)
+		}
+		logging.Info("export snapshot", logging.Collection(name), zap.Int64("documents", manifest.Collections[name].Documents))
+	}
+	return manifest, nil
+}
+
+func (s *Storage) exportSnapshotCollection(ctx context.Context, dir, name string, manifest *SnapshotManifest) error {
+	progress := manifest.Collections[name]
+	file, err := os.OpenFile(filepath.Join(dir, name+snapshotSuffix), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	// drop the documents written after the last recorded batch
+	if err := file.Truncate(progress.Size); err != nil {
+		return err
+	}
+	if _, err := file.Seek(progress.Size, io.SeekStart); err != nil {
+		return err
+	}
+
+	filter := bson.D{}
+	if progress.LastID != nil {
+		var last bson.D
+		if err := bson.UnmarshalExtJSON(progress.LastID, true, &last); err != nil || len(last) == 0 {
+			return fmt.Errorf("invalid last id %s", progress.LastID)
+		}
+		filter = bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: last[0].Value}}}}
+	}
+	cursor, err := s.db.Collection(name).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	buf := bufio.NewWriter(file)
+	var (
+		pending int64
+		size    = progress.Size
+		lastID  json.RawMessage
+	)
+	record := func(complete bool) error {
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			return err
+		}
+		progress.Documents += pending
+		progress.Size = size
+		if lastID != nil {
+			progress.LastID = lastID
+		}
+		progress.Complete = complete
+		pending = 0
+		return writeJSONFile(filepath.Join(dir, snapshotManifest), manifest)
+	}
+
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return err
+		}
+		if _, err := buf.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		size += int64(len(line)) + 1
+		if lastID, err = bson.MarshalExtJSON(bson.D{{Key: "_id", Value: cursor.Current.Lookup("_id")}}, true, false); err != nil {
+			return err
+		}
+		pending++
+		if pending == snapshotBatchSize {
+			if err := record(false); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return record(true)
+}
+
+// ImportSnapshot loads a complete snapshot written by ExportSnapshot. The progress is recorded in
+// the snapshot directory, so an interrupted import resumes where it stopped. Documents keep their
+// `_id`, the ones already in the database are skipped.
+func (s *Storage) ImportSnapshot(ctx context.Context, dir string) (*SnapshotManifest, error) {
+	manifest := &SnapshotManifest{}
+	if err := readJSONFile(filepath.Join(dir, snapshotManifest), manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+	}
+	if !manifest.Complete() {
+		return nil, errors.New("snapshot export is not complete")
+	}
+	imported := make(map[string]int64)
+	if err := readJSONFile(filepath.Join(dir, snapshotImportProgress), &imported); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("invalid import progress: %w", err)
+	}
+
+	names := make([]string, 0, len(manifest.Collections))
+	for name := range manifest.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if imported[name] >= manifest.Collections[name].Documents {
+			logging.Info("import snapshot: already imported", logging.Collection(name))
+			continue
+		}
+		if err := s.importSnapshotCollection(ctx, dir, name, imported); err != nil {
+			return nil, fmt.Errorf("import %s: %w", name, err)
+		}
+		logging.Info("import snapshot", logging.Collection(name), zap.Int64("documents", imported[name]))
+	}
+	return manifest, nil
+}
+
+func (s *Storage) importSnapshotCollection(ctx context.Context, dir, name string, imported map[string]int64) error {
+	file, err := os.Open(filepath.Join(dir, name+snapshotSuffix))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var line int64
+	docs := make([]interface{}, 0, snapshotBatchSize)
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		_, err := s.db.Collection(name).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		imported[name] += int64(len(docs))
+		docs = docs[:0]
+		return writeJSONFile(filepath.Join(dir, snapshotImportProgress), imported)
+	}
+
+	for scanner.Scan() {
+		line++
+		if line <= imported[name] {
+			continue
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		docs = append(docs, doc)
+		if len(docs) == snapshotBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSONFile replaces the file atomically, so that the progress is never lost by a crash.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), snapshotManifest)
+	manifest := &SnapshotManifest{
+		Version: snapshotVersion,
+		Created: 1700000000,
+		Collections: map[string]*SnapshotCollection{
+			"txs":    {Documents: 2000, Size: 123456, LastID: json.RawMessage(`{"_id":{"$oid":"65a000000000000000000001"}}`)},
+			"layers": {Documents: 10, Size: 1000, Complete: true},
+		},
+	}
+	require.False(t, manifest.Complete())
+	require.NoError(t, writeJSONFile(path, manifest))
+
+	var read SnapshotManifest
+	require.NoError(t, readJSONFile(path, &read))
+	require.Equal(t, manifest.Version, read.Version)
+	require.Equal(t, manifest.Collections["layers"], read.Collections["layers"])
+	require.JSONEq(t, string(manifest.Collections["txs"].LastID), string(read.Collections["txs"].LastID))
+
+	read.Collections["txs"].Complete = true
+	require.True(t, read.Complete())
+	require.NoFileExists(t, path+".tmp")
+}
@@ -0,0 +1,568 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// The materialized stats collections are incremented as new transactions and rewards are
+// stored, so the API never scans the raw collections for them. They are not decremented by
+// the retention policies.
+const (
+	statsDailyTxsCollection      = "stats_daily_txs"
+	statsDailyAccountsCollection = "stats_daily_accounts"
+	statsTxTypesCollection       = "stats_tx_types"
+	statsEpochRewardsCollection  = "stats_epoch_rewards"
+	statsSmeshersCollection      = "stats_smeshers"
+	statsBlocksCollection        = "stats_blocks"
+	statsRollupDailyCollection   = "stats_rollup_daily"
+	statsRollupWeeklyCollection  = "stats_rollup_weekly"
+	statsGeoHeatmapCollection    = "stats_geo_heatmap"
+	// statsTotalsCollection holds the single document of the global totals, see model.Totals.
+	statsTotalsCollection = "stats_totals"
+)
+
+const secondsPerDay = 24 * 60 * 60
+
+// totalsID is the id of the document of statsTotalsCollection.
+const totalsID = "totals"
+
+// initStatsStorage creates the indexes of the materialized stats collections.
+func (s *Storage) initStatsStorage(ctx context.Context) error {
+	return s.createIndexes(ctx, statsDailyTxsCollection, statsDailyAccountsCollection, statsTxTypesCollection, statsEpochRewardsCollection, statsSmeshersCollection, statsBlocksCollection, statsRollupDailyCollection, statsRollupWeeklyCollection, statsTotalsCollection)
+}
+
+// incTransactionsStats accounts transactions stored for the first time.
+func (s *Storage) incTransactionsStats(parent context.Context, txs []*model.Transaction) {
+	if len(txs) == 0 {
+		return
+	}
+	days := make(map[uint32]*model.DailyTransactions)
+	type typeKey struct {
+		day, epoch uint32
+		txType     int
+	}
+	types := make(map[typeKey]*model.TransactionTypeStats)
+	for _, tx := range txs {
+		day := tx.Timestamp - tx.Timestamp%secondsPerDay
+		stats, ok := days[day]
+		if !ok {
+			stats = &model.DailyTransactions{Day: day}
+			days[day] = stats
+		}
+		stats.Count++
+		stats.Amount += int64(tx.Amount)
+
+		key := typeKey{day, s.GetEpochForLayer(tx.Layer), tx.Type}
+		typeStats, ok := types[key]
+		if !ok {
+			typeStats = &model.TransactionTypeStats{Day: key.day, Epoch: key.epoch, Type: key.txType}
+			types[key] = typeStats
+		}
+		typeStats.Count++
+		typeStats.Amount += int64(tx.Amount)
+	}
+	// in watch mode the transactions of the whole chain are counted by incChainTransactions
+	if s.watched == nil {
+		s.incTotals(parent, bson.D{{Key: "txs", Value: int64(len(txs))}})
+	}
+
+	models := make([]mongo.WriteModel, 0, len(days))
+	for day, stats := range days {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "day", Value: day}}).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{
+				{Key: "count", Value: stats.Count},
+				{Key: "amount", Value: stats.Amount},
+			}}}).
+			SetUpsert(true))
+	}
+	s.incStats(parent, s.statsDB, statsDailyTxsCollection, models)
+
+	typeModels := make([]mongo.WriteModel, 0, len(types))
+	for _, stats := range types {
+		typeModels = append(typeModels, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "day", Value: stats.Day}, {Key: "epoch", Value: stats.Epoch}, {Key: "type", Value: stats.Type}}).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{
+				{Key: "count", Value: stats.Count},
+				{Key: "amount", Value: stats.Amount},
+			}}}).
+			SetUpsert(true))
+	}
+	s.incStats(parent, s.statsDB, statsTxTypesCollection, typeModels)
+}
+
+// incBlocksStats accounts blocks stored for the first time.
+func (s *Storage) incBlocksStats(parent context.Context, blocks []*model.Block) {
+	if len(blocks) == 0 {
+		return
+	}
+	stats := model.NewBlockStats(blocks)
+	models := make([]mongo.WriteModel, 0, len(stats))
+	for _, stat := range stats {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "epoch", Value: stat.Epoch}, {Key: "txsBucket", Value: stat.TxsBucket}, {Key: "sizeBucket", Value: stat.SizeBucket}}).
+			SetUpdate(bson.D{
+				{Key: "$inc", Value: bson.D{
+					{Key: "blocks", Value: stat.Blocks},
+					{Key: "txs", Value: stat.Txs},
+					{Key: "size", Value: stat.Size},
+				}},
+				{Key: "$max", Value: bson.D{
+					{Key: "maxTxs", Value: stat.MaxTxs},
+					{Key: "maxSize", Value: stat.MaxSize},
+				}},
+			}).
+			SetUpsert(true))
+	}
+	s.incStats(parent, s.statsDB, statsBlocksCollection, models)
+}
+
+// incAccountsStats accounts the accounts created by the upserts of the accounts, created are the
+// layers the new accounts were first seen at.
+func (s *Storage) incAccountsStats(parent context.Context, created []uint32) {
+	if len(created) == 0 {
+		return
+	}
+	days := make(map[uint32]int64)
+	for _, layer := range created {
+		timestamp := s.getLayerTimestamp(layer)
+		days[timestamp-timestamp%secondsPerDay]++
+	}
+
+	models := make([]mongo.WriteModel, 0, len(days))
+	for day, count := range days {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "day", Value: day}}).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{{Key: "count", Value: count}}}}).
+			SetUpsert(true))
+	}
+	s.incStats(parent, s.statsDB, statsDailyAccountsCollection, models)
+	s.incTotals(parent, bson.D{{Key: "accounts", Value: int64(len(created))}})
+}
+
+// incRewardsStats accounts rewards stored for the first time.
+func (s *Storage) incRewardsStats(parent context.Context, rewards []*model.Reward) {
+	if len(rewards) == 0 {
+		return
+	}
+	type sums struct{ total, layerReward, count int64 }
+	epochs := make(map[uint32]*sums)
+	smeshers := make(map[string]*sums)
+	for _, reward := range rewards {
+		epoch := s.GetEpochForLayer(reward.Layer)
+		if epochs[epoch] == nil {
+			epochs[epoch] = &sums{}
+		}
+		if smeshers[reward.Smesher] == nil {
+			smeshers[reward.Smesher] = &sums{}
+		}
+		for _, sum := range []*sums{epochs[epoch], smeshers[reward.Smesher]} {
+			sum.total += int64(reward.Total)
+			sum.layerReward += int64(reward.LayerReward)
+			sum.count++
+		}
+	}
+
+	inc := func(key string, value any, sum *sums) mongo.WriteModel {
+		return mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: key, Value: value}}).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{
+				{Key: "total", Value: sum.total},
+				{Key: "layerReward", Value: sum.layerReward},
+				{Key: "count", Value: sum.count},
+			}}}).
+			SetUpsert(true)
+	}
+	epochModels := make([]mongo.WriteModel, 0, len(epochs))
+	for epoch, sum := range epochs {
+		epochModels = append(epochModels, inc("epoch", epoch, sum))
+	}
+	s.incStats(parent, s.statsDB, statsEpochRewardsCollection, epochModels)
+
+	smesherModels := make([]mongo.WriteModel, 0, len(smeshers))
+	for smesher, sum := range smeshers {
+		smesherModels = append(smesherModels, inc("smesher", smesher, sum))
+	}
+	s.incStats(parent, s.statsDB, statsSmeshersCollection, smesherModels)
+	s.incRewardCounters(parent, rewards)
+
+	var total int64
+	for _, reward := range rewards {
+		total += int64(reward.Total)
+	}
+	s.incTotals(parent, bson.D{{Key: "rewards", Value: total}, {Key: "rewardsCount", Value: int64(len(rewards))}})
+}
+
+// incSmeshersTotals accounts the smeshers stored for the first time.
+func (s *Storage) incSmeshersTotals(parent context.Context, created int64) {
+	if created == 0 {
+		return
+	}
+	s.incTotals(parent, bson.D{{Key: "smeshers", Value: created}})
+}
+
+// incSpaceTotals accounts the storage committed by the activations stored for the first time, by
+// target epoch.
+func (s *Storage) incSpaceTotals(parent context.Context, atxs []*model.Activation) {
+	if len(atxs) == 0 {
+		return
+	}
+	epochs := make(map[uint32]int64)
+	for _, atx := range atxs {
+		epochs[atx.TargetEpoch] += int64(atx.NumUnits) * int64(s.postUnitSize)
+	}
+	inc := make(bson.D, 0, len(epochs))
+	for epoch, space := range epochs {
+		inc = append(inc, bson.E{Key: fmt.Sprintf("space.%d", epoch), Value: space})
+	}
+	s.incTotals(parent, inc)
+}
+
+// incTotals increments the counters of the global totals and records the time of the update.
+func (s *Storage) incTotals(parent context.Context, inc bson.D) {
+	s.incStats(parent, s.statsDB, statsTotalsCollection, []mongo.WriteModel{mongo.NewUpdateOneModel().
+		SetFilter(bson.D{{Key: "id", Value: totalsID}}).
+		SetUpdate(bson.D{
+			{Key: "$inc", Value: inc},
+			{Key: "$max", Value: bson.D{{Key: "updated", Value: uint32(time.Now().Unix())}}},
+		}).
+		SetUpsert(true)})
+}
+
+// incRewardCounters increments the rewards counters of the smesher and coinbase documents, the
+// documents are created by the activations so rewards of unknown smeshers are not counted.
+func (s *Storage) incRewardCounters(parent context.Context, rewards []*model.Reward) {
+	type key struct{ smesher, coinbase string }
+	type counters struct{ total, count int64 }
+	smeshers := make(map[string]*counters)
+	coinbases := make(map[key]*counters)
+	for _, reward := range rewards {
+		k := key{reward.Smesher, reward.Coinbase}
+		if smeshers[k.smesher] == nil {
+			smeshers[k.smesher] = &counters{}
+		}
+		if coinbases[k] == nil {
+			coinbases[k] = &counters{}
+		}
+		for _, c := range []*counters{smeshers[k.smesher], coinbases[k]} {
+			c.total += int64(reward.Total)
+			c.count++
+		}
+	}
+	inc := func(filter bson.D, c *counters) mongo.WriteModel {
+		return mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.D{{Key: "$inc", Value: bson.D{
+				{Key: "totalRewards", Value: c.total},
+				{Key: "rewardsCount", Value: c.count},
+			}}})
+	}
+	smesherModels := make([]mongo.WriteModel, 0, len(smeshers))
+	for smesher, c := range smeshers {
+		smesherModels = append(smesherModels, inc(bson.D{{Key: "id", Value: smesher}}, c))
+	}
+	s.incStats(parent, s.db, "smeshers", smesherModels)
+	coinbaseModels := make([]mongo.WriteModel, 0, len(coinbases))
+	for k, c := range coinbases {
+		coinbaseModels = append(coinbaseModels, inc(bson.D{{Key: "coinbase", Value: k.coinbase}, {Key: "smesherId", Value: k.smesher}}, c))
+	}
+	s.incStats(parent, s.db, "coinbases", coinbaseModels)
+}
+
+// rebuildRewardCounters recomputes the rewards counters of the smesher and coinbase documents.
+func (s *Storage) rebuildRewardCounters(ctx context.Context) error {
+	merge := func(collection string, on bson.A) bson.D {
+		return bson.D{{Key: "$merge", Value: bson.D{
+			{Key: "into", Value: s.db.CollectionName(collection)},
+			{Key: "on", Value: on},
+			{Key: "whenMatched", Value: "merge"},
+			{Key: "whenNotMatched", Value: "discard"},
+		}}}
+	}
+	counters := bson.D{
+		{Key: "totalRewards", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+		{Key: "rewardsCount", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}
+	opts := options.Aggregate().SetAllowDiskUse(true)
+
+	_, err := s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: "$smesher"}}, counters...)}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "id", Value: "$_id"}, {Key: "totalRewards", Value: 1}, {Key: "rewardsCount", Value: 1}}}},
+		merge("smeshers", bson.A{"id"}),
+	}, opts)
+	if err != nil {
+		return fmt.Errorf("error rebuild smeshers rewards counters: %w", err)
+	}
+	_, err = s.db.Collection("rewards").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: bson.D{{Key: "smesher", Value: "$smesher"}, {Key: "coinbase", Value: "$coinbase"}}}}, counters...)}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "smesherId", Value: "$_id.smesher"}, {Key: "coinbase", Value: "$_id.coinbase"}, {Key: "totalRewards", Value: 1}, {Key: "rewardsCount", Value: 1}}}},
+		merge("coinbases", bson.A{"coinbase", "smesherId"}),
+	}, opts)
+	if err != nil {
+		return fmt.Errorf("error rebuild coinbases rewards counters: %w", err)
+	}
+	return nil
+}
+
+// incStats writes the counter updates to the collection of db: the stats database for the stats
+// collections, the main one for the counters of the raw collections.
+func (s *Storage) incStats(parent context.Context, db *Database, collection string, models []mongo.WriteModel) {
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	_, err := db.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("error update stats", err, logging.Collection(collection))
+	}
+}
+
+// upserted returns the items inserted by a bulk write of one upsert per item.
+func upserted[T any](items []T, res *mongo.BulkWriteResult) []T {
+	if res == nil {
+		return nil
+	}
+	inserted := make([]T, 0, len(res.UpsertedIDs))
+	for i := range res.UpsertedIDs {
+		inserted = append(inserted, items[i])
+	}
+	return inserted
+}
+
+// rebuildStats recomputes the materialized stats collections from the raw collections.
+func (s *Storage) rebuildStats(ctx context.Context) error {
+	rewardSums := bson.D{
+		{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total"}}},
+		{Key: "layerReward", Value: bson.D{{Key: "$sum", Value: "$layerReward"}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}
+
+	err := s.mergeStats(ctx, "txs", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{NotOrphaned}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$subtract", Value: bson.A{"$timestamp", bson.D{{Key: "$mod", Value: bson.A{"$timestamp", secondsPerDay}}}}}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "day", Value: "$_id"}, {Key: "count", Value: 1}, {Key: "amount", Value: 1}}}},
+	}, statsDailyTxsCollection, "day")
+	if err != nil {
+		return fmt.Errorf("error rebuild daily transactions: %w", err)
+	}
+
+	err = s.mergeStats(ctx, "rewards", mongo.Pipeline{
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: "$smesher"}}, rewardSums...)}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "smesher", Value: "$_id"}, {Key: "total", Value: 1}, {Key: "layerReward", Value: 1}, {Key: "count", Value: 1}}}},
+	}, statsSmeshersCollection, "smesher")
+	if err != nil {
+		return fmt.Errorf("error rebuild smeshers rewards: %w", err)
+	}
+	if err := s.rebuildBlockStats(ctx); err != nil {
+		return err
+	}
+	if err := s.rebuildTotals(ctx); err != nil {
+		return err
+	}
+
+	info, err := s.GetNetworkInfo(ctx)
+	if err != nil || info.EpochNumLayers == 0 {
+		// rewards are only collected after the network info
+		return nil
+	}
+	if err := s.rebuildDailyAccounts(ctx, info); err != nil {
+		return err
+	}
+	if err := s.rebuildTransactionTypes(ctx, info); err != nil {
+		return err
+	}
+	err = s.mergeStats(ctx, "rewards", mongo.Pipeline{
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: bson.D{{Key: "$toLong", Value: bson.D{{Key: "$floor", Value: bson.D{{Key: "$divide", Value: bson.A{"$layer", info.EpochNumLayers}}}}}}}}}, rewardSums...)}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "epoch", Value: "$_id"}, {Key: "total", Value: 1}, {Key: "layerReward", Value: 1}, {Key: "count", Value: 1}}}},
+	}, statsEpochRewardsCollection, "epoch")
+	if err != nil {
+		return fmt.Errorf("error rebuild epoch rewards: %w", err)
+	}
+	return nil
+}
+
+// rebuildDailyAccounts recomputes the daily accounts stats from the layers the accounts were first
+// seen at.
+func (s *Storage) rebuildDailyAccounts(ctx context.Context, info *model.NetworkInfo) error {
+	timestamp := bson.D{{Key: "$add", Value: bson.A{info.GenesisTime, bson.D{{Key: "$multiply", Value: bson.A{"$created", info.LayerDuration}}}}}}
+	err := s.mergeStats(ctx, "accounts", mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "$subtract", Value: bson.A{timestamp, bson.D{{Key: "$mod", Value: bson.A{timestamp, secondsPerDay}}}}}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 0}, {Key: "day", Value: "$_id"}, {Key: "count", Value: 1}}}},
+	}, statsDailyAccountsCollection, "day")
+	if err != nil {
+		return fmt.Errorf("error rebuild daily accounts: %w", err)
+	}
+	return nil
+}
+
+// rebuildTransactionTypes recomputes the transaction types stats from the transactions.
+func (s *Storage) rebuildTransactionTypes(ctx context.Context, info *model.NetworkInfo) error {
+	err := s.mergeStats(ctx, "txs", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{NotOrphaned}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "day", Value: bson.D{{Key: "$subtract", Value: bson.A{"$timestamp", bson.D{{Key: "$mod", Value: bson.A{"$timestamp", secondsPerDay}}}}}}},
+				{Key: "epoch", Value: bson.D{{Key: "$toLong", Value: bson.D{{Key: "$floor", Value: bson.D{{Key: "$divide", Value: bson.A{"$layer", info.EpochNumLayers}}}}}}}},
+				{Key: "type", Value: "$type"},
+			}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "day", Value: "$_id.day"},
+			{Key: "epoch", Value: "$_id.epoch"},
+			{Key: "type", Value: "$_id.type"},
+			{Key: "count", Value: 1},
+			{Key: "amount", Value: 1},
+		}}},
+	}, statsTxTypesCollection, "day", "epoch", "type")
+	if err != nil {
+		return fmt.Errorf("error rebuild transaction types: %w", err)
+	}
+	return nil
+}
+
+// rebuildBlockStats recomputes the blocks stats from the blocks, see model.NewBlockStats.
+func (s *Storage) rebuildBlockStats(ctx context.Context) error {
+	bucket := func(field string, bounds []uint64) bson.D {
+		branches := make(bson.A, 0, len(bounds))
+		for i := len(bounds) - 1; i > 0; i-- {
+			branches = append(branches, bson.D{
+				{Key: "case", Value: bson.D{{Key: "$gte", Value: bson.A{field, bounds[i]}}}},
+				{Key: "then", Value: bounds[i]},
+			})
+		}
+		return bson.D{{Key: "$switch", Value: bson.D{{Key: "branches", Value: branches}, {Key: "default", Value: bounds[0]}}}}
+	}
+	err := s.mergeStats(ctx, "blocks", mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{NotOrphaned}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "epoch", Value: "$epoch"},
+				{Key: "txsBucket", Value: bucket("$txsnumber", model.BlockTxsBuckets)},
+				{Key: "sizeBucket", Value: bucket("$size", model.BlockSizeBuckets)},
+			}},
+			{Key: "blocks", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "txs", Value: bson.D{{Key: "$sum", Value: "$txsnumber"}}},
+			{Key: "size", Value: bson.D{{Key: "$sum", Value: "$size"}}},
+			{Key: "maxTxs", Value: bson.D{{Key: "$max", Value: "$txsnumber"}}},
+			{Key: "maxSize", Value: bson.D{{Key: "$max", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$size", 0}}}}}},
+		}}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "epoch", Value: "$_id.epoch"},
+			{Key: "txsBucket", Value: "$_id.txsBucket"},
+			{Key: "sizeBucket", Value: "$_id.sizeBucket"},
+			{Key: "blocks", Value: 1},
+			{Key: "txs", Value: 1},
+			{Key: "size", Value: 1},
+			{Key: "maxTxs", Value: 1},
+			{Key: "maxSize", Value: 1},
+		}}},
+	}, statsBlocksCollection, "epoch", "txsBucket", "sizeBucket")
+	if err != nil {
+		return fmt.Errorf("error rebuild blocks stats: %w", err)
+	}
+	return nil
+}
+
+// rebuildTotals recomputes the global totals from the raw collections.
+func (s *Storage) rebuildTotals(ctx context.Context) error {
+	sum := func(source string, match bson.D, group bson.D) (bson.Raw, error) {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: match}},
+			{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: nil}}, group...)}},
+		}
+		if archive, ok := TierArchive(source); ok {
+			pipeline = append(mongo.Pipeline{{{Key: "$unionWith", Value: s.db.CollectionName(archive)}}}, pipeline...)
+		}
+		cursor, err := s.db.Collection(source).Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+		if !cursor.Next(ctx) {
+			return nil, cursor.Err()
+		}
+		return cursor.Current, nil
+	}
+	count := bson.D{{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}
+	lookup := func(doc bson.Raw, key string) int64 {
+		if doc == nil {
+			return 0
+		}
+		return utils.GetAsInt64(doc.Lookup(key))
+	}
+
+	set := bson.D{{Key: "id", Value: totalsID}, {Key: "updated", Value: uint32(time.Now().Unix())}}
+	for _, c := range []struct {
+		source, field string
+		match         bson.D
+	}{
+		{"txs", "txs", bson.D{NotOrphaned}},
+		{"accounts", "accounts", bson.D{}},
+		{"smeshers", "smeshers", bson.D{}},
+	} {
+		doc, err := sum(c.source, c.match, count)
+		if err != nil {
+			return fmt.Errorf("error rebuild total %s: %w", c.field, err)
+		}
+		set = append(set, bson.E{Key: c.field, Value: lookup(doc, "count")})
+	}
+	doc, err := sum("rewards", bson.D{}, append(bson.D{{Key: "total", Value: bson.D{{Key: "$sum", Value: "$total"}}}}, count...))
+	if err != nil {
+		return fmt.Errorf("error rebuild total rewards: %w", err)
+	}
+	set = append(set, bson.E{Key: "rewards", Value: lookup(doc, "total")}, bson.E{Key: "rewardsCount", Value: lookup(doc, "count")})
+
+	orphaned := bson.D{}
+	for _, collection := range []string{"blocks", "txs"} {
+		doc, err := sum(collection, bson.D{{Key: "orphaned", Value: true}}, count)
+		if err != nil {
+			return fmt.Errorf("error rebuild orphaned %s: %w", collection, err)
+		}
+		orphaned = append(orphaned, bson.E{Key: collection, Value: lookup(doc, "count")})
+	}
+	set = append(set, bson.E{Key: "orphaned", Value: orphaned})
+
+	cursor, err := s.db.Collection("activations").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$targetEpoch"},
+			{Key: "space", Value: bson.D{{Key: "$sum", Value: "$commitmentSize"}}},
+		}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return fmt.Errorf("error rebuild total space: %w", err)
+	}
+	defer cursor.Close(ctx)
+	space := bson.D{}
+	for cursor.Next(ctx) {
+		space = append(space, bson.E{Key: fmt.Sprint(utils.GetAsInt64(cursor.Current.Lookup("_id"))), Value: utils.GetAsInt64(cursor.Current.Lookup("space"))})
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("error rebuild total space: %w", err)
+	}
+	set = append(set, bson.E{Key: "space", Value: space})
+
+	_, err = s.statsDB.Collection(statsTotalsCollection).ReplaceOne(ctx, bson.D{{Key: "id", Value: totalsID}}, set, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("error rebuild totals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+// statsCollections are the aggregates computed from the raw chain data, they live in the stats
+// database, see OpenStatsDatabase.
+var statsCollections = map[string]bool{
+	statsDailyTxsCollection:      true,
+	statsDailyAccountsCollection: true,
+	statsTxTypesCollection:       true,
+	statsEpochRewardsCollection:  true,
+	statsSmeshersCollection:      true,
+	statsBlocksCollection:        true,
+	statsRollupDailyCollection:   true,
+	statsRollupWeeklyCollection:  true,
+	statsGeoHeatmapCollection:    true,
+	statsTotalsCollection:        true,
+	epochStatsCollection:         true,
+}
+
+// IsStatsCollection reports whether the collection lives in the stats database.
+func IsStatsCollection(name string) bool {
+	return statsCollections[name]
+}
+
+// OpenStatsDatabase moves the stats collections to the database `name` of the deployment at url,
+// so that the analytics load is isolated from the ingest path. An empty url keeps the deployment
+// of the raw data, an empty name its database name. The collections keep the prefix of the raw
+// data. The stats are rebuilt from the raw data if the stats database is new.
+func (s *Storage) OpenStatsDatabase(parent context.Context, url, name string, opts ...*options.ClientOptions) error {
+	if name == "" {
+		name = s.db.Name()
+	}
+	if url == "" && name == s.db.Name() {
+		return nil
+	}
+	if url == "" {
+		s.statsDB = NewDatabase(s.client, name, s.db.Prefix())
+	} else {
+		client, err := connect(parent, url, opts...)
+		if err != nil {
+			return fmt.Errorf("error connect to stats database: %w", err)
+		}
+		s.statsClient = client
+		s.statsDB = NewDatabase(client, name, s.db.Prefix())
+	}
+
+	if err := s.initStatsStorage(parent); err != nil {
+		return err
+	}
+	if err := s.initEpochStatsStorage(parent); err != nil {
+		return err
+	}
+	n, err := s.statsDB.Collection(statsDailyTxsCollection).EstimatedDocumentCount(parent)
+	if err != nil || n > 0 {
+		return err
+	}
+	logging.Info("rebuilding stats", zap.String("database", name))
+	return s.rebuildStats(parent)
+}
+
+// collection returns the collection from the database holding it.
+func (s *Storage) collection(name string) *mongo.Collection {
+	if statsCollections[name] {
+		return s.statsDB.Collection(name)
+	}
+	return s.db.Collection(name)
+}
+
+// mergeStats runs the pipeline on the raw collection, including its archive if it is tiered, and
+// replaces the documents of the stats collection matching the results on the `on` fields. The
+// results are merged by the server if the stats database is in the same deployment, and copied
+// otherwise.
+func (s *Storage) mergeStats(ctx context.Context, source string, pipeline mongo.Pipeline, collection string, on ...string) error {
+	opts := options.Aggregate().SetAllowDiskUse(true)
+	if archive, ok := TierArchive(source); ok {
+		pipeline = append(mongo.Pipeline{{{Key: "$unionWith", Value: s.db.CollectionName(archive)}}}, pipeline...)
+	}
+	if s.statsClient == nil {
+		_, err := s.db.Collection(source).Aggregate(ctx, append(pipeline, bson.D{{Key: "$merge", Value: bson.D{
+			{Key: "into", Value: bson.D{{Key: "db", Value: s.statsDB.Name()}, {Key: "coll", Value: s.statsDB.CollectionName(collection)}}},
+			{Key: "on", Value: on},
+			{Key: "whenMatched", Value: "replace"},
+			{Key: "whenNotMatched", Value: "insert"},
+		}}}), opts)
+		return err
+	}
+
+	cursor, err := s.db.Collection(source).Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	writes := make([]mongo.WriteModel, 0, bulkWriteBatchSize)
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := s.statsDB.Collection(collection).BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		filter := make(bson.D, 0, len(on))
+		for _, key := range on {
+			filter = append(filter, bson.E{Key: key, Value: cursor.Current.Lookup(key)})
+		}
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(doc).
+			SetUpsert(true))
+		if len(writes) == bulkWriteBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return flush()
+}
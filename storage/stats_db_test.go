@@ -0,0 +1,16 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsStatsCollection(t *testing.T) {
+	for _, name := range []string{"stats_daily_txs", "stats_daily_accounts", "stats_tx_types", "stats_epoch_rewards", "stats_smeshers", "stats_blocks", "stats_rollup_daily", "stats_rollup_weekly", "stats_geo_heatmap", "stats_totals", "epoch_stats"} {
+		require.True(t, IsStatsCollection(name), name)
+	}
+	for _, name := range []string{"epochs", "txs", "rewards", "smeshers"} {
+		require.False(t, IsStatsCollection(name), name)
+	}
+}
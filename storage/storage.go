@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"container/list"
 	"context"
 	"errors"
 	"fmt"
@@ -11,15 +10,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/internal/pipeline"
+	"github.com/spacemeshos/explorer-backend/internal/sink"
+	"github.com/spacemeshos/explorer-backend/internal/storage/cache"
 	"github.com/spacemeshos/explorer-backend/utils"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
 
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
 	"github.com/spacemeshos/explorer-backend/model"
-	"github.com/spacemeshos/go-spacemesh/log"
 )
 
 var (
@@ -47,6 +52,13 @@ var (
 	})
 )
 
+// layersQueueSize is the capacity of the layers queue. When the queue is full
+// the collector blocks on OnLayer until the writer catches up.
+const layersQueueSize = 64
+
+// bulkWriteBatchSize is the maximum number of documents written by a single bulk write.
+const bulkWriteBatchSize = 1000
+
 type AccountUpdaterService interface {
 	GetAccountState(address string) (uint64, uint64, error)
 }
@@ -56,95 +68,173 @@ type Storage struct {
 	postUnitSize uint64
 
 	client *mongo.Client
-	db     *mongo.Database
+	db     *Database
+	// statsDB holds the stats collections, it is db unless set by OpenStatsDatabase. statsClient
+	// is its client if it is another deployment.
+	statsDB     *Database
+	statsClient *mongo.Client
 
 	AccountUpdater AccountUpdaterService
 
+	// watched holds the addresses persisted in watch mode, nil if watch mode is disabled.
+	watched map[string]struct{}
+
+	// sinks receive a copy of every entity persisted to mongo.
+	sinks sink.Multi
+
+	// cache holds the documents read by the API, nil if the cache is disabled.
+	cache *cache.Cache
+
+	// timeouts bound the database operations, see SetTimeouts.
+	timeouts Timeouts
+	// retryPolicy bounds the retries of the reads failing with a transient error, see SetRetryPolicy.
+	retryPolicy RetryPolicy
+
+	// transactions is set if the deployment supports multi-document transactions.
+	transactions bool
+	// sharded is set if the deployment is a sharded cluster.
+	sharded bool
+	// externalIndexes is set if the indexes are managed out of the explorer, see SetCreateIndexes.
+	externalIndexes bool
+
+	// archiveEnabled is set if the raw node responses are archived, see SetArchive.
+	archiveEnabled bool
+
+	// retentionDone stops the retention runs, nil if no retention policy is set.
+	retentionDone chan struct{}
+	// tieringDone stops the tiering runs, nil if the tiering is disabled.
+	tieringDone chan struct{}
+	// snapshotsDone stops the account snapshot runs, nil if the snapshots are disabled.
+	snapshotsDone chan struct{}
+	// rollupsDone stops the rollup runs, nil if the rollups are disabled.
+	rollupsDone chan struct{}
+	// geoHeatmapDone stops the geo heat-map runs, nil if the heat-map is disabled.
+	geoHeatmapDone chan struct{}
+
 	sync.Mutex
 	changedEpoch int32
 	lastEpoch    int32
 
+	// summaryLock serializes the recomputes of the layer summaries, see updateLayerSummary.
+	summaryLock sync.Mutex
+
 	layersLock  sync.Mutex
-	layersQueue *list.List
-	layersReady *sync.Cond
+	layersQueue chan *pb.Layer
+	// layersPending counts the copies of every layer in the queue, a layer can be pushed again
+	// before its first copy is processed.
+	layersPending map[uint32]int
+	layersQueued  int
 
 	accountsLock  sync.Mutex
 	accountsQueue map[uint32]map[string]bool
 	accountsReady *sync.Cond
+	// accountsStopped is set under accountsReady.L by Close, updateAccounts then drains the queue
+	// and returns.
+	accountsStopped bool
+
+	// layersDone stops updateLayers, the loops are waited for by Close before disconnecting.
+	layersDone   chan struct{}
+	layersLoop   sync.WaitGroup
+	accountsLoop sync.WaitGroup
+	stopOnce     sync.Once
+
+	// received holds the received times of the transactions until their layer is processed.
+	received ReceivedTimes
 }
 
-func New(parent context.Context, dbUrl string, dbName string) (*Storage, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(dbUrl))
-
-	if err != nil {
+// New connects to the database, the options override the ones of the url, e.g. the write and
+// read concerns. The collection names start with the prefix, see Database.
+func New(parent context.Context, dbUrl string, dbName string, prefix string, opts ...*options.ClientOptions) (*Storage, error) {
+	if err := ValidatePrefix(prefix); err != nil {
 		return nil, err
 	}
-
-	err = client.Ping(ctx, nil)
-
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client, err := connect(ctx, dbUrl, opts...)
 	if err != nil {
 		return nil, err
 	}
 
 	s := &Storage{
 		client:        client,
-		layersQueue:   list.New(),
-		layersReady:   sync.NewCond(&sync.Mutex{}),
+		timeouts:      DefaultTimeouts,
+		retryPolicy:   DefaultRetryPolicy,
+		layersQueue:   make(chan *pb.Layer, layersQueueSize),
+		layersPending: make(map[uint32]int),
 		accountsQueue: make(map[uint32]map[string]bool),
 		accountsReady: sync.NewCond(&sync.Mutex{}),
+		layersDone:    make(chan struct{}),
 		changedEpoch:  -1,
 	}
-	s.db = client.Database(dbName)
+	s.db = NewDatabase(client, dbName, prefix)
+	s.statsDB = s.db
+	replicaSet, sharded := topology(ctx, s.db.Database)
+	s.transactions = replicaSet || sharded
+	s.sharded = sharded
 
-	err = s.InitAccountsStorage(ctx)
+	s.accountsLoop.Add(1)
+	go s.updateAccounts()
+	s.layersLoop.Add(1)
+	go s.updateLayers()
+
+	return s, nil
+}
+
+// connect connects to the deployment at url, see clientOptions.
+func connect(ctx context.Context, url string, opts ...*options.ClientOptions) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, clientOptions(url, opts...)...)
 	if err != nil {
-		log.Info("Init accounts storage error: %v", err)
+		return nil, err
 	}
-	err = s.InitActivationsStorage(ctx)
-	if err != nil {
-		log.Info("Init activations storage error: %v", err)
+	if err = client.Ping(ctx, nil); err != nil {
+		return nil, err
 	}
-	err = s.InitBlocksStorage(ctx)
-	if err != nil {
-		log.Info("Init blocks storage error: %v", err)
+	return client, nil
+}
+
+// clientOptions returns the options of the client of the deployment at url, the options override the
+// ones of the url. The collector reads back what it writes, so it always reads from the primary
+// whatever the read preference of the url shared with the API.
+func clientOptions(url string, opts ...*options.ClientOptions) []*options.ClientOptions {
+	opts = append([]*options.ClientOptions{options.Client().ApplyURI(url).SetMonitor(NewCommandMonitor())}, opts...)
+	return append(opts, options.Client().SetReadPreference(readpref.Primary()))
+}
+
+// Close stops the background runs and the layers and accounts loops, the accounts requested by the
+// processed layers are updated first, and disconnects from the database.
+func (s *Storage) Close() {
+	s.stopLoops()
+	if s.retentionDone != nil {
+		close(s.retentionDone)
 	}
-	err = s.InitEpochsStorage(ctx)
-	if err != nil {
-		log.Info("Init epochs storage error: %v", err)
+	if s.tieringDone != nil {
+		close(s.tieringDone)
 	}
-	err = s.InitLayersStorage(ctx)
-	if err != nil {
-		log.Info("Init layers storage error: %v", err)
+	if s.snapshotsDone != nil {
+		close(s.snapshotsDone)
 	}
-	err = s.InitRewardsStorage(ctx)
-	if err != nil {
-		log.Info("Init rewards storage error: %v", err)
+	if s.rollupsDone != nil {
+		close(s.rollupsDone)
 	}
-	err = s.InitSmeshersStorage(ctx)
-	if err != nil {
-		log.Info("Init smeshers storage error: %v", err)
+	if s.geoHeatmapDone != nil {
+		close(s.geoHeatmapDone)
 	}
-	err = s.InitTransactionsStorage(ctx)
-	if err != nil {
-		log.Info("Init transactions storage error: %v", err)
+	if err := s.sinks.Close(); err != nil {
+		logging.Error("error while closing sinks", err)
 	}
-
-	go s.updateAccounts()
-	go s.updateLayers()
-
-	return s, nil
-}
-
-func (s *Storage) Close() {
 	if s.client != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		s.db = nil
 		err := s.client.Disconnect(ctx)
 		if err != nil {
-			log.Err(fmt.Errorf("error while disconnecting from database: %v", err))
+			logging.Error("error while disconnecting from database", err)
+		}
+	}
+	if s.statsClient != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.statsClient.Disconnect(ctx); err != nil {
+			logging.Error("error while disconnecting from stats database", err)
 		}
 	}
 }
@@ -158,18 +248,20 @@ func (s *Storage) OnNetworkInfo(genesisId string, genesisTime uint64, epochNumLa
 	s.NetworkInfo.PostUnitSize = postUnitSize
 	s.postUnitSize = postUnitSize
 
-	err := s.SaveOrUpdateNetworkInfo(context.Background(), &s.NetworkInfo)
+	err := s.UpsertNetworkInfo(context.Background(), &s.NetworkInfo)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("OnNetworkInfo: error %v", err))
+		logging.Error("OnNetworkInfo", err)
+	} else {
+		s.invalidate(cache.KeyNetworkInfo)
 	}
 
-	log.Info("Network Info: id: %s, genesis: %v, epoch layers: %v, max tx: %v, duration: %v",
-		s.NetworkInfo.GenesisId,
-		s.NetworkInfo.GenesisTime,
-		s.NetworkInfo.EpochNumLayers,
-		s.NetworkInfo.MaxTransactionsPerSecond,
-		s.NetworkInfo.LayerDuration,
+	logging.Info("network info",
+		zap.String("genesis_id", s.NetworkInfo.GenesisId),
+		zap.Uint32("genesis_time", s.NetworkInfo.GenesisTime),
+		zap.Uint32("epoch_layers", s.NetworkInfo.EpochNumLayers),
+		zap.Uint32("max_tx_per_second", s.NetworkInfo.MaxTransactionsPerSecond),
+		zap.Uint32("layer_duration", s.NetworkInfo.LayerDuration),
 	)
 }
 
@@ -180,10 +272,12 @@ func (s *Storage) OnNodeStatus(connectedPeers uint64, isSynced bool, syncedLayer
 	s.NetworkInfo.TopLayer = topLayer
 	s.NetworkInfo.VerifiedLayer = verifiedLayer
 
-	err := s.SaveOrUpdateNetworkInfo(context.Background(), &s.NetworkInfo)
+	err := s.UpsertNetworkInfo(context.Background(), &s.NetworkInfo)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("OnNodeStatus: error %v", err))
+		logging.Error("OnNodeStatus", err)
+	} else {
+		s.invalidate(cache.KeyNetworkInfo)
 	}
 
 	metricNodeTopLayer.Set(float64(topLayer))
@@ -192,16 +286,11 @@ func (s *Storage) OnNodeStatus(connectedPeers uint64, isSynced bool, syncedLayer
 }
 
 func (s *Storage) GetEpochLayers(epoch int32) (uint32, uint32) {
-	start := uint32(epoch) * s.NetworkInfo.EpochNumLayers
-	end := start + s.NetworkInfo.EpochNumLayers - 1
-	return start, end
+	return utils.EpochLayers(uint32(epoch), s.NetworkInfo.EpochNumLayers)
 }
 
 func (s *Storage) GetEpochForLayer(layer uint32) uint32 {
-	if s.NetworkInfo.EpochNumLayers > 0 {
-		return layer / s.NetworkInfo.EpochNumLayers
-	}
-	return 0
+	return utils.LayerEpoch(layer, s.NetworkInfo.EpochNumLayers)
 }
 
 func (s *Storage) GetEpochNumLayers() uint32 {
@@ -209,8 +298,36 @@ func (s *Storage) GetEpochNumLayers() uint32 {
 }
 
 func (s *Storage) OnLayer(in *pb.Layer) {
+	s.archive(ArchiveLayer, []string{fmt.Sprint(in.GetNumber().GetNumber())}, []proto.Message{in})
 	s.pushLayer(in)
-	metricLayersQueueLen.Set(float64(s.layersQueue.Len()))
+}
+
+func (s *Storage) OnCertificates(certs []*model.BlockCertificate) {
+	if err := s.UpsertCertificates(context.Background(), certs); err != nil {
+		logging.Error("OnCertificates", err)
+		return
+	}
+	for _, cert := range certs {
+		s.sinks.Publish(context.Background(), sink.EntityCertificate, cert.BlockId, cert)
+	}
+}
+
+func (s *Storage) OnBallots(ballots []*model.Ballot) {
+	if err := s.UpsertBallots(context.Background(), ballots); err != nil {
+		logging.Error("OnBallots", err)
+	}
+}
+
+func (s *Storage) OnActiveSet(epoch uint32, size uint32) {
+	if err := s.UpsertEpochActiveSetSize(context.Background(), int32(epoch), size); err != nil {
+		logging.Error("OnActiveSet", err)
+	}
+}
+
+func (s *Storage) OnBeacon(epoch uint32, beacon string) {
+	if err := s.UpsertEpochBeacon(context.Background(), int32(epoch), beacon); err != nil {
+		logging.Error("OnBeacon", err)
+	}
 }
 
 func (s *Storage) OnMalfeasanceProof(in *pb.MalfeasanceProof) {
@@ -218,18 +335,32 @@ func (s *Storage) OnMalfeasanceProof(in *pb.MalfeasanceProof) {
 }
 
 func (s *Storage) OnAccounts(accounts []*types.Account) {
-	log.Info("OnAccounts")
+	logging.Info("OnAccounts", zap.Int("accounts", len(accounts)))
+	defer pipeline.Observe(pipeline.StageWriteAccounts, time.Now())
 
 	var updateOps []mongo.WriteModel
+	var published []*model.Account
 
 	for _, acc := range accounts {
+		if !s.isWatched(acc.Address.String()) {
+			continue
+		}
+		published = append(published, &model.Account{
+			Address: acc.Address.String(),
+			Balance: acc.Balance,
+			Counter: acc.NextNonce,
+			Created: uint64(acc.Layer.Uint32()),
+		})
 		filter := bson.D{{Key: "address", Value: acc.Address.String()}}
+		// the snapshot holds the layer the account was last updated at, the account keeps the first
+		// one, e.g. the genesis layer of the genesis accounts
 		update := bson.D{
 			{Key: "$set", Value: bson.D{
 				{Key: "balance", Value: acc.Balance},
 				{Key: "counter", Value: acc.NextNonce},
-				{Key: "created", Value: acc.Layer.Uint32()},
 			}},
+			{Key: "$min", Value: bson.D{{Key: "created", Value: acc.Layer.Uint32()}}},
+			{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
 		}
 
 		updateModel := mongo.NewUpdateOneModel()
@@ -241,96 +372,196 @@ func (s *Storage) OnAccounts(accounts []*types.Account) {
 	}
 
 	if len(updateOps) > 0 {
-		_, err := s.db.Collection("accounts").BulkWrite(context.TODO(), updateOps)
+		var created []uint32
+		err := s.inTransaction(context.Background(), func(ctx context.Context) error {
+			addresses := make([]string, 0, len(published))
+			layers := make([]uint32, 0, len(published))
+			for _, acc := range published {
+				addresses = append(addresses, acc.Address)
+				layers = append(layers, uint32(acc.Created))
+			}
+			previous, err := s.getBalances(ctx, addresses)
+			if err != nil {
+				return err
+			}
+			res, err := s.db.Collection("accounts").BulkWrite(ctx, updateOps)
+			if err != nil {
+				return err
+			}
+			created = upserted(layers, res)
+			changes := make([]*model.BalanceChange, 0, len(published))
+			for _, acc := range published {
+				changes = append(changes, model.NewBalanceChange(acc.Address, uint32(acc.Created), previous[acc.Address], acc.Balance))
+			}
+			return s.saveBalanceChanges(ctx, changes)
+		})
 		if err != nil {
-			log.Err(fmt.Errorf("OnAccounts: error accounts write %v", err))
+			logging.Error("OnAccounts: accounts write", err)
+			return
 		}
+		s.incAccountsStats(context.Background(), created)
+		s.invalidate(cache.KeyTopAccounts)
+	}
+	for _, acc := range published {
+		s.sinks.Publish(context.Background(), sink.EntityAccount, acc.Address, acc)
 	}
 }
 
 func (s *Storage) OnReward(in *pb.Reward) {
-	log.Info("OnReward(%+v)", in)
-	reward := model.NewReward(in)
-	if reward == nil {
-		return
+	logging.Info("OnReward", logging.Layer(in.Layer.GetNumber()), zap.String("coinbase", in.Coinbase.GetAddress()))
+	s.OnRewards([]*pb.Reward{in})
+}
+
+// OnRewards stores the rewards and touches the coinbase accounts with bulk writes of at most
+// bulkWriteBatchSize documents.
+func (s *Storage) OnRewards(in []*pb.Reward) {
+	for len(in) > bulkWriteBatchSize {
+		s.saveRewards(in[:bulkWriteBatchSize])
+		in = in[bulkWriteBatchSize:]
 	}
-	reward.Timestamp = s.getLayerTimestamp(reward.Layer)
+	s.saveRewards(in)
+}
 
-	err := s.SaveReward(context.Background(), reward)
-	//TODO: better error handling
-	if err != nil {
-		log.Err(fmt.Errorf("OnReward save: error %v", err))
+func (s *Storage) saveRewards(in []*pb.Reward) {
+	defer pipeline.Observe(pipeline.StageWriteReward, time.Now())
+
+	rewards := make([]*model.Reward, 0, len(in))
+	chain := make([]*model.Reward, 0, len(in))
+	archived := make([]proto.Message, 0, len(in))
+	ids := make([]string, 0, len(in))
+	for _, r := range in {
+		reward := model.NewReward(r)
+		if reward == nil {
+			continue
+		}
+		reward.Timestamp = s.getLayerTimestamp(reward.Layer)
+		chain = append(chain, reward)
+		if !s.isWatched(reward.Coinbase) {
+			continue
+		}
+		rewards = append(rewards, reward)
+		archived = append(archived, r)
+		ids = append(ids, fmt.Sprintf("%s-%d", reward.Smesher, reward.Layer))
+	}
+	s.saveChainRewards(context.Background(), chain)
+	if len(rewards) == 0 {
+		return
 	}
+	s.archive(ArchiveReward, ids, archived)
 
-	err = s.AddAccount(context.Background(), reward.Layer, reward.Coinbase, 0)
+	if err := s.linkRewardActivations(context.Background(), rewards); err != nil {
+		logging.Error("OnRewards link activations", err)
+	}
+	err := s.UpsertRewards(context.Background(), rewards)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("OnReward add account: error %v", err))
+		logging.Error("OnRewards save", err)
+	} else {
+		for _, reward := range rewards {
+			s.sinks.Publish(context.Background(), sink.EntityReward, fmt.Sprintf("%s-%d", reward.Smesher, reward.Layer), reward)
+		}
 	}
 
-	err = s.AddAccountReward(context.Background(), reward.Layer, reward.Coinbase, reward.Total, reward.LayerReward)
+	accountsUpdateOps := make([]mongo.WriteModel, 0, len(rewards))
+	layers := make([]uint32, 0, len(rewards))
+	for _, reward := range rewards {
+		accountsUpdateOps = append(accountsUpdateOps, s.UpsertAccountQuery(reward.Layer, reward.Coinbase, 0))
+		layers = append(layers, reward.Layer)
+	}
+	res, err := s.db.Collection("accounts").BulkWrite(context.TODO(), accountsUpdateOps, options.BulkWrite().SetOrdered(false))
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("OnReward add account reward: error %v", err))
+		logging.Error("OnRewards add accounts", err)
+	} else {
+		s.incAccountsStats(context.Background(), upserted(layers, res))
+		s.invalidate(cache.KeyTopAccounts)
 	}
 
-	s.requestBalanceUpdate(reward.Layer, reward.Coinbase)
+	for _, reward := range rewards {
+		s.requestBalanceUpdate(reward.Layer, reward.Coinbase)
+	}
 }
 
 func (s *Storage) UpdateEpochStats(layer uint32) {
+	start := time.Now()
+	s.updateLayerSummary(layer)
 	s.setChangedEpoch(layer)
 	s.updateEpochs()
+	pipeline.Observe(pipeline.StageEpochStats, start)
 }
 
 func (s *Storage) OnTransactionResult(res *pb.TransactionResult, state *pb.TransactionState) {
-	log.Info("OnTransactionReceipt(%+v, %+v)", res, state)
+	logging.Info("OnTransactionResult", zap.String("tx", utils.BytesToHex(res.GetTx().GetId())), zap.Stringer("state", state.GetState()))
 	tx, err := model.NewTransactionResult(res, state, s.NetworkInfo)
 	if err != nil {
-		log.Err(fmt.Errorf("OnTransactionResult: error %v", err))
+		logging.Error("OnTransactionResult", err)
+		return
 	}
+	if !s.isWatchedTransaction(tx) {
+		return
+	}
+	s.archive(ArchiveTransactionResult, []string{tx.Id}, []proto.Message{res})
 
-	err = s.SaveTransactionResult(context.Background(), tx)
+	err = s.UpsertTransactionResult(context.Background(), tx)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("OnTransactionResult: error %v", err))
+		logging.Error("OnTransactionResult", err)
+	} else {
+		s.sinks.Publish(context.Background(), sink.EntityTransaction, tx.Id, tx)
 	}
 }
 
+// OnTransactionsReceived holds the times the node first received the transactions, until their
+// layer is processed from the queue.
+func (s *Storage) OnTransactionsReceived(received map[string]uint32) {
+	s.received.Add(received)
+}
+
 func (s *Storage) pushLayer(layer *pb.Layer) {
+	start := time.Now()
 	s.layersLock.Lock()
-	s.layersQueue.PushBack(layer)
+	s.layersPending[layer.Number.Number]++
+	s.layersQueued++
+	s.reportLayersQueue()
 	s.layersLock.Unlock()
-	s.layersReady.Signal()
-}
 
-func (s *Storage) IsLayerInQueue(layer *pb.Layer) bool {
-	for l := s.layersQueue.Front(); l != nil; l = l.Next() {
-		if val, ok := l.Value.(*pb.Layer); ok {
-			if val.Number.Number == layer.Number.Number {
-				return true
-			}
-		}
-	}
-	return false
+	s.layersQueue <- layer
+	pipeline.Observe(pipeline.StageEnqueueLayer, start)
 }
 
-func (s *Storage) processLayer() *pb.Layer {
+func (s *Storage) IsLayerInQueue(layer *pb.Layer) bool {
 	s.layersLock.Lock()
 	defer s.layersLock.Unlock()
-	l := s.layersQueue.Front()
-	if l == nil {
-		return nil
-	}
+	_, ok := s.layersPending[layer.Number.Number]
+	return ok
+}
 
-	layer := l.Value.(*pb.Layer)
+func (s *Storage) processLayer(layer *pb.Layer) {
+	start := time.Now()
 	s.updateLayer(layer)
+	pipeline.Observe(pipeline.StageWriteLayer, start)
 
-	s.layersQueue.Remove(l)
-
+	s.layerProcessed(layer.Number.Number)
 	metricLastProcessedLayer.Set(float64(layer.Number.Number))
-	metricLayersQueueLen.Set(float64(s.layersQueue.Len()))
+}
 
-	return layer
+// layerProcessed removes a copy of the layer from the pending layers.
+func (s *Storage) layerProcessed(number uint32) {
+	s.layersLock.Lock()
+	defer s.layersLock.Unlock()
+	if s.layersPending[number] <= 1 {
+		delete(s.layersPending, number)
+	} else {
+		s.layersPending[number]--
+	}
+	s.layersQueued--
+	s.reportLayersQueue()
+}
+
+// reportLayersQueue must be called with layersLock held.
+func (s *Storage) reportLayersQueue() {
+	metricLayersQueueLen.Set(float64(s.layersQueued))
+	pipeline.SetQueueDepth(pipeline.StageWriteLayer, s.layersQueued)
 }
 
 func (s *Storage) requestBalanceUpdate(layer uint32, address string) {
@@ -341,24 +572,37 @@ func (s *Storage) requestBalanceUpdate(layer uint32, address string) {
 		s.accountsQueue[layer] = accounts
 	}
 	accounts[address] = true
+	pipeline.SetQueueDepth(pipeline.StageAccountBalances, s.accountsQueueLen())
 	s.accountsLock.Unlock()
 	s.accountsReady.Signal()
 }
 
-func (s *Storage) getAccountsQueue(accounts map[string]bool) int {
+// getAccountsQueue moves the accounts of the confirmed layers to accounts, with the last layer
+// their balance was requested for.
+func (s *Storage) getAccountsQueue(accounts map[string]uint32) int {
 	s.accountsLock.Lock()
 	defer s.accountsLock.Unlock()
 	for layer, accs := range s.accountsQueue {
 		if layer <= s.NetworkInfo.LastConfirmedLayer {
 			for acc := range accs {
-				accounts[acc] = true
+				accounts[acc] = max(accounts[acc], layer)
 			}
 		}
 		delete(s.accountsQueue, layer)
 	}
+	pipeline.SetQueueDepth(pipeline.StageAccountBalances, s.accountsQueueLen())
 	return len(accounts)
 }
 
+// accountsQueueLen must be called with accountsLock held.
+func (s *Storage) accountsQueueLen() int {
+	n := 0
+	for _, accs := range s.accountsQueue {
+		n += len(accs)
+	}
+	return n
+}
+
 func (s *Storage) getChangedEpoch() int32 {
 	s.Lock()
 	defer s.Unlock()
@@ -385,22 +629,46 @@ func (s *Storage) setChangedEpoch(layer uint32) {
 
 func (s *Storage) updateLayer(in *pb.Layer) {
 	layer, blocks, atxs, txs := model.NewLayer(in, &s.NetworkInfo)
-	log.Info("updateLayer(%v) -> %v, %v, %v, %v, %v", in.Number.Number, layer.Number, len(blocks), len(atxs), len(txs), utils.BytesToHex(in.Hash))
+	start := time.Now()
+	defer func() {
+		logging.Info("updateLayer", logging.Layer(layer.Number), zap.Int("blocks", len(blocks)), zap.Int("atxs", len(atxs)), zap.Int("txs", len(txs)), zap.String("hash", utils.BytesToHex(in.Hash)), logging.Duration(time.Since(start)))
+	}()
+	s.received.Apply(txs)
 	s.updateNetworkStatus(layer)
 
-	err := s.SaveOrUpdateBlocks(context.Background(), blocks)
+	previous, err := s.layerHash(context.Background(), layer.Number)
+	if err != nil {
+		logging.Error("updateLayer", err, logging.Layer(layer.Number))
+	}
+
+	err = s.UpsertBlocks(context.Background(), blocks)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateLayer: error %v", err))
+		logging.Error("updateLayer", err, logging.Layer(layer.Number))
+	} else {
+		for _, block := range blocks {
+			s.sinks.Publish(context.Background(), sink.EntityBlock, block.Id, block)
+		}
 	}
 
 	s.updateTransactions(layer, txs)
 
-	err = s.SaveOrUpdateLayer(context.Background(), layer)
+	if LayerReorged(previous, layer.Hash) {
+		if err := s.tombstoneLayer(context.Background(), layer, blocks, txs); err != nil {
+			logging.Error("updateLayer", err, logging.Layer(layer.Number))
+		}
+	}
+
+	s.incChainTransactions(context.Background(), layer)
+	err = s.UpsertLayer(context.Background(), layer)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateLayer: error %v", err))
+		logging.Error("updateLayer", err, logging.Layer(layer.Number))
+	} else {
+		s.sinks.Publish(context.Background(), sink.EntityLayer, fmt.Sprint(layer.Number), layer)
+		s.invalidate(cache.KeyCurrentLayer, cache.KeyTopAccounts)
 	}
+	s.updateLayerSummary(layer.Number)
 
 	s.setChangedEpoch(layer.Number)
 	s.accountsReady.Signal()
@@ -416,42 +684,93 @@ func (s *Storage) updateNetworkStatus(layer *model.Layer) {
 		s.NetworkInfo.LastConfirmedLayer = layer.Number
 	}
 
-	err := s.SaveOrUpdateNetworkInfo(context.Background(), &s.NetworkInfo)
+	err := s.UpsertNetworkInfo(context.Background(), &s.NetworkInfo)
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateNetworkStatus: error %v", err))
+		logging.Error("updateNetworkStatus", err)
+	} else {
+		s.invalidate(cache.KeyNetworkInfo)
 	}
 }
 
 func (s *Storage) OnActivation(atx *types.VerifiedActivationTx) {
-	log.Info("OnActivation(%s)", atx.ShortString())
+	logging.Info("OnActivation", zap.String("atx", atx.ShortString()))
 
 	activation := model.NewActivation(atx)
+	s.saveChainActivations(context.Background(), []*model.Activation{activation})
+	if !s.isWatched(activation.Coinbase) {
+		return
+	}
 
-	err := s.SaveOrUpdateActivation(context.Background(), activation)
+	err := s.UpsertActivation(context.Background(), activation)
 	if err != nil {
-		log.Err(fmt.Errorf("OnActivation: error %v", err))
+		logging.Error("OnActivation", err)
+	} else {
+		s.sinks.Publish(context.Background(), sink.EntityActivation, activation.Id, activation)
+	}
+	err = s.linkActivationRewards(context.Background(), []*model.Activation{activation}, s.GetEpochNumLayers())
+	if err != nil {
+		logging.Error("OnActivation: link rewards", err)
 	}
 
-	err = s.UpdateSmesher(context.Background(), activation.GetSmesher(s.postUnitSize), activation.TargetEpoch)
+	err = s.UpsertSmesher(context.Background(), activation.GetSmesher(s.postUnitSize), activation.TargetEpoch)
 	if err != nil {
-		log.Err(fmt.Errorf("OnActivation: update smesher error %v", err))
+		logging.Error("OnActivation: update smesher", err)
 	}
 
 	epochNumLayers := s.GetEpochNumLayers()
-	err = s.AddAccount(context.Background(), epochNumLayers*activation.PublishEpoch, activation.Coinbase, 0)
+	account := s.UpsertAccountQuery(epochNumLayers*activation.PublishEpoch, activation.Coinbase, 0)
+	res, err := s.db.Collection("accounts").UpdateOne(context.Background(), account.Filter, account.Update, options.Update().SetUpsert(true))
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateActivations: error %v", err))
+		logging.Error("updateActivations", err)
+	} else if res.UpsertedCount > 0 {
+		s.incAccountsStats(context.Background(), []uint32{epochNumLayers * activation.PublishEpoch})
 	}
 }
 
+// OnActivations stores the activations and updates their smeshers, coinbases and accounts by batches
+// of at most bulkWriteBatchSize activations. Every batch is written in its own transaction, so the
+// activations of a whole epoch never hold a single transaction.
 func (s *Storage) OnActivations(atxs []*model.Activation) {
-	log.Info("OnActivations(%d)", len(atxs))
+	logging.Info("OnActivations", zap.Int("atxs", len(atxs)))
+	if s.watched != nil {
+		chain := atxs
+		for len(chain) > bulkWriteBatchSize {
+			s.saveChainActivations(context.Background(), chain[:bulkWriteBatchSize])
+			chain = chain[bulkWriteBatchSize:]
+		}
+		s.saveChainActivations(context.Background(), chain)
+		watched := make([]*model.Activation, 0, len(atxs))
+		for _, atx := range atxs {
+			if s.isWatched(atx.Coinbase) {
+				watched = append(watched, atx)
+			}
+		}
+		atxs = watched
+	}
+
+	for len(atxs) > bulkWriteBatchSize {
+		s.saveActivations(atxs[:bulkWriteBatchSize])
+		atxs = atxs[bulkWriteBatchSize:]
+	}
+	s.saveActivations(atxs)
+}
 
-	err := s.SaveOrUpdateActivations(context.Background(), atxs)
+func (s *Storage) saveActivations(atxs []*model.Activation) {
+	if len(atxs) == 0 {
+		return
+	}
+	err := s.UpsertActivations(context.Background(), atxs)
 	if err != nil {
-		log.Err(fmt.Errorf("OnActivation: error %v", err))
+		logging.Error("OnActivation", err)
+	} else {
+		for _, atx := range atxs {
+			s.sinks.Publish(context.Background(), sink.EntityActivation, atx.Id, atx)
+		}
+	}
+	if err := s.linkActivationRewards(context.Background(), atxs, s.GetEpochNumLayers()); err != nil {
+		logging.Error("OnActivations: link rewards", err)
 	}
 
 	epochNumLayers := s.GetEpochNumLayers()
@@ -459,80 +778,122 @@ func (s *Storage) OnActivations(atxs []*model.Activation) {
 	var coinbaseUpdateOps []mongo.WriteModel
 	var smesherUpdateOps []mongo.WriteModel
 	var accountsUpdateOps []mongo.WriteModel
+	smeshers := make([]*model.Smesher, 0, len(atxs))
+	epochs := make([]uint32, 0, len(atxs))
+	layers := make([]uint32, 0, len(atxs))
 
 	for _, atx := range atxs {
-		smesherUpdateOps = append(smesherUpdateOps, s.SaveSmesherQuery(atx.GetSmesher(s.postUnitSize)))
-		coinbaseOp, smesherOp := s.UpdateSmesherQuery(atx.GetSmesher(s.postUnitSize), atx.TargetEpoch)
+		smesher := atx.GetSmesher(s.postUnitSize)
+		smeshers = append(smeshers, smesher)
+		epochs = append(epochs, atx.TargetEpoch)
+		coinbaseOp, smesherOp := s.UpsertSmesherQuery(smesher, atx.TargetEpoch)
 		coinbaseUpdateOps = append(coinbaseUpdateOps, coinbaseOp)
 		smesherUpdateOps = append(smesherUpdateOps, smesherOp)
-		accountsUpdateOps = append(accountsUpdateOps, s.AddAccountQuery(epochNumLayers*atx.PublishEpoch, atx.Coinbase, 0))
+		accountsUpdateOps = append(accountsUpdateOps, s.UpsertAccountQuery(epochNumLayers*atx.PublishEpoch, atx.Coinbase, 0))
+		layers = append(layers, epochNumLayers*atx.PublishEpoch)
 	}
 
-	if len(smesherUpdateOps) > 0 {
-		_, err = s.db.Collection("smeshers").BulkWrite(context.TODO(), smesherUpdateOps)
-		if err != nil {
-			log.Err(fmt.Errorf("OnActivations: error smeshers write %v", err))
+	var created []uint32
+	var newSmeshers int64
+	err = s.inTransaction(context.Background(), func(ctx context.Context) error {
+		// the changes are computed from the smeshers before the update
+		if len(smeshers) > 0 {
+			historyOps, err := s.smesherChanges(ctx, smeshers, epochs)
+			if err != nil {
+				return err
+			}
+			if len(historyOps) > 0 {
+				if _, err := s.db.Collection(smesherHistoryCollection).BulkWrite(ctx, historyOps, options.BulkWrite().SetOrdered(false)); err != nil {
+					return fmt.Errorf("error smesher history write: %w", err)
+				}
+			}
 		}
-	}
-
-	if len(coinbaseUpdateOps) > 0 {
-		_, err = s.db.Collection("coinbases").BulkWrite(context.TODO(), coinbaseUpdateOps)
-		if err != nil {
-			log.Err(fmt.Errorf("OnActivations: error smeshers write %v", err))
+		if len(smesherUpdateOps) > 0 {
+			res, err := s.db.Collection("smeshers").BulkWrite(ctx, smesherUpdateOps)
+			if err != nil {
+				return fmt.Errorf("error smeshers write: %w", err)
+			}
+			newSmeshers = res.UpsertedCount
 		}
-	}
-
-	if len(accountsUpdateOps) > 0 {
-		_, err = s.db.Collection("accounts").BulkWrite(context.TODO(), accountsUpdateOps)
-		if err != nil {
-			log.Err(fmt.Errorf("OnActivations: error accounts write %v", err))
+		if len(coinbaseUpdateOps) > 0 {
+			if _, err := s.db.Collection("coinbases").BulkWrite(ctx, coinbaseUpdateOps); err != nil {
+				return fmt.Errorf("error coinbases write: %w", err)
+			}
+		}
+		if len(accountsUpdateOps) > 0 {
+			res, err := s.db.Collection("accounts").BulkWrite(ctx, accountsUpdateOps)
+			if err != nil {
+				return fmt.Errorf("error accounts write: %w", err)
+			}
+			created = upserted(layers, res)
+		}
+		return nil
+	})
+	if err != nil {
+		logging.Error("OnActivations", err)
+	} else {
+		s.incAccountsStats(context.Background(), created)
+		// in watch mode the smeshers of the whole chain are counted by saveChainActivations
+		if s.watched == nil {
+			s.incSmeshersTotals(context.Background(), newSmeshers)
 		}
 	}
 }
 
 func (s *Storage) updateTransactions(layer *model.Layer, txs map[string]*model.Transaction) {
-	log.Info("updateTransactions")
+	logging.Info("updateTransactions", logging.Layer(layer.Number), zap.Int("txs", len(txs)))
+	watched := make([]*model.Transaction, 0, len(txs))
 	for _, tx := range txs {
-		err := s.SaveTransaction(context.Background(), tx)
-		if err != nil {
-			continue
+		if s.isWatchedTransaction(tx) {
+			watched = append(watched, tx)
 		}
+	}
+	if len(watched) == 0 {
+		return
+	}
+	if err := s.UpsertTransactions(context.Background(), watched); err != nil {
+		logging.Error("updateTransactions", err)
+		return
+	}
+	if err := s.saveVaults(context.Background(), watched); err != nil {
+		logging.Error("updateTransactions", err)
+	}
 
-		if tx.Sender != "" {
-			err := s.AddAccount(context.Background(), layer.Number, tx.Sender, 0)
-			//TODO: better error handling
-			if err != nil {
-				log.Err(fmt.Errorf("updateTransactions: error %v", err))
-			}
-
-			err = s.AddAccountSent(context.Background(), layer.Number, tx.Sender, tx.Amount, tx.Fee)
-			//TODO: better error handling
-			if err != nil {
-				log.Err(fmt.Errorf("updateTransactions: error %v", err))
+	var accountsUpdateOps []mongo.WriteModel
+	var layers []uint32
+	touched := make(map[string]bool)
+	for _, tx := range watched {
+		s.sinks.Publish(context.Background(), sink.EntityTransaction, tx.Id, tx)
+		for _, address := range []string{tx.Sender, tx.Receiver} {
+			if address == "" || touched[address] {
+				continue
 			}
-
-			s.requestBalanceUpdate(layer.Number, tx.Sender)
+			touched[address] = true
+			accountsUpdateOps = append(accountsUpdateOps, s.UpsertAccountQuery(layer.Number, address, 0))
+			layers = append(layers, layer.Number)
 		}
-		if tx.Receiver != "" {
-			err := s.AddAccount(context.Background(), layer.Number, tx.Receiver, 0)
-			//TODO: better error handling
-			if err != nil {
-				log.Err(fmt.Errorf("updateTransactions: error %v", err))
-			}
-
-			err = s.AddAccountReceived(context.Background(), layer.Number, tx.Receiver, tx.Amount)
-			//TODO: better error handling
-			if err != nil {
-				log.Err(fmt.Errorf("updateTransactions: error %v", err))
-			}
-			s.requestBalanceUpdate(layer.Number, tx.Receiver)
+		if template := tx.SpawnedTemplate(); template != "" {
+			accountsUpdateOps = append(accountsUpdateOps, s.AccountTemplateQuery(tx.Sender, template))
+			layers = append(layers, layer.Number)
+		}
+	}
+	if len(accountsUpdateOps) > 0 {
+		res, err := s.db.Collection("accounts").BulkWrite(context.TODO(), accountsUpdateOps, options.BulkWrite().SetOrdered(false))
+		//TODO: better error handling
+		if err != nil {
+			logging.Error("updateTransactions", err)
+		} else {
+			s.incAccountsStats(context.Background(), upserted(layers, res))
 		}
 	}
+	for address := range touched {
+		s.requestBalanceUpdate(layer.Number, address)
+	}
 }
 
 func (s *Storage) updateEpoch(epochNumber int32, prev *model.Epoch) *model.Epoch {
-	log.Info("updateEpoch(%v)", epochNumber)
-	epoch := &model.Epoch{Number: epochNumber}
+	start := time.Now()
+	epoch := &model.Epoch{Number: epochNumber, StatsVersion: model.EpochStatsVersion}
 	s.computeStatistics(epoch)
 	if prev != nil {
 		epoch.Stats.Cumulative.Capacity = epoch.Stats.Current.Capacity
@@ -545,16 +906,56 @@ func (s *Storage) updateEpoch(epochNumber int32, prev *model.Epoch) *model.Epoch
 		epoch.Stats.Cumulative.RewardsNumber = prev.Stats.Cumulative.RewardsNumber + epoch.Stats.Current.RewardsNumber
 		epoch.Stats.Cumulative.Security = prev.Stats.Current.Security
 		epoch.Stats.Cumulative.TxsAmount = prev.Stats.Cumulative.TxsAmount + epoch.Stats.Current.TxsAmount
+		epoch.Stats.Cumulative.FeesDistributed = prev.Stats.Cumulative.FeesDistributed + epoch.Stats.Current.FeesDistributed
+		epoch.Stats.Cumulative.FeesBurned = prev.Stats.Cumulative.FeesBurned + epoch.Stats.Current.FeesBurned
+		epoch.Stats.Cumulative.SpaceGini = epoch.Stats.Current.SpaceGini
+		epoch.Stats.Cumulative.SpaceNakamoto = epoch.Stats.Current.SpaceNakamoto
+		epoch.Stats.Cumulative.RewardsGini = epoch.Stats.Current.RewardsGini
+		epoch.Stats.Cumulative.RewardsNakamoto = epoch.Stats.Current.RewardsNakamoto
+		epoch.Stats.Cumulative.NewSmeshers = epoch.Stats.Current.NewSmeshers
+		epoch.Stats.Cumulative.ReturningSmeshers = epoch.Stats.Current.ReturningSmeshers
+		epoch.Stats.Cumulative.StoppedSmeshers = epoch.Stats.Current.StoppedSmeshers
+		epoch.Stats.Current.SpaceDelta = epoch.Stats.Current.Space - prev.Stats.Current.Space
+		epoch.Stats.Cumulative.Space = epoch.Stats.Current.Space
+		epoch.Stats.Cumulative.SpaceDelta = epoch.Stats.Current.SpaceDelta
+		epoch.Stats.Current.NewAccounts = epoch.Stats.Current.Accounts - prev.Stats.Current.Accounts
+		epoch.Stats.Cumulative.NewAccounts = epoch.Stats.Current.NewAccounts
+		epoch.Stats.Cumulative.FeesCollected = prev.Stats.Cumulative.FeesCollected + epoch.Stats.Current.FeesCollected
+		epoch.Stats.Cumulative.FeeMin = epoch.Stats.Current.FeeMin
+		epoch.Stats.Cumulative.FeeMedian = epoch.Stats.Current.FeeMedian
+		epoch.Stats.Cumulative.FeeMax = epoch.Stats.Current.FeeMax
+		epoch.Stats.Cumulative.EmptyLayers = prev.Stats.Cumulative.EmptyLayers + epoch.Stats.Current.EmptyLayers
+		epoch.Stats.Cumulative.LayersWithoutTxs = prev.Stats.Cumulative.LayersWithoutTxs + epoch.Stats.Current.LayersWithoutTxs
+		epoch.Stats.Cumulative.RewardedSmeshers = epoch.Stats.Current.RewardedSmeshers
+		epoch.Stats.Cumulative.EffectiveNumUnits = epoch.Stats.Current.EffectiveNumUnits
+		epoch.Stats.Cumulative.Weight = epoch.Stats.Current.Weight
+		epoch.Stats.Cumulative.InclusionTxs = epoch.Stats.Current.InclusionTxs
+		epoch.Stats.Cumulative.InclusionMean = epoch.Stats.Current.InclusionMean
+		epoch.Stats.Cumulative.InclusionMedian = epoch.Stats.Current.InclusionMedian
+		epoch.Stats.Cumulative.InclusionP90 = epoch.Stats.Current.InclusionP90
+		epoch.Stats.Cumulative.InclusionP99 = epoch.Stats.Current.InclusionP99
+		epoch.Stats.Cumulative.Voters = epoch.Stats.Current.Voters
+		epoch.Stats.Cumulative.Ballots = epoch.Stats.Current.Ballots
+		epoch.Stats.Cumulative.Votes = epoch.Stats.Current.Votes
+		epoch.Stats.Cumulative.BallotEligibilities = epoch.Stats.Current.BallotEligibilities
 		epoch.Stats.Current.Circulation = epoch.Stats.Cumulative.Rewards
 		epoch.Stats.Cumulative.Circulation = epoch.Stats.Current.Circulation
 	} else {
 		epoch.Stats.Current.Circulation = epoch.Stats.Current.Rewards
+		epoch.Stats.Current.SpaceDelta = epoch.Stats.Current.Space
+		epoch.Stats.Current.NewAccounts = epoch.Stats.Current.Accounts
 		epoch.Stats.Cumulative = epoch.Stats.Current
 	}
-	err := s.SaveOrUpdateEpoch(context.Background(), epoch)
+	err := s.UpsertEpoch(context.Background(), epoch)
+	if err == nil {
+		err = s.saveEpochStats(context.Background(), epoch)
+	}
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateEpoch: error %v", err))
+		logging.Error("updateEpoch", err, logging.Epoch(uint32(epochNumber)))
+	} else {
+		s.invalidate(cache.KeyCurrentEpoch)
+		logging.Info("updateEpoch", logging.Epoch(uint32(epochNumber)), logging.Duration(time.Since(start)))
 	}
 
 	return epoch
@@ -573,59 +974,115 @@ func (s *Storage) updateEpochs() {
 	}
 }
 
-func (s *Storage) updateAccount(address string) {
-	balance, counter, err := s.AccountUpdater.GetAccountState(address)
-	if err != nil {
-		return
-	}
-	log.Info("Update account %v: balance %v, counter %v", address, balance, counter)
+// updateAccount refreshes the balance of the account from the node. The balance is compared and
+// set on the version of the account read before the node request, and requested again if the
+// account was written meanwhile, so that a stale state never overwrites a newer one.
+func (s *Storage) updateAccount(address string, layer uint32) {
+	defer pipeline.Observe(pipeline.StageAccountBalances, time.Now())
+	var err error
+	for attempt := 0; attempt < AccountUpdateAttempts; attempt++ {
+		var version uint64
+		if version, err = s.GetAccountVersion(context.Background(), address); err != nil {
+			break
+		}
+		balance, counter, stateErr := s.AccountUpdater.GetAccountState(address)
+		if stateErr != nil {
+			return
+		}
+		logging.Info("update account", logging.Address(address), zap.Uint64("balance", balance), zap.Uint64("counter", counter))
 
-	err = s.UpdateAccount(context.Background(), address, balance, counter)
+		err = s.inTransaction(context.Background(), func(ctx context.Context) error {
+			previous, err := s.getBalances(ctx, []string{address})
+			if err != nil {
+				return err
+			}
+			if err := s.UpdateAccount(ctx, address, version, balance, counter); err != nil {
+				return err
+			}
+			return s.saveBalanceChanges(ctx, []*model.BalanceChange{model.NewBalanceChange(address, layer, previous[address], balance)})
+		})
+		if !errors.Is(err, ErrStaleAccount) {
+			break
+		}
+		logging.Info("update account: changed during the update, retrying", logging.Address(address))
+	}
 	//TODO: better error handling
 	if err != nil {
-		log.Err(fmt.Errorf("updateEpoch: error %v", err))
+		logging.Error("updateAccount", err, zap.String("address", address), logging.Layer(layer))
+	} else {
+		s.invalidate(cache.KeyTopAccounts)
 	}
 }
 
 func (s *Storage) updateLayers() {
+	defer s.layersLoop.Done()
 	for {
-		s.layersReady.L.Lock()
-		s.layersReady.Wait()
-		s.layersReady.L.Unlock()
-
-		for s.processLayer() != nil {
-			log.Info("processing layer")
+		select {
+		case layer := <-s.layersQueue:
+			logging.Info("processing layer", logging.Layer(layer.Number.Number))
+			s.processLayer(layer)
+		case <-s.layersDone:
+			return
 		}
 	}
 }
 
 func (s *Storage) updateAccounts() {
+	defer s.accountsLoop.Done()
 	for {
 		s.accountsReady.L.Lock()
-		s.accountsReady.Wait()
+		if !s.accountsStopped {
+			s.accountsReady.Wait()
+		}
+		stopped := s.accountsStopped
 		s.accountsReady.L.Unlock()
 
-		accounts := make(map[string]bool)
+		accounts := make(map[string]uint32)
 		if s.getAccountsQueue(accounts) > 0 {
-			for address := range accounts {
-				s.updateAccount(address)
+			for address, layer := range accounts {
+				s.updateAccount(address, layer)
 			}
 		}
+		if stopped {
+			return
+		}
 	}
 }
 
+// stopLoops stops updateLayers, then updateAccounts once it has drained the accounts requested by
+// the processed layers, and waits for them. The layers still queued are not processed, Flush of the
+// collector waits for them before.
+func (s *Storage) stopLoops() {
+	if s.layersDone == nil {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.layersDone)
+		s.layersLoop.Wait()
+
+		s.accountsReady.L.Lock()
+		s.accountsStopped = true
+		s.accountsReady.L.Unlock()
+		s.accountsReady.Broadcast()
+		s.accountsLoop.Wait()
+	})
+}
+
 func (s *Storage) updateMalfeasanceProof(in *pb.MalfeasanceProof) {
 	proof := model.NewMalfeasanceProof(in)
 	if proof == nil {
 		return
 	}
 
-	log.Info("updateMalfeasanceProof -> %v, %v, %v", proof.Layer, proof.Smesher, proof.Kind)
+	logging.Info("updateMalfeasanceProof", logging.Layer(proof.Layer), zap.String("smesher", proof.Smesher), zap.String("kind", proof.Kind))
+	s.archive(ArchiveMalfeasanceProof, []string{fmt.Sprintf("%s-%d", proof.Smesher, proof.Layer)}, []proto.Message{in})
 
-	err := s.SaveMalfeasanceProof(context.Background(), proof)
+	err := s.UpsertMalfeasanceProof(context.Background(), proof)
 	if err != nil {
-		log.Err(fmt.Errorf("updateMalfeasanceProof: %v", err))
+		logging.Error("updateMalfeasanceProof", err)
+		return
 	}
+	s.sinks.Publish(context.Background(), sink.EntityMalfeasanceProof, proof.Smesher, proof)
 }
 
 func (s *Storage) GetEpochLayersFilter(epochNumber int32, key string) *bson.D {
@@ -654,5 +1111,5 @@ func (s *Storage) Ping() error {
 func (s *Storage) LayersInQueue() int {
 	s.layersLock.Lock()
 	defer s.layersLock.Unlock()
-	return s.layersQueue.Len()
+	return s.layersQueued
 }
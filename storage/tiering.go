@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+)
+
+const (
+	// TieringInterval is the period of the tiering runs.
+	TieringInterval = time.Hour
+	// TieringPause is the pause between two batches of moved documents, so that the tiering does
+	// not starve the collector and the API queries.
+	TieringPause = 100 * time.Millisecond
+)
+
+var metricTieringMoved = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "explorer_tiering_moved_documents",
+	Help: "Number of documents moved to the archive collections by the tiering",
+}, []string{"collection"})
+
+// tier is a collection whose old documents are moved to a compressed archive collection. The age
+// of a document is the age of the layer in LayerField.
+type tier struct {
+	Collection string
+	Archive    string
+	LayerField string
+}
+
+var tiers = []tier{
+	{Collection: "layers", Archive: "layers_archive", LayerField: "number"},
+	{Collection: "txs", Archive: "txs_archive", LayerField: "layer"},
+	{Collection: "blocks", Archive: "blocks_archive", LayerField: "layer"},
+}
+
+// TierArchive returns the archive collection holding the old documents of the collection, or false
+// if the collection is not tiered.
+func TierArchive(collection string) (string, bool) {
+	for _, t := range tiers {
+		if t.Collection == collection {
+			return t.Archive, true
+		}
+	}
+	return "", false
+}
+
+// initTieringStorage creates the archive collections compressed with zstd, which trades some CPU on
+// the rare reads of the deep history for a fraction of the disk of the default snappy, with the
+// indexes of the API queries.
+func (s *Storage) initTieringStorage(ctx context.Context) error {
+	compressed := options.CreateCollection().SetStorageEngine(bson.D{{Key: "wiredTiger", Value: bson.D{
+		{Key: "configString", Value: "block_compressor=zstd"},
+	}}})
+	archives := make([]string, 0, len(tiers))
+	for _, t := range tiers {
+		err := s.db.CreateCollection(ctx, t.Archive, compressed)
+		var cmdErr mongo.CommandError
+		if err != nil && !(errors.As(err, &cmdErr) && cmdErr.Name == "NamespaceExists") {
+			return fmt.Errorf("error create `%s` collection: %w", t.Archive, err)
+		}
+		archives = append(archives, t.Archive)
+	}
+	if err := s.createIndexes(ctx, archives...); err != nil {
+		return err
+	}
+	return s.createQueryIndexes(ctx, archives...)
+}
+
+// TieringCutoff returns the first layer kept in the hot collections when the documents older than
+// the given number of epochs are archived, or false if no layer is old enough yet.
+func TieringCutoff(epochs uint32, lastLayer uint32, epochNumLayers uint32) (uint32, bool) {
+	if epochNumLayers == 0 || epochs == 0 {
+		return 0, false
+	}
+	epoch := lastLayer / epochNumLayers
+	if epoch <= epochs {
+		return 0, false
+	}
+	return (epoch - epochs) * epochNumLayers, true
+}
+
+// SetTiering starts moving the layers, transactions and blocks older than the given number of
+// epochs to their archive collections every TieringInterval. The API reads the archive when a
+// query goes past the hot documents. The documents are not updated once archived, so the number of
+// epochs must exceed the depth of the reorgs and of the recalculated epoch stats.
+func (s *Storage) SetTiering(epochs uint32) {
+	if epochs == 0 {
+		return
+	}
+	s.tieringDone = make(chan struct{})
+	go s.runTiering(epochs)
+}
+
+func (s *Storage) runTiering(epochs uint32) {
+	ticker := time.NewTicker(TieringInterval)
+	defer ticker.Stop()
+	for {
+		before, ok := TieringCutoff(epochs, s.NetworkInfo.LastLayer, s.NetworkInfo.EpochNumLayers)
+		for _, t := range tiers {
+			if !ok {
+				break
+			}
+			n, err := s.moveToArchive(t, before)
+			if err != nil {
+				logging.Error("tiering", err, logging.Collection(t.Collection))
+			}
+			if n > 0 {
+				logging.Info("tiering", logging.Collection(t.Collection), zap.Int64("archived", n), logging.Layer(before))
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-s.tieringDone:
+			return
+		}
+	}
+}
+
+// moveToArchive moves the documents of layers before the given one to the archive collection by
+// batches of bulkWriteBatchSize documents, pausing between the batches. A document is copied before
+// it is removed, so an interrupted batch is copied again by the next run.
+func (s *Storage) moveToArchive(t tier, before uint32) (int64, error) {
+	filter := bson.D{{Key: t.LayerField, Value: bson.D{{Key: "$lt", Value: before}}}}
+	var total int64
+	for {
+		n, err := s.moveBatch(t, filter)
+		total += n
+		metricTieringMoved.WithLabelValues(t.Collection).Add(float64(n))
+		if err != nil || n == 0 {
+			return total, err
+		}
+		select {
+		case <-time.After(TieringPause):
+		case <-s.tieringDone:
+			return total, nil
+		}
+	}
+}
+
+func (s *Storage) moveBatch(t tier, filter bson.D) (int64, error) {
+	ctx, cancel := s.bulkContext(context.Background())
+	defer cancel()
+	cursor, err := s.db.Collection(t.Collection).Find(ctx, filter, options.Find().SetLimit(bulkWriteBatchSize))
+	if err != nil {
+		return 0, err
+	}
+	var docs []bson.Raw
+	if err := cursor.All(ctx, &docs); err != nil || len(docs) == 0 {
+		return 0, err
+	}
+	ids := make(bson.A, 0, len(docs))
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		id := doc.Lookup("_id")
+		ids = append(ids, id)
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: id}}).
+			SetReplacement(doc).
+			SetUpsert(true))
+	}
+	if _, err := s.db.Collection(t.Archive).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return 0, fmt.Errorf("error copy to `%s`: %w", t.Archive, err)
+	}
+	res, err := s.db.Collection(t.Collection).DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		return 0, fmt.Errorf("error remove archived documents: %w", err)
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieringCutoff(t *testing.T) {
+	_, ok := TieringCutoff(2, 100, 0)
+	require.False(t, ok)
+	_, ok = TieringCutoff(0, 100, 10)
+	require.False(t, ok)
+	_, ok = TieringCutoff(2, 25, 10)
+	require.False(t, ok)
+
+	before, ok := TieringCutoff(2, 35, 10)
+	require.True(t, ok)
+	require.EqualValues(t, 10, before)
+}
+
+func TestTierArchive(t *testing.T) {
+	archive, ok := TierArchive("txs")
+	require.True(t, ok)
+	require.Equal(t, "txs_archive", archive)
+
+	_, ok = TierArchive("rewards")
+	require.False(t, ok)
+}
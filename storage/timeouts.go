@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts bound the storage operations by class, so that scans of whole collections are not
+// cut by the limit of the point reads.
+type Timeouts struct {
+	// Query bounds the point reads, counts, finds and single document writes.
+	Query time.Duration
+	// Bulk bounds the bulk writes.
+	Bulk time.Duration
+	// Aggregate bounds the aggregation pipelines.
+	Aggregate time.Duration
+}
+
+// DefaultTimeouts are the timeouts used unless SetTimeouts is called.
+var DefaultTimeouts = Timeouts{
+	Query:     5 * time.Second,
+	Bulk:      30 * time.Second,
+	Aggregate: time.Minute,
+}
+
+// WithDefault returns the timeouts with the unset classes set to d, or to DefaultTimeouts if d
+// is not set either.
+func (t Timeouts) WithDefault(d time.Duration) Timeouts {
+	fill := func(v *time.Duration, def time.Duration) {
+		switch {
+		case *v > 0:
+		case d > 0:
+			*v = d
+		default:
+			*v = def
+		}
+	}
+	fill(&t.Query, DefaultTimeouts.Query)
+	fill(&t.Bulk, DefaultTimeouts.Bulk)
+	fill(&t.Aggregate, DefaultTimeouts.Aggregate)
+	return t
+}
+
+// SetTimeouts sets the timeouts of the storage operations, the unset classes keep DefaultTimeouts.
+func (s *Storage) SetTimeouts(t Timeouts) {
+	s.timeouts = t.WithDefault(0)
+}
+
+func (s *Storage) queryContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, s.timeouts.Query)
+}
+
+func (s *Storage) bulkContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, s.timeouts.Bulk)
+}
+
+func (s *Storage) aggregateContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, s.timeouts.Aggregate)
+}
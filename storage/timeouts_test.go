@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutsWithDefault(t *testing.T) {
+	require.Equal(t, DefaultTimeouts, Timeouts{}.WithDefault(0))
+	require.Equal(t,
+		Timeouts{Query: time.Second, Bulk: time.Minute, Aggregate: time.Minute},
+		Timeouts{Query: time.Second}.WithDefault(time.Minute))
+	require.Equal(t,
+		Timeouts{Query: 5 * time.Second, Bulk: 30 * time.Second, Aggregate: 10 * time.Minute},
+		Timeouts{Aggregate: 10 * time.Minute}.WithDefault(0))
+}
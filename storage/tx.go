@@ -3,133 +3,94 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 
-	"github.com/spacemeshos/go-spacemesh/log"
-
+	"github.com/spacemeshos/explorer-backend/internal/logging"
 	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
+const redecodeBatchSize = 1000
+
 func (s *Storage) InitTransactionsStorage(ctx context.Context) error {
-	models := []mongo.IndexModel{
-		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetName("idIndex").SetUnique(true)},
-		{Keys: bson.D{{Key: "layer", Value: 1}}, Options: options.Index().SetName("layerIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "block", Value: 1}}, Options: options.Index().SetName("blockIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "sender", Value: 1}}, Options: options.Index().SetName("senderIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "receiver", Value: 1}}, Options: options.Index().SetName("receiverIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "timestamp", Value: -1}}, Options: options.Index().SetName("timestampIndex").SetUnique(false)},
-		{Keys: bson.D{{Key: "counter", Value: -1}}, Options: options.Index().SetName("counterIndex").SetUnique(false)},
-	}
-	_, err := s.db.Collection("txs").Indexes().CreateMany(ctx, models, options.CreateIndexes().SetMaxTime(20*time.Second))
-	return err
+	if err := s.createIndexes(ctx, "txs"); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, "txs")
 }
 
 func (s *Storage) GetTransaction(parent context.Context, query *bson.D) (*model.Transaction, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("txs").Find(ctx, query)
-	if err != nil {
-		log.Info("GetTransaction: %v", err)
+	tx := &model.Transaction{}
+	if err := s.findOne(parent, "txs", query, tx); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			logging.Info("GetTransaction: Empty result")
+			return nil, errors.New("empty result")
+		}
+		logging.Error("GetTransaction", err)
 		return nil, err
 	}
-	if !cursor.Next(ctx) {
-		log.Info("GetTransaction: Empty result")
-		return nil, errors.New("empty result")
-	}
-	doc := cursor.Current
-	tx := &model.Transaction{
-		Id:         utils.GetAsString(doc.Lookup("id")),
-		Layer:      utils.GetAsUInt32(doc.Lookup("layer")),
-		Block:      utils.GetAsString(doc.Lookup("block")),
-		BlockIndex: utils.GetAsUInt32(doc.Lookup("blockIndex")),
-		Index:      utils.GetAsUInt32(doc.Lookup("index")),
-		State:      utils.GetAsInt(doc.Lookup("state")),
-		Timestamp:  utils.GetAsUInt32(doc.Lookup("timestamp")),
-		MaxGas:     utils.GetAsUInt64(doc.Lookup("maxGas")),
-		GasPrice:   utils.GetAsUInt64(doc.Lookup("gasPrice")),
-		GasUsed:    utils.GetAsUInt64(doc.Lookup("gasUsed")),
-		Fee:        utils.GetAsUInt64(doc.Lookup("fee")),
-		Amount:     utils.GetAsUInt64(doc.Lookup("amount")),
-		Counter:    utils.GetAsUInt64(doc.Lookup("counter")),
-		Type:       utils.GetAsInt(doc.Lookup("type")),
-		Signature:  utils.GetAsString(doc.Lookup("signature")),
-		PublicKey:  utils.GetAsString(doc.Lookup("pubKey")),
-		Sender:     utils.GetAsString(doc.Lookup("sender")),
-		Receiver:   utils.GetAsString(doc.Lookup("receiver")),
-		SvmData:    utils.GetAsString(doc.Lookup("svmData")),
-	}
 	return tx, nil
 }
 
 func (s *Storage) GetTransactionsCount(parent context.Context, query *bson.D, opts ...*options.CountOptions) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("txs").CountDocuments(ctx, query, opts...)
+	count, err := s.countDocuments(parent, "txs", query, opts...)
 	if err != nil {
-		log.Info("GetTransactionsCount: %v", err)
+		logging.Error("GetTransactionsCount", err)
 		return 0
 	}
 	return count
 }
 
-func (s *Storage) GetTransactionsAmount(parent context.Context, query *bson.D) int64 {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// GetTransactionsStats returns the number and the total amount of the transactions matching the
+// query, summed by the database.
+func (s *Storage) GetTransactionsStats(parent context.Context, query *bson.D) (count, amount int64, err error) {
+	ctx, cancel := s.aggregateContext(parent)
 	defer cancel()
-	matchStage := bson.D{
-		{Key: "$match", Value: query},
-	}
-	groupStage := bson.D{
-		{Key: "$group", Value: bson.D{
-			{Key: "_id", Value: ""},
-			{Key: "amount", Value: bson.D{
-				{Key: "$sum", Value: "$amount"},
-			}},
-		}},
-	}
 	cursor, err := s.db.Collection("txs").Aggregate(ctx, mongo.Pipeline{
-		matchStage,
-		groupStage,
+		{{Key: "$match", Value: query}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: ""},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
 	})
 	if err != nil {
-		log.Info("GetTransactionsAmount: %v", err)
-		return 0
+		return 0, 0, err
 	}
+	defer cursor.Close(ctx)
 	if !cursor.Next(ctx) {
-		log.Info("GetTransactionsAmount: Empty result")
-		return 0
+		return 0, 0, cursor.Err()
+	}
+	var stats struct {
+		Count  int64 `bson:"count"`
+		Amount int64 `bson:"amount"`
 	}
-	doc := cursor.Current
-	return utils.GetAsInt64(doc.Lookup("amount"))
+	if err := cursor.Decode(&stats); err != nil {
+		return 0, 0, err
+	}
+	return stats.Count, stats.Amount, nil
 }
 
 func (s *Storage) IsTransactionExists(parent context.Context, txId string) bool {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	count, err := s.db.Collection("txs").CountDocuments(ctx, bson.D{{Key: "id", Value: txId}})
+	count, err := s.countDocuments(parent, "txs", bson.D{{Key: "id", Value: txId}})
 	if err != nil {
-		log.Info("IsTransactionExists: %v", err)
+		logging.Error("IsTransactionExists", err)
 		return false
 	}
 	return count > 0
 }
 
 func (s *Storage) GetTransactions(parent context.Context, query *bson.D, opts ...*options.FindOptions) ([]model.Transaction, error) {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-	defer cancel()
-	cursor, err := s.db.Collection("txs").Find(ctx, query, opts...)
-	if err != nil {
-		log.Info("GetTransactions: %v", err)
-		return nil, err
-	}
 	var txs []model.Transaction
-	err = cursor.All(ctx, &txs)
+	err := s.findAll(parent, "txs", query, &txs, opts...)
 	if err != nil {
-		log.Info("GetTransactions: %v", err)
+		logging.Error("GetTransactions", err)
 		return nil, err
 	}
 	if len(txs) == 0 {
@@ -138,81 +99,125 @@ func (s *Storage) GetTransactions(parent context.Context, query *bson.D, opts ..
 	return txs, nil
 }
 
-func (s *Storage) SaveTransaction(parent context.Context, in *model.Transaction) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// UpsertTransaction stores the transaction, see UpsertTransactions.
+func (s *Storage) UpsertTransaction(parent context.Context, in *model.Transaction) error {
+	return s.UpsertTransactions(parent, []*model.Transaction{in})
+}
+
+// UpsertTransactions stores the transactions keyed on their id with a single unordered bulk write.
+// It keeps the state and result of the transactions already stored.
+func (s *Storage) UpsertTransactions(parent context.Context, txs []*model.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	ctx, cancel := s.bulkContext(parent)
 	defer cancel()
 
-	transaction, err := s.GetTransaction(ctx, &bson.D{{Key: "id", Value: in.Id}})
-	if err != nil && err.Error() != "empty result" {
+	ids := make(bson.A, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, tx.Id)
+	}
+	cursor, err := s.db.Collection("txs").Find(ctx, bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for cursor.Next(ctx) {
+		existing[utils.GetAsString(cursor.Current.Lookup("id"))] = true
+	}
+	if err := cursor.Err(); err != nil {
 		return err
 	}
 
-	tx := bson.D{
-		{
-			Key: "$set",
-			Value: bson.D{
-				{Key: "id", Value: in.Id},
-				{Key: "layer", Value: in.Layer},
-				{Key: "block", Value: in.Block},
-				{Key: "blockIndex", Value: in.BlockIndex},
-				{Key: "index", Value: in.Index},
-				{Key: "state", Value: in.State},
-				{Key: "timestamp", Value: in.Timestamp},
-				{Key: "maxGas", Value: in.MaxGas},
-				{Key: "gasPrice", Value: in.GasPrice},
-				{Key: "gasUsed", Value: in.GasUsed},
-				{Key: "fee", Value: in.Fee},
-				{Key: "amount", Value: in.Amount},
-				{Key: "counter", Value: in.Counter},
-				{Key: "type", Value: in.Type},
-				{Key: "signature", Value: in.Signature},
-				{Key: "pubKey", Value: in.PublicKey},
-				{Key: "sender", Value: in.Sender},
-				{Key: "receiver", Value: in.Receiver},
-				{Key: "svmData", Value: in.SvmData},
-				{Key: "message", Value: in.Message},
-				{Key: "touchedAddresses", Value: in.TouchedAddresses},
-			},
-		},
+	models := make([]mongo.WriteModel, 0, len(txs))
+	for _, tx := range txs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "id", Value: tx.Id}}).
+			SetUpdate(transactionUpdate(tx, existing[tx.Id])).
+			SetUpsert(true))
+	}
+	res, err := s.db.Collection("txs").BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		logging.Error("UpsertTransactions", err)
 	}
+	s.incTransactionsStats(parent, upserted(txs, res))
+	return err
+}
 
-	if transaction != nil {
-		tx = bson.D{
-			{
-				Key: "$set",
-				Value: bson.D{
-					{Key: "id", Value: in.Id},
-					{Key: "layer", Value: in.Layer},
-					{Key: "block", Value: in.Block},
-					{Key: "blockIndex", Value: in.BlockIndex},
-					{Key: "index", Value: in.Index},
-					{Key: "timestamp", Value: in.Timestamp},
-					{Key: "maxGas", Value: in.MaxGas},
-					{Key: "gasPrice", Value: in.GasPrice},
-					{Key: "fee", Value: in.Fee},
-					{Key: "amount", Value: in.Amount},
-					{Key: "counter", Value: in.Counter},
-					{Key: "type", Value: in.Type},
-					{Key: "signature", Value: in.Signature},
-					{Key: "pubKey", Value: in.PublicKey},
-					{Key: "sender", Value: in.Sender},
-					{Key: "receiver", Value: in.Receiver},
-					{Key: "svmData", Value: in.SvmData},
-				},
-			},
+// transactionUpdate returns the update of a transaction from the mesh. The state, gas used and
+// result set from the transaction result are not overwritten if the transaction already exists, nor
+// the received time if it is not known.
+func transactionUpdate(in *model.Transaction, exists bool) bson.D {
+	var fields bson.D
+	if exists {
+		fields = bson.D{
+			{Key: "id", Value: in.Id},
+			{Key: "layer", Value: in.Layer},
+			{Key: "block", Value: in.Block},
+			{Key: "blockIndex", Value: in.BlockIndex},
+			{Key: "index", Value: in.Index},
+			{Key: "timestamp", Value: in.Timestamp},
+			{Key: "maxGas", Value: in.MaxGas},
+			{Key: "svmData", Value: in.SvmData},
+			{Key: "method", Value: in.Method},
+			{Key: "raw", Value: in.Raw},
 		}
+	} else {
+		fields = bson.D{
+			{Key: "id", Value: in.Id},
+			{Key: "layer", Value: in.Layer},
+			{Key: "block", Value: in.Block},
+			{Key: "blockIndex", Value: in.BlockIndex},
+			{Key: "index", Value: in.Index},
+			{Key: "state", Value: in.State},
+			{Key: "timestamp", Value: in.Timestamp},
+			{Key: "maxGas", Value: in.MaxGas},
+			{Key: "gasUsed", Value: in.GasUsed},
+			{Key: "svmData", Value: in.SvmData},
+			{Key: "message", Value: in.Message},
+			{Key: "touchedAddresses", Value: in.TouchedAddresses},
+			{Key: "method", Value: in.Method},
+			{Key: "raw", Value: in.Raw},
+		}
+	}
+	fields = append(fields, TransactionDecodedFields(in)...)
+	fields = append(fields, transactionTemplate(in)...)
+	if in.Received > 0 {
+		fields = append(fields, bson.E{Key: "received", Value: in.Received})
 	}
+	return bson.D{{Key: "$set", Value: fields}}
+}
 
-	_, err = s.db.Collection("txs").UpdateOne(ctx,
-		bson.D{{Key: "id", Value: in.Id}}, tx, options.Update().SetUpsert(true))
-	if err != nil {
-		log.Info("SaveTransaction: %v obj: %+v", err, tx)
+// TransactionDecodedFields returns the fields of the transaction decoded from its raw payload, with
+// the fee computed from the decoded gas price, as stored on insert and by RedecodeTransactions.
+func TransactionDecodedFields(in *model.Transaction) bson.D {
+	return bson.D{
+		{Key: "gasPrice", Value: in.GasPrice},
+		{Key: "fee", Value: in.Fee},
+		{Key: "amount", Value: in.Amount},
+		{Key: "counter", Value: in.Counter},
+		{Key: "type", Value: in.Type},
+		{Key: "signature", Value: in.Signature},
+		{Key: "pubKey", Value: in.PublicKey},
+		{Key: "sender", Value: in.Sender},
+		{Key: "receiver", Value: in.Receiver},
 	}
-	return err
 }
 
-func (s *Storage) SaveTransactionResult(parent context.Context, in *model.Transaction) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+// transactionTemplate returns the template name of the transaction to set, none if the template of
+// its principal is unknown so that it does not clear a backfilled one.
+func transactionTemplate(in *model.Transaction) bson.D {
+	if in.Template == "" {
+		return nil
+	}
+	return bson.D{{Key: "template", Value: in.Template}}
+}
+
+// UpsertTransactionResult stores the result of the transaction, and the transaction itself if it
+// is not stored yet.
+func (s *Storage) UpsertTransactionResult(parent context.Context, in *model.Transaction) error {
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 
 	transaction, err := s.GetTransaction(ctx, &bson.D{{Key: "id", Value: in.Id}})
@@ -223,7 +228,7 @@ func (s *Storage) SaveTransactionResult(parent context.Context, in *model.Transa
 	tx := bson.D{
 		{
 			Key: "$set",
-			Value: bson.D{
+			Value: append(bson.D{
 				{Key: "id", Value: in.Id},
 				{Key: "layer", Value: in.Layer},
 				{Key: "block", Value: in.Block},
@@ -246,7 +251,9 @@ func (s *Storage) SaveTransactionResult(parent context.Context, in *model.Transa
 				{Key: "message", Value: in.Message},
 				{Key: "touchedAddresses", Value: in.TouchedAddresses},
 				{Key: "result", Value: in.Result},
-			},
+				{Key: "method", Value: in.Method},
+				{Key: "raw", Value: in.Raw},
+			}, transactionTemplate(in)...),
 		},
 	}
 
@@ -254,7 +261,7 @@ func (s *Storage) SaveTransactionResult(parent context.Context, in *model.Transa
 		tx = bson.D{
 			{
 				Key: "$set",
-				Value: bson.D{
+				Value: append(bson.D{
 					{Key: "id", Value: in.Id},
 					{Key: "state", Value: in.State},
 					{Key: "gasUsed", Value: in.GasUsed},
@@ -262,21 +269,33 @@ func (s *Storage) SaveTransactionResult(parent context.Context, in *model.Transa
 					{Key: "message", Value: in.Message},
 					{Key: "touchedAddresses", Value: in.TouchedAddresses},
 					{Key: "result", Value: in.Result},
-				},
+				}, transactionTemplate(in)...),
 			},
 		}
 	}
 
-	_, err = s.db.Collection("txs").UpdateOne(ctx,
+	res, err := s.db.Collection("txs").UpdateOne(ctx,
 		bson.D{{Key: "id", Value: in.Id}}, tx, options.Update().SetUpsert(true))
 	if err != nil {
-		log.Info("SaveTransactionResult: %v obj: %+v", err, tx)
+		logging.Error("UpsertTransactionResult", err, logging.Collection("txs"), zap.String("tx", in.Id))
+		return err
 	}
-	return err
+	if res.UpsertedCount > 0 {
+		s.incTransactionsStats(parent, []*model.Transaction{in})
+	}
+	// the result does not move the stored transaction, nor orphan it
+	stored := *in
+	if transaction != nil {
+		stored.Layer, stored.Orphaned = transaction.Layer, transaction.Orphaned
+	}
+	if TransactionFee(&stored) != TransactionFee(transaction) {
+		s.updateLayerSummary(stored.Layer)
+	}
+	return nil
 }
 
 func (s *Storage) UpdateTransactionState(parent context.Context, id string, state int32) error {
-	ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+	ctx, cancel := s.queryContext(parent)
 	defer cancel()
 
 	tx := bson.D{
@@ -288,10 +307,134 @@ func (s *Storage) UpdateTransactionState(parent context.Context, id string, stat
 		},
 	}
 
-	_, err := s.db.Collection("txs").UpdateOne(ctx,
-		bson.D{{Key: "id", Value: id}}, tx)
+	var before model.Transaction
+	err := s.db.Collection("txs").FindOneAndUpdate(ctx, bson.D{{Key: "id", Value: id}}, tx,
+		options.FindOneAndUpdate().SetProjection(bson.D{
+			{Key: "layer", Value: 1},
+			{Key: "state", Value: 1},
+			{Key: "fee", Value: 1},
+			{Key: "orphaned", Value: 1},
+		})).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
 	if err != nil {
-		log.Info("UpdateTransactionState: %v obj: %+v", err, tx)
+		logging.Error("UpdateTransactionState", err, logging.Collection("txs"), zap.String("tx", id))
+		return err
 	}
-	return err
+	after := before
+	after.State = int(state)
+	if TransactionFee(&after) != TransactionFee(&before) {
+		s.updateLayerSummary(before.Layer)
+	}
+	return nil
+}
+
+// RedecodeTransactions re-parses the stored raw payload of every transaction and updates the decoded
+// fields and the fee, see TransactionDecodedFields. Transactions stored before raw payloads were
+// persisted are skipped. The transactions are read and updated in batches of redecodeBatchSize in
+// the order of _id, each batch bounded by the query and bulk timeouts.
+func (s *Storage) RedecodeTransactions(parent context.Context) error {
+	start := time.Now()
+	count := 0
+	var last interface{}
+	for {
+		filter := bson.D{{Key: "raw", Value: bson.D{{Key: "$exists", Value: true}}}}
+		if last != nil {
+			filter = append(filter, bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: last}}})
+		}
+		txs, ids, err := s.redecodeBatch(parent, filter)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		last = ids[len(ids)-1]
+
+		updateOps := make([]mongo.WriteModel, 0, len(txs))
+		for _, tx := range txs {
+			if err := tx.Redecode(); err != nil {
+				logging.Warn("redecode transaction", zap.String("tx", tx.Id), zap.Error(err))
+				continue
+			}
+			updateOps = append(updateOps, mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: "id", Value: tx.Id}}).
+				SetUpdate(bson.D{{Key: "$set", Value: TransactionDecodedFields(tx)}}))
+		}
+		if len(updateOps) > 0 {
+			ctx, cancel := s.bulkContext(parent)
+			_, err = s.db.Collection("txs").BulkWrite(ctx, updateOps, options.BulkWrite().SetOrdered(false))
+			cancel()
+			if err != nil {
+				return fmt.Errorf("error update re-decoded transactions: %w", err)
+			}
+		}
+		count += len(updateOps)
+		if len(ids) < redecodeBatchSize {
+			break
+		}
+	}
+
+	logging.Info("transactions re-decoded", zap.Int("txs", count), logging.Duration(time.Since(start)))
+	return nil
+}
+
+// redecodeBatch reads the next batch of transactions to re-decode and their _id, in the order of _id.
+func (s *Storage) redecodeBatch(parent context.Context, filter bson.D) ([]*model.Transaction, []interface{}, error) {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	cursor, err := s.db.Collection("txs").Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(redecodeBatchSize).
+		SetBatchSize(redecodeBatchSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error get transactions for re-decoding: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var txs []*model.Transaction
+	var ids []interface{}
+	for cursor.Next(ctx) {
+		var tx model.Transaction
+		if err := cursor.Decode(&tx); err != nil {
+			return nil, nil, fmt.Errorf("error decode transaction: %w", err)
+		}
+		txs = append(txs, &tx)
+		ids = append(ids, cursor.Current.Lookup("_id"))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterate transactions: %w", err)
+	}
+	return txs, ids, nil
+}
+
+// backfillTransactionTemplates sets the template of the transactions, hot or archived, stored before
+// it was written, from the template of the account of their principal.
+func (s *Storage) backfillTransactionTemplates(ctx context.Context) error {
+	cursor, err := s.db.Collection("accounts").Find(ctx,
+		bson.D{{Key: "template", Value: bson.D{{Key: "$exists", Value: true}}}},
+		options.Find().SetProjection(bson.D{{Key: "address", Value: 1}, {Key: "template", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	archive, _ := TierArchive("txs")
+	for cursor.Next(ctx) {
+		address := utils.GetAsString(cursor.Current.Lookup("address"))
+		template := utils.GetAsString(cursor.Current.Lookup("template"))
+		if template == "" {
+			continue
+		}
+		for _, collection := range []string{"txs", archive} {
+			_, err := s.db.Collection(collection).UpdateMany(ctx, bson.D{
+				{Key: "sender", Value: address},
+				{Key: "template", Value: bson.D{{Key: "$exists", Value: false}}},
+			}, bson.D{{Key: "$set", Value: bson.D{{Key: "template", Value: template}}}})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return cursor.Err()
 }
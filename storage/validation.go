@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+const (
+	// validationLevel `moderate` validates the inserts and the updates of valid documents, so that
+	// documents written before the validators do not block the updates fixing them.
+	validationLevel  = "moderate"
+	validationAction = "error"
+)
+
+// collectionValidators are the `$jsonSchema` validators of the collections. They require the
+// identity fields of the documents and check the types of the fields the API looks up, the other
+// fields are free so that new fields do not need a new validator.
+var collectionValidators = map[string]bson.D{
+	"layers": jsonSchema([]string{"number"}, bson.D{
+		{Key: "number", Value: numberType},
+		{Key: "epoch", Value: numberType},
+		{Key: "status", Value: numberType},
+		{Key: "hash", Value: stringType},
+	}),
+	"blocks": jsonSchema([]string{"id", "layer"}, bson.D{
+		{Key: "id", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "epoch", Value: numberType},
+	}),
+	"txs": jsonSchema([]string{"id"}, bson.D{
+		{Key: "id", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "sender", Value: stringType},
+		{Key: "receiver", Value: stringType},
+		{Key: "amount", Value: numberType},
+	}),
+	"rewards": jsonSchema([]string{"smesher", "layer"}, bson.D{
+		{Key: "smesher", Value: stringType},
+		{Key: "coinbase", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "total", Value: numberType},
+	}),
+	"activations": jsonSchema([]string{"id"}, bson.D{
+		{Key: "id", Value: stringType},
+		{Key: "smesher", Value: stringType},
+		{Key: "coinbase", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "targetEpoch", Value: numberType},
+	}),
+	"smeshers": jsonSchema([]string{"id"}, bson.D{
+		{Key: "id", Value: stringType},
+		{Key: "coinbase", Value: stringType},
+		{Key: "atxcount", Value: numberType},
+	}),
+	"accounts": jsonSchema([]string{"address"}, bson.D{
+		{Key: "address", Value: stringType},
+		{Key: "balance", Value: numberType},
+		{Key: "layer", Value: numberType},
+	}),
+	"epochs": jsonSchema([]string{"number"}, bson.D{
+		{Key: "number", Value: numberType},
+	}),
+	"certificates": jsonSchema([]string{"blockId", "layer"}, bson.D{
+		{Key: "blockId", Value: stringType},
+		{Key: "layer", Value: numberType},
+	}),
+	ballotsCollection: jsonSchema([]string{"id", "layer", "smesher"}, bson.D{
+		{Key: "id", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "smesher", Value: stringType},
+	}),
+	"malfeasance_proofs": jsonSchema([]string{"smesher", "layer"}, bson.D{
+		{Key: "smesher", Value: stringType},
+		{Key: "layer", Value: numberType},
+	}),
+	balanceChangesCollection: jsonSchema([]string{"address", "layer"}, bson.D{
+		{Key: "address", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "delta", Value: numberType},
+	}),
+	accountSnapshotsCollection: jsonSchema([]string{"address", "layer", "balance"}, bson.D{
+		{Key: "address", Value: stringType},
+		{Key: "layer", Value: numberType},
+		{Key: "balance", Value: numberType},
+	}),
+	vaultsCollection: jsonSchema([]string{"address", "totalAmount", "vestingStart", "vestingEnd"}, bson.D{
+		{Key: "address", Value: stringType},
+		{Key: "totalAmount", Value: numberType},
+		{Key: "vestingStart", Value: numberType},
+		{Key: "vestingEnd", Value: numberType},
+	}),
+	"coinbases": jsonSchema([]string{"coinbase", "smesherId"}, bson.D{
+		{Key: "coinbase", Value: stringType},
+		{Key: "smesherId", Value: stringType},
+	}),
+	smesherHistoryCollection: jsonSchema([]string{"smesher", "epoch"}, bson.D{
+		{Key: "smesher", Value: stringType},
+		{Key: "epoch", Value: numberType},
+	}),
+	epochStatsCollection: jsonSchema([]string{"epoch", "version", "stats"}, bson.D{
+		{Key: "epoch", Value: numberType},
+		{Key: "version", Value: numberType},
+		{Key: "stats", Value: bson.D{{Key: "bsonType", Value: "object"}}},
+	}),
+	labelsCollection: jsonSchema([]string{"kind", "id", "name"}, bson.D{
+		{Key: "kind", Value: bson.D{{Key: "enum", Value: bson.A{model.LabelAccount, model.LabelSmesher}}}},
+		{Key: "id", Value: stringType},
+		{Key: "name", Value: stringType},
+	}),
+	pricesCollection: jsonSchema([]string{"timestamp", "usd"}, bson.D{
+		{Key: "timestamp", Value: numberType},
+		{Key: "usd", Value: numberType},
+		{Key: "source", Value: stringType},
+	}),
+	archiveCollection: jsonSchema([]string{"kind", "id", "data"}, bson.D{
+		{Key: "kind", Value: stringType},
+		{Key: "id", Value: stringType},
+		{Key: "data", Value: bson.D{{Key: "bsonType", Value: "binData"}}},
+	}),
+}
+
+var (
+	// numberType matches the integer and floating point types, the driver picks the smallest
+	// integer type holding the value.
+	numberType = bson.D{{Key: "bsonType", Value: "number"}}
+	stringType = bson.D{{Key: "bsonType", Value: "string"}}
+)
+
+func jsonSchema(required []string, properties bson.D) bson.D {
+	return bson.D{{Key: "$jsonSchema", Value: bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: required},
+		{Key: "properties", Value: properties},
+	}}}
+}
+
+// applyValidator sets the validator of the collection, creating the collection if needed.
+func applyValidator(ctx context.Context, db *Database, collection string) error {
+	validator, ok := collectionValidators[collection]
+	if !ok {
+		return nil
+	}
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: db.CollectionName(collection)},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: validationLevel},
+		{Key: "validationAction", Value: validationAction},
+	}).Err()
+	if isNamespaceNotFound(err) {
+		err = db.CreateCollection(ctx, collection, options.CreateCollection().
+			SetValidator(validator).
+			SetValidationLevel(validationLevel).
+			SetValidationAction(validationAction))
+	}
+	if err != nil {
+		return fmt.Errorf("error set `%s` validator: %w", collection, err)
+	}
+	return nil
+}
+
+// applyValidators sets the validators of all the collections of the raw data, the stats collections
+// are validated by their init.
+func applyValidators(ctx context.Context, db *Database) error {
+	for collection := range collectionValidators {
+		if statsCollections[collection] {
+			continue
+		}
+		if err := applyValidator(ctx, db, collection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isNamespaceNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 26
+}
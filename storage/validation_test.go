@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCollectionValidators(t *testing.T) {
+	for collection, validator := range collectionValidators {
+		schema, ok := validator.Map()["$jsonSchema"].(bson.D)
+		require.True(t, ok, collection)
+		fields := schema.Map()
+		properties := fields["properties"].(bson.D).Map()
+		for _, field := range fields["required"].([]string) {
+			require.Contains(t, properties, field, "%s: required field without type", collection)
+		}
+	}
+}
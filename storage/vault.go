@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// vaultsCollection holds the vesting schedules of the vaults, decoded from their spawn transactions.
+const vaultsCollection = "vaults"
+
+func (s *Storage) initVaultsStorage(ctx context.Context) error {
+	if err := s.createIndexes(ctx, vaultsCollection); err != nil {
+		return err
+	}
+	return applyValidator(ctx, s.db, vaultsCollection)
+}
+
+// saveVaults stores the vaults spawned by the transactions, keyed on their address.
+func (s *Storage) saveVaults(parent context.Context, txs []*model.Transaction) error {
+	var models []mongo.WriteModel
+	for _, tx := range txs {
+		vault, err := model.NewVault(tx)
+		if err != nil {
+			logging.Error(fmt.Sprintf("saveVaults: tx %s", tx.Id), err)
+			continue
+		}
+		if vault == nil {
+			continue
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.D{{Key: "address", Value: vault.Address}}).
+			SetReplacement(vault).
+			SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	ctx, cancel := s.bulkContext(parent)
+	defer cancel()
+	if _, err := s.db.Collection(vaultsCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("error save vaults: %w", err)
+	}
+	return nil
+}
+
+// UpsertVault stores the vault keyed on its address.
+func (s *Storage) UpsertVault(parent context.Context, vault *model.Vault) error {
+	ctx, cancel := s.queryContext(parent)
+	defer cancel()
+	_, err := s.db.Collection(vaultsCollection).ReplaceOne(ctx, bson.D{{Key: "address", Value: vault.Address}}, vault,
+		options.Replace().SetUpsert(true))
+	return err
+}
+
+// backfillVaults decodes the vaults of the spawn transactions stored before the vaults were kept,
+// hot or archived.
+func (s *Storage) backfillVaults(ctx context.Context) error {
+	archive, _ := TierArchive("txs")
+	for _, collection := range []string{"txs", archive} {
+		cursor, err := s.db.Collection(collection).Find(ctx, bson.D{
+			{Key: "method", Value: model.MethodSpawn},
+			{Key: "template", Value: model.TemplateVault},
+		})
+		if err != nil {
+			return err
+		}
+		var txs []*model.Transaction
+		err = cursor.All(ctx, &txs)
+		if err != nil {
+			return err
+		}
+		if err := s.saveVaults(ctx, txs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
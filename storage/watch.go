@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/spacemeshos/explorer-backend/internal/logging"
+	"github.com/spacemeshos/explorer-backend/model"
+)
+
+// SetWatchedAccounts enables watch mode: only accounts, transactions, rewards and activations
+// touching one of the given addresses are persisted. Layers, blocks, epochs and network info are
+// always stored, and every activation and reward is kept in the chain collections, so the epoch
+// stats and the global totals of transactions, smeshers, space and rewards count the whole chain.
+// The accounts, fees and daily stats only count the watched addresses. An empty list disables
+// filtering.
+func (s *Storage) SetWatchedAccounts(addresses []string) {
+	if len(addresses) == 0 {
+		s.watched = nil
+		return
+	}
+	s.watched = make(map[string]struct{}, len(addresses))
+	for _, address := range addresses {
+		s.watched[address] = struct{}{}
+	}
+	logging.Info("watch mode enabled", zap.Int("accounts", len(s.watched)))
+}
+
+// isWatched reports whether any of the addresses should be persisted.
+func (s *Storage) isWatched(addresses ...string) bool {
+	if s.watched == nil {
+		return true
+	}
+	for _, address := range addresses {
+		if _, ok := s.watched[address]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) isWatchedTransaction(tx *model.Transaction) bool {
+	if s.watched == nil {
+		return true
+	}
+	return s.isWatched(tx.Sender, tx.Receiver) || s.isWatched(tx.TouchedAddresses...)
+}
@@ -0,0 +1,283 @@
+// Package storagetest is the conformance suite of the storage backends: every backend stores what
+// the collector writes so that the API reads the same data back, whatever the database.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+	"github.com/spacemeshos/go-spacemesh/genvm/core"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
+	"github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
+	"github.com/spacemeshos/go-spacemesh/signing"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/spacemeshos/explorer-backend/internal/service"
+	"github.com/spacemeshos/explorer-backend/internal/storage/storagereader"
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/storage"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// Backend is a storage under test: the writer of the collector and the reader of the API, on the
+// same empty database.
+type Backend struct {
+	Writer storage.StorageWriter
+	Reader storagereader.StorageReader
+}
+
+// Run runs the suite against the backends returned by open, called once per test. open registers
+// the release of the backend with t.Cleanup.
+func Run(t *testing.T, open func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, b Backend)
+	}{
+		{"Layers", testLayers},
+		{"Transactions", testTransactions},
+		{"Reorg", testReorg},
+		{"Rewards", testRewards},
+		{"BulkWrites", testBulkWrites},
+		{"LayerHashCheckpoint", testLayerHashCheckpoint},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := open(t)
+			b.Writer.SetAccountUpdater(accountUpdater{})
+			b.Writer.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+			tc.run(t, b)
+		})
+	}
+}
+
+// accountUpdater returns an empty state for every account.
+type accountUpdater struct{}
+
+func (accountUpdater) GetAccountState(string) (uint64, uint64, error) {
+	return 0, 0, nil
+}
+
+// storeLayers stores the layers and waits until they are processed.
+func storeLayers(t *testing.T, b Backend, layers ...*pb.Layer) {
+	for _, layer := range layers {
+		b.Writer.OnLayer(layer)
+	}
+	require.Eventually(t, func() bool {
+		last, err := b.Writer.GetLastLayer(context.Background())
+		return err == nil && b.Writer.LayersInQueue() == 0 && last == layers[len(layers)-1].GetNumber().GetNumber()
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func layer(number uint32, blocks ...*pb.Block) *pb.Layer {
+	return &pb.Layer{
+		Number: &pb.LayerNumber{Number: number},
+		Status: pb.Layer_LAYER_STATUS_CONFIRMED,
+		Hash:   []byte{byte(number)},
+		Blocks: blocks,
+	}
+}
+
+func blockID(layer uint32, i byte) []byte {
+	id := make([]byte, 20)
+	id[0], id[1] = byte(layer), i
+	return id
+}
+
+func testLayers(t *testing.T, b Backend) {
+	ctx := context.Background()
+	for i := uint32(1); i <= 12; i++ {
+		storeLayers(t, b, layer(i, &pb.Block{Id: blockID(i, 1)}, &pb.Block{Id: blockID(i, 2)}))
+	}
+	svc := service.NewService(b.Reader, time.Second)
+
+	info, err := svc.GetNetworkInfo(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", info.GenesisId)
+	require.Equal(t, uint32(10), info.EpochNumLayers)
+	require.Equal(t, uint32(12), info.LastLayer)
+
+	layers, total, err := svc.GetLayers(ctx, 1, 5)
+	require.NoError(t, err)
+	require.Equal(t, int64(12), total)
+	require.Len(t, layers, 5)
+	require.Equal(t, uint32(12), layers[0].Number)
+	require.Equal(t, uint32(1), layers[0].Epoch)
+	require.Equal(t, uint32(2), layers[0].BlocksNumber)
+	require.Equal(t, utils.BytesToHex([]byte{12}), layers[0].Hash)
+
+	blocks, total, err := svc.GetLayerBlocks(ctx, 3, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, blocks, 2)
+}
+
+func testTransactions(t *testing.T, b Backend) {
+	ctx := context.Background()
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	receiver := types.GenerateAddress([]byte{3})
+	tx := &pb.Transaction{
+		Id:     []byte{12},
+		Method: core.MethodSpend,
+		MaxGas: 100,
+		Raw:    wallet.Spend(signer.PrivateKey(), receiver, 10, 1, sdk.WithGasPrice(2)),
+	}
+	storeLayers(t, b, layer(12, &pb.Block{Id: blockID(12, 1), Transactions: []*pb.Transaction{tx}}))
+	svc := service.NewService(b.Reader, time.Second)
+
+	id := utils.BytesToHex(tx.Id)
+	stored, err := svc.GetTransaction(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, uint32(12), stored.Layer)
+	require.Equal(t, uint64(10), stored.Amount)
+	require.Equal(t, uint64(2), stored.GasPrice)
+	require.Equal(t, uint64(200), stored.Fee)
+	require.Equal(t, receiver.String(), stored.Receiver)
+
+	txs, total, err := svc.GetLayerTransactions(ctx, 12, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), total)
+	require.Len(t, txs, 1)
+	require.Equal(t, id, txs[0].Id)
+
+	require.NoError(t, b.Writer.UpdateTransactionState(ctx, id, int32(pb.TransactionState_TRANSACTION_STATE_PROCESSED)))
+	stored, err = svc.GetTransaction(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, int(pb.TransactionState_TRANSACTION_STATE_PROCESSED), stored.State)
+
+	require.NoError(t, b.Writer.RedecodeTransactions(ctx))
+	stored, err = svc.GetTransaction(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, uint64(200), stored.Fee)
+	require.Equal(t, receiver.String(), stored.Receiver)
+}
+
+func testReorg(t *testing.T, b Backend) {
+	ctx := context.Background()
+	signer, err := signing.NewEdSigner()
+	require.NoError(t, err)
+	spend := func(id byte, nonce uint64) *pb.Transaction {
+		return &pb.Transaction{
+			Id:     []byte{id},
+			Method: core.MethodSpend,
+			MaxGas: 100,
+			Raw:    wallet.Spend(signer.PrivateKey(), types.GenerateAddress([]byte{3}), 10, nonce, sdk.WithGasPrice(2)),
+		}
+	}
+	storeLayers(t, b, layer(12, &pb.Block{Id: blockID(12, 1), Transactions: []*pb.Transaction{spend(1, 1), spend(2, 2)}}))
+	svc := service.NewService(b.Reader, time.Second)
+
+	_, total, err := svc.GetTransactions(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+
+	reorged := layer(12, &pb.Block{Id: blockID(12, 2), Transactions: []*pb.Transaction{spend(1, 1)}})
+	reorged.Hash = []byte{0xff}
+	b.Writer.OnLayer(reorged)
+	require.Eventually(t, func() bool {
+		_, total, err := svc.GetTransactions(ctx, 1, 10)
+		return err == nil && total == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	txs, total, err := svc.GetTransactions(service.WithOrphaned(ctx), 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, txs, 2)
+}
+
+func testRewards(t *testing.T, b Backend) {
+	ctx := context.Background()
+	storeLayers(t, b, layer(11), layer(12))
+	b.Writer.OnActivations([]*model.Activation{
+		{Id: "0xa1", SmesherId: "0x51", Coinbase: "sm1", NumUnits: 2, TargetEpoch: 1, Received: 10},
+	})
+	reward := func(layer uint32) *pb.Reward {
+		return &pb.Reward{
+			Layer:       &pb.LayerNumber{Number: layer},
+			Total:       &pb.Amount{Value: 100},
+			LayerReward: &pb.Amount{Value: 90},
+			Coinbase:    &pb.AccountId{Address: "sm1"},
+			Smesher:     &pb.SmesherId{Id: []byte{0x51}},
+		}
+	}
+	b.Writer.OnRewards([]*pb.Reward{reward(11), reward(12)})
+	// a reward received again is not counted twice
+	b.Writer.OnReward(reward(12))
+	svc := service.NewService(b.Reader, time.Second)
+
+	rewards, total, err := svc.GetRewards(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), total)
+	require.Len(t, rewards, 2)
+
+	smesher, err := svc.GetSmesher(ctx, "0x51")
+	require.NoError(t, err)
+	require.Equal(t, int64(200), smesher.Rewards)
+	require.Equal(t, uint64(2048), smesher.CommitmentSize)
+
+	_, err = svc.GetSmesher(ctx, "0x52")
+	require.ErrorIs(t, err, service.ErrNotFound)
+}
+
+// testBulkWrites stores more rewards than a single bulk write of the collector holds, the batches
+// received again replace their documents.
+func testBulkWrites(t *testing.T, b Backend) {
+	ctx := context.Background()
+	storeLayers(t, b, layer(11))
+	const rewardsNumber, atxsNumber = 1500, 10
+	rewards := make([]*pb.Reward, 0, rewardsNumber)
+	for i := 0; i < rewardsNumber; i++ {
+		rewards = append(rewards, &pb.Reward{
+			Layer:    &pb.LayerNumber{Number: 11},
+			Total:    &pb.Amount{Value: 100},
+			Coinbase: &pb.AccountId{Address: "sm1"},
+			Smesher:  &pb.SmesherId{Id: []byte{byte(i >> 8), byte(i)}},
+		})
+	}
+	atxs := make([]*model.Activation, 0, atxsNumber)
+	for i := 0; i < atxsNumber; i++ {
+		atxs = append(atxs, &model.Activation{
+			Id:          fmt.Sprintf("0xa%d", i),
+			SmesherId:   fmt.Sprintf("0x5%d", i),
+			Coinbase:    "sm1",
+			NumUnits:    1,
+			TargetEpoch: 1,
+		})
+	}
+	for i := 0; i < 2; i++ {
+		b.Writer.OnRewards(rewards)
+		b.Writer.OnActivations(atxs)
+	}
+	svc := service.NewService(b.Reader, time.Second)
+
+	_, total, err := svc.GetRewards(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(rewardsNumber), total)
+	total, count, err := svc.GetTotalRewards(ctx, &bson.D{})
+	require.NoError(t, err)
+	require.Equal(t, int64(rewardsNumber*100), total)
+	require.Equal(t, int64(rewardsNumber), count)
+
+	_, total, err = svc.GetActivations(ctx, 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, int64(atxsNumber), total)
+}
+
+func testLayerHashCheckpoint(t *testing.T, b Backend) {
+	ctx := context.Background()
+	layer, err := b.Writer.GetLayerHashCheckpoint(ctx)
+	require.NoError(t, err)
+	require.Zero(t, layer)
+
+	require.NoError(t, b.Writer.SetLayerHashCheckpoint(ctx, 12))
+	// the network info updates keep the checkpoint
+	b.Writer.OnNetworkInfo("0x01", 1000, 10, 100, 60, 1024)
+	layer, err = b.Writer.GetLayerHashCheckpoint(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint32(12), layer)
+}
@@ -3,12 +3,15 @@ package testseed
 import (
 	"errors"
 	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/utils"
 	"github.com/spacemeshos/go-spacemesh/common/types"
 	"github.com/spacemeshos/go-spacemesh/genvm/sdk"
 	sdkWallet "github.com/spacemeshos/go-spacemesh/genvm/sdk/wallet"
 	"github.com/spacemeshos/go-spacemesh/genvm/templates/wallet"
+	"github.com/spacemeshos/go-spacemesh/signing"
 	"github.com/spacemeshos/go-spacemesh/sql"
+	"github.com/spacemeshos/go-spacemesh/sql/certificates"
 	"strings"
 	"time"
 )
@@ -44,10 +47,6 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 			for _, blockContainer := range layerContainer.Blocks {
 				tx := make([]*pb.Transaction, 0, len(blockContainer.Transactions))
 				for _, txContainer := range blockContainer.Transactions {
-					receiver, err := types.StringToAddress(txContainer.Receiver)
-					if err != nil {
-						panic("invalid receiver address: " + err.Error())
-					}
 					signer := c.SeedGen.Accounts[strings.ToLower(txContainer.Sender)].Signer
 					tx = append(tx, &pb.Transaction{
 						Id:     mustParse(txContainer.Id),
@@ -63,7 +62,7 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 						Template: &pb.AccountId{
 							Address: wallet.TemplateAddress.String(),
 						},
-						Raw: sdkWallet.Spend(signer.PrivateKey(), receiver, txContainer.Amount, types.Nonce(txContainer.Counter), sdk.WithGasPrice(txContainer.GasPrice)),
+						Raw: rawSpend(signer, txContainer),
 					})
 				}
 				smesherId, _ := utils.StringToBytes(blockContainer.SmesherID)
@@ -90,6 +89,15 @@ func (c *Client) GetLayer(db *sql.Database, lid types.LayerID, numLayers uint32)
 	return nil, errors.New("could not find layer")
 }
 
+// rawSpend encodes the generated transaction as a spend signed by the sender.
+func rawSpend(signer *signing.EdSigner, tx *model.Transaction) []byte {
+	receiver, err := types.StringToAddress(tx.Receiver)
+	if err != nil {
+		panic("invalid receiver address: " + err.Error())
+	}
+	return sdkWallet.Spend(signer.PrivateKey(), receiver, tx.Amount, types.Nonce(tx.Counter), sdk.WithGasPrice(tx.GasPrice))
+}
+
 func (c *Client) GetLayerRewards(db *sql.Database, lid types.LayerID) (rst []*types.Reward, err error) {
 	for _, epoch := range c.SeedGen.Epochs {
 		for _, reward := range epoch.Rewards {
@@ -218,6 +226,46 @@ func (c *Client) GetAtxById(db *sql.Database, id string) (*types.VerifiedActivat
 	return nil, nil
 }
 
+func (c *Client) GetLayerCertificates(db *sql.Database, lid types.LayerID) ([]certificates.CertValidity, error) {
+	var certs []certificates.CertValidity
+	for _, cert := range c.SeedGen.Certificates {
+		if cert.Layer != lid.Uint32() {
+			continue
+		}
+		var blockID types.BlockID
+		copy(blockID[:], mustParse(cert.BlockId))
+		signatures := make([]types.CertifyMessage, 0, len(cert.Signers))
+		for _, signer := range cert.Signers {
+			signatures = append(signatures, types.CertifyMessage{
+				CertifyContent: types.CertifyContent{LayerID: lid, BlockID: blockID, EligibilityCnt: signer.Eligibilities},
+				SmesherID:      types.BytesToNodeID(mustParse(signer.Smesher)),
+			})
+		}
+		certs = append(certs, certificates.CertValidity{
+			Block: blockID,
+			Cert:  &types.Certificate{BlockID: blockID, Signatures: signatures},
+			Valid: cert.Valid,
+		})
+	}
+	return certs, nil
+}
+
+func (c *Client) GetLayerBallots(db *sql.Database, lid types.LayerID) ([]*types.Ballot, error) {
+	return nil, nil
+}
+
+func (c *Client) GetEpochActiveSetSize(db *sql.Database, epoch types.EpochID) (int, error) {
+	return 0, nil
+}
+
+func (c *Client) GetEpochBeacon(db *sql.Database, epoch types.EpochID) (types.Beacon, error) {
+	return types.EmptyBeacon, nil
+}
+
+func (c *Client) GetLayerTransactionsReceived(db *sql.Database, lid types.LayerID) (map[types.TransactionID]int64, error) {
+	return nil, nil
+}
+
 func mustParse(str string) []byte {
 	res, err := utils.StringToBytes(str)
 	if err != nil {
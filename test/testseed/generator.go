@@ -13,7 +13,6 @@ import (
 
 	"github.com/spacemeshos/explorer-backend/model"
 	v0 "github.com/spacemeshos/explorer-backend/pkg/transactionparser/v0"
-	"github.com/spacemeshos/explorer-backend/storage"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
 
@@ -23,11 +22,13 @@ type SeedGenerator struct {
 	Accounts       map[string]AccountContainer
 	Activations    map[string]*model.Activation
 	Blocks         map[string]*model.Block
+	Certificates   map[string]*model.BlockCertificate // by block id
 	Apps           map[string]model.App
 	Layers         map[uint32]*model.Layer
 	Rewards        map[string]*model.Reward
 	Transactions   map[string]*model.Transaction
 	Smeshers       map[string]*model.Smesher
+	Vault          *model.Vault // seeded by GenerateVault
 	FirstLayerTime time.Time
 	seed           *TestServerSeed
 }
@@ -41,6 +42,7 @@ func NewSeedGenerator(seed *TestServerSeed) *SeedGenerator {
 		seed:         seed,
 		Activations:  map[string]*model.Activation{},
 		Blocks:       map[string]*model.Block{},
+		Certificates: map[string]*model.BlockCertificate{},
 		Layers:       map[uint32]*model.Layer{},
 		Rewards:      map[string]*model.Reward{},
 		Smeshers:     map[string]*model.Smesher{},
@@ -53,6 +55,7 @@ func (s *SeedGenerator) GenerateEpoches(count int) error {
 	now := time.Now()
 	result := make([]*SeedEpoch, 0, count)
 	var prevEpoch *model.Epoch
+	previousSmeshers, earlierSmeshers := map[string]bool{}, map[string]bool{}
 	for i := 1; i < count; i++ {
 		offset := time.Duration(int64(i)*(int64(s.seed.EpochNumLayers)*int64(s.seed.LayersDuration))) * time.Second
 		layerStartDate := now.Add(-1 * offset)
@@ -82,7 +85,38 @@ func (s *SeedGenerator) GenerateEpoches(count int) error {
 			}
 			seedEpoch.Epoch.Layers++
 		}
+		for _, layer := range seedEpoch.Layers {
+			if layer.Layer.BlocksNumber == 0 {
+				seedEpoch.Epoch.Stats.Current.EmptyLayers++
+			}
+			if layer.Layer.Txs == 0 {
+				seedEpoch.Epoch.Stats.Current.LayersWithoutTxs++
+			}
+		}
 		seedEpoch.Epoch.Stats.Current.Decentral = utils.CalcDecentralCoefficient(seedEpoch.SmeshersCommitment)
+		if len(seedEpoch.SmeshersCommitment) > 0 {
+			seedEpoch.Epoch.Stats.Current.SpaceGini = utils.GiniBasisPoints(seedEpoch.SmeshersCommitment)
+			seedEpoch.Epoch.Stats.Current.SpaceNakamoto = utils.Nakamoto(seedEpoch.SmeshersCommitment)
+		}
+		if len(seedEpoch.Rewards) > 0 {
+			smeshersRewards := make(map[string]int64)
+			for _, reward := range seedEpoch.Rewards {
+				smeshersRewards[reward.Smesher] += int64(reward.Total)
+			}
+			seedEpoch.Epoch.Stats.Current.RewardedSmeshers = int64(len(smeshersRewards))
+			seedEpoch.Epoch.Stats.Current.RewardsGini = utils.GiniBasisPoints(smeshersRewards)
+			seedEpoch.Epoch.Stats.Current.RewardsNakamoto = utils.Nakamoto(smeshersRewards)
+		}
+		currentSmeshers := make(map[string]bool, len(seedEpoch.SmeshersCommitment))
+		for smesher := range seedEpoch.SmeshersCommitment {
+			currentSmeshers[smesher] = true
+		}
+		seedEpoch.Epoch.Stats.Current.NewSmeshers, seedEpoch.Epoch.Stats.Current.ReturningSmeshers, seedEpoch.Epoch.Stats.Current.StoppedSmeshers =
+			utils.SmeshersChurn(currentSmeshers, previousSmeshers, earlierSmeshers)
+		for smesher := range previousSmeshers {
+			earlierSmeshers[smesher] = true
+		}
+		previousSmeshers = currentSmeshers
 		duration := float64(s.seed.LayersDuration) * float64(layersEnd-layerStart+1)
 		seedEpoch.Epoch.Stats.Current.Capacity = utils.CalcEpochCapacity(seedEpoch.Epoch.Stats.Current.Transactions, duration, uint32(s.seed.MaxTransactionPerSecond))
 		if prevEpoch != nil {
@@ -95,11 +129,30 @@ func (s *SeedGenerator) GenerateEpoches(count int) error {
 			seedEpoch.Epoch.Stats.Cumulative.RewardsNumber = prevEpoch.Stats.Cumulative.RewardsNumber + seedEpoch.Epoch.Stats.Current.RewardsNumber
 			seedEpoch.Epoch.Stats.Cumulative.Security = prevEpoch.Stats.Current.Security
 			seedEpoch.Epoch.Stats.Cumulative.TxsAmount = prevEpoch.Stats.Cumulative.TxsAmount + seedEpoch.Epoch.Stats.Current.TxsAmount
+			seedEpoch.Epoch.Stats.Cumulative.SpaceGini = seedEpoch.Epoch.Stats.Current.SpaceGini
+			seedEpoch.Epoch.Stats.Cumulative.SpaceNakamoto = seedEpoch.Epoch.Stats.Current.SpaceNakamoto
+			seedEpoch.Epoch.Stats.Cumulative.RewardsGini = seedEpoch.Epoch.Stats.Current.RewardsGini
+			seedEpoch.Epoch.Stats.Cumulative.RewardsNakamoto = seedEpoch.Epoch.Stats.Current.RewardsNakamoto
+			seedEpoch.Epoch.Stats.Cumulative.NewSmeshers = seedEpoch.Epoch.Stats.Current.NewSmeshers
+			seedEpoch.Epoch.Stats.Cumulative.ReturningSmeshers = seedEpoch.Epoch.Stats.Current.ReturningSmeshers
+			seedEpoch.Epoch.Stats.Cumulative.StoppedSmeshers = seedEpoch.Epoch.Stats.Current.StoppedSmeshers
+			seedEpoch.Epoch.Stats.Current.SpaceDelta = seedEpoch.Epoch.Stats.Current.Space - prevEpoch.Stats.Current.Space
+			seedEpoch.Epoch.Stats.Cumulative.Space = seedEpoch.Epoch.Stats.Current.Space
+			seedEpoch.Epoch.Stats.Cumulative.SpaceDelta = seedEpoch.Epoch.Stats.Current.SpaceDelta
+			seedEpoch.Epoch.Stats.Current.NewAccounts = seedEpoch.Epoch.Stats.Current.Accounts - prevEpoch.Stats.Current.Accounts
+			seedEpoch.Epoch.Stats.Cumulative.NewAccounts = seedEpoch.Epoch.Stats.Current.NewAccounts
+			seedEpoch.Epoch.Stats.Cumulative.EmptyLayers = prevEpoch.Stats.Cumulative.EmptyLayers + seedEpoch.Epoch.Stats.Current.EmptyLayers
+			seedEpoch.Epoch.Stats.Cumulative.LayersWithoutTxs = prevEpoch.Stats.Cumulative.LayersWithoutTxs + seedEpoch.Epoch.Stats.Current.LayersWithoutTxs
+			seedEpoch.Epoch.Stats.Cumulative.RewardedSmeshers = seedEpoch.Epoch.Stats.Current.RewardedSmeshers
+			seedEpoch.Epoch.Stats.Cumulative.EffectiveNumUnits = seedEpoch.Epoch.Stats.Current.EffectiveNumUnits
+			seedEpoch.Epoch.Stats.Cumulative.Weight = seedEpoch.Epoch.Stats.Current.Weight
 
 			seedEpoch.Epoch.Stats.Current.Circulation = seedEpoch.Epoch.Stats.Cumulative.Rewards
 			seedEpoch.Epoch.Stats.Cumulative.Circulation = seedEpoch.Epoch.Stats.Current.Circulation
 		} else {
 			seedEpoch.Epoch.Stats.Current.Circulation = seedEpoch.Epoch.Stats.Current.Rewards
+			seedEpoch.Epoch.Stats.Current.SpaceDelta = seedEpoch.Epoch.Stats.Current.Space
+			seedEpoch.Epoch.Stats.Current.NewAccounts = seedEpoch.Epoch.Stats.Current.Accounts
 			seedEpoch.Epoch.Stats.Cumulative = seedEpoch.Epoch.Stats.Current
 		}
 		prevEpoch = &seedEpoch.Epoch
@@ -108,48 +161,82 @@ func (s *SeedGenerator) GenerateEpoches(count int) error {
 	return nil
 }
 
+// Store is a storage the generated data is written to directly, storage.Storage or a
+// docstore.Storage.
+type Store interface {
+	UpsertEpoch(ctx context.Context, epoch *model.Epoch) error
+	UpsertLayer(ctx context.Context, layer *model.Layer) error
+	UpsertTransaction(ctx context.Context, tx *model.Transaction) error
+	UpsertTransactionResult(ctx context.Context, tx *model.Transaction) error
+	UpsertReward(ctx context.Context, reward *model.Reward) error
+	UpsertActivation(ctx context.Context, atx *model.Activation) error
+	UpsertSmesher(ctx context.Context, smesher *model.Smesher, epoch uint32) error
+	UpsertBlock(ctx context.Context, block *model.Block) error
+	UpsertAccount(ctx context.Context, layer uint32, account *model.Account) error
+	UpsertVault(ctx context.Context, vault *model.Vault) error
+	OnCertificates(certs []*model.BlockCertificate)
+}
+
 // SaveEpoches write generated data directly to db.
-func (s *SeedGenerator) SaveEpoches(ctx context.Context, db *storage.Storage) error {
+func (s *SeedGenerator) SaveEpoches(ctx context.Context, db Store) error {
 	for _, epoch := range s.Epochs {
-		if err := db.SaveEpoch(ctx, &epoch.Epoch); err != nil {
+		if err := db.UpsertEpoch(ctx, &epoch.Epoch); err != nil {
 			return fmt.Errorf("failed to save epoch: %v", err)
 		}
 		for _, layerContainer := range epoch.Layers {
-			if err := db.SaveLayer(ctx, &layerContainer.Layer); err != nil {
+			if err := db.UpsertLayer(ctx, &layerContainer.Layer); err != nil {
 				return fmt.Errorf("failed to save layer: %v", err)
 			}
 		}
 		for _, tx := range epoch.Transactions {
-			if err := db.SaveTransaction(ctx, tx); err != nil {
+			if err := db.UpsertTransaction(ctx, tx); err != nil {
 				return fmt.Errorf("failed to save transaction: %v", err)
 			}
+			// the result is only stored with the receipts, which list the touched addresses, e.g.
+			// of the vault drains. A successful result is 0, it can't tell them apart.
+			if len(tx.TouchedAddresses) > 0 {
+				if err := db.UpsertTransactionResult(ctx, tx); err != nil {
+					return fmt.Errorf("failed to save transaction result: %v", err)
+				}
+			}
 		}
 		for _, reward := range epoch.Rewards {
-			if err := db.SaveReward(ctx, reward); err != nil {
+			if err := db.UpsertReward(ctx, reward); err != nil {
 				return fmt.Errorf("failed to save reward: %v", err)
 			}
 		}
-		for _, smesher := range epoch.Smeshers {
-			if err := db.SaveSmesher(ctx, smesher, uint32(epoch.Epoch.Number)); err != nil {
-				return fmt.Errorf("failed to save smesher: %v", err)
-			}
-		}
+		// the activations are saved first, the smeshers count them
 		for _, atx := range epoch.Activations {
-			if err := db.SaveActivation(ctx, atx); err != nil {
+			if err := db.UpsertActivation(ctx, atx); err != nil {
 				return fmt.Errorf("failed to save activation: %v", err)
 			}
 		}
+		for _, smesher := range epoch.Smeshers {
+			if err := db.UpsertSmesher(ctx, smesher, uint32(epoch.Epoch.Number)); err != nil {
+				return fmt.Errorf("failed to save smesher: %v", err)
+			}
+		}
 		for _, block := range epoch.Blocks {
-			if err := db.SaveBlock(ctx, block); err != nil {
+			if err := db.UpsertBlock(ctx, block); err != nil {
 				return fmt.Errorf("failed to save block: %v", err)
 			}
 		}
 	}
 	for _, acc := range s.Accounts {
-		if err := db.SaveAccount(ctx, acc.layerID, &acc.Account); err != nil {
+		if err := db.UpsertAccount(ctx, acc.layerID, &acc.Account); err != nil {
 			return fmt.Errorf("failed to save account: %s", err)
 		}
 	}
+	certs := make([]*model.BlockCertificate, 0, len(s.Certificates))
+	for _, cert := range s.Certificates {
+		certs = append(certs, cert)
+	}
+	db.OnCertificates(certs)
+	if s.Vault != nil {
+		if err := db.UpsertVault(ctx, s.Vault); err != nil {
+			return fmt.Errorf("failed to save vault: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -199,6 +286,7 @@ func (s *SeedGenerator) fillLayer(layerID, epochID int32, seedEpoch *SeedEpoch)
 			layerContainer.Layer.TxsAmount += tmpTx.Amount
 			blockContainer.Block.TxsNumber++
 			blockContainer.Block.TxsValue += tmpTx.Amount
+			blockContainer.Block.Size += uint64(len(rawSpend(tmpAccSigner, &tmpTx)))
 			s.saveTransactionForAccount(&tmpTx, from, to)
 			seedEpoch.Transactions[tmpTx.Id] = &tmpTx
 			s.Transactions[tmpTx.Id] = &tmpTx
@@ -206,7 +294,7 @@ func (s *SeedGenerator) fillLayer(layerID, epochID int32, seedEpoch *SeedEpoch)
 		}
 
 		from := tmpAcc.Address
-		atxNumUnits := uint32(rand.Intn(1000))
+		atxNumUnits := s.seed.MinNumUnits + uint32(rand.Intn(int(s.seed.MaxNumUnits-s.seed.MinNumUnits)))
 		tmpSm := s.generateSmesher(tmpLayer.Number, from, uint64(atxNumUnits)*s.seed.GetPostUnitsSize())
 		layerContainer.Smeshers[tmpSm.Id] = &tmpSm
 		seedEpoch.Epoch.Stats.Current.Smeshers++
@@ -216,10 +304,14 @@ func (s *SeedGenerator) fillLayer(layerID, epochID int32, seedEpoch *SeedEpoch)
 		seedEpoch.Activations[tmpAtx.Id] = &tmpAtx
 		layerContainer.Activations[tmpAtx.Id] = &tmpAtx
 		seedEpoch.Epoch.Stats.Current.Security += int64(tmpAtx.CommitmentSize)
+		seedEpoch.Epoch.Stats.Current.Space += int64(tmpAtx.EffectiveNumUnits) * int64(s.seed.GetPostUnitsSize())
+		seedEpoch.Epoch.Stats.Current.EffectiveNumUnits += int64(tmpAtx.EffectiveNumUnits)
+		seedEpoch.Epoch.Stats.Current.Weight += int64(tmpAtx.Weight)
 		s.Activations[tmpAtx.Id] = &tmpAtx
 
 		seedEpoch.Smeshers[strings.ToLower(tmpSm.Id)] = &tmpSm
 		blockContainer.SmesherID = tmpSm.Id
+		s.Certificates[tmpBl.Id] = s.generateCertificate(&tmpBl, &tmpSm)
 
 		tmpRw := s.generateReward(tmpLayer.Number, &tmpSm)
 		seedEpoch.Rewards[tmpRw.Smesher] = &tmpRw
@@ -326,7 +418,7 @@ func generateTransaction(index int, layer *model.Layer, senderSigner *signing.Ed
 		Block:      block.Id,
 		BlockIndex: uint32(index),
 		Index:      0,
-		State:      int(pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED),
+		State:      int(pb.TransactionState_TRANSACTION_STATE_PROCESSED),
 		Timestamp:  layer.Start,
 		MaxGas:     maxGas,
 		GasPrice:   gasPrice,
@@ -340,6 +432,8 @@ func generateTransaction(index int, layer *model.Layer, senderSigner *signing.Ed
 		Sender:     sender,
 		Receiver:   receiver,
 		SvmData:    "",
+		Method:     methodSend,
+		Template:   model.TemplateWallet,
 	}
 }
 
@@ -354,12 +448,26 @@ func (s *SeedGenerator) generateSmesher(layerNum uint32, coinbase string, commit
 	}
 }
 
+// generateCertificate returns a valid certificate of the block signed by the smesher.
+func (s *SeedGenerator) generateCertificate(block *model.Block, smesher *model.Smesher) *model.BlockCertificate {
+	eligibilities := uint16(rand.Intn(100) + 1)
+	return &model.BlockCertificate{
+		Layer:         block.Layer,
+		BlockId:       block.Id,
+		Valid:         true,
+		Eligibilities: uint32(eligibilities),
+		Signers:       []model.CertificateSigner{{Smesher: smesher.Id, Eligibilities: eligibilities}},
+	}
+}
+
 func (s *SeedGenerator) generateReward(layerNum uint32, smesher *model.Smesher) model.Reward {
 	tx, _ := utils.CalculateLayerStartEndDate(uint32(s.FirstLayerTime.Unix()), layerNum, uint32(s.seed.LayersDuration))
+	// the layer reward is the part of the total which is not paid from the fees
+	total := uint64(rand.Intn(1000))
 	return model.Reward{
 		Layer:         layerNum,
-		Total:         uint64(rand.Intn(1000)),
-		LayerReward:   uint64(rand.Intn(1000)),
+		Total:         total,
+		LayerReward:   uint64(rand.Intn(int(total) + 1)),
 		LayerComputed: 0,
 		Coinbase:      smesher.Coinbase,
 		Smesher:       smesher.Id,
@@ -388,9 +496,10 @@ func (s *SeedGenerator) generateAccount(layerNum uint32) (model.Account, *signin
 		Address: v0.ComputePrincipal(v0.TemplateAddress, &v0.SpawnArguments{
 			PublicKey: key,
 		}).String(),
-		Balance: 0,
-		Counter: 0,
-		Created: uint64(layerNum),
+		Balance:  0,
+		Counter:  0,
+		Created:  uint64(layerNum),
+		Template: model.TemplateWallet,
 	}, signer
 }
 
@@ -0,0 +1,93 @@
+package testseed
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+	"github.com/spacemeshos/go-spacemesh/common/types"
+
+	"github.com/spacemeshos/explorer-backend/model"
+	"github.com/spacemeshos/explorer-backend/pkg/transactionparser/transaction"
+	"github.com/spacemeshos/explorer-backend/utils"
+)
+
+// VaultDrain is a drain of the generated vault, at the first layer of the epoch.
+type VaultDrain struct {
+	Epoch  uint32
+	Amount uint64
+}
+
+// Vault seed: the vault vests VaultLayerVesting per layer from the start of VaultVestingStart to
+// the end of the epoch before VaultVestingEnd, and is drained by VaultDrains.
+const (
+	VaultVestingStart = 5
+	VaultVestingEnd   = 15
+	VaultLayerVesting = 10_000
+)
+
+// VaultDrains are the drains of the generated vault.
+var VaultDrains = []VaultDrain{{Epoch: 6, Amount: 30_000}, {Epoch: 7, Amount: 20_000}}
+
+// GenerateVault seeds a vesting vault and adds its drains to the generated epochs, after
+// GenerateEpoches. The drains are not part of the mesh served by Client.
+func (s *SeedGenerator) GenerateVault() error {
+	numLayers := s.seed.EpochNumLayers
+	owner := types.GenerateAddress(randomBytes(32)).String()
+	s.Vault = &model.Vault{
+		Address:      types.GenerateAddress(randomBytes(32)).String(),
+		Owner:        owner,
+		TotalAmount:  (VaultVestingEnd - VaultVestingStart) * uint64(numLayers) * VaultLayerVesting,
+		VestingStart: VaultVestingStart * numLayers,
+		VestingEnd:   VaultVestingEnd * numLayers,
+		Layer:        numLayers,
+		Tx:           strings.ToLower(utils.BytesToHex(randomBytes(32))),
+	}
+	for _, drain := range VaultDrains {
+		layer, ok := s.Layers[drain.Epoch*numLayers]
+		if !ok {
+			return fmt.Errorf("layer %d not generated", drain.Epoch*numLayers)
+		}
+		tx := &model.Transaction{
+			Id:               strings.ToLower(utils.BytesToHex(randomBytes(32))),
+			Layer:            layer.Number,
+			Result:           int(pb.TransactionResult_SUCCESS),
+			Timestamp:        layer.Start,
+			MaxGas:           100,
+			GasPrice:         1,
+			Fee:              100,
+			Amount:           drain.Amount,
+			Type:             transaction.TypeDrainVault,
+			Sender:           owner,
+			Receiver:         owner,
+			TouchedAddresses: []string{owner, s.Vault.Address},
+		}
+		withoutTxs := layer.Txs == 0
+		layer.Txs++
+		layer.TxsAmount += tx.Amount
+		for _, epoch := range s.Epochs {
+			if uint32(epoch.Epoch.Number) < drain.Epoch {
+				continue
+			}
+			stats := &epoch.Epoch.Stats
+			if uint32(epoch.Epoch.Number) == drain.Epoch {
+				epoch.Transactions[tx.Id] = tx
+				stats.Current.Transactions++
+				stats.Current.TxsAmount += int64(tx.Amount)
+				duration := float64(s.seed.LayersDuration) * float64(numLayers)
+				stats.Current.Capacity = utils.CalcEpochCapacity(stats.Current.Transactions, duration, uint32(s.seed.MaxTransactionPerSecond))
+				stats.Cumulative.Capacity = stats.Current.Capacity
+				if withoutTxs {
+					stats.Current.LayersWithoutTxs--
+				}
+			}
+			stats.Cumulative.Transactions++
+			stats.Cumulative.TxsAmount += int64(tx.Amount)
+			if withoutTxs {
+				stats.Cumulative.LayersWithoutTxs--
+			}
+		}
+		s.Transactions[tx.Id] = tx
+	}
+	return nil
+}
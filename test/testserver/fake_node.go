@@ -18,6 +18,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/spacemeshos/explorer-backend/model"
 	"github.com/spacemeshos/explorer-backend/test/testseed"
 	"github.com/spacemeshos/explorer-backend/utils"
 )
@@ -49,6 +50,11 @@ type globalStateServiceWrapper struct {
 	pb.UnimplementedGlobalStateServiceServer
 }
 
+type transactionServiceWrapper struct {
+	seedGen *testseed.SeedGenerator
+	pb.UnimplementedTransactionServiceServer
+}
+
 type nodeServiceWrapper struct {
 	seedGen *testseed.SeedGenerator
 	pb.UnimplementedNodeServiceServer
@@ -64,6 +70,7 @@ type FakeNode struct {
 	meshService  *meshServiceWrapper
 	globalState  *globalStateServiceWrapper
 	debugService *debugServiceWrapper
+	txService    *transactionServiceWrapper
 }
 
 var stateSynced = make(chan struct{})
@@ -82,6 +89,7 @@ func CreateFakeSMNode(startTime time.Time, seedGen *testseed.SeedGenerator, seed
 		meshService:  &meshServiceWrapper{startTime, seedConf, seedGen, pb.UnimplementedMeshServiceServer{}},
 		globalState:  &globalStateServiceWrapper{seedGen, pb.UnimplementedGlobalStateServiceServer{}},
 		debugService: &debugServiceWrapper{seedGen, pb.UnimplementedDebugServiceServer{}},
+		txService:    &transactionServiceWrapper{seedGen: seedGen},
 	}, nil
 }
 
@@ -111,6 +119,7 @@ func (f *FakeNode) Start() error {
 	pb.RegisterMeshServiceServer(f.server, f.meshService)
 	pb.RegisterNodeServiceServer(f.server, f.nodeService)
 	pb.RegisterGlobalStateServiceServer(f.server, f.globalState)
+	pb.RegisterTransactionServiceServer(f.server, f.txService)
 	return f.server.Serve(lis)
 }
 
@@ -223,6 +232,12 @@ func (m *meshServiceWrapper) LayerStream(_ *pb.LayerStreamRequest, stream pb.Mes
 	}
 }
 
+// MalfeasanceStream holds the stream open, the seed has no malfeasance proofs.
+func (m *meshServiceWrapper) MalfeasanceStream(_ *pb.MalfeasanceStreamRequest, stream pb.MeshService_MalfeasanceStreamServer) error {
+	<-stream.Context().Done()
+	return nil
+}
+
 func (m *meshServiceWrapper) sendEpoch(stream pb.MeshService_LayerStreamServer) error {
 	for _, epoch := range m.seedGen.Epochs {
 		for _, layerContainer := range epoch.Layers {
@@ -242,27 +257,7 @@ func (m *meshServiceWrapper) sendEpoch(stream pb.MeshService_LayerStreamServer)
 			for _, blockContainer := range layerContainer.Blocks {
 				tx := make([]*pb.Transaction, 0, len(blockContainer.Transactions))
 				for _, txContainer := range blockContainer.Transactions {
-					receiver, err := types.StringToAddress(txContainer.Receiver)
-					if err != nil {
-						panic("invalid receiver address: " + err.Error())
-					}
-					signer := m.seedGen.Accounts[strings.ToLower(txContainer.Sender)].Signer
-					tx = append(tx, &pb.Transaction{
-						Id:     mustParse(txContainer.Id),
-						Method: methodSend,
-						Principal: &pb.AccountId{
-							Address: txContainer.Sender,
-						},
-						GasPrice: txContainer.GasPrice,
-						MaxGas:   txContainer.MaxGas,
-						Nonce: &pb.Nonce{
-							Counter: txContainer.Counter,
-						},
-						Template: &pb.AccountId{
-							Address: wallet.TemplateAddress.String(),
-						},
-						Raw: sdkWallet.Spend(signer.PrivateKey(), receiver, txContainer.Amount, types.Nonce(txContainer.Counter), sdk.WithGasPrice(txContainer.GasPrice)),
-					})
+					tx = append(tx, newTransaction(m.seedGen, txContainer))
 				}
 				smesherId, _ := utils.StringToBytes(blockContainer.SmesherID)
 				blocksRes = append(blocksRes, &pb.Block{
@@ -288,6 +283,72 @@ func (m *meshServiceWrapper) sendEpoch(stream pb.MeshService_LayerStreamServer)
 	return nil
 }
 
+// newTransaction returns the generated transaction as served by the node.
+func newTransaction(seedGen *testseed.SeedGenerator, txContainer *model.Transaction) *pb.Transaction {
+	receiver, err := types.StringToAddress(txContainer.Receiver)
+	if err != nil {
+		panic("invalid receiver address: " + err.Error())
+	}
+	signer := seedGen.Accounts[strings.ToLower(txContainer.Sender)].Signer
+	return &pb.Transaction{
+		Id:     mustParse(txContainer.Id),
+		Method: methodSend,
+		Principal: &pb.AccountId{
+			Address: txContainer.Sender,
+		},
+		GasPrice: txContainer.GasPrice,
+		MaxGas:   txContainer.MaxGas,
+		Nonce: &pb.Nonce{
+			Counter: txContainer.Counter,
+		},
+		Template: &pb.AccountId{
+			Address: wallet.TemplateAddress.String(),
+		},
+		Raw: sdkWallet.Spend(signer.PrivateKey(), receiver, txContainer.Amount, types.Nonce(txContainer.Counter), sdk.WithGasPrice(txContainer.GasPrice)),
+	}
+}
+
+// StreamResults sends the results of the generated transactions of the blocks, then holds the
+// stream open.
+func (t *transactionServiceWrapper) StreamResults(_ *pb.TransactionResultsRequest, stream pb.TransactionService_StreamResultsServer) error {
+	for _, epoch := range t.seedGen.Epochs {
+		for _, layerContainer := range epoch.Layers {
+			for _, blockContainer := range layerContainer.Blocks {
+				for _, txContainer := range blockContainer.Transactions {
+					err := stream.Send(&pb.TransactionResult{
+						Tx:               newTransaction(t.seedGen, txContainer),
+						Status:           pb.TransactionResult_Status(txContainer.Result),
+						Message:          txContainer.Message,
+						GasConsumed:      txContainer.GasUsed,
+						Fee:              txContainer.Fee,
+						Block:            mustParse(blockContainer.Block.Id),
+						Layer:            layerContainer.Layer.Number,
+						TouchedAddresses: txContainer.TouchedAddresses,
+					})
+					if err != nil {
+						return fmt.Errorf("send to stream: %w", err)
+					}
+				}
+			}
+		}
+	}
+	<-stream.Context().Done()
+	return nil
+}
+
+// TransactionsState returns the generated state of the requested transactions.
+func (t *transactionServiceWrapper) TransactionsState(_ context.Context, req *pb.TransactionsStateRequest) (*pb.TransactionsStateResponse, error) {
+	states := make([]*pb.TransactionState, 0, len(req.TransactionId))
+	for _, id := range req.TransactionId {
+		state := pb.TransactionState_TRANSACTION_STATE_UNSPECIFIED
+		if tx, ok := t.seedGen.Transactions[strings.ToLower(utils.BytesToHex(id.Id))]; ok {
+			state = pb.TransactionState_TransactionState(tx.State)
+		}
+		states = append(states, &pb.TransactionState{Id: id, State: state})
+	}
+	return &pb.TransactionsStateResponse{TransactionsState: states}, nil
+}
+
 func (n *nodeServiceWrapper) StatusStream(req *pb.StatusStreamRequest, stream pb.NodeService_StatusStreamServer) error {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -2,6 +2,7 @@ package testserver
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"golang.org/x/net/websocket"
 	"log"
@@ -22,12 +23,12 @@ import (
 
 // TestAPIService wrapper over fake api service.
 type TestAPIService struct {
-	Storage *storage.Storage
+	Storage storage.StorageWriter
 	port    int
 }
 
 // StartTestAPIServiceV2 start test api service with refacored router.
-func StartTestAPIServiceV2(db *storage.Storage, dbReader *storagereader.Reader) (*TestAPIService, error) {
+func StartTestAPIServiceV2(db storage.StorageWriter, dbReader storagereader.StorageReader) (*TestAPIService, error) {
 	appPort, err := freeport.GetFreePort()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get free port: %s", err)
@@ -35,7 +36,7 @@ func StartTestAPIServiceV2(db *storage.Storage, dbReader *storagereader.Reader)
 	println("starting test api service on port", appPort)
 
 	api := apiv2.Init(service2.NewService(dbReader, time.Second), []string{"*"}, false)
-	go api.Run(fmt.Sprintf(":%d", appPort))
+	go api.Run(context.Background(), fmt.Sprintf(":%d", appPort), time.Second)
 	return &TestAPIService{
 		Storage: db,
 		port:    appPort,
@@ -79,13 +80,13 @@ func (tx *TestAPIService) GetReadWS(t *testing.T, path string) <-chan []byte {
 	go func() {
 		defer conn.Close()
 		for {
-			data := make([]byte, 1024)
-			readLen, err := conn.Read(data)
-			if err != nil {
+			// a frame longer than the read buffer is returned in parts, the message is read whole
+			var data []byte
+			if err := websocket.Message.Receive(conn, &data); err != nil {
 				log.Println(err)
 				return
 			}
-			result <- data[:readLen]
+			result <- data
 		}
 	}()
 	return result
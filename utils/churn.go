@@ -0,0 +1,22 @@
+package utils
+
+// SmeshersChurn counts the smeshers of an epoch which are new, the smeshers returning after
+// missing the previous epoch, and the smeshers of the previous epoch which stopped publishing
+// activations. Earlier are the smeshers seen before the previous epoch.
+func SmeshersChurn(current, previous, earlier map[string]bool) (joined, returning, stopped int64) {
+	for smesher := range current {
+		switch {
+		case previous[smesher]:
+		case earlier[smesher]:
+			returning++
+		default:
+			joined++
+		}
+	}
+	for smesher := range previous {
+		if !current[smesher] {
+			stopped++
+		}
+	}
+	return joined, returning, stopped
+}
@@ -0,0 +1,20 @@
+package utils
+
+// EpochLayers returns the first and the last layer of the epoch.
+// Both are 0 while the number of layers per epoch is unknown.
+func EpochLayers(epoch, epochNumLayers uint32) (first, last uint32) {
+	if epochNumLayers == 0 {
+		return 0, 0
+	}
+	first = epoch * epochNumLayers
+	last = first + epochNumLayers - 1
+	return first, last
+}
+
+// LayerEpoch returns the epoch the layer belongs to, or 0 while the number of layers per epoch is unknown.
+func LayerEpoch(layer, epochNumLayers uint32) uint32 {
+	if epochNumLayers == 0 {
+		return 0
+	}
+	return layer / epochNumLayers
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// Nakamoto returns the nakamoto coefficient of the smeshers, the least number of them which hold
+// more than half of the total. Empty weights count as 1, as in Gini.
+func Nakamoto(smeshers map[string]int64) int64 {
+	data := make(CommitmentSizes, 0, len(smeshers))
+	var sum int64
+	for _, size := range smeshers {
+		data = append(data, max(size, 1))
+		sum += max(size, 1)
+	}
+	sort.Sort(sort.Reverse(data))
+	var n, top int64
+	for _, y := range data {
+		if 2*top > sum {
+			break
+		}
+		top += y
+		n++
+	}
+	return n
+}
+
+// GiniBasisPoints returns the gini coefficient of the smeshers in basis points, see Gini.
+func GiniBasisPoints(smeshers map[string]int64) int64 {
+	return int64(math.Round(1e4 * Gini(smeshers)))
+}
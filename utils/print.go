@@ -1,16 +1,18 @@
 package utils
 
 import (
-    "github.com/spacemeshos/go-spacemesh/log"
     pb "github.com/spacemeshos/api/release/go/spacemesh/v1"
+    "go.uber.org/zap"
+
+    "github.com/spacemeshos/explorer-backend/internal/logging"
 )
 
 func PrintLayer(layer *pb.Layer) {
-    log.Info("Layer %v, status: %v, blocks: %v, activations: %v",
-        layer.Number,
-        layer.Status,
-        len(layer.Blocks),
-        len(layer.Activations),
+    logging.Info("layer",
+        logging.Layer(layer.GetNumber().GetNumber()),
+        zap.Stringer("status", layer.Status),
+        zap.Int("blocks", len(layer.Blocks)),
+        zap.Int("activations", len(layer.Activations)),
     )
     for _, atx := range layer.Activations {
         PrintActivation(atx)
@@ -45,13 +47,13 @@ func PrintTransaction(tx *pb.Transaction) {
 }
 
 func PrintActivation(atx *pb.Activation) {
-    log.Info("ATX ID: %v, layer: %v, smesher: %v, coinbase: %v, prev: %v, size: %v",
-        atx.Id,
-        atx.Layer,
-        atx.SmesherId,
-        atx.Coinbase,
-        atx.PrevAtx,
-        atx.NumUnits,
+    logging.Info("activation",
+        zap.String("id", BytesToHex(atx.GetId().GetId())),
+        logging.Layer(atx.GetLayer().GetNumber()),
+        zap.String("smesher", BytesToHex(atx.GetSmesherId().GetId())),
+        zap.String("coinbase", atx.GetCoinbase().GetAddress()),
+        zap.String("prev", BytesToHex(atx.GetPrevAtx().GetId())),
+        zap.Uint32("size", atx.NumUnits),
     )
 }
 